@@ -18,8 +18,10 @@ import (
 
 // FileAppender appends audit records to a JSONL file with hash chain.
 type FileAppender struct {
-	path string
-	mu   sync.Mutex
+	path          string
+	correlationID string
+	author        string
+	mu            sync.Mutex
 }
 
 // NewFileAppender creates a new FileAppender.
@@ -27,16 +29,56 @@ func NewFileAppender(path string) *FileAppender {
 	return &FileAppender{path: path}
 }
 
-// Append adds a new audit record to the log.
+// SetCorrelationID tags the records appended from now on with id. Without
+// it, records carry the correlation ID set through CorrelationEnvVar, if
+// any.
+func (a *FileAppender) SetCorrelationID(id string) {
+	a.correlationID = id
+}
+
+// SetAuthor names author as the author of the records appended from now
+// on. Without it, records name identity.Author.
+func (a *FileAppender) SetAuthor(author string) {
+	a.author = author
+}
+
+// Append adds a new audit record to the log. If batching is enabled for the
+// log (see EnableBatching), the record is buffered instead.
 func (a *FileAppender) Append(eventType model.AuditEventType, worktreeName string, snapshotID model.SnapshotID, details map[string]any) error {
+	record := newRecord(a.correlationID, a.author, eventType, worktreeName, snapshotID, details)
+	if b := activeBatchWriter(a.path); b != nil {
+		return b.append(record)
+	}
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	return appendRecords(a.path, []*model.AuditRecord{record})
+}
+
+// newRecord creates an unchained record stamped with the current time,
+// correlationID (see ResolveCorrelationID) and author (see ResolveAuthor).
+func newRecord(correlationID, author string, eventType model.AuditEventType, worktreeName string, snapshotID model.SnapshotID, details map[string]any) *model.AuditRecord {
+	return &model.AuditRecord{
+		Timestamp:     time.Now().UTC(),
+		EventType:     eventType,
+		SnapshotID:    snapshotID,
+		WorktreeName:  worktreeName,
+		CorrelationID: ResolveCorrelationID(correlationID),
+		Author:        ResolveAuthor(author),
+		Details:       details,
+	}
+}
 
-	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+// appendRecords chains records onto the log at path and writes them with a
+// single fsync, holding the file lock throughout, then moves the log's head
+// anchor to the last of them. PrevHash and RecordHash of each record are
+// overwritten.
+func appendRecords(path string, records []*model.AuditRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return fmt.Errorf("create audit dir: %w", err)
 	}
 
-	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_RDWR, 0644)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return fmt.Errorf("open audit log: %w", err)
 	}
@@ -48,45 +90,45 @@ func (a *FileAppender) Append(eventType model.AuditEventType, worktreeName strin
 	defer unlockFile(file)
 
 	// Get previous record hash
-	prevHash, err := a.getLastRecordHashLocked(file)
+	prevHash, count, err := lastRecord(file)
 	if err != nil {
 		return fmt.Errorf("get last record hash: %w", err)
 	}
 
-	// Create record
-	record := &model.AuditRecord{
-		Timestamp:    time.Now().UTC(),
-		EventType:    eventType,
-		SnapshotID:   snapshotID,
-		WorktreeName: worktreeName,
-		Details:      details,
-		PrevHash:     prevHash,
-	}
-
-	// Compute record hash (before setting RecordHash field)
-	recordHash, err := computeRecordHash(record)
-	if err != nil {
-		return fmt.Errorf("compute record hash: %w", err)
-	}
-	record.RecordHash = recordHash
+	var buf []byte
+	for _, record := range records {
+		record.PrevHash = prevHash
+		// Compute record hash (before setting RecordHash field)
+		record.RecordHash = ""
+		recordHash, err := computeRecordHash(record)
+		if err != nil {
+			return fmt.Errorf("compute record hash: %w", err)
+		}
+		record.RecordHash = recordHash
+		prevHash = recordHash
 
-	// Serialize to JSONL
-	line, err := json.Marshal(record)
-	if err != nil {
-		return fmt.Errorf("marshal audit record: %w", err)
+		// Serialize to JSONL
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("marshal audit record: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
 	}
 
 	// Seek to end and append
 	if _, err := file.Seek(0, 2); err != nil {
 		return fmt.Errorf("seek to end: %w", err)
 	}
-	if _, err := file.Write(append(line, '\n')); err != nil {
+	if _, err := file.Write(buf); err != nil {
 		return fmt.Errorf("write audit record: %w", err)
 	}
 	if err := file.Sync(); err != nil {
 		return fmt.Errorf("sync audit log: %w", err)
 	}
 
+	if err := writeHead(path, Head{Records: count + len(records), RecordHash: prevHash}); err != nil {
+		return fmt.Errorf("update audit head: %w", err)
+	}
 	return nil
 }
 
@@ -104,41 +146,49 @@ func (a *FileAppender) GetLastRecordHash() (model.HashValue, error) {
 	}
 	defer file.Close()
 
-	return a.getLastRecordHashLocked(file)
+	lastHash, _, err := lastRecord(file)
+	return lastHash, err
 }
 
-func (a *FileAppender) getLastRecordHashLocked(file *os.File) (model.HashValue, error) {
+// lastRecord returns the hash of the last record in file and the number of
+// records in it.
+func lastRecord(file *os.File) (model.HashValue, int, error) {
 	// Read from beginning to find last record
 	if _, err := file.Seek(0, 0); err != nil {
-		return "", fmt.Errorf("seek to start: %w", err)
+		return "", 0, fmt.Errorf("seek to start: %w", err)
 	}
 
 	var lastHash model.HashValue
+	count := 0
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 	for scanner.Scan() {
 		var record model.AuditRecord
 		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
 			continue // skip malformed lines
 		}
 		lastHash = record.RecordHash
+		count++
 	}
 
 	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("scan audit log: %w", err)
+		return "", 0, fmt.Errorf("scan audit log: %w", err)
 	}
 
-	return lastHash, nil
+	return lastHash, count, nil
 }
 
 func computeRecordHash(record *model.AuditRecord) (model.HashValue, error) {
 	// Create a copy without RecordHash for hash computation
 	hashRecord := &model.AuditRecord{
-		Timestamp:    record.Timestamp,
-		EventType:    record.EventType,
-		SnapshotID:   record.SnapshotID,
-		WorktreeName: record.WorktreeName,
-		Details:      record.Details,
-		PrevHash:     record.PrevHash,
+		Timestamp:     record.Timestamp,
+		EventType:     record.EventType,
+		SnapshotID:    record.SnapshotID,
+		WorktreeName:  record.WorktreeName,
+		CorrelationID: record.CorrelationID,
+		Author:        record.Author,
+		Details:       record.Details,
+		PrevHash:      record.PrevHash,
 		// RecordHash intentionally omitted
 	}
 
@@ -3,15 +3,18 @@ package audit_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/identity"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -661,3 +664,217 @@ func TestFileAppender_RapidSequentialAppends(t *testing.T) {
 	}
 	assert.Equal(t, 100, count)
 }
+
+func TestFileAppender_SetCorrelationID(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	first := audit.NewFileAppender(logPath)
+	first.SetCorrelationID("first")
+	second := audit.NewFileAppender(logPath)
+	second.SetCorrelationID("second")
+
+	// Appenders of concurrent operations on the same log keep their own IDs
+	require.NoError(t, first.Append(model.EventTypeSnapshotCreate, "main", "", nil))
+	require.NoError(t, second.Append(model.EventTypeRestore, "main", "", nil))
+	require.NoError(t, first.Append(model.EventTypeRestore, "main", "", nil))
+	require.NoError(t, audit.NewFileAppender(logPath).Append(model.EventTypeGCRun, "", "", nil))
+
+	records, err := audit.Read(logPath, audit.Filter{CorrelationID: "first"})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, model.EventTypeSnapshotCreate, records[0].EventType)
+	assert.Equal(t, model.EventTypeRestore, records[1].EventType)
+
+	all, err := audit.Read(logPath, audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, "second", all[1].CorrelationID)
+	assert.Empty(t, all[3].CorrelationID)
+	// Correlation IDs are covered by the hash chain
+	assert.Equal(t, all[2].RecordHash, all[3].PrevHash)
+}
+
+func TestResolveCorrelationID_EnvFallback(t *testing.T) {
+	t.Setenv(audit.CorrelationEnvVar, "from-env")
+	assert.Equal(t, "from-env", audit.ResolveCorrelationID(""))
+	assert.Equal(t, "explicit", audit.ResolveCorrelationID("explicit"))
+
+	ctx := audit.WithCorrelationID(context.Background(), "from-ctx")
+	assert.Equal(t, "from-ctx", audit.CorrelationID(ctx))
+	assert.Empty(t, audit.CorrelationID(context.Background()))
+}
+
+func TestResolveAuthor_IdentityFallback(t *testing.T) {
+	t.Setenv(identity.EnvVar, "from-env")
+	assert.Equal(t, "from-env", audit.ResolveAuthor(""))
+	assert.Equal(t, "explicit", audit.ResolveAuthor("explicit"))
+
+	ctx := audit.WithAuthor(context.Background(), "from-ctx")
+	assert.Equal(t, "from-ctx", audit.Author(ctx))
+	assert.Empty(t, audit.Author(context.Background()))
+}
+
+func TestFileAppender_SetAuthor(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	appender := audit.NewFileAppender(logPath)
+	t.Setenv(identity.EnvVar, "from-env")
+
+	appender.SetAuthor("sandbox-7")
+	require.NoError(t, appender.Append(model.EventTypeSnapshotCreate, "main", "", nil))
+	// Other appenders of the log are not affected
+	require.NoError(t, audit.NewFileAppender(logPath).Append(model.EventTypeGCRun, "", "", nil))
+
+	all, err := audit.Read(logPath, audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "sandbox-7", all[0].Author)
+	assert.Equal(t, "from-env", all[1].Author)
+
+	// Authors are covered by the hash chain
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(logPath, bytes.Replace(data, []byte(`"sandbox-7"`), []byte(`"mallory"`), 1), 0644))
+	result, err := audit.Verify(logPath)
+	require.NoError(t, err)
+	assert.False(t, result.OK())
+}
+
+func TestRead_MissingLog(t *testing.T) {
+	records, err := audit.Read(filepath.Join(t.TempDir(), "none.jsonl"), audit.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+// countLines returns the number of lines in path, or 0 if it is missing.
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	return bytes.Count(data, []byte("\n"))
+}
+
+func assertChained(t *testing.T, records []*model.AuditRecord) {
+	t.Helper()
+	for i := 1; i < len(records); i++ {
+		assert.Equal(t, records[i-1].RecordHash, records[i].PrevHash, "record %d", i)
+	}
+}
+
+func TestBatchWriter_FlushesWhenFull(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	batch, err := audit.EnableBatching(logPath, audit.BatchOptions{MaxBuffered: 3, FlushInterval: time.Hour})
+	require.NoError(t, err)
+
+	// Existing appenders for the log write through the batch
+	appender := audit.NewFileAppender(logPath)
+	require.NoError(t, appender.Append(model.EventTypeSnapshotCreate, "main", "s1", nil))
+	require.NoError(t, appender.Append(model.EventTypeSnapshotCreate, "main", "s2", nil))
+	assert.Equal(t, 0, countLines(t, logPath))
+
+	wals, err := filepath.Glob(logPath + ".*.wal")
+	require.NoError(t, err)
+	require.Len(t, wals, 1)
+	assert.Equal(t, 2, countLines(t, wals[0]))
+
+	require.NoError(t, appender.Append(model.EventTypeRestore, "main", "s1", nil))
+	assert.Equal(t, 3, countLines(t, logPath))
+	assert.Equal(t, 0, countLines(t, wals[0]), "flushed records leave the WAL")
+
+	require.NoError(t, appender.Append(model.EventTypeGCRun, "", "", nil))
+	require.NoError(t, batch.Close())
+	assert.NoFileExists(t, wals[0])
+
+	records, err := audit.Read(logPath, audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, model.SnapshotID("s2"), records[1].SnapshotID)
+	assertChained(t, records)
+
+	// After Close, appends are unbuffered again
+	require.NoError(t, appender.Append(model.EventTypeGCRun, "", "", nil))
+	assert.Equal(t, 5, countLines(t, logPath))
+}
+
+func TestBatchWriter_ReadAndIntervalFlush(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	batch, err := audit.EnableBatching(logPath, audit.BatchOptions{FlushInterval: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer batch.Close()
+
+	_, err = audit.EnableBatching(logPath, audit.BatchOptions{})
+	assert.Error(t, err, "one batch per log")
+
+	require.NoError(t, batch.Append(model.EventTypeSnapshotCreate, "main", "s1", nil))
+	records, err := audit.Read(logPath, audit.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 1, "Read flushes pending records")
+
+	require.NoError(t, batch.Append(model.EventTypeSnapshotCreate, "main", "s2", nil))
+	assert.Eventually(t, func() bool { return countLines(t, logPath) == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestRecoverWAL(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	appender := audit.NewFileAppender(logPath)
+	require.NoError(t, appender.Append(model.EventTypeSnapshotCreate, "main", "s1", nil))
+	flushed, err := audit.Read(logPath, audit.Filter{})
+	require.NoError(t, err)
+
+	// A crashed writer left s1 (already flushed) and s2 (not flushed)
+	lost := &model.AuditRecord{
+		Timestamp:    flushed[0].Timestamp.Add(time.Millisecond),
+		EventType:    model.EventTypeSnapshotCreate,
+		WorktreeName: "main",
+		SnapshotID:   "s2",
+	}
+	var wal bytes.Buffer
+	for _, r := range []*model.AuditRecord{{
+		Timestamp:    flushed[0].Timestamp,
+		EventType:    flushed[0].EventType,
+		WorktreeName: flushed[0].WorktreeName,
+		SnapshotID:   flushed[0].SnapshotID,
+	}, lost} {
+		line, err := json.Marshal(r)
+		require.NoError(t, err)
+		wal.Write(append(line, '\n'))
+	}
+	wal.WriteString(`{"timestamp":"torn`)
+	walPath := logPath + ".99999.wal"
+	require.NoError(t, os.WriteFile(walPath, wal.Bytes(), 0644))
+
+	orphans, err := audit.OrphanWALs(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{walPath}, orphans)
+
+	replayed, err := audit.RecoverWAL(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.NoFileExists(t, walPath)
+
+	records, err := audit.Read(logPath, audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, model.SnapshotID("s2"), records[1].SnapshotID)
+	assertChained(t, records)
+}
+
+func TestOrphanWALs_SkipsActiveWriter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("write-ahead files are not locked on Windows")
+	}
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	batch, err := audit.EnableBatching(logPath, audit.BatchOptions{FlushInterval: time.Hour})
+	require.NoError(t, err)
+	defer batch.Close()
+	require.NoError(t, batch.Append(model.EventTypeSnapshotCreate, "main", "s1", nil))
+
+	orphans, err := audit.OrphanWALs(logPath)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	replayed, err := audit.RecoverWAL(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, replayed)
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/identity"
+)
+
+type authorKey struct{}
+
+// WithAuthor returns a copy of ctx carrying author as the author of the
+// operation ctx belongs to. As with WithCorrelationID, operations pass it on
+// explicitly, for example to FileAppender.SetAuthor.
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// Author returns the author carried by ctx, or "".
+func Author(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	author, _ := ctx.Value(authorKey{}).(string)
+	return author
+}
+
+// ResolveAuthor returns author, or identity.Author if author is empty.
+func ResolveAuthor(author string) string {
+	if author != "" {
+		return author
+	}
+	return identity.Author()
+}
@@ -0,0 +1,401 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/logging"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Batching defaults.
+const (
+	DefaultBatchSize     = 64
+	DefaultFlushInterval = time.Second
+)
+
+// walSuffix ends the write-ahead file names of batch writers:
+// "<log>.<host>-<pid>-<random>.wal".
+const walSuffix = ".wal"
+
+// BatchOptions configures a BatchWriter.
+type BatchOptions struct {
+	// MaxBuffered is the number of records held before Append flushes
+	// synchronously; zero uses DefaultBatchSize.
+	MaxBuffered int
+	// FlushInterval is how often buffered records are flushed in the
+	// background; zero uses DefaultFlushInterval.
+	FlushInterval time.Duration
+	// SyncWAL fsyncs the write-ahead file on every Append. Without it,
+	// buffered records survive a process crash but not a power loss.
+	SyncWAL bool
+}
+
+// BatchWriter buffers audit records and appends them to the log in batches,
+// paying for the file lock, chain lookup and fsync once per batch instead of
+// once per record.
+//
+// Every record is first written to a per-process write-ahead file next to the
+// log. Records left there by a crashed process are replayed into the log by
+// RecoverWAL, which EnableBatching runs before starting. Buffered records
+// reach the log, and become visible to readers in other processes, when a
+// batch is flushed: on a full buffer, every FlushInterval, on Flush and on
+// Close.
+type BatchWriter struct {
+	logPath string
+	opts    BatchOptions
+
+	mu      sync.Mutex
+	wal     *os.File
+	pending []*model.AuditRecord
+	closed  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var (
+	batchMu sync.Mutex
+	// batchWriters maps an absolute audit log path to its active BatchWriter.
+	batchWriters = make(map[string]*BatchWriter)
+)
+
+// EnableBatching starts batching for the audit log at logPath: until the
+// returned writer is closed, every FileAppender in this process for the log
+// appends through it. Orphaned write-ahead files are replayed first.
+func EnableBatching(logPath string, opts BatchOptions) (*BatchWriter, error) {
+	if opts.MaxBuffered <= 0 {
+		opts.MaxBuffered = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+
+	key := logKey(logPath)
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	if _, active := batchWriters[key]; active {
+		return nil, fmt.Errorf("audit batching already enabled for %s", logPath)
+	}
+
+	if _, err := RecoverWAL(logPath); err != nil {
+		return nil, err
+	}
+
+	wal, err := openWAL(walPath(logPath))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &BatchWriter{
+		logPath: logPath,
+		opts:    opts,
+		wal:     wal,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	batchWriters[key] = b
+	go b.loop()
+	return b, nil
+}
+
+// walPath returns a new write-ahead file name for the log. The host and a
+// random ID keep processes with the same pid, on other hosts sharing the
+// repository or in other PID namespaces, from picking the same file.
+func walPath(logPath string) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	host = strings.ReplaceAll(host, string(filepath.Separator), "_")
+	var id [4]byte
+	rand.Read(id[:])
+	return fmt.Sprintf("%s.%s-%d-%x%s", logPath, host, os.Getpid(), id, walSuffix)
+}
+
+// openWAL creates and locks a write-ahead file. The lock is held until Close
+// so RecoverWAL in other processes leaves the file alone. The file is only
+// truncated once the lock is held, so a writer already using it keeps its
+// records.
+func openWAL(walPath string) (*os.File, error) {
+	for attempt := 0; ; attempt++ {
+		wal, err := os.OpenFile(walPath, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open audit wal: %w", err)
+		}
+		locked, err := tryLockFile(wal)
+		if err != nil {
+			wal.Close()
+			return nil, fmt.Errorf("flock audit wal: %w", err)
+		}
+		if !locked {
+			wal.Close()
+			return nil, fmt.Errorf("open audit wal: %s is in use by another writer", walPath)
+		}
+
+		// RecoverWAL in another process may have removed the empty file
+		// between open and lock
+		fi, statErr := wal.Stat()
+		pi, pathErr := os.Stat(walPath)
+		if statErr == nil && pathErr == nil && os.SameFile(fi, pi) {
+			if err := wal.Truncate(0); err != nil {
+				unlockFile(wal)
+				wal.Close()
+				return nil, fmt.Errorf("truncate audit wal: %w", err)
+			}
+			return wal, nil
+		}
+		unlockFile(wal)
+		wal.Close()
+		if attempt == 2 {
+			return nil, fmt.Errorf("open audit wal: %s removed concurrently", walPath)
+		}
+	}
+}
+
+func activeBatchWriter(logPath string) *BatchWriter {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	if len(batchWriters) == 0 {
+		return nil
+	}
+	return batchWriters[logKey(logPath)]
+}
+
+// Append buffers a record after writing it to the write-ahead file. The
+// record's timestamp and correlation ID are taken now, not at flush.
+func (b *BatchWriter) Append(eventType model.AuditEventType, worktreeName string, snapshotID model.SnapshotID, details map[string]any) error {
+	return b.append(newRecord("", "", eventType, worktreeName, snapshotID, details))
+}
+
+func (b *BatchWriter) append(record *model.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal audit record: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return fmt.Errorf("audit batch writer closed")
+	}
+
+	if _, err := b.wal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit wal: %w", err)
+	}
+	if b.opts.SyncWAL {
+		if err := b.wal.Sync(); err != nil {
+			return fmt.Errorf("sync audit wal: %w", err)
+		}
+	}
+
+	b.pending = append(b.pending, record)
+	if len(b.pending) >= b.opts.MaxBuffered {
+		return b.flushLocked()
+	}
+	return nil
+}
+
+// Flush appends all buffered records to the log.
+func (b *BatchWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *BatchWriter) flushLocked() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	if err := appendRecords(b.logPath, b.pending); err != nil {
+		return err
+	}
+	b.pending = nil
+
+	// Everything in the WAL is now in the log
+	if err := b.wal.Truncate(0); err != nil {
+		return fmt.Errorf("truncate audit wal: %w", err)
+	}
+	if _, err := b.wal.Seek(0, 0); err != nil {
+		return fmt.Errorf("seek audit wal: %w", err)
+	}
+	return nil
+}
+
+// Close flushes buffered records and stops batching for the log. If the final
+// flush fails, the write-ahead file is kept for RecoverWAL.
+func (b *BatchWriter) Close() error {
+	// Unregister first so new appends go straight to the log
+	batchMu.Lock()
+	if batchWriters[logKey(b.logPath)] == b {
+		delete(batchWriters, logKey(b.logPath))
+	}
+	batchMu.Unlock()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	<-b.done
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	flushErr := b.flushLocked()
+	if flushErr == nil {
+		os.Remove(b.wal.Name())
+	}
+	unlockFile(b.wal)
+	b.wal.Close()
+	return flushErr
+}
+
+func (b *BatchWriter) loop() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				logging.Warn("audit: batch flush failed", map[string]any{"error": err.Error()})
+			}
+		}
+	}
+}
+
+// OrphanWALs returns the write-ahead files of the log at logPath that are not
+// held by a running batch writer.
+func OrphanWALs(logPath string) ([]string, error) {
+	paths, err := filepath.Glob(logPath + ".*" + walSuffix)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []string
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		locked, _ := tryLockFile(f)
+		if locked {
+			orphans = append(orphans, path)
+			unlockFile(f)
+		}
+		f.Close()
+	}
+	return orphans, nil
+}
+
+// RecoverWAL appends records from orphaned write-ahead files of the log at
+// logPath and removes the files. Records that already reached the log (the
+// writer crashed after flushing but before truncating) are not appended again.
+// It returns the number of records replayed.
+func RecoverWAL(logPath string) (int, error) {
+	paths, err := filepath.Glob(logPath + ".*" + walSuffix)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, path := range paths {
+		n, err := recoverWALFile(logPath, path)
+		if err != nil {
+			return replayed, fmt.Errorf("recover audit wal %s: %w", filepath.Base(path), err)
+		}
+		replayed += n
+	}
+	return replayed, nil
+}
+
+func recoverWALFile(logPath, walPath string) (int, error) {
+	f, err := os.OpenFile(walPath, os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	locked, err := tryLockFile(f)
+	if err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil // owned by a running writer
+	}
+	defer unlockFile(f)
+
+	// A torn final line from the crash is skipped as malformed
+	var records []*model.AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record model.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	missing, err := notInLog(logPath, records)
+	if err != nil {
+		return 0, err
+	}
+	if len(missing) > 0 {
+		if err := appendRecords(logPath, missing); err != nil {
+			return 0, err
+		}
+	}
+	if err := os.Remove(walPath); err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// notInLog returns the records not yet present in the log, comparing the
+// fields set at Append time.
+func notInLog(logPath string, records []*model.AuditRecord) ([]*model.AuditRecord, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+	existing, err := readLog(logPath, Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range existing {
+		if !r.Timestamp.Before(records[0].Timestamp) {
+			seen[walRecordKey(r)] = true
+		}
+	}
+	var missing []*model.AuditRecord
+	for _, r := range records {
+		if !seen[walRecordKey(r)] {
+			missing = append(missing, r)
+		}
+	}
+	return missing, nil
+}
+
+func walRecordKey(r *model.AuditRecord) string {
+	return fmt.Sprintf("%d|%s|%s|%s|%s", r.Timestamp.UnixNano(), r.EventType, r.WorktreeName, r.SnapshotID, r.CorrelationID)
+}
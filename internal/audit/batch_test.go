@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALPath_Unique(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	a, b := walPath(logPath), walPath(logPath)
+	assert.NotEqual(t, a, b)
+	assert.True(t, strings.HasPrefix(filepath.Base(a), "audit.jsonl."+host+"-"+strconv.Itoa(os.Getpid())+"-"), a)
+	assert.True(t, strings.HasSuffix(a, walSuffix))
+}
+
+func TestOpenWAL_SharedName(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("write-ahead files are not locked on Windows")
+	}
+	// Two writers that derive the same name, such as equal pids in
+	// different PID namespaces, must not truncate each other's records
+	path := filepath.Join(t.TempDir(), "audit.jsonl.1.wal")
+	first, err := openWAL(path)
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.WriteString("{\"event\":\"pending\"}\n")
+	require.NoError(t, err)
+
+	_, err = openWAL(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "in use")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"event\":\"pending\"}\n", string(data))
+}
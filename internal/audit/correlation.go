@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// CorrelationEnvVar lets an external caller (for example an orchestrator
+// invoking the CLI several times) tag every audit record with its own ID.
+const CorrelationEnvVar = "JVS_CORRELATION_ID"
+
+type correlationKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying id as the correlation ID
+// of the operation ctx belongs to. Operations pass it on explicitly, for
+// example to FileAppender.SetCorrelationID, so concurrent operations on the
+// same repository keep their own IDs.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// ResolveCorrelationID returns id, or the correlation ID set through
+// CorrelationEnvVar if id is empty.
+func ResolveCorrelationID(id string) string {
+	if id != "" {
+		return id
+	}
+	return os.Getenv(CorrelationEnvVar)
+}
+
+func logKey(logPath string) string {
+	if abs, err := filepath.Abs(logPath); err == nil {
+		return abs
+	}
+	return filepath.Clean(logPath)
+}
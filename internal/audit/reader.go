@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// LogPath returns the audit log path for a repository.
+func LogPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".jvs", "audit", "audit.jsonl")
+}
+
+// Filter selects audit records. Zero-value fields match everything.
+type Filter struct {
+	CorrelationID string
+	EventType     model.AuditEventType
+	WorktreeName  string
+}
+
+func (f Filter) matches(r *model.AuditRecord) bool {
+	if f.CorrelationID != "" && r.CorrelationID != f.CorrelationID {
+		return false
+	}
+	if f.EventType != "" && r.EventType != f.EventType {
+		return false
+	}
+	if f.WorktreeName != "" && r.WorktreeName != f.WorktreeName {
+		return false
+	}
+	return true
+}
+
+// Read returns the records in the audit log at path that match filter, oldest
+// first. Malformed lines are skipped. A missing log yields no records. Records
+// buffered by a BatchWriter in this process are flushed first.
+func Read(path string, filter Filter) ([]*model.AuditRecord, error) {
+	if b := activeBatchWriter(path); b != nil {
+		if err := b.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	return readLog(path, filter)
+}
+
+func readLog(path string, filter Filter) ([]*model.AuditRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var records []*model.AuditRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record model.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // skip malformed lines
+		}
+		if filter.matches(&record) {
+			records = append(records, &record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan audit log: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Head anchors the end of an audit log: the number of records it had and
+// the hash of the last one after the latest append. It is kept next to the
+// log, so that records removed from the end are detected.
+type Head struct {
+	Records    int             `json:"records"`
+	RecordHash model.HashValue `json:"record_hash"`
+}
+
+// HeadPath returns the path of the head anchor of the log at logPath.
+func HeadPath(logPath string) string {
+	return logPath + ".head"
+}
+
+func writeHead(logPath string, head Head) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	return fsutil.AtomicWrite(HeadPath(logPath), data, 0644)
+}
+
+// readHead returns the head anchor of the log at logPath, or nil if the log
+// was last written before anchors were kept.
+func readHead(logPath string) (*Head, error) {
+	data, err := os.ReadFile(HeadPath(logPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read audit head: %w", err)
+	}
+	var head Head
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("parse audit head: %w", err)
+	}
+	return &head, nil
+}
+
+// Kinds of ChainProblem.
+const (
+	// ProblemMalformed is a line that is not an audit record.
+	ProblemMalformed = "malformed"
+	// ProblemTampered is a record whose content no longer matches its
+	// record_hash.
+	ProblemTampered = "tampered"
+	// ProblemBrokenLink is a record whose prev_hash is not the record_hash
+	// of the record before it: records were removed, inserted or reordered.
+	ProblemBrokenLink = "broken_link"
+	// ProblemTruncated is a log that ends before its head anchor.
+	ProblemTruncated = "truncated"
+)
+
+// ChainProblem is one inconsistency found by Verify.
+type ChainProblem struct {
+	Line    int    `json:"line,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// VerifyResult reports the state of an audit log's hash chain.
+type VerifyResult struct {
+	Records  int             `json:"records"`
+	LastHash model.HashValue `json:"last_hash,omitempty"`
+	// Anchored tells whether the log has a head anchor; without one,
+	// records removed from its end cannot be detected.
+	Anchored bool           `json:"anchored"`
+	Problems []ChainProblem `json:"problems,omitempty"`
+}
+
+// OK reports whether no problems were found.
+func (r *VerifyResult) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks the hash chain of the audit log at logPath: every record's
+// hash must match its content, every record must link to the one before it,
+// the first must start the chain, and the log must reach its head anchor.
+// Records appended after the anchor was read are accepted. A missing log is
+// an empty, valid one. Records buffered by a BatchWriter in this process are
+// flushed first.
+func Verify(logPath string) (*VerifyResult, error) {
+	if b := activeBatchWriter(logPath); b != nil {
+		if err := b.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	// The head is read before the log, so concurrent appends only add
+	// records past it
+	head, err := readHead(logPath)
+	if err != nil {
+		return nil, err
+	}
+	result := &VerifyResult{Anchored: head != nil}
+
+	file, err := os.Open(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	var headHash model.HashValue
+	if file != nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			var record model.AuditRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				result.add(line, ProblemMalformed, "not an audit record")
+				continue
+			}
+			if record.PrevHash != result.LastHash {
+				if result.Records == 0 {
+					result.add(line, ProblemBrokenLink, "first record does not start the chain; earlier records were removed")
+				} else {
+					result.add(line, ProblemBrokenLink, "prev_hash does not match the previous record")
+				}
+			}
+			if hash, err := computeRecordHash(&record); err != nil || hash != record.RecordHash {
+				result.add(line, ProblemTampered, "record_hash does not match the record")
+			}
+			result.Records++
+			result.LastHash = record.RecordHash
+			if head != nil && result.Records == head.Records {
+				headHash = record.RecordHash
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("scan audit log: %w", err)
+		}
+	}
+
+	switch {
+	case head == nil:
+	case result.Records < head.Records:
+		result.add(0, ProblemTruncated, fmt.Sprintf("log has %d records, but %d were written", result.Records, head.Records))
+	case headHash != head.RecordHash:
+		result.add(0, ProblemTruncated, fmt.Sprintf("record %d is not the one last written; the log was truncated and rewritten", head.Records))
+	}
+	return result, nil
+}
+
+func (r *VerifyResult) add(line int, kind, message string) {
+	r.Problems = append(r.Problems, ChainProblem{Line: line, Kind: kind, Message: message})
+}
@@ -0,0 +1,133 @@
+package audit_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// writeLog appends n records and returns the log's lines.
+func writeLog(t *testing.T, logPath string, n int) []string {
+	t.Helper()
+	appender := audit.NewFileAppender(logPath)
+	for i := range n {
+		require.NoError(t, appender.Append(model.EventTypeSnapshotCreate, "main", "", map[string]any{"n": i, "bytes": int64(1) << 40}))
+	}
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func rewriteLog(t *testing.T, logPath string, lines []string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+}
+
+func problemKinds(r *audit.VerifyResult) []string {
+	var kinds []string
+	for _, p := range r.Problems {
+		kinds = append(kinds, p.Kind)
+	}
+	return kinds
+}
+
+func TestVerify_IntactLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	result, err := audit.Verify(logPath)
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.Zero(t, result.Records)
+
+	writeLog(t, logPath, 3)
+	result, err = audit.Verify(logPath)
+	require.NoError(t, err)
+	assert.True(t, result.OK(), "problems: %v", result.Problems)
+	assert.Equal(t, 3, result.Records)
+	assert.True(t, result.Anchored)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func([]string) []string
+		want []string
+	}{
+		{"edited record", func(l []string) []string {
+			l[1] = strings.Replace(l[1], `"main"`, `"evil"`, 1)
+			return l
+		}, []string{audit.ProblemTampered}},
+		{"removed record", func(l []string) []string {
+			return append(l[:1:1], l[2:]...)
+		}, []string{audit.ProblemBrokenLink, audit.ProblemTruncated}},
+		{"removed first record", func(l []string) []string {
+			return l[1:]
+		}, []string{audit.ProblemBrokenLink, audit.ProblemTruncated}},
+		{"reordered records", func(l []string) []string {
+			l[1], l[2] = l[2], l[1]
+			return l
+		}, []string{audit.ProblemBrokenLink, audit.ProblemBrokenLink, audit.ProblemBrokenLink}},
+		{"truncated log", func(l []string) []string {
+			return l[:2]
+		}, []string{audit.ProblemTruncated}},
+		{"malformed line", func(l []string) []string {
+			return append(l, "{not json")
+		}, []string{audit.ProblemMalformed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+			lines := writeLog(t, logPath, 4)
+			rewriteLog(t, logPath, tt.edit(lines))
+
+			result, err := audit.Verify(logPath)
+			require.NoError(t, err)
+			assert.False(t, result.OK())
+			assert.Equal(t, tt.want, problemKinds(result))
+		})
+	}
+}
+
+func TestVerify_TruncatedAndAppended(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	lines := writeLog(t, logPath, 3)
+	head, err := os.ReadFile(audit.HeadPath(logPath))
+	require.NoError(t, err)
+
+	// Dropping the last record and appending a new one keeps the count
+	rewriteLog(t, logPath, lines[:2])
+	require.NoError(t, os.Remove(audit.HeadPath(logPath)))
+	writeLog(t, logPath, 1)
+	require.NoError(t, os.WriteFile(audit.HeadPath(logPath), head, 0644))
+
+	result, err := audit.Verify(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{audit.ProblemTruncated}, problemKinds(result))
+}
+
+func TestVerify_UnanchoredLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	lines := writeLog(t, logPath, 3)
+	require.NoError(t, os.Remove(audit.HeadPath(logPath)))
+	rewriteLog(t, logPath, lines[:2])
+
+	result, err := audit.Verify(logPath)
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.False(t, result.Anchored)
+
+	// The next append anchors the log again
+	writeLog(t, logPath, 1)
+	result, err = audit.Verify(logPath)
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.True(t, result.Anchored)
+	assert.Equal(t, 3, result.Records)
+}
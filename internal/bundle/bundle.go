@@ -0,0 +1,265 @@
+// Package bundle writes snapshots as portable bundles and imports them.
+//
+// A bundle is a tar stream holding the snapshot descriptor as
+// "descriptor.json", followed by the stored snapshot directory under
+// "payload/" without its .READY marker, and last "integrity.json" with the
+// SHA-256 of every payload file. Compressed and encrypted snapshots stay
+// compressed or encrypted; the descriptor records how. Ownership is
+// normalized to 0:0.
+//
+// The tar stream can be zstd-compressed and then encrypted to age X25519
+// recipients. Both are standard formats, so a bundle can be unpacked
+// without jvs: "tar --zstd -xf bundle.tar.zst", or
+// "age -d -i key.txt bundle | zstd -d | tar x".
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/version"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Entry names in a bundle.
+const (
+	DescriptorName = "descriptor.json"
+	PayloadDir     = "payload"
+	IntegrityName  = "integrity.json"
+)
+
+// FormatVersion is the bundle format written by Write.
+const FormatVersion = 1
+
+// Options configures Write.
+type Options struct {
+	// Recipients are age X25519 public keys ("age1..."). If set, the bundle
+	// is encrypted so that any one of the matching identities can read it.
+	Recipients []string
+
+	// Compress zstd-compresses the tar stream, before any encryption.
+	Compress bool
+}
+
+// Integrity is the content of the integrity.json entry. Together with the
+// descriptor's own checksum it lets a reader check the bundle offline.
+type Integrity struct {
+	FormatVersion      int              `json:"format_version"`
+	SnapshotID         model.SnapshotID `json:"snapshot_id"`
+	DescriptorChecksum model.HashValue  `json:"descriptor_checksum"`
+	PayloadRootHash    model.HashValue  `json:"payload_root_hash"`
+	ExportedAt         time.Time        `json:"exported_at"`
+	JVSVersion         string           `json:"jvs_version"`
+	// Files maps the tar name of each payload file to the hex SHA-256 of
+	// its content as stored in the bundle.
+	Files map[string]string `json:"files"`
+}
+
+// Result describes a written bundle.
+type Result struct {
+	SnapshotID model.SnapshotID `json:"snapshot_id"`
+	Files      int              `json:"files"`
+	Bytes      int64            `json:"bytes"`
+	Compressed bool             `json:"compressed"`
+	Encrypted  bool             `json:"encrypted"`
+	Recipients int              `json:"recipients,omitempty"`
+}
+
+// ParseRecipients parses age X25519 public keys.
+func ParseRecipients(keys []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(keys))
+	for _, key := range keys {
+		r, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", key, err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// Write writes snapshot id as a bundle to w. The snapshot is verified first.
+func Write(w io.Writer, repoRoot string, id model.SnapshotID, opts Options) (*Result, error) {
+	recipients, err := ParseRecipients(opts.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	if err := snapshot.VerifySnapshot(repoRoot, id, false); err != nil {
+		return nil, fmt.Errorf("verify snapshot %s: %w", id, err)
+	}
+	desc, dir, err := snapshot.OpenPayload(repoRoot, id)
+	if err != nil {
+		return nil, err
+	}
+	descData, err := json.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal descriptor: %w", err)
+	}
+
+	cw := &countingWriter{w: w}
+	var out io.Writer = cw
+	var enc io.WriteCloser
+	if len(recipients) > 0 {
+		if enc, err = age.Encrypt(cw, recipients...); err != nil {
+			return nil, fmt.Errorf("encrypt bundle: %w", err)
+		}
+		out = enc
+	}
+	var zw *zstd.Encoder
+	if opts.Compress {
+		if zw, err = zstd.NewWriter(out); err != nil {
+			return nil, fmt.Errorf("compress bundle: %w", err)
+		}
+		out = zw
+	}
+
+	result := &Result{SnapshotID: id, Compressed: zw != nil, Encrypted: enc != nil, Recipients: len(recipients)}
+	integrity := &Integrity{
+		FormatVersion:      FormatVersion,
+		SnapshotID:         id,
+		DescriptorChecksum: desc.DescriptorChecksum,
+		PayloadRootHash:    desc.PayloadRootHash,
+		ExportedAt:         time.Now().UTC(),
+		JVSVersion:         version.String(),
+		Files:              make(map[string]string),
+	}
+	tw := tar.NewWriter(out)
+	if err := writeFile(tw, DescriptorName, descData, desc.CreatedAt); err != nil {
+		return nil, fmt.Errorf("write descriptor: %w", err)
+	}
+
+	if err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && filepath.Dir(p) == dir && (d.Name() == ".READY" || d.Name() == ".READY.gz" || d.Name() == ".READY.zst") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(PayloadDir, filepath.ToSlash(rel))
+		sum, err := writeEntry(tw, p, name)
+		if sum != "" {
+			integrity.Files[name] = sum
+			result.Files++
+		}
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("write payload: %w", err)
+	}
+
+	integrityData, err := json.MarshalIndent(integrity, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal integrity metadata: %w", err)
+	}
+	if err := writeFile(tw, IntegrityName, integrityData, integrity.ExportedAt); err != nil {
+		return nil, fmt.Errorf("write integrity metadata: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("close tar: %w", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("compress bundle: %w", err)
+		}
+	}
+	if enc != nil {
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("encrypt bundle: %w", err)
+		}
+	}
+	result.Bytes = cw.n
+	return result, nil
+}
+
+// writeFile writes a metadata entry.
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// writeEntry writes the directory, regular file or symlink at full as name.
+// Other file types are skipped. For a regular file it returns the hex
+// SHA-256 of the content written.
+func writeEntry(tw *tar.Writer, full, name string) (string, error) {
+	info, err := os.Lstat(full)
+	if err != nil {
+		return "", err
+	}
+	var target string
+	switch {
+	case info.IsDir(), info.Mode().IsRegular():
+	case info.Mode()&os.ModeSymlink != 0:
+		if target, err = os.Readlink(full); err != nil {
+			return "", err
+		}
+	default:
+		return "", nil
+	}
+
+	hdr, err := tar.FileInfoHeader(info, target)
+	if err != nil {
+		return "", err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+	hdr.Format = tar.FormatPAX
+	if err := tw.WriteHeader(hdr); err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", nil
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
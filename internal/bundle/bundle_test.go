@@ -0,0 +1,415 @@
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupSnapshot(t *testing.T) (string, *model.Descriptor) {
+	t.Helper()
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "sub", "b.txt"), []byte("b"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "share", nil)
+	require.NoError(t, err)
+	return repoPath, desc
+}
+
+// readBundle returns the entry names and file contents of a bundle.
+func readBundle(t *testing.T, r io.Reader) ([]string, map[string]string) {
+	t.Helper()
+	var names []string
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names, files
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		if hdr.Typeflag == tar.TypeReg {
+			files[hdr.Name] = string(data)
+		}
+	}
+}
+
+func TestWrite_Plain(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+
+	var buf bytes.Buffer
+	result, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, desc.SnapshotID, result.SnapshotID)
+	assert.Equal(t, 2, result.Files)
+	assert.False(t, result.Encrypted)
+	assert.Equal(t, int64(buf.Len()), result.Bytes)
+
+	names, files := readBundle(t, &buf)
+	assert.Equal(t, []string{"descriptor.json", "payload/", "payload/a.txt", "payload/sub/", "payload/sub/b.txt", "integrity.json"}, names)
+	assert.Equal(t, "secret", files["payload/a.txt"])
+
+	var got model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(files["descriptor.json"]), &got))
+	assert.Equal(t, desc.SnapshotID, got.SnapshotID)
+	assert.Equal(t, desc.PayloadRootHash, got.PayloadRootHash)
+}
+
+func TestWrite_EncryptedToRecipients(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	alice, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	bob, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	eve, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	result, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{
+		Recipients: []string{alice.Recipient().String(), bob.Recipient().String()},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Encrypted)
+	assert.Equal(t, 2, result.Recipients)
+	assert.NotContains(t, buf.String(), "secret")
+
+	// Each recipient can decrypt
+	for _, id := range []*age.X25519Identity{alice, bob} {
+		r, err := age.Decrypt(bytes.NewReader(buf.Bytes()), id)
+		require.NoError(t, err)
+		_, files := readBundle(t, r)
+		assert.Equal(t, "secret", files["payload/a.txt"])
+	}
+
+	_, err = age.Decrypt(bytes.NewReader(buf.Bytes()), eve)
+	assert.Error(t, err, "a non-recipient cannot decrypt")
+}
+
+func TestWrite_CompressedWithIntegrity(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	result, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{
+		Compress:   true,
+		Recipients: []string{identity.Recipient().String()},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Compressed)
+	assert.True(t, result.Encrypted)
+
+	// Compressed first, then encrypted
+	r, err := age.Decrypt(&buf, identity)
+	require.NoError(t, err)
+	zr, err := zstd.NewReader(r)
+	require.NoError(t, err)
+	defer zr.Close()
+	_, files := readBundle(t, zr)
+	assert.Equal(t, "secret", files["payload/a.txt"])
+
+	var integrity bundle.Integrity
+	require.NoError(t, json.Unmarshal([]byte(files[bundle.IntegrityName]), &integrity))
+	assert.Equal(t, bundle.FormatVersion, integrity.FormatVersion)
+	assert.Equal(t, desc.SnapshotID, integrity.SnapshotID)
+	assert.Equal(t, desc.DescriptorChecksum, integrity.DescriptorChecksum)
+	assert.Equal(t, desc.PayloadRootHash, integrity.PayloadRootHash)
+	sumA, sumB := sha256.Sum256([]byte("secret")), sha256.Sum256([]byte("b"))
+	assert.Equal(t, map[string]string{
+		"payload/a.txt":     hex.EncodeToString(sumA[:]),
+		"payload/sub/b.txt": hex.EncodeToString(sumB[:]),
+	}, integrity.Files)
+}
+
+func TestWrite_InvalidRecipient(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+
+	var buf bytes.Buffer
+	_, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{Recipients: []string{"ssh-ed25519 AAAA"}})
+	assert.ErrorContains(t, err, "invalid recipient")
+	assert.Zero(t, buf.Len(), "nothing is written before recipients are validated")
+}
+
+func TestWrite_UnpublishedSnapshot(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	require.NoError(t, os.Remove(filepath.Join(repo.SnapshotPath(repoPath, desc.SnapshotID), ".READY")))
+
+	_, err := bundle.Write(io.Discard, repoPath, desc.SnapshotID, bundle.Options{})
+	assert.Error(t, err)
+}
+
+// rewriteBundle copies a plain bundle, passing each entry through edit.
+// edit returns the new content, or nil to drop the entry.
+func rewriteBundle(t *testing.T, data []byte, edit func(hdr *tar.Header, content []byte) []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	tr := tar.NewReader(bytes.NewReader(data))
+	tw := tar.NewWriter(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		if content = edit(hdr, content); content == nil {
+			continue
+		}
+		hdr.Size = int64(len(content))
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return out.Bytes()
+}
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "dest")
+	require.NoError(t, err)
+	return repoPath
+}
+
+func TestImport_RoundTrip(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	for name, opts := range map[string]bundle.Options{
+		"plain":     {},
+		"zstd":      {Compress: true},
+		"encrypted": {Compress: true, Recipients: []string{identity.Recipient().String()}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := bundle.Write(&buf, repoPath, desc.SnapshotID, opts)
+			require.NoError(t, err)
+
+			dest := newRepo(t)
+			result, err := bundle.Import(&buf, dest, bundle.ImportOptions{Identities: []age.Identity{identity}})
+			require.NoError(t, err)
+			assert.Equal(t, desc.SnapshotID, result.Descriptor.SnapshotID)
+			assert.Equal(t, 2, result.Files)
+			assert.Equal(t, int64(len("secret")+len("b")), result.Bytes)
+			assert.Equal(t, opts.Compress, result.Compressed)
+			assert.Equal(t, len(opts.Recipients) > 0, result.Encrypted)
+
+			require.NoError(t, snapshot.VerifySnapshot(dest, desc.SnapshotID, true))
+			got, err := snapshot.LoadDescriptor(dest, desc.SnapshotID)
+			require.NoError(t, err)
+			assert.Equal(t, desc.DescriptorChecksum, got.DescriptorChecksum)
+			data, err := os.ReadFile(filepath.Join(repo.SnapshotPath(dest, desc.SnapshotID), "sub", "b.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "b", string(data))
+		})
+	}
+}
+
+func TestImport_EncryptedWithoutIdentity(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{Recipients: []string{identity.Recipient().String()}})
+	require.NoError(t, err)
+
+	_, err = bundle.Import(bytes.NewReader(buf.Bytes()), newRepo(t), bundle.ImportOptions{})
+	assert.ErrorContains(t, err, "identity is required")
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	_, err = bundle.Import(bytes.NewReader(buf.Bytes()), newRepo(t), bundle.ImportOptions{Identities: []age.Identity{other}})
+	assert.ErrorContains(t, err, "decrypt bundle")
+}
+
+func TestImport_AlreadyExists(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	var buf bytes.Buffer
+	_, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{})
+	require.NoError(t, err)
+
+	_, err = bundle.Import(&buf, repoPath, bundle.ImportOptions{})
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestImport_Rejected(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	var buf bytes.Buffer
+	_, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{})
+	require.NoError(t, err)
+	original := buf.Bytes()
+
+	tests := map[string]struct {
+		edit func(hdr *tar.Header, content []byte) []byte
+		want error
+	}{
+		"tampered file": {
+			edit: func(hdr *tar.Header, content []byte) []byte {
+				if hdr.Name == "payload/a.txt" {
+					return []byte("public")
+				}
+				return content
+			},
+			want: errclass.ErrPayloadHashMismatch,
+		},
+		"tampered descriptor": {
+			edit: func(hdr *tar.Header, content []byte) []byte {
+				if hdr.Name == bundle.DescriptorName {
+					return bytes.Replace(content, []byte(`"share"`), []byte(`"shared"`), 1)
+				}
+				return content
+			},
+			want: errclass.ErrDescriptorCorrupt,
+		},
+		"missing file": {
+			edit: func(hdr *tar.Header, content []byte) []byte {
+				if hdr.Name == "payload/sub/b.txt" {
+					return nil
+				}
+				return content
+			},
+			want: errclass.ErrPayloadHashMismatch,
+		},
+		"path escape": {
+			edit: func(hdr *tar.Header, content []byte) []byte {
+				if hdr.Name == "payload/a.txt" {
+					hdr.Name = "payload/../a.txt"
+				}
+				return content
+			},
+			want: errclass.ErrPathEscape,
+		},
+		"no integrity metadata": {
+			edit: func(hdr *tar.Header, content []byte) []byte {
+				if hdr.Name == bundle.IntegrityName {
+					return nil
+				}
+				return content
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dest := newRepo(t)
+			_, err := bundle.Import(bytes.NewReader(rewriteBundle(t, original, tt.edit)), dest, bundle.ImportOptions{})
+			require.Error(t, err)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			}
+
+			// Nothing is left behind
+			entries, err := os.ReadDir(filepath.Join(dest, ".jvs", "snapshots"))
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+			_, err = snapshot.LoadDescriptor(dest, desc.SnapshotID)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestImport_SymlinkEscape(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	var buf bytes.Buffer
+	_, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{})
+	require.NoError(t, err)
+	outside := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(outside, "sub"), 0755))
+
+	// Each case inserts its entries before integrity.json
+	tests := map[string][]*tar.Header{
+		"absolute target": {
+			{Name: "payload/a", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "payload/a/sub/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"relative target leaving the payload": {
+			{Name: "payload/sub/a", Typeflag: tar.TypeSymlink, Linkname: "../../outside"},
+		},
+		"write through a symlink": {
+			{Name: "payload/a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "payload/a/sub/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+			tw := tar.NewWriter(&out)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				if hdr.Name == bundle.IntegrityName {
+					for _, e := range entries {
+						require.NoError(t, tw.WriteHeader(e))
+					}
+				}
+				require.NoError(t, tw.WriteHeader(hdr))
+				_, err = io.Copy(tw, tr)
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+
+			_, err := bundle.Import(&out, newRepo(t), bundle.ImportOptions{})
+			require.Error(t, err)
+			if name == "write through a symlink" {
+				assert.ErrorContains(t, err, "not a directory")
+			} else {
+				assert.ErrorIs(t, err, errclass.ErrPathEscape)
+			}
+			entries, err := os.ReadDir(filepath.Join(outside, "sub"))
+			require.NoError(t, err)
+			assert.Empty(t, entries, "nothing is written outside the repository")
+		})
+	}
+}
+
+func TestReadDescriptor(t *testing.T) {
+	repoPath, desc := setupSnapshot(t)
+	var buf bytes.Buffer
+	_, err := bundle.Write(&buf, repoPath, desc.SnapshotID, bundle.Options{Compress: true})
+	require.NoError(t, err)
+
+	got, err := bundle.ReadDescriptor(&buf, bundle.ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, desc.SnapshotID, got.SnapshotID)
+}
+
+func TestParseIdentities(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	ids, err := bundle.ParseIdentities([]string{identity.String()})
+	require.NoError(t, err)
+	assert.Len(t, ids, 1)
+
+	_, err = bundle.ParseIdentities([]string{"AGE-SECRET-KEY-nope"})
+	assert.ErrorContains(t, err, "invalid identity")
+}
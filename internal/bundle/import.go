@@ -0,0 +1,473 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// ageHeader starts every binary age file.
+const ageHeader = "age-encryption.org/v1\n"
+
+// zstdMagic starts every zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// Identities decrypt an age-encrypted bundle. They are only needed if
+	// the bundle is encrypted.
+	Identities []age.Identity
+	// CorrelationID is recorded in the snapshot_import audit record. Empty
+	// uses the one set through audit.CorrelationEnvVar, if any.
+	CorrelationID string
+	// Author is named as the author of the audit record. Empty uses
+	// identity.Author.
+	Author string
+}
+
+// ImportResult describes an imported bundle.
+type ImportResult struct {
+	Descriptor *model.Descriptor `json:"descriptor"`
+	Files      int               `json:"files"`
+	Bytes      int64             `json:"bytes"`
+	Compressed bool              `json:"compressed"`
+	Encrypted  bool              `json:"encrypted"`
+}
+
+// ParseIdentities parses age X25519 secret keys ("AGE-SECRET-KEY-1...").
+func ParseIdentities(keys []string) ([]age.Identity, error) {
+	identities := make([]age.Identity, 0, len(keys))
+	for _, key := range keys {
+		id, err := age.ParseX25519Identity(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity: %w", err)
+		}
+		identities = append(identities, id)
+	}
+	return identities, nil
+}
+
+// stream is the tar stream of a bundle, with its layers removed.
+type stream struct {
+	tr         *tar.Reader
+	compressed bool
+	encrypted  bool
+	close      func()
+}
+
+// openStream detects and removes the age and zstd layers of a bundle.
+func openStream(r io.Reader, identities []age.Identity) (*stream, error) {
+	s := &stream{close: func() {}}
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(ageHeader)); string(head) == ageHeader {
+		if len(identities) == 0 {
+			return nil, fmt.Errorf("bundle is encrypted; an identity is required")
+		}
+		dr, err := age.Decrypt(br, identities...)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt bundle: %w", err)
+		}
+		br = bufio.NewReader(dr)
+		s.encrypted = true
+	}
+	if head, _ := br.Peek(len(zstdMagic)); bytes.Equal(head, zstdMagic) {
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompress bundle: %w", err)
+		}
+		s.tr = tar.NewReader(zr)
+		s.compressed = true
+		s.close = zr.Close
+		return s, nil
+	}
+	s.tr = tar.NewReader(br)
+	return s, nil
+}
+
+// readDescriptor reads the descriptor.json entry, which comes first.
+func readDescriptor(tr *tar.Reader) (*model.Descriptor, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	if hdr.Name != DescriptorName {
+		return nil, fmt.Errorf("not a jvs bundle: first entry is %q, want %q", hdr.Name, DescriptorName)
+	}
+	var desc model.Descriptor
+	if err := json.NewDecoder(tr).Decode(&desc); err != nil {
+		return nil, errclass.ErrDescriptorCorrupt.WithMessagef("decode descriptor: %v", err)
+	}
+	return &desc, nil
+}
+
+// ReadDescriptor returns the descriptor of the bundle read from r, without
+// checking it or reading the payload.
+func ReadDescriptor(r io.Reader, opts ImportOptions) (*model.Descriptor, error) {
+	s, err := openStream(r, opts.Identities)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+	return readDescriptor(s.tr)
+}
+
+// Import reads a bundle written by Write from r and adds its snapshot to the
+// repository at repoRoot, under the snapshot ID it was exported with.
+// Encrypted and compressed bundles are detected from their content.
+//
+// The descriptor checksum, the SHA-256 of every file listed in
+// integrity.json and, for uncompressed snapshots, the payload root hash are
+// checked before the snapshot is published; any mismatch leaves the
+// repository unchanged. The snapshot's parent is recorded as exported, even
+// if it does not exist in this repository.
+func Import(r io.Reader, repoRoot string, opts ImportOptions) (*ImportResult, error) {
+	s, err := openStream(r, opts.Identities)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	desc, err := readDescriptor(s.tr)
+	if err != nil {
+		return nil, err
+	}
+	id := desc.SnapshotID
+	if id == "" || !fs.ValidPath(string(id)) || strings.Contains(string(id), "/") {
+		return nil, errclass.ErrDescriptorCorrupt.WithMessagef("invalid snapshot ID %q", id)
+	}
+	checksum, err := integrity.ComputeDescriptorChecksum(desc)
+	if err != nil {
+		return nil, fmt.Errorf("compute checksum: %w", err)
+	}
+	if checksum != desc.DescriptorChecksum {
+		return nil, errclass.ErrDescriptorCorrupt.WithMessage("descriptor checksum mismatch")
+	}
+	if err := integrity.CheckHashAlgorithm(desc.HashAlgorithm); err != nil {
+		return nil, err
+	}
+
+	lock, err := repolock.NewManager(repoRoot).Acquire(model.LockShared, "import")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	store, err := descstore.Open(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	snapshotDir := repo.SnapshotPath(repoRoot, id)
+	exists, err := store.Exists(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(snapshotDir); exists || err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", id)
+	}
+
+	// Named like a creator's tmp dir, so 'jvs doctor --repair-runtime'
+	// cleans it up after a crash
+	tmpDir := snapshotDir + ".tmp"
+	if err := os.Mkdir(tmpDir, 0700); err != nil {
+		return nil, fmt.Errorf("create snapshot tmp dir: %w", err)
+	}
+	published := false
+	defer func() {
+		if !published {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	result := &ImportResult{Descriptor: desc, Compressed: s.compressed, Encrypted: s.encrypted}
+	sums, meta, err := extractPayload(s.tr, tmpDir, result)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIntegrity(meta, desc, sums); err != nil {
+		return nil, err
+	}
+
+	marker, err := json.Marshal(&model.ReadyMarker{
+		SnapshotID:         id,
+		CompletedAt:        time.Now().UTC(),
+		PayloadHash:        desc.PayloadRootHash,
+		Engine:             desc.Engine,
+		DescriptorChecksum: desc.DescriptorChecksum,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal ready marker: %w", err)
+	}
+	if err := fsutil.AtomicWrite(filepath.Join(tmpDir, ".READY"), marker, 0644); err != nil {
+		return nil, fmt.Errorf("write ready marker: %w", err)
+	}
+	// Directory modes are applied last, as they may clear write bits
+	if err := applyDirMetadata(meta.dirs); err != nil {
+		return nil, err
+	}
+
+	// A compressed or encrypted payload is stored as it is and its root
+	// hash covers the original files; the per-file sums above cover it
+	// instead, and it is imported without a manifest
+	var manifest *model.Manifest
+	if desc.Compression == nil && desc.Encryption == nil {
+		hasher, err := integrity.HashPayload(tmpDir, desc.HashAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("compute payload hash: %w", err)
+		}
+		if hasher.Sum() != desc.PayloadRootHash {
+			return nil, errclass.ErrPayloadHashMismatch.WithMessage("payload hash mismatch")
+		}
+		manifest = &model.Manifest{SnapshotID: id, Entries: hasher.Entries()}
+	}
+
+	if err := fsutil.FsyncTree(tmpDir); err != nil {
+		return nil, fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := fsutil.RenameAndSync(tmpDir, snapshotDir); err != nil {
+		return nil, fmt.Errorf("atomic rename snapshot: %w", err)
+	}
+	published = true
+	if manifest != nil {
+		if err := snapshot.WriteManifest(repoRoot, manifest); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to write manifest: %v\n", err)
+		}
+	}
+	if err := store.Put(desc); err != nil {
+		return nil, fmt.Errorf("write descriptor: %w", err)
+	}
+	if err := index.Update(repoRoot, desc); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update index: %v\n", err)
+	}
+
+	auditPath := filepath.Join(repoRoot, ".jvs", "audit", "audit.jsonl")
+	auditData := map[string]any{
+		"checksum":   string(desc.DescriptorChecksum),
+		"files":      result.Files,
+		"compressed": result.Compressed,
+		"encrypted":  result.Encrypted,
+	}
+	if desc.ParentID != nil {
+		auditData["parent_id"] = string(*desc.ParentID)
+	}
+	appender := audit.NewFileAppender(auditPath)
+	appender.SetCorrelationID(opts.CorrelationID)
+	appender.SetAuthor(opts.Author)
+	if err := appender.Append(model.EventTypeSnapshotImport, desc.WorktreeName, id, auditData); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write audit log: %v\n", err)
+	}
+	return result, nil
+}
+
+// bundleMeta is what extractPayload collects besides file content.
+type bundleMeta struct {
+	integrity *Integrity
+	dirs      []dirEntry
+}
+
+// dirEntry is the metadata of an extracted directory.
+type dirEntry struct {
+	path    string
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// extractPayload extracts the payload entries into dir and reads the
+// integrity.json entry that ends the bundle. It returns the SHA-256 of each
+// extracted file by tar name.
+func extractPayload(tr *tar.Reader, dir string, result *ImportResult) (map[string]string, *bundleMeta, error) {
+	sums := make(map[string]string)
+	meta := &bundleMeta{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("bundle has no %s", IntegrityName)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read bundle: %w", err)
+		}
+		if hdr.Name == IntegrityName {
+			meta.integrity = &Integrity{}
+			if err := json.NewDecoder(tr).Decode(meta.integrity); err != nil {
+				return nil, nil, fmt.Errorf("decode %s: %w", IntegrityName, err)
+			}
+			if _, err := tr.Next(); err != io.EOF {
+				return nil, nil, fmt.Errorf("unexpected entry after %s", IntegrityName)
+			}
+			return sums, meta, nil
+		}
+
+		dst, err := entryPath(dir, hdr.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Never write through a symlink extracted earlier
+		if err := checkParents(dir, dst); err != nil {
+			return nil, nil, fmt.Errorf("entry %s: %w", hdr.Name, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if dst != dir {
+				if err := os.Mkdir(dst, 0700); err != nil {
+					return nil, nil, fmt.Errorf("extract %s: %w", hdr.Name, err)
+				}
+			}
+			meta.dirs = append(meta.dirs, dirEntry{path: dst, mode: hdr.FileInfo().Mode().Perm(), modTime: hdr.ModTime})
+		case tar.TypeReg:
+			sum, n, err := extractFile(tr, dst, hdr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+			sums[hdr.Name] = sum
+			result.Files++
+			result.Bytes += n
+		case tar.TypeSymlink:
+			if err := checkLinkTarget(hdr.Name, hdr.Linkname); err != nil {
+				return nil, nil, err
+			}
+			if err := os.Symlink(hdr.Linkname, dst); err != nil {
+				return nil, nil, fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+		default:
+			return nil, nil, fmt.Errorf("entry %s: unsupported type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+}
+
+// entryPath maps the tar name of a payload entry to its path under dir,
+// refusing names outside the payload.
+func entryPath(dir, name string) (string, error) {
+	rel, ok := strings.CutPrefix(name, PayloadDir+"/")
+	if !ok {
+		return "", fmt.Errorf("unexpected bundle entry %q", name)
+	}
+	rel = strings.TrimSuffix(rel, "/")
+	if rel == "" {
+		return dir, nil
+	}
+	if !fs.ValidPath(rel) {
+		return "", errclass.ErrPathEscape.WithMessagef("bundle entry %q", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), nil
+}
+
+// checkParents checks that every directory between dir and dst is a real
+// directory rather than a symlink, so that extraction stays under dir.
+func checkParents(dir, dst string) error {
+	if dst == dir {
+		return nil
+	}
+	rel, err := filepath.Rel(dir, filepath.Dir(dst))
+	if err != nil || rel == "." {
+		return err
+	}
+	parent := dir
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		parent = filepath.Join(parent, elem)
+		if info, err := os.Lstat(parent); err != nil || !info.IsDir() {
+			return fmt.Errorf("parent %s is not a directory", elem)
+		}
+	}
+	return nil
+}
+
+// checkLinkTarget refuses symlinks whose target is absolute or leaves the
+// payload, resolved from the directory of the link.
+func checkLinkTarget(name, target string) error {
+	rel := strings.TrimSuffix(strings.TrimPrefix(name, PayloadDir+"/"), "/")
+	resolved := path.Join(path.Dir(rel), filepath.ToSlash(target))
+	if target == "" || path.IsAbs(filepath.ToSlash(target)) || filepath.IsAbs(target) || !fs.ValidPath(resolved) {
+		return errclass.ErrPathEscape.WithMessagef("bundle entry %q links to %q outside the payload", name, target)
+	}
+	return nil
+}
+
+// extractFile writes the content of the current entry to a new file at dst
+// and returns its hex SHA-256 and size.
+func extractFile(r io.Reader, dst string, hdr *tar.Header) (string, int64, error) {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Chmod(dst, hdr.FileInfo().Mode().Perm()); err != nil {
+		return "", 0, err
+	}
+	if err := os.Chtimes(dst, hdr.ModTime, hdr.ModTime); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// applyDirMetadata sets the mode and modification time of the extracted
+// directories, deepest first.
+func applyDirMetadata(dirs []dirEntry) error {
+	for _, d := range slices.Backward(dirs) {
+		if err := os.Chmod(d.path, d.mode); err != nil {
+			return fmt.Errorf("chmod %s: %w", d.path, err)
+		}
+		if err := os.Chtimes(d.path, d.modTime, d.modTime); err != nil {
+			return fmt.Errorf("chtimes %s: %w", d.path, err)
+		}
+	}
+	return nil
+}
+
+// checkIntegrity compares the extracted files with integrity.json.
+func checkIntegrity(meta *bundleMeta, desc *model.Descriptor, sums map[string]string) error {
+	in := meta.integrity
+	if in.FormatVersion > FormatVersion {
+		return errclass.ErrFormatUnsupported.WithMessagef("bundle format %d is newer than supported format %d", in.FormatVersion, FormatVersion)
+	}
+	if in.SnapshotID != desc.SnapshotID || in.DescriptorChecksum != desc.DescriptorChecksum {
+		return errclass.ErrDescriptorCorrupt.WithMessagef("%s does not match the descriptor", IntegrityName)
+	}
+	for name, sum := range sums {
+		want, ok := in.Files[name]
+		if !ok {
+			return errclass.ErrPayloadHashMismatch.WithMessagef("%s is not listed in %s", name, IntegrityName)
+		}
+		if sum != want {
+			return errclass.ErrPayloadHashMismatch.WithMessagef("%s does not match %s", name, IntegrityName)
+		}
+	}
+	for name := range in.Files {
+		if _, ok := sums[name]; !ok {
+			return errclass.ErrPayloadHashMismatch.WithMessagef("%s is missing from the bundle", name)
+		}
+	}
+	return nil
+}
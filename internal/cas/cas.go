@@ -0,0 +1,202 @@
+// Package cas implements the repository content store.
+//
+// With content_store enabled, each regular file of a new snapshot is replaced
+// by a hard link to a blob in .jvs/objects/<hh>/<sha256>-<mode>, so identical
+// files across snapshots are stored once. Hard links share permissions, so
+// the mode is part of the blob name; they also share the modification time
+// of the first snapshot that stored the content. Snapshot directories remain
+// complete trees, so everything that reads them is unchanged. Blobs that no
+// snapshot links to any more are removed by Prune, which GC runs.
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Store is the content store of a repository.
+type Store struct {
+	dir string
+}
+
+// NewStore returns the content store of the repository at repoRoot.
+func NewStore(repoRoot string) *Store {
+	return &Store{dir: filepath.Join(repoRoot, ".jvs", "objects")}
+}
+
+func (s *Store) blobPath(sum string, mode fs.FileMode) string {
+	return filepath.Join(s.dir, sum[:2], fmt.Sprintf("%s-%04o", sum, mode.Perm()))
+}
+
+// Ingest replaces every regular file below dir with a hard link to its
+// blob, adding blobs for content the store does not have yet. The .READY
+// marker is left alone. dir must be on the same filesystem as the store.
+func (s *Store) Ingest(dir string) (*model.ContentStoreInfo, error) {
+	info := &model.ContentStoreInfo{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || d.Name() == ".READY" {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", path, err)
+		}
+
+		blob := s.blobPath(sum, fi.Mode())
+		reused, err := s.link(path, blob)
+		if err != nil {
+			return fmt.Errorf("store %s: %w", path, err)
+		}
+		info.Blobs++
+		if reused {
+			info.Reused++
+			info.ReusedBytes += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// link makes path a hard link to blob, first storing path as the blob if
+// there is none. It reports whether an existing blob was reused.
+func (s *Store) link(path, blob string) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return false, err
+	}
+	err := os.Link(path, blob)
+	if err == nil {
+		return false, nil
+	}
+	if !os.IsExist(err) {
+		return false, err
+	}
+
+	// Replace path with a link to the existing blob, unless it already is one
+	pathInfo, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	blobInfo, err := os.Lstat(blob)
+	if err != nil {
+		return false, err
+	}
+	if os.SameFile(pathInfo, blobInfo) {
+		return true, nil
+	}
+	tmp := path + ".cas-tmp"
+	if err := os.Link(blob, tmp); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, nil
+}
+
+// Relink makes path, a file rewritten by 'jvs verify --repair', a hard link
+// to its blob again. A blob whose content no longer matches its name is
+// replaced by path first, so that new snapshots do not link to it.
+func (s *Store) Relink(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", path, err)
+	}
+	blob := s.blobPath(sum, fi.Mode())
+	if blobSum, err := hashFile(blob); err == nil && blobSum != sum {
+		if err := os.Remove(blob); err != nil {
+			return fmt.Errorf("remove corrupt blob: %w", err)
+		}
+	}
+	_, err = s.link(path, blob)
+	return err
+}
+
+// Prune removes blobs that no snapshot links to and returns how many were
+// removed and their total size. It must not run concurrently with Ingest;
+// GC holds the repository lock exclusive.
+func (s *Store) Prune() (int, int64, error) {
+	var removed int
+	var bytes int64
+	err := s.walkBlobs(func(path string, fi os.FileInfo) error {
+		if n, ok := linkCount(fi); !ok || n > 1 {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		bytes += fi.Size()
+		return nil
+	})
+	return removed, bytes, err
+}
+
+// Stats returns the number of blobs and their total size.
+func (s *Store) Stats() (int, int64, error) {
+	var blobs int
+	var bytes int64
+	err := s.walkBlobs(func(_ string, fi os.FileInfo) error {
+		blobs++
+		bytes += fi.Size()
+		return nil
+	})
+	return blobs, bytes, err
+}
+
+func (s *Store) walkBlobs(fn func(path string, fi os.FileInfo) error) error {
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, fi)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("walk content store: %w", err)
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
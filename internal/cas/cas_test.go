@@ -0,0 +1,130 @@
+package cas_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/cas"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func sameFile(t *testing.T, a, b string) bool {
+	t.Helper()
+	ai, err := os.Stat(a)
+	require.NoError(t, err)
+	bi, err := os.Stat(b)
+	require.NoError(t, err)
+	return os.SameFile(ai, bi)
+}
+
+func TestStore_IngestDeduplicates(t *testing.T) {
+	repoPath := t.TempDir()
+	store := cas.NewStore(repoPath)
+	first := filepath.Join(repoPath, "first")
+	second := filepath.Join(repoPath, "second")
+	writeTree(t, first, map[string]string{"a.txt": "shared", "b.txt": "old", ".READY": "{}"})
+	writeTree(t, second, map[string]string{"a.txt": "shared", "sub/c.txt": "shared", "b.txt": "new"})
+
+	info, err := store.Ingest(first)
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Blobs, ".READY is not stored")
+	assert.Zero(t, info.Reused)
+
+	info, err = store.Ingest(second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, info.Blobs)
+	assert.Equal(t, 2, info.Reused)
+	assert.Equal(t, int64(len("shared")*2), info.ReusedBytes)
+
+	assert.True(t, sameFile(t, filepath.Join(first, "a.txt"), filepath.Join(second, "a.txt")))
+	assert.True(t, sameFile(t, filepath.Join(first, "a.txt"), filepath.Join(second, "sub", "c.txt")))
+	assert.False(t, sameFile(t, filepath.Join(first, "b.txt"), filepath.Join(second, "b.txt")))
+	data, err := os.ReadFile(filepath.Join(second, "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	blobs, bytes, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, blobs)
+	assert.Equal(t, int64(len("shared")+len("old")+len("new")), bytes)
+
+	// Ingesting files that are already blob links changes nothing
+	info, err = store.Ingest(second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, info.Reused)
+	entries, err := os.ReadDir(second)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "no temporary links are left behind")
+}
+
+func TestStore_IngestKeepsModes(t *testing.T) {
+	repoPath := t.TempDir()
+	store := cas.NewStore(repoPath)
+	dir := filepath.Join(repoPath, "snap")
+	writeTree(t, dir, map[string]string{"run.sh": "echo", "copy.sh": "echo"})
+	require.NoError(t, os.Chmod(filepath.Join(dir, "run.sh"), 0755))
+
+	info, err := store.Ingest(dir)
+	require.NoError(t, err)
+	assert.Zero(t, info.Reused, "hard links share modes, so each mode has its own blob")
+
+	fi, err := os.Stat(filepath.Join(dir, "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	fi, err = os.Stat(filepath.Join(dir, "copy.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+}
+
+func TestStore_Prune(t *testing.T) {
+	repoPath := t.TempDir()
+	store := cas.NewStore(repoPath)
+	first := filepath.Join(repoPath, "first")
+	second := filepath.Join(repoPath, "second")
+	writeTree(t, first, map[string]string{"a.txt": "shared", "b.txt": "only-first"})
+	writeTree(t, second, map[string]string{"a.txt": "shared"})
+	_, err := store.Ingest(first)
+	require.NoError(t, err)
+	_, err = store.Ingest(second)
+	require.NoError(t, err)
+
+	removed, _, err := store.Prune()
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	require.NoError(t, os.RemoveAll(first))
+	removed, bytes, err := store.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, int64(len("only-first")), bytes)
+
+	blobs, _, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, blobs)
+	data, err := os.ReadFile(filepath.Join(second, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "shared", string(data))
+}
+
+func TestStore_EmptyStore(t *testing.T) {
+	store := cas.NewStore(t.TempDir())
+	blobs, bytes, err := store.Stats()
+	require.NoError(t, err)
+	assert.Zero(t, blobs)
+	assert.Zero(t, bytes)
+	removed, _, err := store.Prune()
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+var annotateAppend bool
+
+var annotateCmd = &cobra.Command{
+	Use:   "annotate <snapshot> <note>",
+	Short: "Replace or append to the note of a snapshot",
+	Long: `Replace or append to the note of a snapshot, given by ID, ID prefix or tag.
+
+The descriptor is rewritten with a recomputed checksum. The old note is kept
+in the snapshot's metadata history (see 'jvs show --metadata-history') and the
+change is audited. With --append the note is added after the existing one,
+separated by "; ".
+
+Examples:
+  jvs annotate v1.0 "known good: passed the full eval suite"
+  jvs annotate 1771589366482-abc12345 "flaky on arm64" --append`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+		snapshotID := resolveSnapshotIDOrExit(r.Root, args[0])
+
+		desc, err := snapshot.Annotate(context.Background(), r.Root, snapshotID, "", args[1], annotateAppend)
+		if err != nil {
+			fmtErr("annotate: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(desc)
+			return
+		}
+		fmt.Printf("Snapshot %s note: %s\n", color.SnapshotID(snapshotID.String()), desc.Note)
+	},
+}
+
+func init() {
+	annotateCmd.Flags().BoolVar(&annotateAppend, "append", false, "append to the existing note instead of replacing it")
+	rootCmd.AddCommand(annotateCmd)
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestAnnotateCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "annotate", "v1", "passed evals", "--append")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "first; passed evals")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "annotate", "v1", "rewritten")
+	require.NoError(t, err)
+	var desc model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &desc))
+	assert.Equal(t, "rewritten", desc.Note)
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	auditCorrelation string
+	auditEventType   string
+	auditWorktree    string
+	auditLimit       int
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log",
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List audit records",
+	Long: `List audit records, oldest first.
+
+Every record produced by one top-level operation carries the same
+correlation ID, so the steps of compound operations can be listed together.
+Set JVS_CORRELATION_ID to tag the records of several CLI invocations with
+an ID of your own.
+
+Examples:
+  jvs audit list
+  jvs audit list --correlation 0f8c2e4a-...
+  jvs audit list --type snapshot_create --worktree main --limit 20`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		records, err := audit.Read(audit.LogPath(r.Root), audit.Filter{
+			CorrelationID: auditCorrelation,
+			EventType:     model.AuditEventType(auditEventType),
+			WorktreeName:  auditWorktree,
+		})
+		if err != nil {
+			fmtErr("read audit log: %v", err)
+			os.Exit(1)
+		}
+		if auditLimit > 0 && len(records) > auditLimit {
+			records = records[len(records)-auditLimit:]
+		}
+
+		if jsonOutput {
+			if records == nil {
+				records = []*model.AuditRecord{}
+			}
+			outputJSON(records)
+			return
+		}
+
+		if len(records) == 0 {
+			fmt.Println(color.Dim("No audit records found."))
+			return
+		}
+		for _, rec := range records {
+			snap := "-"
+			if rec.SnapshotID != "" {
+				snap = color.SnapshotID(rec.SnapshotID.ShortID())
+			}
+			wt := rec.WorktreeName
+			if wt == "" {
+				wt = "-"
+			}
+			corr := rec.CorrelationID
+			if corr == "" {
+				corr = "-"
+			}
+			fmt.Printf("%s  %-20s %-12s %-10s %s\n",
+				displayTime(rec.Timestamp), rec.EventType, wt, snap, color.Dim(corr))
+		}
+	},
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the audit log hash chain",
+	Long: `Verify the audit log hash chain.
+
+Each record embeds the hash of the record before it, and the log's last
+record and record count are kept in .jvs/audit/audit.jsonl.head. Verify
+recomputes every record's hash and reports records that were edited,
+removed, inserted or reordered, and records removed from the end of the
+log. Exits 1 with E_AUDIT_CHAIN_BROKEN if any are found.
+
+Logs last written by older versions have no head until the next record is
+appended; until then, records removed from their end cannot be detected.
+
+Examples:
+  jvs audit verify
+  jvs audit verify --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		result, err := audit.Verify(audit.LogPath(r.Root))
+		if err != nil {
+			fmtErr("verify audit log: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(result)
+		} else if result.OK() {
+			fmt.Printf("%s audit log: %d records, hash chain intact\n", color.Success("OK"), result.Records)
+			if !result.Anchored {
+				fmt.Println(color.Dim("No head record yet; truncation is detected once a record is appended."))
+			}
+		} else {
+			for _, p := range result.Problems {
+				if p.Line > 0 {
+					fmt.Printf("line %d: %s: %s\n", p.Line, p.Kind, p.Message)
+				} else {
+					fmt.Printf("%s: %s\n", p.Kind, p.Message)
+				}
+			}
+		}
+		if !result.OK() {
+			fmtErr("%s: audit log has %d problems", errclass.ErrAuditChainBroken.Code, len(result.Problems))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	auditListCmd.Flags().StringVar(&auditCorrelation, "correlation", "", "only records with this correlation ID")
+	auditListCmd.Flags().StringVar(&auditEventType, "type", "", "only records of this event type")
+	auditListCmd.Flags().StringVar(&auditWorktree, "worktree", "", "only records for this worktree")
+	auditListCmd.Flags().IntVar(&auditLimit, "limit", 0, "show only the newest N records")
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestAuditListCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	t.Setenv(audit.CorrelationEnvVar, "run-1")
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	t.Setenv(audit.CorrelationEnvVar, "run-2")
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "second")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "audit", "list", "--correlation", "run-1")
+	require.NoError(t, err)
+	var records []model.AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(stdout), &records))
+	require.Len(t, records, 1)
+	assert.Equal(t, model.EventTypeSnapshotCreate, records[0].EventType)
+	assert.Equal(t, "run-1", records[0].CorrelationID)
+
+	stdout, err = executeCommand(createTestRootCmd(), "audit", "list", "--type", "snapshot_create", "--limit", "1")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "run-2")
+	assert.NotContains(t, stdout, "run-1")
+
+	stdout, err = executeCommand(createTestRootCmd(), "audit", "list", "--correlation", "nope")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "No audit records found")
+}
+
+func TestAuditVerifyCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "audit", "verify")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "hash chain intact")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "audit", "verify")
+	require.NoError(t, err)
+	var result audit.VerifyResult
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.True(t, result.OK())
+	assert.True(t, result.Anchored)
+	assert.Positive(t, result.Records)
+}
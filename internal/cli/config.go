@@ -19,6 +19,7 @@ Configuration options:
   default_tags      - Tags automatically added to each snapshot (list)
   output_format     - Default output format (text, json)
   progress_enabled  - Enable progress bars (true, false)
+  snapshot_id_scheme - Form of new snapshot IDs (timestamp, worktree)
 
 Available commands:
   show              - Show current configuration
@@ -74,6 +75,12 @@ var configShowCmd = &cobra.Command{
 		} else {
 			fmt.Println("progress_enabled: (auto-detect)")
 		}
+
+		if cfg.SnapshotIDScheme != "" {
+			fmt.Printf("snapshot_id_scheme: %s\n", cfg.SnapshotIDScheme)
+		} else {
+			fmt.Println("snapshot_id_scheme: (not set, timestamp)")
+		}
 	},
 }
 
@@ -92,7 +99,8 @@ Available keys:
   default_engine    - Default snapshot engine (juicefs-clone, reflink-copy, copy, auto)
   default_tags      - Tags automatically added to each snapshot (YAML list)
   output_format     - Default output format (text, json)
-  progress_enabled  - Enable progress bars (true, false)`,
+  progress_enabled  - Enable progress bars (true, false)
+  snapshot_id_scheme - Form of new snapshot IDs (timestamp, worktree)`,
 	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
@@ -133,7 +141,8 @@ Available keys:
   default_engine    - Default snapshot engine
   default_tags      - Default tags (YAML list)
   output_format     - Default output format
-  progress_enabled  - Progress bar setting`,
+  progress_enabled  - Progress bar setting
+  snapshot_id_scheme - Form of new snapshot IDs`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
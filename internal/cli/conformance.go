@@ -6,13 +6,22 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/conformance"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/progress"
 )
 
 var (
 	conformanceProfile string
 	conformanceVerbose bool
+	fuzzOps            int
+	fuzzSeed           int64
+	fuzzKeep           bool
+	examplesKeep       bool
 )
 
 var conformanceCmd = &cobra.Command{
@@ -113,6 +122,132 @@ Shows test IDs, descriptions, and specification references.`,
 	},
 }
 
+var conformanceFuzzCmd = &cobra.Command{
+	Use:   "fuzz",
+	Short: "Run randomized failure-injection checks",
+	Long: `Run a randomized sequence of snapshot, restore, fork, gc and remove
+operations against a temporary repository and check invariants after
+each step:
+
+  - GC never deletes a protected snapshot (worktree heads and ancestors)
+  - restored and forked payloads match their snapshot's payload hash
+  - every surviving snapshot passes strong verification
+
+Unlike 'conformance run', this does not need the JVS source tree, so
+downstream consumers can validate the jvs binary they ship. The same
+seed replays the same operation sequence.
+
+Examples:
+  jvs conformance fuzz --ops 5000 --seed 42
+  jvs conformance fuzz --ops 500 --keep --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		seed := fuzzSeed
+		if !cmd.Flags().Changed("seed") {
+			seed = time.Now().UnixNano()
+		}
+
+		if !jsonOutput {
+			fmt.Printf("Running %d operations (seed %d)...\n", fuzzOps, seed)
+		}
+
+		opts := conformance.FuzzOptions{Ops: fuzzOps, Seed: seed, Keep: fuzzKeep}
+		term := progress.NewTerminal("fuzz", fuzzOps, progressEnabled())
+		cb := term.Callback()
+		opts.Progress = func(done, total int) {
+			cb("fuzz", done, total, "")
+		}
+
+		report, err := conformance.Fuzz(opts)
+		term.Done("")
+		if err != nil {
+			fmtErr("fuzz: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(report)
+		} else {
+			fmt.Printf("Executed %d/%d operations\n", report.Executed, report.Ops)
+			for _, op := range []string{conformance.OpSnapshot, conformance.OpRestore, conformance.OpRestoreLatest,
+				conformance.OpFork, conformance.OpGC, conformance.OpRemove} {
+				fmt.Printf("  %-15s %d\n", op, report.OpCounts[op])
+			}
+			fmt.Printf("Surviving snapshots: %d, worktrees: %d\n", report.SurvivingSnapshots, report.Worktrees)
+			if report.RepoPath != "" {
+				fmt.Printf("Repository kept at: %s\n", color.Dim(report.RepoPath))
+			}
+			for _, v := range report.Violations {
+				fmt.Printf("%s op %d: %s: %s\n", color.Error("VIOLATION"), v.Op, v.Kind, v.Detail)
+			}
+		}
+
+		if !report.Passed() {
+			if !jsonOutput {
+				fmt.Printf("Reproduce with: jvs conformance fuzz --ops %d --seed %d\n", report.Ops, report.Seed)
+			}
+			os.Exit(1)
+		}
+		if !jsonOutput {
+			fmt.Println(color.Success("Fuzz checks passed."))
+		}
+	},
+}
+
+var conformanceExamplesCmd = &cobra.Command{
+	Use:   "examples",
+	Short: "Run the documented usage pattern end to end",
+	Long: `Run the sandbox-manager usage pattern documented for the library
+(OpenOrInit, RestoreLatest, mutate, Snapshot, GC) end to end against a
+temporary repository and check every documented guarantee:
+
+  - the payload path is stable across pod lifecycles and restores
+  - RestoreLatest is a no-op without snapshots and restores the latest one
+  - SkipIfUnchanged returns HEAD instead of creating a snapshot
+  - restoring an older snapshot detaches the worktree and blocks Snapshot
+    until RestoreLatest
+  - GC keeps the worktree's history
+
+Exits 1 on the first failed check, so CI catches docs and code drifting
+apart. Like 'conformance fuzz', it does not need the JVS source tree.
+
+Examples:
+  jvs conformance examples
+  jvs conformance examples --keep --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		report, err := conformance.RunExamples(conformance.ExamplesOptions{Keep: examplesKeep})
+		if err != nil {
+			fmtErr("examples: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(report)
+		} else {
+			for _, c := range report.Checks {
+				status := color.Success("ok  ")
+				if !c.Passed {
+					status = color.Error("FAIL")
+				}
+				fmt.Printf("%s  %-14s %s\n", status, c.Step, c.Guarantee)
+				if c.Detail != "" {
+					fmt.Printf("      %s\n", color.Dim(c.Detail))
+				}
+			}
+			if report.RepoPath != "" {
+				fmt.Printf("Repository kept at: %s\n", color.Dim(report.RepoPath))
+			}
+		}
+
+		if !report.Passed() {
+			os.Exit(1)
+		}
+		if !jsonOutput {
+			fmt.Println(color.Success("Documented examples hold."))
+		}
+	},
+}
+
 func findRepoRoot() (string, error) {
 	// Start from current directory and walk up looking for go.mod
 	dir, err := os.Getwd()
@@ -138,6 +273,12 @@ func init() {
 	conformanceRunCmd.Flags().StringVarP(&conformanceProfile, "profile", "p", "dev", "test profile (dev, full, ci)")
 	conformanceRunCmd.Flags().BoolVarP(&conformanceVerbose, "verbose", "v", false, "verbose output")
 	conformanceCmd.AddCommand(conformanceRunCmd)
+	conformanceFuzzCmd.Flags().IntVar(&fuzzOps, "ops", 1000, "number of operations to execute")
+	conformanceFuzzCmd.Flags().Int64Var(&fuzzSeed, "seed", 0, "random seed (default: time-based)")
+	conformanceFuzzCmd.Flags().BoolVar(&fuzzKeep, "keep", false, "keep the temporary repository for inspection")
 	conformanceCmd.AddCommand(conformanceListCmd)
+	conformanceCmd.AddCommand(conformanceFuzzCmd)
+	conformanceExamplesCmd.Flags().BoolVar(&examplesKeep, "keep", false, "keep the temporary repository for inspection")
+	conformanceCmd.AddCommand(conformanceExamplesCmd)
 	rootCmd.AddCommand(conformanceCmd)
 }
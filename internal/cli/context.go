@@ -1,11 +1,13 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/errclass"
 )
 
 // requireRepo discovers the repo from CWD and returns it, or exits with error.
@@ -16,6 +18,10 @@ func requireRepo() *repo.Repo {
 		os.Exit(1)
 	}
 	r, err := repo.Discover(cwd)
+	if errors.Is(err, errclass.ErrFormatUnsupported) {
+		fmtErr("%v", err)
+		os.Exit(1)
+	}
 	if err != nil {
 		// Enhanced error message with suggestion
 		fmt.Fprintln(os.Stderr, formatNotInRepositoryError())
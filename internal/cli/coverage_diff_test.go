@@ -49,6 +49,23 @@ func TestResolveSnapshotWithTag(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotEmpty(t, stdout)
 	})
+
+	t.Run("Diff patch with tags", func(t *testing.T) {
+		cmd6 := createTestRootCmd()
+		stdout, err := executeCommand(cmd6, "diff", "--patch", "first-tag", "second-tag")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "--- a/file1.txt")
+		assert.Contains(t, stdout, "-v1")
+		assert.Contains(t, stdout, "+v2")
+	})
+
+	t.Run("Diff JSON with patches", func(t *testing.T) {
+		cmd7 := createTestRootCmd()
+		stdout, err := executeCommand(cmd7, "diff", "--json", "--patch", "first-tag", "second-tag")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, `"patch": "diff --git a/file1.txt b/file1.txt`)
+		assert.Contains(t, stdout, `"size_delta": 0`)
+	})
 }
 
 // TestResolveSnapshotWithID tests diff with full snapshot ID resolution.
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/doctor"
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+var descriptorsMigrateTo string
+
+var descriptorsCmd = &cobra.Command{
+	Use:   "descriptors",
+	Short: "Manage the snapshot descriptor store",
+	Long: `Manage the snapshot descriptor store.
+
+Descriptors are stored as JSON files in .jvs/descriptors/ by default (fs).
+Large repositories can keep them in a SQLite database instead (sqlite), which
+makes listing and filtering snapshots a single query.`,
+}
+
+var descriptorsBackendCmd = &cobra.Command{
+	Use:   "backend",
+	Short: "Show the descriptor store backend",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		backend, err := descstore.CurrentBackend(r.Root)
+		if err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(map[string]string{"backend": string(backend)})
+			return
+		}
+		fmt.Println(backend)
+	},
+}
+
+var descriptorsMigrateCmd = &cobra.Command{
+	Use:   "migrate --to <fs|sqlite>",
+	Short: "Convert descriptors to another store backend",
+	Long: `Convert descriptors to another store backend.
+
+Copies every descriptor into the target backend, verifies the copy, then
+switches the repository to it. The old store is left in place; rerun the
+migration if it is interrupted. Do not run other JVS commands on the
+repository while migrating.
+
+Examples:
+  jvs descriptors migrate --to sqlite
+  jvs descriptors migrate --to fs`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		if descriptorsMigrateTo == "" {
+			fmtErr("--to is required")
+			os.Exit(1)
+		}
+		target, err := descstore.ParseBackend(descriptorsMigrateTo)
+		if err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+
+		count, err := descstore.Migrate(r.Root, target)
+		if err != nil {
+			fmtErr("migrate descriptors: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(map[string]any{"backend": string(target), "migrated": count})
+			return
+		}
+		fmt.Printf("Migrated %d descriptors to the %s store.\n", count, color.Highlight(string(target)))
+	},
+}
+
+var descriptorsRelativizeCmd = &cobra.Command{
+	Use:   "relativize-paths",
+	Short: "Rewrite absolute paths recorded in descriptors",
+	Long: `Rewrite absolute paths recorded in descriptors.
+
+Older versions could record partial and dereferenced snapshot paths as
+absolute paths, which break when the repository's mountpoint changes. This
+rewrites them relative to the snapshot's worktree, under the current or any
+former mountpoint, and recomputes the descriptor checksums. Paths outside the
+worktree are left unchanged and reported. Find affected snapshots with
+'jvs doctor --check-paths'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		results, err := doctor.NewDoctor(r.Root).Repair([]string{"relativize_paths"})
+		if err != nil {
+			fmtErr("relativize paths: %v", err)
+			os.Exit(1)
+		}
+		res := results[0]
+
+		if jsonOutput {
+			outputJSON(res)
+		} else {
+			fmt.Println(res.Message)
+		}
+		if !res.Success {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	descriptorsMigrateCmd.Flags().StringVar(&descriptorsMigrateTo, "to", "", "target backend (fs or sqlite)")
+	descriptorsCmd.AddCommand(descriptorsBackendCmd)
+	descriptorsCmd.AddCommand(descriptorsMigrateCmd)
+	descriptorsCmd.AddCommand(descriptorsRelativizeCmd)
+	rootCmd.AddCommand(descriptorsCmd)
+}
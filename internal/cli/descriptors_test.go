@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestDescriptorsMigrateCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoRoot := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "descriptors", "backend")
+	require.NoError(t, err)
+	assert.Equal(t, "fs\n", stdout)
+
+	stdout, err = executeCommand(createTestRootCmd(), "descriptors", "migrate", "--to", "sqlite")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Migrated 1 descriptors")
+	_, err = os.Stat(filepath.Join(repoRoot, ".jvs", "descriptors.db"))
+	require.NoError(t, err)
+
+	// Normal operation continues on the new backend
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "second")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "history")
+	require.NoError(t, err)
+	var history []model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &history))
+	assert.Len(t, history, 2)
+
+	_, err = executeCommand(createTestRootCmd(), "verify", "--all")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "info")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Descriptor store: sqlite")
+}
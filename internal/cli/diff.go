@@ -15,7 +15,10 @@ import (
 )
 
 var (
-	diffStatOnly bool
+	diffStatOnly      bool
+	diffPatch         bool
+	diffUnified       int
+	diffMaxPatchBytes int64
 )
 
 var diffCmd = &cobra.Command{
@@ -30,7 +33,12 @@ Arguments can be:
 - Full snapshot ID
 - Short ID prefix (must be unique)
 - Tag name
-- HEAD (latest snapshot of current worktree)`,
+- HEAD (latest snapshot of current worktree)
+
+Each changed file is listed with its size change. With --patch, the
+summary is followed by a unified diff of each changed file. Binary files
+and files larger than --max-patch-bytes are reported without content.
+With --json, --patch adds each file's diff as its "patch" field.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
 
@@ -101,9 +109,16 @@ Arguments can be:
 			}
 		}
 
-		// Compute diff
-		differ := diff.NewDiffer(r.Root)
-		result, err := differ.Diff(fromID, toID)
+		// Compute diff, from the snapshots' manifests unless file content
+		// is needed for patches
+		var result *diff.DiffResult
+		var err error
+		if !diffPatch {
+			result, err = snapshot.ManifestDiff(r.Root, fromID, toID)
+		}
+		if err == nil && result == nil {
+			result, err = diff.NewDiffer(r.Root).Diff(fromID, toID)
+		}
 		if err != nil {
 			fmtErr("compute diff: %v", err)
 			os.Exit(1)
@@ -112,18 +127,37 @@ Arguments can be:
 		// Set timestamps
 		result.SetTimes(fromTime, toTime)
 
+		patchOpts := diff.PatchOptions{Context: diffUnified, MaxBytes: diffMaxPatchBytes}
+		if diffUnified == 0 {
+			patchOpts.Context = -1 // PatchOptions reads zero as the default
+		}
+
 		if jsonOutput {
+			if diffPatch {
+				if err := result.AttachPatches(patchOpts); err != nil {
+					fmtErr("compute patches: %v", err)
+					os.Exit(1)
+				}
+			}
 			outputJSON(result)
 			return
 		}
 
 		if diffStatOnly {
 			// Print summary only
-			fmt.Printf("Added: %d, Removed: %d, Modified: %d\n",
-				result.TotalAdded, result.TotalRemoved, result.TotalModified)
+			fmt.Printf("Added: %d, Removed: %d, Modified: %d, Size: %s\n",
+				result.TotalAdded, result.TotalRemoved, result.TotalModified, displayOptions().SizeDelta(result.SizeDelta))
 		} else {
 			// Print full diff
-			fmt.Print(result.FormatHuman())
+			fmt.Print(result.FormatHuman(displayOptions()))
+		}
+
+		if diffPatch {
+			fmt.Println()
+			if err := result.WritePatch(os.Stdout, patchOpts); err != nil {
+				fmtErr("write patch: %v", err)
+				os.Exit(1)
+			}
 		}
 	},
 }
@@ -170,5 +204,8 @@ func resolveSnapshot(repoRoot string, ref string) (model.SnapshotID, error) {
 
 func init() {
 	diffCmd.Flags().BoolVar(&diffStatOnly, "stat", false, "show summary only")
+	diffCmd.Flags().BoolVarP(&diffPatch, "patch", "p", false, "show unified diffs of changed files")
+	diffCmd.Flags().IntVarP(&diffUnified, "unified", "U", diff.DefaultPatchContext, "lines of context in --patch output")
+	diffCmd.Flags().Int64Var(&diffMaxPatchBytes, "max-patch-bytes", diff.DefaultPatchMaxBytes, "skip content of files larger than this in --patch output")
 	rootCmd.AddCommand(diffCmd)
 }
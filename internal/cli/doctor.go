@@ -1,18 +1,37 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/jvs-project/jvs/internal/doctor"
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/restore"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
 )
 
 var (
-	doctorStrict     bool
-	doctorRepair     bool
-	doctorRepairList bool
+	doctorStrict      bool
+	doctorRepair      bool
+	doctorRepairRun   []string
+	doctorRepairList  bool
+	doctorCheckPaths  bool
+	doctorCI          bool
+	doctorFixDetached string
+	doctorFixAction   string
+	doctorFixForkName string
+)
+
+// Resolutions offered by --fix-detached.
+const (
+	fixDetachedLatest  = "latest"
+	fixDetachedFork    = "fork"
+	fixDetachedPromote = "promote"
 )
 
 var doctorCmd = &cobra.Command{
@@ -22,10 +41,46 @@ var doctorCmd = &cobra.Command{
 
 Runs diagnostic checks on the repository and reports any issues.
 Use --strict to include full snapshot integrity verification.
-Use --repair-runtime to execute safe automatic repairs.`,
+Use --check-paths to find absolute paths recorded in metadata, which break
+when the repository's mountpoint changes.
+Use --repair-runtime to execute safe automatic repairs.
+Use --repair <action> to run repair actions by name (see --repair-list),
+e.g. --repair lineage to reattach snapshots whose parent descriptor is gone
+to their nearest surviving ancestor, or make them roots if none survives.
+Use --fix-detached <worktree> to resolve a worktree stuck in detached state.
+
+With --ci, every check (including --strict and --check-paths) runs without
+prompting, a JSON summary is printed and the exit code tells the outcome:
+  0  healthy        no findings above info
+  2  warnings       warnings only, none of them repairable
+  3  repair_needed  findings a repair action fixes, e.g. --repair-runtime
+  4  errors         errors or critical findings no repair fixes
+Exit code 1 means doctor could not run. --repair-runtime may be combined
+with --ci to repair first.
+
+Detached resolutions (--action):
+  latest   Restore the worktree to its latest snapshot
+  fork     Fork the detached HEAD into a new worktree (--fork-name)
+  promote  Make the detached HEAD the new latest snapshot
+
+Examples:
+  jvs doctor --ci || [ $? -eq 2 ]                 # Gate on health, allow warnings
+  jvs doctor --repair lineage                     # Relink snapshots with missing parents
+  jvs doctor --fix-detached main                  # Choose interactively
+  jvs doctor --fix-detached main --action promote
+  jvs doctor --fix-detached main --action fork --fork-name experiment`,
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
 
+		if doctorCI && (doctorFixDetached != "" || doctorRepairList) {
+			fmtErr("--ci cannot be combined with --fix-detached or --repair-list")
+			os.Exit(1)
+		}
+		if doctorFixDetached != "" {
+			runFixDetached(r.Root, doctorFixDetached)
+			return
+		}
+
 		doc := doctor.NewDoctor(r.Root)
 
 		// If --repair-list, show available repair actions
@@ -48,23 +103,53 @@ Use --repair-runtime to execute safe automatic repairs.`,
 
 		// If --repair-runtime, execute safe repairs first
 		if doctorRepair {
-			results, err := doc.Repair([]string{"clean_tmp", "clean_intents"})
+			results, err := doc.Repair([]string{"clean_tmp", "clean_intents", "clean_materialized", "replay_audit_wal"})
 			if err != nil {
 				fmtErr("repair: %v", err)
 				os.Exit(1)
 			}
-			if !jsonOutput {
+			if !jsonOutput && !doctorCI {
 				for _, r := range results {
 					fmt.Printf("Repair %s: %s\n", r.Action, r.Message)
 				}
 			}
 		}
 
-		result, err := doc.Check(doctorStrict)
+		if len(doctorRepairRun) > 0 {
+			results, err := doc.Repair(doctorRepairRun)
+			if err != nil {
+				fmtErr("repair: %v", err)
+				os.Exit(1)
+			}
+			for _, r := range results {
+				if !jsonOutput && !doctorCI {
+					fmt.Printf("Repair %s: %s\n", r.Action, r.Message)
+				} else if !r.Success {
+					fmtErr("repair %s: %s", r.Action, r.Message)
+				}
+			}
+		}
+
+		result, err := doc.Check(doctorStrict || doctorCI)
 		if err != nil {
 			fmtErr("doctor: %v", err)
 			os.Exit(1)
 		}
+		if doctorCheckPaths || doctorCI {
+			doc.CheckPaths(result)
+		}
+
+		if doctorCI {
+			// The summary is the contract, so it is printed with or without --json
+			summary := doctor.Summarize(result)
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(summary)
+			if summary.ExitCode != doctor.ExitHealthy {
+				os.Exit(summary.ExitCode)
+			}
+			return
+		}
 
 		if jsonOutput {
 			outputJSON(result)
@@ -91,9 +176,125 @@ Use --repair-runtime to execute safe automatic repairs.`,
 	},
 }
 
+// runFixDetached guides a detached worktree back to a state where it can snapshot.
+func runFixDetached(repoRoot, wtName string) {
+	mgr := worktree.NewManager(repoRoot)
+	cfg, err := mgr.Get(wtName)
+	if err != nil {
+		fmtErr("get worktree: %v", err)
+		os.Exit(1)
+	}
+
+	if !cfg.IsDetached() {
+		if jsonOutput {
+			outputJSON(map[string]any{"worktree": wtName, "detached": false})
+			return
+		}
+		fmt.Printf("Worktree '%s' is not detached; nothing to fix.\n", wtName)
+		return
+	}
+
+	action := doctorFixAction
+	if action == "" {
+		if jsonOutput {
+			fmtErr("--action is required with --json (latest, fork, promote)")
+			os.Exit(1)
+		}
+		action = promptFixDetached(cfg)
+		if action == "" {
+			fmt.Println("Cancelled.")
+			return
+		}
+	}
+
+	result := map[string]any{
+		"worktree":    wtName,
+		"action":      action,
+		"head":        cfg.HeadSnapshotID,
+		"prev_latest": cfg.LatestSnapshotID,
+	}
+
+	switch action {
+	case fixDetachedLatest:
+		restorer := restore.NewRestorer(repoRoot, detectEngine(repoRoot))
+		if err := restorer.RestoreToLatest(wtName); err != nil {
+			fmtErr("restore to latest: %v", err)
+			os.Exit(1)
+		}
+		if !jsonOutput {
+			fmt.Printf("Restored '%s' to latest snapshot %s\n", wtName, color.SnapshotID(cfg.LatestSnapshotID.String()))
+		}
+
+	case fixDetachedFork:
+		name := doctorFixForkName
+		if name == "" {
+			name = fmt.Sprintf("fork-%s", cfg.HeadSnapshotID.ShortID())
+		}
+		eng := engine.NewEngine(detectEngine(repoRoot))
+		if _, err := mgr.Fork(cfg.HeadSnapshotID, name, func(src, dst string) error {
+			_, err := eng.Clone(src, dst)
+			return err
+		}); err != nil {
+			fmtErr("fork worktree: %v", err)
+			os.Exit(1)
+		}
+		result["fork_name"] = name
+		if !jsonOutput {
+			fmt.Printf("Forked %s into worktree '%s'\n", color.SnapshotID(cfg.HeadSnapshotID.String()), color.Success(name))
+			fmt.Printf("Path: %s\n", color.Dim(mgr.Path(name)))
+			fmt.Printf("Worktree '%s' is still detached.\n", wtName)
+		}
+
+	case fixDetachedPromote:
+		if _, err := mgr.Promote(wtName); err != nil {
+			fmtErr("promote head: %v", err)
+			os.Exit(1)
+		}
+		if !jsonOutput {
+			fmt.Printf("Promoted %s as latest of '%s'\n", color.SnapshotID(cfg.HeadSnapshotID.String()), wtName)
+			fmt.Println(color.Dim(fmt.Sprintf("Previous latest %s is kept but no longer protected by this worktree's lineage.", cfg.LatestSnapshotID.ShortID())))
+		}
+
+	default:
+		fmtErr("unknown action %q (expected latest, fork, or promote)", action)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		outputJSON(result)
+	}
+}
+
+// promptFixDetached shows the available resolutions and returns the chosen action,
+// or an empty string if the user cancels.
+func promptFixDetached(cfg *model.WorktreeConfig) string {
+	fmt.Printf("Worktree '%s' is detached at %s (latest is %s).\n\n",
+		cfg.Name, color.SnapshotID(cfg.HeadSnapshotID.ShortID()), color.SnapshotID(cfg.LatestSnapshotID.ShortID()))
+	fmt.Println("  1) Restore to latest snapshot (discards uncommitted changes)")
+	fmt.Println("  2) Fork detached HEAD into a new worktree")
+	fmt.Println("  3) Promote detached HEAD as the new latest")
+	fmt.Print("\nChoose [1-3, 0 to cancel]: ")
+
+	switch readInt(3) {
+	case 1:
+		return fixDetachedLatest
+	case 2:
+		return fixDetachedFork
+	case 3:
+		return fixDetachedPromote
+	}
+	return ""
+}
+
 func init() {
 	doctorCmd.Flags().BoolVar(&doctorStrict, "strict", false, "include full integrity verification")
 	doctorCmd.Flags().BoolVar(&doctorRepair, "repair-runtime", false, "execute safe automatic repairs")
+	doctorCmd.Flags().StringSliceVar(&doctorRepairRun, "repair", nil, "run the named repair actions (see --repair-list)")
 	doctorCmd.Flags().BoolVar(&doctorRepairList, "repair-list", false, "list available repair actions")
+	doctorCmd.Flags().BoolVar(&doctorCheckPaths, "check-paths", false, "report absolute paths recorded in metadata")
+	doctorCmd.Flags().BoolVar(&doctorCI, "ci", false, "run every check, print a JSON summary and exit with a status code")
+	doctorCmd.Flags().StringVar(&doctorFixDetached, "fix-detached", "", "resolve detached state of the named worktree")
+	doctorCmd.Flags().StringVar(&doctorFixAction, "action", "", "detached resolution: latest, fork, or promote")
+	doctorCmd.Flags().StringVar(&doctorFixForkName, "fork-name", "", "worktree name for the fork resolution")
 	rootCmd.AddCommand(doctorCmd)
 }
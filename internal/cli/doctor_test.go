@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/doctor"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// setupDetachedRepo creates a repo with two snapshots and restores main to the first.
+func setupDetachedRepo(t *testing.T) (repoRoot string, first, second model.SnapshotID) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoRoot = filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+
+	snap := func(content string) model.SnapshotID {
+		require.NoError(t, os.WriteFile("file.txt", []byte(content), 0644))
+		stdout, err := executeCommand(createTestRootCmd(), "--json", "snapshot", content)
+		require.NoError(t, err)
+		var desc model.Descriptor
+		require.NoError(t, json.Unmarshal([]byte(stdout), &desc))
+		return desc.SnapshotID
+	}
+	first = snap("v1")
+	second = snap("v2")
+
+	_, err = executeCommand(createTestRootCmd(), "restore", string(first))
+	require.NoError(t, err)
+	// Restore swaps the payload directory; re-enter it
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+	return repoRoot, first, second
+}
+
+func TestDoctorFixDetached_Promote(t *testing.T) {
+	repoRoot, first, _ := setupDetachedRepo(t)
+
+	stdout, err := executeCommand(createTestRootCmd(), "doctor", "--fix-detached", "main", "--action", "promote")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Promoted")
+
+	cfg, err := repo.LoadWorktreeConfig(repoRoot, "main")
+	require.NoError(t, err)
+	assert.False(t, cfg.IsDetached())
+	assert.Equal(t, first, cfg.LatestSnapshotID)
+}
+
+func TestDoctorFixDetached_Latest(t *testing.T) {
+	repoRoot, _, second := setupDetachedRepo(t)
+
+	_, err := executeCommand(createTestRootCmd(), "doctor", "--fix-detached", "main", "--action", "latest")
+	require.NoError(t, err)
+
+	cfg, err := repo.LoadWorktreeConfig(repoRoot, "main")
+	require.NoError(t, err)
+	assert.False(t, cfg.IsDetached())
+	assert.Equal(t, second, cfg.HeadSnapshotID)
+	content, err := os.ReadFile(filepath.Join(repoRoot, "main", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+}
+
+func TestDoctorFixDetached_Fork(t *testing.T) {
+	repoRoot, first, _ := setupDetachedRepo(t)
+
+	_, err := executeCommand(createTestRootCmd(), "doctor", "--fix-detached", "main", "--action", "fork", "--fork-name", "rescued")
+	require.NoError(t, err)
+
+	cfg, err := repo.LoadWorktreeConfig(repoRoot, "rescued")
+	require.NoError(t, err)
+	assert.Equal(t, first, cfg.HeadSnapshotID)
+	assert.False(t, cfg.IsDetached())
+	content, err := os.ReadFile(filepath.Join(repoRoot, "worktrees", "rescued", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+}
+
+func TestDoctorFixDetached_NotDetached(t *testing.T) {
+	setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join("testrepo", "main")))
+
+	stdout, err := executeCommand(createTestRootCmd(), "doctor", "--fix-detached", "main", "--action", "promote")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "not detached")
+}
+
+func TestDoctorCheckPathsAndRelativize(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	stdout, err := executeCommand(createTestRootCmd(), "doctor", "--check-paths")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Repository is healthy.")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "descriptors", "relativize-paths")
+	require.NoError(t, err)
+	var res struct {
+		Action  string `json:"action"`
+		Success bool   `json:"success"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &res))
+	assert.Equal(t, "relativize_paths", res.Action)
+	assert.True(t, res.Success)
+}
+
+func TestDoctorCI_Healthy(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("content"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	// The summary is JSON even without --json
+	stdout, err := executeCommand(createTestRootCmd(), "doctor", "--ci")
+	require.NoError(t, err)
+	var summary doctor.Summary
+	require.NoError(t, json.Unmarshal([]byte(stdout), &summary))
+	assert.Equal(t, doctor.StatusHealthy, summary.Status)
+	assert.Equal(t, doctor.ExitHealthy, summary.ExitCode)
+	assert.Empty(t, summary.Findings)
+	assert.Equal(t, 0, summary.Counts["warning"])
+}
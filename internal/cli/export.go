@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	exportOutput    string
+	exportEncryptTo []string
+	exportZstd      bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <snapshot>",
+	Short: "Write a snapshot as a portable bundle",
+	Long: `Write a snapshot as a portable bundle.
+
+The bundle is a tar stream holding the snapshot descriptor as
+descriptor.json, the stored snapshot payload under payload/, and
+integrity.json with the SHA-256 of every payload file. The snapshot is
+verified first.
+
+With --zstd, or an output name ending in .zst (before any .age), the tar
+stream is zstd-compressed.
+
+With --encrypt-to, the bundle is encrypted to one or more age X25519
+public keys (age1...), so it can be handed to specific teammates. Any one
+of the matching identities can decrypt it, with or without jvs:
+
+  age -d -i key.txt bundle.tar.age | tar x
+
+Use "-o -" to write to stdout.
+
+Examples:
+  jvs export v1.0 -o v1.0.tar
+  jvs export HEAD -o workspace.tar.zst
+  jvs export HEAD -o state.tar.age --encrypt-to age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+  jvs export HEAD -o - --encrypt-to $ALICE --encrypt-to $BOB > state.tar.age`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		if exportOutput == "" {
+			fmtErr("--output is required")
+			os.Exit(1)
+		}
+		opts := bundle.Options{
+			Recipients: exportEncryptTo,
+			Compress:   exportZstd || zstdName(exportOutput),
+		}
+		if _, err := bundle.ParseRecipients(opts.Recipients); err != nil {
+			fmtErr("export: %v", err)
+			os.Exit(1)
+		}
+		snapshotID, err := resolveSnapshot(r.Root, args[0])
+		if err != nil {
+			fmtErr("resolve snapshot: %v", err)
+			os.Exit(1)
+		}
+
+		if exportOutput == "-" {
+			if _, err := bundle.Write(os.Stdout, r.Root, snapshotID, opts); err != nil {
+				fmtErr("export: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		result, err := writeBundleFile(r.Root, snapshotID, opts, exportOutput)
+		if err != nil {
+			fmtErr("export: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(result)
+			return
+		}
+		fmt.Printf("%s %s (%d files, %s)\n",
+			color.Success("Wrote"), exportOutput, result.Files, displaySize(result.Bytes))
+		if result.Compressed {
+			fmt.Println("  compressed with zstd")
+		}
+		if result.Encrypted {
+			fmt.Printf("  encrypted to %d recipient(s)\n", result.Recipients)
+		}
+	},
+}
+
+// zstdName reports whether a bundle path asks for zstd compression by its
+// extension, e.g. "state.tar.zst" or "state.tar.zst.age".
+func zstdName(path string) bool {
+	return filepath.Ext(strings.TrimSuffix(path, ".age")) == ".zst"
+}
+
+// writeBundleFile writes the bundle to a temporary file next to path and
+// renames it into place, so a failed run leaves no partial bundle behind.
+func writeBundleFile(repoRoot string, snapshotID model.SnapshotID, opts bundle.Options, path string) (*bundle.Result, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("create output: %w", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	result, err := bundle.Write(f, repoRoot, snapshotID, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sync output: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("close output: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return nil, fmt.Errorf("chmod output: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("rename output: %w", err)
+	}
+	return result, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "bundle path, or - for stdout (required)")
+	exportCmd.Flags().BoolVar(&exportZstd, "zstd", false, "zstd-compress the bundle (implied by a .zst output name)")
+	exportCmd.Flags().StringArrayVar(&exportEncryptTo, "encrypt-to", nil, "encrypt to this age X25519 public key (repeatable)")
+	rootCmd.AddCommand(exportCmd)
+}
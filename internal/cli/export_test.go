@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+)
+
+func TestExportCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("a.txt", []byte("secret"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+
+	plain := filepath.Join(dir, "base.tar")
+	stdout, err := executeCommand(createTestRootCmd(), "export", "base", "-o", plain)
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "1 files")
+	assert.NotContains(t, stdout, "encrypted")
+	data, err := os.ReadFile(plain)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "secret")
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypted := filepath.Join(dir, "base.tar.age")
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "export", "HEAD", "-o", encrypted,
+		"--encrypt-to", identity.Recipient().String())
+	require.NoError(t, err)
+	var result bundle.Result
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.True(t, result.Encrypted)
+	assert.Equal(t, 1, result.Recipients)
+	data, err = os.ReadFile(encrypted)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.Equal(t, result.Bytes, int64(len(data)))
+
+	compressed := filepath.Join(dir, "base.tar.zst")
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "export", "base", "-o", compressed)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.True(t, result.Compressed)
+	data, err = os.ReadFile(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x28, 0xb5, 0x2f, 0xfd}, data[:4], "zstd frame magic")
+
+	// No temporary files are left next to the output
+	matches, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	assert.Empty(t, matches)
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	fleetRoot        string
+	fleetConcurrency int
+	fleetDepth       int
+	fleetStrict      bool
+)
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Run checks across many repositories",
+	Long: `Run checks across many repositories.
+
+Discovers every JVS repository below --root (searching --depth directory
+levels, skipping hidden directories) and runs the subcommand in each with
+bounded concurrency. With --json an aggregate report is printed. Exits 1
+if any repository fails.
+
+Examples:
+  jvs fleet --root /vol/repos doctor --strict
+  jvs fleet --root /vol/repos --concurrency 16 verify
+  jvs fleet --root /vol/repos gc plan --json
+  jvs fleet --root /vol/repos stats`,
+}
+
+var fleetDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the health of every repository",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runFleet(func(ctx context.Context, f *jvs.Fleet) (*jvs.FleetReport, error) {
+			return f.Doctor(ctx, fleetStrict)
+		}, func(r jvs.FleetResult) string {
+			res, ok := r.Report.(*jvs.DoctorResult)
+			if !ok {
+				return ""
+			}
+			return fmt.Sprintf("%d findings", len(res.Findings))
+		})
+	},
+}
+
+var fleetVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify every snapshot in every repository",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runFleet(func(ctx context.Context, f *jvs.Fleet) (*jvs.FleetReport, error) {
+			return f.Verify(ctx)
+		}, func(r jvs.FleetResult) string {
+			res, ok := r.Report.(*jvs.VerifyReport)
+			if !ok {
+				return ""
+			}
+			return fmt.Sprintf("%d snapshots, %d failed", res.Snapshots, len(res.Failures))
+		})
+	},
+}
+
+var fleetGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Garbage collection across repositories",
+}
+
+var fleetGCPlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Create a GC plan in every repository",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runFleet(func(ctx context.Context, f *jvs.Fleet) (*jvs.FleetReport, error) {
+			return f.GCPlan(ctx)
+		}, func(r jvs.FleetResult) string {
+			plan, ok := r.Report.(*model.GCPlan)
+			if !ok {
+				return ""
+			}
+			return fmt.Sprintf("plan %s: %d to delete, ~%s", plan.PlanID, len(plan.ToDelete), displaySize(plan.DeletableBytesEstimate))
+		})
+	},
+}
+
+var fleetStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize every repository",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runFleet(func(ctx context.Context, f *jvs.Fleet) (*jvs.FleetReport, error) {
+			return f.Stats(ctx)
+		}, func(r jvs.FleetResult) string {
+			s, ok := r.Report.(*jvs.RepoStats)
+			if !ok {
+				return ""
+			}
+			return fmt.Sprintf("%d worktrees, %d snapshots, %s", s.Worktrees, s.Snapshots, displaySize(s.SnapshotBytes))
+		})
+	},
+}
+
+// runFleet runs op over the fleet and prints the report, using summarize for
+// the per-repository summary column.
+func runFleet(op func(context.Context, *jvs.Fleet) (*jvs.FleetReport, error), summarize func(jvs.FleetResult) string) {
+	if fleetRoot == "" {
+		fmtErr("--root is required")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fleet := jvs.NewFleet(jvs.FleetOptions{
+		Root:        fleetRoot,
+		MaxDepth:    fleetDepth,
+		Concurrency: fleetConcurrency,
+	})
+	report, err := op(ctx, fleet)
+	if err != nil {
+		fmtErr("fleet: %v", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		outputJSON(report)
+	} else {
+		printFleetReport(report, summarize)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func printFleetReport(report *jvs.FleetReport, summarize func(jvs.FleetResult) string) {
+	if report.Total == 0 {
+		fmt.Printf("No repositories found under %s.\n", report.Root)
+		return
+	}
+
+	fmt.Printf("%-50s  %-6s  %s\n", "REPOSITORY", "STATUS", "SUMMARY")
+	for _, r := range report.Results {
+		status := color.Success("ok")
+		summary := summarize(r)
+		if !r.OK {
+			status = color.Error("FAILED")
+			if summary == "" {
+				summary = r.Error
+			} else {
+				summary += ": " + r.Error
+			}
+		}
+		fmt.Printf("%-50s  %-6s  %s\n", r.Repo, status, summary)
+	}
+	fmt.Println()
+	fmt.Printf("%s: %d repositories, %d failed\n", report.Operation, report.Total, report.Failed)
+}
+
+func init() {
+	fleetCmd.PersistentFlags().StringVar(&fleetRoot, "root", "", "directory to search for repositories (required)")
+	fleetCmd.PersistentFlags().IntVar(&fleetConcurrency, "concurrency", jvs.DefaultFleetConcurrency, "repositories processed at once")
+	fleetCmd.PersistentFlags().IntVar(&fleetDepth, "depth", jvs.DefaultFleetMaxDepth, "directory levels searched below --root")
+	fleetDoctorCmd.Flags().BoolVar(&fleetStrict, "strict", false, "include snapshot integrity and audit chain checks")
+	fleetGCCmd.AddCommand(fleetGCPlanCmd)
+	fleetCmd.AddCommand(fleetDoctorCmd, fleetVerifyCmd, fleetGCCmd, fleetStatsCmd)
+	rootCmd.AddCommand(fleetCmd)
+}
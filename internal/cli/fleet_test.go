@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+)
+
+func TestFleetCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "alpha")
+	require.NoError(t, err)
+	_, err = executeCommand(createTestRootCmd(), "init", "beta")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "fleet", "--root", dir, "stats")
+	require.NoError(t, err)
+	var report jvs.FleetReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+	assert.Equal(t, "stats", report.Operation)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 0, report.Failed)
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, filepath.Join(dir, "alpha"), report.Results[0].Repo)
+
+	stdout, err = executeCommand(createTestRootCmd(), "fleet", "--root", dir, "doctor")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "alpha")
+	assert.Contains(t, stdout, "doctor: 2 repositories, 0 failed")
+
+	stdout, err = executeCommand(createTestRootCmd(), "fleet", "--root", dir, "gc", "plan")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "0 to delete")
+}
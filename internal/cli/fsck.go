@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/fsck"
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Cross-check repository metadata for consistency",
+	Long: `Cross-check repository metadata for consistency.
+
+Checks descriptors against snapshot directories, and both against worktree
+configs, retained roots, pins, tombstones and manifests. Every problem is
+reported with its kind:
+
+  orphan_payload            snapshot directory without a descriptor (warning)
+  missing_payload           descriptor without a snapshot directory
+  not_ready                 snapshot directory without a .READY marker
+  missing_descriptor        worktree, retained root or pin naming a snapshot
+                            without a descriptor (warning for a worktree base)
+  corrupt_descriptor        descriptor that cannot be read or fails its checksum
+  dangling_parent           descriptor whose parent has no descriptor
+  corrupt_worktree          worktree config that cannot be read
+  missing_worktree_payload  worktree without a payload directory
+  corrupt_pin               pin file that cannot be parsed (warning)
+  live_tombstone            tombstone of a snapshot that still exists (warning)
+  orphan_manifest           manifest without a descriptor (warning)
+
+fsck only reads; jvs doctor repairs, and jvs verify rehashes payloads.
+Snapshots being created are skipped. Exits 1 if any error is found;
+warnings alone exit 0.
+
+Examples:
+  jvs fsck
+  jvs fsck --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		report, err := fsck.Check(r.Root)
+		if err != nil {
+			fmtErr("fsck: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(report)
+		} else {
+			fmt.Printf("Checked %d descriptors, %d snapshot directories, %d worktrees, %d pins, %d tombstones\n",
+				report.Descriptors, report.SnapshotDirs, report.Worktrees, report.Pins, report.Tombstones)
+			if len(report.Problems) == 0 {
+				fmt.Printf("%s repository metadata is consistent\n", color.Success("OK"))
+			}
+			for _, p := range report.Problems {
+				subject := string(p.SnapshotID)
+				if subject == "" {
+					subject = p.Source
+				}
+				fmt.Printf("  [%s] %s %s: %s\n", p.Severity, p.Kind, subject, p.Message)
+			}
+		}
+		if !report.Clean() {
+			fmtErr("fsck found %d errors and %d warnings", report.Errors, report.Warnings)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fsckCmd)
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/fsck"
+)
+
+func TestFsckCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "fsck")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "metadata is consistent")
+
+	// An orphan payload is a warning, so fsck still succeeds
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "testrepo", ".jvs", "snapshots", "1700000000000-deadbeef"), 0755))
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "fsck")
+	require.NoError(t, err)
+	var report fsck.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+	assert.True(t, report.Clean())
+	assert.Equal(t, 1, report.Descriptors)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, fsck.KindOrphanPayload, report.Problems[0].Kind)
+}
@@ -1,17 +1,33 @@
 package cli
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/jvs-project/jvs/pkg/progress"
 )
 
 var (
-	gcPlanID string
+	gcPlanID         string
+	gcCheckConflicts bool
+	gcDaemonInterval time.Duration
+	gcDaemonJitter   time.Duration
+	gcDaemonPolicy   string
+	gcPruneOlderThan string
 )
 
 var gcCmd = &cobra.Command{
@@ -25,12 +41,24 @@ var gcPlanCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
 
+		policy, err := config.LoadRepoRetentionPolicy(r.Root)
+		if err != nil {
+			fmtErr("load retention policy: %v", err)
+			os.Exit(1)
+		}
+
 		collector := gc.NewCollector(r.Root)
-		plan, err := collector.Plan()
+		plan, err := collector.PlanWithPolicy(policy)
 		if err != nil {
 			fmtErr("create gc plan: %v", err)
 			os.Exit(1)
 		}
+		if gcCheckConflicts {
+			if plan.Conflicts, err = collector.Conflicts(plan); err != nil {
+				fmtErr("check conflicts: %v", err)
+				os.Exit(1)
+			}
+		}
 
 		if jsonOutput {
 			outputJSON(plan)
@@ -40,8 +68,31 @@ var gcPlanCmd = &cobra.Command{
 		fmt.Printf("GC Plan: %s\n", plan.PlanID)
 		fmt.Printf("  Protected by lineage: %d snapshots\n", plan.ProtectedByLineage)
 		fmt.Printf("  Protected by pin: %d snapshots\n", plan.ProtectedByPin)
+		fmt.Printf("  Protected by retention: %d snapshots\n", plan.ProtectedByRetention)
 		fmt.Printf("  To delete: %d snapshots\n", len(plan.ToDelete))
-		fmt.Printf("  Estimated reclaim: ~%d MB\n", plan.DeletableBytesEstimate/1024/1024)
+		fmt.Printf("  Estimated reclaim: ~%s\n", displaySize(plan.DeletableBytesEstimate))
+		if len(plan.Reclaim) > 0 {
+			fmt.Println()
+			fmt.Println("Largest reclaims:")
+			printReclaim(plan.Reclaim, "  ", maxReclaimLines)
+		}
+		if len(plan.ProtectedByRule) > 0 {
+			fmt.Println()
+			fmt.Println("Retention rules:")
+			printRetentionRules(plan.ProtectedByRule, "  ")
+		}
+		if len(plan.BudgetUsage) > 0 {
+			fmt.Println()
+			fmt.Println("Tag budgets:")
+			printBudgetUsage(plan.BudgetUsage, "  ", true)
+		}
+		if len(plan.Conflicts) > 0 {
+			fmt.Println()
+			fmt.Println(color.Warning("In use by live operations:"))
+			for _, c := range plan.Conflicts {
+				fmt.Printf("  %s\n", gc.DescribeConflict(c))
+			}
+		}
 		fmt.Println()
 		fmt.Printf("Run: jvs gc run --plan-id %s\n", plan.PlanID)
 	},
@@ -59,6 +110,7 @@ var gcRunCmd = &cobra.Command{
 		}
 
 		collector := gc.NewCollector(r.Root)
+		collector.SetCheckConflicts(gcCheckConflicts)
 
 		// Add progress callback if enabled
 		if progressEnabled() {
@@ -88,9 +140,182 @@ var gcRunCmd = &cobra.Command{
 	},
 }
 
+var gcPruneTombstonesCmd = &cobra.Command{
+	Use:   "prune-tombstones",
+	Short: "Remove old GC tombstones",
+	Long: `Remove old GC tombstones.
+
+Every snapshot GC deletes leaves a tombstone in .jvs/gc/tombstones. This
+removes the tombstones of snapshots deleted longer ago than --older-than, a
+duration such as 90d or 720h. Tombstones of snapshots whose payload or
+descriptor remains are kept, as 'jvs fsck' reports them. Set
+retention.tombstone_max_age to prune them during every GC run instead.
+
+Examples:
+  jvs gc prune-tombstones
+  jvs gc prune-tombstones --older-than 30d`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		olderThan, err := config.ParseDuration(gcPruneOlderThan)
+		if err != nil || olderThan < 0 {
+			fmtErr("invalid --older-than %q: must be a non-negative duration such as 90d", gcPruneOlderThan)
+			os.Exit(1)
+		}
+		pruned, err := gc.NewCollector(r.Root).PruneTombstones(olderThan)
+		if err != nil {
+			fmtErr("prune tombstones: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			if pruned == nil {
+				pruned = []model.SnapshotID{}
+			}
+			outputJSON(map[string]any{"pruned": pruned})
+			return
+		}
+		fmt.Printf("Pruned %d tombstones older than %s.\n", len(pruned), gcPruneOlderThan)
+	},
+}
+
+var gcDaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Plan and run GC periodically",
+	Long: `Plan and run GC periodically.
+
+Runs in the foreground until interrupted. Every cycle plans GC with the
+retention policy and runs the plan at once, under the exclusive repository
+lock, as 'jvs gc plan' followed by 'jvs gc run' would. The first cycle runs
+after the jitter delay, the next ones every --interval plus a random delay
+up to --jitter, so that daemons of many repositories do not collect at once.
+
+The retention policy is the repository's, from .jvs/retention.json or the
+retention section of .jvs/config.yaml, or with --policy a file holding the
+same fields; it is reread every cycle.
+Cycles are audited as gc_plan and gc_run with trigger "schedule", and share
+a correlation ID. A failed cycle is reported and the daemon carries on.
+With --json, each cycle is printed as one JSON object per line.
+
+Examples:
+  jvs gc daemon
+  jvs gc daemon --interval 6h --jitter 15m
+  jvs gc daemon --policy /etc/jvs/retention.yaml --check-conflicts`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		opts := gc.SchedulerOptions{
+			Interval:       gcDaemonInterval,
+			Jitter:         gcDaemonJitter,
+			CheckConflicts: gcCheckConflicts,
+			OnCycle:        printGCCycle,
+		}
+		if gcDaemonPolicy != "" {
+			// Checked once up front, so a bad file fails at start
+			if _, err := config.LoadRetentionPolicy(gcDaemonPolicy); err != nil {
+				fmtErr("%v", err)
+				os.Exit(1)
+			}
+			opts.Policy = func() (model.RetentionPolicy, error) {
+				return config.LoadRetentionPolicy(gcDaemonPolicy)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if !jsonOutput {
+			fmt.Printf("Collecting %s every %s (jitter %s). Press Ctrl-C to stop.\n",
+				color.Highlight(r.Root), gcDaemonInterval, gcDaemonJitter)
+		}
+		if err := gc.NewScheduler(r.Root, opts).Run(ctx); err != nil {
+			fmtErr("gc daemon: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// printGCCycle prints the outcome of a scheduled GC cycle.
+func printGCCycle(cycle *gc.Cycle) {
+	if jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(cycle)
+		return
+	}
+	stamp := cycle.StartedAt.Local().Format(time.DateTime)
+	if cycle.Error != "" {
+		fmt.Printf("%s  %s %s\n", stamp, color.Warning("failed:"), cycle.Error)
+		return
+	}
+	fmt.Printf("%s  deleted %d of %d planned snapshots\n", stamp, len(cycle.Deleted), cycle.Planned)
+}
+
+// maxReclaimLines is how many snapshots 'jvs gc plan' lists by reclaim.
+const maxReclaimLines = 10
+
+// printReclaim prints the n snapshots whose deletion frees the most space,
+// with their size.
+func printReclaim(reclaim []model.SnapshotReclaim, indent string, n int) {
+	sorted := slices.Clone(reclaim)
+	slices.SortStableFunc(sorted, func(a, b model.SnapshotReclaim) int {
+		return cmp.Compare(b.ReclaimBytes, a.ReclaimBytes)
+	})
+	for _, r := range sorted[:min(n, len(sorted))] {
+		fmt.Printf("%s%s  %s of %s\n", indent, r.SnapshotID, displaySize(r.ReclaimBytes), displaySize(r.Bytes))
+	}
+	if len(sorted) > n {
+		fmt.Printf("%s... and %d more\n", indent, len(sorted)-n)
+	}
+}
+
+// printRetentionRules prints how many snapshots each retention rule
+// protects, by rule.
+func printRetentionRules(byRule map[model.SnapshotID]string, indent string) {
+	counts := make(map[string]int)
+	for _, rule := range byRule {
+		counts[rule]++
+	}
+	for _, rule := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Printf("%s%s: %d snapshots\n", indent, rule, counts[rule])
+	}
+}
+
+// printBudgetUsage prints one line per tag budget. If showEvicted is set,
+// the number of snapshots selected for deletion by the budget is included.
+func printBudgetUsage(usage []model.BudgetUsage, indent string, showEvicted bool) {
+	for _, u := range usage {
+		line := fmt.Sprintf("%s%-16s %s", indent, u.Tag, budgetLimit(displaySize(u.UsedBytes), u.MaxBytes > 0, displaySize(u.MaxBytes)))
+		line += fmt.Sprintf(", %s snapshots", budgetLimit(fmt.Sprint(u.UsedCount), u.MaxCount > 0, fmt.Sprint(u.MaxCount)))
+		if u.OverBudget() {
+			line += " " + color.Warning("(over budget)")
+		}
+		if showEvicted && u.Evicted > 0 {
+			line += fmt.Sprintf(" - %d oldest selected for deletion", u.Evicted)
+		}
+		fmt.Println(line)
+	}
+}
+
+func budgetLimit(used string, limited bool, max string) string {
+	if !limited {
+		return used
+	}
+	return used + " / " + max
+}
+
 func init() {
 	gcRunCmd.Flags().StringVar(&gcPlanID, "plan-id", "", "plan ID to execute")
+	gcRunCmd.Flags().BoolVar(&gcCheckConflicts, "check-conflicts", false, "abort if a snapshot in the plan is used by an in-flight restore or a leased worktree")
+	gcPlanCmd.Flags().BoolVar(&gcCheckConflicts, "check-conflicts", false, "list snapshots in the plan used by in-flight restores or leased worktrees")
+	gcDaemonCmd.Flags().DurationVar(&gcDaemonInterval, "interval", gc.DefaultScheduleInterval, "time between GC cycles")
+	gcDaemonCmd.Flags().DurationVar(&gcDaemonJitter, "jitter", 10*time.Minute, "random extra delay of up to this much before each cycle")
+	gcDaemonCmd.Flags().StringVar(&gcDaemonPolicy, "policy", "", "retention policy file (default: the repository's retention policy)")
+	gcPruneTombstonesCmd.Flags().StringVar(&gcPruneOlderThan, "older-than", "90d", "remove tombstones of snapshots deleted longer ago than this")
+	gcDaemonCmd.Flags().BoolVar(&gcCheckConflicts, "check-conflicts", false, "skip a cycle whose plan includes snapshots used by in-flight restores or leased worktrees")
 	gcCmd.AddCommand(gcPlanCmd)
 	gcCmd.AddCommand(gcRunCmd)
+	gcCmd.AddCommand(gcDaemonCmd)
+	gcCmd.AddCommand(gcPruneTombstonesCmd)
 	rootCmd.AddCommand(gcCmd)
 }
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestGCPlanAndInfo_TagBudgets(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoRoot := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+
+	cfgYAML := "retention:\n  budgets:\n    - tag: auto\n      max_size: 1GB\n      max_count: 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, ".jvs", "config.yaml"), []byte(cfgYAML), 0644))
+	config.InvalidateCache(repoRoot)
+	t.Cleanup(func() { config.InvalidateCache(repoRoot) })
+
+	for _, content := range []string{"a", "b"} {
+		require.NoError(t, os.WriteFile("file.txt", []byte(content), 0644))
+		_, err = executeCommand(createTestRootCmd(), "snapshot", "auto", "--tag", "auto")
+		require.NoError(t, err)
+	}
+
+	stdout, err := executeCommand(createTestRootCmd(), "gc", "plan")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Tag budgets:")
+	assert.Contains(t, stdout, "auto")
+	assert.Contains(t, stdout, "2 / 1 snapshots")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "gc", "plan")
+	require.NoError(t, err)
+	var plan model.GCPlan
+	require.NoError(t, json.Unmarshal([]byte(stdout), &plan))
+	require.Len(t, plan.BudgetUsage, 1)
+	assert.Equal(t, 2, plan.BudgetUsage[0].UsedCount)
+
+	stdout, err = executeCommand(createTestRootCmd(), "info")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Tag budgets:")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "info")
+	require.NoError(t, err)
+	var info map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &info))
+	assert.Contains(t, info, "budget_usage")
+}
+
+func TestGCPruneTombstones(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoRoot := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+
+	tombstones := filepath.Join(repoRoot, ".jvs", "gc", "tombstones")
+	require.NoError(t, os.MkdirAll(tombstones, 0755))
+	data, err := json.Marshal(model.Tombstone{SnapshotID: "1700000000000-aaaaaaaa", DeletedAt: time.Now().Add(-40 * 24 * time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tombstones, "1700000000000-aaaaaaaa.json"), data, 0644))
+
+	stdout, err := executeCommand(createTestRootCmd(), "gc", "prune-tombstones")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Pruned 0 tombstones older than 90d")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "gc", "prune-tombstones", "--older-than", "30d")
+	require.NoError(t, err)
+	var result struct {
+		Pruned []model.SnapshotID `json:"pruned"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, []model.SnapshotID{"1700000000000-aaaaaaaa"}, result.Pruned)
+}
+
+// Since tag budgets, 'gc plan' takes its retention from the repository,
+// like 'gc run' and the library: retention.within and retention.keep in
+// .jvs/config.yaml now apply, where the plan used to ignore them and
+// always keep the last 24 hours.
+func TestGCPlan_UsesConfiguredRetention(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoRoot := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+	config.InvalidateCache(repoRoot)
+	t.Cleanup(func() { config.InvalidateCache(repoRoot) })
+
+	// A snapshot only a removed worktree referenced
+	_, err = executeCommand(createTestRootCmd(), "worktree", "create", "scratch")
+	require.NoError(t, err)
+	stdout, err := executeCommand(createTestRootCmd(), "worktree", "path", "scratch")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(strings.TrimSpace(stdout)))
+	require.NoError(t, os.WriteFile("file.txt", []byte("scratch"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "scratch")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+	_, err = executeCommand(createTestRootCmd(), "worktree", "remove", "scratch")
+	require.NoError(t, err)
+
+	plan := func() model.GCPlan {
+		stdout, err := executeCommand(createTestRootCmd(), "--json", "gc", "plan")
+		require.NoError(t, err)
+		var plan model.GCPlan
+		require.NoError(t, json.Unmarshal([]byte(stdout), &plan))
+		return plan
+	}
+	assert.Empty(t, plan().ToDelete, "the default retention keeps the last 24 hours")
+
+	cfgYAML := "retention:\n  within: 1ns\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, ".jvs", "config.yaml"), []byte(cfgYAML), 0644))
+	config.InvalidateCache(repoRoot)
+	assert.Len(t, plan().ToDelete, 1, "retention.within applies to gc plan")
+
+	cfgYAML = "retention:\n  within: 1ns\n  keep: 5\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, ".jvs", "config.yaml"), []byte(cfgYAML), 0644))
+	config.InvalidateCache(repoRoot)
+	assert.Empty(t, plan().ToDelete, "retention.keep applies to gc plan")
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/gitbridge"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+)
+
+var (
+	gitExportRepo         string
+	gitExportBranch       string
+	gitExportAuthor       string
+	gitExportLFSThreshold string
+)
+
+var gitExportCmd = &cobra.Command{
+	Use:   "git-export <worktree>",
+	Short: "Replay a worktree's snapshot history as Git commits",
+	Long: `Replay the lineage of a worktree's latest snapshot as Git commits.
+
+Each snapshot becomes one commit holding its full payload, oldest first.
+Notes become commit messages, and snapshot tags become Git tags. Every
+commit carries a "JVS-Snapshot: <id>" trailer; later runs resume after the
+snapshot recorded at the branch tip, so only new snapshots are committed.
+
+The Git repository is created if needed. With --lfs-threshold, files at
+least that large are committed as Git LFS pointers and their content is
+stored in the repository's LFS object store, ready for 'git lfs push'.
+
+Examples:
+  jvs git-export main --repo ../history
+  jvs git-export exp-1 --repo ../history --branch experiments/exp-1
+  jvs git-export main --repo ../history --lfs-threshold 10MB`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		if gitExportRepo == "" {
+			fmtErr("--repo is required")
+			os.Exit(1)
+		}
+		threshold, err := config.ParseSize(gitExportLFSThreshold)
+		if err != nil {
+			fmtErr("--lfs-threshold: %v", err)
+			os.Exit(1)
+		}
+
+		result, err := gitbridge.Export(r.Root, args[0], gitbridge.Options{
+			GitDir:       gitExportRepo,
+			Branch:       gitExportBranch,
+			Author:       gitExportAuthor,
+			LFSThreshold: threshold,
+		})
+		if err != nil {
+			fmtErr("git export: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(result)
+			return
+		}
+		if len(result.Exported) == 0 {
+			fmt.Printf("Branch %s is up to date (%d snapshot(s) exported earlier)\n", result.Branch, result.UpToDate)
+			return
+		}
+		fmt.Printf("%s %d snapshot(s) to %s on branch %s\n",
+			color.Success("Exported"), len(result.Exported), result.GitDir, result.Branch)
+		fmt.Printf("  head: %s\n", result.Head)
+		if len(result.Tags) > 0 {
+			fmt.Printf("  tags: %d\n", len(result.Tags))
+		}
+		if result.LFSObjects > 0 {
+			fmt.Printf("  LFS objects: %d\n", result.LFSObjects)
+		}
+	},
+}
+
+func init() {
+	gitExportCmd.Flags().StringVar(&gitExportRepo, "repo", "", "Git repository to export into (required)")
+	gitExportCmd.Flags().StringVar(&gitExportBranch, "branch", "", "branch to commit to (default: the worktree name)")
+	gitExportCmd.Flags().StringVar(&gitExportAuthor, "author", "", `commit author as "Name <email>" (default: `+gitbridge.DefaultAuthor+`)`)
+	gitExportCmd.Flags().StringVar(&gitExportLFSThreshold, "lfs-threshold", "", "store files at least this large as Git LFS pointers, e.g. 10MB")
+	rootCmd.AddCommand(gitExportCmd)
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/gitbridge"
+)
+
+func TestGitExportCommand(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	require.NoError(t, os.WriteFile("a.txt", []byte("a"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1")
+	require.NoError(t, err)
+
+	gitDir := filepath.Join(dir, "history")
+	stdout, err := executeCommand(createTestRootCmd(), "git-export", "main", "--repo", gitDir)
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Exported")
+	assert.Contains(t, stdout, "1 snapshot(s)")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "git-export", "main", "--repo", gitDir)
+	require.NoError(t, err)
+	var result gitbridge.Result
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Empty(t, result.Exported)
+	assert.Equal(t, 1, result.UpToDate)
+	assert.Equal(t, "main", result.Branch)
+}
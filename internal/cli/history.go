@@ -3,13 +3,17 @@ package cli
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jvs-project/jvs/internal/identity"
 	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
 	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
@@ -18,6 +22,11 @@ var (
 	historyNoteFilter string
 	historyTagFilter  string
 	historyAll        bool
+	historyEnv        []string
+	historyGraph      bool
+	historySince      string
+	historyUntil      string
+	historyAuthor     string
 )
 
 var historyCmd = &cobra.Command{
@@ -37,10 +46,53 @@ Examples:
   jvs history -n 10              # Show last 10 snapshots
   jvs history --grep "fix"       # Filter by note substring
   jvs history --tag v1.0         # Filter by tag
-  jvs history --all              # Show all snapshots in repo`,
+  jvs history --all              # Show all snapshots in repo
+  jvs history --env hostname=sandbox-7 --env AGENT_ID=a42
+  jvs history --since 2h         # Snapshots from the last two hours
+  jvs history --since 2026-01-01 --until 2026-02-01
+  jvs history --all --author alice
+  jvs history --graph            # Draw the lineage of all worktrees
+
+--env matches the environment recorded with --capture-env: the keys
+hostname, image_digest and jvs_version select those fields, any other key
+a recorded variable.
+
+--since and --until keep snapshots created in a time window. Each takes a
+duration back from now (30m, 2h, 7d), a date (2006-01-02, midnight local
+time) or an RFC 3339 timestamp.
+
+--author keeps snapshots taken by an author, as recorded from JVS_AUTHOR or
+the local user@host. An author without @host matches that user on any host.
+
+--graph draws the lineage of every worktree's snapshots as a graph, like
+git log --graph, showing where worktrees were forked from one another. Each
+snapshot follows its descendants, with the branches growing from it listed
+newest first. It cannot be combined with filters; with --json it outputs
+the snapshots in graph order and the edges from each to its parent.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if historyGraph {
+			runHistoryGraph()
+			return
+		}
 		r, wtName := requireWorktree()
 
+		envFilter, err := parseEnvFilter(historyEnv)
+		if err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+		now := time.Now()
+		since, err := parseTimeBound("--since", historySince, now)
+		if err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+		until, err := parseTimeBound("--until", historyUntil, now)
+		if err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+
 		var history []*model.Descriptor
 		var latestSnapshotID model.SnapshotID
 		var currentSnapshotID model.SnapshotID
@@ -60,6 +112,10 @@ Examples:
 			opts := snapshot.FilterOptions{
 				NoteContains: historyNoteFilter,
 				HasTag:       historyTagFilter,
+				Environment:  envFilter,
+				Since:        since,
+				Until:        until,
+				Author:       historyAuthor,
 			}
 			var err error
 			history, err = snapshot.Find(r.Root, opts)
@@ -96,6 +152,18 @@ Examples:
 					currentID = desc.ParentID
 					continue
 				}
+				if len(envFilter) > 0 && !snapshot.MatchesEnvironment(desc.Environment, envFilter) {
+					currentID = desc.ParentID
+					continue
+				}
+				if (!since.IsZero() && desc.CreatedAt.Before(since)) || (!until.IsZero() && desc.CreatedAt.After(until)) {
+					currentID = desc.ParentID
+					continue
+				}
+				if historyAuthor != "" && !identity.Matches(desc.Author, historyAuthor) {
+					currentID = desc.ParentID
+					continue
+				}
 
 				history = append(history, desc)
 				currentID = desc.ParentID
@@ -141,7 +209,7 @@ Examples:
 			// Print the line with colored snapshot ID
 			fmt.Printf("%s  %s  %s%s%s\n",
 				color.SnapshotID(desc.SnapshotID.ShortID()),
-				color.Dim(desc.CreatedAt.Format("2006-01-02 15:04")),
+				color.Dim(displayTime(desc.CreatedAt)),
 				note,
 				tagsStr,
 				marker,
@@ -159,6 +227,83 @@ Examples:
 	},
 }
 
+// runHistoryGraph implements 'jvs history --graph'.
+func runHistoryGraph() {
+	if historyNoteFilter != "" || historyTagFilter != "" || len(historyEnv) > 0 || historySince != "" || historyUntil != "" || historyAuthor != "" {
+		fmtErr("--graph cannot be combined with --grep, --tag, --env, --since, --until or --author")
+		os.Exit(1)
+	}
+	r := requireRepo()
+
+	all, err := snapshot.ListIndexed(r.Root)
+	if err != nil {
+		fmtErr("list snapshots: %v", err)
+		os.Exit(1)
+	}
+	ordered, edges := snapshot.LineageGraph(all)
+	if historyLimit > 0 && len(ordered) > historyLimit {
+		ordered = ordered[:historyLimit]
+		shown := make(map[model.SnapshotID]bool, len(ordered))
+		for _, desc := range ordered {
+			shown[desc.SnapshotID] = true
+		}
+		edges = slices.DeleteFunc(edges, func(e snapshot.GraphEdge) bool {
+			return !shown[e.SnapshotID] || !shown[e.ParentID]
+		})
+	}
+
+	if jsonOutput {
+		if ordered == nil {
+			ordered = []*model.Descriptor{}
+		}
+		if edges == nil {
+			edges = []snapshot.GraphEdge{}
+		}
+		outputJSON(historyGraphOutput{Snapshots: ordered, Edges: edges})
+		return
+	}
+	if len(ordered) == 0 {
+		fmt.Println("No snapshots yet.")
+		return
+	}
+	for _, line := range graphLines(ordered, graphLabel) {
+		fmt.Println(line)
+	}
+}
+
+// parseEnvFilter parses repeated --env key=value flags into a map.
+func parseEnvFilter(values []string) (map[string]string, error) {
+	filter := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env filter %q (expected key=value)", v)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// parseTimeBound parses a --since or --until value: a duration back from
+// now such as 2h or 7d, a date, or an RFC 3339 timestamp. An empty value is
+// the zero time, which does not filter.
+func parseTimeBound(flag, value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := config.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q: expected a duration such as 2h or 7d, a date (2006-01-02) or an RFC 3339 timestamp", flag, value)
+}
+
 func hasTag(desc *model.Descriptor, tag string) bool {
 	for _, t := range desc.Tags {
 		if t == tag {
@@ -173,5 +318,10 @@ func init() {
 	historyCmd.Flags().StringVarP(&historyNoteFilter, "grep", "g", "", "filter by note substring")
 	historyCmd.Flags().StringVar(&historyTagFilter, "tag", "", "filter by tag")
 	historyCmd.Flags().BoolVar(&historyAll, "all", false, "show all snapshots (not just current worktree)")
+	historyCmd.Flags().StringArrayVar(&historyEnv, "env", nil, "filter by recorded environment, key=value (can be repeated)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "show snapshots created after this time or duration ago (e.g. 2h, 7d, 2026-01-02)")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "show snapshots created before this time or duration ago")
+	historyCmd.Flags().StringVar(&historyAuthor, "author", "", "filter by author (user@host, or user on any host)")
+	historyCmd.Flags().BoolVar(&historyGraph, "graph", false, "draw the lineage of all worktrees as a graph")
 	rootCmd.AddCommand(historyCmd)
 }
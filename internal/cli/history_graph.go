@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// historyGraphOutput is the JSON form of 'jvs history --graph'.
+type historyGraphOutput struct {
+	Snapshots []*model.Descriptor  `json:"snapshots"`
+	Edges     []snapshot.GraphEdge `json:"edges"`
+}
+
+// graphLines draws snapshots, ordered by snapshot.LineageGraph, as an ASCII
+// lineage graph in the style of git log --graph: one line per snapshot,
+// ending with its label, plus lines where branches join their parent.
+//
+// Each lane of the graph waits for the parent of the last snapshot drawn
+// in it. A snapshot no lane waits for starts a new lane on the right.
+func graphLines(ordered []*model.Descriptor, label func(*model.Descriptor) string) []string {
+	present := make(map[model.SnapshotID]bool, len(ordered))
+	for _, desc := range ordered {
+		present[desc.SnapshotID] = true
+	}
+
+	var lanes []model.SnapshotID
+	var lines []string
+	for _, desc := range ordered {
+		var waiting []int
+		for i, id := range lanes {
+			if id == desc.SnapshotID {
+				waiting = append(waiting, i)
+			}
+		}
+		col := len(lanes)
+		if len(waiting) == 0 {
+			lanes = append(lanes, desc.SnapshotID)
+		} else {
+			col = waiting[0]
+			// Join the other branches into the first, rightmost first
+			for i := len(waiting) - 1; i > 0; i-- {
+				lines = append(lines, shiftLine(len(lanes), waiting[i], true))
+				lanes = slices.Delete(lanes, waiting[i], waiting[i]+1)
+			}
+		}
+
+		cells := make([]string, len(lanes))
+		for i := range lanes {
+			cells[i] = "|"
+		}
+		cells[col] = "*"
+		lines = append(lines, strings.Join(cells, " ")+" "+label(desc))
+
+		if desc.ParentID != nil && present[*desc.ParentID] {
+			lanes[col] = *desc.ParentID
+			continue
+		}
+		// The lane ends; the lanes to its right move left
+		if col < len(lanes)-1 {
+			lines = append(lines, shiftLine(len(lanes), col, false))
+		}
+		lanes = slices.Delete(lanes, col, col+1)
+	}
+	return lines
+}
+
+// shiftLine draws lane k of n lanes leaving the graph, the lanes to its
+// right moving one lane left. If join is set, lane k joins lane k-1.
+func shiftLine(n, k int, join bool) string {
+	line := []byte(strings.Repeat(" ", 2*n-1))
+	for i := 0; i < k; i++ {
+		line[2*i] = '|'
+	}
+	if join {
+		line[2*k-1] = '/'
+	}
+	for j := k + 1; j < n; j++ {
+		line[2*j-1] = '/'
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// graphLabel is the text after a snapshot in the graph.
+func graphLabel(desc *model.Descriptor) string {
+	note := desc.Note
+	if note == "" {
+		note = color.Dim("(no note)")
+	}
+	label := color.SnapshotID(desc.SnapshotID.ShortID()) + "  " +
+		color.Dim(displayTime(desc.CreatedAt)) + "  " +
+		color.Highlight(desc.WorktreeName) + "  " + note
+	if len(desc.Tags) > 0 {
+		tagColors := make([]string, len(desc.Tags))
+		for i, tag := range desc.Tags {
+			tagColors[i] = color.Tag(tag)
+		}
+		label += "  [" + strings.Join(tagColors, ",") + "]"
+	}
+	return label
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestGraphLines(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var descs []*model.Descriptor
+	add := func(id, worktree string, parent string, minutes int) {
+		desc := &model.Descriptor{
+			SnapshotID:   model.SnapshotID(id),
+			WorktreeName: worktree,
+			CreatedAt:    base.Add(time.Duration(minutes) * time.Minute),
+		}
+		if parent != "" {
+			p := model.SnapshotID(parent)
+			desc.ParentID = &p
+		}
+		descs = append(descs, desc)
+	}
+	// main: a1 <- b2 <- c5; exp forked from a1: x3 <- x4; y6 forked from
+	// b2; r7 is an unrelated root
+	add("a1", "main", "", 1)
+	add("b2", "main", "a1", 2)
+	add("x3", "exp", "a1", 3)
+	add("x4", "exp", "x3", 4)
+	add("c5", "main", "b2", 5)
+	add("y6", "try", "b2", 6)
+	add("r7", "import", "", 7)
+
+	ordered, edges := snapshot.LineageGraph(descs)
+	lines := graphLines(ordered, func(d *model.Descriptor) string { return string(d.SnapshotID) })
+	assert.Equal(t, []string{
+		"* r7",
+		"* y6",
+		"| * c5",
+		"|/",
+		"* b2",
+		"| * x4",
+		"| * x3",
+		"|/",
+		"* a1",
+	}, lines)
+
+	assert.Len(t, edges, 5)
+	assert.Contains(t, edges, snapshot.GraphEdge{SnapshotID: "x3", ParentID: "a1", Fork: true})
+	assert.Contains(t, edges, snapshot.GraphEdge{SnapshotID: "c5", ParentID: "b2"})
+}
+
+func TestShiftLine(t *testing.T) {
+	assert.Equal(t, "| |/", shiftLine(3, 2, true))
+	assert.Equal(t, "|/ /", shiftLine(3, 1, true))
+	assert.Equal(t, "|  /", shiftLine(3, 1, false))
+}
+
+func TestHistoryCommand_Graph(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "main work")
+	require.NoError(t, err)
+	_, err = executeCommand(createTestRootCmd(), "worktree", "fork", "base", "exp")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "worktrees", "exp")))
+	require.NoError(t, os.WriteFile("exp.txt", []byte("x"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "experiment")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "history", "--graph")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "* ")
+	assert.Contains(t, stdout, "|/")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "history", "--graph")
+	require.NoError(t, err)
+	var graph historyGraphOutput
+	require.NoError(t, json.Unmarshal([]byte(stdout), &graph))
+	require.Len(t, graph.Snapshots, 3)
+	assert.Equal(t, "experiment", graph.Snapshots[0].Note)
+	assert.Equal(t, "base", graph.Snapshots[2].Note)
+	require.Len(t, graph.Edges, 2)
+	assert.True(t, graph.Edges[0].Fork)
+	assert.False(t, graph.Edges[1].Fork)
+}
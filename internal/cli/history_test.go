@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseTimeBound("--since", "", now)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	got, err = parseTimeBound("--since", "2h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), got)
+
+	got, err = parseTimeBound("--since", "7d", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-7*24*time.Hour), got)
+
+	got, err = parseTimeBound("--until", "2026-03-01", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), got)
+
+	got, err = parseTimeBound("--until", "2026-03-01T08:30:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC), got.UTC())
+
+	for _, bad := range []string{"yesterday", "-2h", "2026-13-01"} {
+		_, err = parseTimeBound("--since", bad, now)
+		assert.ErrorContains(t, err, "invalid --since", bad)
+	}
+}
+
+func TestHistoryCommand_SinceUntil(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "second")
+	require.NoError(t, err)
+
+	history := func(args ...string) []*model.Descriptor {
+		t.Helper()
+		stdout, err := executeCommand(createTestRootCmd(), append([]string{"--json", "history"}, args...)...)
+		require.NoError(t, err)
+		var descs []*model.Descriptor
+		require.NoError(t, json.Unmarshal([]byte(stdout), &descs))
+		return descs
+	}
+
+	assert.Len(t, history("--since", "1h"), 2)
+	assert.Len(t, history("--since", "2000-01-01", "--until", "1h"), 0)
+	assert.Len(t, history("--all", "--since", "1h"), 2)
+	assert.Len(t, history("--all", "--until", "2000-01-01T00:00:00Z"), 0)
+}
+
+func TestHistoryCommand_Author(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	t.Setenv(identity.EnvVar, "alice@build-1")
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "by alice")
+	require.NoError(t, err)
+	t.Setenv(identity.EnvVar, "sandbox-7")
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "by sandbox")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "history", "--author", "alice")
+	require.NoError(t, err)
+	var descs []*model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &descs))
+	require.Len(t, descs, 1)
+	assert.Equal(t, "by alice", descs[0].Note)
+	assert.Equal(t, "alice@build-1", descs[0].Author)
+
+	stdout, err = executeCommand(createTestRootCmd(), "history", "--all", "--author", "sandbox-7")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "by sandbox")
+	assert.NotContains(t, stdout, "by alice")
+
+	stdout, err = executeCommand(createTestRootCmd(), "show", "HEAD")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Author:       sandbox-7")
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+var (
+	importWorktree string
+	importIdentity []string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Add a snapshot from a bundle written by jvs export",
+	Long: `Add a snapshot from a bundle written by jvs export.
+
+The snapshot keeps the ID, descriptor and lineage it was exported with.
+Compressed and encrypted bundles are detected from their content; an
+encrypted bundle needs --identity with an age identity file holding one of
+its recipients' keys.
+
+The descriptor checksum and the SHA-256 of every payload file are checked
+against integrity.json, and the payload root hash against the descriptor,
+before the snapshot is published. A bundle that fails any check leaves
+the repository unchanged. A snapshot ID that already exists is refused.
+
+With --worktree, a new worktree is created from the imported snapshot, at
+HEAD state like jvs worktree fork.
+
+Use "-" to read the bundle from stdin.
+
+Examples:
+  jvs import v1.0.tar
+  jvs import workspace.tar.zst --worktree review
+  jvs import state.tar.age --identity ~/.config/age/key.txt
+  ssh build-host jvs export HEAD -o - | jvs import -`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		identities, err := readIdentityFiles(importIdentity)
+		if err != nil {
+			fmtErr("import: %v", err)
+			os.Exit(1)
+		}
+		if importWorktree != "" {
+			if err := pathutil.ValidateName(importWorktree); err != nil {
+				fmtErr("import: %v", err)
+				os.Exit(1)
+			}
+			if _, err := worktree.NewManager(r.Root).Get(importWorktree); err == nil {
+				fmtErr("import: worktree %s already exists", importWorktree)
+				os.Exit(1)
+			}
+		}
+
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmtErr("import: %v", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		result, err := bundle.Import(in, r.Root, bundle.ImportOptions{Identities: identities})
+		if err != nil {
+			fmtErr("import: %v", err)
+			os.Exit(1)
+		}
+		snapshotID := result.Descriptor.SnapshotID
+
+		mgr := quotaManager(r.Root)
+		var cfg *model.WorktreeConfig
+		if importWorktree != "" {
+			eng := engine.NewEngine(detectEngine(r.Root))
+			cfg, err = mgr.Fork(snapshotID, importWorktree, func(src, dst string) error {
+				_, err := eng.Clone(src, dst)
+				return err
+			})
+			if err != nil {
+				fmtErr("imported snapshot %s, but could not create worktree: %v", snapshotID, err)
+				os.Exit(1)
+			}
+		}
+
+		if jsonOutput {
+			outputJSON(struct {
+				*bundle.ImportResult
+				Worktree *model.WorktreeConfig `json:"worktree,omitempty"`
+			}{result, cfg})
+			return
+		}
+		fmt.Printf("%s snapshot %s (%d files, %s)\n",
+			color.Success("Imported"), color.SnapshotID(snapshotID.String()), result.Files, displaySize(result.Bytes))
+		if result.Descriptor.Note != "" {
+			fmt.Printf("  note: %s\n", result.Descriptor.Note)
+		}
+		if cfg != nil {
+			fmt.Printf("Created worktree '%s' at %s\n", color.Success(cfg.Name), color.Dim(mgr.Path(cfg.Name)))
+		}
+	},
+}
+
+// readIdentityFiles parses age identity files, as written by age-keygen.
+func readIdentityFiles(paths []string) ([]age.Identity, error) {
+	var identities []age.Identity
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("read identity file: %w", err)
+		}
+		ids, err := age.ParseIdentities(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parse identity file %s: %w", path, err)
+		}
+		identities = append(identities, ids...)
+	}
+	return identities, nil
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importWorktree, "worktree", "", "create a worktree with this name from the imported snapshot")
+	importCmd.Flags().StringArrayVarP(&importIdentity, "identity", "i", nil, "age identity file for an encrypted bundle (repeatable)")
+	rootCmd.AddCommand(importCmd)
+}
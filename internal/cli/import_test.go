@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestImportCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	_, err = executeCommand(createTestRootCmd(), "init", "other")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("a.txt", []byte("secret"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# test key\n"+identity.String()+"\n"), 0600))
+	bundlePath := filepath.Join(dir, "base.tar.zst.age")
+	_, err = executeCommand(createTestRootCmd(), "export", "base", "-o", bundlePath,
+		"--encrypt-to", identity.Recipient().String())
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chdir(filepath.Join(dir, "other", "main")))
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "import", bundlePath,
+		"--identity", keyFile, "--worktree", "review")
+	require.NoError(t, err)
+	var result struct {
+		Descriptor struct {
+			SnapshotID string `json:"snapshot_id"`
+			Note       string `json:"note"`
+		} `json:"descriptor"`
+		Files      int  `json:"files"`
+		Compressed bool `json:"compressed"`
+		Encrypted  bool `json:"encrypted"`
+		Worktree   struct {
+			Name           string `json:"name"`
+			HeadSnapshotID string `json:"head_snapshot_id"`
+		} `json:"worktree"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, "base", result.Descriptor.Note)
+	assert.Equal(t, 1, result.Files)
+	assert.True(t, result.Compressed)
+	assert.True(t, result.Encrypted)
+	assert.Equal(t, "review", result.Worktree.Name)
+	assert.Equal(t, result.Descriptor.SnapshotID, result.Worktree.HeadSnapshotID)
+
+	data, err := os.ReadFile(filepath.Join(dir, "other", "worktrees", "review", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+
+	require.NoError(t, snapshot.VerifySnapshot(filepath.Join(dir, "other"), model.SnapshotID(result.Descriptor.SnapshotID), true))
+}
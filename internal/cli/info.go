@@ -3,19 +3,53 @@ package cli
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jvs-project/jvs/internal/cas"
+	"github.com/jvs-project/jvs/internal/descstore"
 	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	infoWatch    bool
+	infoInterval time.Duration
+	infoEvents   int
 )
 
 var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Show repository information",
+	Long: `Show repository information.
+
+Worktrees that have not been snapshotted within the maximum age set under
+staleness in .jvs/config.yaml are listed as warnings (see jvs status).
+
+With --watch, shows a refreshing status page instead: worktree states and
+leases, snapshots and restores in progress, the repository lock queue,
+the most recent audit events and storage usage. With --json, --watch
+prints one status object per line at each refresh.
+
+Examples:
+  jvs info
+  jvs info --watch
+  jvs info --watch --interval 10s --events 20`,
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
 
+		if infoWatch {
+			watchInfo(r.Root)
+			return
+		}
+
 		// Count worktrees and snapshots
 		wtMgr := worktree.NewManager(r.Root)
 		wtList, _ := wtMgr.List()
@@ -24,16 +58,46 @@ var infoCmd = &cobra.Command{
 		entries, _ := os.ReadDir(snapshotsDir)
 		snapshotCount := len(entries)
 
+		descBackend, _ := descstore.CurrentBackend(r.Root)
+
 		eng, _ := engine.DetectEngine(r.Root)
 		snapshotEngine := string(eng.Name())
 
+		var budgetUsage []model.BudgetUsage
+		var contentStore *contentStoreUsage
+		var stale []*model.Staleness
+		if cfg, err := config.Load(r.Root); err == nil {
+			stale = staleWorktrees(r.Root, wtList, cfg.GetStaleness())
+			budgetUsage, err = gc.NewCollector(r.Root).BudgetUsage(cfg.GetRetentionPolicy().Budgets)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: compute budget usage: %v\n", err)
+			}
+			if cfg.ContentStore {
+				blobs, bytes, err := cas.NewStore(r.Root).Stats()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "warning: content store stats: %v\n", err)
+				}
+				contentStore = &contentStoreUsage{Blobs: blobs, Bytes: bytes}
+			}
+		}
+
 		info := map[string]any{
-			"repo_root":       r.Root,
-			"repo_id":         r.RepoID,
-			"format_version":  r.FormatVersion,
-			"snapshot_engine": snapshotEngine,
-			"total_worktrees": len(wtList),
-			"total_snapshots": snapshotCount,
+			"repo_root":        r.Root,
+			"repo_id":          r.RepoID,
+			"format_version":   r.FormatVersion,
+			"snapshot_engine":  snapshotEngine,
+			"total_worktrees":  len(wtList),
+			"total_snapshots":  snapshotCount,
+			"descriptor_store": string(descBackend),
+		}
+		if len(budgetUsage) > 0 {
+			info["budget_usage"] = budgetUsage
+		}
+		if contentStore != nil {
+			info["content_store"] = contentStore
+		}
+		if len(stale) > 0 {
+			info["stale_worktrees"] = stale
 		}
 
 		if jsonOutput {
@@ -45,11 +109,48 @@ var infoCmd = &cobra.Command{
 		fmt.Printf("  Repo ID: %s\n", r.RepoID)
 		fmt.Printf("  Format version: %d\n", r.FormatVersion)
 		fmt.Printf("  Snapshot engine: %s\n", snapshotEngine)
+		fmt.Printf("  Descriptor store: %s\n", descBackend)
 		fmt.Printf("  Worktrees: %d\n", len(wtList))
 		fmt.Printf("  Snapshots: %d\n", snapshotCount)
+		if contentStore != nil {
+			fmt.Printf("  Content store: %d blobs, %s\n", contentStore.Blobs, displaySize(contentStore.Bytes))
+		}
+		if len(budgetUsage) > 0 {
+			fmt.Println("  Tag budgets:")
+			printBudgetUsage(budgetUsage, "    ", false)
+		}
+		for _, st := range stale {
+			fmt.Println(color.Warning("warning: " + st.Message()))
+		}
 	},
 }
 
+// staleWorktrees returns the worktrees of wts that are stale under policy.
+func staleWorktrees(repoRoot string, wts []*model.WorktreeConfig, policy model.StalenessPolicy) []*model.Staleness {
+	var stale []*model.Staleness
+	now := time.Now()
+	for _, cfg := range wts {
+		st, err := snapshot.CheckStale(repoRoot, cfg, policy, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: check staleness of %s: %v\n", cfg.Name, err)
+			continue
+		}
+		if st != nil {
+			stale = append(stale, st)
+		}
+	}
+	return stale
+}
+
+// contentStoreUsage is the size of the repository content store.
+type contentStoreUsage struct {
+	Blobs int   `json:"blobs"`
+	Bytes int64 `json:"bytes"`
+}
+
 func init() {
+	infoCmd.Flags().BoolVarP(&infoWatch, "watch", "w", false, "show a refreshing status page")
+	infoCmd.Flags().DurationVar(&infoInterval, "interval", 2*time.Second, "refresh interval with --watch")
+	infoCmd.Flags().IntVar(&infoEvents, "events", jvs.DefaultStatusEvents, "number of recent audit events shown with --watch")
 	rootCmd.AddCommand(infoCmd)
 }
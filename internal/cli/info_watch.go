@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/jvs"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// watchInfo redraws the repository status every interval until interrupted.
+// With --json it prints one status object per line instead.
+func watchInfo(repoRoot string) {
+	client, err := jvs.Open(repoRoot)
+	if err != nil {
+		fmtErr("open repository: %v", err)
+		os.Exit(1)
+	}
+	if infoInterval <= 0 {
+		fmtErr("--interval must be positive")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(infoInterval)
+	defer ticker.Stop()
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		status, err := client.Status(ctx, jvs.StatusOptions{Events: infoEvents})
+		switch {
+		case jsonOutput && err == nil:
+			enc.Encode(status)
+		case jsonOutput:
+			fmtErr("status: %v", err)
+		default:
+			fmt.Print(clearScreen)
+			if err != nil {
+				fmt.Printf("%s %v\n", color.Error("status:"), err)
+			} else {
+				renderStatus(os.Stdout, repoRoot, status)
+			}
+			fmt.Println(color.Dim(fmt.Sprintf("\nRefreshing every %s; Ctrl-C to exit.", infoInterval)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderStatus writes the status dashboard.
+func renderStatus(w io.Writer, repoRoot string, st *jvs.RepoStatus) {
+	fmt.Fprintf(w, "%s  %s\n", color.Highlight(repoRoot), color.Dim(displayTime(st.CollectedAt)))
+	fmt.Fprintf(w, "Snapshots: %d (%s)  Worktrees: %d", st.Stats.Snapshots, displaySize(st.Stats.SnapshotBytes), st.Stats.Worktrees)
+	if !st.Stats.NewestSnapshot.IsZero() {
+		fmt.Fprintf(w, "  Newest: %s", displayTime(st.Stats.NewestSnapshot))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "\n"+color.Header("WORKTREES"))
+	for _, wt := range st.Worktrees {
+		head := "-"
+		if wt.HeadSnapshotID != "" {
+			head = color.SnapshotID(wt.HeadSnapshotID.ShortID())
+		}
+		state := "attached"
+		if wt.Detached {
+			state = color.Warning("detached")
+		}
+		if wt.Frozen {
+			state += ", frozen"
+		}
+		if wt.Stale != nil {
+			state += ", " + color.Warning("stale")
+		}
+		leased := ""
+		if wt.Lease != nil {
+			leased = fmt.Sprintf("leased by %s until %s", wt.Lease.Holder, displayTime(wt.Lease.ExpiresAt))
+		}
+		fmt.Fprintf(w, "  %-20s %-10s %-18s %s\n", wt.Name, head, state, leased)
+	}
+
+	fmt.Fprintln(w, "\n"+color.Header("ACTIVE OPERATIONS"))
+	if len(st.Operations) == 0 {
+		fmt.Fprintln(w, color.Dim("  none"))
+	}
+	for _, op := range st.Operations {
+		fmt.Fprintf(w, "  %-10s %-20s %-10s since %s\n", op.Operation, op.Worktree, color.SnapshotID(op.SnapshotID.ShortID()), displayTime(op.Since))
+	}
+
+	fmt.Fprintln(w, "\n"+color.Header("LOCK QUEUE"))
+	if len(st.LockQueue) == 0 {
+		fmt.Fprintln(w, color.Dim("  free"))
+	}
+	position := 0
+	for _, t := range st.LockQueue {
+		state, since := "held", t.QueuedAt
+		if t.Held() {
+			since = *t.AcquiredAt
+		} else {
+			position++
+			state = fmt.Sprintf("#%d", position)
+		}
+		fmt.Fprintf(w, "  %-6s %-10s %-12s pid %-8d since %s\n", state, t.Mode, t.Operation, t.PID, displayTime(since))
+	}
+
+	fmt.Fprintln(w, "\n"+color.Header("RECENT EVENTS"))
+	if len(st.Events) == 0 {
+		fmt.Fprintln(w, color.Dim("  none"))
+	}
+	for _, rec := range st.Events {
+		snap := "-"
+		if rec.SnapshotID != "" {
+			snap = color.SnapshotID(rec.SnapshotID.ShortID())
+		}
+		wt := rec.WorktreeName
+		if wt == "" {
+			wt = "-"
+		}
+		fmt.Fprintf(w, "  %s  %-20s %-12s %s\n", displayTime(rec.Timestamp), rec.EventType, wt, snap)
+	}
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestRenderStatus(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoPath := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoPath, "main")))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	client, err := jvs.Open(repoPath)
+	require.NoError(t, err)
+	status, err := client.Status(context.Background(), jvs.StatusOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	renderStatus(&buf, repoPath, status)
+	out := buf.String()
+	assert.Contains(t, out, "Snapshots: 1")
+	assert.Contains(t, out, "WORKTREES")
+	assert.Contains(t, out, "main")
+	assert.Contains(t, out, "LOCK QUEUE")
+	assert.Contains(t, out, "free")
+	assert.Contains(t, out, "snapshot_create")
+
+	l, err := repolock.NewManager(repoPath).Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	defer l.Release()
+	status, err = client.Status(context.Background(), jvs.StatusOptions{})
+	require.NoError(t, err)
+	buf.Reset()
+	renderStatus(&buf, repoPath, status)
+	assert.Contains(t, buf.String(), "held")
+	assert.Contains(t, buf.String(), "gc")
+}
@@ -7,11 +7,16 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/jvs-project/jvs/internal/integrity"
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/jvs-project/jvs/pkg/pathutil"
 )
 
+var initHash string
+
 var initCmd = &cobra.Command{
 	Use:   "init <name>",
 	Short: "Initialize a new JVS repository",
@@ -20,7 +25,12 @@ var initCmd = &cobra.Command{
 This creates:
   - .jvs/ directory with all metadata structures
   - main/ worktree as the primary payload directory
-  - format_version file (version 1)`,
+  - format_version file (version 1)
+
+--hash selects the payload hash algorithm of new snapshots: sha256 (the
+default) or blake3, which is much faster on large files such as model
+checkpoints. It is stored as hash_algorithm in .jvs/config.yaml, and each
+snapshot records the algorithm it was hashed with.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
@@ -30,6 +40,11 @@ This creates:
 			os.Exit(1)
 		}
 
+		if err := integrity.CheckHashAlgorithm(model.HashAlgorithm(initHash)); err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+
 		cwd, _ := os.Getwd()
 		repoPath := filepath.Join(cwd, name)
 
@@ -38,20 +53,31 @@ This creates:
 			fmtErr("failed to initialize repository: %v", err)
 			os.Exit(1)
 		}
+		cfg := config.Default()
+		if initHash != "" {
+			cfg.HashAlgorithm = initHash
+			if err := config.Save(r.Root, cfg); err != nil {
+				fmtErr("failed to initialize repository: %v", err)
+				os.Exit(1)
+			}
+		}
 
 		if jsonOutput {
 			outputJSON(map[string]any{
 				"repo_root":      r.Root,
 				"format_version": r.FormatVersion,
 				"repo_id":        r.RepoID,
+				"hash_algorithm": cfg.GetHashAlgorithm(),
 			})
 		} else {
 			fmt.Printf("Initialized JVS repository in %s\n", color.Success(repoPath))
 			fmt.Printf("  Main worktree: %s/main\n", color.Highlight(repoPath))
+			fmt.Printf("  Payload hash:  %s\n", cfg.GetHashAlgorithm())
 		}
 	},
 }
 
 func init() {
+	initCmd.Flags().StringVar(&initHash, "hash", "", "payload hash algorithm: sha256 (default) or blake3")
 	rootCmd.AddCommand(initCmd)
 }
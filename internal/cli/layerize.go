@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/layer"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	layerizeOutput string
+	layerizeParent string
+)
+
+var layerizeCmd = &cobra.Command{
+	Use:   "layerize <snapshot>",
+	Short: "Write a snapshot as an OCI image layer tarball",
+	Long: `Write a snapshot as an OCI image layer tarball.
+
+The layer is an uncompressed tar stream in lexical path order with ownership
+normalized to 0:0, so identical payloads produce identical layers. With
+--parent, only entries added or changed since the parent snapshot are
+written, and removed entries become OCI whiteout files.
+
+The layer's diff_id (the sha256 of the tar stream) is printed for use in an
+image config. Use "-o -" to write to stdout.
+
+Examples:
+  jvs layerize v1.0 -o layer.tar
+  jvs layerize HEAD --parent v1.0 -o delta.tar
+  jvs layerize HEAD -o - | gzip > layer.tar.gz`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		if layerizeOutput == "" {
+			fmtErr("--output is required")
+			os.Exit(1)
+		}
+		snapshotID, err := resolveSnapshot(r.Root, args[0])
+		if err != nil {
+			fmtErr("resolve snapshot: %v", err)
+			os.Exit(1)
+		}
+		var parentID model.SnapshotID
+		if layerizeParent != "" {
+			if parentID, err = resolveSnapshot(r.Root, layerizeParent); err != nil {
+				fmtErr("resolve parent: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		if layerizeOutput == "-" {
+			if _, err := layer.WriteSnapshot(r.Root, snapshotID, parentID, os.Stdout); err != nil {
+				fmtErr("layerize: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		result, err := writeLayerFile(r.Root, snapshotID, parentID, layerizeOutput)
+		if err != nil {
+			fmtErr("layerize: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(result)
+			return
+		}
+		fmt.Printf("%s %s (%d entries, %d whiteouts, %s)\n",
+			color.Success("Wrote"), layerizeOutput, result.Entries, result.Whiteouts, displaySize(result.Bytes))
+		fmt.Printf("  diff_id: %s\n", result.DiffID)
+	},
+}
+
+// writeLayerFile writes the layer to a temporary file next to path and
+// renames it into place, so a failed run leaves no partial layer behind.
+func writeLayerFile(repoRoot string, snapshotID, parentID model.SnapshotID, path string) (*layer.Result, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("create output: %w", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	result, err := layer.WriteSnapshot(repoRoot, snapshotID, parentID, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sync output: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("close output: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return nil, fmt.Errorf("chmod output: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("rename output: %w", err)
+	}
+	return result, nil
+}
+
+func init() {
+	layerizeCmd.Flags().StringVarP(&layerizeOutput, "output", "o", "", "layer tarball path, or - for stdout (required)")
+	layerizeCmd.Flags().StringVar(&layerizeParent, "parent", "", "write only changes since this snapshot, with whiteouts")
+	rootCmd.AddCommand(layerizeCmd)
+}
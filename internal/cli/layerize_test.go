@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/layer"
+)
+
+func TestLayerizeCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	mainDir := filepath.Join(dir, "testrepo", "main")
+	require.NoError(t, os.Chdir(mainDir))
+
+	require.NoError(t, os.WriteFile("a.txt", []byte("a"), 0644))
+	require.NoError(t, os.WriteFile("b.txt", []byte("b"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+	require.NoError(t, os.Remove("b.txt"))
+	require.NoError(t, os.WriteFile("c.txt", []byte("c"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "next")
+	require.NoError(t, err)
+
+	full := filepath.Join(dir, "full.tar")
+	stdout, err := executeCommand(createTestRootCmd(), "layerize", "base", "-o", full)
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "2 entries, 0 whiteouts")
+	assert.Contains(t, stdout, "diff_id: sha256:")
+	assert.FileExists(t, full)
+
+	delta := filepath.Join(dir, "delta.tar")
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "layerize", "HEAD", "--parent", "base", "-o", delta)
+	require.NoError(t, err)
+	var result layer.Result
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, 1, result.Entries)
+	assert.Equal(t, 1, result.Whiteouts)
+	info, err := os.Stat(delta)
+	require.NoError(t, err)
+	assert.Equal(t, result.Bytes, info.Size())
+
+	// No temporary files are left next to the output
+	matches, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	assert.Empty(t, matches)
+}
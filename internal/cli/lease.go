@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	leaseHolder string
+	leaseTTL    time.Duration
+)
+
+var leaseCmd = &cobra.Command{
+	Use:   "lease",
+	Short: "Manage worktree leases",
+	Long: `Manage worktree leases.
+
+A lease records that a consumer (for example an agent pod) is attached to a
+worktree. While a lease is active, 'jvs restore' refuses to replace the
+worktree payload unless --force is given. Leases expire after their TTL, so a
+crashed holder cannot block the worktree forever.`,
+}
+
+var leaseAcquireCmd = &cobra.Command{
+	Use:   "acquire [<worktree>]",
+	Short: "Acquire or renew a lease on a worktree",
+	Long: `Acquire or renew a lease on a worktree.
+
+If no worktree is specified, uses the current worktree. Acquiring a lease
+already held by the same holder renews it.
+
+Examples:
+  jvs lease acquire main --holder agent-pod-1 --ttl 1h`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r, name := leaseTarget(args)
+
+		if leaseHolder == "" {
+			fmtErr("--holder is required")
+			os.Exit(1)
+		}
+		if _, err := worktree.NewManager(r).Get(name); err != nil {
+			fmtErr("worktree: %v", err)
+			os.Exit(1)
+		}
+
+		l, err := lease.NewManager(r).Acquire(name, leaseHolder, leaseTTL)
+		if err != nil {
+			fmtErr("acquire lease: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(l)
+			return
+		}
+		fmt.Printf("Leased worktree %s to %s (expires %s)\n",
+			color.Highlight(name), l.Holder, displayTime(l.ExpiresAt))
+	},
+}
+
+var leaseReleaseCmd = &cobra.Command{
+	Use:   "release [<worktree>]",
+	Short: "Release a lease on a worktree",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r, name := leaseTarget(args)
+
+		if leaseHolder == "" {
+			fmtErr("--holder is required")
+			os.Exit(1)
+		}
+		if err := lease.NewManager(r).Release(name, leaseHolder); err != nil {
+			fmtErr("release lease: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(map[string]string{"worktree": name, "status": "released"})
+			return
+		}
+		fmt.Printf("Released lease on worktree %s\n", color.Highlight(name))
+	},
+}
+
+var leaseListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List active leases",
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		leases, err := lease.NewManager(r.Root).List()
+		if err != nil {
+			fmtErr("list leases: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			if leases == nil {
+				leases = []*model.Lease{}
+			}
+			outputJSON(leases)
+			return
+		}
+		if len(leases) == 0 {
+			fmt.Println("No active leases.")
+			return
+		}
+		fmt.Printf("%-20s %-24s %-20s %s\n", "WORKTREE", "HOLDER", "HOST", "EXPIRES")
+		for _, l := range leases {
+			fmt.Printf("%-20s %-24s %-20s %s\n", l.WorktreeName, l.Holder, l.Host, displayTime(l.ExpiresAt))
+		}
+	},
+}
+
+// leaseTarget returns the repo root and the worktree named in args,
+// falling back to the worktree containing the current directory.
+func leaseTarget(args []string) (string, string) {
+	if len(args) > 0 {
+		return requireRepo().Root, args[0]
+	}
+	r, name := requireWorktree()
+	return r.Root, name
+}
+
+func init() {
+	leaseAcquireCmd.Flags().StringVar(&leaseHolder, "holder", "", "identity of the lease holder (e.g. pod name)")
+	leaseAcquireCmd.Flags().DurationVar(&leaseTTL, "ttl", time.Hour, "lease duration")
+	leaseReleaseCmd.Flags().StringVar(&leaseHolder, "holder", "", "identity of the lease holder")
+	leaseCmd.AddCommand(leaseAcquireCmd)
+	leaseCmd.AddCommand(leaseReleaseCmd)
+	leaseCmd.AddCommand(leaseListCmd)
+	rootCmd.AddCommand(leaseCmd)
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestLeaseCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	mainPath := filepath.Join(dir, "testrepo", "main")
+	require.NoError(t, os.Chdir(mainPath))
+
+	stdout, err := executeCommand(createTestRootCmd(), "lease", "list")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "No active leases")
+
+	stdout, err = executeCommand(createTestRootCmd(), "lease", "acquire", "--holder", "agent-pod-1", "--ttl", "10m")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "agent-pod-1")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "lease", "list")
+	require.NoError(t, err)
+	var leases []model.Lease
+	require.NoError(t, json.Unmarshal([]byte(stdout), &leases))
+	require.Len(t, leases, 1)
+	assert.Equal(t, "main", leases[0].WorktreeName)
+	assert.Equal(t, "agent-pod-1", leases[0].Holder)
+
+	_, err = executeCommand(createTestRootCmd(), "lease", "release", "main", "--holder", "agent-pod-1")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "lease", "list")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "No active leases")
+}
+
+func TestRestoreCommand_ForceWhileLeased(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	mainPath := filepath.Join(dir, "testrepo", "main")
+	require.NoError(t, os.Chdir(mainPath))
+
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "second")
+	require.NoError(t, err)
+
+	_, err = executeCommand(createTestRootCmd(), "lease", "acquire", "--holder", "agent-pod-1")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "restore", "v1", "--force")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Restored")
+
+	// Restore swaps the payload directory; re-enter it
+	require.NoError(t, os.Chdir(mainPath))
+	content, err := os.ReadFile("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+}
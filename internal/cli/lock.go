@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Inspect the repository lock",
+	Long: `Inspect the repository lock.
+
+Snapshots and restores hold the repository lock shared; GC runs hold it
+exclusive. Waiters are served first come, first served: once a GC run is
+queued, snapshots that arrive after it wait until it finishes. An operation
+that waits longer than lock_max_wait (default 5m) fails with E_LOCK_TIMEOUT.
+
+With lock_lease_ttl set, for repositories shared by nodes that do not honour
+each other's flocks, tickets carry leases renewed by their processes, and a
+ticket whose lease expired is taken over.`,
+}
+
+var lockQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Show the holders of and waiters for the repository lock",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		tickets, err := repolock.NewManager(r.Root).Queue()
+		if err != nil {
+			fmtErr("lock queue: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			if tickets == nil {
+				tickets = []*model.LockTicket{}
+			}
+			outputJSON(tickets)
+			return
+		}
+		if len(tickets) == 0 {
+			fmt.Println("Repository lock is free.")
+			return
+		}
+		fmt.Printf("%-8s %-10s %-12s %-8s %-20s %s\n", "STATE", "MODE", "OPERATION", "PID", "HOST", "SINCE")
+		position := 0
+		for _, t := range tickets {
+			state, since := "held", t.QueuedAt
+			if t.Held() {
+				since = *t.AcquiredAt
+			} else {
+				position++
+				state = fmt.Sprintf("#%d", position)
+			}
+			fmt.Printf("%-8s %-10s %-12s %-8d %-20s %s\n", state, t.Mode, t.Operation, t.PID, t.Host, displayTime(since))
+		}
+	},
+}
+
+func init() {
+	lockCmd.AddCommand(lockQueueCmd)
+	rootCmd.AddCommand(lockCmd)
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestLockQueueCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoPath := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(repoPath))
+
+	stdout, err := executeCommand(createTestRootCmd(), "lock", "queue")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Repository lock is free")
+
+	l, err := repolock.NewManager(repoPath).Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+	defer l.Release()
+
+	stdout, err = executeCommand(createTestRootCmd(), "lock", "queue")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "held")
+	assert.Contains(t, stdout, "snapshot")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "lock", "queue")
+	require.NoError(t, err)
+	var tickets []model.LockTicket
+	require.NoError(t, json.Unmarshal([]byte(stdout), &tickets))
+	require.Len(t, tickets, 1)
+	assert.Equal(t, model.LockShared, tickets[0].Mode)
+	assert.True(t, tickets[0].Held())
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/mount"
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+var mountAllowOther bool
+
+var mountCmd = &cobra.Command{
+	Use:   "mount <mountpoint>",
+	Short: "Mount snapshot history as a read-only filesystem",
+	Long: `Mount snapshot history as a read-only filesystem.
+
+Every snapshot is shown as a directory at /<worktree>/<snapshot-id>/ under
+the mountpoint, so old files can be browsed, compared and copied without
+restoring. Snapshots created while mounted appear, and snapshots deleted by
+GC disappear. Compressed snapshots are shown decompressed; encrypted
+snapshots are listed but cannot be opened.
+
+Runs in the foreground until interrupted, then unmounts. Requires FUSE
+(Linux, or macFUSE on macOS).
+
+Examples:
+  jvs mount /mnt/jvs
+  ls /mnt/jvs/main/
+  cp /mnt/jvs/main/1771589-abcdef12/config.yaml .
+  jvs mount /mnt/jvs --allow-other  # needs user_allow_other in /etc/fuse.conf`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		server, err := mount.Mount(r.Root, args[0], mount.Options{
+			AllowOther: mountAllowOther,
+			Debug:      debugOutput,
+		})
+		if err != nil {
+			fmtErr("mount: %v", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			if err := server.Unmount(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: unmount: %v\n", err)
+			}
+		}()
+
+		fmt.Printf("Mounted snapshots of %s at %s (read-only). Press Ctrl-C to unmount.\n",
+			color.Highlight(r.Root), color.Highlight(args[0]))
+		server.Wait()
+	},
+}
+
+func init() {
+	mountCmd.Flags().BoolVar(&mountAllowOther, "allow-other", false, "let other users access the mount")
+	rootCmd.AddCommand(mountCmd)
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+var peekTreeDepth int
+
+var peekCmd = &cobra.Command{
+	Use:   "peek <snapshot> [path]",
+	Short: "List a snapshot's contents without restoring it",
+	Long: `List a snapshot's contents without restoring it.
+
+Shows the top level of the snapshot payload, or of a directory inside it,
+with the size and file count of each subdirectory. Sizes are uncompressed
+sizes, also for compressed snapshots. Use --tree to descend more levels.
+
+The snapshot can be a full ID, short ID prefix, tag name, or HEAD.
+
+Examples:
+  jvs peek HEAD
+  jvs peek v1.0 src
+  jvs peek 1771589 --tree 3
+  jvs peek v1.0 --json`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		snapshotID, err := resolveSnapshot(r.Root, args[0])
+		if err != nil {
+			fmtErr("resolve snapshot: %v", err)
+			os.Exit(1)
+		}
+		relPath := ""
+		if len(args) > 1 {
+			relPath = args[1]
+		}
+
+		entry, err := snapshot.Peek(r.Root, snapshotID, relPath, peekTreeDepth)
+		if err != nil {
+			fmtErr("peek: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(entry)
+			return
+		}
+
+		fmt.Printf("%s %s:%s\n", color.Header("Snapshot"), color.SnapshotID(snapshotID.String()), entry.Path)
+		if entry.Type != snapshot.PeekDir {
+			printPeekEntry(entry, 0)
+			return
+		}
+		for _, child := range entry.Children {
+			printPeekEntry(child, 0)
+		}
+		fmt.Printf("%s %s, %s\n", color.Dim("Total:"), pluralFiles(entry.Files), displaySize(entry.Size))
+	},
+}
+
+func printPeekEntry(e *snapshot.PeekEntry, level int) {
+	indent := strings.Repeat("  ", level+1)
+	switch e.Type {
+	case snapshot.PeekDir:
+		name := color.Highlight(e.Name + "/")
+		fmt.Printf("%s%-*s  %10s  %s\n", indent, peekNameWidth(level, name, e.Name+"/"), name, displaySize(e.Size), color.Dim(pluralFiles(e.Files)))
+		for _, child := range e.Children {
+			printPeekEntry(child, level+1)
+		}
+	case snapshot.PeekSymlink:
+		fmt.Printf("%s%s -> %s\n", indent, e.Name, color.Dim(e.Target))
+	default:
+		fmt.Printf("%s%-*s  %10s\n", indent, peekNameWidth(level, e.Name, e.Name), e.Name, displaySize(e.Size))
+	}
+}
+
+// peekNameWidth pads names to a common column, allowing for the invisible
+// bytes of color codes in colored.
+func peekNameWidth(level int, colored, plain string) int {
+	width := 40 - 2*level
+	if width < len(plain) {
+		width = len(plain)
+	}
+	return width + len(colored) - len(plain)
+}
+
+func pluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+func init() {
+	peekCmd.Flags().IntVar(&peekTreeDepth, "tree", 1, "directory levels to list")
+	rootCmd.AddCommand(peekCmd)
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+)
+
+func TestPeekCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	require.NoError(t, os.MkdirAll(filepath.Join("src", "lib"), 0755))
+	require.NoError(t, os.WriteFile("a.txt", []byte("abc"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join("src", "lib", "b.go"), []byte("package lib"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "peek", "v1")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "src/")
+	assert.Contains(t, stdout, "a.txt")
+	assert.NotContains(t, stdout, "lib/")
+	assert.Contains(t, stdout, "2 files")
+
+	stdout, err = executeCommand(createTestRootCmd(), "peek", "v1", "--tree", "2")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "lib/")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "peek", "HEAD", "src")
+	require.NoError(t, err)
+	var entry snapshot.PeekEntry
+	require.NoError(t, json.Unmarshal([]byte(stdout), &entry))
+	assert.Equal(t, "src", entry.Path)
+	assert.Equal(t, 1, entry.Files)
+	require.Len(t, entry.Children, 1)
+	assert.Equal(t, "lib", entry.Children[0].Name)
+}
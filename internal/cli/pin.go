@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/pin"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	pinReason  string
+	pinExpires string
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <snapshot>",
+	Short: "Protect a snapshot from garbage collection",
+	Long: `Protect a snapshot from garbage collection.
+
+GC never deletes a pinned snapshot, whatever the retention policy. Pins are
+stored in .jvs/pins; pinning a snapshot again replaces its pin. With
+--expires the pin stops protecting the snapshot after the given duration.
+
+Examples:
+  jvs pin v1.0 --reason "release build"
+  jvs pin 1771589366482-abc12345 --expires 30d
+  jvs pin list`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		var ttl time.Duration
+		if pinExpires != "" {
+			d, err := config.ParseDuration(pinExpires)
+			if err != nil || d <= 0 {
+				fmtErr("invalid --expires %q: must be a positive duration such as 30d", pinExpires)
+				os.Exit(1)
+			}
+			ttl = d
+		}
+		snapshotID := resolveSnapshotIDOrExit(r.Root, args[0])
+
+		p, err := pin.NewManager(r.Root).Pin(snapshotID, pinReason, ttl)
+		if err != nil {
+			fmtErr("pin: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(p)
+			return
+		}
+		if p.ExpiresAt != nil {
+			fmt.Printf("Pinned snapshot %s (expires %s)\n", color.SnapshotID(snapshotID.String()), displayTime(*p.ExpiresAt))
+			return
+		}
+		fmt.Printf("Pinned snapshot %s\n", color.SnapshotID(snapshotID.String()))
+	},
+}
+
+var pinListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pins",
+	Long: `List pins, oldest first.
+
+Expired pins are listed too, marked as expired: they no longer protect their
+snapshot, but stay until removed with 'jvs unpin'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		pins, err := pin.NewManager(r.Root).List()
+		if err != nil {
+			fmtErr("list pins: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			if pins == nil {
+				pins = []*model.Pin{}
+			}
+			outputJSON(pins)
+			return
+		}
+		if len(pins) == 0 {
+			fmt.Println("No pins.")
+			return
+		}
+		now := time.Now()
+		fmt.Printf("%-28s %-20s %-20s %s\n", "SNAPSHOT", "PINNED", "EXPIRES", "REASON")
+		for _, p := range pins {
+			expires := "never"
+			if p.ExpiresAt != nil {
+				expires = displayTime(*p.ExpiresAt)
+				if p.Expired(now) {
+					expires = "expired"
+				}
+			}
+			fmt.Printf("%-28s %-20s %-20s %s\n", p.SnapshotID, displayTime(p.PinnedAt), expires, p.Reason)
+		}
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <snapshot>",
+	Short: "Remove the pins of a snapshot",
+	Long: `Remove the pins of a snapshot, expired ones included.
+
+The snapshot may be a full ID, an ID prefix or a tag. A full ID is accepted
+even if the snapshot no longer exists, to remove stale pins.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		snapshotID, err := resolveSnapshotID(r.Root, args[0])
+		if err != nil {
+			snapshotID = model.SnapshotID(args[0])
+		}
+
+		removed, err := pin.NewManager(r.Root).Unpin(snapshotID)
+		if err != nil {
+			fmtErr("unpin: %v", err)
+			os.Exit(1)
+		}
+		if removed == 0 {
+			fmtErr("snapshot %s is not pinned", snapshotID)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(map[string]any{"snapshot_id": snapshotID, "removed": removed})
+			return
+		}
+		fmt.Printf("Unpinned snapshot %s\n", color.SnapshotID(snapshotID.String()))
+	},
+}
+
+func init() {
+	pinCmd.Flags().StringVar(&pinReason, "reason", "", "why the snapshot is pinned")
+	pinCmd.Flags().StringVar(&pinExpires, "expires", "", "unpin after this duration (e.g. 30d, 12h); default never")
+	pinCmd.AddCommand(pinListCmd)
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+}
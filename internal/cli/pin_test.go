@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestPinCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "pin", "list")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "No pins")
+
+	stdout, err = executeCommand(createTestRootCmd(), "pin", "v1", "--reason", "release build", "--expires", "30d")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Pinned snapshot")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "pin", "list")
+	require.NoError(t, err)
+	var pins []model.Pin
+	require.NoError(t, json.Unmarshal([]byte(stdout), &pins))
+	require.Len(t, pins, 1)
+	assert.Equal(t, "release build", pins[0].Reason)
+	require.NotNil(t, pins[0].ExpiresAt)
+
+	_, err = executeCommand(createTestRootCmd(), "unpin", "v1")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "pin", "list")
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", stdout)
+}
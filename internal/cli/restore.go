@@ -16,11 +16,15 @@ import (
 )
 
 var (
-	restoreInteractive bool
+	restoreInteractive     bool
+	restoreForce           bool
+	restoreLatestBeforeTag string
+	restoreLatestWithTag   string
+	restorePaths           []string
 )
 
 var restoreCmd = &cobra.Command{
-	Use:   "restore <snapshot-id>",
+	Use:   "restore <snapshot-id> | --latest-before-tag <tag> | --latest-with-tag <tag>",
 	Short: "Restore worktree to a historical snapshot",
 	Long: `Restore worktree to a historical snapshot.
 
@@ -42,17 +46,71 @@ Examples:
   jvs restore 1771589abc              # Restore by short ID
   jvs restore v1.0                     # Restore by tag
   jvs restore HEAD                     # Return to latest (exit detached)
-  jvs restore -i 177                   # Interactive mode with fuzzy match`,
-	Args: cobra.ExactArgs(1),
+  jvs restore -i 177                   # Interactive mode with fuzzy match
+  jvs restore v1.0 --force             # Restore even if the worktree is leased
+  jvs restore --latest-before-tag experiment  # Newest snapshot not tagged experiment
+  jvs restore --latest-with-tag stable        # Newest snapshot tagged stable
+  jvs restore v1.0 --paths config,data/model.bin  # Restore two paths only
+
+--latest-before-tag and --latest-with-tag consider only snapshots of the
+current worktree and take the place of <snapshot-id>.
+
+Restore is refused while another consumer holds a lease on the worktree
+(see 'jvs lease list'). Use --force only when the holder is known to be gone.
+
+With --paths, only the named files and directories are replaced, read from
+the snapshot's per-file manifest and checked against it as they are copied.
+The rest of the worktree and its head are left as they are, so the worktree
+does not become detached. Snapshots created before manifests were written
+must be restored whole.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		r, wtName := requireWorktree()
+
+		if restoreLatestBeforeTag != "" || restoreLatestWithTag != "" {
+			if len(args) > 0 || (restoreLatestBeforeTag != "" && restoreLatestWithTag != "") {
+				fmtErr("--latest-before-tag and --latest-with-tag cannot be combined with each other or a snapshot argument")
+				os.Exit(1)
+			}
+			var desc *model.Descriptor
+			var err error
+			if restoreLatestBeforeTag != "" {
+				desc, err = snapshot.LatestWithoutTag(r.Root, wtName, restoreLatestBeforeTag)
+			} else {
+				desc, err = snapshot.LatestWithTag(r.Root, wtName, restoreLatestWithTag)
+			}
+			if err != nil {
+				fmtErr("resolve snapshot: %v", err)
+				os.Exit(1)
+			}
+			runRestore(r.Root, wtName, desc.SnapshotID)
+			return
+		}
+		if len(args) == 0 {
+			fmtErr("snapshot-id is required (or use --latest-before-tag / --latest-with-tag)")
+			os.Exit(1)
+		}
 		snapshotArg := args[0]
 
 		var snapshotID model.SnapshotID
 
 		// Handle special "HEAD" case
+		if snapshotArg == "HEAD" && len(restorePaths) > 0 {
+			cfg, err := worktree.NewManager(r.Root).Get(wtName)
+			if err != nil {
+				fmtErr("get worktree: %v", err)
+				os.Exit(1)
+			}
+			if cfg.LatestSnapshotID == "" {
+				fmtErr("worktree has no snapshots")
+				os.Exit(1)
+			}
+			runRestore(r.Root, wtName, cfg.LatestSnapshotID)
+			return
+		}
 		if snapshotArg == "HEAD" {
 			restorer := restore.NewRestorer(r.Root, detectEngine(r.Root))
+			restorer.SetForce(restoreForce)
 			if err := restorer.RestoreToLatest(wtName); err != nil {
 				fmtErr("restore to latest: %v", err)
 				os.Exit(1)
@@ -146,40 +204,63 @@ Examples:
 			}
 		}
 
-		// Perform restore
-		restorer := restore.NewRestorer(r.Root, detectEngine(r.Root))
-		if err := restorer.Restore(wtName, snapshotID); err != nil {
-			fmtErr("restore: %v", err)
-			os.Exit(1)
-		}
+		runRestore(r.Root, wtName, snapshotID)
+	},
+}
 
-		// Check if we're now detached
-		wtMgr := worktree.NewManager(r.Root)
-		cfg, _ := wtMgr.Get(wtName)
-		isDetached := cfg.IsDetached()
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreInteractive, "interactive", "i", false, "interactive mode with fuzzy matching and confirmation")
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "restore even if the worktree has an active lease")
+	restoreCmd.Flags().StringVar(&restoreLatestBeforeTag, "latest-before-tag", "", "restore the newest snapshot without this tag")
+	restoreCmd.Flags().StringVar(&restoreLatestWithTag, "latest-with-tag", "", "restore the newest snapshot with this tag")
+	restoreCmd.Flags().StringSliceVar(&restorePaths, "paths", nil, "restore only these payload paths, using the snapshot's manifest")
+	rootCmd.AddCommand(restoreCmd)
+}
+
+// runRestore restores wtName to snapshotID and reports the result.
+func runRestore(repoRoot, wtName string, snapshotID model.SnapshotID) {
+	restorer := restore.NewRestorer(repoRoot, detectEngine(repoRoot))
+	restorer.SetForce(restoreForce)
+	restorer.SetPaths(restorePaths)
+	if err := restorer.Restore(wtName, snapshotID); err != nil {
+		fmtErr("restore: %v", err)
+		os.Exit(1)
+	}
 
+	if len(restorePaths) > 0 {
 		if jsonOutput {
 			outputJSON(map[string]interface{}{
 				"status":      "restored",
 				"snapshot_id": string(snapshotID),
-				"detached":    isDetached,
+				"paths":       restorePaths,
 			})
 		} else {
-			fmt.Printf("\nRestored to snapshot %s\n", color.SnapshotID(snapshotID.String()))
-			if isDetached {
-				fmt.Println(color.Warning("Worktree is now in DETACHED state."))
-				fmt.Println(color.Dim("To continue working from here: jvs worktree fork <name>"))
-				fmt.Println(color.Dim("To return to latest: jvs restore HEAD"))
-			} else {
-				fmt.Println(color.Success("Worktree is now at HEAD state."))
-			}
+			fmt.Printf("\nRestored %s from snapshot %s\n", strings.Join(restorePaths, ", "), color.SnapshotID(snapshotID.String()))
 		}
-	},
-}
+		return
+	}
 
-func init() {
-	restoreCmd.Flags().BoolVarP(&restoreInteractive, "interactive", "i", false, "interactive mode with fuzzy matching and confirmation")
-	rootCmd.AddCommand(restoreCmd)
+	// Check if we're now detached
+	wtMgr := worktree.NewManager(repoRoot)
+	cfg, _ := wtMgr.Get(wtName)
+	isDetached := cfg.IsDetached()
+
+	if jsonOutput {
+		outputJSON(map[string]interface{}{
+			"status":      "restored",
+			"snapshot_id": string(snapshotID),
+			"detached":    isDetached,
+		})
+	} else {
+		fmt.Printf("\nRestored to snapshot %s\n", color.SnapshotID(snapshotID.String()))
+		if isDetached {
+			fmt.Println(color.Warning("Worktree is now in DETACHED state."))
+			fmt.Println(color.Dim("To continue working from here: jvs worktree fork <name>"))
+			fmt.Println(color.Dim("To return to latest: jvs restore HEAD"))
+		} else {
+			fmt.Println(color.Success("Worktree is now at HEAD state."))
+		}
+	}
 }
 
 // confirm prompts the user for yes/no confirmation.
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestRestoreCommand_LatestByTag(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	mainPath := filepath.Join(dir, "testrepo", "main")
+	require.NoError(t, os.Chdir(mainPath))
+
+	for _, step := range []struct{ content, tag string }{
+		{"stable", "stable"},
+		{"exp-1", "experiment"},
+		{"exp-2", "experiment"},
+	} {
+		require.NoError(t, os.WriteFile("file.txt", []byte(step.content), 0644))
+		_, err = executeCommand(createTestRootCmd(), "snapshot", step.content, "--tag", step.tag)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stdout, err := executeCommand(createTestRootCmd(), "restore", "--latest-before-tag", "experiment")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Restored")
+	require.NoError(t, os.Chdir(mainPath))
+	content, err := os.ReadFile("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "stable", string(content))
+
+	_, err = executeCommand(createTestRootCmd(), "restore", "--latest-with-tag", "experiment")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(mainPath))
+	content, err = os.ReadFile("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "exp-2", string(content))
+}
+
+func TestRestoreCommand_Paths(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	require.NoError(t, os.WriteFile("a.txt", []byte("a1"), 0644))
+	require.NoError(t, os.WriteFile("b.txt", []byte("b1"), 0644))
+	stdout, err := executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1", "--json")
+	require.NoError(t, err)
+	var first model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &first))
+	require.NoError(t, os.WriteFile("a.txt", []byte("a2"), 0644))
+	require.NoError(t, os.WriteFile("b.txt", []byte("b2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "second")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "restore", "v1", "--paths", "a.txt")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Restored a.txt")
+	assert.NotContains(t, stdout, "DETACHED")
+	content, err := os.ReadFile("a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a1", string(content))
+	content, err = os.ReadFile("b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b2", string(content))
+
+	stdout, err = executeCommand(createTestRootCmd(), "verify", string(first.SnapshotID), "--paths", "a.txt")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Paths (a.txt): true")
+}
@@ -1,14 +1,20 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/format"
+	"github.com/jvs-project/jvs/internal/webhook"
 	"github.com/jvs-project/jvs/pkg/color"
 	"github.com/jvs-project/jvs/pkg/logging"
+	"github.com/jvs-project/jvs/pkg/uuidutil"
 )
 
 var (
@@ -16,6 +22,9 @@ var (
 	debugOutput bool
 	noProgress  bool
 	noColor     bool
+	isoTimes    bool
+	utcTimes    bool
+	exactBytes  bool
 	rootCmd     = &cobra.Command{
 		Use:   "jvs",
 		Short: "JVS - Juicy Versioned Workspaces",
@@ -41,16 +50,52 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&debugOutput, "debug", false, "enable debug logging")
 	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "disable progress bars")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output (also respects NO_COLOR env var)")
+	rootCmd.PersistentFlags().BoolVar(&isoTimes, "iso", false, "show times as ISO-8601 instead of relative")
+	rootCmd.PersistentFlags().BoolVar(&utcTimes, "utc", false, "show times as ISO-8601 in UTC")
+	rootCmd.PersistentFlags().BoolVar(&exactBytes, "bytes", false, "show sizes as exact byte counts")
 }
 
 // Execute runs the root command.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	// All audit records of one invocation share a correlation ID unless the
+	// caller supplied one.
+	if os.Getenv(audit.CorrelationEnvVar) == "" {
+		os.Setenv(audit.CorrelationEnvVar, uuidutil.NewV4())
+	}
+	err := rootCmd.Execute()
+	flushWebhooks()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 }
 
+// flushWebhooks gives the completion events the command queued up to
+// webhook.FlushTimeout to be delivered before the process exits.
+func flushWebhooks() {
+	ctx, cancel := context.WithTimeout(context.Background(), webhook.FlushTimeout)
+	defer cancel()
+	if err := webhook.Flush(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// displayOptions returns the time and size rendering selected by the
+// --iso, --utc and --bytes flags.
+func displayOptions() format.Options {
+	return format.Options{ISO: isoTimes, UTC: utcTimes, ExactBytes: exactBytes}
+}
+
+// displayTime renders t for human-readable output.
+func displayTime(t time.Time) string {
+	return displayOptions().Time(t, time.Now())
+}
+
+// displaySize renders a byte count for human-readable output.
+func displaySize(n int64) string {
+	return displayOptions().Size(n)
+}
+
 // progressEnabled returns whether progress bars should be shown.
 func progressEnabled() bool {
 	return !noProgress && !jsonOutput
@@ -2,13 +2,16 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/jvs"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -21,14 +24,21 @@ func executeCommand(root *cobra.Command, args ...string) (stdout string, err err
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
+	// Drain the pipe while the command runs: output larger than the pipe
+	// buffer, such as a completion script, would otherwise block it
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
 	root.SetArgs(args)
 	err = root.Execute()
 
 	w.Close()
 	os.Stdout = oldStdout
-
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
+	<-done
 	return buf.String(), err
 }
 
@@ -72,6 +82,24 @@ func TestInitCommand_CreatesRepo(t *testing.T) {
 	assert.NoError(t, statErr)
 }
 
+func TestInitCommand_HashBLAKE3(t *testing.T) {
+	setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo", "--hash", "blake3")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join("testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("weights"), 0644))
+
+	stdout, err := executeCommand(createTestRootCmd(), "snapshot", "first", "--json")
+	require.NoError(t, err)
+	var desc model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &desc))
+	assert.Equal(t, model.HashBLAKE3, desc.HashAlgorithm)
+
+	stdout, err = executeCommand(createTestRootCmd(), "verify", string(desc.SnapshotID))
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Payload hash: true")
+}
+
 func TestWorktreeCommand_List(t *testing.T) {
 	dir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -116,6 +144,27 @@ func TestInfoCommand_WithRepo(t *testing.T) {
 	os.Chdir(originalWd)
 }
 
+func TestVersionCommand(t *testing.T) {
+	dir := setupTestDir(t)
+
+	stdout, err := executeCommand(createTestRootCmd(), "version")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Repository formats:")
+	assert.NotContains(t, stdout, "This repository")
+
+	_, err = executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo")))
+	require.NoError(t, os.WriteFile(filepath.Join(".jvs", "format_version"), []byte("7\n"), 0600))
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "version")
+	require.NoError(t, err)
+	var info jvs.VersionInfo
+	require.NoError(t, json.Unmarshal([]byte(stdout), &info))
+	assert.Equal(t, 7, info.RepoFormatVersion)
+	assert.Equal(t, 1, info.FormatVersion)
+}
+
 func TestInfoCommand_JSON(t *testing.T) {
 	dir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -467,16 +516,98 @@ func createTestRootCmd() *cobra.Command {
 	jsonOutput = false
 	debugOutput = false
 	worktreeCreateFrom = ""
+	worktreeCreateFromDir = ""
 	worktreeForce = false
+	worktreeVerify = false
+	worktreeRetainAs = ""
+	worktreeLockOwner = ""
+	worktreeLockTTL = 0
 	historyLimit = 0
 	historyNoteFilter = ""
 	historyTagFilter = ""
 	historyAll = false
+	historyEnv = nil
+	historyGraph = false
+	historySince = ""
+	historyUntil = ""
+	historyAuthor = ""
 	snapshotTags = nil
 	snapshotPaths = nil
 	snapshotCompression = ""
+	snapshotFollowLinks = false
+	snapshotTwoPassHash = false
+	snapshotAnnotations = nil
+	snapshotSkipUnchanged = false
+	snapshotIncremental = false
+	snapshotExcludes = nil
+	snapshotCaptureEnv = false
+	snapshotOnReadError = ""
 	restoreInteractive = false
+	restoreForce = false
+	restoreLatestBeforeTag = ""
+	restoreLatestWithTag = ""
+	restorePaths = nil
+	verifyAll = false
+	verifyPaths = nil
+	verifyRepair = false
+	verifyReplicas = nil
+	initHash = ""
+	leaseHolder = ""
+	leaseTTL = time.Hour
+	pinReason = ""
+	pinExpires = ""
+	annotateAppend = false
+	searchLimit = 20
+	descriptorsMigrateTo = ""
+	auditCorrelation = ""
+	auditEventType = ""
+	auditWorktree = ""
+	auditLimit = 0
+	serveInterval = 2 * time.Second
+	serveMetrics = ""
+	serveGRPC = ""
+	serveHTTP = ""
+	fleetRoot = ""
+	fleetConcurrency = 4
+	fleetDepth = 3
+	fleetStrict = false
 	gcPlanID = ""
+	gcCheckConflicts = false
+	gcDaemonInterval = 6 * time.Hour
+	gcDaemonJitter = 10 * time.Minute
+	gcDaemonPolicy = ""
+	gcPruneOlderThan = "90d"
+	infoWatch = false
+	infoInterval = 2 * time.Second
+	infoEvents = 10
+	diffPatch = false
+	diffUnified = 3
+	diffMaxPatchBytes = 1 << 20
+	doctorFixDetached = ""
+	doctorFixAction = ""
+	doctorFixForkName = ""
+	doctorCheckPaths = false
+	doctorCI = false
+	doctorRepairRun = nil
+	showTimings = false
+	showMetadataHistory = false
+	mountAllowOther = false
+	fuzzOps = 1000
+	fuzzSeed = 0
+	fuzzKeep = false
+	examplesKeep = false
+	layerizeOutput = ""
+	layerizeParent = ""
+	gitExportRepo = ""
+	gitExportBranch = ""
+	gitExportAuthor = ""
+	gitExportLFSThreshold = ""
+	exportOutput = ""
+	exportEncryptTo = nil
+	exportZstd = false
+	importWorktree = ""
+	importIdentity = nil
+	peekTreeDepth = 1
 
 	// Create a new root command
 	cmd := &cobra.Command{
@@ -488,6 +619,9 @@ func createTestRootCmd() *cobra.Command {
 	}
 	cmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
 	cmd.PersistentFlags().BoolVar(&debugOutput, "debug", false, "enable debug logging")
+	cmd.PersistentFlags().BoolVar(&isoTimes, "iso", false, "show times as ISO-8601 instead of relative")
+	cmd.PersistentFlags().BoolVar(&utcTimes, "utc", false, "show times as ISO-8601 in UTC")
+	cmd.PersistentFlags().BoolVar(&exactBytes, "bytes", false, "show sizes as exact byte counts")
 
 	// Add all subcommands
 	cmd.AddCommand(initCmd)
@@ -503,6 +637,26 @@ func createTestRootCmd() *cobra.Command {
 	cmd.AddCommand(configCmd)
 	cmd.AddCommand(diffCmd)
 	cmd.AddCommand(conformanceCmd)
+	cmd.AddCommand(showCmd)
+	cmd.AddCommand(leaseCmd)
+	cmd.AddCommand(pinCmd)
+	cmd.AddCommand(unpinCmd)
+	cmd.AddCommand(tagCmd)
+	cmd.AddCommand(annotateCmd)
+	cmd.AddCommand(searchCmd)
+	cmd.AddCommand(lockCmd)
+	cmd.AddCommand(descriptorsCmd)
+	cmd.AddCommand(auditCmd)
+	cmd.AddCommand(serveCmd)
+	cmd.AddCommand(fleetCmd)
+	cmd.AddCommand(layerizeCmd)
+	cmd.AddCommand(gitExportCmd)
+	cmd.AddCommand(exportCmd)
+	cmd.AddCommand(importCmd)
+	cmd.AddCommand(peekCmd)
+	cmd.AddCommand(statusCmd)
+	cmd.AddCommand(versionCmd)
+	cmd.AddCommand(fsckCmd)
 
 	return cmd
 }
@@ -1081,7 +1235,27 @@ func TestConformanceListCommand(t *testing.T) {
 	// Verify conformanceCmd exists and is properly configured
 	assert.NotNil(t, conformanceCmd)
 	assert.Equal(t, "conformance", conformanceCmd.Use)
-	assert.Equal(t, 2, len(conformanceCmd.Commands()))
+	assert.Equal(t, 4, len(conformanceCmd.Commands()))
+}
+
+// TestConformanceFuzzCommand tests a short fuzz run through the CLI.
+func TestConformanceFuzzCommand(t *testing.T) {
+	setupTestDir(t)
+	cmd := createTestRootCmd()
+	stdout, err := executeCommand(cmd, "--json", "conformance", "fuzz", "--ops", "40", "--seed", "5")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, `"seed": 5`)
+	assert.Contains(t, stdout, `"executed": 40`)
+}
+
+// TestConformanceExamplesCommand runs the documented usage pattern through the CLI.
+func TestConformanceExamplesCommand(t *testing.T) {
+	setupTestDir(t)
+	stdout, err := executeCommand(createTestRootCmd(), "conformance", "examples")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "restoring an older snapshot detaches the worktree")
+	assert.Contains(t, stdout, "Documented examples hold.")
+	assert.NotContains(t, stdout, "FAIL")
 }
 
 // TestDetectEngine tests the detectEngine helper.
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var searchLimit int
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>...",
+	Short: "Find snapshots by note, tag or metadata",
+	Long: `Find snapshots whose note, tags, worktree name, annotations or recorded
+environment contain every word of the query, best match first.
+
+Words match whole words, case-insensitively; end a word with * to match
+words starting with it. Searching reads the index in .jvs/index.sqlite,
+which is kept up to date as snapshots are created, amended and deleted and
+built when missing; 'jvs doctor --repair rebuild_index' rebuilds it.
+
+Examples:
+  jvs search "oom crash"
+  jvs search nightly eval* --limit 5
+  jvs search --json llama`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		found, err := snapshot.Search(r.Root, strings.Join(args, " "), searchLimit)
+		if err != nil {
+			fmtErr("search: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			if found == nil {
+				found = []*model.Descriptor{}
+			}
+			outputJSON(found)
+			return
+		}
+		if len(found) == 0 {
+			fmt.Println("No matching snapshots.")
+			return
+		}
+		for _, desc := range found {
+			note := desc.Note
+			if note == "" {
+				note = color.Dim("(no note)")
+			}
+			tagsStr := ""
+			if len(desc.Tags) > 0 {
+				tagColors := make([]string, len(desc.Tags))
+				for i, tag := range desc.Tags {
+					tagColors[i] = color.Tag(tag)
+				}
+				tagsStr = "  [" + strings.Join(tagColors, ",") + "]"
+			}
+			fmt.Printf("%s  %s  %s  %s%s\n",
+				color.SnapshotID(desc.SnapshotID.ShortID()),
+				color.Dim(displayTime(desc.CreatedAt)),
+				desc.WorktreeName,
+				note,
+				tagsStr,
+			)
+		}
+	},
+}
+
+func init() {
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "limit number of results (0 = all)")
+	rootCmd.AddCommand(searchCmd)
+}
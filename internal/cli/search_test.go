@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestSearchCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "OOM crash in trainer", "--tag", "nightly")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "clean run")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "search", "oom", "crash")
+	require.NoError(t, err)
+	var found []model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &found))
+	require.Len(t, found, 1)
+	assert.Equal(t, "OOM crash in trainer", found[0].Note)
+
+	stdout, err = executeCommand(createTestRootCmd(), "search", "night*")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "OOM crash in trainer")
+
+	stdout, err = executeCommand(createTestRootCmd(), "search", "segfault")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "No matching snapshots")
+}
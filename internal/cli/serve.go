@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/jvs-project/jvs/internal/metrics"
+	"github.com/jvs-project/jvs/internal/serve"
+	"github.com/jvs-project/jvs/internal/webhook"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/jvsgrpc"
+	"github.com/jvs-project/jvs/pkg/jvshttp"
+)
+
+var (
+	serveInterval time.Duration
+	serveMetrics  string
+	serveGRPC     string
+	serveHTTP     string
+)
+
+// serveTokenEnv names the environment variable holding the token that
+// callers of the gRPC and REST APIs must present.
+const serveTokenEnv = "JVS_SERVE_TOKEN"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Watch the repository and notify webhooks",
+	Long: `Watch the repository and notify webhooks.
+
+Runs in the foreground until interrupted. Whenever a snapshot annotated
+with ci.notify=true is created, a snapshot.created event is POSTed to every
+webhook in .jvs/config.yaml without an events list, or listing it:
+
+  webhooks:
+    - url: https://ci.example.com/jvs
+      secret: <shared secret>
+
+Payloads are signed with HMAC-SHA256 in the X-JVS-Signature header when a
+secret is set. Failed deliveries are retried with exponential backoff.
+Snapshots created while serve is not running are not announced.
+
+Webhooks listing snapshot.completed, restore.completed or gc.completed
+under events are notified by every snapshot, restore and GC run as it
+completes, whether or not serve is running.
+
+With notify: true under staleness in .jvs/config.yaml, a worktree.stale
+event is POSTed when a worktree goes longer than its max_age without a
+snapshot (see jvs status).
+
+With --metrics, Prometheus metrics are served at /metrics on the given
+address. serve counts the snapshots it sees appear as created and those
+that disappear as deleted by GC; restore durations, bytes copied and
+verify failures are only recorded by programs using the jvs library, in
+Client.Metrics.
+
+With --grpc, Snapshot, Restore, History, GC and Verify are served over gRPC
+on the given address (service jvs.v1.JVS, JSON messages; see package
+pkg/jvsgrpc), with progress streamed for the long-running calls. Every
+call requires the token in JVS_SERVE_TOKEN, sent as a bearer token in the
+authorization metadata. The token travels in clear text: listen on a local
+or otherwise protected address.
+
+With --http, the same operations and more (status, stats, diff, leases,
+doctor) are served as a JSON REST API on the given address (see package
+pkg/jvshttp), with /healthz for health checks. Every other endpoint
+requires the token in JVS_SERVE_TOKEN, sent as a bearer token or as the
+password of HTTP basic authentication.
+
+Examples:
+  jvs serve
+  jvs serve --interval 10s
+  jvs serve --metrics :9090
+  JVS_SERVE_TOKEN=$(cat token) jvs serve --grpc 127.0.0.1:50051
+  JVS_SERVE_TOKEN=$(cat token) jvs serve --http 127.0.0.1:8080
+  jvs snapshot "eval candidate" --annotation ci.notify=true`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		cfg, err := config.Load(r.Root)
+		if err != nil {
+			fmtErr("load config: %v", err)
+			os.Exit(1)
+		}
+		endpoints := webhook.Endpoints(cfg.Webhooks)
+		if len(endpoints) == 0 {
+			fmt.Fprintln(os.Stderr, "warning: no webhooks configured; snapshots will not be announced")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := serve.Options{
+			RepoID:    r.RepoID,
+			Interval:  serveInterval,
+			Endpoints: endpoints,
+		}
+		if cfg.Staleness != nil && cfg.Staleness.Notify {
+			opts.Staleness = cfg.GetStaleness()
+		}
+		var client *jvs.Client
+		if serveGRPC != "" || serveHTTP != "" {
+			client, err = jvs.Open(r.Root)
+			if err != nil {
+				fmtErr("serve: %v", err)
+				os.Exit(1)
+			}
+		}
+		if serveGRPC != "" {
+			addr, err := serveGRPCEndpoint(ctx, serveGRPC, client, os.Getenv(serveTokenEnv))
+			if err != nil {
+				fmtErr("serve grpc: %v", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Serving gRPC at %s\n", addr)
+		}
+		if serveHTTP != "" {
+			addr, err := serveHTTPEndpoint(ctx, serveHTTP, client, os.Getenv(serveTokenEnv))
+			if err != nil {
+				fmtErr("serve http: %v", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Serving REST API at http://%s/v1\n", addr)
+		}
+		if serveMetrics != "" {
+			reg := prometheus.NewRegistry()
+			opts.Metrics = metrics.New(reg)
+			addr, err := serveMetricsEndpoint(ctx, serveMetrics, reg)
+			if err != nil {
+				fmtErr("serve metrics: %v", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Serving metrics at http://%s/metrics\n", addr)
+		}
+
+		fmt.Printf("Watching %s (%d webhooks, every %s). Press Ctrl-C to stop.\n",
+			color.Highlight(r.Root), len(endpoints), serveInterval)
+		srv := serve.New(r.Root, opts)
+		if err := srv.Run(ctx); err != nil {
+			fmtErr("serve: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// serveMetricsEndpoint serves reg at /metrics on addr until ctx is done and
+// returns the address it listens on.
+func serveMetricsEndpoint(ctx context.Context, addr string, reg *prometheus.Registry) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "warning: metrics endpoint stopped: %v\n", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	return ln.Addr(), nil
+}
+
+// serveGRPCEndpoint serves client's operations over gRPC on addr, to
+// callers presenting token, until ctx is done and returns the address it
+// listens on.
+func serveGRPCEndpoint(ctx context.Context, addr string, client *jvs.Client, token string) (net.Addr, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%s must be set", serveTokenEnv)
+	}
+	auth, err := jvsgrpc.TokenAuth(token)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := grpc.NewServer(auth...)
+	jvsgrpc.NewServer(client).Register(srv)
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: grpc endpoint stopped: %v\n", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	return ln.Addr(), nil
+}
+
+// serveHTTPEndpoint serves client's operations as a REST API on addr until
+// ctx is done and returns the address it listens on.
+func serveHTTPEndpoint(ctx context.Context, addr string, client *jvs.Client, token string) (net.Addr, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%s must be set", serveTokenEnv)
+	}
+	handler, err := jvshttp.NewServer(client, token)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "warning: http endpoint stopped: %v\n", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	return ln.Addr(), nil
+}
+
+func init() {
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", serve.DefaultInterval, "how often to check for new snapshots")
+	serveCmd.Flags().StringVar(&serveGRPC, "grpc", "", "serve the gRPC API on this address, e.g. 127.0.0.1:50051 (token in "+serveTokenEnv+")")
+	serveCmd.Flags().StringVar(&serveHTTP, "http", "", "serve the REST API on this address, e.g. 127.0.0.1:8080 (token in "+serveTokenEnv+")")
+	serveCmd.Flags().StringVar(&serveMetrics, "metrics", "", "serve Prometheus metrics at /metrics on this address, e.g. :9090")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var (
+	showTimings         bool
+	showMetadataHistory bool
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show [<snapshot>]",
+	Short: "Show snapshot details",
+	Long: `Show details of a snapshot.
+
+The snapshot can be a full ID, short ID prefix, tag name, or HEAD
+(default).
+
+Examples:
+  jvs show                    # Show current HEAD snapshot
+  jvs show v1.0               # Show snapshot tagged v1.0
+  jvs show 1771589 --timings  # Include per-phase creation timings
+  jvs show v1.0 --metadata-history
+
+Timings are recorded only for snapshots created with JVS_DEBUG_TIMING=1.
+
+--metadata-history lists the changes made to the snapshot's note and tags
+after it was created, oldest first, with who made them and when.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		ref := "HEAD"
+		if len(args) > 0 {
+			ref = args[0]
+		}
+
+		snapshotID, err := resolveSnapshot(r.Root, ref)
+		if err != nil {
+			fmtErr("resolve snapshot: %v", err)
+			os.Exit(1)
+		}
+
+		desc, err := snapshot.LoadDescriptor(r.Root, snapshotID)
+		if err != nil {
+			fmtErr("load descriptor: %v", err)
+			os.Exit(1)
+		}
+
+		if showMetadataHistory {
+			changes, err := snapshot.MetadataHistory(r.Root, snapshotID)
+			if err != nil {
+				fmtErr("load metadata history: %v", err)
+				os.Exit(1)
+			}
+			if jsonOutput {
+				outputJSON(struct {
+					*model.Descriptor
+					MetadataHistory []*model.MetadataChange `json:"metadata_history"`
+				}{desc, changes})
+				return
+			}
+			defer printMetadataHistory(changes)
+		}
+
+		if jsonOutput {
+			outputJSON(desc)
+			return
+		}
+
+		fmt.Printf("%s %s\n", color.Header("Snapshot"), color.SnapshotID(desc.SnapshotID.String()))
+		fmt.Printf("  Worktree:     %s\n", desc.WorktreeName)
+		fmt.Printf("  Created:      %s\n", displayTime(desc.CreatedAt))
+		if desc.Author != "" {
+			fmt.Printf("  Author:       %s\n", desc.Author)
+		}
+		if desc.ParentID != nil {
+			fmt.Printf("  Parent:       %s\n", color.SnapshotID(desc.ParentID.String()))
+		}
+		fmt.Printf("  Engine:       %s\n", desc.Engine)
+		if desc.Note != "" {
+			fmt.Printf("  Note:         %s\n", desc.Note)
+		}
+		if len(desc.Tags) > 0 {
+			tagColors := make([]string, len(desc.Tags))
+			for i, tag := range desc.Tags {
+				tagColors[i] = color.Tag(tag)
+			}
+			fmt.Printf("  Tags:         %s\n", strings.Join(tagColors, ", "))
+		}
+		fmt.Printf("  Payload hash: %s\n", color.Dim(string(desc.PayloadRootHash)))
+		if len(desc.PartialPaths) > 0 {
+			fmt.Printf("  Partial:      %s\n", strings.Join(desc.PartialPaths, ", "))
+		}
+		if desc.Compression != nil {
+			fmt.Printf("  Compression:  %s level %d\n", desc.Compression.Type, desc.Compression.Level)
+		}
+		if desc.Encryption != nil {
+			fmt.Printf("  Encryption:   %s (%d recipients)\n", desc.Encryption.Type, len(desc.Encryption.Recipients))
+		}
+		if len(desc.DereferencedPaths) > 0 {
+			fmt.Printf("  Dereferenced: %s\n", strings.Join(desc.DereferencedPaths, ", "))
+		}
+		if desc.HasReadErrors() {
+			fmt.Printf("  %s %d unreadable paths left out:\n", color.Warning("Incomplete:"), len(desc.ReadErrors))
+			for _, re := range desc.ReadErrors {
+				fmt.Printf("    %s: %s\n", re.Path, re.Error)
+			}
+		}
+		if len(desc.Annotations) > 0 {
+			keys := make([]string, 0, len(desc.Annotations))
+			for k := range desc.Annotations {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Println("  Annotations:")
+			for _, k := range keys {
+				fmt.Printf("    %s=%s\n", k, desc.Annotations[k])
+			}
+		}
+		if env := desc.Environment; env != nil {
+			fmt.Println("  Environment:")
+			if env.Hostname != "" {
+				fmt.Printf("    Hostname:     %s\n", env.Hostname)
+			}
+			if env.ImageDigest != "" {
+				fmt.Printf("    Image:        %s\n", env.ImageDigest)
+			}
+			fmt.Printf("    JVS version:  %s\n", env.JVSVersion)
+			keys := make([]string, 0, len(env.Vars))
+			for k := range env.Vars {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("    %s=%s\n", k, env.Vars[k])
+			}
+		}
+
+		if showTimings {
+			fmt.Println()
+			if desc.Stats == nil || len(desc.Stats.Timings) == 0 {
+				fmt.Println(color.Dim("No timings recorded (create snapshots with JVS_DEBUG_TIMING=1)."))
+				return
+			}
+			fmt.Println(color.Header("Timings:"))
+			var total time.Duration
+			for _, pt := range desc.Stats.Timings {
+				fmt.Printf("  %-18s %s\n", pt.Phase, pt.Duration.Round(time.Microsecond))
+				total += pt.Duration
+			}
+			fmt.Printf("  %-18s %s\n", "total", total.Round(time.Microsecond))
+		}
+	},
+}
+
+// printMetadataHistory prints the changes made to a snapshot's metadata.
+func printMetadataHistory(changes []*model.MetadataChange) {
+	fmt.Println()
+	if len(changes) == 0 {
+		fmt.Println(color.Dim("No metadata changes since creation."))
+		return
+	}
+	fmt.Println(color.Header("Metadata history:"))
+	for _, c := range changes {
+		fmt.Printf("  %s  %s  %s\n", displayTime(c.ChangedAt), c.Operation, color.Dim(c.Actor))
+		if c.OldNote != c.NewNote {
+			fmt.Printf("    note: %q -> %q\n", c.OldNote, c.NewNote)
+		}
+		if !slices.Equal(c.OldTags, c.NewTags) {
+			fmt.Printf("    tags: [%s] -> [%s]\n", strings.Join(c.OldTags, ", "), strings.Join(c.NewTags, ", "))
+		}
+	}
+}
+
+func init() {
+	showCmd.Flags().BoolVar(&showTimings, "timings", false, "show per-phase creation timings")
+	showCmd.Flags().BoolVar(&showMetadataHistory, "metadata-history", false, "show changes made to the note and tags since creation")
+	rootCmd.AddCommand(showCmd)
+}
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestShowCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+
+	t.Setenv("JVS_DEBUG_TIMING", "1")
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "v1")
+	require.NoError(t, err)
+
+	t.Run("HEAD by default", func(t *testing.T) {
+		stdout, err := executeCommand(createTestRootCmd(), "show")
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "first")
+		assert.Contains(t, stdout, "v1")
+		assert.NotContains(t, stdout, "Timings")
+	})
+
+	t.Run("With timings", func(t *testing.T) {
+		stdout, err := executeCommand(createTestRootCmd(), "show", "v1", "--timings")
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "Timings")
+		assert.Contains(t, stdout, "fsync")
+		assert.Contains(t, stdout, "total")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		stdout, err := executeCommand(createTestRootCmd(), "--json", "show", "v1")
+		require.NoError(t, err)
+		var desc model.Descriptor
+		require.NoError(t, json.Unmarshal([]byte(stdout), &desc))
+		assert.Equal(t, "first", desc.Note)
+		require.NotNil(t, desc.Stats)
+	})
+
+	t.Run("Metadata history", func(t *testing.T) {
+		stdout, err := executeCommand(createTestRootCmd(), "show", "v1", "--metadata-history")
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "No metadata changes")
+
+		repoRoot := filepath.Join(dir, "testrepo")
+		id, err := resolveSnapshot(repoRoot, "v1")
+		require.NoError(t, err)
+		_, err = snapshot.Amend(context.Background(), repoRoot, id, "annotate", "ci@runner", func(d *model.Descriptor) error {
+			d.Note = "first, reviewed"
+			return nil
+		})
+		require.NoError(t, err)
+
+		stdout, err = executeCommand(createTestRootCmd(), "show", "v1", "--metadata-history")
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "Metadata history:")
+		assert.Contains(t, stdout, "ci@runner")
+		assert.Contains(t, stdout, `note: "first" -> "first, reviewed"`)
+
+		stdout, err = executeCommand(createTestRootCmd(), "--json", "show", "v1", "--metadata-history")
+		require.NoError(t, err)
+		var out struct {
+			Note            string                  `json:"note"`
+			MetadataHistory []*model.MetadataChange `json:"metadata_history"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+		assert.Equal(t, "first, reviewed", out.Note)
+		require.Len(t, out.MetadataHistory, 1)
+		assert.Equal(t, "first", out.MetadataHistory[0].OldNote)
+	})
+}
+
+func TestSnapshotCaptureEnv(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "plain")
+	require.NoError(t, err)
+	t.Setenv("JVS_IMAGE_DIGEST", "sha256:feed")
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "captured", "--capture-env")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "show")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Environment:")
+	assert.Contains(t, stdout, "sha256:feed")
+
+	stdout, err = executeCommand(createTestRootCmd(), "history", "--env", "image_digest=sha256:feed")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "captured")
+	assert.NotContains(t, stdout, "plain")
+
+	stdout, err = executeCommand(createTestRootCmd(), "history", "--all", "--env", "image_digest=sha256:other")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "No snapshots found")
+}
+
+func TestTimeDisplayFlags(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "show")
+	require.NoError(t, err)
+	assert.Regexp(t, `Created:\s+(just now|\d+s ago)`, stdout)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--utc", "history")
+	require.NoError(t, err)
+	assert.Regexp(t, `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`, stdout)
+	assert.NotContains(t, stdout, "ago")
+}
+
+func TestSnapshotCommand_Annotations(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "snapshot", "annotated",
+		"--annotation", "ci.notify=true", "--annotation", "run=42")
+	require.NoError(t, err)
+	var desc model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &desc))
+	assert.Equal(t, map[string]string{"ci.notify": "true", "run": "42"}, desc.Annotations)
+
+	stdout, err = executeCommand(createTestRootCmd(), "show")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "ci.notify=true")
+
+	_, err = executeCommand(createTestRootCmd(), "verify", "--all")
+	require.NoError(t, err)
+}
+
+func TestSnapshotCommand_SkipUnchanged(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "snapshot", "first")
+	require.NoError(t, err)
+	var first model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &first))
+
+	stdout, err = executeCommand(createTestRootCmd(), "snapshot", "second", "--skip-unchanged")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "snapshot skipped")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "snapshot", "third", "--skip-unchanged")
+	require.NoError(t, err)
+	var skipped model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &skipped))
+	assert.True(t, skipped.Skipped)
+	assert.Equal(t, first.SnapshotID, skipped.SnapshotID)
+}
+
+func TestParseAnnotations(t *testing.T) {
+	got, err := parseAnnotations([]string{"a=1", "b=x=y", "c="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "x=y", "c": ""}, got)
+
+	_, err = parseAnnotations([]string{"novalue"})
+	assert.Error(t, err)
+	_, err = parseAnnotations([]string{"=v"})
+	assert.Error(t, err)
+}
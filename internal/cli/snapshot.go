@@ -18,10 +18,18 @@ import (
 )
 
 var (
-	snapshotTags        []string
-	snapshotPaths       []string
-	snapshotCompression string
-	snapshotNoteFile    string
+	snapshotTags          []string
+	snapshotPaths         []string
+	snapshotCompression   string
+	snapshotNoteFile      string
+	snapshotFollowLinks   bool
+	snapshotTwoPassHash   bool
+	snapshotAnnotations   []string
+	snapshotSkipUnchanged bool
+	snapshotIncremental   bool
+	snapshotExcludes      []string
+	snapshotCaptureEnv    bool
+	snapshotOnReadError   string
 )
 
 var snapshotCmd = &cobra.Command{
@@ -41,16 +49,31 @@ Examples:
   # Partial snapshot of specific paths
   jvs snapshot "Assets only" -- paths/Assets/
 
+  # Copy external symlinked directories into the snapshot
+  jvs snapshot "self-contained" --follow-symlinks
+
   # Compressed snapshot
   jvs snapshot "checkpoint" --compress fast
 
+  # Skip the snapshot if nothing changed since HEAD
+  jvs snapshot "auto: pod shutdown" --skip-unchanged
+
+  # Hard-link files unchanged since HEAD instead of copying them
+  jvs snapshot "hourly" --incremental
+
+  # Record where the snapshot was taken (see 'jvs show')
+  jvs snapshot "agent run 42" --capture-env
+
+  # Annotated snapshot (triggers webhooks in 'jvs serve')
+  jvs snapshot "eval candidate" --annotation ci.notify=true
+
   # Multi-line note via stdin
   jvs snapshot - < <<EOF
   ML Experiment: ResNet50 v2
   Result: 92.3% accuracy
   EOF
 
-Compression levels: none, fast, default, max
+Compression levels: none, fast, default, max (gzip); zstd-fast, zstd, zstd-max
 
 NOTE: Cannot create snapshots in detached state. Use 'jvs worktree fork'
 to create a new worktree from the current position first.`,
@@ -123,6 +146,12 @@ to create a new worktree from the current position first.`,
 			}
 		}
 
+		annotations, err := parseAnnotations(snapshotAnnotations)
+		if err != nil {
+			fmtErr("%v", err)
+			os.Exit(1)
+		}
+
 		// Detect engine from config or auto-detect
 		engine := detectEngine(r.Root)
 		if defaultEngine := jvsCfg.GetDefaultEngine(); defaultEngine != "" {
@@ -137,8 +166,27 @@ to create a new worktree from the current position first.`,
 				fmtErr("invalid compression level: %v", err)
 				os.Exit(1)
 			}
-			creator.SetCompression(comp.Level)
+			creator.SetCompressor(comp)
+		}
+		if snapshotFollowLinks {
+			creator.SetFollowSymlinks(true, 0)
+		}
+		creator.SetTwoPassHash(snapshotTwoPassHash)
+		creator.SetAnnotations(annotations)
+		creator.SetSkipIfUnchanged(snapshotSkipUnchanged)
+		creator.SetIncremental(snapshotIncremental)
+		creator.SetExcludes(snapshotExcludes)
+		readErrorPolicy := jvsCfg.GetReadErrorPolicy()
+		if snapshotOnReadError != "" {
+			readErrorPolicy = model.ReadErrorPolicy(snapshotOnReadError)
 		}
+		if readErrorPolicy != model.ReadErrorFail && readErrorPolicy != model.ReadErrorSkip {
+			fmtErr("invalid --on-read-error %q (must be fail or skip)", snapshotOnReadError)
+			os.Exit(1)
+		}
+		creator.SetReadErrorPolicy(readErrorPolicy)
+		captureEnv, envVars := jvsCfg.GetEnvironmentCapture()
+		creator.SetCaptureEnvironment(captureEnv || snapshotCaptureEnv, envVars)
 
 		var desc *model.Descriptor
 
@@ -154,18 +202,32 @@ to create a new worktree from the current position first.`,
 			fmtErr("create snapshot: %v", err)
 			os.Exit(1)
 		}
+		if !desc.Skipped {
+			warnReadErrors(desc)
+		}
 
 		if jsonOutput {
 			outputJSON(desc)
+		} else if desc.Skipped {
+			fmt.Printf("No changes since %s; snapshot skipped\n", color.SnapshotID(desc.SnapshotID.String()))
 		} else {
 			if len(snapshotPaths) > 0 {
 				fmt.Printf("Created partial snapshot %s (%d paths)\n", color.SnapshotID(desc.SnapshotID.String()), len(snapshotPaths))
 			} else {
 				fmt.Printf("Created snapshot %s\n", color.SnapshotID(desc.SnapshotID.String()))
 			}
+			if len(desc.DereferencedPaths) > 0 {
+				fmt.Printf("  (dereferenced %d symlinks)\n", len(desc.DereferencedPaths))
+			}
+			if desc.Incremental != nil {
+				fmt.Printf("  (incremental: %d unchanged files linked, %d copied)\n", desc.Incremental.Linked, desc.Incremental.Copied)
+			}
 			if desc.Compression != nil {
 				fmt.Printf("  (compressed: %s level %d)\n", desc.Compression.Type, desc.Compression.Level)
 			}
+			if desc.Encryption != nil {
+				fmt.Printf("  (encrypted: %s)\n", desc.Encryption.Type)
+			}
 			if len(allTags) > 0 {
 				tagColors := make([]string, len(allTags))
 				for i, tag := range allTags {
@@ -177,6 +239,43 @@ to create a new worktree from the current position first.`,
 	},
 }
 
+// maxListedReadErrors caps the unreadable paths printed after a snapshot.
+const maxListedReadErrors = 20
+
+// warnReadErrors prints the files left out of desc because they could not
+// be read. It writes to stderr, so it shows with --json too.
+func warnReadErrors(desc *model.Descriptor) {
+	if !desc.HasReadErrors() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s snapshot %s is incomplete: %d unreadable paths were left out\n",
+		color.Warning("WARNING:"), desc.SnapshotID.ShortID(), len(desc.ReadErrors))
+	for i, re := range desc.ReadErrors {
+		if i == maxListedReadErrors {
+			fmt.Fprintf(os.Stderr, "  ... and %d more (see 'jvs show %s --json')\n", len(desc.ReadErrors)-i, desc.SnapshotID.ShortID())
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", re.Path, re.Error)
+	}
+}
+
+// parseAnnotations parses repeated key=value flags into a map.
+func parseAnnotations(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	annotations := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid annotation %q (expected key=value)", v)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
 // readNoteFromStdin reads a multi-line note from stdin.
 // Reads until EOF and returns the trimmed content.
 func readNoteFromStdin() string {
@@ -199,7 +298,15 @@ func readNoteFromStdin() string {
 func init() {
 	snapshotCmd.Flags().StringSliceVar(&snapshotTags, "tag", []string{}, "tag for this snapshot (can be repeated)")
 	snapshotCmd.Flags().StringSliceVar(&snapshotPaths, "paths", []string{}, "paths to include in partial snapshot")
-	snapshotCmd.Flags().StringVar(&snapshotCompression, "compress", "", "compression level (none, fast, default, max)")
+	snapshotCmd.Flags().StringVar(&snapshotCompression, "compress", "", "compression level (none, fast, default, max, zstd, zstd-fast, zstd-max)")
+	snapshotCmd.Flags().BoolVar(&snapshotFollowLinks, "follow-symlinks", false, "copy targets of symlinks that point outside the worktree")
+	snapshotCmd.Flags().StringArrayVar(&snapshotAnnotations, "annotation", nil, "key=value annotation (can be repeated)")
+	snapshotCmd.Flags().BoolVar(&snapshotSkipUnchanged, "skip-unchanged", false, "do not create a snapshot if the worktree is identical to HEAD")
+	snapshotCmd.Flags().BoolVar(&snapshotIncremental, "incremental", false, "hard-link files unchanged since HEAD instead of copying them")
+	snapshotCmd.Flags().StringArrayVar(&snapshotExcludes, "exclude", nil, "pattern of paths to leave out, in .jvsignore syntax (can be repeated)")
+	snapshotCmd.Flags().BoolVar(&snapshotCaptureEnv, "capture-env", false, "record hostname, image digest, jvs version and allowlisted variables (always on with environment.capture)")
+	snapshotCmd.Flags().StringVar(&snapshotOnReadError, "on-read-error", "", "what to do with unreadable files: fail, or skip and record them (default from read_error_policy config, else fail)")
+	snapshotCmd.Flags().BoolVar(&snapshotTwoPassHash, "two-pass-hash", false, "hash payload in a separate pass after copying (for comparison)")
 	snapshotCmd.Flags().StringVarP(&snapshotNoteFile, "file", "F", "", "read note from file")
 	rootCmd.AddCommand(snapshotCmd)
 }
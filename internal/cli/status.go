@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List changes in the current worktree since its HEAD snapshot",
+	Long: `List changes in the current worktree since its HEAD snapshot.
+
+Compares the worktree payload with its HEAD snapshot and lists added,
+removed and modified files. Files whose size and modification time match
+the snapshot are not read, so status is cheap enough to run before every
+shutdown snapshot. Paths excluded by .jvsignore are not listed. Without a
+HEAD snapshot, every file is listed as added.
+
+A warning is shown if the worktree has not been snapshotted within the
+maximum age set under staleness in .jvs/config.yaml:
+
+  staleness:
+    max_age: 24h
+    worktrees:
+      scratch: 0s   # never stale
+
+With --json, the "dirty" field tells whether a new snapshot would record
+anything, and "stale" is set for a stale worktree.
+
+Examples:
+  jvs status
+  jvs status --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r, wtName := requireWorktree()
+
+		changes, err := snapshot.Changes(r.Root, wtName)
+		if err != nil {
+			fmtErr("status: %v", err)
+			os.Exit(1)
+		}
+		var stale *model.Staleness
+		if cfg, err := config.Load(r.Root); err == nil {
+			if wtCfg, err := worktree.NewManager(r.Root).Get(wtName); err == nil {
+				stale, _ = snapshot.CheckStale(r.Root, wtCfg, cfg.GetStaleness(), time.Now())
+			}
+		}
+
+		if jsonOutput {
+			outputJSON(struct {
+				*snapshot.WorktreeChanges
+				Stale *model.Staleness `json:"stale,omitempty"`
+			}{changes, stale})
+			return
+		}
+
+		opts := displayOptions()
+		head := color.Dim("(no snapshots)")
+		if changes.HeadSnapshotID != "" {
+			head = color.SnapshotID(changes.HeadSnapshotID.ShortID())
+		}
+		fmt.Printf("%s %s at %s\n", color.Header("Worktree"), changes.Worktree, head)
+		if stale != nil {
+			fmt.Println(color.Warning("warning: " + stale.Message()))
+		}
+		if !changes.Dirty {
+			fmt.Println("Nothing changed since HEAD.")
+			return
+		}
+		fmt.Println()
+		for _, c := range changes.Added {
+			fmt.Printf("  %s %s (%s)\n", color.Success("+"), c.Path, opts.SizeDelta(c.SizeDelta))
+		}
+		for _, c := range changes.Modified {
+			fmt.Printf("  %s %s", color.Warning("~"), c.Path)
+			if c.OldSize != c.Size {
+				fmt.Printf(" (%s -> %s, %s)", opts.Size(c.OldSize), opts.Size(c.Size), opts.SizeDelta(c.SizeDelta))
+			}
+			fmt.Println()
+		}
+		for _, c := range changes.Removed {
+			fmt.Printf("  %s %s (%s)\n", color.Error("-"), c.Path, opts.SizeDelta(c.SizeDelta))
+		}
+		fmt.Printf("\nAdded: %d, Removed: %d, Modified: %d\n", len(changes.Added), len(changes.Removed), len(changes.Modified))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
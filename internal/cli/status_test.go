@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+)
+
+func TestStatusCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	require.NoError(t, os.WriteFile("a.txt", []byte("abc"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "status")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Nothing changed since HEAD")
+
+	require.NoError(t, os.WriteFile("b.txt", []byte("new"), 0644))
+	require.NoError(t, os.Remove("a.txt"))
+	stdout, err = executeCommand(createTestRootCmd(), "status")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "b.txt (+3 B)")
+	assert.Contains(t, stdout, "a.txt (-3 B)")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "status")
+	require.NoError(t, err)
+	var changes snapshot.WorktreeChanges
+	require.NoError(t, json.Unmarshal([]byte(stdout), &changes))
+	assert.True(t, changes.Dirty)
+	assert.Equal(t, "main", changes.Worktree)
+	require.Len(t, changes.Added, 1)
+	assert.Equal(t, "b.txt", changes.Added[0].Path)
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// tagSummary is one line of 'jvs tag list' without a snapshot.
+type tagSummary struct {
+	Tag       string           `json:"tag"`
+	Snapshots int              `json:"snapshots"`
+	Latest    model.SnapshotID `json:"latest"`
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage snapshot tags",
+	Long: `Manage the tags of published snapshots.
+
+Adding or removing tags rewrites the snapshot's descriptor with a recomputed
+checksum. The old and new tags are kept in the snapshot's metadata history
+(see 'jvs show --metadata-history') and the change is audited.
+
+Examples:
+  jvs tag add 1771589366482-abc12345 v2.1
+  jvs tag rm v2.1 nightly
+  jvs tag list
+  jvs tag find v2.1`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <snapshot> <tag>...",
+	Short: "Add tags to a snapshot",
+	Long: `Add tags to a snapshot, given by ID, ID prefix or tag. Tags the snapshot
+already carries are kept once.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		tags := args[1:]
+		for _, tag := range tags {
+			if err := pathutil.ValidateTag(tag); err != nil {
+				fmtErr("invalid tag: %v", err)
+				os.Exit(1)
+			}
+		}
+		amendTags(args[0], "tag", func(d *model.Descriptor) {
+			for _, tag := range tags {
+				if !slices.Contains(d.Tags, tag) {
+					d.Tags = append(d.Tags, tag)
+				}
+			}
+		})
+	},
+}
+
+var tagRmCmd = &cobra.Command{
+	Use:     "rm <snapshot> <tag>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove tags from a snapshot",
+	Long: `Remove tags from a snapshot, given by ID, ID prefix or tag. Tags the
+snapshot does not carry are ignored.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		tags := args[1:]
+		amendTags(args[0], "untag", func(d *model.Descriptor) {
+			d.Tags = slices.DeleteFunc(d.Tags, func(t string) bool { return slices.Contains(tags, t) })
+		})
+	},
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list [<snapshot>]",
+	Short: "List tags",
+	Long: `List the tags of a snapshot or, without one, every tag in the repository
+with the number of snapshots carrying it and the newest of them.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		if len(args) == 1 {
+			desc, err := snapshot.LoadDescriptor(r.Root, resolveSnapshotIDOrExit(r.Root, args[0]))
+			if err != nil {
+				fmtErr("load descriptor: %v", err)
+				os.Exit(1)
+			}
+			if jsonOutput {
+				outputJSON(append([]string{}, desc.Tags...))
+				return
+			}
+			for _, tag := range desc.Tags {
+				fmt.Println(color.Tag(tag))
+			}
+			return
+		}
+
+		all, err := snapshot.ListAll(r.Root)
+		if err != nil {
+			fmtErr("list snapshots: %v", err)
+			os.Exit(1)
+		}
+		// ListAll is newest first, so the first snapshot seen with a tag is
+		// its latest
+		byTag := make(map[string]*tagSummary)
+		for _, desc := range all {
+			for _, tag := range desc.Tags {
+				s, ok := byTag[tag]
+				if !ok {
+					s = &tagSummary{Tag: tag, Latest: desc.SnapshotID}
+					byTag[tag] = s
+				}
+				s.Snapshots++
+			}
+		}
+		summaries := make([]*tagSummary, 0, len(byTag))
+		for _, s := range byTag {
+			summaries = append(summaries, s)
+		}
+		slices.SortFunc(summaries, func(a, b *tagSummary) int { return strings.Compare(a.Tag, b.Tag) })
+
+		if jsonOutput {
+			outputJSON(summaries)
+			return
+		}
+		if len(summaries) == 0 {
+			fmt.Println("No tags.")
+			return
+		}
+		fmt.Printf("%-24s %-10s %s\n", "TAG", "SNAPSHOTS", "LATEST")
+		for _, s := range summaries {
+			fmt.Printf("%-24s %-10d %s\n", s.Tag, s.Snapshots, s.Latest)
+		}
+	},
+}
+
+var tagFindCmd = &cobra.Command{
+	Use:   "find <tag>",
+	Short: "List the snapshots carrying a tag",
+	Long: `List the IDs of the snapshots carrying a tag, newest first. The first is
+the snapshot the tag resolves to wherever a snapshot is expected.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+
+		matches, err := snapshot.Find(r.Root, snapshot.FilterOptions{HasTag: args[0]})
+		if err != nil {
+			fmtErr("find snapshots: %v", err)
+			os.Exit(1)
+		}
+		ids := make([]model.SnapshotID, len(matches))
+		for i, desc := range matches {
+			ids[i] = desc.SnapshotID
+		}
+
+		if jsonOutput {
+			outputJSON(map[string]any{"tag": args[0], "snapshot_ids": ids})
+			return
+		}
+		if len(ids) == 0 {
+			fmtErr("no snapshot has tag %q", args[0])
+			os.Exit(1)
+		}
+		for _, id := range ids {
+			fmt.Println(color.SnapshotID(id.String()))
+		}
+	},
+}
+
+// amendTags resolves ref and rewrites its tags with update, then prints the
+// resulting tags.
+func amendTags(ref, operation string, update func(*model.Descriptor)) {
+	r := requireRepo()
+	snapshotID := resolveSnapshotIDOrExit(r.Root, ref)
+
+	desc, err := snapshot.Amend(context.Background(), r.Root, snapshotID, operation, "", func(d *model.Descriptor) error {
+		update(d)
+		return nil
+	})
+	if err != nil {
+		fmtErr("%s: %v", operation, err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		outputJSON(desc)
+		return
+	}
+	tagColors := make([]string, len(desc.Tags))
+	for i, tag := range desc.Tags {
+		tagColors[i] = color.Tag(tag)
+	}
+	fmt.Printf("Snapshot %s tags: %s\n", color.SnapshotID(snapshotID.String()), strings.Join(tagColors, ", "))
+}
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRmCmd)
+	tagCmd.AddCommand(tagListCmd)
+	tagCmd.AddCommand(tagFindCmd)
+	rootCmd.AddCommand(tagCmd)
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestTagCommand(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("v1"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "first", "--tag", "nightly")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("file.txt", []byte("v2"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "second", "--tag", "nightly")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "tag", "find", "nightly")
+	require.NoError(t, err)
+	var found struct {
+		SnapshotIDs []model.SnapshotID `json:"snapshot_ids"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &found))
+	require.Len(t, found.SnapshotIDs, 2)
+	second, first := found.SnapshotIDs[0], found.SnapshotIDs[1]
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "tag", "add", string(first), "v2.1", "nightly")
+	require.NoError(t, err)
+	var desc model.Descriptor
+	require.NoError(t, json.Unmarshal([]byte(stdout), &desc))
+	assert.Equal(t, []string{"nightly", "v2.1"}, desc.Tags)
+
+	_, err = executeCommand(createTestRootCmd(), "tag", "rm", "v2.1", "nightly")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "tag", "list")
+	require.NoError(t, err)
+	var summaries []tagSummary
+	require.NoError(t, json.Unmarshal([]byte(stdout), &summaries))
+	assert.Equal(t, []tagSummary{
+		{Tag: "nightly", Snapshots: 1, Latest: second},
+		{Tag: "v2.1", Snapshots: 1, Latest: first},
+	}, summaries)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "tag", "list", string(first))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["v2.1"]`, stdout)
+
+	stdout, err = executeCommand(createTestRootCmd(), "show", string(first), "--metadata-history")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "untag")
+}
@@ -3,6 +3,8 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -11,7 +13,10 @@ import (
 )
 
 var (
-	verifyAll bool
+	verifyAll      bool
+	verifyPaths    []string
+	verifyRepair   bool
+	verifyReplicas []string
 )
 
 var verifyCmd = &cobra.Command{
@@ -21,16 +26,46 @@ var verifyCmd = &cobra.Command{
 
 Checks descriptor checksum and optionally payload hash.
 
+With --paths, only the named files and directories of one snapshot are
+rehashed and checked against its per-file manifest, instead of its whole
+payload. Snapshots created before manifests were written cannot be
+verified this way.
+
+With --repair, every file of one snapshot is checked against its manifest,
+and corrupted files are rewritten with identical copies from related
+snapshots: its parent and children first, then the rest of its lineage,
+then the same snapshot in each replica listed in the replicas config or
+given with --replica. Copies are checked against the manifest before they
+are used. Files no snapshot has a copy of are listed as unrepaired. The
+descriptor and manifest must be intact, and encrypted snapshots cannot be
+repaired.
+
 Examples:
   jvs verify                    # Verify all snapshots
   jvs verify 1771589abc         # Verify specific snapshot
-  jvs verify --all              # Verify all snapshots with payload hash`,
+  jvs verify --all              # Verify all snapshots with payload hash
+  jvs verify 1771589abc --paths config,data/model.bin  # Verify two paths only
+  jvs verify 1771589abc --repair                       # Rewrite corrupted files
+  jvs verify 1771589abc --repair --replica /mnt/backup/repo`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
 
 		verifier := verify.NewVerifier(r.Root)
 
+		if len(verifyPaths) > 0 && (verifyAll || len(args) == 0) {
+			fmtErr("--paths needs a single snapshot-id")
+			os.Exit(1)
+		}
+		if verifyRepair {
+			if verifyAll || len(args) == 0 || len(verifyPaths) > 0 {
+				fmtErr("--repair needs a single snapshot-id and no --paths")
+				os.Exit(1)
+			}
+			runVerifyRepair(verifier, model.SnapshotID(args[0]))
+			return
+		}
+
 		if verifyAll || len(args) == 0 {
 			results, err := verifier.VerifyAll(false)
 			if err != nil {
@@ -46,6 +81,9 @@ Examples:
 			tampered := false
 			for _, res := range results {
 				status := "OK"
+				if len(res.ReadErrors) > 0 {
+					status = fmt.Sprintf("OK (incomplete: %d unreadable paths left out)", len(res.ReadErrors))
+				}
 				if res.TamperDetected {
 					status = "TAMPERED"
 					tampered = true
@@ -58,7 +96,13 @@ Examples:
 			}
 		} else {
 			snapshotID := model.SnapshotID(args[0])
-			result, err := verifier.VerifySnapshot(snapshotID, true)
+			var result *verify.Result
+			var err error
+			if len(verifyPaths) > 0 {
+				result, err = verifier.VerifyPaths(snapshotID, verifyPaths)
+			} else {
+				result, err = verifier.VerifySnapshot(snapshotID, true)
+			}
 			if err != nil {
 				fmtErr("verify: %v", err)
 				os.Exit(1)
@@ -71,16 +115,77 @@ Examples:
 
 			fmt.Printf("Snapshot: %s\n", result.SnapshotID)
 			fmt.Printf("  Checksum: %v\n", result.ChecksumValid)
-			fmt.Printf("  Payload hash: %v\n", result.PayloadHashValid)
+			if len(result.Paths) > 0 {
+				fmt.Printf("  Paths (%s): %v\n", strings.Join(result.Paths, ", "), result.PayloadHashValid)
+			} else {
+				fmt.Printf("  Payload hash: %v\n", result.PayloadHashValid)
+			}
+			if len(result.ReadErrors) > 0 {
+				fmt.Printf("  Incomplete: %d unreadable paths were left out at snapshot time\n", len(result.ReadErrors))
+			}
 			if result.TamperDetected {
 				fmt.Printf("  TAMPER DETECTED: %s\n", result.Error)
+				if result.ChecksumValid {
+					fmt.Printf("  Run 'jvs verify %s --repair' to rewrite corrupted files from related snapshots.\n", result.SnapshotID)
+				}
 				os.Exit(1)
 			}
 		}
 	},
 }
 
+// runVerifyRepair repairs a snapshot and exits 1 unless it verifies
+// afterwards.
+func runVerifyRepair(verifier *verify.Verifier, snapshotID model.SnapshotID) {
+	replicas := make([]string, len(verifyReplicas))
+	for i, r := range verifyReplicas {
+		abs, err := filepath.Abs(r)
+		if err != nil {
+			fmtErr("replica %s: %v", r, err)
+			os.Exit(1)
+		}
+		replicas[i] = abs
+	}
+	result, err := verifier.Repair(snapshotID, replicas)
+	if err != nil {
+		fmtErr("verify: %v", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		outputJSON(result)
+	} else {
+		fmt.Printf("Snapshot: %s\n", result.SnapshotID)
+		for _, r := range result.Repaired {
+			switch {
+			case r.Source == "":
+				fmt.Printf("  Repaired %s (directory recreated)\n", r.Path)
+			case r.Replica != "":
+				fmt.Printf("  Repaired %s from %s in %s\n", r.Path, r.Source, r.Replica)
+			default:
+				fmt.Printf("  Repaired %s from %s\n", r.Path, r.Source)
+			}
+		}
+		for _, p := range result.Unrepaired {
+			fmt.Printf("  Unrepaired %s: no related snapshot has a copy\n", p)
+		}
+		if len(result.Repaired) == 0 && len(result.Unrepaired) == 0 {
+			fmt.Println("  No corrupted files")
+		}
+		fmt.Printf("  Payload hash: %v\n", result.Result.PayloadHashValid)
+	}
+	if !result.Result.PayloadHashValid {
+		if !jsonOutput {
+			fmt.Printf("  Still corrupted: %s\n", result.Result.Error)
+		}
+		os.Exit(1)
+	}
+}
+
 func init() {
 	verifyCmd.Flags().BoolVar(&verifyAll, "all", false, "verify all snapshots")
+	verifyCmd.Flags().StringSliceVar(&verifyPaths, "paths", nil, "verify only these payload paths, using the snapshot's manifest")
+	verifyCmd.Flags().BoolVar(&verifyRepair, "repair", false, "rewrite corrupted files with copies from related snapshots")
+	verifyCmd.Flags().StringSliceVar(&verifyReplicas, "replica", nil, "also take copies from this replica repository (repeatable)")
 	rootCmd.AddCommand(verifyCmd)
 }
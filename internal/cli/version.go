@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/jvs"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show jvs and format versions",
+	Long: `Show the jvs version and the repository and bundle formats it supports.
+
+Inside a repository, also shows the repository's format version. Works in
+repositories this jvs cannot open, to tell which jvs they need.
+
+Examples:
+  jvs version
+  jvs version --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		info := jvs.BuildVersion()
+		if cwd, err := os.Getwd(); err == nil {
+			if root, err := repo.FindRoot(cwd); err == nil {
+				if v, err := repo.ReadFormatVersion(root); err == nil {
+					info.RepoFormatVersion = v
+				}
+			}
+		}
+
+		if jsonOutput {
+			outputJSON(info)
+			return
+		}
+		fmt.Printf("jvs %s\n", info.Version)
+		fmt.Printf("Repository formats: %d to %d\n", info.MinFormatVersion, info.FormatVersion)
+		fmt.Printf("Bundle format:      %d\n", info.BundleFormatVersion)
+		if info.RepoFormatVersion != 0 {
+			fmt.Printf("This repository:    format %d\n", info.RepoFormatVersion)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
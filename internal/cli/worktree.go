@@ -3,21 +3,34 @@ package cli
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/gc"
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/verify"
 	"github.com/jvs-project/jvs/internal/worktree"
 	"github.com/jvs-project/jvs/pkg/color"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
 var (
-	worktreeCreateFrom string
-	worktreeForce      bool
+	worktreeCreateFrom    string
+	worktreeCreateFromDir string
+	worktreeForce         bool
+	worktreeVerify        bool
+	worktreeRetainAs      string
+	worktreeLockOwner     string
+	worktreeLockTTL       time.Duration
 )
 
+// seedTag is attached to the baseline snapshot of worktrees created with
+// --from-dir.
+const seedTag = "seed"
+
 var worktreeCmd = &cobra.Command{
 	Use:     "worktree",
 	Short:   "Manage worktrees",
@@ -29,13 +42,18 @@ var worktreeCreateCmd = &cobra.Command{
 	Short: "Create a new worktree",
 	Long: `Create a new worktree.
 
-If --from is specified, the worktree is created from an existing snapshot,
-otherwise an empty worktree is created.
+If --from is specified, the worktree is created from an existing snapshot.
+If --from-dir is specified, the contents of a directory outside the
+repository are copied in with the configured engine and a baseline snapshot
+tagged "seed" is created; if either step fails the worktree is removed.
+Otherwise an empty worktree is created.
 
 Examples:
   jvs worktree create feature-x                    # Create empty worktree
   jvs worktree create hotfix --from v1.0           # Create from tag
-  jvs worktree create feature-y --from 1771589-abc # Create from snapshot`,
+  jvs worktree create feature-y --from 1771589-abc # Create from snapshot
+  jvs worktree create hotfix --from v1.0 --verify  # Check the clone against the snapshot
+  jvs worktree create data-ws --from-dir /mnt/dataset # Seed from a directory`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
@@ -43,6 +61,15 @@ Examples:
 
 		mgr := worktree.NewManager(r.Root)
 
+		if worktreeCreateFrom != "" && worktreeCreateFromDir != "" {
+			fmtErr("--from and --from-dir are mutually exclusive")
+			os.Exit(1)
+		}
+		if worktreeCreateFromDir != "" {
+			createWorktreeFromDir(r.Root, mgr, name, worktreeCreateFromDir)
+			return
+		}
+
 		// If --from is specified, create from snapshot
 		if worktreeCreateFrom != "" {
 			snapshotID := resolveSnapshotIDOrExit(r.Root, worktreeCreateFrom)
@@ -56,8 +83,10 @@ Examples:
 			// Create engine for cloning
 			eng := engine.NewEngine(detectEngine(r.Root))
 
-			cfg, err := mgr.CreateFromSnapshot(name, snapshotID, func(src, dst string) error {
-				_, err := eng.Clone(src, dst)
+			var cloneResult *engine.CloneResult
+			cfg, err := quotaManager(r.Root).CreateFromSnapshot(name, snapshotID, func(src, dst string) error {
+				var err error
+				cloneResult, err = eng.Clone(src, dst)
 				return err
 			})
 			if err != nil {
@@ -65,11 +94,20 @@ Examples:
 				os.Exit(1)
 			}
 
+			var verification *verify.WorktreeResult
+			if worktreeVerify {
+				verification = verifyClone(r.Root, name, snapshotID, cloneResult)
+			}
+
 			if jsonOutput {
-				outputJSON(cfg)
+				outputCloneJSON(cfg, verification)
 			} else {
 				fmt.Printf("Created worktree '%s' from snapshot %s\n", color.Success(name), color.SnapshotID(snapshotID.String()))
 				fmt.Printf("Path: %s\n", color.Dim(mgr.Path(name)))
+				printCloneVerification(verification)
+			}
+			if verification != nil && !verification.Match {
+				os.Exit(1)
 			}
 			return
 		}
@@ -89,6 +127,55 @@ Examples:
 	},
 }
 
+// createWorktreeFromDir creates worktree name from the contents of srcDir and
+// records a baseline snapshot tagged seedTag. On failure the worktree is
+// removed so no half-seeded worktree is left behind.
+func createWorktreeFromDir(repoRoot string, mgr *worktree.Manager, name, srcDir string) {
+	engineType := detectEngine(repoRoot)
+	eng := engine.NewEngine(engineType)
+
+	var cloneResult *engine.CloneResult
+	cfg, err := mgr.CreateFromDir(name, srcDir, func(src, dst string) error {
+		var err error
+		cloneResult, err = eng.Clone(src, dst)
+		return err
+	})
+	if err != nil {
+		fmtErr("create worktree from directory: %v", err)
+		os.Exit(1)
+	}
+
+	creator := snapshot.NewCreator(repoRoot, engineType)
+	desc, err := creator.Create(name, fmt.Sprintf("seed from %s", srcDir), []string{seedTag})
+	if err != nil {
+		if rmErr := mgr.Remove(name); rmErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: remove worktree %s: %v\n", name, rmErr)
+		}
+		fmtErr("create seed snapshot: %v", err)
+		os.Exit(1)
+	}
+	if cfg, err = mgr.Get(name); err != nil {
+		fmtErr("get worktree: %v", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		outputJSON(map[string]any{
+			"worktree": cfg,
+			"snapshot": desc,
+		})
+		return
+	}
+	fmt.Printf("Created worktree '%s' from %s\n", color.Success(name), srcDir)
+	fmt.Printf("Seed snapshot: %s\n", color.SnapshotID(desc.SnapshotID.String()))
+	fmt.Printf("Path: %s\n", color.Dim(mgr.Path(name)))
+	if cloneResult != nil {
+		for _, d := range cloneResult.Degradations {
+			fmt.Println(color.Warning(fmt.Sprintf("Clone degraded: %s", d)))
+		}
+	}
+}
+
 // resolveSnapshotID resolves a snapshot reference to a full snapshot ID.
 // Returns an error if the snapshot cannot be resolved.
 func resolveSnapshotID(repoRoot, ref string) (model.SnapshotID, error) {
@@ -149,6 +236,12 @@ Shows each worktree name and its current HEAD snapshot.`,
 			} else {
 				head = color.SnapshotID(head)
 			}
+			if cfg.Frozen {
+				head += color.Dim("  (frozen)")
+			}
+			if l, err := mgr.LockInfo(cfg.Name); err == nil && l != nil {
+				head += color.Dim("  (locked by " + l.Owner + ")")
+			}
 			fmt.Printf("%-20s  %s\n", cfg.Name, head)
 		}
 	},
@@ -228,6 +321,161 @@ Examples:
 	},
 }
 
+var worktreeFreezeCmd = &cobra.Command{
+	Use:   "freeze <name>",
+	Short: "Make a worktree read-only",
+	Long: `Make a worktree read-only.
+
+Removes write permissions from every file and directory in the payload and
+marks the worktree frozen, preserving it exactly as it was left (e.g. by an
+agent) for investigation. Snapshots still work and record the permissions
+the payload had before freezing; restore and remove are refused until the
+worktree is thawed.
+
+Note: file permissions do not stop root from writing to the payload.
+
+Examples:
+  jvs worktree freeze agent-run-42
+  jvs worktree thaw agent-run-42`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+		mgr := worktree.NewManager(r.Root)
+		if _, err := mgr.Get(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, formatWorktreeNotFoundError(args[0], r.Root))
+			os.Exit(1)
+		}
+
+		cfg, err := mgr.Freeze(args[0])
+		if err != nil {
+			fmtErr("freeze worktree: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(cfg)
+			return
+		}
+		fmt.Printf("Froze worktree '%s'\n", cfg.Name)
+	},
+}
+
+var worktreeThawCmd = &cobra.Command{
+	Use:   "thaw <name>",
+	Short: "Make a frozen worktree writable again",
+	Long: `Make a frozen worktree writable again.
+
+Restores the payload permissions recorded when the worktree was frozen and
+allows restore and remove again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+		mgr := worktree.NewManager(r.Root)
+		if _, err := mgr.Get(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, formatWorktreeNotFoundError(args[0], r.Root))
+			os.Exit(1)
+		}
+
+		cfg, err := mgr.Thaw(args[0])
+		if err != nil {
+			fmtErr("thaw worktree: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(cfg)
+			return
+		}
+		fmt.Printf("Thawed worktree '%s'\n", cfg.Name)
+	},
+}
+
+var worktreeLockCmd = &cobra.Command{
+	Use:   "lock <name>",
+	Short: "Lock a worktree against other operators",
+	Long: `Lock a worktree against other operators.
+
+While the lock is held, snapshots and restores of the worktree by anyone but
+the lock owner are refused with E_WORKTREE_LOCKED, as are rename and remove.
+The owner defaults to the current user@host. Locking a worktree you already
+hold renews the lock. Locks are advisory: they are honoured by jvs, not by
+other programs writing to the payload.
+
+Examples:
+  jvs worktree lock main
+  jvs worktree lock main --owner alice --ttl 2h
+  jvs worktree unlock main`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+		mgr := worktree.NewManager(r.Root)
+		if _, err := mgr.Get(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, formatWorktreeNotFoundError(args[0], r.Root))
+			os.Exit(1)
+		}
+
+		l, err := mgr.Lock(args[0], lockOwner(), worktreeLockTTL)
+		if err != nil {
+			fmtErr("lock worktree: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(l)
+			return
+		}
+		if l.ExpiresAt != nil {
+			fmt.Printf("Locked worktree '%s' for %s (expires %s)\n", l.WorktreeName, l.Owner, displayTime(*l.ExpiresAt))
+			return
+		}
+		fmt.Printf("Locked worktree '%s' for %s\n", l.WorktreeName, l.Owner)
+	},
+}
+
+var worktreeUnlockCmd = &cobra.Command{
+	Use:   "unlock <name>",
+	Short: "Release a worktree lock",
+	Long: `Release a worktree lock.
+
+Only the lock owner can unlock a worktree; --force releases a lock held by
+anyone else, e.g. an operator who has left.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := requireRepo()
+		mgr := worktree.NewManager(r.Root)
+
+		owner := lockOwner()
+		if worktreeForce {
+			l, err := mgr.LockInfo(args[0])
+			if err != nil {
+				fmtErr("unlock worktree: %v", err)
+				os.Exit(1)
+			}
+			if l != nil {
+				owner = l.Owner
+			}
+		}
+		if err := mgr.Unlock(args[0], owner); err != nil {
+			fmtErr("unlock worktree: %v", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			outputJSON(map[string]string{"worktree": args[0], "status": "unlocked"})
+			return
+		}
+		fmt.Printf("Unlocked worktree '%s'\n", args[0])
+	},
+}
+
+// lockOwner returns the --owner flag, defaulting to the current user@host.
+func lockOwner() string {
+	if worktreeLockOwner != "" {
+		return worktreeLockOwner
+	}
+	return snapshot.CurrentActor()
+}
+
 var worktreeRemoveCmd = &cobra.Command{
 	Use:   "remove <name>",
 	Short: "Remove a worktree",
@@ -236,9 +484,16 @@ var worktreeRemoveCmd = &cobra.Command{
 The worktree payload and metadata are deleted, but all snapshots remain.
 Use --force to remove a worktree that is in detached state.
 
+Snapshots the worktree protected from GC (its head and lineage) become
+collectable. --retain-as keeps them protected instead: pass another
+worktree's name to have it carry them for as long as it exists, or
+archived/<name> to keep them in an archive until
+.jvs/gc/retained/archived/<name>.json is deleted.
+
 Examples:
-  jvs worktree remove feature-x      # Remove worktree
-  jvs worktree remove --force old    # Force remove detached worktree`,
+  jvs worktree remove feature-x                        # Remove worktree
+  jvs worktree remove --force old                      # Force remove detached worktree
+  jvs worktree remove exp-1 --retain-as archived/exp   # Keep its snapshots`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		r := requireRepo()
@@ -268,6 +523,20 @@ Examples:
 			}
 		}
 
+		if worktreeRetainAs != "" {
+			roots, err := mgr.RemoveRetaining(name, worktreeRetainAs)
+			if err != nil {
+				fmtErr("remove worktree: %v", err)
+				os.Exit(1)
+			}
+			if jsonOutput {
+				outputJSON(map[string]any{"worktree": name, "retained_as": worktreeRetainAs, "retained": roots})
+				return
+			}
+			fmt.Printf("Removed worktree '%s'; %d snapshot roots retained as %s\n", name, len(roots), color.Highlight(worktreeRetainAs))
+			return
+		}
+
 		if err := mgr.Remove(name); err != nil {
 			fmtErr("remove worktree: %v", err)
 			os.Exit(1)
@@ -299,7 +568,12 @@ Examples:
   jvs worktree fork                           # Fork from current position, auto-name
   jvs worktree fork feature-x                 # Fork from current position with name
   jvs worktree fork v1.0 hotfix               # Fork from tag v1.0, name hotfix
-  jvs worktree fork 1771589-abc feature-y     # Fork from specific snapshot`,
+  jvs worktree fork 1771589-abc feature-y     # Fork from specific snapshot
+  jvs worktree fork v1.0 hotfix --verify      # Check the clone against the snapshot
+
+--verify hashes the new payload and compares it with the snapshot's payload
+root hash, listing differing paths and any engine degradations. The command
+exits non-zero on a mismatch; the worktree is kept for inspection.`,
 	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		r, wtName := requireWorktree()
@@ -374,9 +648,11 @@ Examples:
 		eng := engine.NewEngine(model.EngineCopy)
 
 		// Fork the worktree
-		mgr := worktree.NewManager(r.Root)
+		mgr := quotaManager(r.Root)
+		var cloneResult *engine.CloneResult
 		cfg, err := mgr.Fork(snapshotID, name, func(src, dst string) error {
-			_, err := eng.Clone(src, dst)
+			var err error
+			cloneResult, err = eng.Clone(src, dst)
 			return err
 		})
 		if err != nil {
@@ -384,24 +660,106 @@ Examples:
 			os.Exit(1)
 		}
 
+		var verification *verify.WorktreeResult
+		if worktreeVerify {
+			verification = verifyClone(r.Root, name, snapshotID, cloneResult)
+		}
+
 		if jsonOutput {
-			outputJSON(cfg)
+			outputCloneJSON(cfg, verification)
 		} else {
 			fmt.Printf("Created worktree '%s' from snapshot %s\n", color.Success(name), color.SnapshotID(snapshotID.String()))
 			fmt.Printf("Path: %s\n", color.Dim(mgr.Path(name)))
 			fmt.Println(color.Success("Worktree is at HEAD state - you can create snapshots."))
+			printCloneVerification(verification)
+		}
+		if verification != nil && !verification.Match {
+			os.Exit(1)
 		}
 	},
 }
 
+// verifyClone checks a freshly cloned worktree against its source snapshot.
+func verifyClone(repoRoot, name string, snapshotID model.SnapshotID, cloneResult *engine.CloneResult) *verify.WorktreeResult {
+	result, err := verify.NewVerifier(repoRoot).VerifyWorktree(name, snapshotID)
+	if err != nil {
+		fmtErr("verify worktree: %v", err)
+		os.Exit(1)
+	}
+	if cloneResult != nil && cloneResult.Degraded {
+		result.Degradations = cloneResult.Degradations
+	}
+	return result
+}
+
+func outputCloneJSON(cfg *model.WorktreeConfig, verification *verify.WorktreeResult) {
+	if verification == nil {
+		outputJSON(cfg)
+		return
+	}
+	outputJSON(map[string]any{
+		"worktree":     cfg,
+		"verification": verification,
+	})
+}
+
+func printCloneVerification(result *verify.WorktreeResult) {
+	if result == nil {
+		return
+	}
+	for _, d := range result.Degradations {
+		fmt.Println(color.Warning(fmt.Sprintf("Clone degraded: %s", d)))
+	}
+	if result.Match {
+		fmt.Println(color.Success("Verified: worktree matches snapshot payload hash."))
+		return
+	}
+	fmt.Println(color.Warning("Verification FAILED: worktree does not match the snapshot."))
+	fmt.Printf("  Expected: %s\n", color.Dim(string(result.ExpectedHash)))
+	fmt.Printf("  Actual:   %s\n", color.Dim(string(result.ActualHash)))
+	if len(result.Mismatched) == 0 {
+		fmt.Println("  Only file modes differ.")
+	}
+	for _, p := range result.Mismatched {
+		fmt.Printf("  %s\n", p)
+	}
+}
+
 func init() {
 	worktreeCreateCmd.Flags().StringVar(&worktreeCreateFrom, "from", "", "create from snapshot (ID, tag, or note prefix)")
+	worktreeCreateCmd.Flags().StringVar(&worktreeCreateFromDir, "from-dir", "", "create from the contents of a directory and snapshot it")
+	worktreeCreateCmd.Flags().BoolVar(&worktreeVerify, "verify", false, "verify the cloned payload against the snapshot")
+	worktreeForkCmd.Flags().BoolVar(&worktreeVerify, "verify", false, "verify the cloned payload against the snapshot")
 	worktreeRemoveCmd.Flags().BoolVarP(&worktreeForce, "force", "f", false, "force removal even if in detached state")
+	worktreeLockCmd.Flags().StringVar(&worktreeLockOwner, "owner", "", "lock owner (default: current user@host)")
+	worktreeLockCmd.Flags().DurationVar(&worktreeLockTTL, "ttl", 0, "release the lock after this duration (default: hold until unlocked)")
+	worktreeUnlockCmd.Flags().StringVar(&worktreeLockOwner, "owner", "", "lock owner (default: current user@host)")
+	worktreeUnlockCmd.Flags().BoolVarP(&worktreeForce, "force", "f", false, "release a lock held by another owner")
+	worktreeRemoveCmd.Flags().StringVar(&worktreeRetainAs, "retain-as", "", "keep GC protection of the worktree's snapshots in another worktree or archived/<name>")
 	worktreeCmd.AddCommand(worktreeCreateCmd)
 	worktreeCmd.AddCommand(worktreeListCmd)
 	worktreeCmd.AddCommand(worktreePathCmd)
 	worktreeCmd.AddCommand(worktreeRenameCmd)
 	worktreeCmd.AddCommand(worktreeRemoveCmd)
+	worktreeCmd.AddCommand(worktreeFreezeCmd)
+	worktreeCmd.AddCommand(worktreeThawCmd)
+	worktreeCmd.AddCommand(worktreeLockCmd)
+	worktreeCmd.AddCommand(worktreeUnlockCmd)
 	worktreeCmd.AddCommand(worktreeForkCmd)
 	rootCmd.AddCommand(worktreeCmd)
 }
+
+// quotaManager returns a worktree manager that enforces the configured
+// quota on forks, suggesting GC candidates when it is exceeded.
+func quotaManager(repoRoot string) *worktree.Manager {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		fmtErr("load config: %v", err)
+		os.Exit(1)
+	}
+	mgr := worktree.NewManager(repoRoot)
+	mgr.SetQuota(cfg.GetQuota(), func() ([]model.SnapshotUsage, error) {
+		return gc.NewCollector(repoRoot).LargestCandidates(cfg.GetRetentionPolicy(), worktree.MaxQuotaCandidates)
+	})
+	return mgr
+}
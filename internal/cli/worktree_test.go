@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestWorktreeForkCommand_Verify(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	require.NoError(t, os.MkdirAll("sub", 0755))
+	require.NoError(t, os.WriteFile(filepath.Join("sub", "file.txt"), []byte("data"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "worktree", "fork", "base", "checked", "--verify")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Verified")
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "worktree", "create", "created", "--from", "base", "--verify")
+	require.NoError(t, err)
+	var out struct {
+		Worktree struct {
+			Name string `json:"name"`
+		} `json:"worktree"`
+		Verification struct {
+			Match bool `json:"match"`
+		} `json:"verification"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	assert.Equal(t, "created", out.Worktree.Name)
+	assert.True(t, out.Verification.Match)
+}
+
+func TestWorktreeForkCommand_EncryptedSnapshot(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	repoRoot := filepath.Join(dir, "testrepo")
+	require.NoError(t, os.Chdir(filepath.Join(repoRoot, "main")))
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	cfg := config.Default()
+	cfg.Encryption = &config.Encryption{Recipients: []string{identity.Recipient().String()}}
+	require.NoError(t, config.Save(repoRoot, cfg))
+	defer config.InvalidateCache(repoRoot)
+	t.Setenv("JVS_AGE_IDENTITY", identity.String())
+
+	require.NoError(t, os.WriteFile("a.txt", []byte("secret"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "worktree", "fork", "base", "plain", "--verify")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Verified")
+	data, err := os.ReadFile(filepath.Join(repoRoot, "worktrees", "plain", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+}
+
+func TestWorktreeCreateCommand_FromDir(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	src := filepath.Join(dir, "dataset")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "shard"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "shard", "part-0"), []byte("rows"), 0644))
+
+	stdout, err := executeCommand(createTestRootCmd(), "--json", "worktree", "create", "data-ws", "--from-dir", src)
+	require.NoError(t, err)
+	var out struct {
+		Worktree struct {
+			Name             string `json:"name"`
+			HeadSnapshotID   string `json:"head_snapshot_id"`
+			LatestSnapshotID string `json:"latest_snapshot_id"`
+		} `json:"worktree"`
+		Snapshot struct {
+			SnapshotID string   `json:"snapshot_id"`
+			Tags       []string `json:"tags"`
+		} `json:"snapshot"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	assert.Equal(t, "data-ws", out.Worktree.Name)
+	assert.Equal(t, []string{"seed"}, out.Snapshot.Tags)
+	assert.Equal(t, out.Snapshot.SnapshotID, out.Worktree.HeadSnapshotID)
+	assert.Equal(t, out.Snapshot.SnapshotID, out.Worktree.LatestSnapshotID)
+
+	data, err := os.ReadFile(filepath.Join(dir, "testrepo", "worktrees", "data-ws", "shard", "part-0"))
+	require.NoError(t, err)
+	assert.Equal(t, "rows", string(data))
+
+	_, err = executeCommand(createTestRootCmd(), "verify", "--all")
+	require.NoError(t, err)
+}
+
+func TestWorktreeRemoveCommand_RetainAs(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "base", "--tag", "base")
+	require.NoError(t, err)
+	_, err = executeCommand(createTestRootCmd(), "worktree", "fork", "base", "exp")
+	require.NoError(t, err)
+
+	stdout, err := executeCommand(createTestRootCmd(), "worktree", "remove", "exp", "--retain-as", "archived/exp")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "1 snapshot roots retained as")
+	assert.Contains(t, stdout, "archived/exp")
+	assert.FileExists(t, filepath.Join(dir, "testrepo", ".jvs", "gc", "retained", "archived", "exp.json"))
+}
+
+func TestWorktreeFreezeThawCommands(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+
+	stdout, err := executeCommand(createTestRootCmd(), "worktree", "freeze", "main")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Froze worktree 'main'")
+	info, err := os.Stat("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	stdout, err = executeCommand(createTestRootCmd(), "worktree", "list")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "(frozen)")
+
+	// Snapshots still work
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "frozen state")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "worktree", "thaw", "main")
+	require.NoError(t, err)
+	var cfg map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &cfg))
+	assert.Nil(t, cfg["frozen"])
+	info, err = os.Stat("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestWorktreeLockUnlockCommands(t *testing.T) {
+	dir := setupTestDir(t)
+	_, err := executeCommand(createTestRootCmd(), "init", "testrepo")
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "testrepo", "main")))
+	require.NoError(t, os.WriteFile("file.txt", []byte("data"), 0644))
+
+	// The current user holds the lock and can still snapshot
+	stdout, err := executeCommand(createTestRootCmd(), "worktree", "lock", "main")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Locked worktree 'main'")
+	_, err = executeCommand(createTestRootCmd(), "snapshot", "while locked")
+	require.NoError(t, err)
+	_, err = executeCommand(createTestRootCmd(), "worktree", "unlock", "main")
+	require.NoError(t, err)
+
+	stdout, err = executeCommand(createTestRootCmd(), "--json", "worktree", "lock", "main", "--owner", "alice", "--ttl", "1h")
+	require.NoError(t, err)
+	var l model.WorktreeLock
+	require.NoError(t, json.Unmarshal([]byte(stdout), &l))
+	assert.Equal(t, "alice", l.Owner)
+	require.NotNil(t, l.ExpiresAt)
+
+	stdout, err = executeCommand(createTestRootCmd(), "worktree", "list")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "(locked by alice)")
+
+	_, err = executeCommand(createTestRootCmd(), "worktree", "unlock", "main", "--force")
+	require.NoError(t, err)
+	stdout, err = executeCommand(createTestRootCmd(), "worktree", "list")
+	require.NoError(t, err)
+	assert.NotContains(t, stdout, "locked")
+}
@@ -1,5 +1,6 @@
 // Package compression provides compression support for JVS snapshots.
-// It supports gzip compression at configurable levels for snapshot data.
+// It supports gzip and zstd compression at configurable levels for snapshot
+// data.
 package compression
 
 import (
@@ -10,6 +11,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // CompressionLevel represents the compression level.
@@ -32,6 +35,8 @@ type CompressionType string
 const (
 	// TypeGzip uses gzip compression.
 	TypeGzip CompressionType = "gzip"
+	// TypeZstd uses zstd compression.
+	TypeZstd CompressionType = "zstd"
 	// TypeNone indicates no compression.
 	TypeNone CompressionType = "none"
 )
@@ -51,10 +56,33 @@ func NewCompressor(level CompressionLevel) *Compressor {
 	return &Compressor{Type: TypeGzip, Level: level}
 }
 
+// NewCompressorWithType creates a compressor using the given algorithm.
+// Level 0 or TypeNone means no compression. Levels map onto zstd's speed
+// presets: LevelFast or below is its fastest, LevelMax or above its best
+// compression, anything between its default.
+func NewCompressorWithType(typ CompressionType, level CompressionLevel) *Compressor {
+	if typ == TypeNone || level <= LevelNone {
+		return &Compressor{Type: TypeNone, Level: LevelNone}
+	}
+	return &Compressor{Type: typ, Level: level}
+}
+
 // NewCompressorFromString creates a compressor from a string level.
-// Valid values: "none", "fast", "default", "max"
+// Valid values: "none", "fast", "default", "max" for gzip, and "zstd",
+// "zstd-fast", "zstd-default", "zstd-max" for zstd.
 func NewCompressorFromString(level string) (*Compressor, error) {
-	switch strings.ToLower(level) {
+	lower := strings.ToLower(level)
+	if lower == "zstd" {
+		return NewCompressorWithType(TypeZstd, LevelDefault), nil
+	}
+	if rest, ok := strings.CutPrefix(lower, "zstd-"); ok {
+		c, err := NewCompressorFromString(rest)
+		if err != nil || !c.IsEnabled() {
+			return nil, fmt.Errorf("invalid compression level: %s (must be zstd, zstd-fast, zstd-default, or zstd-max)", level)
+		}
+		return NewCompressorWithType(TypeZstd, c.Level), nil
+	}
+	switch lower {
 	case "none", "0":
 		return NewCompressor(LevelNone), nil
 	case "fast", "1":
@@ -64,7 +92,7 @@ func NewCompressorFromString(level string) (*Compressor, error) {
 	case "max", "9":
 		return NewCompressor(LevelMax), nil
 	default:
-		return nil, fmt.Errorf("invalid compression level: %s (must be none, fast, default, or max)", level)
+		return nil, fmt.Errorf("invalid compression level: %s (must be none, fast, default, max, or zstd-<level>)", level)
 	}
 }
 
@@ -75,6 +103,9 @@ func (c *Compressor) IsEnabled() bool {
 
 // String returns the string representation of the compressor.
 func (c *Compressor) String() string {
+	if c.Type == TypeZstd {
+		return "zstd-" + (&Compressor{Type: TypeGzip, Level: c.Level}).String()
+	}
 	switch c.Level {
 	case LevelNone:
 		return "none"
@@ -89,8 +120,17 @@ func (c *Compressor) String() string {
 	}
 }
 
+// Extension returns the suffix added to files compressed with typ: ".gz" for
+// gzip and ".zst" for zstd.
+func Extension(typ CompressionType) string {
+	if typ == TypeZstd {
+		return ".zst"
+	}
+	return ".gz"
+}
+
 // CompressFile compresses a file and returns the compressed path.
-// The compressed file has a .gz extension added.
+// The compressed file has a .gz or .zst extension added.
 // If compression is disabled, returns the original path.
 func (c *Compressor) CompressFile(path string) (string, error) {
 	if !c.IsEnabled() {
@@ -110,7 +150,7 @@ func (c *Compressor) CompressFile(path string) (string, error) {
 	}
 
 	// Write compressed file
-	compressedPath := path + ".gz"
+	compressedPath := path + Extension(c.Type)
 	if err := os.WriteFile(compressedPath, compressed, 0600); err != nil {
 		return "", fmt.Errorf("write compressed file: %w", err)
 	}
@@ -118,11 +158,12 @@ func (c *Compressor) CompressFile(path string) (string, error) {
 	return compressedPath, nil
 }
 
-// DecompressFile decompresses a .gz file and returns the decompressed path.
-// If the file is not compressed, returns the original path.
+// DecompressFile decompresses a .gz or .zst file and returns the
+// decompressed path. If the file is not compressed, returns the original path.
 func DecompressFile(path string) (string, error) {
 	// Check if file is compressed
-	if !strings.HasSuffix(path, ".gz") {
+	typ := typeOf(path)
+	if typ == TypeNone {
 		return path, nil
 	}
 
@@ -133,13 +174,13 @@ func DecompressFile(path string) (string, error) {
 	}
 
 	// Decompress
-	decompressed, err := decompressBytes(data)
+	decompressed, err := decompress(typ, data)
 	if err != nil {
 		return "", fmt.Errorf("decompress: %w", err)
 	}
 
-	// Write decompressed file (remove .gz extension)
-	decompressedPath := strings.TrimSuffix(path, ".gz")
+	// Write decompressed file (remove the extension)
+	decompressedPath := strings.TrimSuffix(path, Extension(typ))
 	if err := os.WriteFile(decompressedPath, decompressed, 0600); err != nil {
 		return "", fmt.Errorf("write decompressed file: %w", err)
 	}
@@ -147,6 +188,20 @@ func DecompressFile(path string) (string, error) {
 	return decompressedPath, nil
 }
 
+// ReadFile returns the decompressed content of a .gz or .zst file, without
+// writing it to disk.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read compressed file: %w", err)
+	}
+	decompressed, err := decompress(typeOf(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	return decompressed, nil
+}
+
 // CompressDir compresses all files in a directory tree.
 // Returns the count of compressed files and any error.
 func (c *Compressor) CompressDir(root string) (int, error) {
@@ -160,8 +215,10 @@ func (c *Compressor) CompressDir(root string) (int, error) {
 			return err
 		}
 
-		// Skip directories and already compressed files
-		if info.IsDir() || strings.HasSuffix(path, ".gz") {
+		// Skip directories and already compressed files. zstd compresses
+		// every file, so that payload files already ending in .zst survive
+		// DecompressDirType unchanged.
+		if info.IsDir() || (c.Type == TypeGzip && strings.HasSuffix(path, ".gz")) {
 			return nil
 		}
 
@@ -186,6 +243,18 @@ func (c *Compressor) CompressDir(root string) (int, error) {
 // DecompressDir decompresses all .gz files in a directory tree.
 // Returns the count of decompressed files and any error.
 func DecompressDir(root string) (int, error) {
+	return DecompressDirType(root, TypeGzip)
+}
+
+// DecompressDirType decompresses all files in a directory tree compressed
+// with typ, as recorded in the snapshot descriptor. Files with another
+// compression suffix are payload data and are left alone.
+// Returns the count of decompressed files and any error.
+func DecompressDirType(root string, typ CompressionType) (int, error) {
+	if typ == TypeNone {
+		return 0, nil
+	}
+	ext := Extension(typ)
 	count := 0
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -193,11 +262,11 @@ func DecompressDir(root string) (int, error) {
 		}
 
 		// Skip directories and non-compressed files
-		if info.IsDir() || !strings.HasSuffix(path, ".gz") {
+		if info.IsDir() || !strings.HasSuffix(path, ext) {
 			return nil
 		}
 
-		// Skip .READY.gz markers (metadata, don't decompress)
+		// Skip .READY.gz and .READY.zst markers (metadata, don't decompress)
 		if strings.HasPrefix(filepath.Base(path), ".READY") {
 			return nil
 		}
@@ -220,8 +289,17 @@ func DecompressDir(root string) (int, error) {
 	return count, err
 }
 
-// compressBytes compresses a byte slice using gzip.
+// compressBytes compresses a byte slice using the compressor's algorithm.
 func (c *Compressor) compressBytes(data []byte) ([]byte, error) {
+	if c.Type == TypeZstd {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(c.zstdLevel()))
+		if err != nil {
+			return nil, fmt.Errorf("create zstd writer: %w", err)
+		}
+		defer enc.Close()
+		return enc.EncodeAll(data, nil), nil
+	}
+
 	var buf bytes.Buffer
 	w, err := gzip.NewWriterLevel(&buf, int(c.Level))
 	if err != nil {
@@ -240,6 +318,40 @@ func (c *Compressor) compressBytes(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// zstdLevel maps the compressor's level onto a zstd speed preset.
+func (c *Compressor) zstdLevel() zstd.EncoderLevel {
+	switch {
+	case c.Level <= LevelFast:
+		return zstd.SpeedFastest
+	case c.Level >= LevelMax:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// decompress decompresses a byte slice compressed with typ.
+func decompress(typ CompressionType, data []byte) ([]byte, error) {
+	if typ == TypeZstd {
+		return decompressZstd(data)
+	}
+	return decompressBytes(data)
+}
+
+// decompressZstd decompresses a zstd byte slice.
+func decompressZstd(data []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("create zstd reader: %w", err)
+	}
+	defer dec.Close()
+	result, err := dec.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return result, nil
+}
+
 // decompressBytes decompresses a gzipped byte slice.
 func decompressBytes(data []byte) ([]byte, error) {
 	r, err := gzip.NewReader(bytes.NewReader(data))
@@ -258,7 +370,19 @@ func decompressBytes(data []byte) ([]byte, error) {
 
 // IsCompressedFile returns true if the file path indicates a compressed file.
 func IsCompressedFile(path string) bool {
-	return strings.HasSuffix(path, ".gz")
+	return typeOf(path) != TypeNone
+}
+
+// typeOf returns the compression type a path's suffix indicates.
+func typeOf(path string) CompressionType {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return TypeGzip
+	case strings.HasSuffix(path, ".zst"):
+		return TypeZstd
+	default:
+		return TypeNone
+	}
 }
 
 // CompressedPath returns the compressed path for a file.
@@ -268,7 +392,7 @@ func CompressedPath(path string) string {
 
 // UncompressedPath returns the uncompressed path for a file.
 func UncompressedPath(path string) string {
-	return strings.TrimSuffix(path, ".gz")
+	return strings.TrimSuffix(path, Extension(typeOf(path)))
 }
 
 // SnapshotCompressionInfo stores compression metadata in the descriptor.
@@ -1,6 +1,7 @@
 package compression
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -1003,3 +1004,83 @@ func TestCompressDir_NestedDeepStructure(t *testing.T) {
 		t.Errorf("compressed file not found at %s", deepGzPath)
 	}
 }
+
+func TestZstdRoundTrip(t *testing.T) {
+	for _, level := range []string{"zstd", "zstd-fast", "zstd-default", "zstd-max"} {
+		t.Run(level, func(t *testing.T) {
+			c, err := NewCompressorFromString(level)
+			if err != nil {
+				t.Fatalf("parse %s: %v", level, err)
+			}
+			if c.Type != TypeZstd {
+				t.Fatalf("expected zstd, got %s", c.Type)
+			}
+			if level != "zstd" && c.String() != level {
+				t.Errorf("expected %s, got %s", level, c.String())
+			}
+
+			tmpDir := t.TempDir()
+			data := bytes.Repeat([]byte("zstd test data "), 100)
+			files := map[string][]byte{"a.txt": data, "sub/b.txt": data[:10], "empty": nil, "archive.gz": []byte("raw")}
+			for name, content := range files {
+				p := filepath.Join(tmpDir, name)
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			count, err := c.CompressDir(tmpDir)
+			if err != nil {
+				t.Fatalf("compress dir: %v", err)
+			}
+			if count != len(files) {
+				t.Errorf("expected %d compressed files, got %d", len(files), count)
+			}
+			if _, err := os.Stat(filepath.Join(tmpDir, "a.txt.zst")); err != nil {
+				t.Errorf("expected a.txt.zst: %v", err)
+			}
+
+			count, err = DecompressDirType(tmpDir, TypeZstd)
+			if err != nil {
+				t.Fatalf("decompress dir: %v", err)
+			}
+			if count != len(files) {
+				t.Errorf("expected %d decompressed files, got %d", len(files), count)
+			}
+			for name, content := range files {
+				got, err := os.ReadFile(filepath.Join(tmpDir, name))
+				if err != nil {
+					t.Fatalf("read %s: %v", name, err)
+				}
+				if !bytes.Equal(got, content) {
+					t.Errorf("%s: content mismatch", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressDirType_IgnoresOtherType(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "data.zst"), []byte("not zstd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	count, err := DecompressDir(tmpDir)
+	if err != nil {
+		t.Fatalf("decompress dir: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 files, got %d", count)
+	}
+}
+
+func TestNewCompressorFromString_InvalidZstd(t *testing.T) {
+	for _, level := range []string{"zstd-none", "zstd-bogus", "zstd-"} {
+		if _, err := NewCompressorFromString(level); err == nil {
+			t.Errorf("expected error for %q", level)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Example scenario steps, in execution order.
+const (
+	StepFirstStartup   = "first_startup"
+	StepShutdown       = "shutdown"
+	StepRestart        = "restart"
+	StepDetached       = "detached"
+	StepGarbageCollect = "gc"
+)
+
+// ExamplesOptions configures RunExamples.
+type ExamplesOptions struct {
+	Dir    string           // Parent directory for the temp repo; os.TempDir() if empty
+	Keep   bool             // Keep the temp repo after the run
+	Engine model.EngineType // Snapshot engine; copy if empty
+}
+
+// ExampleCheck is one documented guarantee checked along the scenario.
+type ExampleCheck struct {
+	Step      string `json:"step"`
+	Guarantee string `json:"guarantee"`
+	Passed    bool   `json:"passed"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// ExamplesReport lists the checks of an examples run. The run stops at the
+// first failed check, since later steps build on earlier ones.
+type ExamplesReport struct {
+	Checks   []ExampleCheck `json:"checks"`
+	RepoPath string         `json:"repo_path,omitempty"`
+}
+
+// Passed reports whether every check passed.
+func (r *ExamplesReport) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// scenario holds the state of one examples run.
+type scenario struct {
+	ctx      context.Context
+	repoPath string
+	engine   model.EngineType
+	report   *ExamplesReport
+	step     string
+}
+
+// check records a guarantee; detail explains a failure and is dropped when
+// the check passes.
+func (s *scenario) check(guarantee string, ok bool, detail string, args ...any) bool {
+	c := ExampleCheck{Step: s.step, Guarantee: guarantee, Passed: ok}
+	if !ok {
+		c.Detail = fmt.Sprintf(detail, args...)
+	}
+	s.report.Checks = append(s.report.Checks, c)
+	return ok
+}
+
+// noError records a guarantee that holds when err is nil.
+func (s *scenario) noError(guarantee string, err error) bool {
+	if err != nil {
+		return s.check(guarantee, false, "%v", err)
+	}
+	return s.check(guarantee, true, "")
+}
+
+// RunExamples executes the sandbox-manager usage pattern documented in
+// package jvs (OpenOrInit, RestoreLatest, mutate, Snapshot, GC) against a
+// fresh temp repository through the public library, checking each
+// documented guarantee: a stable payload path across restores, no-op
+// restores of empty worktrees, SkipIfUnchanged, detached-state semantics
+// and GC keeping worktree history. An error is returned only when the
+// harness itself fails.
+func RunExamples(opts ExamplesOptions) (*ExamplesReport, error) {
+	eng := opts.Engine
+	if eng == "" {
+		eng = model.EngineCopy
+	}
+
+	dir, err := os.MkdirTemp(opts.Dir, "jvs-examples-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	if !opts.Keep {
+		defer os.RemoveAll(dir)
+	}
+
+	s := &scenario{
+		ctx:      context.Background(),
+		repoPath: filepath.Join(dir, "agent-ws"),
+		engine:   eng,
+		report:   &ExamplesReport{},
+	}
+	if opts.Keep {
+		s.report.RepoPath = s.repoPath
+	}
+	s.run()
+	return s.report, nil
+}
+
+func (s *scenario) run() {
+	// Pod startup on a fresh volume
+	s.step = StepFirstStartup
+	client, payload, ok := s.startup(true)
+	if !ok {
+		return
+	}
+
+	// Pod shutdown: snapshot after the pod is gone
+	s.step = StepShutdown
+	if !s.noError("agent writes to the payload path", os.WriteFile(filepath.Join(payload, "state.txt"), []byte("run 1"), 0644)) {
+		return
+	}
+	first, ok := s.shutdownSnapshot(client, "auto: pod shutdown")
+	if !ok {
+		return
+	}
+	again, err := client.Snapshot(s.ctx, jvs.SnapshotOptions{Note: "auto: pod shutdown", Tags: []string{"auto", "shutdown"}, SkipIfUnchanged: true})
+	if !s.noError("unchanged snapshot succeeds", err) {
+		return
+	}
+	if !s.check("SkipIfUnchanged returns HEAD instead of a new snapshot",
+		again.Skipped && again.SnapshotID == first.SnapshotID,
+		"got %s (skipped=%v), want %s skipped", again.SnapshotID, again.Skipped, first.SnapshotID) {
+		return
+	}
+
+	// Next pod startup: reopen and restore over whatever the last pod left
+	s.step = StepRestart
+	if !s.noError("pod leaves stray changes", errors.Join(
+		os.WriteFile(filepath.Join(payload, "state.txt"), []byte("crashed"), 0644),
+		os.WriteFile(filepath.Join(payload, "junk.txt"), []byte("junk"), 0644))) {
+		return
+	}
+	client2, payload2, ok := s.startup(false)
+	if !ok {
+		return
+	}
+	if !s.check("OpenOrInit reopens the same repository", client2.RepoID() == client.RepoID(),
+		"repo id %s, want %s", client2.RepoID(), client.RepoID()) {
+		return
+	}
+	if !s.check("payload path is stable across pod lifecycles", payload2 == payload,
+		"payload path %s, want %s", payload2, payload) {
+		return
+	}
+	if !s.payloadEquals("RestoreLatest brings back the last snapshot", payload, "run 1") {
+		return
+	}
+	client = client2
+
+	// Restoring an older snapshot detaches the worktree until RestoreLatest
+	s.step = StepDetached
+	if !s.noError("agent writes to the payload path", os.WriteFile(filepath.Join(payload, "state.txt"), []byte("run 2"), 0644)) {
+		return
+	}
+	second, ok := s.shutdownSnapshot(client, "auto: pod shutdown")
+	if !ok {
+		return
+	}
+	if !s.noError("Restore to an older snapshot succeeds", client.Restore(s.ctx, jvs.RestoreOptions{Target: string(first.SnapshotID)})) {
+		return
+	}
+	cfg, err := client.Worktree(s.ctx, "main")
+	if !s.noError("worktree config is readable", err) {
+		return
+	}
+	if !s.check("restoring an older snapshot detaches the worktree",
+		cfg.IsDetached() && cfg.HeadSnapshotID == first.SnapshotID && cfg.LatestSnapshotID == second.SnapshotID,
+		"head %s latest %s detached=%v", cfg.HeadSnapshotID, cfg.LatestSnapshotID, cfg.IsDetached()) {
+		return
+	}
+	if !s.payloadEquals("restore replaces content under the same payload path", payload, "run 1") {
+		return
+	}
+	_, err = client.Snapshot(s.ctx, jvs.SnapshotOptions{Note: "detached"})
+	if !s.check("Snapshot is refused in detached state", err != nil, "snapshot succeeded while detached") {
+		return
+	}
+	if !s.noError("RestoreLatest succeeds", client.RestoreLatest(s.ctx, "main")) {
+		return
+	}
+	cfg, err = client.Worktree(s.ctx, "main")
+	if !s.noError("worktree config is readable", err) {
+		return
+	}
+	if !s.check("RestoreLatest returns to HEAD state", !cfg.IsDetached() && cfg.HeadSnapshotID == second.SnapshotID,
+		"head %s latest %s detached=%v", cfg.HeadSnapshotID, cfg.LatestSnapshotID, cfg.IsDetached()) {
+		return
+	}
+	if !s.payloadEquals("RestoreLatest brings back the latest snapshot", payload, "run 2") {
+		return
+	}
+
+	// GC never touches the history of a live worktree
+	s.step = StepGarbageCollect
+	plan, err := client.GC(s.ctx, jvs.GCOptions{KeepMinAge: time.Nanosecond})
+	if !s.noError("GC runs", err) {
+		return
+	}
+	if !s.check("GC deletes nothing in the worktree's lineage", len(plan.ToDelete) == 0,
+		"plan deletes %v", plan.ToDelete) {
+		return
+	}
+	for _, id := range []model.SnapshotID{first.SnapshotID, second.SnapshotID} {
+		if !s.noError(fmt.Sprintf("snapshot %s verifies after GC", id.ShortID()), client.Verify(s.ctx, id)) {
+			return
+		}
+	}
+	s.noError("RestoreLatest still works after GC", client.RestoreLatest(s.ctx, "main"))
+}
+
+// startup runs the documented pod startup sequence: OpenOrInit, look up the
+// payload path and restore the latest snapshot if there is one.
+func (s *scenario) startup(fresh bool) (*jvs.Client, string, bool) {
+	client, err := jvs.OpenOrInit(s.repoPath, jvs.InitOptions{Name: "agent-ws", EngineType: s.engine})
+	if !s.noError("OpenOrInit opens or initializes the repository", err) {
+		return nil, "", false
+	}
+	payload := client.WorktreePayloadPath("main")
+	if !s.check("payload path is <repo>/main", payload == filepath.Join(s.repoPath, "main"),
+		"payload path %s", payload) {
+		return nil, "", false
+	}
+
+	has, err := client.HasSnapshots(s.ctx, "main")
+	if !s.noError("HasSnapshots succeeds", err) {
+		return nil, "", false
+	}
+	if !s.check("HasSnapshots reports whether there is history", has != fresh,
+		"HasSnapshots = %v, want %v", has, !fresh) {
+		return nil, "", false
+	}
+	if fresh {
+		// Documented as a no-op, so the guard in the pattern is optional
+		if !s.noError("RestoreLatest without snapshots is a no-op", client.RestoreLatest(s.ctx, "main")) {
+			return nil, "", false
+		}
+	} else if !s.noError("RestoreLatest succeeds", client.RestoreLatest(s.ctx, "main")) {
+		return nil, "", false
+	}
+	return client, payload, true
+}
+
+// shutdownSnapshot takes the documented pod shutdown snapshot and expects a
+// new one to be created.
+func (s *scenario) shutdownSnapshot(client *jvs.Client, note string) (*model.Descriptor, bool) {
+	desc, err := client.Snapshot(s.ctx, jvs.SnapshotOptions{Note: note, Tags: []string{"auto", "shutdown"}, SkipIfUnchanged: true})
+	if !s.noError("Snapshot after pod shutdown succeeds", err) {
+		return nil, false
+	}
+	return desc, s.check("a changed payload produces a new snapshot", !desc.Skipped, "snapshot was skipped")
+}
+
+// payloadEquals checks that the payload holds only state.txt, containing
+// want; restores must not leave stray files behind.
+func (s *scenario) payloadEquals(guarantee, payload, want string) bool {
+	entries, err := os.ReadDir(payload)
+	if err != nil {
+		return s.check(guarantee, false, "read payload: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "state.txt" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return s.check(guarantee, false, "payload holds %v, want [state.txt]", names)
+	}
+	data, err := os.ReadFile(filepath.Join(payload, "state.txt"))
+	if err != nil {
+		return s.check(guarantee, false, "read state.txt: %v", err)
+	}
+	return s.check(guarantee, string(data) == want, "state.txt = %q, want %q", data, want)
+}
@@ -0,0 +1,36 @@
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/conformance"
+)
+
+func TestRunExamples_Passes(t *testing.T) {
+	report, err := conformance.RunExamples(conformance.ExamplesOptions{Dir: t.TempDir()})
+	require.NoError(t, err)
+	for _, c := range report.Checks {
+		assert.True(t, c.Passed, "%s: %s: %s", c.Step, c.Guarantee, c.Detail)
+	}
+	require.True(t, report.Passed())
+
+	// Every step of the scenario ran
+	steps := make(map[string]bool)
+	for _, c := range report.Checks {
+		steps[c.Step] = true
+	}
+	for _, step := range []string{conformance.StepFirstStartup, conformance.StepShutdown, conformance.StepRestart,
+		conformance.StepDetached, conformance.StepGarbageCollect} {
+		assert.True(t, steps[step], step)
+	}
+	assert.Empty(t, report.RepoPath)
+}
+
+func TestRunExamples_Keep(t *testing.T) {
+	report, err := conformance.RunExamples(conformance.ExamplesOptions{Dir: t.TempDir(), Keep: true})
+	require.NoError(t, err)
+	assert.DirExists(t, report.RepoPath)
+}
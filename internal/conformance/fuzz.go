@@ -0,0 +1,466 @@
+// Package conformance provides self-contained conformance checks that run
+// against the current build without the source tree, for downstream CI.
+package conformance
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/restore"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/verify"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Fuzz operation names.
+const (
+	OpSnapshot      = "snapshot"
+	OpRestore       = "restore"
+	OpRestoreLatest = "restore_latest"
+	OpFork          = "fork"
+	OpGC            = "gc"
+	OpRemove        = "remove"
+)
+
+// opWeights controls how often each operation is chosen.
+var opWeights = []struct {
+	op     string
+	weight int
+}{
+	{OpSnapshot, 40},
+	{OpRestore, 15},
+	{OpRestoreLatest, 10},
+	{OpFork, 12},
+	{OpGC, 10},
+	{OpRemove, 15},
+}
+
+// FuzzOptions configures a randomized conformance run.
+type FuzzOptions struct {
+	Ops    int              // Number of operations to execute
+	Seed   int64            // Random seed; the same seed replays the same sequence
+	Dir    string           // Parent directory for the temp repo; os.TempDir() if empty
+	Keep   bool             // Keep the temp repo after the run
+	Engine model.EngineType // Snapshot engine; copy if empty
+
+	// Progress, if set, is called after each operation.
+	Progress func(done, total int)
+}
+
+// Violation describes a failed property check.
+type Violation struct {
+	Op     int    `json:"op"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// FuzzReport summarizes a fuzz run.
+type FuzzReport struct {
+	Seed               int64          `json:"seed"`
+	Ops                int            `json:"ops"`
+	Executed           int            `json:"executed"`
+	OpCounts           map[string]int `json:"op_counts"`
+	SurvivingSnapshots int            `json:"surviving_snapshots"`
+	Worktrees          int            `json:"worktrees"`
+	RepoPath           string         `json:"repo_path,omitempty"`
+	Violations         []Violation    `json:"violations,omitempty"`
+}
+
+// Passed reports whether the run finished without property violations.
+func (r *FuzzReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// fuzzer holds the state of one run.
+type fuzzer struct {
+	rng      *rand.Rand
+	repoRoot string
+	engine   model.EngineType
+	report   *FuzzReport
+	op       int
+	nextName int
+}
+
+// Fuzz executes a randomized sequence of snapshot, restore, fork, gc and
+// remove operations against a fresh temp repository, checking after each
+// step that no protected snapshot is lost and that surviving snapshots verify.
+// The run stops at the first violation. An error is returned only when the
+// harness itself fails (e.g. the temp repo cannot be created).
+func Fuzz(opts FuzzOptions) (*FuzzReport, error) {
+	if opts.Ops <= 0 {
+		return nil, fmt.Errorf("ops must be positive")
+	}
+	eng := opts.Engine
+	if eng == "" {
+		eng = model.EngineCopy
+	}
+
+	dir, err := os.MkdirTemp(opts.Dir, "jvs-fuzz-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	if !opts.Keep {
+		defer os.RemoveAll(dir)
+	}
+
+	repoRoot := filepath.Join(dir, "repo")
+	if _, err := repo.Init(repoRoot, "fuzz"); err != nil {
+		return nil, fmt.Errorf("init repo: %w", err)
+	}
+
+	f := &fuzzer{
+		rng:      rand.New(rand.NewPCG(uint64(opts.Seed), 0x6a7673)),
+		repoRoot: repoRoot,
+		engine:   eng,
+		report: &FuzzReport{
+			Seed:     opts.Seed,
+			Ops:      opts.Ops,
+			OpCounts: make(map[string]int),
+		},
+	}
+	if opts.Keep {
+		f.report.RepoPath = repoRoot
+	}
+
+	for f.op = 1; f.op <= opts.Ops; f.op++ {
+		op := f.pickOp()
+		if err := f.run(op); err != nil {
+			f.violate("op_failed", fmt.Sprintf("%s: %v", op, err))
+		}
+		f.report.OpCounts[op]++
+		f.report.Executed = f.op
+		if opts.Progress != nil {
+			opts.Progress(f.op, opts.Ops)
+		}
+		if !f.report.Passed() {
+			break
+		}
+	}
+
+	if f.report.Passed() {
+		f.checkSurvivorsVerify()
+	}
+
+	all, err := snapshot.ListAll(repoRoot)
+	if err == nil {
+		f.report.SurvivingSnapshots = len(all)
+	}
+	wts, err := worktree.NewManager(repoRoot).List()
+	if err == nil {
+		f.report.Worktrees = len(wts)
+	}
+	return f.report, nil
+}
+
+func (f *fuzzer) violate(kind, detail string) {
+	f.report.Violations = append(f.report.Violations, Violation{Op: f.op, Kind: kind, Detail: detail})
+}
+
+func (f *fuzzer) pickOp() string {
+	total := 0
+	for _, w := range opWeights {
+		total += w.weight
+	}
+	n := f.rng.IntN(total)
+	for _, w := range opWeights {
+		if n < w.weight {
+			return w.op
+		}
+		n -= w.weight
+	}
+	return OpSnapshot
+}
+
+func (f *fuzzer) run(op string) error {
+	switch op {
+	case OpSnapshot:
+		return f.doSnapshot()
+	case OpRestore:
+		return f.doRestore()
+	case OpRestoreLatest:
+		return f.doRestoreLatest()
+	case OpFork:
+		return f.doFork()
+	case OpGC:
+		return f.doGC()
+	case OpRemove:
+		return f.doRemove()
+	}
+	return fmt.Errorf("unknown op %q", op)
+}
+
+// worktrees returns all worktree configs sorted by name for determinism.
+func (f *fuzzer) worktrees() ([]*model.WorktreeConfig, error) {
+	list, err := worktree.NewManager(f.repoRoot).List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// snapshots returns all snapshot IDs sorted for determinism.
+func (f *fuzzer) snapshots() ([]model.SnapshotID, error) {
+	all, err := snapshot.ListAll(f.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]model.SnapshotID, len(all))
+	for i, d := range all {
+		ids[i] = d.SnapshotID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (f *fuzzer) doSnapshot() error {
+	list, err := f.worktrees()
+	if err != nil {
+		return err
+	}
+	var candidates []*model.WorktreeConfig
+	for _, cfg := range list {
+		if cfg.CanSnapshot() {
+			candidates = append(candidates, cfg)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	cfg := candidates[f.rng.IntN(len(candidates))]
+
+	if err := f.mutate(repo.WorktreePayloadPath(f.repoRoot, cfg.Name)); err != nil {
+		return fmt.Errorf("mutate payload: %w", err)
+	}
+
+	creator := snapshot.NewCreator(f.repoRoot, f.engine)
+	desc, err := creator.Create(cfg.Name, fmt.Sprintf("fuzz op %d", f.op), nil)
+	if err != nil {
+		return err
+	}
+	if err := snapshot.VerifySnapshot(f.repoRoot, desc.SnapshotID, true); err != nil {
+		f.violate("verify_new_snapshot", fmt.Sprintf("%s: %v", desc.SnapshotID, err))
+	}
+	return nil
+}
+
+// mutate applies a few random file changes to a payload directory.
+func (f *fuzzer) mutate(payload string) error {
+	changes := 1 + f.rng.IntN(4)
+	for i := 0; i < changes; i++ {
+		dir := payload
+		if f.rng.IntN(3) == 0 {
+			dir = filepath.Join(payload, fmt.Sprintf("d%d", f.rng.IntN(3)))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(dir, fmt.Sprintf("f%d.txt", f.rng.IntN(8)))
+		if f.rng.IntN(5) == 0 {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		data := make([]byte, f.rng.IntN(1024))
+		for j := range data {
+			data[j] = byte(f.rng.IntN(256))
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fuzzer) doRestore() error {
+	list, err := f.worktrees()
+	if err != nil {
+		return err
+	}
+	ids, err := f.snapshots()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	cfg := list[f.rng.IntN(len(list))]
+	id := ids[f.rng.IntN(len(ids))]
+
+	if err := restore.NewRestorer(f.repoRoot, f.engine).Restore(cfg.Name, id); err != nil {
+		return err
+	}
+	f.checkPayloadMatches(cfg.Name, id)
+	return nil
+}
+
+func (f *fuzzer) doRestoreLatest() error {
+	list, err := f.worktrees()
+	if err != nil {
+		return err
+	}
+	var candidates []*model.WorktreeConfig
+	for _, cfg := range list {
+		if !cfg.IsDetached() {
+			continue
+		}
+		// Latest may have been collected: GC protects heads, not latest
+		if _, err := snapshot.LoadDescriptor(f.repoRoot, cfg.LatestSnapshotID); err == nil {
+			candidates = append(candidates, cfg)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	cfg := candidates[f.rng.IntN(len(candidates))]
+
+	if err := restore.NewRestorer(f.repoRoot, f.engine).RestoreToLatest(cfg.Name); err != nil {
+		return err
+	}
+	f.checkPayloadMatches(cfg.Name, cfg.LatestSnapshotID)
+	return nil
+}
+
+func (f *fuzzer) doFork() error {
+	ids, err := f.snapshots()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	id := ids[f.rng.IntN(len(ids))]
+	f.nextName++
+	name := fmt.Sprintf("fz%d", f.nextName)
+
+	eng := engine.NewEngine(f.engine)
+	if _, err := worktree.NewManager(f.repoRoot).Fork(id, name, func(src, dst string) error {
+		_, err := eng.Clone(src, dst)
+		return err
+	}); err != nil {
+		return err
+	}
+	f.checkPayloadMatches(name, id)
+	return nil
+}
+
+func (f *fuzzer) doGC() error {
+	expected, err := f.expectedProtected()
+	if err != nil {
+		return err
+	}
+
+	collector := gc.NewCollector(f.repoRoot)
+	plan, err := collector.PlanWithPolicy(model.RetentionPolicy{})
+	if err != nil {
+		return err
+	}
+	for _, id := range plan.ToDelete {
+		if expected[id] {
+			f.violate("gc_plans_protected", string(id))
+		}
+	}
+	if err := collector.Run(plan.PlanID); err != nil {
+		return err
+	}
+
+	for id := range expected {
+		if _, err := snapshot.LoadDescriptor(f.repoRoot, id); err != nil {
+			f.violate("protected_snapshot_lost", fmt.Sprintf("%s: %v", id, err))
+			continue
+		}
+		if err := repo.CheckSnapshotReady(f.repoRoot, id); err != nil {
+			f.violate("protected_snapshot_lost", fmt.Sprintf("%s: missing .READY", id))
+		}
+	}
+	f.checkSurvivorsVerify()
+	return nil
+}
+
+// expectedProtected independently computes the protected set defined by the
+// GC spec: worktree heads and their ancestors. The fuzzer creates no pins or
+// intents, so those rules do not apply.
+func (f *fuzzer) expectedProtected() (map[model.SnapshotID]bool, error) {
+	protected := make(map[model.SnapshotID]bool)
+	list, err := f.worktrees()
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range list {
+		id := cfg.HeadSnapshotID
+		for id != "" && !protected[id] {
+			protected[id] = true
+			desc, err := snapshot.LoadDescriptor(f.repoRoot, id)
+			if err != nil || desc.ParentID == nil {
+				break
+			}
+			id = *desc.ParentID
+		}
+	}
+	return protected, nil
+}
+
+func (f *fuzzer) doRemove() error {
+	list, err := f.worktrees()
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	for _, cfg := range list {
+		if cfg.Name != "main" {
+			candidates = append(candidates, cfg.Name)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	name := candidates[f.rng.IntN(len(candidates))]
+	mgr := worktree.NewManager(f.repoRoot)
+	if err := mgr.Remove(name); err != nil {
+		return err
+	}
+	if _, err := mgr.Get(name); err == nil {
+		f.violate("remove_incomplete", name)
+	}
+	return nil
+}
+
+// checkPayloadMatches verifies a worktree payload hashes to the snapshot's payload hash.
+func (f *fuzzer) checkPayloadMatches(wtName string, id model.SnapshotID) {
+	desc, err := snapshot.LoadDescriptor(f.repoRoot, id)
+	if err != nil {
+		f.violate("payload_mismatch", fmt.Sprintf("%s: %v", id, err))
+		return
+	}
+	hash, err := integrity.ComputePayloadRootHash(repo.WorktreePayloadPath(f.repoRoot, wtName), desc.HashAlgorithm)
+	if err != nil {
+		f.violate("payload_mismatch", fmt.Sprintf("%s: %v", wtName, err))
+		return
+	}
+	if hash != desc.PayloadRootHash {
+		f.violate("payload_mismatch", fmt.Sprintf("worktree %s does not match snapshot %s", wtName, id))
+	}
+}
+
+// checkSurvivorsVerify runs strong verification over all remaining snapshots.
+func (f *fuzzer) checkSurvivorsVerify() {
+	results, err := verify.NewVerifier(f.repoRoot).VerifyAll(true)
+	if err != nil {
+		f.violate("verify_failed", err.Error())
+		return
+	}
+	for _, r := range results {
+		if r.TamperDetected || !r.ChecksumValid || !r.PayloadHashValid {
+			f.violate("verify_failed", fmt.Sprintf("%s: %s", r.SnapshotID, r.Error))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/conformance"
+)
+
+func TestFuzz_Passes(t *testing.T) {
+	report, err := conformance.Fuzz(conformance.FuzzOptions{Ops: 150, Seed: 42, Dir: t.TempDir()})
+	require.NoError(t, err)
+	assert.True(t, report.Passed(), "violations: %+v", report.Violations)
+	assert.Equal(t, 150, report.Executed)
+	assert.Positive(t, report.OpCounts[conformance.OpSnapshot])
+}
+
+func TestFuzz_SeedIsDeterministic(t *testing.T) {
+	a, err := conformance.Fuzz(conformance.FuzzOptions{Ops: 60, Seed: 7, Dir: t.TempDir()})
+	require.NoError(t, err)
+	b, err := conformance.Fuzz(conformance.FuzzOptions{Ops: 60, Seed: 7, Dir: t.TempDir()})
+	require.NoError(t, err)
+	assert.Equal(t, a.OpCounts, b.OpCounts)
+	assert.Equal(t, a.Worktrees, b.Worktrees)
+}
+
+func TestFuzz_InvalidOps(t *testing.T) {
+	_, err := conformance.Fuzz(conformance.FuzzOptions{Ops: 0})
+	require.Error(t, err)
+}
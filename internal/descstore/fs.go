@@ -0,0 +1,141 @@
+package descstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// FSStore stores descriptors as JSON files in .jvs/descriptors/.
+type FSStore struct {
+	dir string
+}
+
+// NewFSStore creates a filesystem descriptor store.
+func NewFSStore(repoRoot string) *FSStore {
+	return &FSStore{dir: filepath.Join(repoRoot, ".jvs", "descriptors")}
+}
+
+// Path returns the file holding a descriptor.
+func (s *FSStore) Path(id model.SnapshotID) string {
+	return filepath.Join(s.dir, string(id)+".json")
+}
+
+// Get implements Store.
+func (s *FSStore) Get(id model.SnapshotID) (*model.Descriptor, error) {
+	data, err := os.ReadFile(s.Path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errclass.ErrSnapshotNotFound.WithMessagef("snapshot %s not found", id)
+		}
+		return nil, fmt.Errorf("read descriptor: %w", err)
+	}
+	var desc model.Descriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, errclass.ErrDescriptorCorrupt.WithMessagef("parse descriptor: %v", err)
+	}
+	return &desc, nil
+}
+
+// Exists implements Store.
+func (s *FSStore) Exists(id model.SnapshotID) (bool, error) {
+	_, err := os.Stat(s.Path(id))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Put implements Store.
+func (s *FSStore) Put(desc *model.Descriptor) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileRetry(s.Path(desc.SnapshotID), data, 0644)
+}
+
+// Delete implements Store.
+func (s *FSStore) Delete(id model.SnapshotID) error {
+	if err := os.Remove(s.Path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *FSStore) List() ([]*model.Descriptor, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read descriptors directory: %w", err)
+	}
+	var descs []*model.Descriptor
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		desc, err := s.Get(model.SnapshotID(strings.TrimSuffix(name, ".json")))
+		if err != nil {
+			continue
+		}
+		descs = append(descs, desc)
+	}
+	return descs, nil
+}
+
+// Page implements Store. Every descriptor is read, as files are not
+// ordered, but only the best candidates are kept.
+func (s *FSStore) Page(opts PageOptions) ([]*model.Descriptor, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read descriptors directory: %w", err)
+	}
+	var page []*model.Descriptor
+	trim := func() {
+		slices.SortFunc(page, model.CompareNewestFirst)
+		if len(page) > opts.Limit {
+			page = page[:opts.Limit]
+		}
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		desc, err := s.Get(model.SnapshotID(strings.TrimSuffix(name, ".json")))
+		if err != nil || !opts.selects(desc) {
+			continue
+		}
+		page = append(page, desc)
+		if len(page) >= 2*opts.Limit {
+			trim()
+		}
+	}
+	trim()
+	return page, nil
+}
+
+// Close implements Store.
+func (s *FSStore) Close() error {
+	return nil
+}
@@ -0,0 +1,161 @@
+package descstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// SQLiteFile is the database file used by the SQLite backend, under .jvs/.
+const SQLiteFile = "descriptors.db"
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS descriptors (
+	snapshot_id   TEXT PRIMARY KEY,
+	worktree_name TEXT NOT NULL,
+	created_at    INTEGER NOT NULL,
+	data          BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS descriptors_created_at ON descriptors(created_at);
+`
+
+// SQLiteStore stores descriptors in a SQLite database. The full descriptor is
+// kept as JSON; a few columns are extracted for indexed queries.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens (creating if needed) the SQLite descriptor store.
+func OpenSQLiteStore(repoRoot string) (*SQLiteStore, error) {
+	path := filepath.Join(repoRoot, ".jvs", SQLiteFile)
+	// Rollback journal rather than WAL: WAL needs shared memory, which is
+	// unreliable on network filesystems such as JuiceFS.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(DELETE)&_pragma=synchronous(FULL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open descriptor database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initialize descriptor database: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Get implements Store.
+func (s *SQLiteStore) Get(id model.SnapshotID) (*model.Descriptor, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM descriptors WHERE snapshot_id = ?`, string(id)).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errclass.ErrSnapshotNotFound.WithMessagef("snapshot %s not found", id)
+		}
+		return nil, fmt.Errorf("read descriptor: %w", err)
+	}
+	var desc model.Descriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, errclass.ErrDescriptorCorrupt.WithMessagef("parse descriptor: %v", err)
+	}
+	return &desc, nil
+}
+
+// Exists implements Store.
+func (s *SQLiteStore) Exists(id model.SnapshotID) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM descriptors WHERE snapshot_id = ?`, string(id)).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("read descriptor: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Put implements Store.
+func (s *SQLiteStore) Put(desc *model.Descriptor) error {
+	data, err := json.Marshal(desc)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO descriptors (snapshot_id, worktree_name, created_at, data)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(snapshot_id) DO UPDATE SET
+			worktree_name = excluded.worktree_name,
+			created_at = excluded.created_at,
+			data = excluded.data`,
+		string(desc.SnapshotID), desc.WorktreeName, desc.CreatedAt.UnixNano(), data)
+	if err != nil {
+		return fmt.Errorf("write descriptor: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *SQLiteStore) Delete(id model.SnapshotID) error {
+	if _, err := s.db.Exec(`DELETE FROM descriptors WHERE snapshot_id = ?`, string(id)); err != nil {
+		return fmt.Errorf("delete descriptor: %w", err)
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *SQLiteStore) List() ([]*model.Descriptor, error) {
+	rows, err := s.db.Query(`SELECT data FROM descriptors ORDER BY created_at DESC, snapshot_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list descriptors: %w", err)
+	}
+	defer rows.Close()
+
+	var descs []*model.Descriptor
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("list descriptors: %w", err)
+		}
+		var desc model.Descriptor
+		if err := json.Unmarshal(data, &desc); err != nil {
+			continue
+		}
+		descs = append(descs, &desc)
+	}
+	return descs, rows.Err()
+}
+
+// Page implements Store, reading descriptors in order until the page is
+// full.
+func (s *SQLiteStore) Page(opts PageOptions) ([]*model.Descriptor, error) {
+	afterTime := opts.AfterTime.UnixNano()
+	rows, err := s.db.Query(`SELECT data FROM descriptors
+		WHERE ? = '' OR created_at < ? OR (created_at = ? AND snapshot_id < ?)
+		ORDER BY created_at DESC, snapshot_id DESC`,
+		string(opts.AfterID), afterTime, afterTime, string(opts.AfterID))
+	if err != nil {
+		return nil, fmt.Errorf("list descriptors: %w", err)
+	}
+	defer rows.Close()
+
+	var page []*model.Descriptor
+	for len(page) < opts.Limit && rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("list descriptors: %w", err)
+		}
+		var desc model.Descriptor
+		if err := json.Unmarshal(data, &desc); err != nil || !opts.selects(&desc) {
+			continue
+		}
+		page = append(page, &desc)
+	}
+	return page, rows.Err()
+}
+
+// Close implements Store.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,190 @@
+// Package descstore abstracts snapshot descriptor storage.
+//
+// Descriptors are stored as flat JSON files by default. At scale, metadata
+// operations (listing, filtering) dominate, so a repository can instead keep
+// descriptors in a SQLite database. The backend is recorded per repository in
+// .jvs/descriptor_store and changed with Migrate.
+package descstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Backend names a descriptor storage implementation.
+type Backend string
+
+const (
+	// BackendFS stores one JSON file per descriptor in .jvs/descriptors/.
+	BackendFS Backend = "fs"
+	// BackendSQLite stores descriptors in .jvs/descriptors.db.
+	BackendSQLite Backend = "sqlite"
+)
+
+// BackendFile is the control-plane file recording the repository's backend.
+const BackendFile = "descriptor_store"
+
+// Store reads and writes snapshot descriptors.
+type Store interface {
+	// Get returns a descriptor. A missing descriptor is reported as
+	// errclass.ErrSnapshotNotFound, an unreadable one as
+	// errclass.ErrDescriptorCorrupt.
+	Get(id model.SnapshotID) (*model.Descriptor, error)
+	// Exists reports whether a descriptor is stored, without parsing it.
+	Exists(id model.SnapshotID) (bool, error)
+	// Put creates or replaces a descriptor atomically.
+	Put(desc *model.Descriptor) error
+	// Delete removes a descriptor. Deleting a missing descriptor is not an error.
+	Delete(id model.SnapshotID) error
+	// List returns all readable descriptors in no particular order.
+	// Unreadable descriptors are skipped.
+	List() ([]*model.Descriptor, error)
+	// Page returns the first opts.Limit readable descriptors selected by
+	// opts, ordered by model.CompareNewestFirst, holding no more than about
+	// twice that many in memory.
+	Page(opts PageOptions) ([]*model.Descriptor, error)
+	// Close releases resources held by the store.
+	Close() error
+}
+
+// PageOptions selects the descriptors returned by Store.Page.
+type PageOptions struct {
+	// AfterTime and AfterID, if AfterID is set, are the creation time and
+	// snapshot ID of the last descriptor of the previous page; only
+	// descriptors ordered after it are returned.
+	AfterTime time.Time
+	AfterID   model.SnapshotID
+	// Limit is the most descriptors returned. It must be positive.
+	Limit int
+	// Match, if set, keeps only the descriptors it returns true for.
+	Match func(*model.Descriptor) bool
+}
+
+// selects reports whether desc belongs to the page.
+func (o PageOptions) selects(desc *model.Descriptor) bool {
+	if o.AfterID != "" && model.CompareNewestFirst(&model.Descriptor{SnapshotID: o.AfterID, CreatedAt: o.AfterTime}, desc) >= 0 {
+		return false
+	}
+	return o.Match == nil || o.Match(desc)
+}
+
+// ParseBackend validates a backend name.
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(s); b {
+	case BackendFS, BackendSQLite:
+		return b, nil
+	default:
+		return "", fmt.Errorf("unknown descriptor store backend %q (must be fs or sqlite)", s)
+	}
+}
+
+// CurrentBackend returns the backend configured for a repository.
+// Repositories without a backend file use BackendFS.
+func CurrentBackend(repoRoot string) (Backend, error) {
+	data, err := os.ReadFile(filepath.Join(repoRoot, ".jvs", BackendFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return BackendFS, nil
+		}
+		return "", fmt.Errorf("read descriptor store backend: %w", err)
+	}
+	return ParseBackend(strings.TrimSpace(string(data)))
+}
+
+// Open opens the descriptor store configured for a repository.
+func Open(repoRoot string) (Store, error) {
+	backend, err := CurrentBackend(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBackend(repoRoot, backend)
+}
+
+// OpenBackend opens a specific descriptor store backend for a repository.
+func OpenBackend(repoRoot string, backend Backend) (Store, error) {
+	switch backend {
+	case BackendFS:
+		return NewFSStore(repoRoot), nil
+	case BackendSQLite:
+		return OpenSQLiteStore(repoRoot)
+	default:
+		return nil, fmt.Errorf("unknown descriptor store backend %q", backend)
+	}
+}
+
+// Migrate copies every descriptor from the repository's current backend to
+// target and switches the repository to it. The source data is left in place
+// so that an interrupted migration can simply be rerun.
+// Returns the number of descriptors migrated.
+func Migrate(repoRoot string, target Backend) (int, error) {
+	current, err := CurrentBackend(repoRoot)
+	if err != nil {
+		return 0, err
+	}
+	if current == target {
+		return 0, fmt.Errorf("repository already uses the %s descriptor store", target)
+	}
+
+	src, err := OpenBackend(repoRoot, current)
+	if err != nil {
+		return 0, fmt.Errorf("open %s store: %w", current, err)
+	}
+	defer src.Close()
+
+	dst, err := OpenBackend(repoRoot, target)
+	if err != nil {
+		return 0, fmt.Errorf("open %s store: %w", target, err)
+	}
+	defer dst.Close()
+
+	// Step 1: Copy all descriptors
+	descs, err := src.List()
+	if err != nil {
+		return 0, fmt.Errorf("list descriptors: %w", err)
+	}
+	for _, desc := range descs {
+		if err := dst.Put(desc); err != nil {
+			return 0, fmt.Errorf("migrate descriptor %s: %w", desc.SnapshotID, err)
+		}
+	}
+
+	// Step 2: Drop stale target entries left by an earlier migration and
+	// verify the target holds everything before switching
+	want := make(map[model.SnapshotID]bool, len(descs))
+	for _, desc := range descs {
+		want[desc.SnapshotID] = true
+	}
+	copied, err := dst.List()
+	if err != nil {
+		return 0, fmt.Errorf("list migrated descriptors: %w", err)
+	}
+	have := make(map[model.SnapshotID]bool, len(copied))
+	for _, desc := range copied {
+		if !want[desc.SnapshotID] {
+			if err := dst.Delete(desc.SnapshotID); err != nil {
+				return 0, fmt.Errorf("remove stale descriptor %s: %w", desc.SnapshotID, err)
+			}
+			continue
+		}
+		have[desc.SnapshotID] = true
+	}
+	for id := range want {
+		if !have[id] {
+			return 0, fmt.Errorf("descriptor %s missing after migration", id)
+		}
+	}
+
+	// Step 3: Switch the repository to the new backend
+	path := filepath.Join(repoRoot, ".jvs", BackendFile)
+	if err := fsutil.WriteFileRetry(path, []byte(string(target)+"\n"), 0644); err != nil {
+		return 0, fmt.Errorf("write descriptor store backend: %w", err)
+	}
+
+	return len(descs), nil
+}
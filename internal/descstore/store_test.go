@@ -0,0 +1,190 @@
+package descstore_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	return dir
+}
+
+func testDescriptor(id string, createdAt time.Time) *model.Descriptor {
+	return &model.Descriptor{
+		SnapshotID:      model.SnapshotID(id),
+		WorktreeName:    "main",
+		CreatedAt:       createdAt,
+		Note:            "note " + id,
+		Tags:            []string{"v1"},
+		Engine:          model.EngineCopy,
+		PayloadRootHash: "hash-" + model.HashValue(id),
+	}
+}
+
+func TestStore_Backends(t *testing.T) {
+	for _, backend := range []descstore.Backend{descstore.BackendFS, descstore.BackendSQLite} {
+		t.Run(string(backend), func(t *testing.T) {
+			repoPath := setupTestRepo(t)
+			store, err := descstore.OpenBackend(repoPath, backend)
+			require.NoError(t, err)
+			defer store.Close()
+
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			d1 := testDescriptor("1700000000000-aaaaaaaa", now.Add(-time.Minute))
+			d2 := testDescriptor("1700000000001-bbbbbbbb", now)
+
+			// Missing descriptor
+			_, err = store.Get(d1.SnapshotID)
+			assert.True(t, errors.Is(err, errclass.ErrSnapshotNotFound))
+			exists, err := store.Exists(d1.SnapshotID)
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			require.NoError(t, store.Put(d1))
+			require.NoError(t, store.Put(d2))
+
+			got, err := store.Get(d1.SnapshotID)
+			require.NoError(t, err)
+			assert.Equal(t, d1.Note, got.Note)
+			assert.Equal(t, d1.Tags, got.Tags)
+			assert.True(t, d1.CreatedAt.Equal(got.CreatedAt))
+
+			// Put replaces
+			d1.Note = "updated"
+			require.NoError(t, store.Put(d1))
+			got, err = store.Get(d1.SnapshotID)
+			require.NoError(t, err)
+			assert.Equal(t, "updated", got.Note)
+
+			all, err := store.List()
+			require.NoError(t, err)
+			assert.Len(t, all, 2)
+
+			require.NoError(t, store.Delete(d1.SnapshotID))
+			require.NoError(t, store.Delete(d1.SnapshotID), "deleting twice is not an error")
+			exists, err = store.Exists(d1.SnapshotID)
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			all, err = store.List()
+			require.NoError(t, err)
+			require.Len(t, all, 1)
+			assert.Equal(t, d2.SnapshotID, all[0].SnapshotID)
+		})
+	}
+}
+
+func TestStore_Page(t *testing.T) {
+	for _, backend := range []descstore.Backend{descstore.BackendFS, descstore.BackendSQLite} {
+		t.Run(string(backend), func(t *testing.T) {
+			repoPath := setupTestRepo(t)
+			store, err := descstore.OpenBackend(repoPath, backend)
+			require.NoError(t, err)
+			defer store.Close()
+
+			// Two snapshots share a creation time; ties go by ID
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			var want []model.SnapshotID
+			for i, id := range []string{"1700000000004-eeeeeeee", "1700000000003-dddddddd", "1700000000002-cccccccc", "1700000000001-bbbbbbbb", "1700000000000-aaaaaaaa"} {
+				at := now.Add(-time.Duration(i) * time.Minute)
+				if i == 2 {
+					at = now.Add(-time.Minute)
+				}
+				require.NoError(t, store.Put(testDescriptor(id, at)))
+				if id != "1700000000000-aaaaaaaa" {
+					want = append(want, model.SnapshotID(id))
+				}
+			}
+			skip := func(d *model.Descriptor) bool { return d.SnapshotID != "1700000000000-aaaaaaaa" }
+
+			var got []model.SnapshotID
+			opts := descstore.PageOptions{Limit: 2, Match: skip}
+			for {
+				page, err := store.Page(opts)
+				require.NoError(t, err)
+				if len(page) == 0 {
+					break
+				}
+				require.LessOrEqual(t, len(page), 2)
+				for _, d := range page {
+					got = append(got, d.SnapshotID)
+				}
+				last := page[len(page)-1]
+				opts.AfterTime, opts.AfterID = last.CreatedAt, last.SnapshotID
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestCurrentBackend(t *testing.T) {
+	repoPath := setupTestRepo(t)
+
+	backend, err := descstore.CurrentBackend(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, descstore.BackendFS, backend)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".jvs", descstore.BackendFile), []byte("bogus\n"), 0644))
+	_, err = descstore.CurrentBackend(repoPath)
+	assert.Error(t, err)
+}
+
+func TestParseBackend(t *testing.T) {
+	b, err := descstore.ParseBackend("sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, descstore.BackendSQLite, b)
+
+	_, err = descstore.ParseBackend("postgres")
+	assert.Error(t, err)
+}
+
+func TestMigrate_RoundTrip(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	fsStore := descstore.NewFSStore(repoPath)
+	now := time.Now().UTC()
+	for i, id := range []string{"1700000000000-aaaaaaaa", "1700000000001-bbbbbbbb", "1700000000002-cccccccc"} {
+		require.NoError(t, fsStore.Put(testDescriptor(id, now.Add(time.Duration(i)*time.Second))))
+	}
+
+	count, err := descstore.Migrate(repoPath, descstore.BackendSQLite)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	backend, err := descstore.CurrentBackend(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, descstore.BackendSQLite, backend)
+
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	_, isSQLite := store.(*descstore.SQLiteStore)
+	assert.True(t, isSQLite)
+	require.NoError(t, store.Put(testDescriptor("1700000000003-dddddddd", now.Add(time.Hour))))
+	require.NoError(t, store.Delete("1700000000000-aaaaaaaa"))
+	store.Close()
+
+	// Migrating to the current backend is refused
+	_, err = descstore.Migrate(repoPath, descstore.BackendSQLite)
+	assert.Error(t, err)
+
+	// Back to fs picks up changes made while on sqlite
+	count, err = descstore.Migrate(repoPath, descstore.BackendFS)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	_, err = os.Stat(fsStore.Path("1700000000003-dddddddd"))
+	assert.NoError(t, err)
+	_, err = os.Stat(fsStore.Path("1700000000000-aaaaaaaa"))
+	assert.True(t, os.IsNotExist(err), "descriptor deleted on sqlite must not reappear")
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jvs-project/jvs/internal/format"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
@@ -34,25 +35,39 @@ type Change struct {
 	OldHash   string      `json:"old_hash,omitempty"`
 	NewHash   string      `json:"new_hash,omitempty"`
 	IsSymlink bool        `json:"is_symlink,omitempty"`
+	// SizeDelta is the change in size: Size for an added file, minus the
+	// size of a removed one and Size-OldSize for a modified one.
+	SizeDelta int64 `json:"size_delta"`
+	// Patch is the file's unified diff, set by AttachPatches.
+	Patch string `json:"patch,omitempty"`
 }
 
 // DiffResult represents the result of comparing two snapshots.
 type DiffResult struct {
 	FromSnapshotID model.SnapshotID `json:"from_snapshot_id"`
 	ToSnapshotID   model.SnapshotID `json:"to_snapshot_id"`
-	FromTime       time.Time        `json:"from_time"`
-	ToTime         time.Time        `json:"to_time"`
-	Added          []*Change        `json:"added"`
-	Removed        []*Change        `json:"removed"`
-	Modified       []*Change        `json:"modified"`
-	TotalAdded     int              `json:"total_added"`
-	TotalRemoved   int              `json:"total_removed"`
-	TotalModified  int              `json:"total_modified"`
+	// ToWorktree is set instead of ToSnapshotID when the result compares
+	// a snapshot with the current content of a worktree.
+	ToWorktree    string    `json:"to_worktree,omitempty"`
+	FromTime      time.Time `json:"from_time"`
+	ToTime        time.Time `json:"to_time"`
+	Added         []*Change `json:"added"`
+	Removed       []*Change `json:"removed"`
+	Modified      []*Change `json:"modified"`
+	TotalAdded    int       `json:"total_added"`
+	TotalRemoved  int       `json:"total_removed"`
+	TotalModified int       `json:"total_modified"`
+	// SizeDelta is the sum of the changes' size deltas.
+	SizeDelta int64 `json:"size_delta"`
+
+	// fromRoot and toRoot are the compared trees, read by WritePatch
+	fromRoot, toRoot string
 }
 
 // Differ computes differences between snapshots.
 type Differ struct {
 	repoRoot string
+	skip     func(rel string, isDir bool) bool
 }
 
 // NewDiffer creates a new Differ.
@@ -60,6 +75,12 @@ func NewDiffer(repoRoot string) *Differ {
 	return &Differ{repoRoot: repoRoot}
 }
 
+// SetSkip leaves the paths skip returns true for out of DiffPaths and, for
+// directories, everything below them, as QuickDiff does with its skip.
+func (d *Differ) SetSkip(skip func(rel string, isDir bool) bool) {
+	d.skip = skip
+}
+
 // Diff compares two snapshots and returns the differences.
 // If fromID is empty, compares against an empty snapshot (shows all as added).
 func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
@@ -76,6 +97,18 @@ func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
 		return nil, fmt.Errorf("to snapshot not found: %w", err)
 	}
 
+	result, err := d.DiffPaths(fromPath, toPath)
+	if err != nil {
+		return nil, err
+	}
+	result.FromSnapshotID = fromID
+	result.ToSnapshotID = toID
+	return result, nil
+}
+
+// DiffPaths compares two directory trees, such as a snapshot and a worktree
+// payload. If fromPath is empty, every entry of toPath is reported as added.
+func (d *Differ) DiffPaths(fromPath, toPath string) (*DiffResult, error) {
 	// Build file trees for comparison
 	fromTree := make(map[string]*fileInfo)
 	toTree := make(map[string]*fileInfo)
@@ -89,11 +122,54 @@ func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
 		return nil, fmt.Errorf("build to tree: %w", err)
 	}
 
-	// Compute differences
-	result := &DiffResult{
-		FromSnapshotID: fromID,
-		ToSnapshotID:   toID,
+	result := compareTrees(fromTree, toTree)
+	result.fromRoot, result.toRoot = fromPath, toPath
+	return result, nil
+}
+
+// DiffManifests compares two snapshots by their manifests, without reading
+// their payloads. If from is nil, every entry of to is reported as added.
+// The result has no payload trees to read, so it cannot be patched.
+func DiffManifests(from, to *model.Manifest) *DiffResult {
+	fromTree := make(map[string]*fileInfo)
+	if from != nil {
+		manifestTree(from, fromTree)
+	}
+	toTree := make(map[string]*fileInfo)
+	manifestTree(to, toTree)
+
+	result := compareTrees(fromTree, toTree)
+	if from != nil {
+		result.FromSnapshotID = from.SnapshotID
+	}
+	result.ToSnapshotID = to.SnapshotID
+	return result
+}
+
+// manifestTree adds the files and symlinks of m to tree, like buildTree.
+func manifestTree(m *model.Manifest, tree map[string]*fileInfo) {
+	for _, e := range m.Entries {
+		if e.Type == "dir" {
+			continue
+		}
+		path := filepath.FromSlash(e.Path)
+		info := &fileInfo{
+			Path: path,
+			Mode: os.FileMode(e.Mode),
+			Size: e.Size,
+			Hash: string(e.Hash),
+		}
+		if e.Type == "symlink" {
+			info.Mode |= os.ModeSymlink
+			info.IsSymlink = true
+		}
+		tree[path] = info
 	}
+}
+
+// compareTrees computes the changes from fromTree to toTree.
+func compareTrees(fromTree, toTree map[string]*fileInfo) *DiffResult {
+	result := &DiffResult{}
 
 	// Find added and modified files
 	for path, toInfo := range toTree {
@@ -107,6 +183,7 @@ func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
 				Size:      toInfo.Size,
 				NewHash:   toInfo.Hash,
 				IsSymlink: toInfo.IsSymlink,
+				SizeDelta: toInfo.Size,
 			})
 		} else if !fromInfo.equals(toInfo) {
 			// File was modified
@@ -119,6 +196,7 @@ func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
 				OldHash:   fromInfo.Hash,
 				NewHash:   toInfo.Hash,
 				IsSymlink: toInfo.IsSymlink,
+				SizeDelta: toInfo.Size - fromInfo.Size,
 			})
 		}
 	}
@@ -133,6 +211,7 @@ func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
 				Size:      fromInfo.Size,
 				OldHash:   fromInfo.Hash,
 				IsSymlink: fromInfo.IsSymlink,
+				SizeDelta: -fromInfo.Size,
 			})
 		}
 	}
@@ -145,8 +224,13 @@ func (d *Differ) Diff(fromID, toID model.SnapshotID) (*DiffResult, error) {
 	result.TotalAdded = len(result.Added)
 	result.TotalRemoved = len(result.Removed)
 	result.TotalModified = len(result.Modified)
+	for _, changes := range [][]*Change{result.Added, result.Removed, result.Modified} {
+		for _, c := range changes {
+			result.SizeDelta += c.SizeDelta
+		}
+	}
 
-	return result, nil
+	return result
 }
 
 // fileInfo represents metadata about a file in a snapshot.
@@ -192,6 +276,9 @@ func (d *Differ) buildTree(root, relPath string, tree map[string]*fileInfo) erro
 		if err != nil {
 			return err
 		}
+		if d.skip != nil && d.skip(entryPath, info.IsDir()) {
+			continue
+		}
 
 		// Check if it's a symlink
 		isSymlink := entry.Type()&os.ModeSymlink != 0
@@ -264,21 +351,23 @@ func sortChanges(changes []*Change) {
 	})
 }
 
-// FormatHuman returns a human-readable string representation of the diff.
-func (r *DiffResult) FormatHuman() string {
+// FormatHuman returns a human-readable string representation of the diff,
+// rendering times and sizes according to opts.
+func (r *DiffResult) FormatHuman(opts format.Options) string {
+	now := time.Now()
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("Diff %s -> %s\n", r.FromSnapshotID.ShortID(), r.ToSnapshotID.ShortID()))
 	if !r.FromTime.IsZero() {
-		sb.WriteString(fmt.Sprintf("From: %s\n", r.FromTime.Format("2006-01-02 15:04:05")))
+		sb.WriteString(fmt.Sprintf("From: %s\n", opts.Time(r.FromTime, now)))
 	}
-	sb.WriteString(fmt.Sprintf("To:   %s\n", r.ToTime.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("To:   %s\n", opts.Time(r.ToTime, now)))
 	sb.WriteString("\n")
 
 	if r.TotalAdded > 0 {
 		sb.WriteString(fmt.Sprintf("Added (%d):\n", r.TotalAdded))
 		for _, c := range r.Added {
-			sb.WriteString(fmt.Sprintf("  + %s\n", c.Path))
+			sb.WriteString(fmt.Sprintf("  + %s (%s)\n", c.Path, opts.SizeDelta(c.SizeDelta)))
 		}
 		sb.WriteString("\n")
 	}
@@ -286,7 +375,7 @@ func (r *DiffResult) FormatHuman() string {
 	if r.TotalRemoved > 0 {
 		sb.WriteString(fmt.Sprintf("Removed (%d):\n", r.TotalRemoved))
 		for _, c := range r.Removed {
-			sb.WriteString(fmt.Sprintf("  - %s\n", c.Path))
+			sb.WriteString(fmt.Sprintf("  - %s (%s)\n", c.Path, opts.SizeDelta(c.SizeDelta)))
 		}
 		sb.WriteString("\n")
 	}
@@ -296,7 +385,7 @@ func (r *DiffResult) FormatHuman() string {
 		for _, c := range r.Modified {
 			sb.WriteString(fmt.Sprintf("  ~ %s", c.Path))
 			if c.OldSize != c.Size {
-				sb.WriteString(fmt.Sprintf(" (%d -> %d bytes)", c.OldSize, c.Size))
+				sb.WriteString(fmt.Sprintf(" (%s -> %s, %s)", opts.Size(c.OldSize), opts.Size(c.Size), opts.SizeDelta(c.Size-c.OldSize)))
 			}
 			sb.WriteString("\n")
 		}
@@ -305,6 +394,8 @@ func (r *DiffResult) FormatHuman() string {
 
 	if r.TotalAdded == 0 && r.TotalRemoved == 0 && r.TotalModified == 0 {
 		sb.WriteString("No changes.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Size change: %s\n", opts.SizeDelta(r.SizeDelta)))
 	}
 
 	return sb.String()
@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/format"
 )
 
 func TestDiffer_Diff_NoChanges(t *testing.T) {
@@ -49,6 +51,7 @@ func TestDiffer_Diff_AddedFile(t *testing.T) {
 
 	assert.Equal(t, 1, result.TotalAdded)
 	assert.Equal(t, "newfile.txt", result.Added[0].Path)
+	assert.Equal(t, int64(3), result.Added[0].SizeDelta)
 	assert.Equal(t, 0, result.TotalRemoved)
 	assert.Equal(t, 0, result.TotalModified)
 }
@@ -71,6 +74,8 @@ func TestDiffer_Diff_RemovedFile(t *testing.T) {
 	assert.Equal(t, 0, result.TotalAdded)
 	assert.Equal(t, 1, result.TotalRemoved)
 	assert.Equal(t, "removed.txt", result.Removed[0].Path)
+	assert.Equal(t, int64(-4), result.Removed[0].SizeDelta)
+	assert.Equal(t, int64(-4), result.SizeDelta)
 	assert.Equal(t, 0, result.TotalModified)
 }
 
@@ -201,14 +206,18 @@ func TestDiffResult_FormatHuman(t *testing.T) {
 		TotalModified: 1,
 	}
 
-	output := result.FormatHuman()
+	output := result.FormatHuman(format.Options{ExactBytes: true})
 	assert.Contains(t, output, "Added (1):")
 	assert.Contains(t, output, "+ newfile.txt")
 	assert.Contains(t, output, "Removed (1):")
 	assert.Contains(t, output, "- oldfile.txt")
 	assert.Contains(t, output, "Modified (1):")
 	assert.Contains(t, output, "~ changed.txt")
-	assert.Contains(t, output, "(100 -> 200 bytes)")
+	assert.Contains(t, output, "(100 -> 200, +100)")
+	assert.Contains(t, output, "Size change: +0")
+
+	output = result.FormatHuman(format.Options{})
+	assert.Contains(t, output, "(100 B -> 200 B, +100 B)")
 }
 
 func TestDiff_NonExistentSnapshot(t *testing.T) {
@@ -263,6 +272,27 @@ func TestDiffResult_FormatHuman_NoChanges(t *testing.T) {
 		TotalModified:  0,
 	}
 
-	output := result.FormatHuman()
+	output := result.FormatHuman(format.Options{})
 	assert.Contains(t, output, "No changes.")
 }
+
+func TestDiffer_DiffPaths_Skip(t *testing.T) {
+	from, to := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(from, "kept.txt"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(to, "kept.txt"), []byte("v2"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(to, "debug.log"), []byte("log"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(to, "cache", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(to, "cache", "sub", "blob"), []byte("cached"), 0644))
+
+	differ := NewDiffer(t.TempDir())
+	differ.SetSkip(func(rel string, isDir bool) bool {
+		return rel == "cache" || filepath.Ext(rel) == ".log"
+	})
+	result, err := differ.DiffPaths(from, to)
+	require.NoError(t, err)
+	assert.Empty(t, result.Added)
+	require.Len(t, result.Modified, 1)
+	assert.Equal(t, "kept.txt", result.Modified[0].Path)
+	assert.NotEmpty(t, result.Modified[0].OldHash)
+	assert.NotEqual(t, result.Modified[0].OldHash, result.Modified[0].NewHash)
+}
@@ -0,0 +1,493 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+// Patch defaults.
+const (
+	DefaultPatchContext  = 3
+	DefaultPatchMaxBytes = 1 << 20
+)
+
+// maxEditDistance bounds the work spent on one file: files that differ by
+// more lines than this are shown as fully replaced.
+const maxEditDistance = 2000
+
+// binarySniffLen is how much of a file is inspected to decide whether it is
+// text, as git does.
+const binarySniffLen = 8000
+
+// PatchOptions configures WritePatch.
+type PatchOptions struct {
+	// Context is the number of unchanged lines shown around each change;
+	// zero uses DefaultPatchContext, negative shows none.
+	Context int
+	// MaxBytes skips files larger than this on either side; zero uses
+	// DefaultPatchMaxBytes, negative means no limit.
+	MaxBytes int64
+}
+
+// WritePatch writes a unified diff of every change in r to w, one file at a
+// time in path order, so large results are streamed rather than built in
+// memory. Binary files and files over the size limit are reported by a
+// single line instead of a patch. Hunk headers name the enclosing function
+// or section when the file's language is recognized by its extension.
+func (r *DiffResult) WritePatch(w io.Writer, opts PatchOptions) error {
+	opts = opts.withDefaults()
+	changes := make([]*Change, 0, len(r.Added)+len(r.Removed)+len(r.Modified))
+	changes = append(changes, r.Added...)
+	changes = append(changes, r.Removed...)
+	changes = append(changes, r.Modified...)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	bw := bufio.NewWriter(w)
+	for _, c := range changes {
+		if err := r.writeFilePatch(bw, c, opts, color.Enabled()); err != nil {
+			return fmt.Errorf("patch %s: %w", c.Path, err)
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttachPatches sets the Patch field of every change to the same text
+// WritePatch writes for it, without color, so that the patches travel with
+// the result, e.g. in JSON.
+func (r *DiffResult) AttachPatches(opts PatchOptions) error {
+	opts = opts.withDefaults()
+	for _, changes := range [][]*Change{r.Added, r.Removed, r.Modified} {
+		for _, c := range changes {
+			var buf bytes.Buffer
+			bw := bufio.NewWriter(&buf)
+			if err := r.writeFilePatch(bw, c, opts, false); err != nil {
+				return fmt.Errorf("patch %s: %w", c.Path, err)
+			}
+			bw.Flush()
+			c.Patch = buf.String()
+		}
+	}
+	return nil
+}
+
+func (o PatchOptions) withDefaults() PatchOptions {
+	if o.Context == 0 {
+		o.Context = DefaultPatchContext
+	} else if o.Context < 0 {
+		o.Context = 0
+	}
+	if o.MaxBytes == 0 {
+		o.MaxBytes = DefaultPatchMaxBytes
+	}
+	return o
+}
+
+// paint applies a color function only if colored is set.
+func paint(colored bool, fn func(string) string, s string) string {
+	if !colored {
+		return s
+	}
+	return fn(s)
+}
+
+func (r *DiffResult) writeFilePatch(w *bufio.Writer, c *Change, opts PatchOptions, colored bool) error {
+	if r.toRoot == "" {
+		return fmt.Errorf("diff was computed from manifests and has no content to patch")
+	}
+	slash := filepath.ToSlash(c.Path)
+	oldName, newName := "a/"+slash, "b/"+slash
+	var oldPath, newPath string
+	if c.Type != ChangeAdded {
+		oldPath = filepath.Join(r.fromRoot, c.Path)
+	} else {
+		oldName = "/dev/null"
+	}
+	if c.Type != ChangeRemoved {
+		newPath = filepath.Join(r.toRoot, c.Path)
+	} else {
+		newName = "/dev/null"
+	}
+
+	fmt.Fprintln(w, paint(colored, color.Header, fmt.Sprintf("diff --git a/%s b/%s", slash, slash)))
+	switch c.Type {
+	case ChangeAdded:
+		fmt.Fprintf(w, "new file mode %06o\n", gitMode(c))
+	case ChangeRemoved:
+		fmt.Fprintf(w, "deleted file mode %06o\n", gitMode(c))
+	}
+
+	oldData, oldTooLarge, err := readForPatch(oldPath, opts.MaxBytes)
+	if err != nil {
+		return err
+	}
+	newData, newTooLarge, err := readForPatch(newPath, opts.MaxBytes)
+	if err != nil {
+		return err
+	}
+	if oldTooLarge || newTooLarge {
+		fmt.Fprintln(w, paint(colored, color.Dim, fmt.Sprintf("File too large to diff (limit %d bytes)", opts.MaxBytes)))
+		return nil
+	}
+	if isBinary(oldData) || isBinary(newData) {
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", oldName, newName)
+		return nil
+	}
+
+	oldLines, newLines := splitLines(oldData), splitLines(newData)
+	hunks := buildHunks(editScript(oldLines, newLines), opts.Context)
+	if len(hunks) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, paint(colored, color.Header, "--- "+oldName))
+	fmt.Fprintln(w, paint(colored, color.Header, "+++ "+newName))
+	funcLine := funcMatcher(c.Path)
+	for _, h := range hunks {
+		header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.oldStart, h.oldLines), hunkRange(h.newStart, h.newLines))
+		fmt.Fprint(w, paint(colored, color.Info, header))
+		if fn := enclosingFunc(oldLines, h.oldStart-1, funcLine); fn != "" {
+			fmt.Fprint(w, " "+fn)
+		}
+		fmt.Fprintln(w)
+		for _, op := range h.ops {
+			writeLine(w, op, oldLines, newLines, colored)
+		}
+	}
+	return nil
+}
+
+// gitMode returns the git-style mode of a change, for file headers.
+func gitMode(c *Change) uint32 {
+	if c.IsSymlink {
+		return 0120000
+	}
+	return 0100000 | uint32(c.Mode.Perm())
+}
+
+// readForPatch returns the content to diff at path: the file's content, a
+// symlink's target, or nothing if path is "". It reports whether the file
+// exceeds maxBytes instead of reading it.
+func readForPatch(path string, maxBytes int64) ([]byte, bool, error) {
+	if path == "" {
+		return nil, false, nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(target), false, nil
+	}
+	if maxBytes > 0 && info.Size() > maxBytes {
+		return nil, true, nil
+	}
+	data, err := os.ReadFile(path)
+	return data, false, err
+}
+
+// isBinary reports whether data looks binary: a NUL byte or invalid UTF-8
+// in its first bytes.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+		// Do not count a multi-byte rune cut off at the end
+		for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+			if utf8.RuneStart(data[len(data)-i]) {
+				if !utf8.FullRune(data[len(data)-i:]) {
+					data = data[:len(data)-i]
+				}
+				break
+			}
+		}
+	}
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// line is one line of a file; noEOL marks a last line without a newline.
+type line struct {
+	text  string
+	noEOL bool
+}
+
+func splitLines(data []byte) []line {
+	if len(data) == 0 {
+		return nil
+	}
+	parts := strings.SplitAfter(string(data), "\n")
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	lines := make([]line, len(parts))
+	for i, p := range parts {
+		lines[i] = line{text: strings.TrimSuffix(p, "\n"), noEOL: !strings.HasSuffix(p, "\n")}
+	}
+	return lines
+}
+
+// edit operation kinds.
+const (
+	opEqual = iota
+	opDelete
+	opInsert
+)
+
+// edit is one step of an edit script: a line kept (old and new index), deleted
+// (old index) or inserted (new index).
+type edit struct {
+	kind     int
+	old, new int
+}
+
+// editScript returns a shortest edit script from a to b using Myers'
+// algorithm. Beyond maxEditDistance it gives up and replaces everything
+// between the common prefix and suffix.
+func editScript(a, b []line) []edit {
+	// Trim the common prefix and suffix, which most edits leave large
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var script []edit
+	for i := 0; i < prefix; i++ {
+		script = append(script, edit{opEqual, i, i})
+	}
+	script = append(script, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := 0; i < suffix; i++ {
+		script = append(script, edit{opEqual, len(a) - suffix + i, len(b) - suffix + i})
+	}
+	return script
+}
+
+// myers diffs a and b, whose first lines are at index offset in both files.
+func myers(a, b []line, offset int) []edit {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil
+	}
+	limit := min(total, maxEditDistance)
+
+	// v[k+total] is the furthest x reached on diagonal k; trace keeps v for
+	// each edit distance d to backtrack
+	v := make([]int, 2*total+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v[total-d:total+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+total] < v[k+1+total]) {
+				x = v[k+1+total]
+			} else {
+				x = v[k-1+total] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+total] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		script := make([]edit, 0, n+m)
+		for i := 0; i < n; i++ {
+			script = append(script, edit{opDelete, offset + i, offset})
+		}
+		for j := 0; j < m; j++ {
+			script = append(script, edit{opInsert, offset + n, offset + j})
+		}
+		return script
+	}
+
+	// Backtrack from (n, m) through the saved frontiers
+	var rev []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d] // v before step d, covering diagonals -d..d
+		at := func(k int) int { return prev[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, edit{opEqual, offset + x, offset + y})
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, edit{opInsert, offset + x, offset + y})
+		} else {
+			x--
+			rev = append(rev, edit{opDelete, offset + x, offset + y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		rev = append(rev, edit{opEqual, offset + x, offset + y})
+	}
+
+	script := make([]edit, len(rev))
+	for i, e := range rev {
+		script[len(rev)-1-i] = e
+	}
+	return script
+}
+
+// hunk is a group of edits with surrounding context. Starts are 1-based.
+type hunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	ops                []edit
+}
+
+// buildHunks groups the changes of script with up to context unchanged
+// lines around them, merging groups whose context touches.
+func buildHunks(script []edit, context int) []hunk {
+	type span struct{ lo, hi int }
+	var spans []span
+	for i, e := range script {
+		if e.kind == opEqual {
+			continue
+		}
+		lo, hi := max(0, i-context), min(len(script), i+1+context)
+		if n := len(spans); n > 0 && lo <= spans[n-1].hi {
+			spans[n-1].hi = hi
+		} else {
+			spans = append(spans, span{lo, hi})
+		}
+	}
+
+	hunks := make([]hunk, len(spans))
+	for i, sp := range spans {
+		h := &hunks[i]
+		h.ops = script[sp.lo:sp.hi]
+		h.oldStart, h.newStart = h.ops[0].old+1, h.ops[0].new+1
+		for _, op := range h.ops {
+			if op.kind != opInsert {
+				h.oldLines++
+			}
+			if op.kind != opDelete {
+				h.newLines++
+			}
+		}
+		// An empty side starts at the line before it, as diff does
+		if h.oldLines == 0 {
+			h.oldStart--
+		}
+		if h.newLines == 0 {
+			h.newStart--
+		}
+	}
+	return hunks
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+func writeLine(w *bufio.Writer, op edit, oldLines, newLines []line, colored bool) {
+	var l line
+	var s string
+	switch op.kind {
+	case opEqual:
+		l = newLines[op.new]
+		s = " " + l.text
+	case opDelete:
+		l = oldLines[op.old]
+		s = paint(colored, color.Error, "-"+l.text)
+	case opInsert:
+		l = newLines[op.new]
+		s = paint(colored, color.Success, "+"+l.text)
+	}
+	fmt.Fprintln(w, s)
+	if l.noEOL {
+		fmt.Fprintln(w, `\ No newline at end of file`)
+	}
+}
+
+// Function header patterns by file extension, for hunk headers. Files of
+// other types use a line starting with a letter, '_' or '$', like git.
+var funcPatterns = map[string]*regexp.Regexp{
+	".go":   regexp.MustCompile(`^(func|type)\b`),
+	".py":   regexp.MustCompile(`^\s*(async\s+def|def|class)\s`),
+	".js":   regexp.MustCompile(`^\s*((export\s+)?(default\s+)?(async\s+)?function\b|class\s|(export\s+)?(const|let)\s+\w+\s*=\s*(async\s+)?(\(|function))`),
+	".ts":   regexp.MustCompile(`^\s*((export\s+)?(default\s+)?(async\s+)?function\b|(export\s+)?(abstract\s+)?class\s|(export\s+)?interface\s|(export\s+)?(const|let)\s+\w+\s*=\s*(async\s+)?\()`),
+	".java": regexp.MustCompile(`^\s*((public|protected|private|static|final|abstract)\s+)+[\w<>\[\], ]+\s+\w+\s*\(|^\s*((public|protected|private)\s+)?(class|interface|enum)\s`),
+	".c":    regexp.MustCompile(`^[A-Za-z_][\w\s\*]*\s\**\w+\s*\([^;]*$`),
+	".rs":   regexp.MustCompile(`^\s*(pub(\([\w:]+\))?\s+)?(async\s+)?(fn|struct|enum|trait|impl|mod)\b`),
+	".rb":   regexp.MustCompile(`^\s*(def|class|module)\s`),
+	".sh":   regexp.MustCompile(`^\s*(function\s+\w+|\w+\s*\(\)\s*\{?)`),
+	".md":   regexp.MustCompile(`^#{1,6}\s`),
+	".yaml": regexp.MustCompile(`^[A-Za-z_][\w.-]*:`),
+	".toml": regexp.MustCompile(`^\[`),
+	".ini":  regexp.MustCompile(`^\[`),
+}
+
+var defaultFuncPattern = regexp.MustCompile(`^[A-Za-z_$]`)
+
+func init() {
+	for alias, ext := range map[string]string{
+		".jsx": ".js", ".mjs": ".js", ".tsx": ".ts", ".h": ".c", ".cc": ".c", ".cpp": ".c",
+		".hpp": ".c", ".bash": ".sh", ".markdown": ".md", ".yml": ".yaml", ".cfg": ".ini",
+	} {
+		funcPatterns[alias] = funcPatterns[ext]
+	}
+}
+
+func funcMatcher(path string) *regexp.Regexp {
+	if re, ok := funcPatterns[strings.ToLower(filepath.Ext(path))]; ok {
+		return re
+	}
+	return defaultFuncPattern
+}
+
+// enclosingFunc returns the nearest line before index before (0-based) that
+// matches re, trimmed to fit a hunk header, or "".
+func enclosingFunc(lines []line, before int, re *regexp.Regexp) string {
+	for i := min(before, len(lines)) - 1; i >= 0; i-- {
+		if re.MatchString(lines[i].text) {
+			text := strings.TrimRight(lines[i].text, " \t{")
+			if len(text) > 80 {
+				text = text[:80]
+			}
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/color"
+)
+
+func writePatchTrees(t *testing.T, from, to map[string]string) string {
+	t.Helper()
+	color.Disable()
+	t.Cleanup(color.Enable)
+	root := t.TempDir()
+	for dir, files := range map[string]map[string]string{"from": from, "to": to} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(root, dir, name), []byte(content), 0644))
+		}
+	}
+	result, err := NewDiffer(root).DiffPaths(filepath.Join(root, "from"), filepath.Join(root, "to"))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, result.WritePatch(&buf, PatchOptions{MaxBytes: 256}))
+	return buf.String()
+}
+
+func TestWritePatch_Text(t *testing.T) {
+	oldMain := "package main\n\nfunc main() {\n\ta := 1\n\tb := 2\n\tc := 3\n\td := 4\n\te := 5\n\tf := 6\n}\n"
+	newMain := "package main\n\nfunc main() {\n\ta := 1\n\tb := 2\n\tc := 3\n\td := 40\n\te := 5\n\tf := 6\n}\n"
+	out := writePatchTrees(t,
+		map[string]string{"main.go": oldMain, "gone.txt": "bye\n"},
+		map[string]string{"main.go": newMain, "new.txt": "hello"},
+	)
+	assert.Equal(t, `diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -4,7 +4,7 @@ func main()
+ 	a := 1
+ 	b := 2
+ 	c := 3
+-	d := 4
++	d := 40
+ 	e := 5
+ 	f := 6
+ }
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+\ No newline at end of file
+`, out)
+}
+
+func TestWritePatch_BinaryAndLarge(t *testing.T) {
+	out := writePatchTrees(t,
+		map[string]string{"blob.bin": "a\x00b", "big.txt": "small\n"},
+		map[string]string{"blob.bin": "a\x00c", "big.txt": strings.Repeat("x", 300)},
+	)
+	assert.Contains(t, out, "Binary files a/blob.bin and b/blob.bin differ")
+	assert.Contains(t, out, "File too large to diff (limit 256 bytes)")
+	assert.NotContains(t, out, "@@")
+}
+
+func TestAttachPatches(t *testing.T) {
+	if !color.Enabled() {
+		color.Enable()
+		t.Cleanup(color.Disable)
+	}
+	root := t.TempDir()
+	from, to := filepath.Join(root, "from"), filepath.Join(root, "to")
+	require.NoError(t, os.MkdirAll(from, 0755))
+	require.NoError(t, os.MkdirAll(to, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "a.txt"), []byte("one\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(to, "a.txt"), []byte("two\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(to, "big.txt"), []byte(strings.Repeat("x", 300)), 0644))
+
+	result, err := NewDiffer(root).DiffPaths(from, to)
+	require.NoError(t, err)
+	require.NoError(t, result.AttachPatches(PatchOptions{MaxBytes: 256}))
+
+	// Patches are plain text even when color is on
+	assert.Equal(t, "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+two\n", result.Modified[0].Patch)
+	assert.Contains(t, result.Added[0].Patch, "File too large to diff")
+}
+
+func TestEditScript_Reconstructs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []line {
+		lines := make([]line, rng.Intn(30))
+		for i := range lines {
+			lines[i] = line{text: fmt.Sprint(rng.Intn(5))}
+		}
+		return lines
+	}
+	for i := 0; i < 200; i++ {
+		a, b := randomLines(), randomLines()
+		var gotA, gotB []line
+		for _, e := range editScript(a, b) {
+			switch e.kind {
+			case opEqual:
+				require.Equal(t, a[e.old], b[e.new])
+				gotA, gotB = append(gotA, a[e.old]), append(gotB, b[e.new])
+			case opDelete:
+				gotA = append(gotA, a[e.old])
+			case opInsert:
+				gotB = append(gotB, b[e.new])
+			}
+		}
+		assert.Equal(t, len(a), len(gotA))
+		assert.Equal(t, len(b), len(gotB))
+		if len(a) > 0 {
+			assert.Equal(t, a, gotA)
+		}
+		if len(b) > 0 {
+			assert.Equal(t, b, gotB)
+		}
+	}
+}
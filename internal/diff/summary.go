@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Summary counts the file changes between two trees, for audit records.
+type Summary struct {
+	Added       int `json:"added"`
+	Removed     int `json:"removed"`
+	Overwritten int `json:"overwritten"`
+	// BytesChanged is the size of the files added and overwritten, at their
+	// new size, plus the size of the files removed.
+	BytesChanged int64 `json:"bytes_changed"`
+}
+
+// Summarize counts the files and symlinks that replacing the tree at
+// fromPath with the one at toPath adds, removes and overwrites, comparing
+// them as QuickDiff does.
+func Summarize(fromPath, toPath string) (*Summary, error) {
+	r, err := QuickDiff(fromPath, toPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summary{Added: r.TotalAdded, Removed: r.TotalRemoved, Overwritten: r.TotalModified}
+	for _, changes := range [][]*Change{r.Added, r.Removed, r.Modified} {
+		for _, c := range changes {
+			s.BytesChanged += c.Size
+		}
+	}
+	return s, nil
+}
+
+// manifestEntry is the metadata QuickDiff compares a file by.
+type manifestEntry struct {
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	target  string // symlink target
+}
+
+// QuickDiff compares two trees like DiffPaths without hashing every file:
+// files are compared by their manifest of type, size, executable bits and
+// modification time, which the engines preserve, and only files that match
+// in all but modification time have their content compared. Write bits are
+// ignored, as frozen worktrees clear them. If fromPath is empty, every entry
+// of toPath is reported as added. skip, if not nil, leaves out the paths it
+// returns true for and, for directories, everything below them. Changes
+// carry no hashes.
+func QuickDiff(fromPath, toPath string, skip func(rel string, isDir bool) bool) (*DiffResult, error) {
+	from, err := buildManifest(fromPath, skip)
+	if err != nil {
+		return nil, err
+	}
+	to, err := buildManifest(toPath, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DiffResult{fromRoot: fromPath, toRoot: toPath}
+	for path, t := range to {
+		f, ok := from[path]
+		if !ok {
+			result.Added = append(result.Added, &Change{
+				Path:      path,
+				Type:      ChangeAdded,
+				Mode:      t.mode,
+				Size:      t.size,
+				IsSymlink: t.target != "",
+				SizeDelta: t.size,
+			})
+			continue
+		}
+		changed := comparableMode(f.mode) != comparableMode(t.mode) || f.size != t.size || f.target != t.target
+		if !changed && !f.modTime.Equal(t.modTime) && t.mode.IsRegular() {
+			same, err := sameContent(filepath.Join(fromPath, path), filepath.Join(toPath, path))
+			if err != nil {
+				return nil, err
+			}
+			changed = !same
+		}
+		if changed {
+			result.Modified = append(result.Modified, &Change{
+				Path:      path,
+				Type:      ChangeModified,
+				Mode:      t.mode,
+				Size:      t.size,
+				OldSize:   f.size,
+				IsSymlink: t.target != "",
+				SizeDelta: t.size - f.size,
+			})
+		}
+	}
+	for path, f := range from {
+		if _, ok := to[path]; !ok {
+			result.Removed = append(result.Removed, &Change{
+				Path:      path,
+				Type:      ChangeRemoved,
+				Mode:      f.mode,
+				Size:      f.size,
+				IsSymlink: f.target != "",
+				SizeDelta: -f.size,
+			})
+		}
+	}
+
+	sortChanges(result.Added)
+	sortChanges(result.Removed)
+	sortChanges(result.Modified)
+	result.TotalAdded = len(result.Added)
+	result.TotalRemoved = len(result.Removed)
+	result.TotalModified = len(result.Modified)
+	for _, changes := range [][]*Change{result.Added, result.Removed, result.Modified} {
+		for _, c := range changes {
+			result.SizeDelta += c.SizeDelta
+		}
+	}
+	return result, nil
+}
+
+// comparableMode keeps the file type and executable bits of mode.
+func comparableMode(mode os.FileMode) os.FileMode {
+	return mode.Type() | mode&0111
+}
+
+// buildManifest maps the relative path of every file and symlink under
+// root to its metadata. An empty root is an empty tree.
+func buildManifest(root string, skip func(rel string, isDir bool) bool) (map[string]manifestEntry, error) {
+	manifest := make(map[string]manifestEntry)
+	if root == "" {
+		return manifest, nil
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if skip != nil && skip(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || d.Name() == ".READY" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := manifestEntry{size: info.Size(), mode: info.Mode(), modTime: info.ModTime()}
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
+			if entry.target, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		manifest[rel] = entry
+		return nil
+	})
+	return manifest, err
+}
+
+// sameContent reports whether two files of equal size hold the same bytes.
+func sameContent(a, b string) (bool, error) {
+	d := &Differ{}
+	ha, err := d.hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := d.hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return ha == hb, nil
+}
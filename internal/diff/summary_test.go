@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickDiff(t *testing.T) {
+	from, to := t.TempDir(), t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	write := func(root, name, content string, mode os.FileMode, mtime time.Time) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), mode))
+		require.NoError(t, os.Chmod(path, mode))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	write(from, "same.txt", "same", 0644, old)
+	write(to, "same.txt", "same", 0644, old)
+	// Touched but unchanged
+	write(from, "touched.txt", "abc", 0644, old)
+	write(to, "touched.txt", "abc", 0644, time.Now())
+	// Same size, new content
+	write(from, "edited.txt", "abc", 0644, old)
+	write(to, "edited.txt", "xyz", 0644, time.Now())
+	// Frozen: write bits cleared
+	write(from, "frozen.txt", "f", 0644, old)
+	write(to, "frozen.txt", "f", 0444, old)
+	write(from, "run.sh", "#!/bin/sh", 0644, old)
+	write(to, "run.sh", "#!/bin/sh", 0755, old)
+	write(from, "gone.txt", "gone", 0644, old)
+	write(to, "new.txt", "hello", 0644, old)
+	write(to, "cache/big.bin", "ignored", 0644, old)
+
+	skip := func(rel string, isDir bool) bool { return isDir && rel == "cache" }
+	result, err := QuickDiff(from, to, skip)
+	require.NoError(t, err)
+
+	var modified []string
+	for _, c := range result.Modified {
+		modified = append(modified, c.Path)
+	}
+	assert.ElementsMatch(t, []string{"edited.txt", "run.sh"}, modified)
+	require.Len(t, result.Added, 1)
+	assert.Equal(t, "new.txt", result.Added[0].Path)
+	assert.Equal(t, int64(5), result.Added[0].SizeDelta)
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, "gone.txt", result.Removed[0].Path)
+	assert.Equal(t, int64(1), result.SizeDelta)
+}
+
+func TestQuickDiff_EmptyFrom(t *testing.T) {
+	to := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(to, "a.txt"), []byte("a"), 0644))
+
+	result, err := QuickDiff("", to, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.TotalAdded)
+	assert.Zero(t, result.TotalRemoved+result.TotalModified)
+}
@@ -0,0 +1,76 @@
+package doctor
+
+import (
+	"slices"
+)
+
+// Statuses of a Summary, from best to worst.
+const (
+	StatusHealthy      = "healthy"
+	StatusWarnings     = "warnings"
+	StatusRepairNeeded = "repair_needed"
+	StatusErrors       = "errors"
+)
+
+// Exit codes of 'jvs doctor --ci', one per status. Exit code 1 is left to
+// failures that keep doctor from running at all.
+const (
+	ExitHealthy      = 0
+	ExitWarnings     = 2
+	ExitRepairNeeded = 3
+	ExitErrors       = 4
+)
+
+// Summary classifies a Result for scripts that gate on repository health.
+type Summary struct {
+	Status   string         `json:"status"`
+	ExitCode int            `json:"exit_code"`
+	Counts   map[string]int `json:"counts"`            // findings by severity
+	Repairs  []string       `json:"repairs,omitempty"` // actions that fix the findings, sorted
+	Findings []Finding      `json:"findings"`
+}
+
+// Summarize classifies result. Info findings do not count against health.
+// An error or critical finding that no repair action fixes makes the status
+// errors; otherwise any warning or worse finding that a repair action
+// fixes makes it repair_needed, and any other warning makes it warnings.
+func Summarize(result *Result) *Summary {
+	s := &Summary{
+		Status:   StatusHealthy,
+		ExitCode: ExitHealthy,
+		Counts:   map[string]int{"critical": 0, "error": 0, "warning": 0, "info": 0},
+		Findings: result.Findings,
+	}
+	if s.Findings == nil {
+		s.Findings = []Finding{}
+	}
+	var unrepaired, repairable, warnings bool
+	for _, f := range result.Findings {
+		s.Counts[f.Severity]++
+		if f.Severity == "info" {
+			continue
+		}
+		if f.Repair != "" {
+			repairable = true
+			if !slices.Contains(s.Repairs, f.Repair) {
+				s.Repairs = append(s.Repairs, f.Repair)
+			}
+			continue
+		}
+		if f.Severity == "warning" {
+			warnings = true
+		} else {
+			unrepaired = true
+		}
+	}
+	slices.Sort(s.Repairs)
+	switch {
+	case unrepaired || !result.Healthy:
+		s.Status, s.ExitCode = StatusErrors, ExitErrors
+	case repairable:
+		s.Status, s.ExitCode = StatusRepairNeeded, ExitRepairNeeded
+	case warnings:
+		s.Status, s.ExitCode = StatusWarnings, ExitWarnings
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package doctor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/doctor"
+)
+
+func TestSummarize(t *testing.T) {
+	tmp := doctor.Finding{Category: "tmp", Severity: "warning", Repair: "clean_tmp"}
+	intent := doctor.Finding{Category: "intent", Severity: "warning", Repair: "clean_intents"}
+	head := doctor.Finding{Category: "worktree", Severity: "warning"}
+	missing := doctor.Finding{Category: "worktree", Severity: "error"}
+	info := doctor.Finding{Category: "tmp", Severity: "info", Repair: "clean_tmp"}
+
+	tests := []struct {
+		name     string
+		result   doctor.Result
+		status   string
+		exitCode int
+		repairs  []string
+	}{
+		{"no findings", doctor.Result{Healthy: true}, doctor.StatusHealthy, doctor.ExitHealthy, nil},
+		{"info only", doctor.Result{Healthy: true, Findings: []doctor.Finding{info}}, doctor.StatusHealthy, doctor.ExitHealthy, nil},
+		{"warning", doctor.Result{Healthy: true, Findings: []doctor.Finding{head}}, doctor.StatusWarnings, doctor.ExitWarnings, nil},
+		{"repairable", doctor.Result{Healthy: true, Findings: []doctor.Finding{tmp, head, intent, tmp}},
+			doctor.StatusRepairNeeded, doctor.ExitRepairNeeded, []string{"clean_intents", "clean_tmp"}},
+		{"error", doctor.Result{Healthy: true, Findings: []doctor.Finding{tmp, missing}},
+			doctor.StatusErrors, doctor.ExitErrors, []string{"clean_tmp"}},
+		{"unhealthy", doctor.Result{Healthy: false}, doctor.StatusErrors, doctor.ExitErrors, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := doctor.Summarize(&tt.result)
+			assert.Equal(t, tt.status, s.Status)
+			assert.Equal(t, tt.exitCode, s.ExitCode)
+			assert.Equal(t, tt.repairs, s.Repairs)
+			assert.NotNil(t, s.Findings)
+		})
+	}
+
+	s := doctor.Summarize(&doctor.Result{Healthy: true, Findings: []doctor.Finding{tmp, head, info, missing}})
+	assert.Equal(t, map[string]int{"critical": 0, "error": 1, "warning": 2, "info": 1}, s.Counts)
+}
+
+func TestSummarize_RepairRuntimeClearsFindings(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	intentsDir := filepath.Join(repoPath, ".jvs", "intents")
+	require.NoError(t, os.MkdirAll(intentsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(intentsDir, "orphan.json"), []byte("{}"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, ".jvs", "snapshots", "1708300800000-deadbeef.tmp"), 0755))
+
+	doc := doctor.NewDoctor(repoPath)
+	result, err := doc.Check(true)
+	require.NoError(t, err)
+	s := doctor.Summarize(result)
+	assert.Equal(t, doctor.StatusRepairNeeded, s.Status, "%+v", s.Findings)
+	assert.Equal(t, []string{"clean_intents", "clean_tmp"}, s.Repairs)
+
+	_, err = doc.Repair(s.Repairs)
+	require.NoError(t, err)
+	result, err = doc.Check(true)
+	require.NoError(t, err)
+	assert.Equal(t, doctor.StatusHealthy, doctor.Summarize(result).Status)
+}
@@ -2,17 +2,22 @@
 package doctor
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/internal/materialize"
 	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/verify"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
@@ -23,6 +28,8 @@ type Finding struct {
 	Severity    string `json:"severity"`
 	ErrorCode   string `json:"error_code,omitempty"`
 	Path        string `json:"path,omitempty"`
+	// Repair is the ID of the repair action that fixes the finding, if any.
+	Repair string `json:"repair,omitempty"`
 }
 
 // Result contains doctor check results.
@@ -61,9 +68,13 @@ func (d *Doctor) ListRepairActions() []RepairAction {
 	return []RepairAction{
 		{ID: "clean_tmp", Description: "Remove orphan .tmp files and directories", AutoSafe: true},
 		{ID: "clean_intents", Description: "Remove completed/abandoned intent files", AutoSafe: true},
+		{ID: "clean_materialized", Description: "Remove expired materialized snapshot copies", AutoSafe: true},
+		{ID: "replay_audit_wal", Description: "Append audit records left in write-ahead files by crashed processes", AutoSafe: true},
 		{ID: "rebuild_index", Description: "Rebuild index from snapshot state", AutoSafe: false},
 		{ID: "audit_repair", Description: "Recompute audit hash chain", AutoSafe: false},
 		{ID: "advance_head", Description: "Advance stale head to latest READY", AutoSafe: false},
+		{ID: "relativize_paths", Description: "Rewrite absolute paths in descriptors as worktree-relative paths", AutoSafe: false},
+		{ID: "lineage", Description: "Reattach snapshots with a missing parent to their nearest surviving ancestor, or make them roots", AutoSafe: false},
 	}
 }
 
@@ -76,8 +87,18 @@ func (d *Doctor) Repair(actions []string) ([]RepairResult, error) {
 			results = append(results, d.repairCleanTmp())
 		case "clean_intents":
 			results = append(results, d.repairCleanIntents())
+		case "clean_materialized":
+			results = append(results, d.repairCleanMaterialized())
 		case "advance_head":
 			results = append(results, d.repairAdvanceHead())
+		case "replay_audit_wal":
+			results = append(results, d.repairReplayAuditWAL())
+		case "relativize_paths":
+			results = append(results, d.repairRelativizePaths())
+		case "lineage":
+			results = append(results, d.repairLineage())
+		case "rebuild_index":
+			results = append(results, d.repairRebuildIndex())
 		default:
 			results = append(results, RepairResult{
 				Action:  action,
@@ -128,6 +149,24 @@ func (d *Doctor) repairCleanTmp() RepairResult {
 	}
 }
 
+func (d *Doctor) repairCleanMaterialized() RepairResult {
+	removed, err := materialize.NewManager(d.repoRoot).Sweep()
+	if err != nil {
+		return RepairResult{
+			Action:  "clean_materialized",
+			Success: false,
+			Message: fmt.Sprintf("removed %d expired copies: %v", removed, err),
+			Cleaned: removed,
+		}
+	}
+	return RepairResult{
+		Action:  "clean_materialized",
+		Success: true,
+		Message: fmt.Sprintf("removed %d expired copies", removed),
+		Cleaned: removed,
+	}
+}
+
 func (d *Doctor) repairCleanIntents() RepairResult {
 	intentsDir := filepath.Join(d.repoRoot, ".jvs", "intents")
 	cleaned := 0
@@ -157,6 +196,42 @@ func (d *Doctor) repairCleanIntents() RepairResult {
 	}
 }
 
+func (d *Doctor) repairReplayAuditWAL() RepairResult {
+	replayed, err := audit.RecoverWAL(audit.LogPath(d.repoRoot))
+	if err != nil {
+		return RepairResult{Action: "replay_audit_wal", Success: false, Message: err.Error()}
+	}
+	return RepairResult{
+		Action:  "replay_audit_wal",
+		Success: true,
+		Message: fmt.Sprintf("replayed %d audit records", replayed),
+		Cleaned: replayed,
+	}
+}
+
+// repairRebuildIndex rebuilds the repository index from the descriptors of
+// every READY snapshot.
+func (d *Doctor) repairRebuildIndex() RepairResult {
+	indexed, err := snapshot.RebuildIndex(d.repoRoot)
+	if err != nil {
+		return RepairResult{Action: "rebuild_index", Success: false, Message: err.Error()}
+	}
+	return RepairResult{
+		Action:  "rebuild_index",
+		Success: true,
+		Message: fmt.Sprintf("indexed %d snapshots", indexed),
+		Cleaned: indexed,
+	}
+}
+
+// updateIndex indexes a descriptor rewritten by a repair. The index can be
+// rebuilt with rebuild_index, so a failed update only warns.
+func (d *Doctor) updateIndex(desc *model.Descriptor) {
+	if err := index.Update(d.repoRoot, desc); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update index: %v\n", err)
+	}
+}
+
 func (d *Doctor) repairAdvanceHead() RepairResult {
 	// Find worktrees with stale head_snapshot_id and advance to latest READY
 	wtMgr := worktree.NewManager(d.repoRoot)
@@ -174,9 +249,7 @@ func (d *Doctor) repairAdvanceHead() RepairResult {
 		// Check if head is stale (not pointing to latest)
 		if cfg.HeadSnapshotID != cfg.LatestSnapshotID {
 			// Verify the latest snapshot has a .READY marker
-			snapshotDir := filepath.Join(d.repoRoot, ".jvs", "snapshots", string(cfg.LatestSnapshotID))
-			readyPath := filepath.Join(snapshotDir, ".READY")
-			if _, err := os.Stat(readyPath); err == nil {
+			if err := repo.CheckSnapshotReady(d.repoRoot, cfg.LatestSnapshotID); err == nil {
 				// Advance head to latest
 				if err := wtMgr.SetLatest(cfg.Name, cfg.LatestSnapshotID); err == nil {
 					advanced++
@@ -200,11 +273,13 @@ func (d *Doctor) Check(strict bool) (*Result, error) {
 	// 1. Check format version
 	d.checkFormatVersion(result)
 
-	// 2. Check worktrees
+	// 2. Check worktrees and snapshot lineage
 	d.checkWorktrees(result)
+	d.checkLineage(result)
 
 	// 3. Check for orphan intents
 	d.checkOrphanIntents(result)
+	d.checkOrphanAuditWALs(result)
 
 	// 4. Check snapshot integrity (if strict)
 	if strict {
@@ -267,6 +342,12 @@ func (d *Doctor) checkWorktrees(result *Result) {
 		return
 	}
 
+	var staleness model.StalenessPolicy
+	if repoCfg, err := config.Load(d.repoRoot); err == nil {
+		staleness = repoCfg.GetStaleness()
+	}
+	now := time.Now()
+
 	for _, cfg := range list {
 		// Check payload directory exists
 		payloadPath := wtMgr.Path(cfg.Name)
@@ -281,8 +362,7 @@ func (d *Doctor) checkWorktrees(result *Result) {
 
 		// Check head snapshot exists
 		if cfg.HeadSnapshotID != "" {
-			descPath := filepath.Join(d.repoRoot, ".jvs", "descriptors", string(cfg.HeadSnapshotID)+".json")
-			if _, err := os.Stat(descPath); os.IsNotExist(err) {
+			if exists, err := d.descriptorExists(cfg.HeadSnapshotID); err == nil && !exists {
 				result.Findings = append(result.Findings, Finding{
 					Category:    "worktree",
 					Description: fmt.Sprintf("worktree '%s' head snapshot %s not found", cfg.Name, cfg.HeadSnapshotID),
@@ -290,7 +370,24 @@ func (d *Doctor) checkWorktrees(result *Result) {
 				})
 			}
 		}
+
+		if stale, err := snapshot.CheckStale(d.repoRoot, cfg, staleness, now); err == nil && stale != nil {
+			result.Findings = append(result.Findings, Finding{
+				Category:    "staleness",
+				Description: stale.Message(),
+				Severity:    "warning",
+			})
+		}
+	}
+}
+
+func (d *Doctor) descriptorExists(id model.SnapshotID) (bool, error) {
+	store, err := descstore.Open(d.repoRoot)
+	if err != nil {
+		return false, err
 	}
+	defer store.Close()
+	return store.Exists(id)
 }
 
 func (d *Doctor) checkOrphanIntents(result *Result) {
@@ -306,6 +403,23 @@ func (d *Doctor) checkOrphanIntents(result *Result) {
 			Description: fmt.Sprintf("orphan intent file: %s", entry.Name()),
 			Severity:    "warning",
 			Path:        filepath.Join(intentsDir, entry.Name()),
+			Repair:      "clean_intents",
+		})
+	}
+}
+
+func (d *Doctor) checkOrphanAuditWALs(result *Result) {
+	paths, err := audit.OrphanWALs(audit.LogPath(d.repoRoot))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		result.Findings = append(result.Findings, Finding{
+			Category:    "audit",
+			Description: fmt.Sprintf("unflushed audit write-ahead file: %s (run --repair-runtime)", filepath.Base(path)),
+			Severity:    "warning",
+			Path:        path,
+			Repair:      "replay_audit_wal",
 		})
 	}
 }
@@ -347,6 +461,7 @@ func (d *Doctor) checkOrphanTmp(result *Result) {
 				Description: fmt.Sprintf("orphan temp file: %s", name),
 				Severity:    "info",
 				Path:        path,
+				Repair:      "clean_tmp",
 			})
 		}
 		return nil
@@ -363,6 +478,7 @@ func (d *Doctor) checkOrphanTmp(result *Result) {
 					Description: fmt.Sprintf("orphan snapshot tmp directory: %s", entry.Name()),
 					Severity:    "warning",
 					Path:        filepath.Join(snapshotsDir, entry.Name()),
+					Repair:      "clean_tmp",
 				})
 			}
 		}
@@ -371,59 +487,45 @@ func (d *Doctor) checkOrphanTmp(result *Result) {
 
 // checkAuditChain verifies the audit log hash chain integrity.
 func (d *Doctor) checkAuditChain(result *Result) {
-	auditPath := filepath.Join(d.repoRoot, ".jvs", "audit", "audit.jsonl")
-	file, err := os.Open(auditPath)
-	if os.IsNotExist(err) {
-		return // No audit log yet is OK
-	}
+	auditPath := audit.LogPath(d.repoRoot)
+	verified, err := audit.Verify(auditPath)
 	if err != nil {
 		result.Findings = append(result.Findings, Finding{
 			Category:    "audit",
-			Description: fmt.Sprintf("cannot open audit log: %v", err),
+			Description: fmt.Sprintf("cannot verify audit log: %v", err),
 			Severity:    "warning",
 			Path:        auditPath,
 		})
 		return
 	}
-	defer file.Close()
 
-	var prevHash model.HashValue
-	var lineNum int
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lineNum++
-		var record model.AuditRecord
-		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+	broken := false
+	for _, p := range verified.Problems {
+		if p.Kind == audit.ProblemMalformed {
 			result.Findings = append(result.Findings, Finding{
 				Category:    "audit",
-				Description: fmt.Sprintf("malformed record at line %d", lineNum),
+				Description: fmt.Sprintf("malformed record at line %d", p.Line),
 				Severity:    "warning",
 				Path:        auditPath,
 			})
 			continue
 		}
-
-		// Verify chain linkage (skip first record which has empty prevHash)
-		if prevHash != "" && record.PrevHash != prevHash {
-			result.Findings = append(result.Findings, Finding{
-				Category:    "audit",
-				Description: fmt.Sprintf("audit hash chain broken at line %d", lineNum),
-				Severity:    "critical",
-				ErrorCode:   "E_AUDIT_CHAIN_BROKEN",
-				Path:        auditPath,
-			})
-			result.Healthy = false
-			return
+		// Later chain problems usually follow from the first
+		if broken {
+			continue
+		}
+		broken = true
+		description := "audit hash chain broken: " + p.Message
+		if p.Line > 0 {
+			description = fmt.Sprintf("audit hash chain broken at line %d: %s", p.Line, p.Message)
 		}
-		prevHash = record.RecordHash
-	}
-
-	if err := scanner.Err(); err != nil {
 		result.Findings = append(result.Findings, Finding{
 			Category:    "audit",
-			Description: fmt.Sprintf("error reading audit log: %v", err),
-			Severity:    "warning",
+			Description: description,
+			Severity:    "critical",
+			ErrorCode:   "E_AUDIT_CHAIN_BROKEN",
 			Path:        auditPath,
 		})
+		result.Healthy = false
 	}
 }
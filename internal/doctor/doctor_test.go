@@ -5,9 +5,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/jvs-project/jvs/internal/audit"
 	"github.com/jvs-project/jvs/internal/doctor"
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -108,6 +110,24 @@ func TestDoctor_Check_MissingWorktreePayload(t *testing.T) {
 	assert.True(t, found, "expected worktree finding for missing payload")
 }
 
+func TestDoctor_Check_StaleWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+
+	doc := doctor.NewDoctor(repoPath)
+	result, err := doc.Check(false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Findings, "no staleness check by default")
+
+	require.NoError(t, config.Save(repoPath, &config.Config{Staleness: &config.Staleness{MaxAge: "1ns"}}))
+	result, err = doc.Check(false)
+	require.NoError(t, err)
+	require.Len(t, result.Findings, 1)
+	assert.Equal(t, "staleness", result.Findings[0].Category)
+	assert.Equal(t, "warning", result.Findings[0].Severity)
+	assert.Contains(t, result.Findings[0].Description, "worktree 'main' was last snapshotted")
+}
+
 func TestDoctor_ListRepairActions(t *testing.T) {
 	repoPath := setupTestRepo(t)
 	doc := doctor.NewDoctor(repoPath)
@@ -123,6 +143,55 @@ func TestDoctor_ListRepairActions(t *testing.T) {
 	assert.True(t, actionMap["clean_tmp"])
 	assert.True(t, actionMap["clean_intents"])
 	assert.True(t, actionMap["advance_head"])
+	assert.True(t, actionMap["replay_audit_wal"])
+	assert.True(t, actionMap["clean_materialized"])
+	assert.True(t, actionMap["lineage"])
+}
+
+func TestDoctor_Repair_ReplayAuditWAL(t *testing.T) {
+	repoPath := setupTestRepo(t)
+
+	// Left behind by a crashed batch writer
+	walPath := audit.LogPath(repoPath) + ".4242.wal"
+	line := `{"timestamp":"2026-01-02T03:04:05Z","event_type":"snapshot_create","worktree_name":"main","snapshot_id":"s1"}`
+	require.NoError(t, os.MkdirAll(filepath.Dir(walPath), 0755))
+	require.NoError(t, os.WriteFile(walPath, []byte(line+"\n"), 0644))
+
+	doc := doctor.NewDoctor(repoPath)
+	result, err := doc.Check(false)
+	require.NoError(t, err)
+	found := false
+	for _, f := range result.Findings {
+		if f.Category == "audit" && f.Path == walPath {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected finding for unflushed audit WAL")
+
+	results, err := doc.Repair([]string{"replay_audit_wal"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, 1, results[0].Cleaned)
+	assert.NoFileExists(t, walPath)
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeSnapshotCreate})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, model.SnapshotID("s1"), records[0].SnapshotID)
+}
+
+func TestDoctor_Repair_RebuildIndex(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+	createTestSnapshot(t, repoPath)
+
+	results, err := doctor.NewDoctor(repoPath).Repair([]string{"rebuild_index"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success, results[0].Message)
+	assert.Equal(t, 2, results[0].Cleaned)
+	assert.FileExists(t, filepath.Join(repoPath, ".jvs", "index.sqlite"))
 }
 
 func TestDoctor_Repair_CleanTmp(t *testing.T) {
@@ -0,0 +1,177 @@
+package doctor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// A descriptor whose parent was deleted by hand or lost to corruption keeps
+// a dangling ParentID, which cuts history and GC lineage walks short.
+// checkLineage reports them and the lineage repair splices each one onto its
+// nearest surviving ancestor, found through the parent_id recorded by
+// snapshot_create and snapshot_import audit records. A snapshot with no
+// surviving ancestor becomes an explicit root.
+
+// checkLineage reports descriptors whose parent descriptor is missing.
+func (d *Doctor) checkLineage(result *Result) {
+	descs, err := d.listDescriptors()
+	if err != nil {
+		result.Findings = append(result.Findings, Finding{
+			Category:    "lineage",
+			Description: fmt.Sprintf("cannot list descriptors: %v", err),
+			Severity:    "warning",
+		})
+		return
+	}
+	for _, desc := range danglingParents(descs) {
+		result.Findings = append(result.Findings, Finding{
+			Category:    "lineage",
+			Description: fmt.Sprintf("snapshot %s has missing parent %s (run jvs doctor --repair lineage)", desc.SnapshotID, *desc.ParentID),
+			Severity:    "error",
+			ErrorCode:   errclass.ErrLineageBroken.Code,
+			Repair:      "lineage",
+		})
+		result.Healthy = false
+	}
+}
+
+// repairLineage reattaches every snapshot with a missing parent to its
+// nearest surviving ancestor, or makes it a root if none survives, and
+// audits each splice as lineage_repair.
+func (d *Doctor) repairLineage() RepairResult {
+	const action = "lineage"
+	lock, err := repolock.NewManager(d.repoRoot).Acquire(model.LockExclusive, "doctor repair lineage")
+	if err != nil {
+		return RepairResult{Action: action, Success: false, Message: err.Error()}
+	}
+	defer lock.Release()
+
+	store, err := descstore.Open(d.repoRoot)
+	if err != nil {
+		return RepairResult{Action: action, Success: false, Message: err.Error()}
+	}
+	defer store.Close()
+	descs, err := store.List()
+	if err != nil {
+		return RepairResult{Action: action, Success: false, Message: err.Error()}
+	}
+	dangling := danglingParents(descs)
+	if len(dangling) == 0 {
+		return RepairResult{Action: action, Success: true, Message: "no missing parents"}
+	}
+
+	exists := make(map[model.SnapshotID]bool, len(descs))
+	for _, desc := range descs {
+		exists[desc.SnapshotID] = true
+	}
+	recorded, err := d.recordedParents()
+	if err != nil {
+		return RepairResult{Action: action, Success: false, Message: fmt.Sprintf("read audit log: %v", err)}
+	}
+
+	appender := audit.NewFileAppender(audit.LogPath(d.repoRoot))
+	var spliced []string
+	for _, desc := range dangling {
+		oldParent := *desc.ParentID
+		details := map[string]any{"old_parent_id": string(oldParent)}
+		splice := fmt.Sprintf("%s -> root", desc.SnapshotID)
+		if ancestor, ok := nearestSurvivor(oldParent, recorded, exists); ok {
+			desc.ParentID = &ancestor
+			details["new_parent_id"] = string(ancestor)
+			splice = fmt.Sprintf("%s -> %s", desc.SnapshotID, ancestor)
+		} else {
+			desc.ParentID = nil
+			details["root"] = true
+		}
+
+		checksum, err := integrity.ComputeDescriptorChecksum(desc)
+		if err != nil {
+			return RepairResult{Action: action, Success: false, Message: err.Error(), Cleaned: len(spliced)}
+		}
+		desc.DescriptorChecksum = checksum
+		if err := store.Put(desc); err != nil {
+			return RepairResult{Action: action, Success: false, Message: fmt.Sprintf("write descriptor %s: %v", desc.SnapshotID, err), Cleaned: len(spliced)}
+		}
+		spliced = append(spliced, splice)
+		if err := d.updateReadyChecksum(desc.SnapshotID, checksum); err != nil {
+			return RepairResult{Action: action, Success: false, Message: fmt.Sprintf("update ready marker %s: %v", desc.SnapshotID, err), Cleaned: len(spliced)}
+		}
+		d.updateIndex(desc)
+		if err := appender.Append(model.EventTypeLineageRepair, desc.WorktreeName, desc.SnapshotID, details); err != nil {
+			return RepairResult{Action: action, Success: false, Message: fmt.Sprintf("write audit log: %v", err), Cleaned: len(spliced)}
+		}
+	}
+	return RepairResult{
+		Action:  action,
+		Success: true,
+		Message: fmt.Sprintf("relinked %d snapshots: %s", len(spliced), strings.Join(spliced, ", ")),
+		Cleaned: len(spliced),
+	}
+}
+
+// recordedParents maps each snapshot to the parent its creation or import
+// audit record names.
+func (d *Doctor) recordedParents() (map[model.SnapshotID]model.SnapshotID, error) {
+	records, err := audit.Read(audit.LogPath(d.repoRoot), audit.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[model.SnapshotID]model.SnapshotID)
+	for _, r := range records {
+		if r.EventType != model.EventTypeSnapshotCreate && r.EventType != model.EventTypeSnapshotImport {
+			continue
+		}
+		if parent, _ := r.Details["parent_id"].(string); parent != "" {
+			parents[r.SnapshotID] = model.SnapshotID(parent)
+		}
+	}
+	return parents, nil
+}
+
+// nearestSurvivor follows recorded parents up from the missing snapshot id
+// to the first one whose descriptor still exists.
+func nearestSurvivor(id model.SnapshotID, recorded map[model.SnapshotID]model.SnapshotID, exists map[model.SnapshotID]bool) (model.SnapshotID, bool) {
+	seen := map[model.SnapshotID]bool{id: true}
+	for {
+		parent, ok := recorded[id]
+		if !ok || seen[parent] {
+			return "", false
+		}
+		if exists[parent] {
+			return parent, true
+		}
+		seen[parent] = true
+		id = parent
+	}
+}
+
+// danglingParents returns the descriptors whose parent is not among descs.
+func danglingParents(descs []*model.Descriptor) []*model.Descriptor {
+	exists := make(map[model.SnapshotID]bool, len(descs))
+	for _, desc := range descs {
+		exists[desc.SnapshotID] = true
+	}
+	var dangling []*model.Descriptor
+	for _, desc := range descs {
+		if desc.ParentID != nil && !exists[*desc.ParentID] {
+			dangling = append(dangling, desc)
+		}
+	}
+	return dangling
+}
+
+func (d *Doctor) listDescriptors() ([]*model.Descriptor, error) {
+	store, err := descstore.Open(d.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return store.List()
+}
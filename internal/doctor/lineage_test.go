@@ -0,0 +1,106 @@
+package doctor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/doctor"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// createChain creates n snapshots of main, each the parent of the next.
+func createChain(t *testing.T, repoPath string, n int) []model.SnapshotID {
+	t.Helper()
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	ids := make([]model.SnapshotID, n)
+	for i := range ids {
+		desc, err := creator.Create("main", "step", nil)
+		require.NoError(t, err)
+		ids[i] = desc.SnapshotID
+	}
+	return ids
+}
+
+func deleteDescriptors(t *testing.T, repoPath string, ids ...model.SnapshotID) {
+	t.Helper()
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	defer store.Close()
+	for _, id := range ids {
+		require.NoError(t, store.Delete(id))
+	}
+}
+
+func TestDoctor_Check_DanglingParent(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createChain(t, repoPath, 3)
+	doc := doctor.NewDoctor(repoPath)
+
+	result, err := doc.Check(false)
+	require.NoError(t, err)
+	assert.True(t, result.Healthy)
+
+	deleteDescriptors(t, repoPath, ids[1])
+	result, err = doc.Check(false)
+	require.NoError(t, err)
+	assert.False(t, result.Healthy)
+	require.Len(t, result.Findings, 1)
+	assert.Equal(t, "lineage", result.Findings[0].Category)
+	assert.Equal(t, "E_LINEAGE_BROKEN", result.Findings[0].ErrorCode)
+	assert.Equal(t, "lineage", result.Findings[0].Repair)
+	assert.Contains(t, result.Findings[0].Description, string(ids[2]))
+}
+
+func TestDoctor_Repair_LineageReattaches(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createChain(t, repoPath, 4)
+	deleteDescriptors(t, repoPath, ids[1], ids[2])
+
+	doc := doctor.NewDoctor(repoPath)
+	results, err := doc.Repair([]string{"lineage"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success, results[0].Message)
+	assert.Equal(t, 1, results[0].Cleaned)
+
+	desc, err := snapshot.LoadDescriptor(repoPath, ids[3])
+	require.NoError(t, err)
+	require.NotNil(t, desc.ParentID)
+	assert.Equal(t, ids[0], *desc.ParentID)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, ids[3], false))
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeLineageRepair})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, ids[3], records[0].SnapshotID)
+	assert.Equal(t, string(ids[2]), records[0].Details["old_parent_id"])
+	assert.Equal(t, string(ids[0]), records[0].Details["new_parent_id"])
+
+	result, err := doc.Check(false)
+	require.NoError(t, err)
+	assert.True(t, result.Healthy)
+}
+
+func TestDoctor_Repair_LineageMarksRoot(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createChain(t, repoPath, 2)
+	deleteDescriptors(t, repoPath, ids[0])
+
+	results, err := doctor.NewDoctor(repoPath).Repair([]string{"lineage"})
+	require.NoError(t, err)
+	assert.True(t, results[0].Success, results[0].Message)
+
+	desc, err := snapshot.LoadDescriptor(repoPath, ids[1])
+	require.NoError(t, err)
+	assert.Nil(t, desc.ParentID)
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeLineageRepair})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, true, records[0].Details["root"])
+}
@@ -0,0 +1,202 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Metadata stores paths relative to the repository (payload paths relative to
+// their worktree) so a repository keeps working when its mountpoint changes.
+// Older versions could record absolute paths; CheckPaths finds them and the
+// relativize_paths repair rewrites those in descriptors.
+
+// CheckPaths reports absolute paths recorded in repository metadata.
+// Descriptors holding them get a warning; audit records, which cannot be
+// rewritten without breaking the hash chain, are reported as info.
+func (d *Doctor) CheckPaths(result *Result) {
+	store, err := descstore.Open(d.repoRoot)
+	if err != nil {
+		result.Findings = append(result.Findings, Finding{
+			Category:    "paths",
+			Description: fmt.Sprintf("cannot open descriptor store: %v", err),
+			Severity:    "warning",
+		})
+		return
+	}
+	defer store.Close()
+
+	descs, err := store.List()
+	if err != nil {
+		result.Findings = append(result.Findings, Finding{
+			Category:    "paths",
+			Description: fmt.Sprintf("cannot list descriptors: %v", err),
+			Severity:    "warning",
+		})
+		return
+	}
+	for _, desc := range descs {
+		for _, p := range absolutePaths(desc) {
+			result.Findings = append(result.Findings, Finding{
+				Category:    "paths",
+				Description: fmt.Sprintf("snapshot %s records absolute path %s (run jvs descriptors relativize-paths)", desc.SnapshotID, p),
+				Severity:    "warning",
+				Repair:      "relativize_paths",
+			})
+		}
+	}
+
+	records, err := audit.Read(audit.LogPath(d.repoRoot), audit.Filter{})
+	if err != nil {
+		return
+	}
+	for _, r := range records {
+		for _, key := range []string{"partial_paths", "dereferenced_paths"} {
+			paths, _ := r.Details[key].([]any)
+			for _, p := range paths {
+				if s, ok := p.(string); ok && isAbsolute(s) {
+					result.Findings = append(result.Findings, Finding{
+						Category:    "paths",
+						Description: fmt.Sprintf("audit record for %s %s records absolute path %s (historical, not rewritten)", r.EventType, r.SnapshotID, s),
+						Severity:    "info",
+					})
+				}
+			}
+		}
+	}
+}
+
+// repairRelativizePaths rewrites absolute paths in descriptors as paths
+// relative to the snapshot's worktree and recomputes their checksums. Paths
+// that do not lie inside the worktree, under its current or any former
+// mountpoint, are left alone and reported.
+func (d *Doctor) repairRelativizePaths() RepairResult {
+	const action = "relativize_paths"
+	store, err := descstore.Open(d.repoRoot)
+	if err != nil {
+		return RepairResult{Action: action, Success: false, Message: err.Error()}
+	}
+	defer store.Close()
+
+	descs, err := store.List()
+	if err != nil {
+		return RepairResult{Action: action, Success: false, Message: err.Error()}
+	}
+
+	rewritten := 0
+	var unresolved []string
+	for _, desc := range descs {
+		if len(absolutePaths(desc)) == 0 {
+			continue
+		}
+		var bad []string
+		desc.PartialPaths, bad = d.relativize(desc.WorktreeName, desc.PartialPaths, bad)
+		desc.DereferencedPaths, bad = d.relativize(desc.WorktreeName, desc.DereferencedPaths, bad)
+		for _, p := range bad {
+			unresolved = append(unresolved, fmt.Sprintf("%s: %s", desc.SnapshotID, p))
+		}
+
+		checksum, err := integrity.ComputeDescriptorChecksum(desc)
+		if err != nil {
+			return RepairResult{Action: action, Success: false, Message: err.Error(), Cleaned: rewritten}
+		}
+		desc.DescriptorChecksum = checksum
+		if err := store.Put(desc); err != nil {
+			return RepairResult{Action: action, Success: false, Message: fmt.Sprintf("write descriptor %s: %v", desc.SnapshotID, err), Cleaned: rewritten}
+		}
+		if err := d.updateReadyChecksum(desc.SnapshotID, checksum); err != nil {
+			return RepairResult{Action: action, Success: false, Message: fmt.Sprintf("update ready marker %s: %v", desc.SnapshotID, err), Cleaned: rewritten}
+		}
+		d.updateIndex(desc)
+		rewritten++
+	}
+
+	msg := fmt.Sprintf("rewrote paths in %d descriptors", rewritten)
+	if len(unresolved) > 0 {
+		msg += fmt.Sprintf("; %d paths outside the worktree left unchanged: %s", len(unresolved), strings.Join(unresolved, ", "))
+	}
+	return RepairResult{Action: action, Success: len(unresolved) == 0, Message: msg, Cleaned: rewritten}
+}
+
+// relativize returns paths with absolute entries made relative to the payload
+// of worktree wt, appending entries it cannot place to bad.
+func (d *Doctor) relativize(wt string, paths, bad []string) ([]string, []string) {
+	payload := repo.WorktreePayloadPath(d.repoRoot, wt)
+	rel, err := filepath.Rel(d.repoRoot, payload)
+	if err != nil {
+		return paths, append(bad, paths...)
+	}
+	// e.g. "/main/" or "/worktrees/feature/" as seen below any mountpoint
+	marker := "/" + filepath.ToSlash(rel) + "/"
+
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = p
+		if !isAbsolute(p) {
+			continue
+		}
+		slashed := filepath.ToSlash(p)
+		if idx := strings.LastIndex(slashed, marker); idx >= 0 {
+			if r := path.Clean(slashed[idx+len(marker):]); r != "." && r != "" {
+				out[i] = filepath.FromSlash(r)
+				continue
+			}
+		}
+		bad = append(bad, p)
+	}
+	return out, bad
+}
+
+// updateReadyChecksum keeps the .READY marker's copy of the descriptor
+// checksum in step with a rewritten descriptor. Compressed markers are left
+// as they are.
+func (d *Doctor) updateReadyChecksum(id model.SnapshotID, checksum model.HashValue) error {
+	readyPath := filepath.Join(d.repoRoot, ".jvs", "snapshots", string(id), ".READY")
+	data, err := os.ReadFile(readyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var marker model.ReadyMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return err
+	}
+	marker.DescriptorChecksum = checksum
+	data, err = json.Marshal(&marker)
+	if err != nil {
+		return err
+	}
+	return fsutil.AtomicWrite(readyPath, data, 0644)
+}
+
+func absolutePaths(desc *model.Descriptor) []string {
+	var abs []string
+	for _, p := range desc.PartialPaths {
+		if isAbsolute(p) {
+			abs = append(abs, p)
+		}
+	}
+	for _, p := range desc.DereferencedPaths {
+		if isAbsolute(p) {
+			abs = append(abs, p)
+		}
+	}
+	return abs
+}
+
+// isAbsolute also catches slash-rooted paths written on another platform.
+func isAbsolute(p string) bool {
+	return filepath.IsAbs(p) || strings.HasPrefix(p, "/")
+}
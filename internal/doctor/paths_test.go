@@ -0,0 +1,85 @@
+package doctor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/doctor"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// writeLegacyPaths rewrites a descriptor as an older version would have left
+// it, with absolute paths under a former mountpoint.
+func writeLegacyPaths(t *testing.T, repoPath string, id model.SnapshotID, partial, dereferenced []string) {
+	t.Helper()
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	defer store.Close()
+	desc, err := store.Get(id)
+	require.NoError(t, err)
+	desc.PartialPaths = partial
+	desc.DereferencedPaths = dereferenced
+	desc.DescriptorChecksum, err = integrity.ComputeDescriptorChecksum(desc)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(desc))
+}
+
+func TestDoctor_CheckPaths(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "main", "src"), 0755))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).CreatePartial("main", "partial", nil, []string{"src"})
+	require.NoError(t, err)
+
+	doc := doctor.NewDoctor(repoPath)
+	result, err := doc.Check(false)
+	require.NoError(t, err)
+	doc.CheckPaths(result)
+	assert.Empty(t, result.Findings, "new snapshots record relative paths")
+
+	writeLegacyPaths(t, repoPath, desc.SnapshotID, []string{"/mnt/old-jfs/repo/main/src"}, []string{"/mnt/old-jfs/repo/main/lib/link"})
+	result, err = doc.Check(false)
+	require.NoError(t, err)
+	doc.CheckPaths(result)
+	require.Len(t, result.Findings, 2)
+	assert.Equal(t, "paths", result.Findings[0].Category)
+	assert.Equal(t, "warning", result.Findings[0].Severity)
+	assert.Contains(t, result.Findings[0].Description, "/mnt/old-jfs/repo/main/src")
+}
+
+func TestDoctor_Repair_RelativizePaths(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "main", "src"), 0755))
+	partial, err := snapshot.NewCreator(repoPath, model.EngineCopy).CreatePartial("main", "partial", nil, []string{"src"})
+	require.NoError(t, err)
+	other, err := snapshot.NewCreator(repoPath, model.EngineCopy).CreatePartial("main", "other", nil, []string{"src"})
+	require.NoError(t, err)
+
+	writeLegacyPaths(t, repoPath, partial.SnapshotID, []string{"/mnt/old-jfs/repo/main/src"}, []string{filepath.Join(repoPath, "main", "lib", "link")})
+	writeLegacyPaths(t, repoPath, other.SnapshotID, []string{"/etc/passwd"}, nil)
+
+	doc := doctor.NewDoctor(repoPath)
+	results, err := doc.Repair([]string{"relativize_paths"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success, "a path outside the worktree is reported")
+	assert.Equal(t, 2, results[0].Cleaned)
+	assert.Contains(t, results[0].Message, "/etc/passwd")
+
+	fixed, err := snapshot.LoadDescriptor(repoPath, partial.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src"}, fixed.PartialPaths)
+	assert.Equal(t, []string{filepath.Join("lib", "link")}, fixed.DereferencedPaths)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, partial.SnapshotID, true))
+
+	unchanged, err := snapshot.LoadDescriptor(repoPath, other.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/etc/passwd"}, unchanged.PartialPaths)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, other.SnapshotID, true))
+}
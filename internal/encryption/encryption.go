@@ -0,0 +1,284 @@
+// Package encryption encrypts snapshot payloads at rest with age.
+//
+// Each regular file of an encrypted snapshot is stored as <name>.age,
+// encrypted to the repository's X25519 recipients, with the permissions of
+// the original file. Directory layout, file names and symlinks are left in
+// the clear. The snapshot creator encrypts a payload before publishing it,
+// so its plaintext never appears under a published snapshot; readers
+// decrypt a copy with one of the matching identities.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Type is the encryption recorded in descriptors.
+const Type = "age"
+
+// Extension is the suffix added to encrypted files.
+const Extension = ".age"
+
+// Environment variables supplying identities.
+const (
+	EnvIdentity     = "JVS_AGE_IDENTITY"
+	EnvIdentityFile = "JVS_AGE_IDENTITY_FILE"
+)
+
+// identityCommandTimeout bounds the identity command.
+const identityCommandTimeout = 30 * time.Second
+
+// ErrNoIdentity is returned when an encrypted snapshot must be read and no
+// identity is configured.
+var ErrNoIdentity = errors.New("snapshot is encrypted and no identity is configured (set " +
+	EnvIdentity + " or " + EnvIdentityFile + ", or encryption.identity_file or identity_command in config)")
+
+// ParseRecipients parses age X25519 public keys.
+func ParseRecipients(keys []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(keys))
+	for _, key := range keys {
+		r, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", key, err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// LoadIdentities returns the identities that decrypt the snapshots of the
+// repository at repoRoot, from the first source that is set: the
+// JVS_AGE_IDENTITY variable, the file named by JVS_AGE_IDENTITY_FILE, then
+// the repository's encryption.identity_file and identity_command. The
+// command runs in the repository with JVS_REPO set. Returns ErrNoIdentity
+// if no source is set.
+func LoadIdentities(repoRoot string) ([]age.Identity, error) {
+	if keys := os.Getenv(EnvIdentity); keys != "" {
+		return parseIdentities(strings.NewReader(keys), EnvIdentity)
+	}
+	if path := os.Getenv(EnvIdentityFile); path != "" {
+		return readIdentityFile(path)
+	}
+
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	enc := cfg.Encryption
+	switch {
+	case enc != nil && enc.IdentityFile != "":
+		return readIdentityFile(enc.IdentityFile)
+	case enc != nil && len(enc.IdentityCommand) > 0:
+		return runIdentityCommand(repoRoot, enc.IdentityCommand)
+	}
+	return nil, ErrNoIdentity
+}
+
+func readIdentityFile(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read identity file: %w", err)
+	}
+	defer f.Close()
+	return parseIdentities(f, path)
+}
+
+func runIdentityCommand(repoRoot string, command []string) ([]age.Identity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), identityCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = repoRoot
+	cmd.Env = append(os.Environ(), "JVS_REPO="+repoRoot)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("identity command: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("identity command: %w", err)
+	}
+	return parseIdentities(&stdout, "identity command output")
+}
+
+func parseIdentities(r io.Reader, source string) ([]age.Identity, error) {
+	ids, err := age.ParseIdentities(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse identities from %s: %w", source, err)
+	}
+	return ids, nil
+}
+
+// EncryptDir encrypts every regular file below root to recipients,
+// replacing it with <name>.age with the same permissions. Payload files
+// already ending in .age are encrypted too, so DecryptDir restores them.
+// .READY markers are left alone. Returns the count of encrypted files.
+func EncryptDir(root string, recipients []age.Recipient) (int, error) {
+	if len(recipients) == 0 {
+		return 0, fmt.Errorf("no encryption recipients")
+	}
+	files, err := regularFiles(root)
+	if err != nil {
+		return 0, err
+	}
+	// Backwards, so x.age becomes x.age.age before x becomes x.age
+	count := 0
+	for _, f := range slices.Backward(files) {
+		if isReadyMarker(root, f.path) {
+			continue
+		}
+		if err := encryptFile(f.path, f.perm, recipients); err != nil {
+			return count, fmt.Errorf("encrypt %s: %w", f.path, err)
+		}
+		if err := os.Remove(f.path); err != nil {
+			return count, fmt.Errorf("remove plaintext %s: %w", f.path, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DecryptDir decrypts every .age file below root with identities, as
+// written by EncryptDir, restoring the original names and permissions.
+// Returns the count of decrypted files.
+func DecryptDir(root string, identities []age.Identity) (int, error) {
+	files, err := regularFiles(root)
+	if err != nil {
+		return 0, err
+	}
+	// Forwards, so x.age becomes x before x.age.age becomes x.age
+	count := 0
+	for _, f := range files {
+		if !strings.HasSuffix(f.path, Extension) {
+			continue
+		}
+		if err := decryptFile(f.path, f.perm, identities); err != nil {
+			return count, fmt.Errorf("decrypt %s: %w", f.path, err)
+		}
+		if err := os.Remove(f.path); err != nil {
+			return count, fmt.Errorf("remove encrypted %s: %w", f.path, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DecryptSnapshot decrypts dir, a copy of the stored payload of the
+// snapshot desc describes, with the identities LoadIdentities finds for
+// the repository. It does nothing if the snapshot is not encrypted.
+// Returns the count of decrypted files.
+func DecryptSnapshot(repoRoot string, desc *model.Descriptor, dir string) (int, error) {
+	if desc.Encryption == nil {
+		return 0, nil
+	}
+	if desc.Encryption.Type != Type {
+		return 0, errclass.ErrFormatUnsupported.WithMessagef("snapshot %s is encrypted with unsupported %q", desc.SnapshotID, desc.Encryption.Type)
+	}
+	identities, err := LoadIdentities(repoRoot)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt snapshot %s: %w", desc.SnapshotID, err)
+	}
+	count, err := DecryptDir(dir, identities)
+	if err != nil {
+		return count, fmt.Errorf("decrypt snapshot %s: %w", desc.SnapshotID, err)
+	}
+	return count, nil
+}
+
+type regularFile struct {
+	path string
+	perm os.FileMode
+}
+
+// regularFiles lists the regular files below root in lexical order.
+func regularFiles(root string) ([]regularFile, error) {
+	var files []regularFile
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, regularFile{path: path, perm: info.Mode().Perm()})
+		}
+		return nil
+	})
+	return files, err
+}
+
+func encryptFile(path string, perm os.FileMode, recipients []age.Recipient) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := createWritable(path+Extension, perm)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	w, err := age.Encrypt(dst, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return finish(dst, perm)
+}
+
+func decryptFile(path string, perm os.FileMode, identities []age.Identity) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return err
+	}
+	dst, err := createWritable(strings.TrimSuffix(path, Extension), perm)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, r); err != nil {
+		return err
+	}
+	return finish(dst, perm)
+}
+
+// createWritable creates a file that can be written whatever its final
+// permissions; finish applies them.
+func createWritable(path string, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm|0200)
+}
+
+func finish(f *os.File, perm os.FileMode) error {
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// isReadyMarker reports whether path is a snapshot's .READY marker, which
+// stays readable without a key.
+func isReadyMarker(root, path string) bool {
+	return filepath.Dir(path) == root && strings.HasPrefix(filepath.Base(path), ".READY")
+}
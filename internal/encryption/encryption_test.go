@@ -0,0 +1,93 @@
+package encryption_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/encryption"
+	"github.com/jvs-project/jvs/pkg/config"
+)
+
+func TestEncryptDir_RoundTrip(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "x"), []byte("plain"), 0600))
+	// A payload file already named like an encrypted one
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "x.age"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "script.sh"), []byte("#!/bin/sh"), 0555))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".READY"), []byte("{}"), 0644))
+
+	count, err := encryption.EncryptDir(root, []age.Recipient{id.Recipient()})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoFileExists(t, filepath.Join(root, "sub", "x"))
+	assert.FileExists(t, filepath.Join(root, "sub", "x.age.age"))
+	assert.FileExists(t, filepath.Join(root, ".READY"))
+	data, err := os.ReadFile(filepath.Join(root, "script.sh.age"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "#!/bin/sh")
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	_, err = encryption.DecryptDir(root, []age.Identity{other})
+	require.Error(t, err)
+
+	count, err = encryption.DecryptDir(root, []age.Identity{id})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	for name, want := range map[string]string{"sub/x": "plain", "sub/x.age": "data", "script.sh": "#!/bin/sh"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data), name)
+	}
+	info, err := os.Stat(filepath.Join(root, "script.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0555), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(root, "sub", "x"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestLoadIdentities(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	repoRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, ".jvs"), 0755))
+	t.Setenv(encryption.EnvIdentity, "")
+	t.Setenv(encryption.EnvIdentityFile, "")
+
+	_, err = encryption.LoadIdentities(repoRoot)
+	assert.ErrorIs(t, err, encryption.ErrNoIdentity)
+
+	t.Setenv(encryption.EnvIdentity, id.String())
+	ids, err := encryption.LoadIdentities(repoRoot)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	t.Setenv(encryption.EnvIdentity, "")
+
+	// The identity command stands in for a KMS
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte(id.String()+"\n"), 0600))
+	cfg := config.Default()
+	cfg.Encryption = &config.Encryption{
+		Recipients:      []string{id.Recipient().String()},
+		IdentityCommand: []string{"cat", keyFile},
+	}
+	require.NoError(t, config.Save(repoRoot, cfg))
+	defer config.InvalidateCache(repoRoot)
+	ids, err = encryption.LoadIdentities(repoRoot)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	cfg.Encryption.IdentityCommand = []string{"false"}
+	require.NoError(t, config.Save(repoRoot, cfg))
+	_, err = encryption.LoadIdentities(repoRoot)
+	assert.ErrorContains(t, err, "identity command")
+}
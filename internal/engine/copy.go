@@ -1,11 +1,14 @@
 package engine
 
 import (
+	"context"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/jvs-project/jvs/internal/integrity"
 	"github.com/jvs-project/jvs/pkg/fsutil"
 	"github.com/jvs-project/jvs/pkg/model"
 )
@@ -13,7 +16,9 @@ import (
 // CopyEngine performs a full recursive copy of directories.
 // This is the fallback engine that works on any filesystem but does not
 // preserve hardlinks (they become separate copies).
-type CopyEngine struct{}
+type CopyEngine struct {
+	progress func(bytes int64)
+}
 
 // NewCopyEngine creates a new CopyEngine.
 func NewCopyEngine() *CopyEngine {
@@ -28,6 +33,28 @@ func (e *CopyEngine) Name() model.EngineType {
 // Clone recursively copies src to dst.
 // Returns a degraded result if hardlinks were detected (they become separate copies).
 func (e *CopyEngine) Clone(src, dst string) (*CloneResult, error) {
+	return e.clone(context.Background(), src, dst, nil)
+}
+
+// CloneContext is Clone stopping once ctx is done.
+func (e *CopyEngine) CloneContext(ctx context.Context, src, dst string) (*CloneResult, error) {
+	return e.clone(ctx, src, dst, nil)
+}
+
+// SetProgress makes later clones call fn with the size of every regular
+// file once it is copied.
+func (e *CopyEngine) SetProgress(fn func(bytes int64)) {
+	e.progress = fn
+}
+
+// CloneWithHash copies src to dst, teeing file content into hasher so the
+// payload is hashed in the same pass as the copy.
+func (e *CopyEngine) CloneWithHash(ctx context.Context, src, dst string, hasher *integrity.PayloadHasher) (*CloneResult, error) {
+	return e.clone(ctx, src, dst, hasher)
+}
+
+// clone copies src to dst, recording entries in hasher when it is non-nil.
+func (e *CopyEngine) clone(ctx context.Context, src, dst string, hasher *integrity.PayloadHasher) (*CloneResult, error) {
 	result := &CloneResult{}
 
 	seenInodes := make(map[uint64]string)
@@ -36,6 +63,9 @@ func (e *CopyEngine) Clone(src, dst string) (*CloneResult, error) {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		rel, err := filepath.Rel(src, path)
 		if err != nil {
@@ -54,16 +84,20 @@ func (e *CopyEngine) Clone(src, dst string) (*CloneResult, error) {
 			}
 		}
 
-		switch {
-		case info.IsDir():
-			return e.copyDir(path, dstPath, info)
+		if hasher == nil {
+			switch {
+			case info.IsDir():
+				return e.copyDir(path, dstPath, info)
 
-		case info.Mode()&os.ModeSymlink != 0:
-			return e.copySymlink(path, dstPath, info)
+			case info.Mode()&os.ModeSymlink != 0:
+				return e.copySymlink(path, dstPath, info)
 
-		default:
-			return e.copyFile(path, dstPath, info)
+			default:
+				return e.copyFileTee(ctx, path, dstPath, info, nil)
+			}
 		}
+
+		return e.copyAndHash(ctx, path, dstPath, rel, info, hasher)
 	})
 
 	if err != nil {
@@ -84,7 +118,53 @@ func (e *CopyEngine) copyDir(src, dst string, info os.FileInfo) error {
 	return nil
 }
 
-func (e *CopyEngine) copyFile(src, dst string, info os.FileInfo) error {
+// copyAndHash copies a single entry and records it in hasher. Metadata is taken
+// from dst so the result matches a later hash of the destination tree.
+func (e *CopyEngine) copyAndHash(ctx context.Context, src, dst, rel string, info os.FileInfo, hasher *integrity.PayloadHasher) error {
+	var sum []byte
+	switch {
+	case info.IsDir():
+		if err := e.copyDir(src, dst, info); err != nil {
+			return err
+		}
+
+	case info.Mode()&os.ModeSymlink != 0:
+		if err := e.copySymlink(src, dst, info); err != nil {
+			return err
+		}
+
+	default:
+		h := hasher.NewFileHash()
+		if err := e.copyFileTee(ctx, src, dst, info, h); err != nil {
+			return err
+		}
+		sum = h.Sum(nil)
+	}
+
+	if rel == "." {
+		return nil
+	}
+	dstInfo, err := os.Lstat(dst)
+	if err != nil {
+		return fmt.Errorf("stat dst %s: %w", dst, err)
+	}
+	switch {
+	case dstInfo.IsDir():
+		hasher.AddDir(rel, dstInfo)
+	case dstInfo.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(dst)
+		if err != nil {
+			return fmt.Errorf("readlink %s: %w", dst, err)
+		}
+		hasher.AddSymlink(rel, dstInfo, target)
+	default:
+		hasher.AddFile(rel, dstInfo, sum)
+	}
+	return nil
+}
+
+// copyFileTee copies a file, also writing its content to tee when non-nil.
+func (e *CopyEngine) copyFileTee(ctx context.Context, src, dst string, info os.FileInfo, tee hash.Hash) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("open src %s: %w", src, err)
@@ -97,7 +177,11 @@ func (e *CopyEngine) copyFile(src, dst string, info os.FileInfo) error {
 	}
 	defer dstFile.Close()
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	var w io.Writer = dstFile
+	if tee != nil {
+		w = io.MultiWriter(dstFile, tee)
+	}
+	if _, err := io.Copy(w, fsutil.ContextReader(ctx, srcFile)); err != nil {
 		return fmt.Errorf("copy %s to %s: %w", src, dst, err)
 	}
 
@@ -107,7 +191,13 @@ func (e *CopyEngine) copyFile(src, dst string, info os.FileInfo) error {
 	}
 
 	// Preserve mod time
-	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	if e.progress != nil {
+		e.progress(info.Size())
+	}
+	return nil
 }
 
 func (e *CopyEngine) copySymlink(src, dst string, info os.FileInfo) error {
@@ -1,6 +1,9 @@
 package engine
 
 import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/integrity"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
@@ -19,4 +22,29 @@ type Engine interface {
 	// Clone performs a copy of src to dst.
 	// Returns CloneResult with degradation info if applicable.
 	Clone(src, dst string) (*CloneResult, error)
+
+	// CloneContext is Clone stopping early with ctx's error once ctx is
+	// done. dst is then left partially written for the caller to remove.
+	CloneContext(ctx context.Context, src, dst string) (*CloneResult, error)
+}
+
+// HashingEngine is implemented by engines that can compute the payload root
+// hash while cloning, so snapshot data is read only once.
+type HashingEngine interface {
+	Engine
+
+	// CloneWithHash clones src to dst like CloneContext and records every
+	// entry of dst in hasher, whose Sum is then identical to
+	// integrity.ComputePayloadRootHash(dst).
+	CloneWithHash(ctx context.Context, src, dst string, hasher *integrity.PayloadHasher) (*CloneResult, error)
+}
+
+// ProgressEngine is implemented by engines that can report how much of src
+// they have cloned.
+type ProgressEngine interface {
+	Engine
+
+	// SetProgress makes later clones call fn with the size of every
+	// regular file once it is cloned. Nil stops reporting.
+	SetProgress(fn func(bytes int64))
 }
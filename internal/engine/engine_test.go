@@ -1,12 +1,14 @@
 package engine_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/integrity"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -239,3 +241,58 @@ func TestCopyEngine_PreservesModTime(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, info.ModTime().Equal(pastTime) || info.ModTime().Sub(pastTime) < time.Second)
 }
+
+func TestCopyEngine_CloneWithHashMatchesTwoPass(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "cloned")
+
+	require.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "wide.txt"), []byte("perm"), 0666))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "subdir", "deep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "subdir", "deep", "nested.txt"), []byte("world"), 0600))
+	require.NoError(t, os.Symlink("file.txt", filepath.Join(src, "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "empty"), nil, 0644))
+
+	eng := engine.NewCopyEngine()
+	hasher := integrity.NewPayloadHasher(model.HashSHA256)
+	_, err := eng.CloneWithHash(context.Background(), src, dst, hasher)
+	require.NoError(t, err)
+	assert.NotEmpty(t, hasher.Sum())
+
+	expected, err := integrity.HashPayload(dst, model.HashSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Sum(), hasher.Sum())
+	assert.Equal(t, expected.Entries(), hasher.Entries())
+}
+
+func TestEngines_CloneContextCancelled(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0644))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, eng := range []engine.Engine{engine.NewCopyEngine(), engine.NewReflinkEngine(), engine.NewJuiceFSEngine()} {
+		_, err := eng.CloneContext(ctx, src, filepath.Join(t.TempDir(), "cloned"))
+		assert.ErrorIs(t, err, context.Canceled, eng.Name())
+	}
+}
+
+func TestEngines_SetProgress(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("world!"), 0644))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(src, "link")))
+
+	for _, eng := range []engine.ProgressEngine{engine.NewCopyEngine(), engine.NewReflinkEngine(), engine.NewJuiceFSEngine()} {
+		var files, bytes int64
+		eng.SetProgress(func(n int64) {
+			files++
+			bytes += n
+		})
+		_, err := eng.Clone(src, filepath.Join(t.TempDir(), "cloned"))
+		require.NoError(t, err, eng.Name())
+		assert.Equal(t, int64(2), files, eng.Name())
+		assert.Equal(t, int64(11), bytes, eng.Name())
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"bufio"
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -32,10 +33,23 @@ func (e *JuiceFSEngine) Name() model.EngineType {
 // Clone performs a juicefs clone if available, falls back to copy otherwise.
 // Returns a degraded result if juicefs is not available or not on JuiceFS.
 func (e *JuiceFSEngine) Clone(src, dst string) (*CloneResult, error) {
+	return e.CloneContext(context.Background(), src, dst)
+}
+
+// SetProgress makes later clones that fall back to the copy engine call fn
+// with the size of every regular file once it is copied. A juicefs clone
+// reports nothing.
+func (e *JuiceFSEngine) SetProgress(fn func(bytes int64)) {
+	e.CopyEngine.SetProgress(fn)
+}
+
+// CloneContext is Clone stopping once ctx is done; a running juicefs clone
+// is killed.
+func (e *JuiceFSEngine) CloneContext(ctx context.Context, src, dst string) (*CloneResult, error) {
 	// Check if juicefs command is available
 	if !e.isJuiceFSAvailable() {
 		// Fall back to copy engine
-		result, err := e.CopyEngine.Clone(src, dst)
+		result, err := e.CopyEngine.CloneContext(ctx, src, dst)
 		if err != nil {
 			return nil, err
 		}
@@ -47,7 +61,7 @@ func (e *JuiceFSEngine) Clone(src, dst string) (*CloneResult, error) {
 	// Check if source is on JuiceFS
 	if !e.isOnJuiceFS(src) {
 		// Fall back to copy engine
-		result, err := e.CopyEngine.Clone(src, dst)
+		result, err := e.CopyEngine.CloneContext(ctx, src, dst)
 		if err != nil {
 			return nil, err
 		}
@@ -57,13 +71,16 @@ func (e *JuiceFSEngine) Clone(src, dst string) (*CloneResult, error) {
 	}
 
 	// Execute juicefs clone
-	cmd := exec.Command("juicefs", "clone", src, dst, "-p")
+	cmd := exec.CommandContext(ctx, "juicefs", "clone", src, dst, "-p")
 	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// Fall back to copy on failure
-		result, err := e.CopyEngine.Clone(src, dst)
+		result, err := e.CopyEngine.CloneContext(ctx, src, dst)
 		if err != nil {
 			return nil, err
 		}
@@ -75,6 +92,12 @@ func (e *JuiceFSEngine) Clone(src, dst string) (*CloneResult, error) {
 	return &CloneResult{Degraded: false}, nil
 }
 
+// Available reports whether Clone can use juicefs clone for path: the
+// juicefs command is installed and path is on a JuiceFS mount.
+func (e *JuiceFSEngine) Available(path string) bool {
+	return e.isJuiceFSAvailable() && e.isOnJuiceFS(path)
+}
+
 func (e *JuiceFSEngine) isJuiceFSAvailable() bool {
 	_, err := exec.LookPath("juicefs")
 	return err == nil
@@ -140,7 +163,7 @@ func DetectEngine(repoRoot string) (Engine, error) {
 	// Auto-detect based on filesystem
 	// 1. Check if on JuiceFS
 	juicefsEngine := NewJuiceFSEngine()
-	if juicefsEngine.isOnJuiceFS(repoRoot) && juicefsEngine.isJuiceFSAvailable() {
+	if juicefsEngine.Available(repoRoot) {
 		return juicefsEngine, nil
 	}
 
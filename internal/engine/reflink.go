@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -14,6 +15,7 @@ import (
 // Falls back to regular copy for files that cannot be reflinked.
 type ReflinkEngine struct {
 	CopyEngine *CopyEngine
+	progress   func(bytes int64)
 }
 
 // NewReflinkEngine creates a new ReflinkEngine.
@@ -30,6 +32,17 @@ func (e *ReflinkEngine) Name() model.EngineType {
 
 // Clone performs a reflink copy if supported, falls back to regular copy otherwise.
 func (e *ReflinkEngine) Clone(src, dst string) (*CloneResult, error) {
+	return e.CloneContext(context.Background(), src, dst)
+}
+
+// SetProgress makes later clones call fn with the size of every regular
+// file once it is reflinked or copied.
+func (e *ReflinkEngine) SetProgress(fn func(bytes int64)) {
+	e.progress = fn
+}
+
+// CloneContext is Clone stopping once ctx is done.
+func (e *ReflinkEngine) CloneContext(ctx context.Context, src, dst string) (*CloneResult, error) {
 	result := &CloneResult{}
 
 	if err := os.MkdirAll(dst, 0755); err != nil {
@@ -40,6 +53,9 @@ func (e *ReflinkEngine) Clone(src, dst string) (*CloneResult, error) {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		rel, err := filepath.Rel(src, path)
 		if err != nil {
@@ -58,7 +74,12 @@ func (e *ReflinkEngine) Clone(src, dst string) (*CloneResult, error) {
 			if err := reflinkFile(path, dstPath, info); err != nil {
 				result.Degraded = true
 				result.Degradations = append(result.Degradations, "reflink")
-				return e.copyFile(path, dstPath, info)
+				if err := e.copyFile(ctx, path, dstPath, info); err != nil {
+					return err
+				}
+			}
+			if e.progress != nil {
+				e.progress(info.Size())
 			}
 			return nil
 		}
@@ -87,7 +108,7 @@ func (e *ReflinkEngine) copySymlink(src, dst string, info os.FileInfo) error {
 	return os.Symlink(target, dst)
 }
 
-func (e *ReflinkEngine) copyFile(src, dst string, info os.FileInfo) error {
+func (e *ReflinkEngine) copyFile(ctx context.Context, src, dst string, info os.FileInfo) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("open src: %w", err)
@@ -100,7 +121,7 @@ func (e *ReflinkEngine) copyFile(src, dst string, info os.FileInfo) error {
 	}
 	defer dstFile.Close()
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	if _, err := io.Copy(dstFile, fsutil.ContextReader(ctx, srcFile)); err != nil {
 		return fmt.Errorf("copy: %w", err)
 	}
 
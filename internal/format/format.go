@@ -0,0 +1,99 @@
+// Package format renders times and sizes for human-readable CLI output.
+//
+// By default times are shown relative to now ("2h ago", "in 5m") and sizes
+// with binary units ("1.4 GiB"). Options switch to exact ISO-8601 times and
+// byte counts for scripting. JSON output never goes through this package.
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// relativeLimit is how far from now times are still shown relatively;
+// older or later times are shown as a date.
+const relativeLimit = 30 * 24 * time.Hour
+
+// Options controls how values are rendered.
+type Options struct {
+	ISO        bool // render times as RFC 3339 in local time
+	UTC        bool // render times as RFC 3339 in UTC (implies ISO)
+	ExactBytes bool // render sizes as exact byte counts
+}
+
+// Time renders t according to o, relative to now unless ISO or UTC is set.
+func (o Options) Time(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	switch {
+	case o.UTC:
+		return t.UTC().Format(time.RFC3339)
+	case o.ISO:
+		return t.Local().Format(time.RFC3339)
+	}
+	return RelativeTime(t, now)
+}
+
+// Size renders n bytes according to o.
+func (o Options) Size(n int64) string {
+	if o.ExactBytes {
+		return strconv.FormatInt(n, 10)
+	}
+	return Bytes(n)
+}
+
+// SizeDelta renders a change of n bytes with its sign, as in "+1.5 MiB".
+func (o Options) SizeDelta(n int64) string {
+	if n < 0 {
+		return "-" + o.Size(-n)
+	}
+	return "+" + o.Size(n)
+}
+
+// RelativeTime renders t relative to now: "just now", "45s ago", "2h ago",
+// "3d ago", "in 5m". Times more than 30 days away are shown as a date.
+func RelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d >= relativeLimit {
+		return t.Local().Format("2006-01-02")
+	}
+	if d < time.Second {
+		return "just now"
+	}
+
+	var s string
+	switch {
+	case d < time.Minute:
+		s = fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		s = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
+
+// Bytes renders a byte count with a binary unit suffix ("1.5 MiB").
+func Bytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
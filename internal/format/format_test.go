@@ -0,0 +1,48 @@
+package format_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jvs-project/jvs/internal/format"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "just now", format.RelativeTime(now, now))
+	assert.Equal(t, "45s ago", format.RelativeTime(now.Add(-45*time.Second), now))
+	assert.Equal(t, "5m ago", format.RelativeTime(now.Add(-5*time.Minute-10*time.Second), now))
+	assert.Equal(t, "2h ago", format.RelativeTime(now.Add(-2*time.Hour-59*time.Minute), now))
+	assert.Equal(t, "3d ago", format.RelativeTime(now.Add(-75*time.Hour), now))
+	assert.Equal(t, "in 10m", format.RelativeTime(now.Add(10*time.Minute), now))
+
+	old := now.Add(-60 * 24 * time.Hour)
+	assert.Equal(t, old.Local().Format("2006-01-02"), format.RelativeTime(old, now))
+}
+
+func TestOptions_Time(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ts := now.Add(-2 * time.Hour)
+
+	assert.Equal(t, "2h ago", format.Options{}.Time(ts, now))
+	assert.Equal(t, "2026-03-01T10:00:00Z", format.Options{UTC: true}.Time(ts, now))
+	assert.Equal(t, ts.Local().Format(time.RFC3339), format.Options{ISO: true}.Time(ts, now))
+	assert.Equal(t, "-", format.Options{}.Time(time.Time{}, now))
+}
+
+func TestBytes(t *testing.T) {
+	assert.Equal(t, "512 B", format.Bytes(512))
+	assert.Equal(t, "1.0 KiB", format.Bytes(1024))
+	assert.Equal(t, "1.5 MiB", format.Bytes(3<<19))
+	assert.Equal(t, "50.0 GiB", format.Bytes(50<<30))
+}
+
+func TestOptions_Size(t *testing.T) {
+	assert.Equal(t, "1.5 MiB", format.Options{}.Size(3<<19))
+	assert.Equal(t, "1572864", format.Options{ExactBytes: true}.Size(3<<19))
+	assert.Equal(t, "+1.5 MiB", format.Options{}.SizeDelta(3<<19))
+	assert.Equal(t, "-12", format.Options{ExactBytes: true}.SizeDelta(-12))
+}
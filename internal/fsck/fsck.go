@@ -0,0 +1,467 @@
+// Package fsck cross-checks the control plane of a repository: descriptors
+// against snapshot directories, and both against worktree configs, retained
+// roots, pins, tombstones and manifests.
+//
+// Unlike doctor, which looks for known failure modes and repairs them, fsck
+// only reads. It lists every inconsistency it finds, classified by kind, so
+// that tools can act on the report. Payloads are not rehashed; jvs verify
+// does that.
+package fsck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Kinds of Problem.
+const (
+	// KindOrphanPayload is a snapshot directory without a descriptor.
+	KindOrphanPayload = "orphan_payload"
+	// KindMissingPayload is a descriptor without a snapshot directory.
+	KindMissingPayload = "missing_payload"
+	// KindNotReady is a described snapshot whose directory has no .READY
+	// marker and no creation in progress.
+	KindNotReady = "not_ready"
+	// KindMissingDescriptor is a snapshot referenced by a worktree, a
+	// retained root or a pin that has no descriptor.
+	KindMissingDescriptor = "missing_descriptor"
+	// KindCorruptDescriptor is a descriptor that cannot be parsed, or whose
+	// ID or checksum does not match.
+	KindCorruptDescriptor = "corrupt_descriptor"
+	// KindDanglingParent is a descriptor whose parent has no descriptor.
+	KindDanglingParent = "dangling_parent"
+	// KindCorruptWorktree is a worktree whose config cannot be read.
+	KindCorruptWorktree = "corrupt_worktree"
+	// KindMissingWorktreePayload is a worktree without a payload directory.
+	KindMissingWorktreePayload = "missing_worktree_payload"
+	// KindCorruptPin is a pin file that cannot be parsed.
+	KindCorruptPin = "corrupt_pin"
+	// KindLiveTombstone is a tombstone for a snapshot whose descriptor or
+	// payload still exists, left by an interrupted GC.
+	KindLiveTombstone = "live_tombstone"
+	// KindOrphanManifest is a manifest without a descriptor.
+	KindOrphanManifest = "orphan_manifest"
+)
+
+// Severities of Problem.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is one inconsistency.
+type Problem struct {
+	Kind       string           `json:"kind"`
+	Severity   string           `json:"severity"`
+	SnapshotID model.SnapshotID `json:"snapshot_id,omitempty"`
+	// Source names what refers to the snapshot, like "worktree main head"
+	// or "pin release.json".
+	Source  string `json:"source,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the result of Check.
+type Report struct {
+	Descriptors  int       `json:"descriptors"`
+	SnapshotDirs int       `json:"snapshot_dirs"`
+	Worktrees    int       `json:"worktrees"`
+	Pins         int       `json:"pins"`
+	Tombstones   int       `json:"tombstones"`
+	Errors       int       `json:"errors"`
+	Warnings     int       `json:"warnings"`
+	Problems     []Problem `json:"problems"`
+}
+
+// Clean reports whether no problem of error severity was found.
+func (r *Report) Clean() bool {
+	return r.Errors == 0
+}
+
+func (r *Report) add(p Problem) {
+	if p.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Problems = append(r.Problems, p)
+}
+
+// checker holds the state of one Check.
+type checker struct {
+	repoRoot string
+	store    descstore.Store
+	report   *Report
+	// descs holds the descriptors that were read, by ID; corrupt holds the
+	// IDs whose descriptor exists but could not be read.
+	descs   map[model.SnapshotID]*model.Descriptor
+	corrupt map[model.SnapshotID]bool
+	// creating holds the snapshots whose creation is in progress.
+	creating map[model.SnapshotID]bool
+}
+
+// Check cross-checks the repository at repoRoot under a shared repository
+// lock, so that GC does not run meanwhile. Snapshots being created are
+// skipped. Problems are sorted by kind, then snapshot ID.
+func Check(repoRoot string) (*Report, error) {
+	lock, err := repolock.NewManager(repoRoot).Acquire(model.LockShared, "fsck")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	store, err := descstore.Open(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	c := &checker{
+		repoRoot: repoRoot,
+		store:    store,
+		report:   &Report{Problems: []Problem{}},
+		descs:    make(map[model.SnapshotID]*model.Descriptor),
+		corrupt:  make(map[model.SnapshotID]bool),
+		creating: make(map[model.SnapshotID]bool),
+	}
+	steps := []func() error{
+		c.loadCreating,
+		c.checkDescriptors,
+		c.checkSnapshotDirs,
+		c.checkWorktrees,
+		c.checkRetainedRoots,
+		c.checkPins,
+		c.checkTombstones,
+		c.checkManifests,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortStableFunc(c.report.Problems, func(a, b Problem) int {
+		if n := strings.Compare(a.Kind, b.Kind); n != 0 {
+			return n
+		}
+		return strings.Compare(string(a.SnapshotID), string(b.SnapshotID))
+	})
+	return c.report, nil
+}
+
+// loadCreating records the snapshots with a creation intent, which are named
+// after the snapshot.
+func (c *checker) loadCreating() error {
+	entries, err := readDir(repo.IntentsDir(c.repoRoot))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !strings.HasPrefix(name, repo.RestoreIntentPrefix) {
+			c.creating[model.SnapshotID(name)] = true
+		}
+	}
+	return nil
+}
+
+// checkDescriptors checks every readable descriptor's checksum and parent.
+func (c *checker) checkDescriptors() error {
+	descs, err := c.store.List()
+	if err != nil {
+		return fmt.Errorf("list descriptors: %w", err)
+	}
+	for _, desc := range descs {
+		c.descs[desc.SnapshotID] = desc
+	}
+	c.report.Descriptors = len(descs)
+
+	for _, desc := range descs {
+		checksum, err := integrity.ComputeDescriptorChecksum(desc)
+		if err != nil || checksum != desc.DescriptorChecksum {
+			c.report.add(Problem{
+				Kind:       KindCorruptDescriptor,
+				Severity:   SeverityError,
+				SnapshotID: desc.SnapshotID,
+				Message:    "descriptor checksum mismatch",
+			})
+		}
+		if desc.ParentID != nil && !c.described(*desc.ParentID) {
+			c.report.add(Problem{
+				Kind:       KindDanglingParent,
+				Severity:   SeverityError,
+				SnapshotID: desc.SnapshotID,
+				Message:    fmt.Sprintf("parent %s has no descriptor (jvs doctor --repair lineage reattaches it)", *desc.ParentID),
+			})
+		}
+		if c.creating[desc.SnapshotID] {
+			continue
+		}
+		if _, err := os.Stat(repo.SnapshotPath(c.repoRoot, desc.SnapshotID)); os.IsNotExist(err) {
+			c.report.add(Problem{
+				Kind:       KindMissingPayload,
+				Severity:   SeverityError,
+				SnapshotID: desc.SnapshotID,
+				Path:       repo.SnapshotPath(c.repoRoot, desc.SnapshotID),
+				Message:    "snapshot directory is missing",
+			})
+		} else if err := repo.CheckSnapshotReady(c.repoRoot, desc.SnapshotID); err != nil {
+			c.report.add(Problem{
+				Kind:       KindNotReady,
+				Severity:   SeverityError,
+				SnapshotID: desc.SnapshotID,
+				Path:       repo.SnapshotPath(c.repoRoot, desc.SnapshotID),
+				Message:    "snapshot directory has no .READY marker",
+			})
+		}
+	}
+	return nil
+}
+
+// described reports whether id has a descriptor, reading descriptors that
+// List skipped. Descriptors that exist but cannot be read are reported once
+// as corrupt and count as described.
+func (c *checker) described(id model.SnapshotID) bool {
+	if c.descs[id] != nil || c.corrupt[id] {
+		return true
+	}
+	exists, err := c.store.Exists(id)
+	if err != nil || !exists {
+		return false
+	}
+	desc, err := c.store.Get(id)
+	if err == nil && desc.SnapshotID == id {
+		c.descs[id] = desc
+		return true
+	}
+	var msg string
+	if err != nil {
+		msg = err.Error()
+	} else {
+		msg = fmt.Sprintf("descriptor names snapshot %s", desc.SnapshotID)
+	}
+	c.corrupt[id] = true
+	c.report.add(Problem{
+		Kind:       KindCorruptDescriptor,
+		Severity:   SeverityError,
+		SnapshotID: id,
+		Message:    msg,
+	})
+	return true
+}
+
+// checkSnapshotDirs reports snapshot directories without a descriptor.
+// Temporary directories of snapshots being created are skipped.
+func (c *checker) checkSnapshotDirs() error {
+	entries, err := readDir(filepath.Join(c.repoRoot, repo.JVSDirName, "snapshots"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		c.report.SnapshotDirs++
+		id := model.SnapshotID(entry.Name())
+		if c.creating[id] || c.described(id) {
+			continue
+		}
+		c.report.add(Problem{
+			Kind:       KindOrphanPayload,
+			Severity:   SeverityWarning,
+			SnapshotID: id,
+			Path:       repo.SnapshotPath(c.repoRoot, id),
+			Message:    "snapshot directory has no descriptor",
+		})
+	}
+	return nil
+}
+
+// checkWorktrees checks every worktree's config, payload and snapshots.
+func (c *checker) checkWorktrees() error {
+	entries, err := readDir(filepath.Join(c.repoRoot, repo.JVSDirName, "worktrees"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c.report.Worktrees++
+		name := entry.Name()
+		cfg, err := repo.LoadWorktreeConfig(c.repoRoot, name)
+		if err != nil {
+			c.report.add(Problem{
+				Kind:     KindCorruptWorktree,
+				Severity: SeverityError,
+				Source:   "worktree " + name,
+				Path:     repo.WorktreeConfigPath(c.repoRoot, name),
+				Message:  err.Error(),
+			})
+			continue
+		}
+		if _, err := os.Stat(repo.WorktreePayloadPath(c.repoRoot, name)); os.IsNotExist(err) {
+			c.report.add(Problem{
+				Kind:     KindMissingWorktreePayload,
+				Severity: SeverityError,
+				Source:   "worktree " + name,
+				Path:     repo.WorktreePayloadPath(c.repoRoot, name),
+				Message:  "worktree payload directory is missing",
+			})
+		}
+		c.reference(cfg.HeadSnapshotID, "worktree "+name+" head", SeverityError)
+		c.reference(cfg.LatestSnapshotID, "worktree "+name+" latest", SeverityError)
+		// The base may have been collected since the worktree was forked
+		c.reference(cfg.BaseSnapshotID, "worktree "+name+" base", SeverityWarning)
+	}
+	return nil
+}
+
+// checkRetainedRoots checks the snapshots kept from removed worktrees.
+func (c *checker) checkRetainedRoots() error {
+	buckets, err := worktree.NewManager(c.repoRoot).RetainedRoots()
+	if err != nil {
+		return fmt.Errorf("read retained roots: %w", err)
+	}
+	for bucket, roots := range buckets {
+		for _, root := range roots {
+			c.reference(root.SnapshotID, "retained "+bucket, SeverityError)
+		}
+	}
+	return nil
+}
+
+// checkPins checks that every pin parses and names a described snapshot.
+func (c *checker) checkPins() error {
+	dir := filepath.Join(c.repoRoot, repo.JVSDirName, "pins")
+	entries, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		c.report.Pins++
+		path := filepath.Join(dir, entry.Name())
+		var pin model.Pin
+		if err := readJSON(path, &pin); err != nil {
+			c.report.add(Problem{
+				Kind:     KindCorruptPin,
+				Severity: SeverityWarning,
+				Source:   "pin " + entry.Name(),
+				Path:     path,
+				Message:  err.Error(),
+			})
+			continue
+		}
+		c.reference(pin.SnapshotID, "pin "+entry.Name(), SeverityError)
+	}
+	return nil
+}
+
+// checkTombstones reports tombstones of snapshots that were not fully
+// deleted.
+func (c *checker) checkTombstones() error {
+	dir := filepath.Join(c.repoRoot, repo.JVSDirName, "gc", "tombstones")
+	entries, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		c.report.Tombstones++
+		var tombstone model.Tombstone
+		if err := readJSON(filepath.Join(dir, entry.Name()), &tombstone); err != nil {
+			continue
+		}
+		id := tombstone.SnapshotID
+		_, statErr := os.Stat(repo.SnapshotPath(c.repoRoot, id))
+		if c.described(id) || statErr == nil {
+			c.report.add(Problem{
+				Kind:       KindLiveTombstone,
+				Severity:   SeverityWarning,
+				SnapshotID: id,
+				Path:       filepath.Join(dir, entry.Name()),
+				Message:    fmt.Sprintf("snapshot was deleted at %s but its descriptor or payload remains", tombstone.DeletedAt.Format("2006-01-02 15:04:05")),
+			})
+		}
+	}
+	return nil
+}
+
+// checkManifests reports manifests of snapshots without a descriptor.
+func (c *checker) checkManifests() error {
+	entries, err := readDir(filepath.Join(c.repoRoot, repo.JVSDirName, "manifests"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".jsonl.zst")
+		if !ok {
+			if name, ok = strings.CutSuffix(entry.Name(), ".json"); !ok {
+				continue
+			}
+		}
+		id := model.SnapshotID(name)
+		if c.creating[id] || c.described(id) {
+			continue
+		}
+		c.report.add(Problem{
+			Kind:       KindOrphanManifest,
+			Severity:   SeverityWarning,
+			SnapshotID: id,
+			Path:       filepath.Join(c.repoRoot, repo.JVSDirName, "manifests", entry.Name()),
+			Message:    "manifest has no descriptor",
+		})
+	}
+	return nil
+}
+
+// reference reports id as a missing descriptor unless it is empty or
+// described.
+func (c *checker) reference(id model.SnapshotID, source, severity string) {
+	if id == "" || c.described(id) {
+		return
+	}
+	c.report.add(Problem{
+		Kind:       KindMissingDescriptor,
+		Severity:   severity,
+		SnapshotID: id,
+		Source:     source,
+		Message:    fmt.Sprintf("%s names a snapshot without a descriptor", source),
+	})
+}
+
+// readDir lists dir; a missing directory is empty.
+func readDir(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package fsck_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/fsck"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	return dir
+}
+
+func createSnapshot(t *testing.T, repoPath string) *model.Descriptor {
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "test", nil)
+	require.NoError(t, err)
+	return desc
+}
+
+func writeJSON(t *testing.T, path string, v any) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func kinds(report *fsck.Report) []string {
+	var result []string
+	for _, p := range report.Problems {
+		result = append(result, p.Kind)
+	}
+	return result
+}
+
+func TestCheck_Clean(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createSnapshot(t, repoPath)
+	createSnapshot(t, repoPath)
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+	assert.Empty(t, report.Problems)
+	assert.Equal(t, 2, report.Descriptors)
+	assert.Equal(t, 2, report.SnapshotDirs)
+	assert.Equal(t, 1, report.Worktrees)
+}
+
+func TestCheck_OrphanPayload(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createSnapshot(t, repoPath)
+	orphan := repo.SnapshotPath(repoPath, "1700000000000-deadbeef")
+	require.NoError(t, os.MkdirAll(orphan, 0755))
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.True(t, report.Clean(), "orphan payloads are warnings")
+	require.Equal(t, []string{fsck.KindOrphanPayload}, kinds(report))
+	assert.Equal(t, model.SnapshotID("1700000000000-deadbeef"), report.Problems[0].SnapshotID)
+	assert.Equal(t, 1, report.Warnings)
+}
+
+func TestCheck_MissingPayload(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	require.NoError(t, os.RemoveAll(repo.SnapshotPath(repoPath, desc.SnapshotID)))
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.False(t, report.Clean())
+	assert.Equal(t, []string{fsck.KindMissingPayload}, kinds(report))
+}
+
+func TestCheck_NotReady(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	require.NoError(t, os.Remove(filepath.Join(repo.SnapshotPath(repoPath, desc.SnapshotID), ".READY")))
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fsck.KindNotReady}, kinds(report))
+}
+
+func TestCheck_DanglingParentAndMissingDescriptor(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	first := createSnapshot(t, repoPath)
+	second := createSnapshot(t, repoPath)
+
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(first.SnapshotID))
+	require.NoError(t, store.Close())
+	writeJSON(t, filepath.Join(repoPath, ".jvs", "pins", "old.json"), model.Pin{SnapshotID: first.SnapshotID, PinnedAt: time.Now()})
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.False(t, report.Clean())
+	assert.Equal(t, []string{fsck.KindDanglingParent, fsck.KindMissingDescriptor, fsck.KindOrphanManifest, fsck.KindOrphanPayload}, kinds(report))
+	assert.Equal(t, second.SnapshotID, report.Problems[0].SnapshotID)
+	assert.Equal(t, "pin old.json", report.Problems[1].Source)
+	assert.Equal(t, 1, report.Pins)
+}
+
+func TestCheck_CorruptDescriptor(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	path := filepath.Join(repoPath, ".jvs", "descriptors", string(desc.SnapshotID)+".json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.False(t, report.Clean())
+	assert.Equal(t, []string{fsck.KindCorruptDescriptor}, kinds(report), "referrers of a corrupt descriptor are not reported again")
+}
+
+func TestCheck_CorruptPin(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	pins := filepath.Join(repoPath, ".jvs", "pins")
+	require.NoError(t, os.MkdirAll(pins, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(pins, "bad.json"), []byte("nope"), 0644))
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+	assert.Equal(t, []string{fsck.KindCorruptPin}, kinds(report))
+}
+
+func TestCheck_LiveTombstone(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	writeJSON(t, filepath.Join(repoPath, ".jvs", "gc", "tombstones", string(desc.SnapshotID)+".json"),
+		model.Tombstone{SnapshotID: desc.SnapshotID, DeletedAt: time.Now()})
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fsck.KindLiveTombstone}, kinds(report))
+	assert.Equal(t, 1, report.Tombstones)
+}
+
+func TestCheck_MissingWorktreePayload(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createSnapshot(t, repoPath)
+	require.NoError(t, os.RemoveAll(repo.WorktreePayloadPath(repoPath, "main")))
+
+	report, err := fsck.Check(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fsck.KindMissingWorktreePayload}, kinds(report))
+}
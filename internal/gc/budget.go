@@ -0,0 +1,128 @@
+package gc
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// BudgetUsage reports the current usage of each tag budget without planning
+// any deletions.
+func (c *Collector) BudgetUsage(budgets []model.TagBudget) ([]model.BudgetUsage, error) {
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	descs, err := snapshot.ListAll(c.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[model.SnapshotID]int64)
+	usage := make([]model.BudgetUsage, 0, len(budgets))
+	for _, b := range budgets {
+		u := model.BudgetUsage{Tag: b.Tag, MaxBytes: b.MaxBytes, MaxCount: b.MaxCount}
+		for _, desc := range descs {
+			if !slices.Contains(desc.Tags, b.Tag) {
+				continue
+			}
+			u.UsedCount++
+			u.UsedBytes += c.cachedSize(desc.SnapshotID, sizes)
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+// applyBudgets marks the oldest snapshots of each over-budget tag class as
+// deletable. Snapshots in hardProtected (heads, lineage, intents, pins, tag
+// rules) are never evicted but still count toward the budget. Snapshots
+// only protected by age or count retention are evicted and removed from
+// protected and byRetention. Returns per-budget usage.
+func (c *Collector) applyBudgets(descs []*model.Descriptor, budgets []model.TagBudget,
+	hardProtected, protected map[model.SnapshotID]bool, byRetention map[model.SnapshotID]string) []model.BudgetUsage {
+	sizes := make(map[model.SnapshotID]int64)
+	usage := make([]model.BudgetUsage, 0, len(budgets))
+
+	for _, b := range budgets {
+		u := model.BudgetUsage{Tag: b.Tag, MaxBytes: b.MaxBytes, MaxCount: b.MaxCount}
+
+		// descs is newest first: keep the newest snapshots that fit within
+		// the budget and evict everything older once it is exhausted.
+		var keptBytes int64
+		keptCount := 0
+		for _, desc := range descs {
+			if !slices.Contains(desc.Tags, b.Tag) {
+				continue
+			}
+			size := c.cachedSize(desc.SnapshotID, sizes)
+			u.UsedCount++
+			u.UsedBytes += size
+
+			if !protected[desc.SnapshotID] {
+				// Already deletable; does not consume budget
+				continue
+			}
+			fits := (b.MaxBytes == 0 || keptBytes+size <= b.MaxBytes) &&
+				(b.MaxCount == 0 || keptCount+1 <= b.MaxCount)
+			if fits || hardProtected[desc.SnapshotID] {
+				keptBytes += size
+				keptCount++
+				continue
+			}
+			delete(protected, desc.SnapshotID)
+			delete(byRetention, desc.SnapshotID)
+			u.Evicted++
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// LargestCandidates returns up to n of the snapshots a GC plan under
+// policy would delete, largest first, without saving a plan. It suggests
+// what to collect when space runs out.
+func (c *Collector) LargestCandidates(policy model.RetentionPolicy, n int) ([]model.SnapshotUsage, error) {
+	plan, err := c.plan(policy)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]model.SnapshotUsage, 0, len(plan.ToDelete))
+	for _, id := range plan.ToDelete {
+		candidates = append(candidates, model.SnapshotUsage{SnapshotID: id, Bytes: c.snapshotSize(id)})
+	}
+	slices.SortStableFunc(candidates, func(a, b model.SnapshotUsage) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates, nil
+}
+
+func (c *Collector) cachedSize(id model.SnapshotID, sizes map[model.SnapshotID]int64) int64 {
+	if size, ok := sizes[id]; ok {
+		return size
+	}
+	size := c.snapshotSize(id)
+	sizes[id] = size
+	return size
+}
+
+// snapshotSize returns the on-disk size of a snapshot's payload in bytes.
+func (c *Collector) snapshotSize(id model.SnapshotID) int64 {
+	var total int64
+	root := filepath.Join(c.repoRoot, ".jvs", "snapshots", string(id))
+	filepath.Walk(root, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
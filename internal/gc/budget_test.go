@@ -0,0 +1,319 @@
+package gc_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createOrphanedAutoSnapshots creates n snapshots tagged "auto" in a temporary
+// worktree and removes the worktree, so the snapshots are protected only by
+// age retention. Returns IDs oldest first.
+func createOrphanedAutoSnapshots(t *testing.T, repoPath string, n int) []model.SnapshotID {
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("scratch", nil)
+	require.NoError(t, err)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	var ids []model.SnapshotID
+	for i := 0; i < n; i++ {
+		content := strings.Repeat(string(rune('a'+i)), 1000)
+		require.NoError(t, os.WriteFile(filepath.Join(wtMgr.Path("scratch"), "data.bin"), []byte(content), 0644))
+		desc, err := creator.Create("scratch", "auto", []string{"auto"})
+		require.NoError(t, err)
+		ids = append(ids, desc.SnapshotID)
+	}
+	require.NoError(t, wtMgr.Remove("scratch"))
+	return ids
+}
+
+func TestCollector_LargestCandidates(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("scratch", nil)
+	require.NoError(t, err)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	var ids []model.SnapshotID
+	for _, size := range []int{100, 3000, 2000} {
+		require.NoError(t, os.WriteFile(filepath.Join(wtMgr.Path("scratch"), "data.bin"), make([]byte, size), 0644))
+		desc, err := creator.Create("scratch", "", nil)
+		require.NoError(t, err)
+		ids = append(ids, desc.SnapshotID)
+	}
+	require.NoError(t, wtMgr.Remove("scratch"))
+
+	candidates, err := gc.NewCollector(repoPath).LargestCandidates(model.RetentionPolicy{}, 2)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	assert.Equal(t, ids[1], candidates[0].SnapshotID)
+	assert.Equal(t, ids[2], candidates[1].SnapshotID)
+	assert.Greater(t, candidates[0].Bytes, candidates[1].Bytes)
+
+	// No plan is saved
+	plans, err := os.ReadDir(filepath.Join(repoPath, ".jvs", "gc"))
+	if err == nil {
+		for _, p := range plans {
+			assert.False(t, strings.HasSuffix(p.Name(), ".json"), p.Name())
+		}
+	}
+}
+
+func TestCollector_PlanWithPolicy_BudgetCount(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createOrphanedAutoSnapshots(t, repoPath, 3)
+
+	policy := model.RetentionPolicy{
+		KeepMinAge: time.Hour,
+		Budgets:    []model.TagBudget{{Tag: "auto", MaxCount: 1}},
+	}
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+
+	// Oldest two are evicted despite being within the age window
+	assert.ElementsMatch(t, ids[:2], plan.ToDelete)
+	assert.Contains(t, plan.ProtectedSet, ids[2])
+	assert.Equal(t, 1, plan.ProtectedByRetention)
+
+	require.Len(t, plan.BudgetUsage, 1)
+	u := plan.BudgetUsage[0]
+	assert.Equal(t, "auto", u.Tag)
+	assert.Equal(t, 3, u.UsedCount)
+	assert.Equal(t, 2, u.Evicted)
+	assert.True(t, u.OverBudget())
+}
+
+func TestCollector_PlanWithPolicy_BudgetBytes(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createOrphanedAutoSnapshots(t, repoPath, 3)
+
+	// Room for the two newest snapshots (all three are the same size)
+	collector := gc.NewCollector(repoPath)
+	usage, err := collector.BudgetUsage([]model.TagBudget{{Tag: "auto"}})
+	require.NoError(t, err)
+	maxBytes := usage[0].UsedBytes*2/3 + 16
+
+	policy := model.RetentionPolicy{
+		KeepMinAge: time.Hour,
+		Budgets:    []model.TagBudget{{Tag: "auto", MaxBytes: maxBytes}},
+	}
+	plan, err := collector.PlanWithPolicy(policy)
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.SnapshotID{ids[0]}, plan.ToDelete)
+	require.Len(t, plan.BudgetUsage, 1)
+	assert.Greater(t, plan.BudgetUsage[0].UsedBytes, int64(3000))
+	assert.Equal(t, 1, plan.BudgetUsage[0].Evicted)
+}
+
+func TestCollector_PlanWithPolicy_BudgetWithinLimit(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createOrphanedAutoSnapshots(t, repoPath, 2)
+
+	policy := model.RetentionPolicy{
+		KeepMinAge: time.Hour,
+		Budgets:    []model.TagBudget{{Tag: "auto", MaxCount: 5, MaxBytes: 1 << 20}},
+	}
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+
+	assert.Empty(t, plan.ToDelete)
+	require.Len(t, plan.BudgetUsage, 1)
+	assert.Equal(t, 2, plan.BudgetUsage[0].UsedCount)
+	assert.Zero(t, plan.BudgetUsage[0].Evicted)
+	assert.False(t, plan.BudgetUsage[0].OverBudget())
+}
+
+func TestCollector_PlanWithPolicy_BudgetKeepsLineage(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte{byte('a' + i)}, 0644))
+		_, err := creator.Create("main", "auto", []string{"auto"})
+		require.NoError(t, err)
+	}
+
+	policy := model.RetentionPolicy{
+		Budgets: []model.TagBudget{{Tag: "auto", MaxCount: 1}},
+	}
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+
+	// Head lineage is never evicted, but usage still reports the overrun
+	assert.Empty(t, plan.ToDelete)
+	require.Len(t, plan.BudgetUsage, 1)
+	assert.Equal(t, 3, plan.BudgetUsage[0].UsedCount)
+	assert.True(t, plan.BudgetUsage[0].OverBudget())
+}
+
+func TestCollector_BudgetUsage(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createOrphanedAutoSnapshots(t, repoPath, 2)
+
+	collector := gc.NewCollector(repoPath)
+	usage, err := collector.BudgetUsage(nil)
+	require.NoError(t, err)
+	assert.Empty(t, usage)
+
+	usage, err = collector.BudgetUsage([]model.TagBudget{{Tag: "auto", MaxCount: 1}, {Tag: "release"}})
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, 2, usage[0].UsedCount)
+	assert.True(t, usage[0].OverBudget())
+	assert.Zero(t, usage[1].UsedCount)
+}
+
+func TestCollector_PlanWithPolicy_KeepTags(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createOrphanedAutoSnapshots(t, repoPath, 3)
+
+	// Kept tags win over budgets
+	policy := model.RetentionPolicy{
+		KeepTags: []string{"auto"},
+		Budgets:  []model.TagBudget{{Tag: "auto", MaxCount: 1}},
+	}
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+	assert.Equal(t, 3, plan.ProtectedByRetention)
+
+	plan, err = gc.NewCollector(repoPath).PlanWithPolicy(model.RetentionPolicy{KeepTags: []string{"release"}})
+	require.NoError(t, err)
+	assert.Len(t, plan.ToDelete, 3)
+}
+
+func TestCollector_PlanWithPolicy_StaleIndex(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createOrphanedAutoSnapshots(t, repoPath, 2)
+	_, err := snapshot.ListIndexed(repoPath)
+	require.NoError(t, err)
+
+	// Tag the first snapshot in the descriptor store only, as an amend
+	// whose index update failed leaves it
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	desc, err := store.Get(ids[0])
+	require.NoError(t, err)
+	desc.Tags = append(desc.Tags, "release")
+	desc.DescriptorChecksum, err = integrity.ComputeDescriptorChecksum(desc)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(desc))
+	require.NoError(t, store.Close())
+
+	// Retention reads the descriptor store, not the stale index
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(model.RetentionPolicy{KeepTags: []string{"release"}})
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{ids[1]}, plan.ToDelete)
+
+	// Nor one that lost a snapshot
+	idx, err := index.Open(repoPath)
+	require.NoError(t, err)
+	require.NoError(t, idx.Delete(ids[1]))
+	require.NoError(t, idx.Close())
+	plan, err = gc.NewCollector(repoPath).PlanWithPolicy(model.RetentionPolicy{KeepTags: []string{"auto"}})
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+	assert.Equal(t, 2, plan.ProtectedByRetention)
+}
+
+func TestCollector_Plan_RepoRetentionPolicy(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ids := createOrphanedAutoSnapshots(t, repoPath, 2)
+
+	// The default policy keeps snapshots younger than a day
+	plan, err := gc.NewCollector(repoPath).Plan()
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+
+	require.NoError(t, os.WriteFile(config.RetentionPolicyPath(repoPath), []byte(`{"within": "0s"}`), 0644))
+	plan, err = gc.NewCollector(repoPath).Plan()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, ids, plan.ToDelete)
+
+	require.NoError(t, os.WriteFile(config.RetentionPolicyPath(repoPath), []byte(`{"within": "forever"}`), 0644))
+	_, err = gc.NewCollector(repoPath).Plan()
+	assert.Error(t, err)
+}
+
+func TestCollector_PlanWithPolicy_WorktreeRetention(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	autoIDs := createOrphanedAutoSnapshots(t, repoPath, 3)
+	featureID := createRemovedWorktreeSnapshot(t, repoPath)
+
+	// scratch keeps its newest snapshot; feature falls back to the policy,
+	// whose count does not see scratch's snapshots
+	policy := model.RetentionPolicy{
+		KeepMinSnapshots: 1,
+		Worktrees: map[string]model.WorktreeRetention{
+			"scratch": {KeepMinSnapshots: 1},
+		},
+	}
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, autoIDs[:2], plan.ToDelete)
+	assert.Contains(t, plan.ProtectedSet, autoIDs[2])
+	assert.Contains(t, plan.ProtectedSet, featureID)
+
+	// A worktree's age replaces the policy's
+	policy = model.RetentionPolicy{
+		KeepMinAge: time.Hour,
+		Worktrees: map[string]model.WorktreeRetention{
+			"feature": {},
+		},
+	}
+	plan, err = gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{featureID}, plan.ToDelete)
+}
+
+func TestCollector_PlanWithPolicy_TagRules(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	autoIDs := createOrphanedAutoSnapshots(t, repoPath, 3)
+
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("release", nil)
+	require.NoError(t, err)
+	release, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("release", "v1", []string{"release-1.0"})
+	require.NoError(t, err)
+	require.NoError(t, wtMgr.Remove("release"))
+
+	policy := model.RetentionPolicy{
+		KeepMinAge: time.Hour,
+		TagRules: []model.TagRule{
+			{Pattern: "release-*"},
+			{Pattern: "auto", KeepLast: 1},
+		},
+		Budgets: []model.TagBudget{{Tag: "auto", MaxCount: 1}, {Tag: "release-1.0", MaxBytes: 1}},
+	}
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+
+	// The release snapshot is over its budget but kept by its rule
+	assert.ElementsMatch(t, autoIDs[:2], plan.ToDelete)
+	assert.Equal(t, map[model.SnapshotID]string{
+		release.SnapshotID: "tag_rules:release-*",
+		autoIDs[2]:         "tag_rules:auto",
+	}, plan.ProtectedByRule)
+	assert.Equal(t, 2, plan.ProtectedByRetention)
+
+	// Without budgets, age protects what the rules do not
+	policy.Budgets = nil
+	plan, err = gc.NewCollector(repoPath).PlanWithPolicy(policy)
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+	assert.Equal(t, "keep_min_age", plan.ProtectedByRule[autoIDs[0]])
+}
@@ -2,16 +2,27 @@
 package gc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/cas"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/hooks"
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/webhook"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/fsutil"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/jvs-project/jvs/pkg/uuidutil"
@@ -22,6 +33,12 @@ type Collector struct {
 	repoRoot         string
 	auditLogger      *audit.FileAppender
 	progressCallback func(string, int, int, string)
+	checkConflicts   bool
+	lockWait         *time.Duration     // nil waits lock_max_wait
+	deleted          []model.SnapshotID // by the last Run
+	ctx              context.Context
+	correlationID    string
+	author           string
 }
 
 // NewCollector creates a new GC collector.
@@ -30,6 +47,7 @@ func NewCollector(repoRoot string) *Collector {
 	return &Collector{
 		repoRoot:    repoRoot,
 		auditLogger: audit.NewFileAppender(auditPath),
+		ctx:         context.Background(),
 	}
 }
 
@@ -38,13 +56,78 @@ func (c *Collector) SetProgressCallback(cb func(string, int, int, string)) {
 	c.progressCallback = cb
 }
 
-// Plan creates a GC plan.
+// SetLockWait sets how long Run and PruneTombstones wait for the exclusive
+// repository lock, instead of the configured lock_max_wait. Zero gives up
+// at once if another operation holds it.
+func (c *Collector) SetLockWait(d time.Duration) {
+	c.lockWait = &d
+}
+
+// SetCorrelationID sets the correlation ID of the operation Run belongs
+// to, recorded in its audit record and passed to its hook and webhooks.
+// Empty, the default, uses the one set through audit.CorrelationEnvVar, if
+// any.
+func (c *Collector) SetCorrelationID(id string) {
+	c.correlationID = id
+	c.auditLogger.SetCorrelationID(id)
+}
+
+// SetAuthor sets who Run's audit record names as its author, also passed
+// to its hook and webhooks. Empty, the default, uses identity.Author.
+func (c *Collector) SetAuthor(author string) {
+	c.author = author
+	c.auditLogger.SetAuthor(author)
+}
+
+// SetContext makes planning and Run stop once ctx is done, with ctx's
+// error. Run stops between deletions: snapshots already deleted stay
+// deleted and get their tombstones.
+func (c *Collector) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// locks returns a repository lock manager waiting as set by SetLockWait.
+func (c *Collector) locks() *repolock.Manager {
+	m := repolock.NewManager(c.repoRoot)
+	if c.lockWait != nil {
+		m.SetMaxWait(*c.lockWait)
+	}
+	return m
+}
+
+// Deleted returns the snapshots deleted by the last Run. Snapshots in the
+// plan that could not be deleted are not included.
+func (c *Collector) Deleted() []model.SnapshotID {
+	return c.deleted
+}
+
+// Plan creates a GC plan using the repository's retention policy, from
+// .jvs/retention.json or config.yaml.
 func (c *Collector) Plan() (*model.GCPlan, error) {
-	return c.PlanWithPolicy(model.DefaultRetentionPolicy())
+	policy, err := config.LoadRepoRetentionPolicy(c.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("load retention policy: %w", err)
+	}
+	return c.PlanWithPolicy(policy)
 }
 
 // PlanWithPolicy creates a GC plan using the given retention policy.
 func (c *Collector) PlanWithPolicy(policy model.RetentionPolicy) (*model.GCPlan, error) {
+	plan, err := c.plan(policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writePlan(plan); err != nil {
+		return nil, fmt.Errorf("write plan: %w", err)
+	}
+	return plan, nil
+}
+
+// plan computes a GC plan without saving it.
+func (c *Collector) plan(policy model.RetentionPolicy) (*model.GCPlan, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
 	protectedSet, protectedByLineage, protectedByPin, err := c.computeProtectedSet()
 	if err != nil {
 		return nil, fmt.Errorf("compute protected set: %w", err)
@@ -57,51 +140,135 @@ func (c *Collector) PlanWithPolicy(policy model.RetentionPolicy) (*model.GCPlan,
 	}
 
 	protectedMap := make(map[model.SnapshotID]bool)
+	hardProtected := make(map[model.SnapshotID]bool)
 	for _, id := range protectedSet {
 		protectedMap[id] = true
+		hardProtected[id] = true
+	}
+
+	// The retention rules read every descriptor, once, from the descriptor
+	// store. The repository index is not trusted here: it is a best-effort
+	// cache, and a stale row, such as one missing tags added by an amend
+	// whose index update failed, must not let a protected snapshot go.
+	var allDescs []*model.Descriptor
+	var allDescsErr error
+	listed := false
+	listDescriptors := func() ([]*model.Descriptor, error) {
+		if !listed {
+			allDescs, allDescsErr = snapshot.ListAll(c.repoRoot)
+			listed = true
+		}
+		return allDescs, allDescsErr
 	}
 
-	// Apply retention policy: protect by age
-	protectedByRetention := 0
+	// Apply retention policy: protect by tag first, so that the rules
+	// budgets cannot override are reported over age and count. byRetention
+	// records the rule protecting each snapshot.
+	byRetention := make(map[model.SnapshotID]string)
+	if len(policy.KeepTags) > 0 || len(policy.TagRules) > 0 {
+		allDescs, err := listDescriptors()
+		if err != nil {
+			return nil, fmt.Errorf("list descriptors for tag rules: %w", err)
+		}
+		protectByTag := func(desc *model.Descriptor, rule string) {
+			if hardProtected[desc.SnapshotID] {
+				return
+			}
+			hardProtected[desc.SnapshotID] = true
+			protectedMap[desc.SnapshotID] = true
+			byRetention[desc.SnapshotID] = rule
+		}
+		for _, desc := range allDescs {
+			for _, tag := range desc.Tags {
+				if slices.Contains(policy.KeepTags, tag) {
+					protectByTag(desc, "keep_tags:"+tag)
+					break
+				}
+			}
+		}
+		// allDescs is newest first
+		for _, rule := range policy.TagRules {
+			kept := 0
+			for _, desc := range allDescs {
+				if rule.KeepLast > 0 && kept >= rule.KeepLast {
+					break
+				}
+				if rule.Matches(desc.Tags) {
+					protectByTag(desc, "tag_rules:"+rule.Pattern)
+					kept++
+				}
+			}
+		}
+	}
+
+	// Apply retention policy: protect by age, with the age of the
+	// snapshot's worktree if it has its own
 	now := time.Now()
-	if policy.KeepMinAge > 0 {
+	if policy.KeepMinAge > 0 || len(policy.Worktrees) > 0 {
+		allDescs, err := listDescriptors()
+		if err != nil {
+			return nil, fmt.Errorf("list descriptors for age rules: %w", err)
+		}
+		byID := make(map[model.SnapshotID]*model.Descriptor, len(allDescs))
+		for _, desc := range allDescs {
+			byID[desc.SnapshotID] = desc
+		}
 		for _, id := range allSnapshots {
 			if protectedMap[id] {
 				continue
 			}
-			desc, err := snapshot.LoadDescriptor(c.repoRoot, id)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "warning: gc: skipping descriptor %s: %v\n", id, err)
+			desc, ok := byID[id]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "warning: gc: skipping snapshot %s without a READY descriptor\n", id)
 				continue
 			}
-			if now.Sub(desc.CreatedAt) < policy.KeepMinAge {
+			wr, own := policy.RetentionFor(desc.WorktreeName)
+			if now.Sub(desc.CreatedAt) < wr.KeepMinAge {
 				protectedMap[id] = true
-				protectedByRetention++
+				byRetention[id] = retentionRule(desc.WorktreeName, own, "keep_min_age")
 			}
 		}
 	}
 
-	// Apply retention policy: protect by count (keep most recent N)
-	if policy.KeepMinSnapshots > 0 {
-		allDescs, err := snapshot.ListAll(c.repoRoot)
+	// Apply retention policy: protect by count (keep most recent N). The
+	// snapshots of worktrees with their own retention are counted per
+	// worktree, the others together.
+	if policy.KeepMinSnapshots > 0 || len(policy.Worktrees) > 0 {
+		allDescs, err := listDescriptors()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "warning: gc: failed to list all descriptors for retention-by-count: %v\n", err)
 		}
 		if err == nil {
-			kept := 0
+			kept := make(map[string]int)
 			for _, desc := range allDescs {
-				if kept >= policy.KeepMinSnapshots {
-					break
+				wr, own := policy.RetentionFor(desc.WorktreeName)
+				group := ""
+				if own {
+					group = desc.WorktreeName
+				}
+				if kept[group] >= wr.KeepMinSnapshots {
+					continue
 				}
 				if !protectedMap[desc.SnapshotID] {
 					protectedMap[desc.SnapshotID] = true
-					protectedByRetention++
+					byRetention[desc.SnapshotID] = retentionRule(desc.WorktreeName, own, "keep_min_snapshots")
 				}
-				kept++
+				kept[group]++
 			}
 		}
 	}
 
+	// Apply tag budgets: evict the oldest snapshots of over-budget classes
+	// even if age or count retention would keep them
+	var budgetUsage []model.BudgetUsage
+	if len(policy.Budgets) > 0 {
+		allDescs, err := listDescriptors()
+		if err != nil {
+			return nil, fmt.Errorf("list descriptors for budgets: %w", err)
+		}
+		budgetUsage = c.applyBudgets(allDescs, policy.Budgets, hardProtected, protectedMap, byRetention)
+	}
+
 	// Rebuild protected set from map
 	protectedSet = protectedSet[:0]
 	for id := range protectedMap {
@@ -115,7 +282,7 @@ func (c *Collector) PlanWithPolicy(policy model.RetentionPolicy) (*model.GCPlan,
 		}
 	}
 
-	deletableBytes := int64(len(toDelete)) * 1024 * 1024
+	reclaim, deletableBytes := c.estimateReclaim(toDelete)
 
 	plan := &model.GCPlan{
 		PlanID:                 uuidutil.NewV4(),
@@ -123,31 +290,70 @@ func (c *Collector) PlanWithPolicy(policy model.RetentionPolicy) (*model.GCPlan,
 		ProtectedSet:           protectedSet,
 		ProtectedByPin:         protectedByPin,
 		ProtectedByLineage:     protectedByLineage,
-		ProtectedByRetention:   protectedByRetention,
+		ProtectedByRetention:   len(byRetention),
 		CandidateCount:         len(toDelete),
 		ToDelete:               toDelete,
 		DeletableBytesEstimate: deletableBytes,
 		RetentionPolicy:        policy,
+		BudgetUsage:            budgetUsage,
+		Reclaim:                reclaim,
 	}
-
-	if err := c.writePlan(plan); err != nil {
-		return nil, fmt.Errorf("write plan: %w", err)
+	if len(byRetention) > 0 {
+		plan.ProtectedByRule = byRetention
 	}
-
 	return plan, nil
 }
 
-// Run executes a GC plan.
+// Run executes a GC plan under the exclusive repository lock, so it waits
+// for running snapshots and restores and new ones queue behind it. With
+// SetCheckConflicts, snapshots still needed by live restores and leased
+// worktrees abort the run first; then the protected set is recomputed and
+// must not overlap the plan. Under lease locking, a run that loses the
+// lock stops deleting and fails with errclass.ErrLockLost; a run whose
+// context is done (SetContext) stops likewise with the context's error.
+//
+// The repository's post-gc hook runs after a successful run, and then its
+// gc.completed webhooks.
 func (c *Collector) Run(planID string) error {
 	if planID == "" {
 		return fmt.Errorf("plan ID is required")
 	}
+	if err := c.run(planID); err != nil {
+		return err
+	}
+	ctx := audit.WithAuthor(audit.WithCorrelationID(c.ctx, c.correlationID), c.author)
+	hooks.RunPost(ctx, c.repoRoot, hooks.PostGC, map[string]string{
+		hooks.EnvOperation: "gc",
+		hooks.EnvGCPlanID:  planID,
+		hooks.EnvGCDeleted: strconv.Itoa(len(c.deleted)),
+	})
+	webhook.Notify(ctx, c.repoRoot, webhook.NewGCEvent(planID, c.deleted))
+	return nil
+}
+
+func (c *Collector) run(planID string) error {
+
+	lock, err := c.locks().Acquire(model.LockExclusive, "gc")
+	if err != nil {
+		return fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
 
 	plan, err := c.LoadPlan(planID)
 	if err != nil {
 		return fmt.Errorf("load plan: %w", err)
 	}
 
+	if c.checkConflicts {
+		conflicts, err := c.Conflicts(plan)
+		if err != nil {
+			return fmt.Errorf("check conflicts: %w", err)
+		}
+		if len(conflicts) > 0 {
+			return &ConflictError{Conflicts: conflicts}
+		}
+	}
+
 	// Revalidate protected set
 	currentProtected, _, _, err := c.computeProtectedSet()
 	if err != nil {
@@ -170,7 +376,15 @@ func (c *Collector) Run(planID string) error {
 
 	// Delete snapshots
 	var deleted []model.SnapshotID
+	var stopErr error
 	for i, snapshotID := range plan.ToDelete {
+		if stopErr = c.ctx.Err(); stopErr != nil {
+			break
+		}
+		if stopErr = lock.Check(); stopErr != nil {
+			break
+		}
+
 		// Report progress
 		if c.progressCallback != nil {
 			c.progressCallback("gc", i+1, totalToDelete, fmt.Sprintf("deleting %s", snapshotID.ShortID()))
@@ -183,6 +397,7 @@ func (c *Collector) Run(planID string) error {
 		}
 		deleted = append(deleted, snapshotID)
 	}
+	c.deleted = deleted
 
 	// Report completion
 	if c.progressCallback != nil && totalToDelete > 0 {
@@ -198,15 +413,40 @@ func (c *Collector) Run(planID string) error {
 		}
 		c.writeTombstone(tombstone)
 	}
+	if stopErr != nil {
+		return stopErr
+	}
+
+	// Remove tombstones the plan's policy no longer keeps
+	var tombstonesPruned []model.SnapshotID
+	if maxAge := plan.RetentionPolicy.TombstoneMaxAge; maxAge > 0 {
+		if tombstonesPruned, err = c.pruneTombstones(maxAge); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to prune tombstones: %v\n", err)
+		}
+	}
+
+	// Remove blobs only the deleted snapshots linked to
+	blobsPruned, blobBytes, err := cas.NewStore(c.repoRoot).Prune()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to prune content store: %v\n", err)
+	}
 
 	// Cleanup plan
 	c.deletePlan(planID)
 
 	// Audit
-	c.auditLogger.Append(model.EventTypeGCRun, "", "", map[string]any{
+	auditData := map[string]any{
 		"plan_id":       planID,
 		"deleted_count": len(deleted),
-	})
+	}
+	if blobsPruned > 0 {
+		auditData["blobs_pruned"] = blobsPruned
+		auditData["blob_bytes_pruned"] = blobBytes
+	}
+	if len(tombstonesPruned) > 0 {
+		auditData["tombstones_pruned"] = len(tombstonesPruned)
+	}
+	c.auditLogger.Append(model.EventTypeGCRun, "", "", auditData)
 
 	return nil
 }
@@ -228,20 +468,37 @@ func (c *Collector) computeProtectedSet() ([]model.SnapshotID, int, int, error)
 		}
 	}
 
+	// 1b. Roots retained from removed worktrees count as heads
+	retained, err := wtMgr.RetainedRoots()
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("read retained roots: %w", err)
+	}
+	for _, roots := range retained {
+		for _, r := range roots {
+			protected[r.SnapshotID] = true
+		}
+	}
+
 	// 2. Lineage traversal (keep parent chains)
 	for id := range protected {
 		lineageCount += c.walkLineage(id, protected)
 	}
 
 	// 3. All intents (in-progress operations)
-	intentsDir := filepath.Join(c.repoRoot, ".jvs", "intents")
-	entries, _ := os.ReadDir(intentsDir)
+	// Creation intents are named after the snapshot being created
+	entries, _ := os.ReadDir(repo.IntentsDir(c.repoRoot))
 	for _, entry := range entries {
 		name := entry.Name()
-		if strings.HasSuffix(name, ".json") {
+		if strings.HasSuffix(name, ".json") && !strings.HasPrefix(name, repo.RestoreIntentPrefix) {
 			protected[model.SnapshotID(strings.TrimSuffix(name, ".json"))] = true
 		}
 	}
+	intents, _ := repo.ListIntents(c.repoRoot)
+	for _, intent := range intents {
+		if intent.Operation == model.IntentOperationRestore {
+			protected[intent.SnapshotID] = true
+		}
+	}
 
 	// 4. All pins
 	pinsDir := filepath.Join(c.repoRoot, ".jvs", "pins")
@@ -260,7 +517,7 @@ func (c *Collector) computeProtectedSet() ([]model.SnapshotID, int, int, error)
 					continue
 				}
 				// Check if pin has expired
-				if pin.ExpiresAt != nil && pin.ExpiresAt.Before(time.Now()) {
+				if pin.Expired(time.Now()) {
 					continue // Skip expired pins
 				}
 				if !protected[pin.SnapshotID] {
@@ -278,9 +535,17 @@ func (c *Collector) computeProtectedSet() ([]model.SnapshotID, int, int, error)
 	return result, lineageCount, pinCount, nil
 }
 
+// walkLineage protects the ancestors of snapshotID. Descriptors are read
+// from the store directly: a snapshot that is not READY must still keep its
+// parents alive, since deleting them is not reversible.
 func (c *Collector) walkLineage(snapshotID model.SnapshotID, protected map[model.SnapshotID]bool) int {
 	count := 0
-	desc, err := snapshot.LoadDescriptor(c.repoRoot, snapshotID)
+	store, err := descstore.Open(c.repoRoot)
+	if err != nil {
+		return count
+	}
+	desc, err := store.Get(snapshotID)
+	store.Close()
 	if err != nil {
 		return count
 	}
@@ -317,9 +582,26 @@ func (c *Collector) deleteSnapshot(snapshotID model.SnapshotID) error {
 		return fmt.Errorf("remove snapshot dir: %w", err)
 	}
 
+	if err := os.Remove(repo.MetadataHistoryPath(c.repoRoot, snapshotID)); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "warning: failed to remove metadata history %s: %v\n", snapshotID, err)
+	}
+	for _, path := range []string{repo.ManifestPath(c.repoRoot, snapshotID), repo.LegacyManifestPath(c.repoRoot, snapshotID)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove manifest %s: %v\n", snapshotID, err)
+		}
+	}
+	if err := index.Remove(c.repoRoot, snapshotID); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to remove %s from index: %v\n", snapshotID, err)
+	}
+
 	// Delete descriptor - log warning if fails but don't fail the operation
-	descriptorPath := filepath.Join(c.repoRoot, ".jvs", "descriptors", string(snapshotID)+".json")
-	if err := os.Remove(descriptorPath); err != nil && !os.IsNotExist(err) {
+	store, err := descstore.Open(c.repoRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to remove descriptor %s: %v\n", snapshotID, err)
+		return nil
+	}
+	defer store.Close()
+	if err := store.Delete(snapshotID); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: failed to remove descriptor %s: %v\n", snapshotID, err)
 	}
 
@@ -370,3 +652,11 @@ func (c *Collector) writeTombstone(tombstone *model.Tombstone) {
 	}
 	fsutil.AtomicWrite(path, data, 0644)
 }
+
+// retentionRule names an age or count rule for GCPlan.ProtectedByRule.
+func retentionRule(worktreeName string, own bool, field string) string {
+	if own {
+		return "worktrees." + worktreeName + "." + field
+	}
+	return field
+}
@@ -1,16 +1,21 @@
 package gc_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jvs-project/jvs/internal/cas"
 	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/hooks"
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -70,6 +75,27 @@ func TestCollector_Run(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCollector_Run_PostGCHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need an executable bit and sh")
+	}
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+	hookDir := hooks.Dir(repoPath)
+	require.NoError(t, os.MkdirAll(hookDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(hookDir, hooks.PostGC),
+		[]byte("#!/bin/sh\necho \"$JVS_GC_PLAN_ID $JVS_GC_DELETED\" > gc.txt\n"), 0755))
+
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.Plan()
+	require.NoError(t, err)
+	require.NoError(t, collector.Run(plan.PlanID))
+
+	data, err := os.ReadFile(filepath.Join(repoPath, "gc.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, plan.PlanID+" 0\n", string(data))
+}
+
 func TestCollector_Run_InvalidPlanID(t *testing.T) {
 	repoPath := setupTestRepo(t)
 
@@ -928,3 +954,125 @@ func TestCollector_PlanWithPolicy_CombinedRetention(t *testing.T) {
 	assert.Empty(t, plan.ToDelete)
 	assert.Greater(t, plan.ProtectedByRetention, 0)
 }
+
+func TestCollector_Plan_RetainedRootsProtectLineage(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("exp", nil)
+	require.NoError(t, err)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	first, err := creator.Create("exp", "one", nil)
+	require.NoError(t, err)
+	second, err := creator.Create("exp", "two", nil)
+	require.NoError(t, err)
+
+	_, err = wtMgr.RemoveRetaining("exp", "archived/exp")
+	require.NoError(t, err)
+
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	assert.Contains(t, plan.ProtectedSet, second.SnapshotID)
+	assert.Contains(t, plan.ProtectedSet, first.SnapshotID, "ancestors of a retained root are kept")
+	assert.Empty(t, plan.ToDelete)
+
+	// Without retention the snapshots become collectable
+	require.NoError(t, os.Remove(filepath.Join(repoPath, ".jvs", "gc", "retained", "archived", "exp.json")))
+	plan, err = gc.NewCollector(repoPath).PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []model.SnapshotID{first.SnapshotID, second.SnapshotID}, plan.ToDelete)
+}
+
+func TestCollector_Plan_SnapshotNotReady(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	mainPath := filepath.Join(repoPath, "main")
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("v1"), 0644))
+	parent, err := creator.Create("main", "parent", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("v2"), 0644))
+	head, err := creator.Create("main", "head", nil)
+	require.NoError(t, err)
+
+	// An orphan from a removed worktree
+	wtMgr := worktree.NewManager(repoPath)
+	_, err = wtMgr.Create("temp", nil)
+	require.NoError(t, err)
+	orphan, err := creator.Create("temp", "orphan", nil)
+	require.NoError(t, err)
+	require.NoError(t, wtMgr.Remove("temp"))
+
+	// Both lose their .READY markers
+	for _, id := range []model.SnapshotID{head.SnapshotID, orphan.SnapshotID} {
+		require.NoError(t, os.Remove(filepath.Join(repo.SnapshotPath(repoPath, id), ".READY")))
+	}
+
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(model.RetentionPolicy{KeepMinSnapshots: 10})
+	require.NoError(t, err)
+
+	// A head is never collected and its lineage stays protected
+	assert.Contains(t, plan.ProtectedSet, head.SnapshotID)
+	assert.Contains(t, plan.ProtectedSet, parent.SnapshotID)
+	// Retention does not keep unpublished snapshots
+	assert.Equal(t, []model.SnapshotID{orphan.SnapshotID}, plan.ToDelete)
+}
+
+func TestCollector_Run_PrunesContentStore(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	cfg := config.Default()
+	cfg.ContentStore = true
+	require.NoError(t, config.Save(repoPath, cfg))
+	createTestSnapshot(t, repoPath)
+
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("feature", nil)
+	require.NoError(t, err)
+	featurePath := wtMgr.Path("feature")
+	require.NoError(t, os.WriteFile(filepath.Join(featurePath, "file.txt"), []byte("content"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(featurePath, "extra.txt"), []byte("feature only"), 0644))
+	_, err = snapshot.NewCreator(repoPath, model.EngineCopy).Create("feature", "feature snapshot", nil)
+	require.NoError(t, err)
+	require.NoError(t, wtMgr.Remove("feature"))
+
+	store := cas.NewStore(repoPath)
+	blobs, _, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, blobs)
+
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	// Only the blob of extra.txt is expected to be freed
+	require.Len(t, plan.Reclaim, 1)
+	assert.Equal(t, plan.Reclaim[0].Bytes-int64(len("content")), plan.Reclaim[0].ReclaimBytes)
+	require.NoError(t, collector.Run(plan.PlanID))
+
+	// The blob shared with main's snapshot stays
+	blobs, bytes, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, blobs)
+	assert.Equal(t, int64(len("content")), bytes)
+}
+
+func TestCollector_SetContext(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+	featureID := createRemovedWorktreeSnapshot(t, repoPath)
+
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	require.Equal(t, []model.SnapshotID{featureID}, plan.ToDelete)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	collector.SetContext(ctx)
+	_, err = collector.PlanWithPolicy(zeroRetention)
+	assert.ErrorIs(t, err, context.Canceled)
+	err = collector.Run(plan.PlanID)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, collector.Deleted())
+	_, err = snapshot.LoadDescriptor(repoPath, featureID)
+	assert.NoError(t, err)
+}
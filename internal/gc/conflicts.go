@@ -0,0 +1,115 @@
+package gc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// ConflictError is returned by Run when conflict checking is enabled and
+// snapshots in the plan are still needed by live operations. It matches
+// errclass.ErrGCPlanMismatch with errors.Is.
+type ConflictError struct {
+	Conflicts []model.GCConflict
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d snapshot(s) in the plan are in use:", errclass.ErrGCPlanMismatch.Code, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		b.WriteString("\n  ")
+		b.WriteString(DescribeConflict(c))
+	}
+	return b.String()
+}
+
+// Is reports whether target is errclass.ErrGCPlanMismatch.
+func (e *ConflictError) Is(target error) bool {
+	return errors.Is(errclass.ErrGCPlanMismatch, target)
+}
+
+// DescribeConflict returns a one-line description of a conflict.
+func DescribeConflict(c model.GCConflict) string {
+	switch c.Reason {
+	case model.GCConflictRestore:
+		return fmt.Sprintf("%s: being restored into %s since %s", c.SnapshotID, c.Worktree, c.Since.Format("2006-01-02 15:04:05"))
+	default:
+		return fmt.Sprintf("%s: needed by worktree %s, leased by %s since %s", c.SnapshotID, c.Worktree, c.Holder, c.Since.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// SetCheckConflicts makes Run abort with a *ConflictError, before deleting
+// anything, if Conflicts finds any.
+func (c *Collector) SetCheckConflicts(check bool) {
+	c.checkConflicts = check
+}
+
+// Conflicts returns the snapshots in plan.ToDelete that live operations
+// still need: the source of an in-flight restore, found in the restore
+// intents, and the latest or base snapshot of a worktree with an active
+// lease, which its consumer may restore at any time. A detached worktree's
+// latest snapshot is not protected by lineage.
+func (c *Collector) Conflicts(plan *model.GCPlan) ([]model.GCConflict, error) {
+	candidates := make(map[model.SnapshotID]bool, len(plan.ToDelete))
+	for _, id := range plan.ToDelete {
+		candidates[id] = true
+	}
+	var conflicts []model.GCConflict
+
+	intents, err := repo.ListIntents(c.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, intent := range intents {
+		if intent.Operation == model.IntentOperationRestore && candidates[intent.SnapshotID] {
+			conflicts = append(conflicts, model.GCConflict{
+				SnapshotID: intent.SnapshotID,
+				Reason:     model.GCConflictRestore,
+				Worktree:   intent.WorktreeName,
+				Since:      intent.StartedAt,
+			})
+		}
+	}
+
+	leases, err := lease.NewManager(c.repoRoot).List()
+	if err != nil {
+		return nil, fmt.Errorf("list leases: %w", err)
+	}
+	wtMgr := worktree.NewManager(c.repoRoot)
+	for _, l := range leases {
+		cfg, err := wtMgr.Get(l.WorktreeName)
+		if err != nil {
+			continue // lease on a removed worktree
+		}
+		ids := []model.SnapshotID{cfg.LatestSnapshotID}
+		if cfg.BaseSnapshotID != cfg.LatestSnapshotID {
+			ids = append(ids, cfg.BaseSnapshotID)
+		}
+		for _, id := range ids {
+			if id != "" && candidates[id] {
+				conflicts = append(conflicts, model.GCConflict{
+					SnapshotID: id,
+					Reason:     model.GCConflictLease,
+					Worktree:   l.WorktreeName,
+					Holder:     l.Holder,
+					Since:      l.AcquiredAt,
+				})
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].SnapshotID != conflicts[j].SnapshotID {
+			return conflicts[i].SnapshotID < conflicts[j].SnapshotID
+		}
+		return conflicts[i].Reason < conflicts[j].Reason
+	})
+	return conflicts, nil
+}
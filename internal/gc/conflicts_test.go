@@ -0,0 +1,117 @@
+package gc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/restore"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// writeRestoreIntent simulates a restore of id into worktreeName that is
+// still copying.
+func writeRestoreIntent(t *testing.T, repoPath string, id model.SnapshotID, worktreeName string) {
+	t.Helper()
+	data, err := json.Marshal(model.IntentRecord{
+		SnapshotID:   id,
+		WorktreeName: worktreeName,
+		StartedAt:    time.Now().UTC(),
+		Engine:       model.EngineCopy,
+		Operation:    model.IntentOperationRestore,
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repo.IntentsDir(repoPath), repo.RestoreIntentPrefix+"test.json"), data, 0644))
+}
+
+// removedWorktreeSnapshot returns an unprotected snapshot of a removed worktree.
+func removedWorktreeSnapshot(t *testing.T, repoPath string) model.SnapshotID {
+	t.Helper()
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("feature", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(wtMgr.Path("feature"), "f.txt"), []byte("f"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("feature", "feature", nil)
+	require.NoError(t, err)
+	require.NoError(t, wtMgr.Remove("feature"))
+	return desc.SnapshotID
+}
+
+func TestCollector_Conflicts_InFlightRestore(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	orphan := removedWorktreeSnapshot(t, repoPath)
+
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	require.Contains(t, plan.ToDelete, orphan)
+
+	// A restore starts after the plan was made
+	writeRestoreIntent(t, repoPath, orphan, "main")
+
+	conflicts, err := collector.Conflicts(plan)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, orphan, conflicts[0].SnapshotID)
+	assert.Equal(t, model.GCConflictRestore, conflicts[0].Reason)
+	assert.Equal(t, "main", conflicts[0].Worktree)
+
+	collector.SetCheckConflicts(true)
+	err = collector.Run(plan.PlanID)
+	var conflictErr *gc.ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.True(t, errors.Is(err, errclass.ErrGCPlanMismatch))
+	assert.Contains(t, err.Error(), "being restored into main")
+	assert.DirExists(t, repo.SnapshotPath(repoPath, orphan), "nothing is deleted")
+
+	// Restore targets are protected in new plans
+	plan, err = collector.PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	assert.NotContains(t, plan.ToDelete, orphan)
+	assert.Contains(t, plan.ProtectedSet, orphan)
+}
+
+func TestCollector_Conflicts_LeasedWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	first := createTestSnapshot(t, repoPath)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte("v2"), 0644))
+	latest, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "v2", nil)
+	require.NoError(t, err)
+
+	// Detached at the first snapshot: the latest one is not in the lineage
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", first))
+
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	require.Contains(t, plan.ToDelete, latest.SnapshotID)
+
+	conflicts, err := collector.Conflicts(plan)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts, "no consumer is attached")
+
+	_, err = lease.NewManager(repoPath).Acquire("main", "pod-1", time.Hour)
+	require.NoError(t, err)
+	conflicts, err = collector.Conflicts(plan)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, latest.SnapshotID, conflicts[0].SnapshotID)
+	assert.Equal(t, model.GCConflictLease, conflicts[0].Reason)
+	assert.Equal(t, "pod-1", conflicts[0].Holder)
+
+	// Without the check, the run goes ahead
+	require.NoError(t, collector.Run(plan.PlanID))
+	assert.NoDirExists(t, repo.SnapshotPath(repoPath, latest.SnapshotID))
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package gc
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileLinks returns the identity of a file and its number of hard links.
+func fileLinks(info os.FileInfo) (fileID, uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, uint64(stat.Nlink), true
+}
@@ -0,0 +1,82 @@
+package gc
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// fileID identifies a file across its hard links.
+type fileID struct {
+	dev, ino uint64
+}
+
+// linkedFile is a file found in the payloads of deleted snapshots.
+type linkedFile struct {
+	size  int64
+	links uint64 // hard links to the file
+	seen  uint64 // of which in the deleted payloads
+	owner int    // index of the first deleted snapshot that has it
+	// stored is set if the file is a content store blob, whose own link
+	// goes when no snapshot links to it any more
+	stored bool
+}
+
+// estimateReclaim returns the size of each snapshot in toDelete and the
+// space deleting them all frees. A file hard-linked from snapshots that
+// are kept, such as unchanged files of incremental snapshots, frees
+// nothing; one linked only from deleted snapshots is counted once, for the
+// first of them in toDelete. Reflinked copies share blocks the filesystem
+// does not expose, so they count in full.
+func (c *Collector) estimateReclaim(toDelete []model.SnapshotID) ([]model.SnapshotReclaim, int64) {
+	reclaim := make([]model.SnapshotReclaim, len(toDelete))
+	files := make(map[fileID]*linkedFile)
+	for i, id := range toDelete {
+		reclaim[i].SnapshotID = id
+		stored := false
+		if desc, err := snapshot.LoadDescriptor(c.repoRoot, id); err == nil {
+			stored = desc.ContentStore != nil
+		}
+		counted := make(map[fileID]bool)
+		filepath.Walk(repo.SnapshotPath(c.repoRoot, id), func(_ string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			fid, links, ok := fileLinks(info)
+			if !ok || links <= 1 {
+				reclaim[i].Bytes += info.Size()
+				reclaim[i].ReclaimBytes += info.Size()
+				return nil
+			}
+			if !counted[fid] {
+				counted[fid] = true
+				reclaim[i].Bytes += info.Size()
+			}
+			f, ok := files[fid]
+			if !ok {
+				f = &linkedFile{size: info.Size(), links: links, owner: i, stored: stored}
+				files[fid] = f
+			}
+			f.seen++
+			return nil
+		})
+	}
+
+	for _, f := range files {
+		seen := f.seen
+		if f.stored {
+			seen++
+		}
+		if seen >= f.links {
+			reclaim[f.owner].ReclaimBytes += f.size
+		}
+	}
+	var total int64
+	for _, r := range reclaim {
+		total += r.ReclaimBytes
+	}
+	return reclaim, total
+}
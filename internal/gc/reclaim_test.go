@@ -0,0 +1,65 @@
+package gc_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestCollector_PlanWithPolicy_Reclaim(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links are not detected on Windows")
+	}
+	repoPath := setupTestRepo(t)
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("scratch", nil)
+	require.NoError(t, err)
+	scratch := wtMgr.Path("scratch")
+
+	// The second snapshot hard-links the unchanged shared.bin
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetIncremental(true)
+	require.NoError(t, os.WriteFile(filepath.Join(scratch, "shared.bin"), make([]byte, 1000), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(scratch, "changed.bin"), make([]byte, 500), 0644))
+	first, err := creator.Create("scratch", "first", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(scratch, "changed.bin"), make([]byte, 300), 0644))
+	second, err := creator.Create("scratch", "second", nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, second.Incremental.Linked)
+	require.NoError(t, wtMgr.Remove("scratch"))
+	// Payload sizes include the .READY markers
+	marker := func(id model.SnapshotID) int64 {
+		info, err := os.Stat(filepath.Join(repo.SnapshotPath(repoPath, id), ".READY"))
+		require.NoError(t, err)
+		return info.Size()
+	}
+	firstMarker, secondMarker := marker(first.SnapshotID), marker(second.SnapshotID)
+
+	// Deleting both frees the shared file once
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(zeroRetention)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotReclaim{
+		{SnapshotID: first.SnapshotID, Bytes: 1500 + firstMarker, ReclaimBytes: 1500 + firstMarker},
+		{SnapshotID: second.SnapshotID, Bytes: 1300 + secondMarker, ReclaimBytes: 300 + secondMarker},
+	}, plan.Reclaim)
+	assert.Equal(t, 1800+firstMarker+secondMarker, plan.DeletableBytesEstimate)
+
+	// The kept second snapshot still links the shared file
+	plan, err = gc.NewCollector(repoPath).PlanWithPolicy(model.RetentionPolicy{KeepMinSnapshots: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotReclaim{
+		{SnapshotID: first.SnapshotID, Bytes: 1500 + firstMarker, ReclaimBytes: 500 + firstMarker},
+	}, plan.Reclaim)
+	assert.Equal(t, 500+firstMarker, plan.DeletableBytesEstimate)
+}
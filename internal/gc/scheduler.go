@@ -0,0 +1,182 @@
+package gc
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/uuidutil"
+)
+
+// DefaultScheduleInterval is how often a Scheduler collects by default.
+const DefaultScheduleInterval = 6 * time.Hour
+
+// SchedulerOptions configures a Scheduler.
+type SchedulerOptions struct {
+	Interval time.Duration // zero uses DefaultScheduleInterval
+
+	// Jitter delays every cycle, the first included, by a random duration
+	// up to Jitter, so that schedulers of many repositories started
+	// together do not all collect at once.
+	Jitter time.Duration
+
+	// Policy returns the retention policy of a cycle. It is called every
+	// cycle, so that policy changes apply without a restart. Nil uses the
+	// repository's retention policy, reread every cycle.
+	Policy func() (model.RetentionPolicy, error)
+
+	// CheckConflicts skips a cycle's deletions if a snapshot in its plan
+	// is used by an in-flight restore or a leased worktree.
+	CheckConflicts bool
+
+	// BeforeRun, if set, is called with the plan of every cycle that would
+	// delete snapshots before it runs. An error fails the cycle without
+	// deleting anything.
+	BeforeRun func(ctx context.Context, plan *model.GCPlan) error
+
+	// OnCycle, if set, is called after every cycle.
+	OnCycle func(*Cycle)
+}
+
+// Cycle is the outcome of one scheduled GC: a plan and its run.
+type Cycle struct {
+	StartedAt time.Time          `json:"started_at"`
+	PlanID    string             `json:"plan_id,omitempty"`
+	Planned   int                `json:"planned"`
+	Deleted   []model.SnapshotID `json:"deleted,omitempty"`
+	// Error says why the cycle failed; the next cycle plans afresh.
+	Error string `json:"error,omitempty"`
+}
+
+// Scheduler plans and runs GC periodically, so that the two-step plan/run
+// flow does not need an external cron job.
+type Scheduler struct {
+	repoRoot string
+	opts     SchedulerOptions
+}
+
+// NewScheduler creates a Scheduler for the repository at repoRoot.
+func NewScheduler(repoRoot string, opts SchedulerOptions) *Scheduler {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultScheduleInterval
+	}
+	if opts.Jitter < 0 {
+		opts.Jitter = 0
+	}
+	return &Scheduler{repoRoot: repoRoot, opts: opts}
+}
+
+// Run collects after the jitter delay and then every interval plus jitter,
+// until ctx is cancelled; it then returns nil. A failed cycle does not stop
+// the scheduler.
+func (s *Scheduler) Run(ctx context.Context) error {
+	timer := time.NewTimer(s.jitter())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			cycle := s.runCycle(ctx)
+			if s.opts.OnCycle != nil {
+				s.opts.OnCycle(cycle)
+			}
+			timer.Reset(s.opts.Interval + s.jitter())
+		}
+	}
+}
+
+func (s *Scheduler) jitter() time.Duration {
+	if s.opts.Jitter == 0 {
+		return 0
+	}
+	return rand.N(s.opts.Jitter + 1)
+}
+
+// RunCycle plans GC with the current policy and runs the plan at once. The
+// plan is audited as gc_plan and, like the run, tagged with a correlation
+// ID of the cycle; a failed run is audited as gc_run with its error. Run
+// holds the repository lock exclusive and revalidates the plan, so a
+// snapshot protected between planning and running fails the cycle rather
+// than being deleted.
+func (s *Scheduler) RunCycle() *Cycle {
+	return s.runCycle(context.Background())
+}
+
+// runCycle is RunCycle stopping once ctx is done, as the scheduler is.
+func (s *Scheduler) runCycle(ctx context.Context) *Cycle {
+	cycle := &Cycle{StartedAt: time.Now().UTC()}
+	correlationID := "gc-" + uuidutil.NewV4()[:8]
+	ctx = audit.WithCorrelationID(ctx, correlationID)
+	auditLogger := audit.NewFileAppender(audit.LogPath(s.repoRoot))
+	auditLogger.SetCorrelationID(correlationID)
+
+	policy, err := s.policy()
+	if err != nil {
+		cycle.Error = err.Error()
+		return cycle
+	}
+	if err := policy.Validate(); err != nil {
+		cycle.Error = err.Error()
+		return cycle
+	}
+
+	collector := NewCollector(s.repoRoot)
+	collector.SetContext(ctx)
+	collector.SetCorrelationID(correlationID)
+	plan, err := collector.PlanWithPolicy(policy)
+	if err != nil {
+		cycle.Error = "plan: " + err.Error()
+		return cycle
+	}
+	cycle.PlanID = plan.PlanID
+	cycle.Planned = len(plan.ToDelete)
+	auditLogger.Append(model.EventTypeGCPlan, "", "", map[string]any{
+		"plan_id":   plan.PlanID,
+		"to_delete": len(plan.ToDelete),
+		"trigger":   "schedule",
+	})
+	if len(plan.ToDelete) == 0 {
+		collector.deletePlan(plan.PlanID)
+		// Runs prune old tombstones; with nothing to run, prune them here
+		if policy.TombstoneMaxAge > 0 {
+			if _, err := collector.PruneTombstones(policy.TombstoneMaxAge); err != nil {
+				cycle.Error = "prune tombstones: " + err.Error()
+			}
+		}
+		return cycle
+	}
+
+	if s.opts.BeforeRun != nil {
+		if err := s.opts.BeforeRun(ctx, plan); err != nil {
+			collector.deletePlan(plan.PlanID)
+			cycle.Error = "before run: " + err.Error()
+			return cycle
+		}
+	}
+
+	collector.SetCheckConflicts(s.opts.CheckConflicts)
+	if err := collector.Run(plan.PlanID); err != nil {
+		collector.deletePlan(plan.PlanID)
+		cycle.Error = "run: " + err.Error()
+		auditLogger.Append(model.EventTypeGCRun, "", "", map[string]any{
+			"plan_id": plan.PlanID,
+			"error":   err.Error(),
+			"trigger": "schedule",
+		})
+		return cycle
+	}
+	cycle.Deleted = collector.Deleted()
+	return cycle
+}
+
+func (s *Scheduler) policy() (model.RetentionPolicy, error) {
+	if s.opts.Policy != nil {
+		return s.opts.Policy()
+	}
+	config.InvalidateCache(s.repoRoot)
+	return config.LoadRepoRetentionPolicy(s.repoRoot)
+}
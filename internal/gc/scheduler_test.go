@@ -0,0 +1,143 @@
+package gc_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// createRemovedWorktreeSnapshot snapshots a worktree and removes it, leaving
+// a snapshot that only age and count retention protect.
+func createRemovedWorktreeSnapshot(t *testing.T, repoPath string) model.SnapshotID {
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Create("feature", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(wtMgr.Path("feature"), "file.txt"), []byte("feature"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("feature", "feature snapshot", nil)
+	require.NoError(t, err)
+	require.NoError(t, wtMgr.Remove("feature"))
+	return desc.SnapshotID
+}
+
+func TestScheduler_RunCycle(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+	featureID := createRemovedWorktreeSnapshot(t, repoPath)
+
+	s := gc.NewScheduler(repoPath, gc.SchedulerOptions{
+		Policy: func() (model.RetentionPolicy, error) { return zeroRetention, nil },
+	})
+	cycle := s.RunCycle()
+	assert.Empty(t, cycle.Error)
+	assert.NotEmpty(t, cycle.PlanID)
+	assert.Equal(t, 1, cycle.Planned)
+	assert.Equal(t, []model.SnapshotID{featureID}, cycle.Deleted)
+	_, err := snapshot.LoadDescriptor(repoPath, featureID)
+	assert.Error(t, err)
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{})
+	require.NoError(t, err)
+	var planRecord, runRecord *model.AuditRecord
+	for _, r := range records {
+		switch r.EventType {
+		case model.EventTypeGCPlan:
+			planRecord = r
+		case model.EventTypeGCRun:
+			runRecord = r
+		}
+	}
+	require.NotNil(t, planRecord)
+	require.NotNil(t, runRecord)
+	assert.Equal(t, "schedule", planRecord.Details["trigger"])
+	assert.NotEmpty(t, planRecord.CorrelationID)
+	assert.Equal(t, planRecord.CorrelationID, runRecord.CorrelationID)
+
+	// Nothing left to collect: the cycle plans and stops
+	cycle = s.RunCycle()
+	assert.Empty(t, cycle.Error)
+	assert.Zero(t, cycle.Planned)
+	assert.Empty(t, cycle.Deleted)
+}
+
+func TestScheduler_RunCycle_DefaultPolicyKeepsRecent(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createRemovedWorktreeSnapshot(t, repoPath)
+
+	// The default retention keeps snapshots younger than a day
+	cycle := gc.NewScheduler(repoPath, gc.SchedulerOptions{}).RunCycle()
+	assert.Empty(t, cycle.Error)
+	assert.Zero(t, cycle.Planned)
+}
+
+func TestScheduler_RunCycle_PolicyError(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	cycle := gc.NewScheduler(repoPath, gc.SchedulerOptions{
+		Policy: func() (model.RetentionPolicy, error) { return model.RetentionPolicy{}, errors.New("bad policy") },
+	}).RunCycle()
+	assert.Equal(t, "bad policy", cycle.Error)
+	assert.Empty(t, cycle.PlanID)
+}
+
+func TestScheduler_RunCycle_BeforeRunAborts(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+	featureID := createRemovedWorktreeSnapshot(t, repoPath)
+
+	var planned []model.SnapshotID
+	cycle := gc.NewScheduler(repoPath, gc.SchedulerOptions{
+		Policy: func() (model.RetentionPolicy, error) { return zeroRetention, nil },
+		BeforeRun: func(_ context.Context, plan *model.GCPlan) error {
+			planned = plan.ToDelete
+			return errors.New("maintenance window")
+		},
+	}).RunCycle()
+	assert.Equal(t, "before run: maintenance window", cycle.Error)
+	assert.Equal(t, []model.SnapshotID{featureID}, planned)
+	assert.Empty(t, cycle.Deleted)
+	_, err := snapshot.LoadDescriptor(repoPath, featureID)
+	assert.NoError(t, err)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cycles := make(chan *gc.Cycle, 10)
+	s := gc.NewScheduler(repoPath, gc.SchedulerOptions{
+		Interval: 10 * time.Millisecond,
+		Jitter:   5 * time.Millisecond,
+		Policy:   func() (model.RetentionPolicy, error) { return zeroRetention, nil },
+		OnCycle: func(c *gc.Cycle) {
+			select {
+			case cycles <- c:
+			default:
+			}
+		},
+	})
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	for range 2 {
+		select {
+		case c := <-cycles:
+			assert.Empty(t, c.Error)
+		case <-time.After(10 * time.Second):
+			t.Fatal("no GC cycle ran")
+		}
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,89 @@
+package gc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// PruneTombstones removes the tombstones of snapshots deleted more than
+// olderThan ago, under the exclusive repository lock, and audits them as
+// gc_tombstone_prune. It returns the snapshots whose tombstones were
+// removed.
+func (c *Collector) PruneTombstones(olderThan time.Duration) ([]model.SnapshotID, error) {
+	if olderThan < 0 {
+		return nil, fmt.Errorf("tombstone age must be non-negative")
+	}
+	lock, err := c.locks().Acquire(model.LockExclusive, "gc prune-tombstones")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	pruned, err := c.pruneTombstones(olderThan)
+	if err != nil {
+		return nil, err
+	}
+	if len(pruned) > 0 {
+		c.auditLogger.Append(model.EventTypeGCTombstonePrune, "", "", map[string]any{
+			"older_than": olderThan.String(),
+			"pruned":     len(pruned),
+		})
+	}
+	return pruned, nil
+}
+
+// pruneTombstones removes tombstones older than olderThan. Tombstones that
+// cannot be read, and those of snapshots whose payload or descriptor
+// remains, are kept: they record a deletion that did not finish, which
+// 'jvs fsck' reports.
+func (c *Collector) pruneTombstones(olderThan time.Duration) ([]model.SnapshotID, error) {
+	dir := filepath.Join(c.repoRoot, ".jvs", "gc", "tombstones")
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read tombstones: %w", err)
+	}
+	store, err := descstore.Open(c.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	cutoff := time.Now().Add(-olderThan)
+	var pruned []model.SnapshotID
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var tombstone model.Tombstone
+		if err := json.Unmarshal(data, &tombstone); err != nil || !tombstone.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if _, err := os.Stat(repo.SnapshotPath(c.repoRoot, tombstone.SnapshotID)); err == nil {
+			continue
+		}
+		if exists, err := store.Exists(tombstone.SnapshotID); err != nil || exists {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return pruned, fmt.Errorf("remove tombstone %s: %w", tombstone.SnapshotID, err)
+		}
+		pruned = append(pruned, tombstone.SnapshotID)
+	}
+	return pruned, nil
+}
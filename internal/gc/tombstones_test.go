@@ -0,0 +1,64 @@
+package gc_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func writeTombstone(t *testing.T, repoPath string, id model.SnapshotID, deletedAt time.Time) string {
+	dir := filepath.Join(repoPath, ".jvs", "gc", "tombstones")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	data, err := json.Marshal(model.Tombstone{SnapshotID: id, DeletedAt: deletedAt, Reclaimable: true})
+	require.NoError(t, err)
+	path := filepath.Join(dir, string(id)+".json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestCollector_PruneTombstones(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	liveID := createTestSnapshot(t, repoPath)
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	oldPath := writeTombstone(t, repoPath, "1700000000000-aaaaaaaa", old)
+	recentPath := writeTombstone(t, repoPath, "1700000000000-bbbbbbbb", time.Now())
+	// A deletion that did not finish keeps its tombstone
+	livePath := writeTombstone(t, repoPath, liveID, old)
+
+	pruned, err := gc.NewCollector(repoPath).PruneTombstones(90 * 24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{"1700000000000-aaaaaaaa"}, pruned)
+	assert.NoFileExists(t, oldPath)
+	assert.FileExists(t, recentPath)
+	assert.FileExists(t, livePath)
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeGCTombstonePrune})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.EqualValues(t, 1, records[0].Details["pruned"])
+}
+
+func TestCollector_Run_PrunesTombstones(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+	featureID := createRemovedWorktreeSnapshot(t, repoPath)
+	oldPath := writeTombstone(t, repoPath, "1700000000000-aaaaaaaa", time.Now().Add(-48*time.Hour))
+
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.PlanWithPolicy(model.RetentionPolicy{TombstoneMaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, collector.Run(plan.PlanID))
+
+	// The old tombstone goes, the run's own stays
+	assert.NoFileExists(t, oldPath)
+	assert.FileExists(t, filepath.Join(repoPath, ".jvs", "gc", "tombstones", string(featureID)+".json"))
+}
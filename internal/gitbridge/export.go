@@ -0,0 +1,416 @@
+// Package gitbridge exports the snapshot history of a worktree to a Git
+// repository, so it can be browsed with Git tooling.
+package gitbridge
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+const (
+	// DefaultAuthor is the committer of exported commits unless
+	// Options.Author is set.
+	DefaultAuthor = "jvs <jvs@localhost>"
+
+	// TrailerSnapshot is the commit message trailer naming the snapshot a
+	// commit was exported from. Later exports resume after the branch tip's.
+	TrailerSnapshot = "JVS-Snapshot"
+	// TrailerWorktree is the commit message trailer naming the worktree.
+	TrailerWorktree = "JVS-Worktree"
+
+	lfsSpec = "https://git-lfs.github.com/spec/v1"
+)
+
+// Options configures Export.
+type Options struct {
+	// GitDir is the Git repository to export into. It is created with
+	// 'git init' if it is not a repository yet.
+	GitDir string
+	// Branch receives the commits; empty uses the worktree name.
+	Branch string
+	// Author is the "Name <email>" recorded as author and committer; empty
+	// uses DefaultAuthor.
+	Author string
+	// LFSThreshold stores regular files of at least this many bytes as Git
+	// LFS pointers, with the content in the repository's LFS object store.
+	// Zero or negative stores every file in Git.
+	LFSThreshold int64
+}
+
+// Result describes an export.
+type Result struct {
+	Worktree string `json:"worktree"`
+	GitDir   string `json:"git_dir"`
+	Branch   string `json:"branch"`
+	// Head is the branch's commit after the export.
+	Head string `json:"head,omitempty"`
+	// Exported are the snapshots committed by this run, oldest first.
+	Exported []model.SnapshotID `json:"exported"`
+	// UpToDate counts the snapshots an earlier run already exported.
+	UpToDate int `json:"up_to_date"`
+	// Tags are the Git tags created for snapshot tags.
+	Tags []string `json:"tags"`
+	// LFSObjects counts the files added to the LFS object store.
+	LFSObjects int `json:"lfs_objects"`
+}
+
+// Export replays the lineage of a worktree's latest snapshot as commits on
+// a Git branch, one per snapshot, oldest first. Each commit holds the
+// snapshot's full payload (Git does not keep empty directories); the note
+// becomes the commit message and snapshot tags become Git tags, except
+// where a tag of that name already exists. Export is incremental: if the
+// branch exists, only the snapshots after the one recorded in its tip's
+// JVS-Snapshot trailer are committed, and the tip must be in the lineage.
+// History starts at the oldest ancestor whose descriptor is still readable.
+// Export runs 'git fast-import', so git must be on PATH.
+func Export(repoRoot, worktreeName string, opts Options) (*Result, error) {
+	if opts.GitDir == "" {
+		return nil, fmt.Errorf("git repository path is required")
+	}
+	if opts.Branch == "" {
+		opts.Branch = worktreeName
+	}
+	if opts.Author == "" {
+		opts.Author = DefaultAuthor
+	}
+	if !strings.Contains(opts.Author, "<") || !strings.HasSuffix(opts.Author, ">") {
+		return nil, fmt.Errorf("author must be \"Name <email>\": %q", opts.Author)
+	}
+
+	cfg, err := worktree.NewManager(repoRoot).Get(worktreeName)
+	if err != nil {
+		return nil, fmt.Errorf("get worktree: %w", err)
+	}
+	if cfg.LatestSnapshotID == "" {
+		return nil, fmt.Errorf("worktree %s has no snapshots", worktreeName)
+	}
+	lineage, err := loadLineage(repoRoot, cfg.LatestSnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := openRepo(opts.GitDir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := g.run("check-ref-format", "--branch", opts.Branch); err != nil {
+		return nil, fmt.Errorf("invalid branch name %q", opts.Branch)
+	}
+	result := &Result{
+		Worktree: worktreeName,
+		GitDir:   g.dir,
+		Branch:   opts.Branch,
+		Exported: []model.SnapshotID{},
+		Tags:     []string{},
+	}
+
+	tip, err := g.revParse("refs/heads/" + opts.Branch)
+	if err != nil {
+		return nil, err
+	}
+	if tip != "" {
+		done, err := g.exportedSnapshot(tip)
+		if err != nil {
+			return nil, err
+		}
+		if done == "" {
+			return nil, fmt.Errorf("branch %s was not created by git-export (its tip has no %s trailer)", opts.Branch, TrailerSnapshot)
+		}
+		i := indexOf(lineage, done)
+		if i < 0 {
+			return nil, fmt.Errorf("branch %s is at snapshot %s, which is not in the lineage of worktree %s; export to a new branch", opts.Branch, done, worktreeName)
+		}
+		result.UpToDate = i + 1
+		lineage = lineage[i+1:]
+	}
+	result.Head = tip
+	if len(lineage) == 0 {
+		return result, nil
+	}
+
+	tags, err := g.tags()
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{repoRoot: repoRoot, git: g, opts: opts, worktree: worktreeName, tags: tags, result: result}
+	if err := g.fastImport(func(w *bufio.Writer) error {
+		return e.writeCommits(w, lineage, tip)
+	}); err != nil {
+		return nil, err
+	}
+
+	if result.Head, err = g.revParse("refs/heads/" + opts.Branch); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadLineage returns the snapshot latest and its ancestors, oldest first.
+// The walk stops at the first parent whose descriptor cannot be read, such
+// as one removed by gc.
+func loadLineage(repoRoot string, latest model.SnapshotID) ([]*model.Descriptor, error) {
+	desc, err := snapshot.LoadDescriptor(repoRoot, latest)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", latest, err)
+	}
+	lineage := []*model.Descriptor{desc}
+	seen := map[model.SnapshotID]bool{latest: true}
+	for desc.ParentID != nil && !seen[*desc.ParentID] {
+		seen[*desc.ParentID] = true
+		if desc, err = snapshot.LoadDescriptor(repoRoot, *desc.ParentID); err != nil {
+			break
+		}
+		lineage = append(lineage, desc)
+	}
+	for i, j := 0, len(lineage)-1; i < j; i, j = i+1, j-1 {
+		lineage[i], lineage[j] = lineage[j], lineage[i]
+	}
+	return lineage, nil
+}
+
+func indexOf(lineage []*model.Descriptor, id model.SnapshotID) int {
+	for i, desc := range lineage {
+		if desc.SnapshotID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// exporter writes the fast-import stream for one export.
+type exporter struct {
+	repoRoot string
+	git      *gitRepo
+	opts     Options
+	worktree string
+	tags     map[string]bool
+	result   *Result
+}
+
+// writeCommits writes one commit per snapshot, the first on top of from
+// ("" for a new branch), followed by the tags.
+func (e *exporter) writeCommits(w *bufio.Writer, lineage []*model.Descriptor, from string) error {
+	type tagRef struct {
+		name string
+		mark int
+	}
+	var tags []tagRef
+	for i, desc := range lineage {
+		mark := i + 1
+		if err := e.writeCommit(w, desc, mark, from); err != nil {
+			return err
+		}
+		from = ":" + strconv.Itoa(mark)
+		e.result.Exported = append(e.result.Exported, desc.SnapshotID)
+		for _, tag := range desc.Tags {
+			if !e.tags[tag] {
+				e.tags[tag] = true
+				tags = append(tags, tagRef{tag, mark})
+			}
+		}
+	}
+	for _, t := range tags {
+		if _, err := e.git.run("check-ref-format", "refs/tags/"+t.name); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping tag %q: not a valid Git tag name\n", t.name)
+			continue
+		}
+		fmt.Fprintf(w, "reset refs/tags/%s\nfrom :%d\n\n", t.name, t.mark)
+		e.result.Tags = append(e.result.Tags, t.name)
+	}
+	return nil
+}
+
+func (e *exporter) writeCommit(w *bufio.Writer, desc *model.Descriptor, mark int, from string) error {
+	_, dir, cleanup, err := snapshot.UncompressedPayload(e.repoRoot, desc.SnapshotID, "gitexport")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	message := desc.Note
+	if message == "" {
+		message = "Snapshot " + string(desc.SnapshotID)
+	}
+	message = fmt.Sprintf("%s\n\n%s: %s\n%s: %s\n", strings.TrimRight(message, "\n"), TrailerSnapshot, desc.SnapshotID, TrailerWorktree, e.worktree)
+	when := fmt.Sprintf("%d +0000", desc.CreatedAt.Unix())
+
+	fmt.Fprintf(w, "commit refs/heads/%s\nmark :%d\n", e.opts.Branch, mark)
+	fmt.Fprintf(w, "author %s %s\ncommitter %s %s\n", e.opts.Author, when, e.opts.Author, when)
+	fmt.Fprintf(w, "data %d\n%s\n", len(message), message)
+	if from != "" {
+		fmt.Fprintf(w, "from %s\n", from)
+	}
+	// Every commit holds the full payload, as restoring the snapshot would
+	fmt.Fprint(w, "deleteall\n")
+
+	var lfsPaths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() || isReadyMarker(rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("readlink %s: %w", rel, err)
+			}
+			writeInline(w, "120000", rel, []byte(target))
+		case !info.Mode().IsRegular():
+			return nil
+		case rel == ".gitattributes" && e.opts.LFSThreshold > 0:
+			return nil // rewritten below with the LFS entries
+		case e.opts.LFSThreshold > 0 && info.Size() >= e.opts.LFSThreshold:
+			pointer, err := e.storeLFS(path)
+			if err != nil {
+				return fmt.Errorf("store %s in LFS: %w", rel, err)
+			}
+			writeInline(w, fileMode(info), rel, pointer)
+			lfsPaths = append(lfsPaths, rel)
+		default:
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			fmt.Fprintf(w, "M %s inline %s\ndata %d\n", fileMode(info), quotePath(rel), info.Size())
+			if _, err := io.CopyN(w, f, info.Size()); err != nil {
+				return fmt.Errorf("read %s: %w", rel, err)
+			}
+			w.WriteString("\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("export snapshot %s: %w", desc.SnapshotID, err)
+	}
+
+	if e.opts.LFSThreshold > 0 {
+		attrs, err := lfsAttributes(dir, lfsPaths)
+		if err != nil {
+			return err
+		}
+		if len(attrs) > 0 {
+			writeInline(w, "100644", ".gitattributes", attrs)
+		}
+	}
+	w.WriteString("\n")
+	return w.Flush()
+}
+
+// storeLFS copies the file at path into the LFS object store and returns
+// its pointer file content.
+func (e *exporter) storeLFS(path string) ([]byte, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	tmpDir := filepath.Join(e.git.gitDir, "lfs", "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(tmpDir, "jvs-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	oid := hex.EncodeToString(h.Sum(nil))
+	objPath := filepath.Join(e.git.gitDir, "lfs", "objects", oid[:2], oid[2:4], oid)
+	if _, err := os.Stat(objPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp.Name(), objPath); err != nil {
+			return nil, err
+		}
+		e.result.LFSObjects++
+	}
+	return fmt.Appendf(nil, "version %s\noid sha256:%s\nsize %d\n", lfsSpec, oid, size), nil
+}
+
+// lfsAttributes returns the payload's .gitattributes followed by an LFS
+// entry for each pointer file, or nil if there is neither.
+func lfsAttributes(dir string, lfsPaths []string) ([]byte, error) {
+	attrs, err := os.ReadFile(filepath.Join(dir, ".gitattributes"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read .gitattributes: %w", err)
+	}
+	if len(lfsPaths) == 0 {
+		return attrs, nil
+	}
+	var b bytes.Buffer
+	b.Write(attrs)
+	if len(attrs) > 0 && !bytes.HasSuffix(attrs, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	sort.Strings(lfsPaths)
+	for _, p := range lfsPaths {
+		// Spaces end a pattern; git-lfs writes them as [[:space:]] too
+		fmt.Fprintf(&b, "/%s filter=lfs diff=lfs merge=lfs -text\n", strings.ReplaceAll(p, " ", "[[:space:]]"))
+	}
+	return b.Bytes(), nil
+}
+
+func writeInline(w *bufio.Writer, mode, path string, data []byte) {
+	fmt.Fprintf(w, "M %s inline %s\ndata %d\n", mode, quotePath(path), len(data))
+	w.Write(data)
+	w.WriteString("\n")
+}
+
+func fileMode(info os.FileInfo) string {
+	if info.Mode()&0111 != 0 {
+		return "100755"
+	}
+	return "100644"
+}
+
+// quotePath quotes a path for fast-import if it starts with a quote or
+// contains a newline.
+func quotePath(p string) string {
+	if strings.HasPrefix(p, `"`) || strings.ContainsAny(p, "\n") {
+		return strconv.Quote(p)
+	}
+	return p
+}
+
+// isReadyMarker reports whether a payload path is the snapshot's .READY
+// marker, which compressed snapshots keep as .READY.gz or .READY.zst.
+func isReadyMarker(rel string) bool {
+	return rel == ".READY" || rel == ".READY.gz" || rel == ".READY.zst"
+}
@@ -0,0 +1,137 @@
+package gitbridge_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/gitbridge"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/restore"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupRepo(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	return dir, filepath.Join(dir, "main")
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+	return strings.TrimSpace(string(out))
+}
+
+func TestExport(t *testing.T) {
+	repoPath, mainPath := setupRepo(t)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "README.md"), []byte("v1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.Symlink("README.md", filepath.Join(mainPath, "link")))
+	first, err := creator.Create("main", "first", []string{"v1"})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "README.md"), []byte("v2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "weights bin"), []byte(strings.Repeat("w", 100)), 0644))
+	require.NoError(t, os.Remove(filepath.Join(mainPath, "run.sh")))
+	second, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+
+	gitDir := filepath.Join(t.TempDir(), "export")
+	result, err := gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir, LFSThreshold: 50})
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{first.SnapshotID, second.SnapshotID}, result.Exported)
+	assert.Equal(t, []string{"v1"}, result.Tags)
+	assert.Equal(t, 1, result.LFSObjects)
+	assert.Equal(t, git(t, gitDir, "rev-parse", "main"), result.Head)
+
+	assert.Equal(t, "first\n\nJVS-Snapshot: "+string(first.SnapshotID)+"\nJVS-Worktree: main", git(t, gitDir, "log", "-1", "--format=%B", "v1"))
+	assert.Equal(t, "Snapshot "+string(second.SnapshotID), git(t, gitDir, "log", "-1", "--format=%s", "main"))
+	assert.Equal(t, "v1", git(t, gitDir, "show", "v1:README.md"))
+	assert.Equal(t, "v2", git(t, gitDir, "show", "main:README.md"))
+	assert.Contains(t, git(t, gitDir, "ls-tree", "v1"), "100755 blob")
+	assert.Contains(t, git(t, gitDir, "ls-tree", "v1", "link"), "120000 blob")
+	assert.NotContains(t, git(t, gitDir, "ls-tree", "main"), "run.sh")
+	assert.Equal(t, strconv.FormatInt(first.CreatedAt.Unix(), 10), git(t, gitDir, "log", "-1", "--format=%ct", "v1"))
+
+	pointer := git(t, gitDir, "show", "main:weights bin")
+	assert.Contains(t, pointer, "version https://git-lfs.github.com/spec/v1")
+	assert.Contains(t, pointer, "size 100")
+	assert.Equal(t, "/weights[[:space:]]bin filter=lfs diff=lfs merge=lfs -text", git(t, gitDir, "show", "main:.gitattributes"))
+	oid := strings.TrimPrefix(strings.Split(pointer, "\n")[1], "oid sha256:")
+	assert.FileExists(t, filepath.Join(gitDir, ".git", "lfs", "objects", oid[:2], oid[2:4], oid))
+
+	// A second run exports only the new snapshot
+	result, err = gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir})
+	require.NoError(t, err)
+	assert.Empty(t, result.Exported)
+	assert.Equal(t, 2, result.UpToDate)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "README.md"), []byte("v3\n"), 0644))
+	third, err := creator.Create("main", "third", nil)
+	require.NoError(t, err)
+	result, err = gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir})
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{third.SnapshotID}, result.Exported)
+	assert.Equal(t, "3", git(t, gitDir, "rev-list", "--count", "main"))
+	assert.Equal(t, "v3", git(t, gitDir, "show", "main:README.md"))
+}
+
+func TestExport_CompressedSnapshot(t *testing.T) {
+	repoPath, mainPath := setupRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data.txt"), []byte("compressed"), 0644))
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompressor(compression.NewCompressorWithType(compression.TypeZstd, compression.LevelFast))
+	_, err := creator.Create("main", "zstd", nil)
+	require.NoError(t, err)
+
+	gitDir := filepath.Join(t.TempDir(), "export")
+	_, err = gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir, Branch: "history"})
+	require.NoError(t, err)
+	assert.Equal(t, "compressed", git(t, gitDir, "show", "history:data.txt"))
+	assert.Equal(t, "data.txt", git(t, gitDir, "ls-tree", "--name-only", "history"))
+}
+
+func TestExport_Diverged(t *testing.T) {
+	repoPath, mainPath := setupRepo(t)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("a"), 0644))
+	first, err := creator.Create("main", "first", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("b"), 0644))
+	_, err = creator.Create("main", "second", nil)
+	require.NoError(t, err)
+
+	gitDir := filepath.Join(t.TempDir(), "export")
+	_, err = gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir})
+	require.NoError(t, err)
+
+	// Branch off the first snapshot; the exported tip is no longer in the
+	// worktree's lineage
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", first.SnapshotID))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("c"), 0644))
+	_, err = creator.Create("main", "third", nil)
+	require.NoError(t, err)
+	_, err = gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir})
+	assert.ErrorContains(t, err, "not in the lineage")
+
+	result, err := gitbridge.Export(repoPath, "main", gitbridge.Options{GitDir: gitDir, Branch: "main-2"})
+	require.NoError(t, err)
+	assert.Len(t, result.Exported, 2)
+}
@@ -0,0 +1,134 @@
+package gitbridge
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// gitRepo runs git commands against one repository.
+type gitRepo struct {
+	dir    string // working tree, or the repository itself if bare
+	gitDir string // absolute path of the .git directory
+}
+
+// openRepo opens the Git repository at dir, creating it first if dir is
+// not inside one.
+func openRepo(dir string) (*gitRepo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve git repository path: %w", err)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found on PATH: %w", err)
+	}
+	g := &gitRepo{dir: abs}
+	if _, err := os.Stat(abs); os.IsNotExist(err) {
+		if err := os.MkdirAll(abs, 0755); err != nil {
+			return nil, fmt.Errorf("create git repository: %w", err)
+		}
+	}
+	gitDir, err := g.run("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		if _, err := g.run("init", "--quiet"); err != nil {
+			return nil, err
+		}
+		if gitDir, err = g.run("rev-parse", "--absolute-git-dir"); err != nil {
+			return nil, err
+		}
+	}
+	g.gitDir = gitDir
+	return g, nil
+}
+
+// run runs git with args and returns its trimmed standard output.
+func (g *gitRepo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// revParse returns the commit ref points to, or "" if it does not exist.
+func (g *gitRepo) revParse(ref string) (string, error) {
+	cmd := exec.Command("git", "-C", g.dir, "rev-parse", "--quiet", "--verify", ref+"^{commit}")
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git rev-parse %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// exportedSnapshot returns the snapshot recorded in commit's JVS-Snapshot
+// trailer, or "" if it has none.
+func (g *gitRepo) exportedSnapshot(commit string) (model.SnapshotID, error) {
+	message, err := g.run("log", "-1", "--format=%B", commit)
+	if err != nil {
+		return "", err
+	}
+	var id model.SnapshotID
+	for _, line := range strings.Split(message, "\n") {
+		if v, ok := strings.CutPrefix(line, TrailerSnapshot+": "); ok {
+			id = model.SnapshotID(strings.TrimSpace(v))
+		}
+	}
+	return id, nil
+}
+
+// tags returns the names of the repository's tags.
+func (g *gitRepo) tags() (map[string]bool, error) {
+	out, err := g.run("for-each-ref", "--format=%(refname:strip=2)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]bool)
+	for _, name := range strings.Fields(out) {
+		tags[name] = true
+	}
+	return tags, nil
+}
+
+// fastImport runs 'git fast-import' with the stream written by write.
+func (g *gitRepo) fastImport(write func(w *bufio.Writer) error) error {
+	cmd := exec.Command("git", "-C", g.dir, "fast-import", "--quiet", "--done")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("git fast-import: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git fast-import: %w", err)
+	}
+
+	w := bufio.NewWriterSize(stdin, 1<<16)
+	// With --done, a stream cut short by a failed write makes fast-import
+	// exit without updating the branch or tags
+	writeErr := write(w)
+	if writeErr == nil {
+		w.WriteString("done\n")
+		writeErr = w.Flush()
+	}
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if writeErr != nil {
+		return writeErr
+	}
+	if waitErr != nil {
+		return fmt.Errorf("git fast-import: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
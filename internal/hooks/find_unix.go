@@ -0,0 +1,24 @@
+//go:build !windows
+
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// find returns the script of hook name in dir. A script that is not
+// executable is ignored with a warning, as git does.
+func find(dir, name string) (string, bool) {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		fmt.Fprintf(os.Stderr, "warning: %s hook ignored because %s is not executable\n", name, path)
+		return "", false
+	}
+	return path, true
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// windowsExts are the extensions a hook script may have on Windows, which
+// has no executable bit.
+var windowsExts = []string{".exe", ".bat", ".cmd"}
+
+// find returns the script of hook name in dir: name with one of
+// windowsExts.
+func find(dir, name string) (string, bool) {
+	for _, ext := range windowsExts {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,107 @@
+// Package hooks runs the executable hook scripts of a repository, in
+// .jvs/hooks/, around snapshots, restores and GC.
+//
+// A pre hook runs before the operation takes any lock, so it may itself
+// run jvs, e.g. to check status; exiting non-zero aborts the operation
+// with errclass.ErrHookFailed. A post hook runs after a successful
+// operation has released its locks; its failure is only reported. Hooks
+// run in the repository root with stdout and stderr sent to jvs's stderr,
+// and are told about the operation by JVS_* environment variables.
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+// Hook names, which are also the names of their scripts.
+const (
+	PreSnapshot  = "pre-snapshot"
+	PostSnapshot = "post-snapshot"
+	PreRestore   = "pre-restore"
+	PostRestore  = "post-restore"
+	PostGC       = "post-gc"
+)
+
+// Environment variables describing the operation to a hook. Variables that
+// do not apply to an operation are not set.
+const (
+	EnvHook         = "JVS_HOOK"          // name of the running hook
+	EnvRepo         = "JVS_REPO"          // repository root, as jvs commands read it
+	EnvOperation    = "JVS_OPERATION"     // snapshot, restore or gc
+	EnvWorktree     = "JVS_WORKTREE"      // worktree name
+	EnvWorktreePath = "JVS_WORKTREE_PATH" // worktree payload directory
+	EnvSnapshotID   = "JVS_SNAPSHOT_ID"   // snapshot taken, or restored
+	EnvNote         = "JVS_NOTE"          // snapshot note
+	EnvTags         = "JVS_TAGS"          // snapshot tags, comma-separated
+	EnvPaths        = "JVS_PATHS"         // paths of a partial snapshot or restore, comma-separated
+	EnvSkipped      = "JVS_SKIPPED"       // "true" if the snapshot was skipped as unchanged
+	EnvGCPlanID     = "JVS_GC_PLAN_ID"    // GC plan run
+	EnvGCDeleted    = "JVS_GC_DELETED"    // number of snapshots GC deleted
+)
+
+// DirName is the hooks directory, under .jvs/.
+const DirName = "hooks"
+
+// Dir returns the hooks directory of a repository.
+func Dir(repoRoot string) string {
+	return filepath.Join(repoRoot, repo.JVSDirName, DirName)
+}
+
+// Run runs the hook name of the repository, if it has one, with vars added
+// to its environment. A hook that exits non-zero or cannot be started
+// fails with errclass.ErrHookFailed. Done ctx kills a running hook, and the
+// author and correlation ID ctx carries (see audit.WithAuthor and
+// audit.WithCorrelationID) are passed on.
+func Run(ctx context.Context, repoRoot, name string, vars map[string]string) error {
+	path, ok := find(Dir(repoRoot), name)
+	if !ok {
+		return nil
+	}
+	env := append(os.Environ(),
+		EnvHook+"="+name,
+		EnvRepo+"="+repoRoot,
+		// jvs commands run by the hook belong to the operation
+		identity.EnvVar+"="+audit.ResolveAuthor(audit.Author(ctx)),
+	)
+	if id := audit.ResolveCorrelationID(audit.CorrelationID(ctx)); id != "" {
+		env = append(env, audit.CorrelationEnvVar+"="+id)
+	}
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		env = append(env, k+"="+vars[k])
+	}
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = repoRoot
+	cmd.Env = env
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errclass.ErrHookFailed.WithMessagef("%s hook: %v", name, err)
+	}
+	return nil
+}
+
+// RunPost runs a post hook like Run, printing a warning if it fails.
+func RunPost(ctx context.Context, repoRoot, name string, vars map[string]string) {
+	if err := Run(ctx, repoRoot, name, vars); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// List returns a comma-separated list for the JVS_TAGS and JVS_PATHS
+// variables.
+func List(values []string) string {
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,71 @@
+package hooks_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/hooks"
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+func writeHook(t *testing.T, repoRoot, name, script string, mode os.FileMode) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need an executable bit and sh")
+	}
+	require.NoError(t, os.MkdirAll(hooks.Dir(repoRoot), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(hooks.Dir(repoRoot), name), []byte("#!/bin/sh\n"+script), mode))
+}
+
+func TestRun_Environment(t *testing.T) {
+	repoRoot := t.TempDir()
+	t.Setenv(identity.EnvVar, "sandbox-7")
+	writeHook(t, repoRoot, hooks.PreSnapshot, `env | grep ^JVS_ | sort > "$JVS_REPO/env.txt"; pwd >> env.txt`, 0755)
+
+	err := hooks.Run(context.Background(), repoRoot, hooks.PreSnapshot, map[string]string{
+		hooks.EnvOperation: "snapshot",
+		hooks.EnvWorktree:  "main",
+		hooks.EnvTags:      hooks.List([]string{"a", "b"}),
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(repoRoot, "env.txt"))
+	require.NoError(t, err)
+	env := string(data)
+	assert.Contains(t, env, "JVS_HOOK=pre-snapshot\n")
+	assert.Contains(t, env, "JVS_REPO="+repoRoot+"\n")
+	assert.Contains(t, env, "JVS_OPERATION=snapshot\n")
+	assert.Contains(t, env, "JVS_WORKTREE=main\n")
+	assert.Contains(t, env, "JVS_TAGS=a,b\n")
+	assert.Contains(t, env, "JVS_AUTHOR=sandbox-7\n")
+	root, err := filepath.EvalSymlinks(repoRoot)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(env), root), "runs in the repository root")
+}
+
+func TestRun_Failure(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeHook(t, repoRoot, hooks.PreRestore, "echo refusing >&2; exit 3\n", 0755)
+
+	err := hooks.Run(context.Background(), repoRoot, hooks.PreRestore, nil)
+	assert.ErrorIs(t, err, errclass.ErrHookFailed)
+	assert.ErrorContains(t, err, "pre-restore hook: exit status 3")
+}
+
+func TestRun_MissingOrNotExecutable(t *testing.T) {
+	repoRoot := t.TempDir()
+	assert.NoError(t, hooks.Run(context.Background(), repoRoot, hooks.PostGC, nil))
+
+	// Only executable scripts run
+
+	writeHook(t, repoRoot, hooks.PostGC, "exit 1\n", 0644)
+	assert.NoError(t, hooks.Run(context.Background(), repoRoot, hooks.PostGC, nil))
+}
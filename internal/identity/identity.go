@@ -0,0 +1,45 @@
+// Package identity names who is running jvs, for the author recorded on
+// snapshots and audit records.
+package identity
+
+import (
+	"os"
+	"os/user"
+	"strings"
+)
+
+// EnvVar overrides the author recorded on snapshots and audit records,
+// e.g. with a pod or agent name in a repository shared between sandboxes.
+const EnvVar = "JVS_AUTHOR"
+
+// Default identifies the local user as user@host. In a container the host
+// name is usually the pod name.
+func Default() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		return name
+	}
+	return name + "@" + host
+}
+
+// Author returns who new snapshots and audit records name as their author:
+// $JVS_AUTHOR if set, Default otherwise.
+func Author() string {
+	if author := strings.TrimSpace(os.Getenv(EnvVar)); author != "" {
+		return author
+	}
+	return Default()
+}
+
+// Matches reports whether author is selected by filter: the same identity,
+// or, for a filter without a host, the same user on any host.
+func Matches(author, filter string) bool {
+	if author == filter {
+		return true
+	}
+	return !strings.Contains(filter, "@") && strings.HasPrefix(author, filter+"@")
+}
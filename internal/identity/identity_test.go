@@ -0,0 +1,27 @@
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jvs-project/jvs/internal/identity"
+)
+
+func TestAuthor_EnvOverride(t *testing.T) {
+	t.Setenv(identity.EnvVar, "")
+	assert.Equal(t, identity.Default(), identity.Author())
+
+	t.Setenv(identity.EnvVar, " sandbox-7 ")
+	assert.Equal(t, "sandbox-7", identity.Author())
+}
+
+func TestMatches(t *testing.T) {
+	assert.True(t, identity.Matches("alice@build-1", "alice@build-1"))
+	assert.True(t, identity.Matches("alice@build-1", "alice"))
+	assert.True(t, identity.Matches("sandbox-7", "sandbox-7"))
+	assert.False(t, identity.Matches("alice@build-1", "alice@build-2"))
+	assert.False(t, identity.Matches("alicia@build-1", "alice"))
+	assert.False(t, identity.Matches("bob@alice", "alice"))
+	assert.False(t, identity.Matches("", "alice"))
+}
@@ -0,0 +1,60 @@
+package index
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// List returns the indexed descriptors of a worktree, or of every
+// worktree if worktreeName is empty, ordered by model.CompareNewestFirst.
+func (idx *Index) List(worktreeName string) ([]*model.Descriptor, error) {
+	if worktreeName == "" {
+		return idx.query(`SELECT data FROM catalog ORDER BY created_at DESC, snapshot_id DESC`)
+	}
+	return idx.query(`SELECT data FROM catalog WHERE worktree_name = ?
+		ORDER BY created_at DESC, snapshot_id DESC`, worktreeName)
+}
+
+// Tagged returns the indexed descriptors carrying tag, of a worktree or of
+// every worktree if worktreeName is empty, ordered by
+// model.CompareNewestFirst.
+func (idx *Index) Tagged(tag, worktreeName string) ([]*model.Descriptor, error) {
+	return idx.query(`SELECT c.data FROM catalog_tags t JOIN catalog c ON c.snapshot_id = t.snapshot_id
+		WHERE t.tag = ? AND (? = '' OR c.worktree_name = ?)
+		ORDER BY t.created_at DESC, t.snapshot_id DESC`, tag, worktreeName, worktreeName)
+}
+
+func (idx *Index) query(query string, args ...any) ([]*model.Descriptor, error) {
+	rows, err := idx.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+	defer rows.Close()
+	var descs []*model.Descriptor
+	for rows.Next() {
+		desc, err := scanDescriptor(rows)
+		if err != nil {
+			return nil, err
+		}
+		descs = append(descs, desc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+	return descs, nil
+}
+
+func scanDescriptor(rows *sql.Rows) (*model.Descriptor, error) {
+	var data []byte
+	if err := rows.Scan(&data); err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+	var desc model.Descriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("read index: parse descriptor: %w", err)
+	}
+	return &desc, nil
+}
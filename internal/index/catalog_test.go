@@ -0,0 +1,64 @@
+package index_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func ids(descs []*model.Descriptor) []model.SnapshotID {
+	out := make([]model.SnapshotID, len(descs))
+	for i, d := range descs {
+		out[i] = d.SnapshotID
+	}
+	return out
+}
+
+func TestCatalog(t *testing.T) {
+	repoRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, ".jvs"), 0755))
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := testDescriptor("1000-aaaaaaaa", "a", []string{"nightly"}, base)
+	b := testDescriptor("2000-bbbbbbbb", "b", []string{"nightly", "v1"}, base.Add(time.Hour))
+	c := testDescriptor("3000-cccccccc", "c", []string{"nightly"}, base.Add(2*time.Hour))
+	c.WorktreeName = "exp"
+	require.NoError(t, index.Build(repoRoot, []*model.Descriptor{a, b}))
+	require.NoError(t, index.Update(repoRoot, c))
+
+	idx, err := index.Open(repoRoot)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	all, err := idx.List("")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{c.SnapshotID, b.SnapshotID, a.SnapshotID}, ids(all))
+	assert.Equal(t, "b", all[1].Note)
+	main, err := idx.List("main")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{b.SnapshotID, a.SnapshotID}, ids(main))
+
+	tagged, err := idx.Tagged("nightly", "")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{c.SnapshotID, b.SnapshotID, a.SnapshotID}, ids(tagged))
+	tagged, err = idx.Tagged("nightly", "main")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{b.SnapshotID, a.SnapshotID}, ids(tagged))
+
+	// Amending tags moves the snapshot between tag lists
+	b.Tags = []string{"v2"}
+	require.NoError(t, idx.Put(b))
+	require.NoError(t, idx.Delete(a.SnapshotID))
+	tagged, err = idx.Tagged("nightly", "")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{c.SnapshotID}, ids(tagged))
+	tagged, err = idx.Tagged("v2", "")
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{b.SnapshotID}, ids(tagged))
+}
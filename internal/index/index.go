@@ -0,0 +1,262 @@
+// Package index maintains .jvs/index.sqlite, the repository's catalog of
+// snapshot descriptors and full-text index over their notes, tags and
+// metadata.
+//
+// The index is a cache: it can be deleted at any time and rebuilt from the
+// descriptor store. Writers only update an index that already exists, so a
+// missing index is never mistaken for a complete one; Build creates it
+// from every descriptor at once. An index written by an older version of
+// jvs is treated as missing.
+package index
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// File is the index database, under .jvs/.
+const File = "index.sqlite"
+
+// version is recorded as the database's user_version by Build. It changes
+// whenever the schema does, so that older indexes are rebuilt.
+const version = 2
+
+// The catalog table holds every READY snapshot's descriptor, with the
+// columns queries select on extracted and indexed. The search table is an
+// FTS5 table: its indexed columns are tokenized for matching, the
+// UNINDEXED ones are only stored.
+const schema = `
+CREATE TABLE IF NOT EXISTS catalog (
+	snapshot_id   TEXT PRIMARY KEY,
+	worktree_name TEXT NOT NULL,
+	created_at    INTEGER NOT NULL,
+	data          BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS catalog_created_at ON catalog(created_at);
+CREATE INDEX IF NOT EXISTS catalog_worktree ON catalog(worktree_name, created_at);
+CREATE TABLE IF NOT EXISTS catalog_tags (
+	tag         TEXT NOT NULL,
+	snapshot_id TEXT NOT NULL,
+	created_at  INTEGER NOT NULL,
+	PRIMARY KEY (tag, snapshot_id)
+);
+CREATE INDEX IF NOT EXISTS catalog_tags_created_at ON catalog_tags(tag, created_at);
+CREATE VIRTUAL TABLE IF NOT EXISTS search USING fts5(
+	snapshot_id UNINDEXED,
+	created_at UNINDEXED,
+	worktree,
+	note,
+	tags,
+	metadata
+);
+`
+
+// ErrNotBuilt is returned by Open when the repository has no index, or one
+// written by an older version of jvs, which Build must replace.
+var ErrNotBuilt = errors.New("index not built")
+
+// Path returns the index database of a repository.
+func Path(repoRoot string) string {
+	return filepath.Join(repoRoot, repo.JVSDirName, File)
+}
+
+// Index is an open index.
+type Index struct {
+	db *sql.DB
+}
+
+// Open opens the index of a repository, or returns ErrNotBuilt.
+func Open(repoRoot string) (*Index, error) {
+	path := Path(repoRoot)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotBuilt
+		}
+		return nil, fmt.Errorf("open index: %w", err)
+	}
+	idx, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	var v int
+	if err := idx.db.QueryRow(`PRAGMA user_version`).Scan(&v); err != nil {
+		idx.Close()
+		return nil, fmt.Errorf("open index: %w", err)
+	}
+	if v != version {
+		idx.Close()
+		return nil, ErrNotBuilt
+	}
+	return idx, nil
+}
+
+func open(path string) (*Index, error) {
+	// Rollback journal rather than WAL, as for the SQLite descriptor store:
+	// WAL needs shared memory, which is unreliable on network filesystems.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(DELETE)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open index: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	return &Index{db: db}, nil
+}
+
+// Build replaces the index of a repository with one of descs. The index
+// is written to a temporary file first, so readers see either the old
+// index or the complete new one.
+func Build(repoRoot string, descs []*model.Descriptor) error {
+	tmp, err := os.CreateTemp(filepath.Join(repoRoot, repo.JVSDirName), ".jvs-tmp-index-*")
+	if err != nil {
+		return fmt.Errorf("create index: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	idx, err := open(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := idx.build(descs); err != nil {
+		idx.Close()
+		return err
+	}
+	if err := idx.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, Path(repoRoot)); err != nil {
+		return fmt.Errorf("install index: %w", err)
+	}
+	return nil
+}
+
+func (idx *Index) build(descs []*model.Descriptor) error {
+	if _, err := idx.db.Exec(schema + fmt.Sprintf("PRAGMA user_version = %d;", version)); err != nil {
+		return fmt.Errorf("initialize index: %w", err)
+	}
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return fmt.Errorf("build index: %w", err)
+	}
+	defer tx.Rollback()
+	for _, desc := range descs {
+		if err := insert(tx, desc); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("build index: %w", err)
+	}
+	return nil
+}
+
+// Put indexes desc, replacing any previous entry of the snapshot.
+func (idx *Index) Put(desc *model.Descriptor) error {
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return fmt.Errorf("update index: %w", err)
+	}
+	defer tx.Rollback()
+	if err := remove(tx, desc.SnapshotID); err != nil {
+		return err
+	}
+	if err := insert(tx, desc); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("update index: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a snapshot from the index.
+func (idx *Index) Delete(id model.SnapshotID) error {
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return fmt.Errorf("update index: %w", err)
+	}
+	defer tx.Rollback()
+	if err := remove(tx, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("update index: %w", err)
+	}
+	return nil
+}
+
+// Close closes the index.
+func (idx *Index) Close() error {
+	return idx.db.Close()
+}
+
+// Update indexes a new or amended descriptor if the repository has an
+// index, and does nothing otherwise.
+func Update(repoRoot string, desc *model.Descriptor) error {
+	idx, err := Open(repoRoot)
+	if err != nil {
+		if errors.Is(err, ErrNotBuilt) {
+			return nil
+		}
+		return err
+	}
+	defer idx.Close()
+	return idx.Put(desc)
+}
+
+// Remove removes a deleted snapshot from the index if the repository has
+// one.
+func Remove(repoRoot string, id model.SnapshotID) error {
+	idx, err := Open(repoRoot)
+	if err != nil {
+		if errors.Is(err, ErrNotBuilt) {
+			return nil
+		}
+		return err
+	}
+	defer idx.Close()
+	return idx.Delete(id)
+}
+
+func insert(tx *sql.Tx, desc *model.Descriptor) error {
+	data, err := json.Marshal(desc)
+	if err != nil {
+		return fmt.Errorf("index snapshot %s: %w", desc.SnapshotID, err)
+	}
+	id, created := string(desc.SnapshotID), desc.CreatedAt.UnixNano()
+	if _, err := tx.Exec(`INSERT INTO catalog (snapshot_id, worktree_name, created_at, data) VALUES (?, ?, ?, ?)`,
+		id, desc.WorktreeName, created, data); err != nil {
+		return fmt.Errorf("index snapshot %s: %w", desc.SnapshotID, err)
+	}
+	for _, tag := range desc.Tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO catalog_tags (tag, snapshot_id, created_at) VALUES (?, ?, ?)`,
+			tag, id, created); err != nil {
+			return fmt.Errorf("index snapshot %s: %w", desc.SnapshotID, err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO search (snapshot_id, created_at, worktree, note, tags, metadata) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, created, desc.WorktreeName, desc.Note, strings.Join(desc.Tags, " "), metadataText(desc)); err != nil {
+		return fmt.Errorf("index snapshot %s: %w", desc.SnapshotID, err)
+	}
+	return nil
+}
+
+func remove(tx *sql.Tx, id model.SnapshotID) error {
+	for _, table := range []string{"catalog", "catalog_tags", "search"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE snapshot_id = ?`, string(id)); err != nil {
+			return fmt.Errorf("update index: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package index
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Search returns the IDs of the snapshots matching every word of query,
+// best match first and newest first among equal matches. Words match
+// whole tokens, case-insensitively; a word ending in * matches tokens
+// starting with it. limit caps the results; zero means no limit.
+func (idx *Index) Search(query string, limit int) ([]model.SnapshotID, error) {
+	match := matchExpr(query)
+	if match == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := idx.db.Query(`SELECT snapshot_id FROM search WHERE search MATCH ?
+		ORDER BY rank, created_at DESC, snapshot_id DESC LIMIT ?`, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search index: %w", err)
+	}
+	defer rows.Close()
+	var ids []model.SnapshotID
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("search index: %w", err)
+		}
+		ids = append(ids, model.SnapshotID(id))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search index: %w", err)
+	}
+	return ids, nil
+}
+
+// metadataText is the searchable text of a descriptor's annotations and
+// recorded environment.
+func metadataText(desc *model.Descriptor) string {
+	var words []string
+	for _, k := range slices.Sorted(maps.Keys(desc.Annotations)) {
+		words = append(words, k, desc.Annotations[k])
+	}
+	if env := desc.Environment; env != nil {
+		words = append(words, env.Hostname, env.ImageDigest)
+		for _, k := range slices.Sorted(maps.Keys(env.Vars)) {
+			words = append(words, k, env.Vars[k])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// matchExpr turns a query into an FTS5 expression matching all its words.
+// Each word is quoted, so characters with a meaning in FTS5 syntax are
+// searched for literally.
+func matchExpr(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.TrimRight(word, "*")
+		if word == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " AND ")
+}
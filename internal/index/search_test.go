@@ -0,0 +1,74 @@
+package index_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func testDescriptor(id, note string, tags []string, created time.Time) *model.Descriptor {
+	return &model.Descriptor{
+		SnapshotID:   model.SnapshotID(id),
+		WorktreeName: "main",
+		CreatedAt:    created,
+		Note:         note,
+		Tags:         tags,
+	}
+}
+
+func TestSearch(t *testing.T) {
+	repoRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, ".jvs"), 0755))
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	older := testDescriptor("1000-aaaaaaaa", "OOM crash in trainer", []string{"nightly"}, base)
+	newer := testDescriptor("2000-bbbbbbbb", "second oom crash", []string{"v2.1"}, base.Add(time.Hour))
+	other := testDescriptor("3000-cccccccc", "clean run", nil, base.Add(2*time.Hour))
+	other.Annotations = map[string]string{"model": "llama-oom"}
+
+	// Writers leave a missing index alone
+	require.NoError(t, index.Update(repoRoot, older))
+	_, err := index.Open(repoRoot)
+	assert.ErrorIs(t, err, index.ErrNotBuilt)
+
+	require.NoError(t, index.Build(repoRoot, []*model.Descriptor{older, newer}))
+	require.NoError(t, index.Update(repoRoot, other))
+
+	idx, err := index.Open(repoRoot)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	ids, err := idx.Search("oom crash", 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []model.SnapshotID{newer.SnapshotID, older.SnapshotID}, ids)
+
+	ids, err = idx.Search("v2.1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{newer.SnapshotID}, ids)
+
+	ids, err = idx.Search("llama*", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{other.SnapshotID}, ids, "annotations are indexed")
+
+	// Query syntax is searched for literally
+	ids, err = idx.Search(`"OR (`, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// Amended notes replace the old entry
+	newer.Note = "fixed"
+	require.NoError(t, index.Update(repoRoot, newer))
+	require.NoError(t, index.Remove(repoRoot, older.SnapshotID))
+	ids, err = idx.Search("crash", 0)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	ids, err = idx.Search("fixed", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{newer.SnapshotID}, ids)
+}
@@ -11,22 +11,34 @@ import (
 )
 
 // ComputeDescriptorChecksum computes SHA-256 checksum of the descriptor.
-// Excludes: descriptor_checksum, integrity_state (per spec 04)
+// Excludes: descriptor_checksum, integrity_state (per spec 04), stats
 // Includes all other fields to ensure tamper detection.
 func ComputeDescriptorChecksum(desc *model.Descriptor) (model.HashValue, error) {
 	checksumDesc := &model.Descriptor{
-		SnapshotID:      desc.SnapshotID,
-		ParentID:        desc.ParentID,
-		WorktreeName:    desc.WorktreeName,
-		CreatedAt:       desc.CreatedAt,
-		Note:            desc.Note,
-		Tags:            desc.Tags,
-		Engine:          desc.Engine,
-		PayloadRootHash: desc.PayloadRootHash,
-		PartialPaths:    desc.PartialPaths,
-		Compression:     desc.Compression,
+		SnapshotID:        desc.SnapshotID,
+		ParentID:          desc.ParentID,
+		WorktreeName:      desc.WorktreeName,
+		CreatedAt:         desc.CreatedAt,
+		Author:            desc.Author,
+		Note:              desc.Note,
+		Tags:              desc.Tags,
+		Engine:            desc.Engine,
+		PayloadRootHash:   desc.PayloadRootHash,
+		HashAlgorithm:     desc.HashAlgorithm,
+		ManifestHash:      desc.ManifestHash,
+		PartialPaths:      desc.PartialPaths,
+		Compression:       desc.Compression,
+		Encryption:        desc.Encryption,
+		ContentStore:      desc.ContentStore,
+		Incremental:       desc.Incremental,
+		DereferencedPaths: desc.DereferencedPaths,
+		Annotations:       desc.Annotations,
+		ReadErrors:        desc.ReadErrors,
+		Environment:       desc.Environment,
 		// DescriptorChecksum: excluded
 		// IntegrityState: excluded
+		// Stats: excluded (diagnostic only)
+		// Skipped: excluded (never persisted)
 	}
 
 	data, err := jsonutil.CanonicalMarshal(checksumDesc)
@@ -0,0 +1,42 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	"lukechampine.com/blake3"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// HashAlgorithms lists the supported payload hash algorithms.
+var HashAlgorithms = []model.HashAlgorithm{model.HashSHA256, model.HashBLAKE3}
+
+// CheckHashAlgorithm fails with errclass.ErrFormatUnsupported unless alg is
+// a supported payload hash algorithm. Empty means SHA-256.
+func CheckHashAlgorithm(alg model.HashAlgorithm) error {
+	switch alg {
+	case "", model.HashSHA256, model.HashBLAKE3:
+		return nil
+	}
+	return errclass.ErrFormatUnsupported.WithMessagef("unsupported hash algorithm %q (must be sha256 or blake3)", alg)
+}
+
+// NewHash returns a hash of algorithm alg with a 32-byte digest. Empty and
+// unsupported algorithms hash with SHA-256; callers check descriptors with
+// CheckHashAlgorithm first.
+func NewHash(alg model.HashAlgorithm) hash.Hash {
+	if alg == model.HashBLAKE3 {
+		return blake3.New(32, nil)
+	}
+	return sha256.New()
+}
+
+// SumBytes returns the hex digest of data under algorithm alg.
+func SumBytes(alg model.HashAlgorithm, data []byte) model.HashValue {
+	h := NewHash(alg)
+	h.Write(data)
+	return model.HashValue(hex.EncodeToString(h.Sum(nil)))
+}
@@ -1,28 +1,58 @@
 package integrity
 
 import (
-	"crypto/sha256"
+	"context"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
+	"github.com/jvs-project/jvs/pkg/fsutil"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
-// ComputePayloadRootHash computes a deterministic hash of the entire payload tree.
+// ComputePayloadRootHash computes a deterministic hash of the entire payload
+// tree with algorithm alg (empty for SHA-256).
 // Algorithm: walk in byte-order sorted path order, compute per-entry hash,
 // concatenate all lines, hash the result.
-func ComputePayloadRootHash(root string) (model.HashValue, error) {
-	var lines []string
+func ComputePayloadRootHash(root string, alg model.HashAlgorithm) (model.HashValue, error) {
+	return ComputePayloadRootHashContext(context.Background(), root, alg)
+}
+
+// ComputePayloadRootHashContext is ComputePayloadRootHash stopping with
+// ctx's error once ctx is done.
+func ComputePayloadRootHashContext(ctx context.Context, root string, alg model.HashAlgorithm) (model.HashValue, error) {
+	hasher, err := HashPayloadContext(ctx, root, alg)
+	if err != nil {
+		return "", err
+	}
+	return hasher.Sum(), nil
+}
+
+// HashPayload hashes every entry of the payload tree at root, returning the
+// hasher so that both the root hash and the per-entry manifest can be read
+// from it.
+func HashPayload(root string, alg model.HashAlgorithm) (*PayloadHasher, error) {
+	return HashPayloadContext(context.Background(), root, alg)
+}
+
+// HashPayloadContext is HashPayload stopping with ctx's error once ctx is
+// done.
+func HashPayloadContext(ctx context.Context, root string, alg model.HashAlgorithm) (*PayloadHasher, error) {
+	hasher := NewPayloadHasher(alg)
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// Skip root itself
 		if path == root {
@@ -39,22 +69,101 @@ func ComputePayloadRootHash(root string) (model.HashValue, error) {
 			return fmt.Errorf("relative path: %w", err)
 		}
 
-		entryHash, err := computeEntryHash(path, info)
+		entryHash, err := computeEntryHash(ctx, path, info, alg)
 		if err != nil {
 			return fmt.Errorf("hash entry %s: %w", rel, err)
 		}
 
-		// Format: <type>:<path>:<metadata>:<hash>
-		// path uses forward slashes for portability
-		pathPortable := filepath.ToSlash(rel)
-		meta := formatMetadata(info)
-		line := fmt.Sprintf("%s:%s:%s:%s", entryType(info), pathPortable, meta, entryHash)
-		lines = append(lines, line)
-
+		hasher.add(rel, info, entryHash)
 		return nil
 	})
 	if err != nil {
-		return "", fmt.Errorf("walk payload: %w", err)
+		return nil, fmt.Errorf("walk payload: %w", err)
+	}
+
+	return hasher, nil
+}
+
+// PayloadHasher accumulates payload entries and produces the same root hash as
+// ComputePayloadRootHash. Engines use it to hash data while copying so the
+// payload is read only once. Paths are relative to the payload root.
+type PayloadHasher struct {
+	alg     model.HashAlgorithm
+	entries []model.ManifestEntry
+}
+
+// NewPayloadHasher creates an empty PayloadHasher for algorithm alg (empty
+// for SHA-256).
+func NewPayloadHasher(alg model.HashAlgorithm) *PayloadHasher {
+	return &PayloadHasher{alg: alg}
+}
+
+// NewFileHash returns a hash for the content of a file passed to AddFile.
+func (p *PayloadHasher) NewFileHash() hash.Hash {
+	return NewHash(p.alg)
+}
+
+// AddDir records a directory entry.
+func (p *PayloadHasher) AddDir(rel string, info os.FileInfo) {
+	p.add(rel, info, string(SumBytes(p.alg, []byte(info.Name()))))
+}
+
+// AddSymlink records a symlink entry with the given link target.
+func (p *PayloadHasher) AddSymlink(rel string, info os.FileInfo, target string) {
+	p.add(rel, info, string(SumBytes(p.alg, []byte(target))))
+}
+
+// AddFile records a regular file whose content digest, computed with a hash
+// from NewFileHash, is sum.
+func (p *PayloadHasher) AddFile(rel string, info os.FileInfo, sum []byte) {
+	p.add(rel, info, hex.EncodeToString(sum))
+}
+
+func (p *PayloadHasher) add(rel string, info os.FileInfo, entryHash string) {
+	if info.Name() == ".READY" {
+		return
+	}
+
+	entry := model.ManifestEntry{
+		// path uses forward slashes for portability
+		Path: filepath.ToSlash(rel),
+		Type: entryType(info),
+		Mode: uint32(info.Mode().Perm()),
+		Hash: model.HashValue(entryHash),
+	}
+	if !info.IsDir() {
+		entry.Size = info.Size()
+	}
+	p.entries = append(p.entries, entry)
+}
+
+// Sum returns the payload root hash of all recorded entries.
+func (p *PayloadHasher) Sum() model.HashValue {
+	return ManifestRootHash(p.alg, p.entries)
+}
+
+// Entries returns the recorded entries sorted by path, for a snapshot's
+// manifest.
+func (p *PayloadHasher) Entries() []model.ManifestEntry {
+	entries := slices.Clone(p.entries)
+	slices.SortFunc(entries, func(a, b model.ManifestEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return entries
+}
+
+// ManifestRootHash returns the payload root hash of a manifest's entries
+// under algorithm alg, which matches the descriptor's PayloadRootHash if the
+// manifest describes the snapshot's payload.
+func ManifestRootHash(alg model.HashAlgorithm, entries []model.ManifestEntry) model.HashValue {
+	// Format: <type>:<path>:<metadata>:<hash>
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		meta := fmt.Sprintf("mode=%04o", e.Mode)
+		if e.Type == "file" {
+			meta += fmt.Sprintf(",size=%d", e.Size)
+		}
+		lines[i] = fmt.Sprintf("%s:%s:%s:%s", e.Type, e.Path, meta, e.Hash)
 	}
 
 	// Sort lines by path (byte order)
@@ -67,8 +176,7 @@ func ComputePayloadRootHash(root string) (model.HashValue, error) {
 		buf.WriteByte('\n')
 	}
 
-	hash := sha256.Sum256([]byte(buf.String()))
-	return model.HashValue(hex.EncodeToString(hash[:])), nil
+	return SumBytes(alg, []byte(buf.String()))
 }
 
 func entryType(info os.FileInfo) string {
@@ -81,21 +189,8 @@ func entryType(info os.FileInfo) string {
 	return "file"
 }
 
-func formatMetadata(info os.FileInfo) string {
-	switch {
-	case info.IsDir():
-		return fmt.Sprintf("mode=%04o", info.Mode().Perm())
-	case info.Mode()&os.ModeSymlink != 0:
-		return fmt.Sprintf("mode=%04o", info.Mode().Perm())
-	default:
-		return fmt.Sprintf("mode=%04o,size=%d",
-			info.Mode().Perm(),
-			info.Size())
-	}
-}
-
-func computeEntryHash(path string, info os.FileInfo) (string, error) {
-	h := sha256.New()
+func computeEntryHash(ctx context.Context, path string, info os.FileInfo, alg model.HashAlgorithm) (string, error) {
+	h := NewHash(alg)
 
 	switch {
 	case info.IsDir():
@@ -117,7 +212,7 @@ func computeEntryHash(path string, info os.FileInfo) (string, error) {
 			return "", fmt.Errorf("open file: %w", err)
 		}
 		defer f.Close()
-		if _, err := io.Copy(h, f); err != nil {
+		if _, err := io.Copy(h, fsutil.ContextReader(ctx, f)); err != nil {
 			return "", fmt.Errorf("read file: %w", err)
 		}
 	}
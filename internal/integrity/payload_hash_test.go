@@ -1,12 +1,15 @@
 package integrity_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,9 +18,9 @@ func TestComputePayloadRootHash_Deterministic(t *testing.T) {
 	dir := t.TempDir()
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0644)
 
-	hash1, err := integrity.ComputePayloadRootHash(dir)
+	hash1, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
-	hash2, err := integrity.ComputePayloadRootHash(dir)
+	hash2, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.Equal(t, hash1, hash2, "hash must be deterministic")
 }
@@ -27,9 +30,9 @@ func TestComputePayloadRootHash_DetectsContentChange(t *testing.T) {
 	file := filepath.Join(dir, "file.txt")
 	os.WriteFile(file, []byte("original"), 0644)
 
-	hash1, _ := integrity.ComputePayloadRootHash(dir)
+	hash1, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	os.WriteFile(file, []byte("modified"), 0644)
-	hash2, _ := integrity.ComputePayloadRootHash(dir)
+	hash2, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 
 	assert.NotEqual(t, hash1, hash2, "content change must produce different hash")
 }
@@ -39,9 +42,9 @@ func TestComputePayloadRootHash_DetectsPermissionChange(t *testing.T) {
 	file := filepath.Join(dir, "file.txt")
 	os.WriteFile(file, []byte("content"), 0644)
 
-	hash1, _ := integrity.ComputePayloadRootHash(dir)
+	hash1, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	os.Chmod(file, 0755)
-	hash2, _ := integrity.ComputePayloadRootHash(dir)
+	hash2, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 
 	assert.NotEqual(t, hash1, hash2, "permission change must produce different hash")
 }
@@ -51,7 +54,7 @@ func TestComputePayloadRootHash_IncludesDirectory(t *testing.T) {
 	subdir := filepath.Join(dir, "subdir")
 	require.NoError(t, os.Mkdir(subdir, 0755))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -61,14 +64,14 @@ func TestComputePayloadRootHash_IncludesSymlink(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "target.txt"), []byte("target"), 0644)
 	require.NoError(t, os.Symlink("target.txt", filepath.Join(dir, "link")))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
 
 func TestComputePayloadRootHash_EmptyDir(t *testing.T) {
 	dir := t.TempDir()
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash, "empty dir should still produce a hash")
 }
@@ -78,14 +81,14 @@ func TestComputePayloadRootHash_DeterministicWithDifferentModTime(t *testing.T)
 	file := filepath.Join(dir, "file.txt")
 	os.WriteFile(file, []byte("content"), 0644)
 
-	hash1, err := integrity.ComputePayloadRootHash(dir)
+	hash1, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 
 	// Change modification time
 	newTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
 	require.NoError(t, os.Chtimes(file, newTime, newTime))
 
-	hash2, err := integrity.ComputePayloadRootHash(dir)
+	hash2, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 
 	// Hash should be the same despite mod time change (per spec)
@@ -100,7 +103,7 @@ func TestComputePayloadRootHash_NestedStructure(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "a", "file1.txt"), []byte("file1"), 0644)
 	os.WriteFile(filepath.Join(dir, "a", "b", "file2.txt"), []byte("file2"), 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -110,13 +113,13 @@ func TestComputePayloadRootHash_SkipsReadyMarker(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644)
 	os.WriteFile(filepath.Join(dir, ".READY"), []byte(`{"snapshot_id":"test"}`), 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 
 	// Remove .READY and verify hash is the same
 	os.Remove(filepath.Join(dir, ".READY"))
-	hash2, err := integrity.ComputePayloadRootHash(dir)
+	hash2, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.Equal(t, hash, hash2, ".READY should be excluded from hash")
 }
@@ -126,7 +129,7 @@ func TestComputePayloadRootHash_BrokenSymlink(t *testing.T) {
 	// Create a symlink pointing to nothing
 	require.NoError(t, os.Symlink("nonexistent-target", filepath.Join(dir, "broken")))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -137,7 +140,7 @@ func TestComputePayloadRootHash_MultipleFiles(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bbb"), 0644)
 	os.WriteFile(filepath.Join(dir, "c.txt"), []byte("ccc"), 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -149,7 +152,7 @@ func TestComputePayloadRootHash_Hardlink(t *testing.T) {
 	file2 := filepath.Join(dir, "file2.txt")
 	require.NoError(t, os.Link(file1, file2))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -160,7 +163,7 @@ func TestComputePayloadRootHash_DeeplyNested(t *testing.T) {
 	require.NoError(t, os.MkdirAll(deepPath, 0755))
 	os.WriteFile(filepath.Join(deepPath, "deep.txt"), []byte("deep content"), 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -170,7 +173,7 @@ func TestComputePayloadRootHash_SpecialCharactersInFilename(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "file with spaces.txt"), []byte("content"), 0644)
 	os.WriteFile(filepath.Join(dir, "file-with-dashes.txt"), []byte("content"), 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -184,7 +187,7 @@ func TestComputePayloadRootHash_MixedContentTypes(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "link-target.txt"), []byte("target"), 0644)
 	require.NoError(t, os.Symlink("link-target.txt", filepath.Join(dir, "link")))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -202,7 +205,7 @@ func TestComputePayloadRootHash_UnreadableFile(t *testing.T) {
 
 	// On Unix systems, this should cause an error
 	// On Windows, the behavior may differ
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	if err != nil {
 		// Expected on systems that respect chmod 0000
 		assert.Empty(t, hash)
@@ -226,7 +229,7 @@ func TestComputePayloadRootHash_SymlinkReadError(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "target.txt"), []byte("target"), 0644)
 	require.NoError(t, os.Symlink("target.txt", filepath.Join(dir, "link")))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -238,7 +241,7 @@ func TestComputePayloadRootHash_WalkError(t *testing.T) {
 
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -260,7 +263,7 @@ func TestComputePayloadRootHash_FileWithVariousSizes(t *testing.T) {
 	}
 	os.WriteFile(filepath.Join(dir, "large.txt"), largeContent, 0644)
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -276,7 +279,7 @@ func TestComputePayloadRootHash_SymlinkToDirectory(t *testing.T) {
 	// Create a symlink to the directory
 	require.NoError(t, os.Symlink("targetdir", filepath.Join(dir, "linkdir")))
 
-	hash, err := integrity.ComputePayloadRootHash(dir)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 	require.NoError(t, err)
 	assert.NotEmpty(t, hash)
 }
@@ -290,12 +293,12 @@ func TestComputePayloadRootHash_DirectoryWithDifferentPermissions(t *testing.T)
 	require.NoError(t, os.MkdirAll(subdir1, 0755))
 	require.NoError(t, os.MkdirAll(subdir2, 0700))
 
-	hash1, _ := integrity.ComputePayloadRootHash(dir)
+	hash1, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 
 	// Change permissions
 	os.Chmod(subdir2, 0755)
 
-	hash2, _ := integrity.ComputePayloadRootHash(dir)
+	hash2, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 
 	// Hashes should be the same because we sort by path and permissions
 	// are included in metadata. Actually, with different permissions,
@@ -310,12 +313,89 @@ func TestComputePayloadRootHash_FileWithDifferentPermissions(t *testing.T) {
 	file := filepath.Join(dir, "file.txt")
 	os.WriteFile(file, []byte("content"), 0644)
 
-	hash1, _ := integrity.ComputePayloadRootHash(dir)
+	hash1, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 
 	// Change permissions
 	os.Chmod(file, 0755)
 
-	hash2, _ := integrity.ComputePayloadRootHash(dir)
+	hash2, _ := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
 
 	assert.NotEqual(t, hash1, hash2, "file permissions should affect hash")
 }
+
+func TestHashPayload_ManifestEntries(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("bb"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".READY"), []byte("{}"), 0644))
+
+	hasher, err := integrity.HashPayload(dir, model.HashSHA256)
+	require.NoError(t, err)
+	hash, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, hash, hasher.Sum())
+
+	entries := hasher.Entries()
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"a.txt", "link", "sub", "sub/b.txt"}, paths, "sorted, without .READY")
+	assert.Equal(t, "file", entries[0].Type)
+	assert.Equal(t, uint32(0644), entries[0].Mode)
+	assert.Equal(t, int64(1), entries[0].Size)
+	assert.Equal(t, "symlink", entries[1].Type)
+	assert.Equal(t, "dir", entries[2].Type)
+	assert.Equal(t, uint32(0750), entries[2].Mode)
+
+	assert.Equal(t, hash, integrity.ManifestRootHash(model.HashSHA256, entries))
+	entries[3].Hash = entries[0].Hash
+	assert.NotEqual(t, hash, integrity.ManifestRootHash(model.HashSHA256, entries), "a changed entry changes the root hash")
+}
+
+func TestComputePayloadRootHash_BLAKE3(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+
+	sha, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
+	require.NoError(t, err)
+	legacy, err := integrity.ComputePayloadRootHash(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, sha, legacy, "empty means SHA-256")
+
+	b3, err := integrity.ComputePayloadRootHash(dir, model.HashBLAKE3)
+	require.NoError(t, err)
+	assert.Len(t, string(b3), 64)
+	assert.NotEqual(t, sha, b3)
+
+	hasher, err := integrity.HashPayload(dir, model.HashBLAKE3)
+	require.NoError(t, err)
+	assert.Equal(t, b3, integrity.ManifestRootHash(model.HashBLAKE3, hasher.Entries()))
+	assert.Equal(t, model.HashValue("17762fddd969a453925d65717ac3eea21320b66b54342fde15128d6caf21215f"), hasher.Entries()[0].Hash,
+		"BLAKE3 of the file content")
+}
+
+func TestCheckHashAlgorithm(t *testing.T) {
+	for _, alg := range []model.HashAlgorithm{"", model.HashSHA256, model.HashBLAKE3} {
+		assert.NoError(t, integrity.CheckHashAlgorithm(alg))
+	}
+	assert.ErrorIs(t, integrity.CheckHashAlgorithm("md5"), errclass.ErrFormatUnsupported)
+}
+
+func TestComputePayloadRootHashContext_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0644))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	expected, err := integrity.ComputePayloadRootHash(dir, model.HashSHA256)
+	require.NoError(t, err)
+	actual, err := integrity.ComputePayloadRootHashContext(ctx, dir, model.HashSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	cancel()
+	_, err = integrity.ComputePayloadRootHashContext(ctx, dir, model.HashSHA256)
+	assert.ErrorIs(t, err, context.Canceled)
+}
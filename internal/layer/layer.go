@@ -0,0 +1,303 @@
+// Package layer writes snapshots as OCI image layer tarballs.
+//
+// A layer is an uncompressed tar stream. Without a parent it holds the whole
+// payload; with a parent it holds only entries that were added or changed,
+// plus whiteout files (".wh.<name>") for removed entries, per the OCI image
+// layer specification. Ownership is normalized to 0:0 and entries are
+// written in lexical path order, so identical payloads produce identical
+// layers.
+package layer
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WhiteoutPrefix marks a removed entry in an OCI layer.
+const WhiteoutPrefix = ".wh."
+
+// Stats describes a written layer.
+type Stats struct {
+	// DiffID is the digest of the uncompressed tar stream ("sha256:<hex>"),
+	// as listed in an image config's rootfs.diff_ids.
+	DiffID    string `json:"diff_id"`
+	Entries   int    `json:"entries"`
+	Whiteouts int    `json:"whiteouts"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// entry is one filesystem object in a payload tree.
+type entry struct {
+	rel    string // slash-separated, relative to the tree root
+	full   string
+	info   fs.FileInfo
+	target string // symlink target
+}
+
+// Write writes dir as a layer to w. If parentDir is not empty, the layer
+// is a delta against the tree at parentDir.
+func Write(w io.Writer, dir, parentDir string) (*Stats, error) {
+	tree, err := scan(dir)
+	if err != nil {
+		return nil, fmt.Errorf("scan payload: %w", err)
+	}
+
+	var include map[string]bool
+	var whiteouts []string
+	if parentDir != "" {
+		parent, err := scan(parentDir)
+		if err != nil {
+			return nil, fmt.Errorf("scan parent payload: %w", err)
+		}
+		include, whiteouts, err = delta(tree, parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(w, h)}
+	tw := tar.NewWriter(cw)
+	stats := &Stats{}
+
+	// Merge entries and whiteouts into one lexically ordered stream
+	type item struct {
+		name     string
+		entry    *entry
+		whiteout bool
+	}
+	var items []item
+	for rel, e := range tree {
+		if include == nil || include[rel] {
+			items = append(items, item{name: rel, entry: e})
+		}
+	}
+	for _, rel := range whiteouts {
+		items = append(items, item{name: path.Join(path.Dir(rel), WhiteoutPrefix+path.Base(rel)), whiteout: true})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].name < items[j].name })
+
+	for _, it := range items {
+		if it.whiteout {
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     it.name,
+				Mode:     0644,
+				Format:   tar.FormatPAX,
+			}); err != nil {
+				return nil, fmt.Errorf("write whiteout %s: %w", it.name, err)
+			}
+			stats.Whiteouts++
+			continue
+		}
+		if err := writeEntry(tw, it.entry); err != nil {
+			return nil, err
+		}
+		stats.Entries++
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("close tar: %w", err)
+	}
+	stats.Bytes = cw.n
+	stats.DiffID = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	return stats, nil
+}
+
+func writeEntry(tw *tar.Writer, e *entry) error {
+	hdr, err := tar.FileInfoHeader(e.info, e.target)
+	if err != nil {
+		return fmt.Errorf("tar header %s: %w", e.rel, err)
+	}
+	hdr.Name = e.rel
+	if e.info.IsDir() {
+		hdr.Name += "/"
+	}
+	// Reproducible, host-independent headers
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+	hdr.Format = tar.FormatPAX
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write header %s: %w", e.rel, err)
+	}
+	if !e.info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(e.full)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", e.rel, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("write %s: %w", e.rel, err)
+	}
+	return nil
+}
+
+// scan collects the directories, regular files and symlinks below root.
+// The snapshot's .READY marker and other file types are skipped.
+func scan(root string) (map[string]*entry, error) {
+	tree := make(map[string]*entry)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root || (filepath.Dir(p) == root && isReadyMarker(d.Name())) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		e := &entry{rel: filepath.ToSlash(rel), full: p, info: info}
+		if isWhiteout(e.rel) {
+			return fmt.Errorf("%s: names starting with %s are reserved in layers", e.rel, WhiteoutPrefix)
+		}
+
+		switch {
+		case info.IsDir(), info.Mode().IsRegular():
+		case info.Mode()&os.ModeSymlink != 0:
+			if e.target, err = os.Readlink(p); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		tree[e.rel] = e
+		return nil
+	})
+	return tree, err
+}
+
+// delta returns the entries of tree to include in a layer on top of parent,
+// and the topmost parent entries that no longer exist.
+func delta(tree, parent map[string]*entry) (map[string]bool, []string, error) {
+	include := make(map[string]bool)
+	addWithAncestors := func(rel string) {
+		for rel != "." && !include[rel] {
+			include[rel] = true
+			rel = path.Dir(rel)
+		}
+	}
+
+	for rel, e := range tree {
+		changed, err := differs(e, parent[rel])
+		if err != nil {
+			return nil, nil, err
+		}
+		if changed {
+			addWithAncestors(rel)
+		}
+	}
+
+	var whiteouts []string
+	for rel := range parent {
+		if _, exists := tree[rel]; exists {
+			continue
+		}
+		// Only the topmost removed entry needs a whiteout; a directory
+		// replaced by a file or symlink is removed by the new entry itself
+		dir := path.Dir(rel)
+		if dir != "." {
+			if d, exists := tree[dir]; !exists || !d.info.IsDir() {
+				continue
+			}
+			addWithAncestors(dir)
+		}
+		whiteouts = append(whiteouts, rel)
+	}
+	return include, whiteouts, nil
+}
+
+// differs reports whether e must be written to replace old, which may be nil.
+func differs(e, old *entry) (bool, error) {
+	if old == nil {
+		return true, nil
+	}
+	if e.info.Mode() != old.info.Mode() {
+		return true, nil // type or permissions changed
+	}
+	switch {
+	case e.info.IsDir():
+		return false, nil
+	case e.info.Mode()&os.ModeSymlink != 0:
+		return e.target != old.target, nil
+	default:
+		if e.info.Size() != old.info.Size() {
+			return true, nil
+		}
+		return contentDiffers(e.full, old.full)
+	}
+}
+
+func contentDiffers(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return true, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB != io.EOF && errB != io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// isWhiteout reports whether a tar entry name is a whiteout file.
+func isWhiteout(name string) bool {
+	return strings.HasPrefix(path.Base(name), WhiteoutPrefix)
+}
+
+// isReadyMarker reports whether a top-level name is the snapshot's .READY
+// marker, which compressed snapshots keep as .READY.gz or .READY.zst.
+func isReadyMarker(name string) bool {
+	return name == ".READY" || name == ".READY.gz" || name == ".READY.zst"
+}
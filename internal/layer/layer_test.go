@@ -0,0 +1,169 @@
+package layer_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/layer"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+type tarEntry struct {
+	name    string
+	mode    int64
+	content string
+	link    string
+}
+
+func readTar(t *testing.T, data []byte) []tarEntry {
+	t.Helper()
+	var entries []tarEntry
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		require.NoError(t, err)
+		assert.Zero(t, hdr.Uid)
+		assert.Zero(t, hdr.Gid)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries = append(entries, tarEntry{name: hdr.Name, mode: hdr.Mode & 0777, content: string(content), link: hdr.Linkname})
+	}
+}
+
+func names(entries []tarEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.name)
+	}
+	return out
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+	require.NoError(t, os.Chmod(path, mode))
+}
+
+func TestWrite_FullLayer(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "b.txt"), "b", 0644)
+	writeFile(t, filepath.Join(dir, "a", "z.sh"), "#!/bin/sh", 0755)
+	writeFile(t, filepath.Join(dir, "a", "b", "c.txt"), "c", 0644)
+	writeFile(t, filepath.Join(dir, ".READY"), "{}", 0644)
+	require.NoError(t, os.Symlink("b.txt", filepath.Join(dir, "link")))
+
+	var buf bytes.Buffer
+	stats, err := layer.Write(&buf, dir, "")
+	require.NoError(t, err)
+
+	entries := readTar(t, buf.Bytes())
+	assert.Equal(t, []string{"a/", "a/b/", "a/b/c.txt", "a/z.sh", "b.txt", "link"}, names(entries))
+	assert.Equal(t, int64(0755), entries[3].mode)
+	assert.Equal(t, "#!/bin/sh", entries[3].content)
+	assert.Equal(t, "b.txt", entries[5].link)
+
+	assert.Equal(t, 6, stats.Entries)
+	assert.Zero(t, stats.Whiteouts)
+	assert.Equal(t, int64(buf.Len()), stats.Bytes)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", stats.DiffID)
+
+	// Writing the same tree again yields the same bytes
+	var again bytes.Buffer
+	stats2, err := layer.Write(&again, dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, stats.DiffID, stats2.DiffID)
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+}
+
+func TestWrite_DeltaWithWhiteouts(t *testing.T) {
+	parent := t.TempDir()
+	writeFile(t, filepath.Join(parent, "keep.txt"), "same", 0644)
+	writeFile(t, filepath.Join(parent, "edit.txt"), "old", 0644)
+	writeFile(t, filepath.Join(parent, "run.sh"), "x", 0644)
+	writeFile(t, filepath.Join(parent, "gone.txt"), "bye", 0644)
+	writeFile(t, filepath.Join(parent, "olddir", "x", "y.txt"), "y", 0644)
+	writeFile(t, filepath.Join(parent, "src", "old.go"), "old", 0644)
+	writeFile(t, filepath.Join(parent, "src", "keep.go"), "keep", 0644)
+	writeFile(t, filepath.Join(parent, "swap", "inner.txt"), "i", 0644)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "keep.txt"), "same", 0644)
+	writeFile(t, filepath.Join(dir, "edit.txt"), "new", 0644)
+	writeFile(t, filepath.Join(dir, "run.sh"), "x", 0755)
+	writeFile(t, filepath.Join(dir, "src", "keep.go"), "keep", 0644)
+	writeFile(t, filepath.Join(dir, "src", "new.go"), "new", 0644)
+	writeFile(t, filepath.Join(dir, "swap"), "now a file", 0644)
+
+	var buf bytes.Buffer
+	stats, err := layer.Write(&buf, dir, parent)
+	require.NoError(t, err)
+
+	entries := readTar(t, buf.Bytes())
+	assert.Equal(t, []string{
+		".wh.gone.txt",
+		".wh.olddir",
+		"edit.txt",
+		"run.sh",
+		"src/",
+		"src/.wh.old.go",
+		"src/new.go",
+		"swap",
+	}, names(entries))
+	assert.Equal(t, 5, stats.Entries)
+	assert.Equal(t, 3, stats.Whiteouts)
+}
+
+func TestWrite_ReservedName(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".wh.file"), "x", 0644)
+
+	_, err := layer.Write(io.Discard, dir, "")
+	assert.ErrorContains(t, err, "reserved")
+}
+
+func TestWriteSnapshot_Compressed(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+	mainPath := filepath.Join(repoPath, "main")
+
+	writeFile(t, filepath.Join(mainPath, "data.txt"), "version one", 0644)
+	plain, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "plain", nil)
+	require.NoError(t, err)
+
+	writeFile(t, filepath.Join(mainPath, "data.txt"), "version two", 0644)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompression(compression.LevelFast)
+	compressed, err := creator.Create("main", "compressed", nil)
+	require.NoError(t, err)
+	require.NotNil(t, compressed.Compression)
+
+	var buf bytes.Buffer
+	result, err := layer.WriteSnapshot(repoPath, compressed.SnapshotID, plain.SnapshotID, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, compressed.SnapshotID, result.SnapshotID)
+	assert.Equal(t, plain.SnapshotID, result.ParentID)
+
+	entries := readTar(t, buf.Bytes())
+	require.Len(t, entries, 1)
+	assert.Equal(t, "data.txt", entries[0].name)
+	assert.Equal(t, "version two", entries[0].content)
+
+	// The temporary decompressed copy is removed
+	tmps, _ := filepath.Glob(filepath.Join(repoPath, ".jvs", ".jvs-tmp-layer-*"))
+	assert.Empty(t, tmps)
+}
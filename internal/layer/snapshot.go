@@ -0,0 +1,52 @@
+package layer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Result describes a layer written from a snapshot.
+type Result struct {
+	SnapshotID model.SnapshotID `json:"snapshot_id"`
+	ParentID   model.SnapshotID `json:"parent_id,omitempty"`
+	Stats
+}
+
+// WriteSnapshot writes snapshot id as a layer to w, as a delta against
+// parentID if it is not empty. Both snapshots are verified first. Compressed
+// snapshots are decompressed into a temporary directory under .jvs.
+func WriteSnapshot(repoRoot string, id, parentID model.SnapshotID, w io.Writer) (*Result, error) {
+	dir, cleanup, err := payloadDir(repoRoot, id)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var parentDir string
+	if parentID != "" {
+		var cleanupParent func()
+		parentDir, cleanupParent, err = payloadDir(repoRoot, parentID)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanupParent()
+	}
+
+	stats, err := Write(w, dir, parentDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{SnapshotID: id, ParentID: parentID, Stats: *stats}, nil
+}
+
+// payloadDir returns a directory holding the uncompressed payload of id.
+func payloadDir(repoRoot string, id model.SnapshotID) (string, func(), error) {
+	if err := snapshot.VerifySnapshot(repoRoot, id, false); err != nil {
+		return "", nil, fmt.Errorf("verify snapshot %s: %w", id, err)
+	}
+	_, dir, cleanup, err := snapshot.UncompressedPayload(repoRoot, id, "layer")
+	return dir, cleanup, err
+}
@@ -0,0 +1,187 @@
+// Package lease manages worktree attachment leases.
+//
+// A lease tells JVS that a consumer is using a worktree payload, so that
+// operations which replace the payload out from under it (restore) are refused
+// until the lease is released or expires.
+package lease
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/leasefile"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// BusyError is returned when a worktree has an active lease held by someone else.
+// It matches errclass.ErrWorktreeBusy with errors.Is.
+type BusyError struct {
+	Lease *model.Lease
+}
+
+func (e *BusyError) Error() string {
+	l := e.Lease
+	holder := l.Holder
+	if l.Host != "" {
+		holder += " on " + l.Host
+	}
+	return fmt.Sprintf("%s: worktree %s is leased by %s until %s",
+		errclass.ErrWorktreeBusy.Code, l.WorktreeName, holder, l.ExpiresAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is errclass.ErrWorktreeBusy.
+func (e *BusyError) Is(target error) bool {
+	return errors.Is(errclass.ErrWorktreeBusy, target)
+}
+
+// Manager reads and writes worktree leases.
+type Manager struct {
+	repoRoot    string
+	auditLogger *audit.FileAppender
+	now         func() time.Time
+}
+
+// NewManager creates a new lease manager.
+func NewManager(repoRoot string) *Manager {
+	return &Manager{
+		repoRoot:    repoRoot,
+		auditLogger: audit.NewFileAppender(filepath.Join(repoRoot, ".jvs", "audit", "audit.jsonl")),
+		now:         time.Now,
+	}
+}
+
+// SetCorrelationID sets the correlation ID recorded in the audit records
+// of the manager's operations. Empty, the default, uses the one set through
+// audit.CorrelationEnvVar, if any.
+func (m *Manager) SetCorrelationID(id string) {
+	m.auditLogger.SetCorrelationID(id)
+}
+
+// SetAuthor sets who the audit records of the manager's operations name as
+// their author. Empty, the default, uses identity.Author.
+func (m *Manager) SetAuthor(author string) {
+	m.auditLogger.SetAuthor(author)
+}
+
+// SetClock replaces the clock used to stamp and expire leases.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.now = now
+}
+
+func (m *Manager) file(worktreeName string) *leasefile.File[model.Lease, *model.Lease] {
+	path := filepath.Join(m.repoRoot, ".jvs", "leases", worktreeName+".json")
+	return leasefile.New(path, "lease", func(l *model.Lease) string { return l.Holder })
+}
+
+// Acquire takes a lease on a worktree for holder, valid for ttl.
+// Re-acquiring a lease already held by the same holder renews it.
+// Returns a *BusyError if another holder has an unexpired lease.
+func (m *Manager) Acquire(worktreeName, holder string, ttl time.Duration) (*model.Lease, error) {
+	if err := pathutil.ValidateName(worktreeName); err != nil {
+		return nil, err
+	}
+	if holder == "" {
+		return nil, fmt.Errorf("lease holder is required")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lease ttl must be positive")
+	}
+
+	now := m.now().UTC()
+	host, _ := os.Hostname()
+	var l *model.Lease
+	existing, err := m.file(worktreeName).Acquire(holder, now, func(prev *model.Lease) *model.Lease {
+		l = &model.Lease{
+			WorktreeName: worktreeName,
+			Holder:       holder,
+			Host:         host,
+			AcquiredAt:   now,
+			ExpiresAt:    now.Add(ttl),
+		}
+		if prev != nil {
+			l.AcquiredAt = prev.AcquiredAt
+		}
+		return l
+	})
+	if errors.Is(err, leasefile.ErrHeld) {
+		return nil, &BusyError{Lease: existing}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		m.auditLogger.Append(model.EventTypeLeaseAcquire, worktreeName, "", map[string]any{
+			"holder":     holder,
+			"host":       host,
+			"expires_at": l.ExpiresAt,
+		})
+	}
+	return l, nil
+}
+
+// Release removes the lease on a worktree held by holder.
+// Releasing a missing or expired lease is not an error.
+func (m *Manager) Release(worktreeName, holder string) error {
+	existing, err := m.file(worktreeName).Release(holder, m.now())
+	if errors.Is(err, leasefile.ErrHeld) {
+		return &BusyError{Lease: existing}
+	}
+	if err != nil || existing == nil {
+		return err
+	}
+	m.auditLogger.Append(model.EventTypeLeaseRelease, worktreeName, "", map[string]any{
+		"holder": holder,
+	})
+	return nil
+}
+
+// Get returns the active lease on a worktree, or nil if there is none or it has expired.
+func (m *Manager) Get(worktreeName string) (*model.Lease, error) {
+	return m.file(worktreeName).Read(m.now())
+}
+
+// List returns all active leases.
+func (m *Manager) List() ([]*model.Lease, error) {
+	entries, err := os.ReadDir(filepath.Join(m.repoRoot, ".jvs", "leases"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read leases directory: %w", err)
+	}
+	var leases []*model.Lease
+	for _, entry := range entries {
+		name := entry.Name()
+		if filepath.Ext(name) != ".json" {
+			continue
+		}
+		l, err := m.Get(name[:len(name)-len(".json")])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping lease %s: %v\n", name, err)
+			continue
+		}
+		if l != nil {
+			leases = append(leases, l)
+		}
+	}
+	return leases, nil
+}
+
+// CheckFree returns a *BusyError if the worktree has an active lease.
+func (m *Manager) CheckFree(worktreeName string) error {
+	l, err := m.Get(worktreeName)
+	if err != nil {
+		return err
+	}
+	if l != nil {
+		return &BusyError{Lease: l}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package lease_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestManager_AcquireAndGet(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	l, err := mgr.Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "main", l.WorktreeName)
+	assert.Equal(t, "agent-pod-1", l.Holder)
+	assert.True(t, l.ExpiresAt.After(l.AcquiredAt))
+
+	got, err := mgr.Get("main")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "agent-pod-1", got.Holder)
+
+	assert.Error(t, mgr.CheckFree("main"))
+}
+
+func TestManager_AcquireRenewsSameHolder(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	first, err := mgr.Acquire("main", "agent-pod-1", time.Minute)
+	require.NoError(t, err)
+	second, err := mgr.Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, first.AcquiredAt, second.AcquiredAt)
+	assert.True(t, second.ExpiresAt.After(first.ExpiresAt))
+}
+
+func TestManager_AcquireBusy(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	_, err := mgr.Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+
+	_, err = mgr.Acquire("main", "agent-pod-2", time.Hour)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeBusy))
+
+	var busy *lease.BusyError
+	require.True(t, errors.As(err, &busy))
+	assert.Equal(t, "agent-pod-1", busy.Lease.Holder)
+	assert.Contains(t, err.Error(), "agent-pod-1")
+}
+
+func TestManager_AcquireConcurrent(t *testing.T) {
+	repoPath := setupTestRepo(t)
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate managers, as separate processes would have.
+			_, errs[i] = lease.NewManager(repoPath).Acquire("main", fmt.Sprintf("agent-pod-%d", i), time.Hour)
+		}()
+	}
+	wg.Wait()
+
+	acquired := 0
+	for _, err := range errs {
+		if err == nil {
+			acquired++
+			continue
+		}
+		assert.True(t, errors.Is(err, errclass.ErrWorktreeBusy), "%v", err)
+	}
+	assert.Equal(t, 1, acquired, "exactly one holder takes the lease")
+}
+
+func TestManager_Release(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	_, err := mgr.Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+
+	// Another holder cannot release it
+	err = mgr.Release("main", "agent-pod-2")
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeBusy))
+
+	require.NoError(t, mgr.Release("main", "agent-pod-1"))
+	assert.NoError(t, mgr.CheckFree("main"))
+
+	// Releasing again is a no-op
+	assert.NoError(t, mgr.Release("main", "agent-pod-1"))
+}
+
+func TestManager_ExpiredLeaseIsFree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	_, err := mgr.Acquire("main", "agent-pod-1", time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	got, err := mgr.Get("main")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	// A new holder can take over an expired lease
+	_, err = mgr.Acquire("main", "agent-pod-2", time.Hour)
+	assert.NoError(t, err)
+}
+
+func TestManager_List(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	leases, err := mgr.List()
+	require.NoError(t, err)
+	assert.Empty(t, leases)
+
+	_, err = mgr.Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+
+	leases, err = mgr.List()
+	require.NoError(t, err)
+	require.Len(t, leases, 1)
+	assert.Equal(t, "main", leases[0].WorktreeName)
+}
+
+func TestManager_AcquireInvalid(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := lease.NewManager(repoPath)
+
+	_, err := mgr.Acquire("../evil", "agent", time.Hour)
+	assert.Error(t, err)
+	_, err = mgr.Acquire("main", "", time.Hour)
+	assert.Error(t, err)
+	_, err = mgr.Acquire("main", "agent", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+// Package leasefile keeps a small JSON record, such as a worktree lock or
+// lease, in a file owned by at most one holder at a time.
+//
+// Every change to the file is made while holding an exclusive flock on a
+// guard file next to it, so two processes racing to take the record cannot
+// both see it free and both write it. A crashed process releases its flock,
+// so a guard it leaves behind is simply taken over. A record is only
+// replaced when it is missing, expired, or already belongs to the holder
+// taking it.
+package leasefile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+)
+
+// ErrHeld is returned when the record is held by another, unexpired holder.
+var ErrHeld = errors.New("held by another holder")
+
+const (
+	// guardWait is how long to wait for another process's guard.
+	guardWait = 5 * time.Second
+	guardPoll = 5 * time.Millisecond
+)
+
+// Record is implemented by the pointer type of a record kept in a File.
+type Record[T any] interface {
+	*T
+	Expired(now time.Time) bool
+}
+
+// File is a record of type T kept at a path. holder returns who holds a
+// record; noun names the record in errors.
+type File[T any, P Record[T]] struct {
+	path   string
+	noun   string
+	holder func(P) string
+}
+
+// New returns the File at path.
+func New[T any, P Record[T]](path, noun string, holder func(P) string) *File[T, P] {
+	return &File[T, P]{path: path, noun: noun, holder: holder}
+}
+
+// Read returns the active record, or nil if there is none or it has expired.
+func (f *File[T, P]) Read(now time.Time) (P, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", f.noun, err)
+	}
+	rec := P(new(T))
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", f.noun, err)
+	}
+	if rec.Expired(now) {
+		return nil, nil
+	}
+	return rec, nil
+}
+
+// Acquire writes the record built by build for holder, unless another holder
+// has an active record, in which case it returns that record and ErrHeld.
+// build is passed the active record holder already has, or nil, and Acquire
+// returns the same record so the caller can tell a renewal from a new hold.
+func (f *File[T, P]) Acquire(holder string, now time.Time, build func(prev P) P) (P, error) {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return nil, fmt.Errorf("create %s directory: %w", f.noun, err)
+	}
+	release, err := f.guard()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	prev, err := f.Read(now)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && f.holder(prev) != holder {
+		return prev, ErrHeld
+	}
+	data, err := json.MarshalIndent(build(prev), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s: %w", f.noun, err)
+	}
+	if err := fsutil.AtomicWrite(f.path, data, 0644); err != nil {
+		return nil, fmt.Errorf("write %s: %w", f.noun, err)
+	}
+	return prev, nil
+}
+
+// Release removes the record if holder holds it, returning the removed
+// record. A missing or expired record is removed too and returns nil; one
+// held by another holder is left alone and returned with ErrHeld.
+func (f *File[T, P]) Release(holder string, now time.Time) (P, error) {
+	if _, err := os.Stat(f.path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	release, err := f.guard()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	prev, err := f.Read(now)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && f.holder(prev) != holder {
+		return prev, ErrHeld
+	}
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove %s: %w", f.noun, err)
+	}
+	return prev, nil
+}
+
+// guard takes the guard file's flock, waiting for another holder to let it
+// go, and returns the function that releases it.
+func (f *File[T, P]) guard() (func(), error) {
+	path := f.path + ".guard"
+	deadline := time.Now().Add(guardWait)
+	for {
+		g, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("guard %s: %w", f.noun, err)
+		}
+		ok, err := repolock.TryLockFile(g, true)
+		if err != nil {
+			g.Close()
+			return nil, fmt.Errorf("lock %s guard: %w", f.noun, err)
+		}
+		if ok {
+			// A releasing holder may have removed the file after we opened
+			// it; the lock only counts on the file still at path
+			if sameFile(g, path) {
+				return func() {
+					os.Remove(path)
+					repolock.UnlockFile(g)
+					g.Close()
+				}, nil
+			}
+			repolock.UnlockFile(g)
+			g.Close()
+			continue
+		}
+		g.Close()
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("guard %s: timed out waiting for %s", f.noun, path)
+		}
+		time.Sleep(guardPoll)
+	}
+}
+
+// sameFile reports whether f is still the file at path.
+func sameFile(f *os.File, path string) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	pi, err := os.Stat(path)
+	return err == nil && os.SameFile(fi, pi)
+}
@@ -0,0 +1,156 @@
+package leasefile_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/leasefile"
+	"github.com/jvs-project/jvs/internal/repolock"
+)
+
+type record struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r *record) Expired(now time.Time) bool { return !now.Before(r.ExpiresAt) }
+
+func newFile(t *testing.T) (*leasefile.File[record, *record], string) {
+	path := filepath.Join(t.TempDir(), "leases", "main.json")
+	return leasefile.New(path, "record", func(r *record) string { return r.Holder }), path
+}
+
+func take(f *leasefile.File[record, *record], holder string, now time.Time, ttl time.Duration) (*record, error) {
+	return f.Acquire(holder, now, func(*record) *record {
+		return &record{Holder: holder, ExpiresAt: now.Add(ttl)}
+	})
+}
+
+func TestFile_AcquireRelease(t *testing.T) {
+	f, _ := newFile(t)
+	now := time.Now()
+
+	prev, err := take(f, "alice", now, time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, prev)
+	prev, err = take(f, "alice", now, 2*time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, prev, "renewal returns the record it replaced")
+	assert.Equal(t, "alice", prev.Holder)
+
+	prev, err = take(f, "bob", now, time.Hour)
+	assert.True(t, errors.Is(err, leasefile.ErrHeld))
+	require.NotNil(t, prev)
+	assert.Equal(t, "alice", prev.Holder)
+	_, err = f.Release("bob", now)
+	assert.True(t, errors.Is(err, leasefile.ErrHeld))
+
+	prev, err = f.Release("alice", now)
+	require.NoError(t, err)
+	require.NotNil(t, prev)
+	got, err := f.Read(now)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	prev, err = f.Release("alice", now)
+	require.NoError(t, err)
+	assert.Nil(t, prev)
+}
+
+func TestFile_AcquireExpired(t *testing.T) {
+	f, _ := newFile(t)
+	now := time.Now()
+
+	_, err := take(f, "alice", now, time.Minute)
+	require.NoError(t, err)
+	later := now.Add(time.Hour)
+	prev, err := take(f, "bob", later, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, prev, "an expired record is replaced as if missing")
+	got, err := f.Read(later)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", got.Holder)
+}
+
+func TestFile_AcquireConcurrent(t *testing.T) {
+	f, _ := newFile(t)
+	now := time.Now()
+
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = take(f, fmt.Sprintf("holder-%d", i), now, time.Hour)
+		}()
+	}
+	wg.Wait()
+
+	var winners []string
+	for i, err := range errs {
+		if err == nil {
+			winners = append(winners, fmt.Sprintf("holder-%d", i))
+			continue
+		}
+		assert.True(t, errors.Is(err, leasefile.ErrHeld), "holder-%d: %v", i, err)
+	}
+	require.Len(t, winners, 1, "exactly one holder takes the record")
+	got, err := f.Read(now)
+	require.NoError(t, err)
+	assert.Equal(t, winners[0], got.Holder)
+}
+
+func TestFile_LeftoverGuard(t *testing.T) {
+	f, path := newFile(t)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	guard := path + ".guard"
+	require.NoError(t, os.WriteFile(guard, nil, 0644))
+
+	_, err := take(f, "alice", time.Now(), time.Hour)
+	require.NoError(t, err, "a guard file nobody has locked is taken over")
+	_, err = os.Stat(guard)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFile_HeldGuard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("flock is a no-op on Windows")
+	}
+	f, path := newFile(t)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	g, err := os.OpenFile(path+".guard", os.O_CREATE|os.O_RDWR, 0644)
+	require.NoError(t, err)
+	ok, err := repolock.TryLockFile(g, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := take(f, "alice", time.Now(), time.Hour)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("acquired while the guard was locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, repolock.UnlockFile(g))
+	require.NoError(t, g.Close())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not acquired after the guard was unlocked")
+	}
+}
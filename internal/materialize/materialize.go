@@ -0,0 +1,299 @@
+// Package materialize keeps read-only copies of snapshots beside a
+// worktree.
+//
+// A copy lets a tool compare the live payload with a past state side by
+// side, without restoring the snapshot and so without detaching the
+// worktree. Copies live under .jvs/materialized/<worktree>/<snapshot> and
+// expire after a TTL; expired copies are removed by the next Materialize
+// call or by 'jvs doctor --repair-runtime'.
+package materialize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// DefaultTTL is how long a copy is kept when no TTL is given.
+const DefaultTTL = time.Hour
+
+// Manager creates and expires materialized snapshot copies.
+type Manager struct {
+	repoRoot string
+	now      func() time.Time
+}
+
+// NewManager creates a new materialization manager.
+func NewManager(repoRoot string) *Manager {
+	return &Manager{repoRoot: repoRoot, now: time.Now}
+}
+
+// SetClock replaces the clock used to stamp and expire copies.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.now = now
+}
+
+// Dir returns the directory holding the copies made for a worktree.
+func (m *Manager) Dir(worktreeName string) string {
+	return filepath.Join(m.repoRoot, ".jvs", "materialized", worktreeName)
+}
+
+// Materialize returns a read-only copy of snapshot id for worktreeName,
+// kept for at least ttl (DefaultTTL if zero). An unexpired copy of the same
+// snapshot is reused, with its expiry extended if needed. clone copies the
+// snapshot directory to a new directory. The worktree itself is not
+// touched.
+func (m *Manager) Materialize(worktreeName string, id model.SnapshotID, ttl time.Duration, clone func(src, dst string) error) (*model.Materialization, error) {
+	if err := pathutil.ValidateName(worktreeName); err != nil {
+		return nil, err
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("materialize ttl must not be negative")
+	}
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	// Keeps GC from deleting the snapshot while it is copied
+	lock, err := repolock.NewManager(m.repoRoot).Acquire(model.LockShared, "materialize")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	if _, err := repo.LoadWorktreeConfig(m.repoRoot, worktreeName); err != nil {
+		return nil, fmt.Errorf("load worktree %s: %w", worktreeName, err)
+	}
+	desc, snapshotDir, err := snapshot.OpenPayload(m.repoRoot, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.Sweep(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: remove expired materializations: %v\n", err)
+	}
+
+	now := m.now().UTC()
+	mat, err := m.get(worktreeName, id)
+	if err != nil {
+		return nil, err
+	}
+	if mat != nil && !mat.Expired(now) {
+		if expires := now.Add(ttl); expires.After(mat.ExpiresAt) {
+			mat.ExpiresAt = expires
+			if err := m.write(mat); err != nil {
+				return nil, err
+			}
+		}
+		return mat, nil
+	}
+
+	// The record is written before the copy appears, so a copy is never
+	// left without one to expire it
+	mat = &model.Materialization{
+		WorktreeName: worktreeName,
+		SnapshotID:   id,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+		Path:         m.path(worktreeName, id),
+	}
+	if err := m.write(mat); err != nil {
+		return nil, err
+	}
+	if err := m.copy(desc, snapshotDir, mat.Path, clone); err != nil {
+		return nil, err
+	}
+	return mat, nil
+}
+
+// copy clones the snapshot into a temporary directory, renames it to dst
+// and makes it read-only.
+func (m *Manager) copy(desc *model.Descriptor, snapshotDir, dst string, clone func(src, dst string) error) error {
+	// Named so 'jvs doctor --repair-runtime' cleans it up after a crash
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), ".jvs-tmp-materialize-")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer removeAll(tmp)
+	payload := filepath.Join(tmp, "payload")
+	if err := clone(snapshotDir, payload); err != nil {
+		return fmt.Errorf("clone snapshot %s: %w", desc.SnapshotID, err)
+	}
+	if err := repo.RemoveReadyMarkers(payload); err != nil {
+		return err
+	}
+	if _, err := snapshot.DecryptPayload(m.repoRoot, desc, payload); err != nil {
+		return err
+	}
+	if desc.Compression != nil {
+		if _, err := compression.DecompressDirType(payload, compression.CompressionType(desc.Compression.Type)); err != nil {
+			return fmt.Errorf("decompress snapshot %s: %w", desc.SnapshotID, err)
+		}
+	}
+
+	if err := os.Rename(payload, dst); err != nil {
+		// A concurrent call for the same snapshot got there first
+		if _, statErr := os.Stat(dst); statErr == nil {
+			return nil
+		}
+		return fmt.Errorf("publish copy: %w", err)
+	}
+	// Only after the rename, which needs the directory to be writable
+	if err := makeReadOnly(dst); err != nil {
+		removeAll(dst)
+		return err
+	}
+	return nil
+}
+
+// Sweep removes expired copies of every worktree and returns how many it
+// removed.
+func (m *Manager) Sweep() (int, error) {
+	root := filepath.Join(m.repoRoot, ".jvs", "materialized")
+	worktrees, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read materialized directory: %w", err)
+	}
+	now := m.now()
+	removed := 0
+	var errs []error
+	for _, wt := range worktrees {
+		if !wt.IsDir() {
+			continue
+		}
+		records, err := filepath.Glob(filepath.Join(root, wt.Name(), "*.json"))
+		if err != nil {
+			return removed, err
+		}
+		for _, record := range records {
+			id := model.SnapshotID(strings.TrimSuffix(filepath.Base(record), ".json"))
+			mat, err := m.get(wt.Name(), id)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if mat == nil || !mat.Expired(now) {
+				continue
+			}
+			if err := removeAll(mat.Path); err != nil {
+				errs = append(errs, fmt.Errorf("remove %s: %w", mat.Path, err))
+				continue
+			}
+			if err := os.Remove(record); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+func (m *Manager) path(worktreeName string, id model.SnapshotID) string {
+	return filepath.Join(m.Dir(worktreeName), string(id))
+}
+
+func (m *Manager) recordPath(worktreeName string, id model.SnapshotID) string {
+	return m.path(worktreeName, id) + ".json"
+}
+
+// get returns the record of a copy, or nil if there is none. An unexpired
+// record whose copy is missing counts as none; an expired one is returned
+// so that Sweep removes it.
+func (m *Manager) get(worktreeName string, id model.SnapshotID) (*model.Materialization, error) {
+	data, err := os.ReadFile(m.recordPath(worktreeName, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read materialization: %w", err)
+	}
+	var mat model.Materialization
+	if err := json.Unmarshal(data, &mat); err != nil {
+		return nil, fmt.Errorf("parse materialization: %w", err)
+	}
+	mat.Path = m.path(worktreeName, id)
+	if _, err := os.Lstat(mat.Path); os.IsNotExist(err) && !mat.Expired(m.now()) {
+		return nil, nil
+	}
+	return &mat, nil
+}
+
+func (m *Manager) write(mat *model.Materialization) error {
+	stored := *mat
+	stored.Path = ""
+	data, err := json.MarshalIndent(&stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal materialization: %w", err)
+	}
+	if err := os.MkdirAll(m.Dir(mat.WorktreeName), 0755); err != nil {
+		return fmt.Errorf("create materialized directory: %w", err)
+	}
+	if err := fsutil.AtomicWrite(m.recordPath(mat.WorktreeName, mat.SnapshotID), data, 0644); err != nil {
+		return fmt.Errorf("write materialization: %w", err)
+	}
+	return nil
+}
+
+// makeReadOnly clears the write bits of every entry below dir.
+func makeReadOnly(dir string) error {
+	var dirs []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Cleared after the walk, as a read-only directory can still be
+			// read but its entries could not be changed
+			dirs = append(dirs, p)
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.Chmod(p, info.Mode().Perm()&^0222)
+	})
+	if err != nil {
+		return fmt.Errorf("make copy read-only: %w", err)
+	}
+	for _, p := range dirs {
+		info, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("make copy read-only: %w", err)
+		}
+		if err := os.Chmod(p, info.Mode().Perm()&^0222); err != nil {
+			return fmt.Errorf("make copy read-only: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeAll removes a read-only copy, restoring the owner's write bit on
+// its directories first.
+func removeAll(dir string) error {
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(p, 0700)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
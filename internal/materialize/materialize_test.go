@@ -0,0 +1,123 @@
+package materialize_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/materialize"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	// Copies are read-only; remove them before the temp dir is cleaned up
+	t.Cleanup(func() {
+		m := materialize.NewManager(dir)
+		m.SetClock(func() time.Time { return time.Now().Add(24 * 365 * time.Hour) })
+		m.Sweep()
+	})
+	return dir
+}
+
+func cloneCopy(src, dst string) error {
+	_, err := engine.NewEngine(model.EngineCopy).Clone(src, dst)
+	return err
+}
+
+func TestManager_Materialize(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data.txt"), []byte("v1"), 0644))
+	first, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "v1", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data.txt"), []byte("v2"), 0644))
+	_, err = snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "v2", nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	m := materialize.NewManager(repoPath)
+	m.SetClock(func() time.Time { return now })
+	mat, err := m.Materialize("main", first.SnapshotID, time.Minute, cloneCopy)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repoPath, ".jvs", "materialized", "main", string(first.SnapshotID)), mat.Path)
+	assert.Equal(t, now.Add(time.Minute).UTC(), mat.ExpiresAt)
+
+	data, err := os.ReadFile(filepath.Join(mat.Path, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+	assert.NoFileExists(t, filepath.Join(mat.Path, ".READY"))
+	info, err := os.Stat(filepath.Join(mat.Path, "data.txt"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0222, "copy is read-only")
+
+	// The worktree is left alone
+	cfg, err := repo.LoadWorktreeConfig(repoPath, "main")
+	require.NoError(t, err)
+	assert.False(t, cfg.IsDetached())
+	data, err = os.ReadFile(filepath.Join(mainPath, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	// A second call reuses the copy and extends its expiry
+	now = now.Add(30 * time.Second)
+	again, err := m.Materialize("main", first.SnapshotID, time.Minute, func(src, dst string) error {
+		t.Fatal("copy is reused")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, mat.Path, again.Path)
+	assert.Equal(t, mat.CreatedAt, again.CreatedAt)
+	assert.Equal(t, now.Add(time.Minute).UTC(), again.ExpiresAt)
+
+	// Expired copies are swept
+	now = now.Add(2 * time.Minute)
+	removed, err := m.Sweep()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NoDirExists(t, mat.Path)
+	assert.NoFileExists(t, mat.Path+".json")
+}
+
+func TestManager_MaterializeCompressed(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "data.txt"), []byte("hello world"), 0644))
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompression(compression.LevelDefault)
+	desc, err := creator.Create("main", "compressed", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Compression)
+
+	mat, err := materialize.NewManager(repoPath).Materialize("main", desc.SnapshotID, 0, cloneCopy)
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(mat.Path, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.WithinDuration(t, time.Now().Add(materialize.DefaultTTL), mat.ExpiresAt, time.Minute)
+}
+
+func TestManager_MaterializeErrors(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "", nil)
+	require.NoError(t, err)
+	m := materialize.NewManager(repoPath)
+
+	_, err = m.Materialize("missing", desc.SnapshotID, 0, cloneCopy)
+	assert.Error(t, err)
+	_, err = m.Materialize("main", "1700000000000-deadbeef", 0, cloneCopy)
+	assert.Error(t, err)
+	_, err = m.Materialize("../main", desc.SnapshotID, 0, cloneCopy)
+	assert.Error(t, err)
+	_, err = m.Materialize("main", desc.SnapshotID, -time.Second, cloneCopy)
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+// Package metacache provides a bounded LRU cache whose concurrent misses for
+// the same key share a single load, for repository metadata that is read far
+// more often than it changes.
+package metacache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Stats counts cache activity since creation.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Shared    uint64 `json:"shared"` // misses that waited on another caller's load
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// Cache is a concurrency-safe LRU cache with single-flight loading.
+// The zero value is not usable; use New.
+type Cache[K comparable, V any] struct {
+	capacity int
+
+	mu       sync.Mutex
+	order    *list.List // front is most recently used
+	items    map[K]*list.Element
+	inflight map[K]*call[V]
+	stats    Stats
+}
+
+type item[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	stale bool // invalidated while loading; the result is not cached
+}
+
+// New returns a cache holding at most capacity entries. A capacity below
+// one is treated as one.
+func New[K comparable, V any](capacity int) *Cache[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Cache[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+		inflight: make(map[K]*call[V]),
+	}
+}
+
+// Get returns the cached value for key, calling load on a miss. Concurrent
+// misses for the same key wait for one load. Errors are returned to every
+// waiter and not cached. A value loaded while the key was invalidated is
+// returned to its callers but not cached.
+func (c *Cache[K, V]) Get(key K, load func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		c.stats.Hits++
+		v := el.Value.(*item[K, V]).value
+		c.mu.Unlock()
+		return v, nil
+	}
+	c.stats.Misses++
+	if cl, ok := c.inflight[key]; ok {
+		c.stats.Shared++
+		c.mu.Unlock()
+		<-cl.done
+		return cl.value, cl.err
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.mu.Unlock()
+
+	cl.value, cl.err = load()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if cl.err == nil && !cl.stale {
+		c.add(key, cl.value)
+	}
+	c.mu.Unlock()
+	close(cl.done)
+	return cl.value, cl.err
+}
+
+func (c *Cache[K, V]) add(key K, value V) {
+	if el, ok := c.items[key]; ok {
+		el.Value.(*item[K, V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&item[K, V]{key: key, value: value})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*item[K, V]).key)
+		c.stats.Evictions++
+	}
+}
+
+// Invalidate drops key from the cache. A load for key already in flight
+// completes but its result is not cached.
+func (c *Cache[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+	if cl, ok := c.inflight[key]; ok {
+		cl.stale = true
+	}
+}
+
+// Purge drops every entry.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[K]*list.Element)
+	for _, cl := range c.inflight {
+		cl.stale = true
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.order.Len()
+	return s
+}
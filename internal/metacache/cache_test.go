@@ -0,0 +1,107 @@
+package metacache_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/metacache"
+)
+
+func TestCache_HitMissEvict(t *testing.T) {
+	c := metacache.New[string, int](2)
+	loads := 0
+	load := func(v int) func() (int, error) {
+		return func() (int, error) {
+			loads++
+			return v, nil
+		}
+	}
+
+	v, err := c.Get("a", load(1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	v, _ = c.Get("a", load(99))
+	assert.Equal(t, 1, v, "served from cache")
+	c.Get("b", load(2))
+	c.Get("a", load(99)) // a is now most recently used
+	c.Get("c", load(3))  // evicts b
+
+	v, _ = c.Get("a", load(99))
+	assert.Equal(t, 1, v)
+	v, _ = c.Get("b", load(20))
+	assert.Equal(t, 20, v, "b was evicted and reloaded")
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(3), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.Equal(t, uint64(2), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, 4, loads)
+}
+
+func TestCache_ErrorsNotCached(t *testing.T) {
+	c := metacache.New[string, int](4)
+	_, err := c.Get("a", func() (int, error) { return 0, errors.New("boom") })
+	assert.Error(t, err)
+	v, err := c.Get("a", func() (int, error) { return 7, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 7, v)
+}
+
+func TestCache_SingleFlight(t *testing.T) {
+	c := metacache.New[string, int](4)
+	release := make(chan struct{})
+	var loads atomic.Int32
+
+	const callers = 8
+	var started, wg sync.WaitGroup
+	started.Add(callers)
+	wg.Add(callers)
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			results[i], _ = c.Get("k", func() (int, error) {
+				loads.Add(1)
+				<-release
+				return 42, nil
+			})
+		}(i)
+	}
+	started.Wait()
+	// Let every caller reach Get before the load finishes
+	for c.Stats().Misses < callers {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for _, r := range results {
+		assert.Equal(t, 42, r)
+	}
+	assert.Equal(t, uint64(callers-1), c.Stats().Shared)
+}
+
+func TestCache_InvalidateDuringLoad(t *testing.T) {
+	c := metacache.New[string, int](4)
+	v, err := c.Get("k", func() (int, error) {
+		c.Invalidate("k") // the value being loaded is already stale
+		return 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.Zero(t, c.Stats().Entries)
+
+	c.Get("k", func() (int, error) { return 2, nil })
+	c.Purge()
+	v, _ = c.Get("k", func() (int, error) { return 3, nil })
+	assert.Equal(t, 3, v)
+}
@@ -0,0 +1,64 @@
+// Package metrics defines the Prometheus metrics exported by the jvs library
+// and by 'jvs serve --metrics'.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes every metric name.
+const Namespace = "jvs"
+
+// RestoreBuckets are the restore duration histogram buckets, in seconds,
+// from a small worktree restored with reflinks to a large full copy.
+var RestoreBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Metrics holds the JVS counters and histograms.
+type Metrics struct {
+	SnapshotsCreated prometheus.Counter
+	RestoreDuration  prometheus.Histogram
+	BytesCopied      *prometheus.CounterVec // by operation
+	GCDeletions      prometheus.Counter
+	VerifyFailures   prometheus.Counter
+}
+
+// New creates the metrics and registers them with reg.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		SnapshotsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "snapshots_created_total",
+			Help:      "Snapshots created.",
+		}),
+		RestoreDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "restore_duration_seconds",
+			Help:      "Time taken by successful restores.",
+			Buckets:   RestoreBuckets,
+		}),
+		BytesCopied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "bytes_copied_total",
+			Help:      "Payload bytes written by snapshots, restores and forks; data hard-linked instead of copied is not counted.",
+		}, []string{"operation"}),
+		GCDeletions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "gc_deleted_snapshots_total",
+			Help:      "Snapshots deleted by garbage collection.",
+		}),
+		VerifyFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "verify_failures_total",
+			Help:      "Snapshots that failed verification.",
+		}),
+	}
+	reg.MustRegister(m.SnapshotsCreated, m.RestoreDuration, m.BytesCopied, m.GCDeletions, m.VerifyFailures)
+	return m
+}
+
+// Operation labels of BytesCopied.
+const (
+	OpSnapshot = "snapshot"
+	OpRestore  = "restore"
+	OpFork     = "fork"
+)
@@ -0,0 +1,281 @@
+//go:build !windows
+
+package mount
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// cacheTimeout is how long the kernel may cache entries and attributes.
+// Payloads never change; listings may, as snapshots come and go.
+const cacheTimeout = time.Second
+
+// Mount mounts the snapshots of the repository at repoRoot on mountpoint.
+// The filesystem is served in the background until it is unmounted, with
+// the returned server's Unmount or externally (fusermount -u).
+func Mount(repoRoot, mountpoint string, opts Options) (Server, error) {
+	timeout := cacheTimeout
+	server, err := fs.Mount(mountpoint, &rootNode{repoRoot: repoRoot}, &fs.Options{
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+		MountOptions: fuse.MountOptions{
+			FsName:     "jvs",
+			Name:       "jvs",
+			Options:    []string{"ro"},
+			AllowOther: opts.AllowOther,
+			Debug:      opts.Debug,
+			// Lets root mount without fusermount, e.g. in containers
+			DirectMount: true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// rootNode lists the worktrees that have snapshots, including worktrees
+// that have since been removed.
+type rootNode struct {
+	fs.Inode
+	repoRoot string
+}
+
+var (
+	_ fs.NodeReaddirer = (*rootNode)(nil)
+	_ fs.NodeLookuper  = (*rootNode)(nil)
+)
+
+// worktrees returns the names of the worktrees that have snapshots.
+func (n *rootNode) worktrees() ([]string, error) {
+	all, err := snapshot.ListAll(n.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, desc := range all {
+		if !slices.Contains(names, desc.WorktreeName) {
+			names = append(names, desc.WorktreeName)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (n *rootNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	names, err := n.worktrees()
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	entries := make([]fuse.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = fuse.DirEntry{Name: name, Mode: fuse.S_IFDIR}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *rootNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	names, err := n.worktrees()
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	if !slices.Contains(names, name) {
+		return nil, syscall.ENOENT
+	}
+	out.Mode = fuse.S_IFDIR | 0555
+	return n.NewInode(ctx, &worktreeNode{repoRoot: n.repoRoot, name: name}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+// worktreeNode lists the snapshots of a worktree.
+type worktreeNode struct {
+	fs.Inode
+	repoRoot string
+	name     string
+}
+
+var (
+	_ fs.NodeReaddirer = (*worktreeNode)(nil)
+	_ fs.NodeLookuper  = (*worktreeNode)(nil)
+)
+
+func (n *worktreeNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	all, err := snapshot.ListAll(n.repoRoot)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	var entries []fuse.DirEntry
+	for _, desc := range all {
+		if desc.WorktreeName == n.name {
+			entries = append(entries, fuse.DirEntry{Name: string(desc.SnapshotID), Mode: fuse.S_IFDIR})
+		}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *worktreeNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if pathutil.ValidateName(name) != nil {
+		return nil, syscall.ENOENT
+	}
+	desc, payload, err := snapshot.OpenPayload(n.repoRoot, model.SnapshotID(name))
+	if err != nil || desc.WorktreeName != n.name {
+		return nil, syscall.ENOENT
+	}
+	if desc.Encryption != nil {
+		return nil, syscall.EACCES
+	}
+	node := &payloadNode{path: payload, root: true}
+	if desc.Compression != nil {
+		node.ext = compression.Extension(compression.CompressionType(desc.Compression.Type))
+	}
+	return node.newInode(ctx, &n.Inode, out)
+}
+
+// payloadNode is a file, directory or symlink in a snapshot payload.
+type payloadNode struct {
+	fs.Inode
+	// path is the entry's path on disk; for a compressed file, it has the
+	// compression suffix that the entry's name lacks.
+	path string
+	// ext is the suffix of compressed files in the snapshot, or "".
+	ext  string
+	root bool
+}
+
+var (
+	_ fs.NodeGetattrer  = (*payloadNode)(nil)
+	_ fs.NodeLookuper   = (*payloadNode)(nil)
+	_ fs.NodeReaddirer  = (*payloadNode)(nil)
+	_ fs.NodeOpener     = (*payloadNode)(nil)
+	_ fs.NodeReadlinker = (*payloadNode)(nil)
+)
+
+// newInode creates the inode of n below parent and fills out with its
+// attributes.
+func (n *payloadNode) newInode(ctx context.Context, parent *fs.Inode, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	info, err := os.Lstat(n.path)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	if errno := n.fillAttr(info, &out.Attr); errno != 0 {
+		return nil, errno
+	}
+	return parent.NewInode(ctx, n, fs.StableAttr{Mode: out.Attr.Mode & syscall.S_IFMT, Ino: out.Attr.Ino}), 0
+}
+
+// fillAttr sets out from info, read-only and with the uncompressed size.
+func (n *payloadNode) fillAttr(info os.FileInfo, out *fuse.Attr) syscall.Errno {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		out.FromStat(st)
+	}
+	out.Mode &^= 0222
+	if info.Mode().IsRegular() {
+		size, err := snapshot.LogicalSize(n.path, info, n.ext)
+		if err != nil {
+			return fs.ToErrno(err)
+		}
+		out.Size = uint64(size)
+	}
+	return 0
+}
+
+func (n *payloadNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	info, err := os.Lstat(n.path)
+	if err != nil {
+		return fs.ToErrno(err)
+	}
+	return n.fillAttr(info, &out.Attr)
+}
+
+func (n *payloadNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, syscall.ENOENT
+	}
+	path := filepath.Join(n.path, name)
+	if n.ext != "" {
+		if info, err := os.Lstat(path + n.ext); err == nil && info.Mode().IsRegular() {
+			path += n.ext
+		}
+	}
+	child := &payloadNode{path: path, ext: n.ext}
+	return child.newInode(ctx, &n.Inode, out)
+}
+
+func (n *payloadNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	dirEntries, err := os.ReadDir(n.path)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		name := d.Name()
+		if n.hidden(name) {
+			continue
+		}
+		if n.ext != "" && d.Type().IsRegular() {
+			name = strings.TrimSuffix(name, n.ext)
+		}
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: uint32(d.Type())})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+// hidden reports whether name is a control file of the payload, such as the
+// .READY marker, rather than part of the snapshot.
+func (n *payloadNode) hidden(name string) bool {
+	return n.root && (name == ".READY" || name == ".READY"+n.ext)
+}
+
+func (n *payloadNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_TRUNC|syscall.O_APPEND) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	if n.ext != "" && strings.HasSuffix(n.path, n.ext) {
+		data, err := compression.ReadFile(n.path)
+		if err != nil {
+			return nil, 0, syscall.EIO
+		}
+		return &bytesHandle{data: data}, fuse.FOPEN_KEEP_CACHE, 0
+	}
+	fd, err := syscall.Open(n.path, syscall.O_RDONLY, 0)
+	if err != nil {
+		return nil, 0, fs.ToErrno(err)
+	}
+	return fs.NewLoopbackFile(fd), fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (n *payloadNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	target, err := os.Readlink(n.path)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	return []byte(target), 0
+}
+
+// bytesHandle serves reads of a decompressed file from memory.
+type bytesHandle struct {
+	data []byte
+}
+
+var _ fs.FileReader = (*bytesHandle)(nil)
+
+func (h *bytesHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	if off >= int64(len(h.data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := min(off+int64(len(dest)), int64(len(h.data)))
+	return fuse.ReadResultData(h.data[off:end]), 0
+}
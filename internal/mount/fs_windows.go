@@ -0,0 +1,8 @@
+package mount
+
+import "errors"
+
+// Mount is not supported on Windows, which has no FUSE.
+func Mount(repoRoot, mountpoint string, opts Options) (Server, error) {
+	return nil, errors.New("mounting snapshots is not supported on Windows")
+}
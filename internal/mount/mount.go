@@ -0,0 +1,26 @@
+// Package mount exposes a repository's snapshots as a read-only FUSE
+// filesystem, laid out as /<worktree>/<snapshot-id>/<payload...>.
+//
+// The tree is read from the repository on each lookup, so snapshots
+// created while mounted appear and snapshots deleted by GC disappear. Only
+// READY snapshots are listed. Files of compressed snapshots are shown under
+// their original names and decompressed in memory when opened. Encrypted
+// snapshots are listed but cannot be entered.
+package mount
+
+// Options configures a mount.
+type Options struct {
+	// AllowOther lets users other than the one mounting access the
+	// filesystem; it needs user_allow_other in /etc/fuse.conf.
+	AllowOther bool
+	// Debug logs every FUSE request.
+	Debug bool
+}
+
+// Server is a mounted filesystem.
+type Server interface {
+	// Unmount unmounts the filesystem.
+	Unmount() error
+	// Wait blocks until the filesystem is unmounted.
+	Wait()
+}
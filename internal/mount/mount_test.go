@@ -0,0 +1,110 @@
+//go:build !windows
+
+package mount_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/mount"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// mountRepo mounts repoPath's snapshots, skipping the test where FUSE is
+// not available.
+func mountRepo(t *testing.T, repoPath string) string {
+	t.Helper()
+	mnt := t.TempDir()
+	server, err := mount.Mount(repoPath, mnt, mount.Options{})
+	if err != nil {
+		t.Skipf("FUSE not available: %v", err)
+	}
+	t.Cleanup(func() { server.Unmount() })
+	return mnt
+}
+
+func TestMount_BrowseSnapshots(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+	payload := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(payload, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(payload, "dir", "a.txt"), []byte("v1"), 0644))
+	require.NoError(t, os.Symlink("dir/a.txt", filepath.Join(payload, "link")))
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	first, err := creator.Create("main", "first", nil)
+	require.NoError(t, err)
+
+	mnt := mountRepo(t, repoPath)
+
+	entries, err := os.ReadDir(mnt)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "main", entries[0].Name())
+
+	snapDir := filepath.Join(mnt, "main", string(first.SnapshotID))
+	entries, err = os.ReadDir(snapDir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"dir", "link"}, names, ".READY is hidden")
+
+	content, err := os.ReadFile(filepath.Join(snapDir, "dir", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+	target, err := os.Readlink(filepath.Join(snapDir, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "dir/a.txt", target)
+
+	err = os.WriteFile(filepath.Join(snapDir, "dir", "a.txt"), []byte("v2"), 0644)
+	assert.Error(t, err, "the mount is read-only")
+
+	// Snapshots created while mounted appear
+	require.NoError(t, os.WriteFile(filepath.Join(payload, "dir", "a.txt"), []byte("v2"), 0644))
+	second, err := creator.Create("main", "second", nil)
+	require.NoError(t, err)
+	content, err = os.ReadFile(filepath.Join(mnt, "main", string(second.SnapshotID), "dir", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+
+	_, err = os.Stat(filepath.Join(mnt, "main", "no-such-snapshot"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(mnt, "other"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMount_CompressedSnapshot(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+	data := []byte(strings.Repeat("compressible ", 1000))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "data.txt"), data, 0644))
+	creator := snapshot.NewCreatorWithCompression(repoPath, model.EngineCopy, compression.NewCompressorWithType(compression.TypeZstd, compression.LevelDefault))
+	desc, err := creator.Create("main", "compressed", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Compression)
+
+	mnt := mountRepo(t, repoPath)
+	snapDir := filepath.Join(mnt, "main", string(desc.SnapshotID))
+	entries, err := os.ReadDir(snapDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "data.txt", entries[0].Name())
+
+	info, err := os.Stat(filepath.Join(snapDir, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size())
+	content, err := os.ReadFile(filepath.Join(snapDir, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+}
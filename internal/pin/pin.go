@@ -0,0 +1,199 @@
+// Package pin manages pins, which protect snapshots from garbage
+// collection.
+//
+// A pin is a JSON file in .jvs/pins. Pins made by Manager are named after
+// their snapshot, so pinning a snapshot again replaces its pin; pin files
+// written by hand may have any name and are listed and removed alike.
+package pin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Dir returns the directory holding a repository's pins.
+func Dir(repoRoot string) string {
+	return filepath.Join(repoRoot, repo.JVSDirName, "pins")
+}
+
+// Manager reads and writes pins.
+type Manager struct {
+	repoRoot    string
+	auditLogger *audit.FileAppender
+	now         func() time.Time
+}
+
+// NewManager creates a new pin manager.
+func NewManager(repoRoot string) *Manager {
+	return &Manager{
+		repoRoot:    repoRoot,
+		auditLogger: audit.NewFileAppender(audit.LogPath(repoRoot)),
+		now:         time.Now,
+	}
+}
+
+// SetCorrelationID sets the correlation ID recorded in the audit records
+// of the manager's operations. Empty, the default, uses the one set through
+// audit.CorrelationEnvVar, if any.
+func (m *Manager) SetCorrelationID(id string) {
+	m.auditLogger.SetCorrelationID(id)
+}
+
+// SetAuthor sets who the audit records of the manager's operations name as
+// their author. Empty, the default, uses identity.Author.
+func (m *Manager) SetAuthor(author string) {
+	m.auditLogger.SetAuthor(author)
+}
+
+// SetClock replaces the clock used to stamp pins.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.now = now
+}
+
+// Pin protects a snapshot from GC, for ttl if it is positive or else until
+// it is unpinned, and audits it as snapshot_pin. The pin is written under
+// the repository lock held shared, so a GC run either deletes the snapshot
+// first, failing the pin, or sees the pin when it revalidates its plan.
+func (m *Manager) Pin(snapshotID model.SnapshotID, reason string, ttl time.Duration) (*model.Pin, error) {
+	if ttl < 0 {
+		return nil, fmt.Errorf("pin ttl must be non-negative")
+	}
+	lock, err := repolock.NewManager(m.repoRoot).Acquire(model.LockShared, "pin")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	desc, err := snapshot.LoadDescriptor(m.repoRoot, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", snapshotID, err)
+	}
+
+	now := m.now().UTC()
+	p := &model.Pin{SnapshotID: snapshotID, PinnedAt: now, Reason: reason}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		p.ExpiresAt = &expiresAt
+	}
+	if err := os.MkdirAll(Dir(m.repoRoot), 0755); err != nil {
+		return nil, fmt.Errorf("create pins directory: %w", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.AtomicWrite(filepath.Join(Dir(m.repoRoot), string(snapshotID)+".json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("write pin: %w", err)
+	}
+
+	details := map[string]any{}
+	if reason != "" {
+		details["reason"] = reason
+	}
+	if p.ExpiresAt != nil {
+		details["expires_at"] = *p.ExpiresAt
+	}
+	m.auditLogger.Append(model.EventTypeSnapshotPin, desc.WorktreeName, snapshotID, details)
+	return p, nil
+}
+
+// Unpin removes every pin of a snapshot, expired ones included, and audits
+// it as snapshot_unpin. It returns the number of pins removed, zero if the
+// snapshot had none.
+func (m *Manager) Unpin(snapshotID model.SnapshotID) (int, error) {
+	lock, err := repolock.NewManager(m.repoRoot).Acquire(model.LockShared, "unpin")
+	if err != nil {
+		return 0, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	paths, err := m.paths()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, path := range paths {
+		p, err := readPin(path)
+		if err != nil || p.SnapshotID != snapshotID {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("remove pin: %w", err)
+		}
+		removed++
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	m.auditLogger.Append(model.EventTypeSnapshotUnpin, "", snapshotID, map[string]any{
+		"pins_removed": removed,
+	})
+	return removed, nil
+}
+
+// List returns all pins, expired ones included, oldest first. Pins that
+// cannot be read are skipped with a warning; 'jvs fsck' reports them.
+func (m *Manager) List() ([]*model.Pin, error) {
+	paths, err := m.paths()
+	if err != nil {
+		return nil, err
+	}
+	var pins []*model.Pin
+	for _, path := range paths {
+		p, err := readPin(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping pin %s: %v\n", filepath.Base(path), err)
+			continue
+		}
+		pins = append(pins, p)
+	}
+	slices.SortStableFunc(pins, func(a, b *model.Pin) int {
+		if c := a.PinnedAt.Compare(b.PinnedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.SnapshotID), string(b.SnapshotID))
+	})
+	return pins, nil
+}
+
+// paths returns the pin files of the repository.
+func (m *Manager) paths() ([]string, error) {
+	entries, err := os.ReadDir(Dir(m.repoRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read pins directory: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".json") {
+			paths = append(paths, filepath.Join(Dir(m.repoRoot), entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+func readPin(path string) (*model.Pin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p model.Pin
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse pin: %w", err)
+	}
+	return &p, nil
+}
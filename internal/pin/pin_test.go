@@ -0,0 +1,98 @@
+package pin_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/pin"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupTestRepo(t *testing.T) (string, model.SnapshotID) {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main", "file.txt"), []byte("content"), 0644))
+	desc, err := snapshot.NewCreator(dir, model.EngineCopy).Create("main", "test", nil)
+	require.NoError(t, err)
+	return dir, desc.SnapshotID
+}
+
+func TestManager_PinAndList(t *testing.T) {
+	repoPath, snapshotID := setupTestRepo(t)
+	mgr := pin.NewManager(repoPath)
+
+	p, err := mgr.Pin(snapshotID, "release build", 0)
+	require.NoError(t, err)
+	assert.Equal(t, snapshotID, p.SnapshotID)
+	assert.Nil(t, p.ExpiresAt)
+
+	// Pinning again replaces the pin
+	p, err = mgr.Pin(snapshotID, "audit hold", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, p.ExpiresAt)
+	assert.False(t, p.Expired(time.Now()))
+	assert.True(t, p.Expired(p.ExpiresAt.Add(time.Second)))
+
+	pins, err := mgr.List()
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, "audit hold", pins[0].Reason)
+
+	// GC honours the pin
+	plan, err := gc.NewCollector(repoPath).PlanWithPolicy(model.RetentionPolicy{})
+	require.NoError(t, err)
+	assert.Contains(t, plan.ProtectedSet, snapshotID)
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeSnapshotPin})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestManager_PinMissingSnapshot(t *testing.T) {
+	repoPath, _ := setupTestRepo(t)
+	_, err := pin.NewManager(repoPath).Pin("1700000000000-deadbeef", "", 0)
+	assert.Error(t, err)
+}
+
+func TestManager_Unpin(t *testing.T) {
+	repoPath, snapshotID := setupTestRepo(t)
+	mgr := pin.NewManager(repoPath)
+	_, err := mgr.Pin(snapshotID, "", 0)
+	require.NoError(t, err)
+	// A hand-written pin of the same snapshot
+	require.NoError(t, os.WriteFile(filepath.Join(pin.Dir(repoPath), "release.json"),
+		[]byte(`{"snapshot_id":"`+string(snapshotID)+`","pinned_at":"2024-01-01T00:00:00Z"}`), 0644))
+
+	removed, err := mgr.Unpin(snapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	pins, err := mgr.List()
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+
+	removed, err = mgr.Unpin(snapshotID)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestManager_ListSkipsCorrupt(t *testing.T) {
+	repoPath, snapshotID := setupTestRepo(t)
+	mgr := pin.NewManager(repoPath)
+	_, err := mgr.Pin(snapshotID, "", 0)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(pin.Dir(repoPath), "bad.json"), []byte("{"), 0644))
+
+	pins, err := mgr.List()
+	require.NoError(t, err)
+	assert.Len(t, pins, 1)
+}
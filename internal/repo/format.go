@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jvs-project/jvs/internal/version"
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+// MinFormatVersion is the oldest repository format version this build
+// opens.
+const MinFormatVersion = 1
+
+// UpgradeURL is where newer jvs releases are published.
+const UpgradeURL = "https://github.com/jvs-project/jvs/releases/latest"
+
+// IncompatibleFormatError reports a repository whose format version this
+// build cannot open, and how to open it.
+type IncompatibleFormatError struct {
+	Root          string
+	FormatVersion int
+}
+
+func (e *IncompatibleFormatError) Error() string {
+	msg := fmt.Sprintf("%s: repository %s has format version %d, but jvs %s supports format versions %d to %d",
+		errclass.ErrFormatUnsupported.Code, e.Root, e.FormatVersion, version.String(), MinFormatVersion, FormatVersion)
+	if e.FormatVersion > FormatVersion {
+		return msg + "; it was created by a newer jvs: upgrade jvs (" + UpgradeURL + ") to open it"
+	}
+	return msg + "; it was created by an older jvs: open it with a jvs release that supports both formats to migrate it"
+}
+
+// Is reports whether target is errclass.ErrFormatUnsupported.
+func (e *IncompatibleFormatError) Is(target error) bool {
+	return errors.Is(errclass.ErrFormatUnsupported, target)
+}
+
+// ReadFormatVersion returns the format version of the repository at
+// repoRoot.
+func ReadFormatVersion(repoRoot string) (int, error) {
+	return readFormatVersion(filepath.Join(repoRoot, JVSDirName))
+}
+
+// checkFormatVersion fails with an IncompatibleFormatError if this build
+// cannot open a repository of the given format version.
+func checkFormatVersion(root string, version int) error {
+	if version < MinFormatVersion || version > FormatVersion {
+		return &IncompatibleFormatError{Root: root, FormatVersion: version}
+	}
+	return nil
+}
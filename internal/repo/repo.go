@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -103,32 +104,42 @@ func Init(path string, name string) (*Repo, error) {
 }
 
 // Discover walks up from cwd to find the repo root (directory containing .jvs/).
+// It fails with an IncompatibleFormatError if the repository's format version
+// is not supported by this build.
 func Discover(cwd string) (*Repo, error) {
+	path, err := FindRoot(cwd)
+	if err != nil {
+		return nil, err
+	}
+	jvsDir := filepath.Join(path, JVSDirName)
+	version, err := readFormatVersion(jvsDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFormatVersion(path, version); err != nil {
+		return nil, err
+	}
+	repoID, _ := readRepoID(jvsDir)
+	return &Repo{
+		Root:          path,
+		FormatVersion: version,
+		RepoID:        repoID,
+	}, nil
+}
+
+// FindRoot walks up from cwd to the nearest directory containing .jvs/,
+// without checking the repository's format.
+func FindRoot(cwd string) (string, error) {
 	path := cwd
 	for {
-		jvsDir := filepath.Join(path, JVSDirName)
-		if info, err := os.Stat(jvsDir); err == nil && info.IsDir() {
-			// Found .jvs/, read format_version
-			version, err := readFormatVersion(jvsDir)
-			if err != nil {
-				return nil, err
-			}
-			if version > FormatVersion {
-				return nil, errclass.ErrFormatUnsupported.WithMessagef(
-					"format version %d > supported %d", version, FormatVersion)
-			}
-			repoID, _ := readRepoID(jvsDir)
-			return &Repo{
-				Root:          path,
-				FormatVersion: version,
-				RepoID:        repoID,
-			}, nil
+		if info, err := os.Stat(filepath.Join(path, JVSDirName)); err == nil && info.IsDir() {
+			return path, nil
 		}
 
 		parent := filepath.Dir(path)
 		if parent == path {
 			// Reached root without finding .jvs/
-			return nil, fmt.Errorf("no JVS repository found (no .jvs/ in parent directories)")
+			return "", errclass.ErrNotARepo.WithMessage("no JVS repository found (no .jvs/ in parent directories)")
 		}
 		path = parent
 	}
@@ -181,7 +192,7 @@ func WriteWorktreeConfig(repoRoot, name string, cfg *model.WorktreeConfig) error
 	if err != nil {
 		return fmt.Errorf("marshal worktree config: %w", err)
 	}
-	return fsutil.AtomicWrite(path, data, 0644)
+	return fsutil.WriteFileRetry(path, data, 0644)
 }
 
 // LoadWorktreeConfig loads a worktree config.
@@ -189,6 +200,10 @@ func LoadWorktreeConfig(repoRoot, name string) (*model.WorktreeConfig, error) {
 	path := WorktreeConfigPath(repoRoot, name)
 	data, err := os.ReadFile(path)
 	if err != nil {
+		if os.IsNotExist(err) {
+			// Still matches fs.ErrNotExist, as callers used to check
+			return nil, fmt.Errorf("%w: %w", errclass.ErrWorktreeNotFound.WithMessagef("worktree %s not found", name), err)
+		}
 		return nil, fmt.Errorf("read worktree config: %w", err)
 	}
 	var cfg model.WorktreeConfig
@@ -206,6 +221,102 @@ func WorktreePayloadPath(repoRoot, name string) string {
 	return filepath.Join(repoRoot, "worktrees", name)
 }
 
+// RestoreIntentPrefix starts the intent file names of in-flight restores;
+// other intents are named after the snapshot being created.
+const RestoreIntentPrefix = "restore-"
+
+// IntentsDir returns the directory holding intent records.
+func IntentsDir(repoRoot string) string {
+	return filepath.Join(repoRoot, JVSDirName, "intents")
+}
+
+// ListIntents returns the intent records of in-flight operations, ordered by
+// start time. Records removed or rewritten while listing are skipped.
+func ListIntents(repoRoot string) ([]*model.IntentRecord, error) {
+	entries, err := os.ReadDir(IntentsDir(repoRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read intents: %w", err)
+	}
+	var intents []*model.IntentRecord
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(IntentsDir(repoRoot), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var intent model.IntentRecord
+		if err := json.Unmarshal(data, &intent); err != nil {
+			continue
+		}
+		intents = append(intents, &intent)
+	}
+	sort.Slice(intents, func(i, j int) bool {
+		return intents[i].StartedAt.Before(intents[j].StartedAt)
+	})
+	return intents, nil
+}
+
+// SnapshotPath returns the payload directory of a snapshot.
+func SnapshotPath(repoRoot string, id model.SnapshotID) string {
+	return filepath.Join(repoRoot, JVSDirName, "snapshots", string(id))
+}
+
+// MetadataHistoryPath returns the file holding a snapshot's metadata
+// history, one model.MetadataChange per line.
+func MetadataHistoryPath(repoRoot string, id model.SnapshotID) string {
+	return filepath.Join(repoRoot, JVSDirName, "metadata-history", string(id)+".jsonl")
+}
+
+// ManifestPath returns the file holding a snapshot's per-file manifest,
+// compressed with zstd.
+func ManifestPath(repoRoot string, id model.SnapshotID) string {
+	return filepath.Join(repoRoot, JVSDirName, "manifests", string(id)+".jsonl.zst")
+}
+
+// LegacyManifestPath returns the file holding a snapshot's manifest written
+// as a plain model.Manifest, before manifests were compressed.
+func LegacyManifestPath(repoRoot string, id model.SnapshotID) string {
+	return filepath.Join(repoRoot, JVSDirName, "manifests", string(id)+".json")
+}
+
+// readyMarkers are the names of a snapshot's .READY marker; compressed
+// snapshots keep it as .READY.gz or .READY.zst.
+var readyMarkers = []string{".READY", ".READY.gz", ".READY.zst"}
+
+// CheckSnapshotReady returns errclass.ErrSnapshotNotReady unless the snapshot
+// directory carries its .READY marker (.READY.gz or .READY.zst in compressed
+// snapshots). A snapshot without the marker was never published and must not
+// be read.
+func CheckSnapshotReady(repoRoot string, id model.SnapshotID) error {
+	dir := SnapshotPath(repoRoot, id)
+	for _, name := range readyMarkers {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if err == nil {
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("check ready marker: %w", err)
+		}
+	}
+	return errclass.ErrSnapshotNotReady.WithMessagef("snapshot %s has no .READY marker", id)
+}
+
+// RemoveReadyMarkers deletes the .READY marker from a payload cloned out of
+// a snapshot; it is control-plane metadata, not worktree content.
+func RemoveReadyMarkers(payloadDir string) error {
+	for _, name := range readyMarkers {
+		if err := os.Remove(filepath.Join(payloadDir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove ready marker: %w", err)
+		}
+	}
+	return nil
+}
+
 func readFormatVersion(jvsDir string) (int, error) {
 	data, err := os.ReadFile(filepath.Join(jvsDir, FormatVersionFile))
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -227,7 +228,18 @@ func TestDiscover_WrongFormatVersion(t *testing.T) {
 	require.NoError(t, err)
 
 	_, err = repo.Discover(repoPath)
-	assert.Error(t, err)
+	var incompatible *repo.IncompatibleFormatError
+	require.ErrorAs(t, err, &incompatible)
+	assert.Equal(t, 999, incompatible.FormatVersion)
+	assert.ErrorIs(t, err, errclass.ErrFormatUnsupported)
+
+	// The root is still found, to report the repository's format
+	root, err := repo.FindRoot(filepath.Join(repoPath, "main"))
+	require.NoError(t, err)
+	assert.Equal(t, repoPath, root)
+	version, err := repo.ReadFormatVersion(root)
+	require.NoError(t, err)
+	assert.Equal(t, 999, version)
 }
 
 func TestDiscover_MissingFormatVersion(t *testing.T) {
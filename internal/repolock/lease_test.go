@@ -0,0 +1,171 @@
+package repolock_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func newLeaseManager(repoPath string, maxWait, ttl time.Duration) *repolock.Manager {
+	mgr := newManager(repoPath, maxWait)
+	mgr.SetLeaseTTL(ttl)
+	return mgr
+}
+
+// writeRemoteTicket queues an exclusive ticket held by a process on another
+// node, whose flock this node cannot see, with a lease expiring at
+// expiresAt.
+func writeRemoteTicket(t *testing.T, repoPath string, expiresAt time.Time) string {
+	t.Helper()
+	queueDir := filepath.Join(repoPath, ".jvs", "lock", "queue")
+	require.NoError(t, os.MkdirAll(queueDir, 0755))
+	now := time.Now().UTC()
+	ticket := &model.LockTicket{
+		Ticket:         "0000000000000000001-4242-remote00",
+		Mode:           model.LockExclusive,
+		Operation:      "gc",
+		PID:            4242,
+		Host:           "node-b",
+		QueuedAt:       now,
+		AcquiredAt:     &now,
+		HeartbeatAt:    &now,
+		LeaseExpiresAt: &expiresAt,
+	}
+	data, err := json.Marshal(ticket)
+	require.NoError(t, err)
+	path := filepath.Join(queueDir, ticket.Ticket+".json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestLease_LiveRemoteHolderBlocks(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	remote := writeRemoteTicket(t, repoPath, time.Now().Add(time.Hour))
+
+	// Without leases, the ticket looks abandoned as no flock holds it
+	_, err := newLeaseManager(repoPath, 20*time.Millisecond, time.Minute).Acquire(model.LockShared, "snapshot")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errclass.ErrLockTimeout))
+	assert.FileExists(t, remote)
+}
+
+func TestLease_ExpiredTicketIsTakenOver(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	remote := writeRemoteTicket(t, repoPath, time.Now().Add(-time.Second))
+
+	l, err := newLeaseManager(repoPath, 0, time.Minute).Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	assert.NoFileExists(t, remote)
+	assert.NoError(t, l.Check())
+	require.NoError(t, l.Release())
+}
+
+func TestLease_HeartbeatKeepsLockHeld(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	ttl := 150 * time.Millisecond
+	holder, err := newLeaseManager(repoPath, 0, ttl).Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+	defer holder.Release()
+
+	time.Sleep(3 * ttl)
+	_, err = newLeaseManager(repoPath, 0, ttl).Acquire(model.LockExclusive, "gc")
+	assert.True(t, errors.Is(err, errclass.ErrLockTimeout))
+	assert.NoError(t, holder.Check())
+
+	tickets, err := newLeaseManager(repoPath, 0, ttl).Queue()
+	require.NoError(t, err)
+	require.Len(t, tickets, 1)
+	require.NotNil(t, tickets[0].HeartbeatAt)
+	assert.True(t, tickets[0].HeartbeatAt.After(tickets[0].QueuedAt))
+}
+
+func TestLease_ExclusiveHoldersDoNotOverlap(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				l, err := newLeaseManager(repoPath, 10*time.Second, time.Minute).Acquire(model.LockExclusive, "gc")
+				if !assert.NoError(t, err) {
+					return
+				}
+				n := holders.Add(1)
+				if n > maxHolders.Load() {
+					maxHolders.Store(n)
+				}
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+				assert.NoError(t, l.Release())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), maxHolders.Load())
+
+	fence, err := os.ReadFile(filepath.Join(repoPath, ".jvs", "lock", "fence"))
+	require.NoError(t, err)
+	assert.Equal(t, "20\n", string(fence))
+}
+
+func TestLease_FencingTokens(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newLeaseManager(repoPath, 0, time.Minute)
+
+	for want := uint64(1); want <= 2; want++ {
+		l, err := mgr.Acquire(model.LockExclusive, "gc")
+		require.NoError(t, err)
+		assert.Equal(t, want, l.FencingToken())
+		require.NoError(t, l.Release())
+	}
+
+	// Shared holders share the current token, and lose the lock once an
+	// exclusive holder moves past it
+	shared, err := mgr.Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+	defer shared.Release()
+	assert.Equal(t, uint64(2), shared.FencingToken())
+	require.NoError(t, shared.Check())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".jvs", "lock", "fence"), []byte("3\n"), 0644))
+	err = shared.Check()
+	assert.True(t, errors.Is(err, errclass.ErrLockLost))
+	assert.Contains(t, err.Error(), "superseded")
+}
+
+func TestLease_CheckDetectsTakeover(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newLeaseManager(repoPath, 0, time.Minute)
+	l, err := mgr.Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	defer l.Release()
+
+	tickets, err := mgr.Queue()
+	require.NoError(t, err)
+	require.Len(t, tickets, 1)
+	require.NoError(t, os.Remove(filepath.Join(repoPath, ".jvs", "lock", "queue", tickets[0].Ticket+".json")))
+	assert.True(t, errors.Is(l.Check(), errclass.ErrLockLost))
+}
+
+func TestLease_CheckWithoutLeases(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	l, err := newManager(repoPath, 0).Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	defer l.Release()
+	assert.NoError(t, l.Check())
+	assert.Zero(t, l.FencingToken())
+}
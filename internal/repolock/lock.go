@@ -0,0 +1,578 @@
+// Package repolock implements the repository lock with a fair waiting queue.
+//
+// Operations that may overlap (snapshot, restore) hold the lock shared;
+// operations that must run alone (GC) hold it exclusive. Each process
+// holding or waiting for the lock has a ticket in .jvs/lock/queue, and
+// waiters are served in ticket order: once an exclusive waiter is queued,
+// shared requests that arrive after it wait behind it, so a steady stream
+// of snapshots cannot starve a scheduled GC.
+//
+// Tickets and .jvs/lock/repo.lock are held with flock, so a crashed
+// process releases its place automatically and its ticket is pruned by
+// the next process that lists the queue.
+//
+// Where flock is not honoured across the nodes sharing a repository, lease
+// locking (config lock_lease_ttl) makes the queue itself the lock. Each
+// process renews a lease on its ticket while it holds or waits for the
+// lock, and a ticket whose lease expired is taken over. A process takes
+// the lock by marking its ticket held and listing the queue again; if an
+// incompatible holder shows up, it backs off and waits. Every holder gets
+// a fencing token from .jvs/lock/fence, which exclusive holders increment,
+// and Lock.Check tells an operation that it lost the lock before it acts
+// on the repository.
+package repolock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/uuidutil"
+)
+
+// Backoff between attempts to take the lock while queued.
+const (
+	minBackoff = 5 * time.Millisecond
+	maxBackoff = 500 * time.Millisecond
+)
+
+const tmpTicketPrefix = ".tmp-"
+
+// TimeoutError is returned when the lock could not be taken within the
+// maximum wait. It matches errclass.ErrLockTimeout with errors.Is.
+type TimeoutError struct {
+	Operation string
+	Waited    time.Duration
+	// Blockers are the holders and earlier waiters the operation was
+	// queued behind when it gave up.
+	Blockers []*model.LockTicket
+}
+
+func (e *TimeoutError) Error() string {
+	var holders, waiters int
+	for _, t := range e.Blockers {
+		if t.Held() {
+			holders++
+		} else {
+			waiters++
+		}
+	}
+	msg := fmt.Sprintf("%s: %s gave up waiting for the repository lock after %s (%d holder(s), %d waiter(s) ahead)",
+		errclass.ErrLockTimeout.Code, e.Operation, e.Waited.Round(time.Millisecond), holders, waiters)
+	if len(e.Blockers) > 0 {
+		t := e.Blockers[0]
+		msg += fmt.Sprintf("; first is %s by pid %d", t.Operation, t.PID)
+		if t.Host != "" {
+			msg += " on " + t.Host
+		}
+	}
+	return msg
+}
+
+// Is reports whether target is errclass.ErrLockTimeout.
+func (e *TimeoutError) Is(target error) bool {
+	return errors.Is(errclass.ErrLockTimeout, target)
+}
+
+// Manager takes the repository lock.
+type Manager struct {
+	repoRoot string
+	maxWait  time.Duration
+	leaseTTL time.Duration
+}
+
+// NewManager creates a lock manager that waits up to the repository's
+// configured lock_max_wait, with lease locking if lock_lease_ttl is set.
+func NewManager(repoRoot string) *Manager {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: load config: %v; using default lock wait\n", err)
+		cfg = config.Default()
+	}
+	return &Manager{repoRoot: repoRoot, maxWait: cfg.GetLockMaxWait(), leaseTTL: cfg.GetLockLeaseTTL()}
+}
+
+// SetMaxWait sets how long Acquire waits before giving up. Zero makes it
+// give up as soon as the lock is unavailable.
+func (m *Manager) SetMaxWait(d time.Duration) {
+	m.maxWait = d
+}
+
+// SetLeaseTTL sets the lease TTL of lease locking. Zero relies on flock
+// alone. Every process sharing the repository must use the same mode.
+func (m *Manager) SetLeaseTTL(d time.Duration) {
+	m.leaseTTL = d
+}
+
+func (m *Manager) leased() bool {
+	return m.leaseTTL > 0
+}
+
+func (m *Manager) lockPath() string {
+	return filepath.Join(m.repoRoot, ".jvs", "lock", "repo.lock")
+}
+
+func (m *Manager) queueDir() string {
+	return filepath.Join(m.repoRoot, ".jvs", "lock", "queue")
+}
+
+func (m *Manager) fencePath() string {
+	return filepath.Join(m.repoRoot, ".jvs", "lock", "fence")
+}
+
+// Lock is a held repository lock.
+type Lock struct {
+	mgr        *Manager
+	ticket     *model.LockTicket
+	ticketFile *os.File
+	lockFile   *os.File
+
+	// Under lease locking, mu guards the ticket against the heartbeat,
+	// which renews the lease until stop is closed.
+	mu         sync.Mutex
+	lastBeat   time.Time
+	lostReason string
+	stop       chan struct{}
+	stopped    chan struct{}
+}
+
+// Acquire queues for the repository lock in mode on behalf of operation
+// and waits for its turn, backing off progressively between attempts.
+// Returns a *TimeoutError if the lock is not taken within the maximum wait.
+func (m *Manager) Acquire(mode model.LockMode, operation string) (*Lock, error) {
+	if mode != model.LockShared && mode != model.LockExclusive {
+		return nil, fmt.Errorf("invalid lock mode %q", mode)
+	}
+	// Create only below an existing .jvs, never a repository of our own
+	for _, dir := range []string{filepath.Dir(m.queueDir()), m.queueDir()} {
+		if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("create lock queue: %w", err)
+		}
+	}
+	lf, err := os.OpenFile(m.lockPath(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open repo lock: %w", err)
+	}
+
+	now := time.Now().UTC()
+	host, _ := os.Hostname()
+	ticket := &model.LockTicket{
+		Ticket:    fmt.Sprintf("%019d-%d-%s", now.UnixNano(), os.Getpid(), uuidutil.NewV4()[:8]),
+		Mode:      mode,
+		Operation: operation,
+		PID:       os.Getpid(),
+		Host:      host,
+		QueuedAt:  now,
+	}
+	if m.leased() {
+		ticket.HeartbeatAt, ticket.LeaseExpiresAt = leaseTimes(now, m.leaseTTL)
+	}
+	tf, err := m.enqueue(ticket)
+	if err != nil {
+		lf.Close()
+		return nil, err
+	}
+	l := &Lock{mgr: m, ticket: ticket, ticketFile: tf, lockFile: lf, lastBeat: now}
+	if m.leased() {
+		l.stop, l.stopped = make(chan struct{}), make(chan struct{})
+		go l.heartbeat()
+	}
+
+	backoff := minBackoff
+	for {
+		acquired, blockers, err := l.tryAcquire()
+		if err != nil {
+			l.abandon()
+			return nil, err
+		}
+		if acquired {
+			return l, nil
+		}
+		waited := time.Since(now)
+		if waited >= m.maxWait {
+			l.abandon()
+			return nil, &TimeoutError{Operation: operation, Waited: waited, Blockers: blockers}
+		}
+		time.Sleep(min(backoff, m.maxWait-waited))
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// Release releases the lock and removes its ticket from the queue.
+func (l *Lock) Release() error {
+	unlockErr := unlockFile(l.lockFile)
+	l.abandon()
+	if unlockErr != nil {
+		return fmt.Errorf("unlock repo lock: %w", unlockErr)
+	}
+	return nil
+}
+
+// abandon removes the ticket and closes the lock files.
+func (l *Lock) abandon() {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.stopped
+	}
+	os.Remove(l.mgr.ticketPath(l.ticket.Ticket))
+	l.ticketFile.Close()
+	l.lockFile.Close()
+}
+
+// tryAcquire takes the lock if it is this ticket's turn. Otherwise it
+// returns the tickets it is waiting behind.
+func (l *Lock) tryAcquire() (bool, []*model.LockTicket, error) {
+	tickets, err := l.mgr.list(l.ticket.Ticket)
+	if err != nil {
+		return false, nil, err
+	}
+	blockers := l.blockers(tickets, false)
+	if len(blockers) > 0 {
+		return false, blockers, nil
+	}
+	if l.mgr.leased() {
+		return l.tryAcquireLeased()
+	}
+
+	// A holder whose ticket is not visible yet still holds the flock
+	ok, err := tryLockFile(l.lockFile, l.ticket.Mode == model.LockExclusive)
+	if err != nil {
+		return false, nil, fmt.Errorf("lock repo lock: %w", err)
+	}
+	if !ok {
+		return false, nil, nil
+	}
+
+	acquired := time.Now().UTC()
+	l.ticket.AcquiredAt = &acquired
+	data, err := json.MarshalIndent(l.ticket, "", "  ")
+	if err != nil {
+		unlockFile(l.lockFile)
+		return false, nil, err
+	}
+	// The held ticket is longer, so it fully overwrites the waiting one
+	if _, err := l.ticketFile.WriteAt(data, 0); err != nil {
+		unlockFile(l.lockFile)
+		return false, nil, fmt.Errorf("write lock ticket: %w", err)
+	}
+	l.ticketFile.Truncate(int64(len(data)))
+	return true, nil, nil
+}
+
+// blockers returns the tickets incompatible with this one that are ahead of
+// it, or with heldOnly, that hold the lock.
+func (l *Lock) blockers(tickets []*model.LockTicket, heldOnly bool) []*model.LockTicket {
+	var blockers []*model.LockTicket
+	for _, t := range tickets {
+		ahead := t.Held() || (!heldOnly && t.Ticket < l.ticket.Ticket)
+		if ahead && (l.ticket.Mode == model.LockExclusive || t.Mode == model.LockExclusive) {
+			blockers = append(blockers, t)
+		}
+	}
+	return blockers
+}
+
+// tryAcquireLeased takes the lock under lease locking, where no flock
+// guards it: the ticket is marked held and the queue listed again. A
+// process whose ticket was not visible to the first listing may have
+// marked itself held meanwhile; if so, this one backs off. Of two such
+// processes, at least the later to list sees the other, and on the next
+// attempt both see each other's tickets, so the earlier ticket goes first.
+func (l *Lock) tryAcquireLeased() (bool, []*model.LockTicket, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lostReason != "" {
+		return false, nil, errclass.ErrLockLost.WithMessagef("%s while waiting for the repository lock", l.lostReason)
+	}
+
+	acquired := time.Now().UTC()
+	l.ticket.AcquiredAt = &acquired
+	if err := l.writeTicket(); err != nil {
+		return false, nil, err
+	}
+	tickets, err := l.mgr.list(l.ticket.Ticket)
+	if err == nil {
+		if blockers := l.blockers(tickets, true); len(blockers) > 0 {
+			l.ticket.AcquiredAt = nil
+			return false, blockers, l.writeTicket()
+		}
+		if l.ticket.Mode == model.LockExclusive {
+			l.ticket.FencingToken, err = l.mgr.nextFence()
+		} else {
+			l.ticket.FencingToken, err = l.mgr.readFence()
+		}
+	}
+	if err == nil {
+		err = l.writeTicket()
+	}
+	if err != nil {
+		l.ticket.AcquiredAt = nil
+		l.writeTicket()
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// writeTicket renews the lease and atomically rewrites the ticket, unless
+// it was taken over. The caller holds l.mu.
+func (l *Lock) writeTicket() error {
+	path := l.mgr.ticketPath(l.ticket.Ticket)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			l.lostReason = "lock ticket was taken over"
+			return errclass.ErrLockLost.WithMessagef("%s by another process", l.lostReason)
+		}
+		return fmt.Errorf("stat lock ticket: %w", err)
+	}
+	now := time.Now().UTC()
+	l.ticket.HeartbeatAt, l.ticket.LeaseExpiresAt = leaseTimes(now, l.mgr.leaseTTL)
+	data, err := json.MarshalIndent(l.ticket, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.AtomicWrite(path, data, 0644); err != nil {
+		return fmt.Errorf("write lock ticket: %w", err)
+	}
+	l.lastBeat = now
+	return nil
+}
+
+// heartbeat renews the lease every third of its TTL until the lock is
+// released or lost.
+func (l *Lock) heartbeat() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(l.mgr.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			if l.lostReason == "" {
+				if err := l.writeTicket(); err != nil && l.lostReason == "" {
+					fmt.Fprintf(os.Stderr, "warning: renew repository lock lease: %v\n", err)
+				}
+			}
+			lost := l.lostReason != ""
+			l.mu.Unlock()
+			if lost {
+				return
+			}
+		}
+	}
+}
+
+// Check reports whether the lock is still held, for operations to call
+// before irreversible steps. Without lease locking a live process cannot
+// lose the lock. Under lease locking it returns errclass.ErrLockLost if the
+// lease has gone unrenewed for two thirds of its TTL, if the ticket was
+// taken over, or if the fencing counter moved past the lock's token, as an
+// exclusive holder has taken the lock since.
+func (l *Lock) Check() error {
+	if !l.mgr.leased() {
+		return nil
+	}
+	l.mu.Lock()
+	reason, lastBeat := l.lostReason, l.lastBeat
+	l.mu.Unlock()
+	if reason == "" {
+		if since := time.Since(lastBeat); since > 2*l.mgr.leaseTTL/3 {
+			reason = fmt.Sprintf("lease was not renewed for %s", since.Round(time.Millisecond))
+		} else if _, err := os.Stat(l.mgr.ticketPath(l.ticket.Ticket)); os.IsNotExist(err) {
+			reason = "lock ticket was taken over"
+		} else if fence, err := l.mgr.readFence(); err != nil {
+			return err
+		} else if fence != l.ticket.FencingToken {
+			reason = fmt.Sprintf("fencing token %d was superseded by %d", l.ticket.FencingToken, fence)
+		}
+	}
+	if reason != "" {
+		return errclass.ErrLockLost.WithMessagef("%s lost the repository lock: %s", l.ticket.Operation, reason)
+	}
+	return nil
+}
+
+// FencingToken returns the lock's fencing token, zero without lease
+// locking.
+func (l *Lock) FencingToken() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ticket.FencingToken
+}
+
+func leaseTimes(now time.Time, ttl time.Duration) (*time.Time, *time.Time) {
+	expiresAt := now.Add(ttl)
+	return &now, &expiresAt
+}
+
+// readFence returns the repository's fencing counter.
+func (m *Manager) readFence() (uint64, error) {
+	data, err := os.ReadFile(m.fencePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read lock fence: %w", err)
+	}
+	fence, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse lock fence: %w", err)
+	}
+	return fence, nil
+}
+
+// nextFence increments the fencing counter and returns its new value. Only
+// exclusive holders call it, so increments never race.
+func (m *Manager) nextFence() (uint64, error) {
+	fence, err := m.readFence()
+	if err != nil {
+		return 0, err
+	}
+	fence++
+	if err := fsutil.AtomicWrite(m.fencePath(), []byte(strconv.FormatUint(fence, 10)+"\n"), 0644); err != nil {
+		return 0, fmt.Errorf("write lock fence: %w", err)
+	}
+	return fence, nil
+}
+
+// Queue returns the processes holding the lock followed by the waiters in
+// the order they will be served.
+func (m *Manager) Queue() ([]*model.LockTicket, error) {
+	return m.list("")
+}
+
+func (m *Manager) ticketPath(ticket string) string {
+	return filepath.Join(m.queueDir(), ticket+".json")
+}
+
+// enqueue writes ticket under a temporary name, locks it and renames it
+// into the queue, so that a visible ticket is always locked by a live
+// process. The returned file keeps the lock.
+func (m *Manager) enqueue(ticket *model.LockTicket) (*os.File, error) {
+	data, err := json.MarshalIndent(ticket, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := filepath.Join(m.queueDir(), tmpTicketPrefix+ticket.Ticket)
+	f, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("create lock ticket: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("lock ticket: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("write lock ticket: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.ticketPath(ticket.Ticket)); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("queue lock ticket: %w", err)
+	}
+	return f, nil
+}
+
+// list returns the live tickets other than self, holders first, then
+// waiters in ticket order. Tickets of processes that exited are removed;
+// under lease locking, so are tickets whose lease expired.
+func (m *Manager) list(self string) ([]*model.LockTicket, error) {
+	entries, err := os.ReadDir(m.queueDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read lock queue: %w", err)
+	}
+	var tickets []*model.LockTicket
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(m.queueDir(), name)
+		if strings.HasPrefix(name, tmpTicketPrefix) {
+			if !m.leased() {
+				pruneIfAbandoned(path)
+			} else if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > m.leaseTTL {
+				// flock may not reach the node queueing it, so wait a lease
+				os.Remove(path)
+			}
+			continue
+		}
+		if filepath.Ext(name) != ".json" || strings.TrimSuffix(name, ".json") == self {
+			continue
+		}
+		if !m.leased() && pruneIfAbandoned(path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // released while listing
+		}
+		var t model.LockTicket
+		if err := json.Unmarshal(data, &t); err != nil {
+			continue // being rewritten by its holder
+		}
+		if m.leased() {
+			if t.LeaseExpiresAt == nil && pruneIfAbandoned(path) {
+				continue
+			}
+			if t.LeaseExpired(time.Now()) {
+				fmt.Fprintf(os.Stderr, "warning: lease of repository lock ticket %s (%s by pid %d on %s) expired at %s; taking it over\n",
+					t.Ticket, t.Operation, t.PID, t.Host, t.LeaseExpiresAt.Local().Format(time.DateTime))
+				os.Remove(path)
+				continue
+			}
+		}
+		tickets = append(tickets, &t)
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		if tickets[i].Held() != tickets[j].Held() {
+			return tickets[i].Held()
+		}
+		return tickets[i].Ticket < tickets[j].Ticket
+	})
+	return tickets, nil
+}
+
+// pruneIfAbandoned removes the ticket at path if no process holds its lock,
+// and reports whether it did.
+func pruneIfAbandoned(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	defer f.Close()
+	ok, err := tryLockFile(f, true)
+	if err != nil || !ok {
+		return false
+	}
+	defer unlockFile(f)
+	os.Remove(path)
+	return true
+}
+
+// TryLockFile takes a lock on f, exclusive or shared, without blocking and
+// reports whether it was acquired. It lets other packages guard their own
+// files with the flock the repository lock uses.
+func TryLockFile(f *os.File, exclusive bool) (bool, error) {
+	return tryLockFile(f, exclusive)
+}
+
+// UnlockFile releases a lock taken with TryLockFile.
+func UnlockFile(f *os.File) error {
+	return unlockFile(f)
+}
@@ -0,0 +1,152 @@
+package repolock_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	return dir
+}
+
+func newManager(repoPath string, maxWait time.Duration) *repolock.Manager {
+	mgr := repolock.NewManager(repoPath)
+	mgr.SetMaxWait(maxWait)
+	return mgr
+}
+
+// waitForQueue waits until the queue holds n tickets.
+func waitForQueue(t *testing.T, mgr *repolock.Manager, n int) []*model.LockTicket {
+	t.Helper()
+	var tickets []*model.LockTicket
+	require.Eventually(t, func() bool {
+		var err error
+		tickets, err = mgr.Queue()
+		require.NoError(t, err)
+		return len(tickets) == n
+	}, 5*time.Second, 5*time.Millisecond)
+	return tickets
+}
+
+func TestAcquire_SharedHoldersOverlap(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newManager(repoPath, 0)
+
+	first, err := mgr.Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+	second, err := mgr.Acquire(model.LockShared, "restore")
+	require.NoError(t, err)
+
+	tickets, err := mgr.Queue()
+	require.NoError(t, err)
+	require.Len(t, tickets, 2)
+	assert.True(t, tickets[0].Held())
+	assert.True(t, tickets[1].Held())
+
+	require.NoError(t, first.Release())
+	require.NoError(t, second.Release())
+	tickets, err = mgr.Queue()
+	require.NoError(t, err)
+	assert.Empty(t, tickets)
+}
+
+func TestAcquire_ExclusiveTimesOut(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	holder, err := newManager(repoPath, 0).Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+	defer holder.Release()
+
+	start := time.Now()
+	_, err = newManager(repoPath, 50*time.Millisecond).Acquire(model.LockExclusive, "gc")
+	require.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.True(t, errors.Is(err, errclass.ErrLockTimeout))
+
+	var timeout *repolock.TimeoutError
+	require.True(t, errors.As(err, &timeout))
+	assert.Equal(t, "gc", timeout.Operation)
+	require.Len(t, timeout.Blockers, 1)
+	assert.Equal(t, "snapshot", timeout.Blockers[0].Operation)
+	assert.Contains(t, err.Error(), "1 holder(s), 0 waiter(s) ahead")
+
+	// The timed-out waiter left the queue
+	tickets, err := newManager(repoPath, 0).Queue()
+	require.NoError(t, err)
+	assert.Len(t, tickets, 1)
+}
+
+func TestAcquire_ExclusiveWaiterIsNotStarved(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newManager(repoPath, 0)
+	snapshot, err := mgr.Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+
+	gcDone := make(chan error, 1)
+	go func() {
+		gc, err := newManager(repoPath, 5*time.Second).Acquire(model.LockExclusive, "gc")
+		if err == nil {
+			err = gc.Release()
+		}
+		gcDone <- err
+	}()
+	tickets := waitForQueue(t, mgr, 2)
+	assert.True(t, tickets[0].Held())
+	assert.Equal(t, model.LockExclusive, tickets[1].Mode)
+	assert.False(t, tickets[1].Held())
+
+	// A later snapshot queues behind the waiting GC even though the lock is
+	// only held shared
+	_, err = newManager(repoPath, 20*time.Millisecond).Acquire(model.LockShared, "snapshot")
+	require.Error(t, err)
+	var timeout *repolock.TimeoutError
+	require.True(t, errors.As(err, &timeout))
+	require.Len(t, timeout.Blockers, 1)
+	assert.Equal(t, "gc", timeout.Blockers[0].Operation)
+
+	require.NoError(t, snapshot.Release())
+	require.NoError(t, <-gcDone)
+
+	later, err := mgr.Acquire(model.LockShared, "snapshot")
+	require.NoError(t, err)
+	require.NoError(t, later.Release())
+}
+
+func TestQueue_PrunesAbandonedTickets(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newManager(repoPath, 0)
+	queueDir := filepath.Join(repoPath, ".jvs", "lock", "queue")
+	require.NoError(t, os.MkdirAll(queueDir, 0755))
+
+	// A ticket that no process holds locked, as left by a crash
+	stale := filepath.Join(queueDir, "0000000000000000001-99999-deadbeef.json")
+	require.NoError(t, os.WriteFile(stale, []byte(`{"ticket":"0000000000000000001-99999-deadbeef","mode":"exclusive","operation":"gc","pid":99999}`), 0644))
+
+	tickets, err := mgr.Queue()
+	require.NoError(t, err)
+	assert.Empty(t, tickets)
+	assert.NoFileExists(t, stale)
+
+	l, err := mgr.Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
+
+func TestAcquire_InvalidMode(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	_, err := newManager(repoPath, 0).Acquire("upgrade", "gc")
+	assert.Error(t, err)
+}
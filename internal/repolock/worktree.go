@@ -0,0 +1,177 @@
+package repolock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// Operations sharing the repository lock may still conflict on a worktree:
+// two snapshots of the same payload, or a restore replacing a payload while
+// it is being snapshotted. Each such operation holds a per-worktree guard,
+// a marker file naming the operation, process and host that is locked for
+// as long as the operation runs, so a second operation fails at once with
+// an error saying who is busy instead of interleaving with the first. A
+// crashed process releases its lock, and its marker is taken over.
+
+// OperationInProgressError is returned when another operation holds a
+// worktree's guard. It matches errclass.ErrWorktreeBusy with errors.Is.
+type OperationInProgressError struct {
+	Marker *model.OperationMarker
+}
+
+func (e *OperationInProgressError) Error() string {
+	m := e.Marker
+	msg := fmt.Sprintf("%s: %s already running on worktree %s", errclass.ErrWorktreeBusy.Code, m.Operation, m.WorktreeName)
+	if m.Host != "" {
+		msg += " on host " + m.Host
+	}
+	if m.PID != 0 {
+		msg += fmt.Sprintf(" (pid %d) since %s", m.PID, m.StartedAt.Local().Format(time.TimeOnly))
+	}
+	return msg
+}
+
+// Is reports whether target is errclass.ErrWorktreeBusy.
+func (e *OperationInProgressError) Is(target error) bool {
+	return errors.Is(errclass.ErrWorktreeBusy, target)
+}
+
+// Guard is a held worktree guard.
+type Guard struct {
+	path string
+	file *os.File
+}
+
+func (m *Manager) guardPath(worktreeName string) string {
+	return filepath.Join(m.repoRoot, ".jvs", "lock", "worktrees", worktreeName+".json")
+}
+
+// GuardWorktree marks worktreeName as busy with operation until the guard
+// is released. Returns an *OperationInProgressError without waiting if
+// another operation, in this process or another, holds the guard.
+func (m *Manager) GuardWorktree(worktreeName, operation string) (*Guard, error) {
+	if err := pathutil.ValidateName(worktreeName); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(m.guardPath(worktreeName))
+	// Create only below an existing .jvs, never a repository of our own
+	for _, d := range []string{filepath.Dir(dir), dir} {
+		if err := os.Mkdir(d, 0755); err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("create worktree guards: %w", err)
+		}
+	}
+
+	path := m.guardPath(worktreeName)
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open worktree guard: %w", err)
+		}
+		ok, err := tryLockFile(f, true)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock worktree guard: %w", err)
+		}
+		if !ok {
+			f.Close()
+			marker, err := readMarker(path)
+			if err != nil || marker == nil {
+				// Released or being written; the holder is unknown
+				marker = &model.OperationMarker{WorktreeName: worktreeName, Operation: "another operation"}
+			}
+			return nil, &OperationInProgressError{Marker: marker}
+		}
+		// A releasing holder may have removed the file after we opened
+		// it; the lock only counts on the file still at path
+		if !sameFile(f, path) {
+			unlockFile(f)
+			f.Close()
+			continue
+		}
+
+		host, _ := os.Hostname()
+		data, err := json.MarshalIndent(&model.OperationMarker{
+			WorktreeName: worktreeName,
+			Operation:    operation,
+			PID:          os.Getpid(),
+			Host:         host,
+			StartedAt:    time.Now().UTC(),
+		}, "", "  ")
+		if err == nil {
+			if err = f.Truncate(0); err == nil {
+				_, err = f.WriteAt(data, 0)
+			}
+		}
+		if err != nil {
+			unlockFile(f)
+			f.Close()
+			return nil, fmt.Errorf("write worktree guard: %w", err)
+		}
+		return &Guard{path: path, file: f}, nil
+	}
+}
+
+// Release removes the guard's marker and unlocks it.
+func (g *Guard) Release() error {
+	os.Remove(g.path)
+	unlockErr := unlockFile(g.file)
+	g.file.Close()
+	if unlockErr != nil {
+		return fmt.Errorf("unlock worktree guard: %w", unlockErr)
+	}
+	return nil
+}
+
+// WorktreeOperation returns the operation in progress on worktreeName, or
+// nil if there is none. Markers left by crashed processes are removed.
+func (m *Manager) WorktreeOperation(worktreeName string) (*model.OperationMarker, error) {
+	if err := pathutil.ValidateName(worktreeName); err != nil {
+		return nil, err
+	}
+	path := m.guardPath(worktreeName)
+	if pruneIfAbandoned(path) {
+		return nil, nil
+	}
+	marker, err := readMarker(path)
+	if err != nil {
+		return nil, fmt.Errorf("read worktree guard: %w", err)
+	}
+	return marker, nil
+}
+
+// readMarker reads the marker at path, or nil if there is none or it is
+// still being written.
+func readMarker(path string) (*model.OperationMarker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var marker model.OperationMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, nil
+	}
+	return &marker, nil
+}
+
+func sameFile(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
@@ -0,0 +1,79 @@
+package repolock_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+func TestGuardWorktree_RefusesSecondOperation(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newManager(repoPath, 0)
+
+	guard, err := mgr.GuardWorktree("main", "snapshot")
+	require.NoError(t, err)
+
+	marker, err := mgr.WorktreeOperation("main")
+	require.NoError(t, err)
+	require.NotNil(t, marker)
+	assert.Equal(t, "snapshot", marker.Operation)
+	assert.Equal(t, os.Getpid(), marker.PID)
+
+	_, err = mgr.GuardWorktree("main", "restore")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeBusy))
+	var busy *repolock.OperationInProgressError
+	require.True(t, errors.As(err, &busy))
+	assert.Equal(t, "snapshot", busy.Marker.Operation)
+	assert.Contains(t, err.Error(), "snapshot already running on worktree main")
+	if host, _ := os.Hostname(); host != "" {
+		assert.Contains(t, err.Error(), "on host "+host)
+	}
+
+	// Other worktrees are not affected
+	other, err := mgr.GuardWorktree("feature", "snapshot")
+	require.NoError(t, err)
+	require.NoError(t, other.Release())
+
+	require.NoError(t, guard.Release())
+	marker, err = mgr.WorktreeOperation("main")
+	require.NoError(t, err)
+	assert.Nil(t, marker)
+
+	guard, err = mgr.GuardWorktree("main", "restore")
+	require.NoError(t, err)
+	require.NoError(t, guard.Release())
+}
+
+func TestGuardWorktree_TakesOverAbandonedMarker(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := newManager(repoPath, 0)
+	dir := filepath.Join(repoPath, ".jvs", "lock", "worktrees")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	// A marker that no process holds locked, as left by a crash
+	stale := filepath.Join(dir, "main.json")
+	require.NoError(t, os.WriteFile(stale, []byte(`{"worktree_name":"main","operation":"snapshot","pid":99999,"host":"gone"}`), 0644))
+
+	guard, err := mgr.GuardWorktree("main", "restore")
+	require.NoError(t, err)
+	marker, err := mgr.WorktreeOperation("main")
+	require.NoError(t, err)
+	require.NotNil(t, marker)
+	assert.Equal(t, "restore", marker.Operation)
+	require.NoError(t, guard.Release())
+	assert.NoFileExists(t, stale)
+}
+
+func TestGuardWorktree_InvalidName(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	_, err := newManager(repoPath, 0).GuardWorktree("../escape", "snapshot")
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+// Package resolver asks external services for snapshot references that a
+// repository does not know itself, such as "nightly" or "release-2024.06"
+// kept in a release system. Resolvers are configured under "resolvers" in
+// the repository config and are either HTTP endpoints or commands.
+//
+// Answers are checked against the repository and cached for the resolver's
+// cache_ttl; each answer obtained from a resolver is audited as
+// ref_resolve.
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// maxResponseBytes bounds what is read from a resolver.
+const maxResponseBytes = 64 << 10
+
+// Response is the JSON body an HTTP resolver answers with.
+type Response struct {
+	SnapshotID model.SnapshotID `json:"snapshot_id"`
+}
+
+// Resolver resolves references through the resolvers configured for a
+// repository. It is safe for concurrent use.
+type Resolver struct {
+	repoRoot string
+	client   *http.Client
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	id       model.SnapshotID
+	resolver string
+	expires  time.Time
+}
+
+// New creates a Resolver for the repository at repoRoot.
+func New(repoRoot string) *Resolver {
+	return &Resolver{
+		repoRoot: repoRoot,
+		client:   &http.Client{},
+		now:      time.Now,
+		cache:    make(map[string]cacheEntry),
+	}
+}
+
+// SetClock sets the clock that cache expiry is measured with.
+func (r *Resolver) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// Resolve asks the configured resolvers, in order, for ref and returns the
+// descriptor of the snapshot the first one that knows it names. It fails
+// with an error matching fs.ErrNotExist if none does, and with the errors of
+// the resolvers that failed, if any, so that an outage is not mistaken for
+// an unknown reference. Answers are audited as ref_resolve with the author
+// and correlation ID ctx carries (see audit.WithAuthor and
+// audit.WithCorrelationID).
+func (r *Resolver) Resolve(ctx context.Context, ref string) (*model.Descriptor, error) {
+	cfg, err := config.Load(r.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, rc := range cfg.Resolvers {
+		if id, ok := r.cached(rc.Name, ref); ok {
+			desc, err := snapshot.LoadDescriptor(r.repoRoot, id)
+			if err == nil {
+				return desc, nil
+			}
+			// The snapshot has gone since; ask again
+			r.forget(ref)
+		}
+
+		id, err := r.lookup(ctx, rc, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolver %s: %w", rc.Name, err))
+			continue
+		}
+		if id == "" {
+			continue
+		}
+		desc, err := snapshot.LoadDescriptor(r.repoRoot, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolver %s answered %s: %w", rc.Name, id, err))
+			continue
+		}
+		if ttl := rc.GetCacheTTL(); ttl > 0 {
+			r.mu.Lock()
+			r.cache[ref] = cacheEntry{id: id, resolver: rc.Name, expires: r.now().Add(ttl)}
+			r.mu.Unlock()
+		}
+		// Concurrent calls share the resolver, so each audits with its own
+		// appender and the author and correlation ID of its ctx
+		auditLogger := audit.NewFileAppender(audit.LogPath(r.repoRoot))
+		auditLogger.SetCorrelationID(audit.CorrelationID(ctx))
+		auditLogger.SetAuthor(audit.Author(ctx))
+		auditLogger.Append(model.EventTypeRefResolve, desc.WorktreeName, id, map[string]any{
+			"ref":      ref,
+			"resolver": rc.Name,
+		})
+		return desc, nil
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("resolve %q: %w", ref, errors.Join(errs...))
+	}
+	return nil, fmt.Errorf("no resolver knows %q: %w", ref, fs.ErrNotExist)
+}
+
+// cached returns the unexpired answer of resolver name for ref.
+func (r *Resolver) cached(name, ref string) (model.SnapshotID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.cache[ref]
+	if !ok || e.resolver != name {
+		return "", false
+	}
+	if !r.now().Before(e.expires) {
+		delete(r.cache, ref)
+		return "", false
+	}
+	return e.id, true
+}
+
+func (r *Resolver) forget(ref string) {
+	r.mu.Lock()
+	delete(r.cache, ref)
+	r.mu.Unlock()
+}
+
+// lookup asks one resolver for ref, returning an empty ID if it does not
+// know it.
+func (r *Resolver) lookup(ctx context.Context, rc config.Resolver, ref string) (model.SnapshotID, error) {
+	ctx, cancel := context.WithTimeout(ctx, rc.GetTimeout())
+	defer cancel()
+	if rc.URL != "" {
+		return r.lookupHTTP(ctx, rc.URL, ref)
+	}
+	return r.lookupCommand(ctx, rc.Command, ref)
+}
+
+func (r *Resolver) lookupHTTP(ctx context.Context, endpoint, ref string) (model.SnapshotID, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("ref", ref)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	var body Response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if body.SnapshotID == "" {
+		return "", fmt.Errorf("response has no snapshot_id")
+	}
+	return body.SnapshotID, nil
+}
+
+func (r *Resolver) lookupCommand(ctx context.Context, command []string, ref string) (model.SnapshotID, error) {
+	cmd := exec.CommandContext(ctx, command[0], append(slices.Clone(command[1:]), ref)...)
+	cmd.Env = append(os.Environ(), "JVS_REPO="+r.repoRoot)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	if len(out) > maxResponseBytes {
+		return "", fmt.Errorf("output too long")
+	}
+	return model.SnapshotID(strings.TrimSpace(string(out))), nil
+}
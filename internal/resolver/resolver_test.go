@@ -0,0 +1,124 @@
+package resolver_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/resolver"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupTestRepo(t *testing.T) (string, model.SnapshotID) {
+	dir := t.TempDir()
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main", "file.txt"), []byte("content"), 0644))
+	desc, err := snapshot.NewCreator(dir, model.EngineCopy).Create("main", "test", nil)
+	require.NoError(t, err)
+	return dir, desc.SnapshotID
+}
+
+func writeConfig(t *testing.T, repoRoot, yaml string) {
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, ".jvs", "config.yaml"), []byte(yaml), 0644))
+	config.InvalidateCache(repoRoot)
+	t.Cleanup(func() { config.InvalidateCache(repoRoot) })
+}
+
+func TestResolver_HTTP(t *testing.T) {
+	repoRoot, id := setupTestRepo(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Query().Get("ref") {
+		case "nightly":
+			json.NewEncoder(w).Encode(resolver.Response{SnapshotID: id})
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	writeConfig(t, repoRoot, "resolvers:\n  - name: releases\n    url: "+srv.URL+"\n")
+
+	now := time.Now()
+	r := resolver.New(repoRoot)
+	r.SetClock(func() time.Time { return now })
+
+	desc, err := r.Resolve(context.Background(), "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, id, desc.SnapshotID)
+
+	// Cached until the TTL passes
+	_, err = r.Resolve(context.Background(), "nightly")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, calls.Load())
+	now = now.Add(config.DefaultResolverCacheTTL)
+	_, err = r.Resolve(context.Background(), "nightly")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, calls.Load())
+
+	_, err = r.Resolve(context.Background(), "unknown")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = r.Resolve(context.Background(), "broken")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "releases")
+
+	records, err := audit.Read(audit.LogPath(repoRoot), audit.Filter{EventType: model.EventTypeRefResolve})
+	require.NoError(t, err)
+	require.Len(t, records, 2, "cache hits are not audited")
+	assert.Equal(t, id, records[0].SnapshotID)
+	assert.Equal(t, "nightly", records[0].Details["ref"])
+	assert.Equal(t, "releases", records[0].Details["resolver"])
+}
+
+func TestResolver_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	repoRoot, id := setupTestRepo(t)
+	script := filepath.Join(t.TempDir(), "resolve.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+case "$2" in
+  nightly) echo "`+string(id)+`" ;;
+  gone) echo 1700000000000-deadbeef ;;
+  fail) echo "release db down" >&2; exit 3 ;;
+esac
+`), 0755))
+	// The first resolver knows nothing, so the second one is asked
+	writeConfig(t, repoRoot, "resolvers:\n  - name: empty\n    command: [/bin/sh, -c, 'exit 0', sh]\n  - name: script\n    command: ["+script+", --ref]\n    cache_ttl: 0s\n")
+
+	r := resolver.New(repoRoot)
+	desc, err := r.Resolve(context.Background(), "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, id, desc.SnapshotID)
+
+	_, err = r.Resolve(context.Background(), "other")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = r.Resolve(context.Background(), "fail")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "release db down")
+
+	_, err = r.Resolve(context.Background(), "gone")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "answered 1700000000000-deadbeef")
+}
@@ -0,0 +1,181 @@
+package restore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+	"github.com/jvs-project/jvs/pkg/progress"
+	"github.com/jvs-project/jvs/pkg/uuidutil"
+)
+
+// restorePaths replaces the paths set by SetPaths in a worktree with their
+// content in a snapshot. The rest of the payload, the worktree's head and
+// its detached state are left as they are. Only those paths are read from
+// the snapshot, as listed by its manifest, and every file is checked
+// against the manifest while it is copied, so a few files can be restored
+// from a large snapshot without cloning or rehashing all of it. It fails if
+// the snapshot has no manifest or a path is not in it; lease and freeze
+// checks apply as for a full restore. Audited as restore_paths.
+func (r *Restorer) restorePaths(worktreeName string, snapshotID model.SnapshotID) error {
+	if worktreeName == "" {
+		return fmt.Errorf("worktree name is required")
+	}
+	if snapshotID == "" {
+		return fmt.Errorf("snapshot ID is required")
+	}
+	paths, err := normalizePaths(r.paths)
+	if err != nil {
+		return err
+	}
+
+	locks := r.locks()
+	guard, err := locks.GuardWorktree(worktreeName, "restore")
+	if err != nil {
+		return err
+	}
+	defer guard.Release()
+	lock, err := locks.Acquire(model.LockShared, "restore")
+	if err != nil {
+		return fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+	mark := r.phaseMarker()
+
+	// Record the in-flight restore so GC keeps its source snapshot
+	intentPath, err := r.writeIntent(worktreeName, snapshotID)
+	if err != nil {
+		return fmt.Errorf("write intent: %w", err)
+	}
+	defer os.Remove(intentPath)
+
+	desc, snapshotDir, err := snapshot.OpenPayload(r.repoRoot, snapshotID)
+	if err != nil {
+		return fmt.Errorf("load snapshot: %w", err)
+	}
+	if err := snapshot.VerifySnapshot(r.repoRoot, snapshotID, false); err != nil {
+		return fmt.Errorf("verify snapshot: %w", err)
+	}
+	if desc.Encryption != nil {
+		return fmt.Errorf("snapshot %s is encrypted; restore it whole", snapshotID)
+	}
+	entries, err := snapshot.LoadManifestEntriesUnder(r.repoRoot, desc, paths)
+	if err != nil {
+		return fmt.Errorf("load manifest: %w", err)
+	}
+	if entries == nil {
+		return fmt.Errorf("snapshot %s has no manifest; restore it whole", snapshotID)
+	}
+	mark("verify")
+
+	activeLease, _, err := r.checkWritable(worktreeName)
+	if err != nil {
+		return err
+	}
+	payloadPath := worktree.NewManager(r.repoRoot).Path(worktreeName)
+	for _, p := range paths {
+		if err := pathutil.ValidatePathSafety(payloadPath, filepath.Join(payloadPath, p)); err != nil {
+			return err
+		}
+	}
+
+	// Step 1: Extract the entries next to the payload, so they can be
+	// renamed into it
+	tempPath := payloadPath + ".restore-tmp-" + uuidutil.NewV4()[:8]
+	defer os.RemoveAll(tempPath)
+	var files int
+	var size int64
+	var dirs []model.ManifestEntry
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	extracted := progress.NewBytes("clone", total, r.progress)
+	for _, e := range entries {
+		dst := filepath.Join(tempPath, filepath.FromSlash(e.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("create parent of %s: %w", e.Path, err)
+		}
+		if err := snapshot.ExtractManifestEntry(snapshotDir, desc, e, dst); err != nil {
+			return fmt.Errorf("extract %s: %w", e.Path, err)
+		}
+		switch e.Type {
+		case "dir":
+			dirs = append(dirs, e)
+		case "file":
+			files++
+			size += e.Size
+		}
+		extracted.Add(e.Size)
+	}
+	extracted.Done()
+	// Directory modes are applied last, as they may clear write bits
+	for _, e := range slices.Backward(dirs) {
+		if err := os.Chmod(filepath.Join(tempPath, filepath.FromSlash(e.Path)), os.FileMode(e.Mode)); err != nil {
+			return fmt.Errorf("set mode of %s: %w", e.Path, err)
+		}
+	}
+	mark("clone")
+
+	// Step 2: Replace each path of the payload
+	for _, p := range paths {
+		target := filepath.Join(payloadPath, p)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("create parent of %s: %w", p, err)
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("remove %s: %w", p, err)
+		}
+		if err := fsutil.RenameAndSync(filepath.Join(tempPath, p), target); err != nil {
+			return fmt.Errorf("restore %s: %w", p, err)
+		}
+	}
+	mark("swap")
+
+	auditData := map[string]any{
+		"paths": paths,
+		"files": files,
+		"bytes": size,
+	}
+	if activeLease != nil {
+		auditData["forced_lease_holder"] = activeLease.Holder
+	}
+	r.auditLogger.Append(model.EventTypeRestorePaths, worktreeName, snapshotID, auditData)
+	return nil
+}
+
+// normalizePaths cleans payload paths given to SetPaths, rejecting any that
+// leave the payload, and drops those inside another path.
+func normalizePaths(paths []string) ([]string, error) {
+	var cleaned []string
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+			return nil, errclass.ErrPathEscape.WithMessagef("path must be inside the payload: %s", p)
+		}
+		if p == "." {
+			return nil, fmt.Errorf("path must name a file or directory of the payload; restore the whole snapshot instead")
+		}
+		cleaned = append(cleaned, p)
+	}
+	slices.Sort(cleaned)
+
+	var result []string
+	for _, p := range cleaned {
+		inside := slices.ContainsFunc(result, func(kept string) bool {
+			return p == kept || strings.HasPrefix(p, kept+string(filepath.Separator))
+		})
+		if !inside {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
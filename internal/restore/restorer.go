@@ -2,26 +2,49 @@
 package restore
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/jvs-project/jvs/internal/audit"
 	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/diff"
 	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/hooks"
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/webhook"
 	"github.com/jvs-project/jvs/internal/worktree"
 	"github.com/jvs-project/jvs/pkg/fsutil"
 	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/progress"
 	"github.com/jvs-project/jvs/pkg/uuidutil"
 )
 
 // Restorer handles snapshot restore operations.
 type Restorer struct {
-	repoRoot    string
-	engineType  model.EngineType
-	engine      engine.Engine
-	auditLogger *audit.FileAppender
+	repoRoot      string
+	engineType    model.EngineType
+	engine        engine.Engine
+	auditLogger   *audit.FileAppender
+	force         bool
+	ephemeral     bool
+	paths         []string
+	now           func() time.Time
+	observe       snapshot.PhaseObserver
+	progress      progress.ByteCallback
+	lockOwner     string
+	lockWait      *time.Duration // nil waits lock_max_wait
+	ctx           context.Context
+	correlationID string
+	author        string
+
+	degradations []string
 }
 
 // NewRestorer creates a new restorer.
@@ -34,14 +57,156 @@ func NewRestorer(repoRoot string, engineType model.EngineType) *Restorer {
 		engineType:  engineType,
 		engine:      eng,
 		auditLogger: audit.NewFileAppender(auditPath),
+		now:         time.Now,
+		ctx:         context.Background(),
+	}
+}
+
+// SetForce allows restoring a worktree that has an active lease.
+func (r *Restorer) SetForce(force bool) {
+	r.force = force
+}
+
+// SetLockOwner sets who restores, as far as worktree locks are concerned:
+// a worktree locked by another owner is refused, even with SetForce. Empty,
+// the default, uses snapshot.CurrentActor.
+func (r *Restorer) SetLockOwner(owner string) {
+	r.lockOwner = owner
+}
+
+// SetLockWait sets how long restores wait for the repository lock, instead
+// of the configured lock_max_wait. Zero gives up at once if an exclusive
+// operation such as GC holds it.
+func (r *Restorer) SetLockWait(d time.Duration) {
+	r.lockWait = &d
+}
+
+// SetCorrelationID sets the correlation ID of the operation the restore
+// belongs to, recorded in its audit record and passed to its hooks and
+// webhooks. Empty, the default, uses the one set through
+// audit.CorrelationEnvVar, if any.
+func (r *Restorer) SetCorrelationID(id string) {
+	r.correlationID = id
+	r.auditLogger.SetCorrelationID(id)
+}
+
+// SetAuthor sets who the restore's audit record names as its author, also
+// passed to its hooks and webhooks. Empty, the default, uses
+// identity.Author.
+func (r *Restorer) SetAuthor(author string) {
+	r.author = author
+	r.auditLogger.SetAuthor(author)
+}
+
+// SetContext makes restores stop once ctx is done: cloning the snapshot
+// fails with ctx's error and the worktree is left as it was. A restore
+// whose payload was already swapped in is not affected.
+func (r *Restorer) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Degradations returns the engine degradations of the last Restore, such
+// as a reflink falling back to a copy. Path restores report none.
+func (r *Restorer) Degradations() []string {
+	return r.degradations
+}
+
+// locks returns a repository lock manager waiting as set by SetLockWait.
+func (r *Restorer) locks() *repolock.Manager {
+	m := repolock.NewManager(r.repoRoot)
+	if r.lockWait != nil {
+		m.SetMaxWait(*r.lockWait)
+	}
+	return m
+}
+
+// SetEphemeral makes restores replace the payload only: the worktree's head
+// is not moved, so its detached state does not change. Such restores are
+// audited as restore_ephemeral.
+func (r *Restorer) SetEphemeral(ephemeral bool) {
+	r.ephemeral = ephemeral
+}
+
+// SetPaths makes restores replace only the given payload paths of the
+// worktree, read from the snapshot's manifest; see restorePaths.
+func (r *Restorer) SetPaths(paths []string) {
+	r.paths = paths
+}
+
+// SetClock replaces the clock used to decide whether a lease has expired.
+func (r *Restorer) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// SetEngine replaces the engine that clones snapshots into worktrees.
+func (r *Restorer) SetEngine(eng engine.Engine) {
+	r.engine = eng
+}
+
+// SetPhaseObserver makes the restorer report each phase of a restore
+// (verify, clone, decompress, summarize, swap, head_update) to observe as it
+// ends.
+func (r *Restorer) SetPhaseObserver(observe snapshot.PhaseObserver) {
+	r.observe = observe
+}
+
+// SetProgress makes the restorer report the progress of a restore to fn,
+// as phase "clone" while the snapshot, or the paths set with SetPaths, are
+// copied out of the repository. done and total count bytes of regular
+// files.
+func (r *Restorer) SetProgress(fn progress.ByteCallback) {
+	r.progress = fn
+}
+
+// phaseMarker returns a function that ends the current phase under the
+// given name and starts the next one.
+func (r *Restorer) phaseMarker() func(phase string) {
+	last := time.Now()
+	return func(phase string) {
+		now := time.Now()
+		if r.observe != nil {
+			r.observe(phase, last, now)
+		}
+		last = now
 	}
 }
 
 // Restore replaces the content of a worktree with a snapshot.
+// Fails with a *lease.BusyError (errclass.ErrWorktreeBusy) if the worktree
+// has an active lease, unless SetForce(true) was called, with
+// errclass.ErrWorktreeFrozen if the worktree is frozen, and with
+// errclass.ErrWorktreeLocked if another owner has locked it.
 // This puts the worktree into a "detached" state (unless restoring to HEAD).
 // The worktree is specified by name, not derived from the snapshot.
+// The repository's pre-restore hook runs first and may abort it; the
+// post-restore hook runs after it succeeds, and then its restore.completed
+// webhooks.
 func (r *Restorer) Restore(worktreeName string, snapshotID model.SnapshotID) error {
-	return r.restore(worktreeName, snapshotID)
+	ctx := audit.WithAuthor(audit.WithCorrelationID(r.ctx, r.correlationID), r.author)
+	vars := map[string]string{
+		hooks.EnvOperation:    "restore",
+		hooks.EnvWorktree:     worktreeName,
+		hooks.EnvWorktreePath: worktree.NewManager(r.repoRoot).Path(worktreeName),
+		hooks.EnvSnapshotID:   string(snapshotID),
+	}
+	if len(r.paths) > 0 {
+		vars[hooks.EnvPaths] = hooks.List(r.paths)
+	}
+	if err := hooks.Run(ctx, r.repoRoot, hooks.PreRestore, vars); err != nil {
+		return err
+	}
+	var err error
+	if len(r.paths) > 0 {
+		err = r.restorePaths(worktreeName, snapshotID)
+	} else {
+		err = r.restore(worktreeName, snapshotID)
+	}
+	if err != nil {
+		return err
+	}
+	hooks.RunPost(ctx, r.repoRoot, hooks.PostRestore, vars)
+	webhook.Notify(ctx, r.repoRoot, webhook.NewRestoreEvent(worktreeName, snapshotID, r.paths))
+	return nil
 }
 
 // restore performs the actual restore operation.
@@ -53,8 +218,28 @@ func (r *Restorer) restore(worktreeName string, snapshotID model.SnapshotID) err
 		return fmt.Errorf("snapshot ID is required")
 	}
 
+	locks := r.locks()
+	guard, err := locks.GuardWorktree(worktreeName, "restore")
+	if err != nil {
+		return err
+	}
+	defer guard.Release()
+	lock, err := locks.Acquire(model.LockShared, "restore")
+	if err != nil {
+		return fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+	mark := r.phaseMarker()
+
+	// Record the in-flight restore so GC keeps its source snapshot
+	intentPath, err := r.writeIntent(worktreeName, snapshotID)
+	if err != nil {
+		return fmt.Errorf("write intent: %w", err)
+	}
+	defer os.Remove(intentPath)
+
 	// Load and verify snapshot
-	desc, err := snapshot.LoadDescriptor(r.repoRoot, snapshotID)
+	desc, snapshotDir, err := snapshot.OpenPayload(r.repoRoot, snapshotID)
 	if err != nil {
 		return fmt.Errorf("load snapshot: %w", err)
 	}
@@ -62,29 +247,47 @@ func (r *Restorer) restore(worktreeName string, snapshotID model.SnapshotID) err
 	if err := snapshot.VerifySnapshot(r.repoRoot, snapshotID, false); err != nil {
 		return fmt.Errorf("verify snapshot: %w", err)
 	}
+	mark("verify")
 
-	// Get worktree info
-	wtMgr := worktree.NewManager(r.repoRoot)
-	cfg, err := wtMgr.Get(worktreeName)
+	// Refuse to swap the payload while a consumer is attached
+	activeLease, cfg, err := r.checkWritable(worktreeName)
 	if err != nil {
-		return fmt.Errorf("get worktree: %w", err)
+		return err
 	}
+	wtMgr := worktree.NewManager(r.repoRoot)
 
 	payloadPath := wtMgr.Path(worktreeName)
 
 	// Create backup directory for atomic swap
 	backupPath := payloadPath + ".restore-backup-" + uuidutil.NewV4()[:8]
-	snapshotDir := filepath.Join(r.repoRoot, ".jvs", "snapshots", string(snapshotID))
 	tempPath := payloadPath + ".restore-tmp-" + uuidutil.NewV4()[:8]
 
 	// Step 1: Clone snapshot to temp location
-	if _, err := r.engine.Clone(snapshotDir, tempPath); err != nil {
+	cloneResult, err := r.clone(snapshotDir, tempPath)
+	if err != nil {
+		os.RemoveAll(tempPath)
 		return fmt.Errorf("clone to temp: %w", err)
 	}
+	for _, d := range cloneResult.Degradations {
+		fmt.Fprintf(os.Stderr, "warning: restore clone degraded: %s\n", d)
+	}
+	r.degradations = cloneResult.Degradations
+	mark("clone")
 
-	// Step 1.5: Decompress if snapshot was compressed
+	// Step 1.5: Decrypt and decompress if the snapshot was stored so
+	if desc.Encryption != nil {
+		count, err := snapshot.DecryptPayload(r.repoRoot, desc, tempPath)
+		if err != nil {
+			os.RemoveAll(tempPath)
+			return err
+		}
+		if count > 0 {
+			fmt.Fprintf(os.Stderr, "decrypted %d files\n", count)
+		}
+		mark("decrypt")
+	}
 	if desc.Compression != nil {
-		count, err := compression.DecompressDir(tempPath)
+		count, err := compression.DecompressDirType(tempPath, compression.CompressionType(desc.Compression.Type))
 		if err != nil {
 			os.RemoveAll(tempPath)
 			return fmt.Errorf("decompress snapshot: %w", err)
@@ -92,6 +295,25 @@ func (r *Restorer) restore(worktreeName string, snapshotID model.SnapshotID) err
 		if count > 0 {
 			fmt.Fprintf(os.Stderr, "decompressed %d files\n", count)
 		}
+		mark("decompress")
+	}
+
+	if err := repo.RemoveReadyMarkers(tempPath); err != nil {
+		os.RemoveAll(tempPath)
+		return err
+	}
+
+	// Record how much of the payload the restore rewrites; the restore
+	// itself does not depend on it
+	changes, err := diff.Summarize(payloadPath, tempPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to summarize restore changes: %v\n", err)
+	}
+	mark("summarize")
+
+	if err := r.ctx.Err(); err != nil {
+		os.RemoveAll(tempPath)
+		return err
 	}
 
 	// Step 2: Atomic swap: rename current to backup, temp to payload
@@ -110,24 +332,166 @@ func (r *Restorer) restore(worktreeName string, snapshotID model.SnapshotID) err
 	if err := os.RemoveAll(backupPath); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: failed to cleanup backup %s: %v\n", backupPath, err)
 	}
+	mark("swap")
+
+	if r.ephemeral {
+		auditData := map[string]any{
+			"head_snapshot_id": string(cfg.HeadSnapshotID),
+		}
+		if changes != nil {
+			auditData["changes"] = changes
+		}
+		if cloneResult.Degraded {
+			auditData["degradations"] = cloneResult.Degradations
+		}
+		if activeLease != nil {
+			auditData["forced_lease_holder"] = activeLease.Holder
+		}
+		r.auditLogger.Append(model.EventTypeRestoreEphemeral, worktreeName, snapshotID, auditData)
+		return nil
+	}
 
 	// Step 4: Update head (NOT latest - this puts worktree in detached state)
 	if err := wtMgr.UpdateHead(worktreeName, snapshotID); err != nil {
 		// Don't fail, head update is secondary
 		fmt.Fprintf(os.Stderr, "warning: failed to update head: %v\n", err)
 	}
+	mark("head_update")
 
 	// Determine if we're now detached
 	isDetached := snapshotID != cfg.LatestSnapshotID
 
 	// Audit log
-	r.auditLogger.Append(model.EventTypeRestore, worktreeName, snapshotID, map[string]any{
+	auditData := map[string]any{
 		"detached": isDetached,
-	})
+	}
+	if changes != nil {
+		auditData["changes"] = changes
+	}
+	if cloneResult.Degraded {
+		auditData["degradations"] = cloneResult.Degradations
+	}
+	if activeLease != nil {
+		auditData["forced_lease_holder"] = activeLease.Holder
+	}
+	r.auditLogger.Append(model.EventTypeRestore, worktreeName, snapshotID, auditData)
 
 	return nil
 }
 
+// checkWritable refuses to change a worktree's payload while a consumer is
+// attached, unless forced, or while the worktree is frozen or locked by
+// another owner. It returns the lease overridden by force, if any, and the
+// worktree's config.
+func (r *Restorer) checkWritable(worktreeName string) (*model.Lease, *model.WorktreeConfig, error) {
+	leases := lease.NewManager(r.repoRoot)
+	leases.SetClock(r.now)
+	activeLease, err := leases.Get(worktreeName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("check lease: %w", err)
+	}
+	if activeLease != nil && !r.force {
+		return nil, nil, &lease.BusyError{Lease: activeLease}
+	}
+
+	wtMgr := worktree.NewManager(r.repoRoot)
+	cfg, err := wtMgr.Get(worktreeName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get worktree: %w", err)
+	}
+	if err := worktree.CheckNotFrozen(cfg); err != nil {
+		return nil, nil, err
+	}
+	owner := r.lockOwner
+	if owner == "" {
+		owner = snapshot.CurrentActor()
+	}
+	if err := wtMgr.CheckUnlocked(worktreeName, owner); err != nil {
+		return nil, nil, err
+	}
+	return activeLease, cfg, nil
+}
+
+// clone copies snapshotDir to dst. On a JuiceFS mount with the juicefs
+// command installed it uses juicefs clone whatever the configured engine,
+// like snapshots taken with the juicefs-clone engine, so the data is cloned
+// by the metadata engine instead of streamed through this client. A
+// server-side clone is spot-checked against the snapshot; if the check
+// fails it is discarded and the snapshot copied instead, recorded as the
+// "juicefs-clone-spot-check-failed" degradation.
+func (r *Restorer) clone(snapshotDir, dst string) (*engine.CloneResult, error) {
+	eng := r.engine
+	if eng.Name() != model.EngineJuiceFSClone {
+		if jfs := engine.NewJuiceFSEngine(); jfs.Available(snapshotDir) {
+			eng = jfs
+		}
+	}
+	result, err := r.cloneWith(eng, snapshotDir, dst)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &engine.CloneResult{}
+	}
+	if eng.Name() != model.EngineJuiceFSClone || result.Degraded {
+		return result, nil
+	}
+
+	checkErr := spotCheck(snapshotDir, dst)
+	if checkErr == nil {
+		return result, nil
+	}
+	fmt.Fprintf(os.Stderr, "warning: juicefs clone failed spot-check, copying instead: %v\n", checkErr)
+	if err := os.RemoveAll(dst); err != nil {
+		return nil, fmt.Errorf("remove failed clone: %w", err)
+	}
+	result, err = r.cloneWith(engine.NewCopyEngine(), snapshotDir, dst)
+	if err != nil {
+		return nil, err
+	}
+	result.Degraded = true
+	result.Degradations = append(result.Degradations, "juicefs-clone-spot-check-failed")
+	return result, nil
+}
+
+// cloneWith clones snapshotDir to dst with eng, reporting its progress.
+func (r *Restorer) cloneWith(eng engine.Engine, snapshotDir, dst string) (*engine.CloneResult, error) {
+	if r.progress == nil {
+		return eng.CloneContext(r.ctx, snapshotDir, dst)
+	}
+	cloned := progress.NewBytes("clone", fsutil.DirSize(snapshotDir), r.progress)
+	if pe, ok := eng.(engine.ProgressEngine); ok {
+		pe.SetProgress(cloned.Add)
+		defer pe.SetProgress(nil)
+	}
+	result, err := eng.CloneContext(r.ctx, snapshotDir, dst)
+	if err != nil {
+		return nil, err
+	}
+	cloned.Done()
+	return result, nil
+}
+
+// writeIntent writes the intent record of a restore and returns its path.
+func (r *Restorer) writeIntent(worktreeName string, snapshotID model.SnapshotID) (string, error) {
+	intent := &model.IntentRecord{
+		SnapshotID:   snapshotID,
+		WorktreeName: worktreeName,
+		StartedAt:    time.Now().UTC(),
+		Engine:       r.engineType,
+		Operation:    model.IntentOperationRestore,
+	}
+	data, err := json.MarshalIndent(intent, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(repo.IntentsDir(r.repoRoot), repo.RestoreIntentPrefix+uuidutil.NewV4()[:8]+".json")
+	if err := fsutil.AtomicWrite(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
 // RestoreToLatest restores a worktree to its latest snapshot (exits detached state).
 func (r *Restorer) RestoreToLatest(worktreeName string) error {
 	wtMgr := worktree.NewManager(r.repoRoot)
@@ -1,15 +1,28 @@
 package restore_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"filippo.io/age"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/hooks"
+	"github.com/jvs-project/jvs/internal/lease"
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/internal/restore"
 	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -50,6 +63,7 @@ func TestRestorer_Restore(t *testing.T) {
 	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
 	require.NoError(t, err)
 	assert.Equal(t, "snapshot-content", string(content))
+	assert.NoFileExists(t, filepath.Join(mainPath, ".READY"), "the snapshot marker is not payload content")
 
 	// Verify worktree state (since this is the only snapshot, we're at HEAD, not detached)
 	wtMgr := worktree.NewManager(repoPath)
@@ -61,6 +75,101 @@ func TestRestorer_Restore(t *testing.T) {
 	assert.Equal(t, desc.SnapshotID, cfg.LatestSnapshotID)
 }
 
+func TestRestorer_PhaseObserver(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	var phases []string
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetPhaseObserver(func(phase string, start, end time.Time) {
+		phases = append(phases, phase)
+		assert.False(t, end.Before(start))
+	})
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+	assert.Equal(t, []string{"verify", "clone", "summarize", "swap", "head_update"}, phases)
+}
+
+func TestRestorer_Restore_Locked(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	_, err := worktree.NewManager(repoPath).Lock("main", "alice", 0)
+	require.NoError(t, err)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetLockOwner("bob")
+	restorer.SetForce(true)
+	err = restorer.Restore("main", desc.SnapshotID)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeLocked), "force only overrides leases")
+
+	restorer.SetLockOwner("alice")
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+}
+
+func TestRestorer_Restore_Cancelled(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("modified"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetContext(ctx)
+	err := restorer.Restore("main", desc.SnapshotID)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The worktree was left as it was, without temp copies beside it
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "modified", string(content))
+	matches, err := filepath.Glob(mainPath + ".restore-*")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestRestorer_SetProgress(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	var reports [][2]int64
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetProgress(func(phase string, done, total int64) {
+		assert.Equal(t, "clone", phase)
+		reports = append(reports, [2]int64{done, total})
+	})
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+	require.GreaterOrEqual(t, len(reports), 2)
+	total := reports[0][1]
+	assert.GreaterOrEqual(t, total, int64(len("snapshot-content")))
+	assert.Equal(t, [2]int64{0, total}, reports[0])
+	assert.Equal(t, [2]int64{total, total}, reports[len(reports)-1])
+
+	// Restoring paths reports the bytes of the extracted files
+	reports = nil
+	restorer.SetPaths([]string{"file.txt"})
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+	assert.Equal(t, [][2]int64{{0, 16}, {16, 16}}, reports)
+}
+
+func TestRestorer_Restore_Frozen(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	wtMgr := worktree.NewManager(repoPath)
+	_, err := wtMgr.Freeze("main")
+	require.NoError(t, err)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	err = restorer.Restore("main", desc.SnapshotID)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeFrozen))
+	restorer.SetForce(true)
+	err = restorer.RestoreToLatest("main")
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeFrozen), "force only overrides leases")
+
+	_, err = wtMgr.Thaw("main")
+	require.NoError(t, err)
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+}
+
 func TestRestorer_RestoreToLatest(t *testing.T) {
 	repoPath := setupTestRepo(t)
 	desc := createSnapshot(t, repoPath)
@@ -702,6 +811,109 @@ func TestRestorer_Restore_CreatesAuditLogEntry(t *testing.T) {
 	assert.Contains(t, string(content), "\"restore\"")
 }
 
+func TestRestorer_Restore_AuditChangeSummary(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "keep.txt"), []byte("same"), 0644))
+	desc := createSnapshot(t, repoPath)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("modified"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "extra.txt"), []byte("abc"), 0644))
+	// Same content with a new modification time is not a change
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "keep.txt"), []byte("same"), 0644))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(mainPath, "keep.txt"), later, later))
+
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", desc.SnapshotID))
+
+	data, err := os.ReadFile(filepath.Join(repoPath, ".jvs", "audit", "audit.jsonl"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last model.AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	require.Equal(t, model.EventTypeRestore, last.EventType)
+	assert.Equal(t, map[string]any{
+		"added":         float64(0),
+		"removed":       float64(1),
+		"overwritten":   float64(1),
+		"bytes_changed": float64(len("snapshot-content") + len("abc")),
+	}, last.Details["changes"])
+}
+
+// lastAuditRecord returns the newest record of the repository's audit log.
+func lastAuditRecord(t *testing.T, repoPath string) model.AuditRecord {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(repoPath, ".jvs", "audit", "audit.jsonl"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last model.AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	return last
+}
+
+func TestRestorer_Restore_AuditsDegradations(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // no juicefs command
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineJuiceFSClone).Restore("main", desc.SnapshotID))
+
+	last := lastAuditRecord(t, repoPath)
+	require.Equal(t, model.EventTypeRestore, last.EventType)
+	assert.Equal(t, []any{"juicefs-not-available"}, last.Details["degradations"])
+}
+
+// serverCloneEngine stands in for juicefs clone; corrupt overwrites every
+// cloned file.txt with different content of the same size.
+type serverCloneEngine struct {
+	corrupt bool
+}
+
+func (e *serverCloneEngine) Name() model.EngineType { return model.EngineJuiceFSClone }
+
+func (e *serverCloneEngine) Clone(src, dst string) (*engine.CloneResult, error) {
+	if _, err := engine.NewCopyEngine().Clone(src, dst); err != nil {
+		return nil, err
+	}
+	if e.corrupt {
+		path := filepath.Join(dst, "file.txt")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", len(data))), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return &engine.CloneResult{}, nil
+}
+
+func (e *serverCloneEngine) CloneContext(_ context.Context, src, dst string) (*engine.CloneResult, error) {
+	return e.Clone(src, dst)
+}
+
+func TestRestorer_Restore_SpotChecksServerClone(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	mainPath := filepath.Join(repoPath, "main")
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("modified"), 0644))
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetEngine(&serverCloneEngine{})
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+	assert.NotContains(t, lastAuditRecord(t, repoPath).Details, "degradations")
+
+	// A clone that fails the spot-check is replaced by a copy
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("modified"), 0644))
+	restorer.SetEngine(&serverCloneEngine{corrupt: true})
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot-content", string(content))
+	assert.Equal(t, []any{"juicefs-clone-spot-check-failed"}, lastAuditRecord(t, repoPath).Details["degradations"])
+}
+
 func TestRestorer_Restore_DetachedStateInAuditLog(t *testing.T) {
 	// Test that detached state is recorded in audit log
 	repoPath := setupTestRepo(t)
@@ -762,6 +974,67 @@ func TestRestorer_Restore_WithCompression(t *testing.T) {
 	assert.Equal(t, "original content", string(content))
 }
 
+func TestRestorer_Restore_WithZstdCompression(t *testing.T) {
+	repoPath := setupTestRepo(t)
+
+	mp := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mp, "file.txt"), []byte("original content"), 0644))
+	// Payload files with a gzip suffix are data, not gzip compression
+	require.NoError(t, os.WriteFile(filepath.Join(mp, "data.gz"), []byte("not gzip"), 0644))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompressor(compression.NewCompressorWithType(compression.TypeZstd, compression.LevelMax))
+	desc, err := creator.Create("main", "zstd snapshot", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Compression)
+	assert.Equal(t, "zstd", desc.Compression.Type)
+	assert.FileExists(t, filepath.Join(repoPath, ".jvs", "snapshots", string(desc.SnapshotID), "file.txt.zst"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(mp, "file.txt"), []byte("modified content"), 0644))
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+
+	content, err := os.ReadFile(filepath.Join(mp, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "original content", string(content))
+	content, err = os.ReadFile(filepath.Join(mp, "data.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, "not gzip", string(content))
+}
+
+func TestRestorer_Restore_WithEncryption(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	cfg := config.Default()
+	cfg.Encryption = &config.Encryption{Recipients: []string{id.Recipient().String()}}
+	require.NoError(t, config.Save(repoPath, cfg))
+	defer config.InvalidateCache(repoPath)
+
+	mp := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mp, "file.txt"), []byte("original content"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "encrypted", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Encryption)
+	require.NoError(t, os.WriteFile(filepath.Join(mp, "file.txt"), []byte("modified content"), 0644))
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	t.Setenv("JVS_AGE_IDENTITY", "")
+	t.Setenv("JVS_AGE_IDENTITY_FILE", "")
+	require.Error(t, restorer.Restore("main", desc.SnapshotID))
+
+	t.Setenv("JVS_AGE_IDENTITY", id.String())
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+	content, err := os.ReadFile(filepath.Join(mp, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "original content", string(content))
+	assert.NoFileExists(t, filepath.Join(mp, "file.txt.age"))
+
+	restorer.SetPaths([]string{"file.txt"})
+	assert.ErrorContains(t, restorer.Restore("main", desc.SnapshotID), "encrypted")
+}
+
 func TestRestorer_Restore_CorruptedSnapshotData(t *testing.T) {
 	// Test restore when snapshot data is corrupted
 	repoPath := setupTestRepo(t)
@@ -845,3 +1118,190 @@ func TestRestorer_Restore_EmptyBothArgs(t *testing.T) {
 	err := restorer.Restore("", "")
 	assert.Error(t, err)
 }
+
+func TestRestorer_Restore_RefusedWhileLeased(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	mainPath := filepath.Join(repoPath, "main")
+	os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("agent-writing"), 0644)
+
+	_, err := lease.NewManager(repoPath).Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	err = restorer.Restore("main", desc.SnapshotID)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeBusy))
+	var busy *lease.BusyError
+	require.True(t, errors.As(err, &busy))
+	assert.Equal(t, "agent-pod-1", busy.Lease.Holder)
+
+	// Payload untouched
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "agent-writing", string(content))
+
+	// RestoreToLatest is guarded too
+	assert.True(t, errors.Is(restorer.RestoreToLatest("main"), errclass.ErrWorktreeBusy))
+}
+
+func TestRestorer_Restore_ForceOverridesLease(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	mainPath := filepath.Join(repoPath, "main")
+	os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("agent-writing"), 0644)
+
+	_, err := lease.NewManager(repoPath).Acquire("main", "agent-pod-1", time.Hour)
+	require.NoError(t, err)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetForce(true)
+	require.NoError(t, restorer.Restore("main", desc.SnapshotID))
+
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot-content", string(content))
+}
+
+func TestRestorer_Restore_ExpiredLeaseIgnored(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	_, err := lease.NewManager(repoPath).Acquire("main", "agent-pod-1", time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	assert.NoError(t, restorer.Restore("main", desc.SnapshotID))
+}
+
+func TestRestorer_Restore_SnapshotNotReady(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("current"), 0644))
+
+	// Simulate a snapshot that was never published
+	require.NoError(t, os.Remove(filepath.Join(repo.SnapshotPath(repoPath, desc.SnapshotID), ".READY")))
+
+	err := restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", desc.SnapshotID)
+	require.ErrorIs(t, err, errclass.ErrSnapshotNotReady)
+
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "current", string(content), "worktree must be untouched")
+}
+
+func TestRestorer_Restore_Ephemeral(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	first := createSnapshot(t, repoPath)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("v2"), 0644))
+	second, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "v2", nil)
+	require.NoError(t, err)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetEphemeral(true)
+	require.NoError(t, restorer.Restore("main", first.SnapshotID))
+
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot-content", string(content))
+
+	cfg, err := worktree.NewManager(repoPath).Get("main")
+	require.NoError(t, err)
+	assert.Equal(t, second.SnapshotID, cfg.HeadSnapshotID)
+	assert.False(t, cfg.IsDetached())
+
+	data, err := os.ReadFile(filepath.Join(repoPath, ".jvs", "audit", "audit.jsonl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"event_type":"restore_ephemeral"`)
+}
+
+func TestRestorer_Restore_RemovesIntent(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", desc.SnapshotID))
+	entries, err := os.ReadDir(repo.IntentsDir(repoPath))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the intent only lives while the restore runs")
+}
+
+func TestRestorer_RestorePaths(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "data", "deep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data", "deep", "a.bin"), []byte("aaa"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data", "b.bin"), []byte("bbb"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "config"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "other"), []byte("v1"), 0644))
+	first, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(mainPath, "data")))
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data", "stray"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "config"), []byte("v2"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "other"), []byte("v2"), 0644))
+	second, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "second", nil)
+	require.NoError(t, err)
+
+	restorer := restore.NewRestorer(repoPath, model.EngineCopy)
+	restorer.SetPaths([]string{"data", "config", "data/deep"})
+	require.NoError(t, restorer.Restore("main", first.SnapshotID))
+
+	for path, want := range map[string]string{"data/deep/a.bin": "aaa", "data/b.bin": "bbb", "config": "v1", "other": "v2"} {
+		content, err := os.ReadFile(filepath.Join(mainPath, path))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content), path)
+	}
+	assert.NoFileExists(t, filepath.Join(mainPath, "data", "stray"), "restored directories are replaced")
+	info, err := os.Stat(filepath.Join(mainPath, "data", "deep", "a.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cfg, err := worktree.NewManager(repoPath).Get("main")
+	require.NoError(t, err)
+	assert.Equal(t, second.SnapshotID, cfg.HeadSnapshotID, "a path restore does not move the head")
+
+	restorer.SetPaths([]string{"missing"})
+	assert.Error(t, restorer.Restore("main", first.SnapshotID))
+	restorer.SetPaths([]string{"../escape"})
+	assert.ErrorIs(t, restorer.Restore("main", first.SnapshotID), errclass.ErrPathEscape)
+
+	require.NoError(t, os.Remove(repo.ManifestPath(repoPath, first.SnapshotID)))
+	restorer.SetPaths([]string{"config"})
+	assert.ErrorContains(t, restorer.Restore("main", first.SnapshotID), "no manifest")
+}
+
+func TestRestorer_Hooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need an executable bit and sh")
+	}
+	repoPath := setupTestRepo(t)
+	desc := createSnapshot(t, repoPath)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("modified"), 0644))
+
+	hookDir := hooks.Dir(repoPath)
+	require.NoError(t, os.MkdirAll(hookDir, 0755))
+	pre := filepath.Join(hookDir, hooks.PreRestore)
+	require.NoError(t, os.WriteFile(pre, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(hookDir, hooks.PostRestore),
+		[]byte("#!/bin/sh\necho \"$JVS_SNAPSHOT_ID\" > \"$JVS_WORKTREE_PATH/../restored.txt\"\n"), 0755))
+
+	err := restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", desc.SnapshotID)
+	require.ErrorIs(t, err, errclass.ErrHookFailed)
+	content, err := os.ReadFile(filepath.Join(mainPath, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "modified", string(content), "a failing pre-restore hook aborts the restore")
+
+	require.NoError(t, os.Remove(pre))
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", desc.SnapshotID))
+	restored, err := os.ReadFile(filepath.Join(repoPath, "restored.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, string(desc.SnapshotID)+"\n", string(restored))
+}
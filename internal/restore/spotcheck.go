@@ -0,0 +1,84 @@
+package restore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// spotCheckFiles is how many files of a server-side clone have their
+// content compared with the snapshot.
+const spotCheckFiles = 16
+
+// spotCheck compares a server-side clone dst with the snapshot directory
+// src it was cloned from. Every entry must exist in dst with the same type,
+// and regular files the same size; the content of up to spotCheckFiles
+// files, spread evenly over the tree, must be identical. Reading every file
+// would cost what the clone saved, so this only catches a clone that went
+// wrong, not every possible corruption.
+func spotCheck(src, dst string) error {
+	var files []string
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		srcInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dstInfo, err := os.Lstat(filepath.Join(dst, rel))
+		if err != nil {
+			return fmt.Errorf("%s missing from clone", rel)
+		}
+		if srcInfo.Mode().Type() != dstInfo.Mode().Type() {
+			return fmt.Errorf("%s has a different type in the clone", rel)
+		}
+		if srcInfo.Mode().IsRegular() {
+			if srcInfo.Size() != dstInfo.Size() {
+				return fmt.Errorf("%s has size %d in the clone, want %d", rel, dstInfo.Size(), srcInfo.Size())
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	step := max((len(files)+spotCheckFiles-1)/spotCheckFiles, 1)
+	for i := 0; i < len(files); i += step {
+		want, err := fileSum(filepath.Join(src, files[i]))
+		if err != nil {
+			return err
+		}
+		got, err := fileSum(filepath.Join(dst, files[i]))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(want, got) {
+			return fmt.Errorf("%s differs in the clone", files[i])
+		}
+	}
+	return nil
+}
+
+func fileSum(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
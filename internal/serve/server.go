@@ -0,0 +1,193 @@
+// Package serve implements 'jvs serve', a long-running process that watches a
+// repository and reacts to new snapshots. It is optional: every other JVS
+// operation works without it.
+package serve
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/metrics"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/webhook"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/logging"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// NotifyAnnotation marks snapshots that trigger webhooks when set to "true".
+const NotifyAnnotation = "ci.notify"
+
+// DefaultInterval is how often the repository is polled for new snapshots.
+const DefaultInterval = 2 * time.Second
+
+// Options configures a Server.
+type Options struct {
+	RepoID    string
+	Interval  time.Duration // zero uses DefaultInterval
+	Endpoints []webhook.Endpoint
+	Sender    *webhook.Sender // nil uses webhook.NewSender()
+
+	// Metrics, if set, counts the snapshots that appear and disappear
+	// between polls as created and GC-deleted.
+	Metrics *metrics.Metrics
+
+	// Staleness, if it sets a maximum age, makes the server POST a
+	// worktree.stale event to every endpoint when a worktree becomes
+	// stale, including worktrees already stale when Run starts. A worktree
+	// is announced again only after a snapshot made it fresh.
+	Staleness model.StalenessPolicy
+}
+
+// Server polls a repository for new snapshots and POSTs a snapshot.created
+// event to every endpoint for snapshots annotated with ci.notify=true.
+// Snapshots that exist when Run starts are not announced, nor are snapshots
+// created while the server is not running.
+type Server struct {
+	repoRoot string
+	opts     Options
+	seen     map[model.SnapshotID]bool
+	stale    map[string]bool
+}
+
+// New creates a Server for the repository at repoRoot.
+func New(repoRoot string, opts Options) *Server {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Sender == nil {
+		opts.Sender = webhook.NewSender()
+	}
+	return &Server{repoRoot: repoRoot, opts: opts, stale: make(map[string]bool)}
+}
+
+// Run watches the repository until ctx is cancelled. It returns nil on
+// cancellation, or an error if the initial scan fails.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.prime(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.poll(ctx)
+		}
+	}
+}
+
+// prime records the snapshots that already exist so they are not announced.
+func (s *Server) prime() error {
+	descs, err := snapshot.ListAll(s.repoRoot)
+	if err != nil {
+		return err
+	}
+	s.seen = make(map[model.SnapshotID]bool, len(descs))
+	for _, desc := range descs {
+		s.seen[desc.SnapshotID] = true
+	}
+	return nil
+}
+
+// poll announces snapshots created since the last poll, oldest first.
+func (s *Server) poll(ctx context.Context) {
+	descs, err := snapshot.ListAll(s.repoRoot)
+	if err != nil {
+		logging.Warn("serve: list snapshots", map[string]any{"error": err.Error()})
+		return
+	}
+	s.forgetDeleted(descs)
+	for i := len(descs) - 1; i >= 0; i-- {
+		desc := descs[i]
+		if s.seen[desc.SnapshotID] {
+			continue
+		}
+		s.seen[desc.SnapshotID] = true
+		if s.opts.Metrics != nil {
+			s.opts.Metrics.SnapshotsCreated.Inc()
+		}
+		if desc.Annotations[NotifyAnnotation] != "true" {
+			continue
+		}
+		s.notify(ctx, desc)
+	}
+	s.checkStale(ctx)
+}
+
+// checkStale announces the worktrees that became stale since the last
+// poll.
+func (s *Server) checkStale(ctx context.Context) {
+	policy := s.opts.Staleness
+	if policy.MaxAge <= 0 && len(policy.Worktrees) == 0 {
+		return
+	}
+	wts, err := worktree.NewManager(s.repoRoot).List()
+	if err != nil {
+		logging.Warn("serve: list worktrees", map[string]any{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	current := make(map[string]bool, len(wts))
+	for _, cfg := range wts {
+		st, err := snapshot.CheckStale(s.repoRoot, cfg, policy, now)
+		if err != nil {
+			logging.Warn("serve: check staleness", map[string]any{"worktree": cfg.Name, "error": err.Error()})
+			continue
+		}
+		if st == nil {
+			continue
+		}
+		current[cfg.Name] = true
+		if !s.stale[cfg.Name] {
+			s.send(ctx, webhook.NewStaleEvent(s.opts.RepoID, st), map[string]any{"worktree": cfg.Name})
+		}
+	}
+	s.stale = current
+}
+
+// forgetDeleted drops the snapshots that are no longer listed from the
+// seen set, counting them as deleted by GC.
+func (s *Server) forgetDeleted(descs []*model.Descriptor) {
+	listed := make(map[model.SnapshotID]bool, len(descs))
+	for _, desc := range descs {
+		listed[desc.SnapshotID] = true
+	}
+	for id := range s.seen {
+		if listed[id] {
+			continue
+		}
+		delete(s.seen, id)
+		if s.opts.Metrics != nil {
+			s.opts.Metrics.GCDeletions.Inc()
+		}
+	}
+}
+
+func (s *Server) notify(ctx context.Context, desc *model.Descriptor) {
+	s.send(ctx, webhook.NewSnapshotEvent(s.opts.RepoID, desc), map[string]any{"snapshot_id": string(desc.SnapshotID)})
+}
+
+// send POSTs event to every endpoint, logging each delivery with fields.
+func (s *Server) send(ctx context.Context, event *webhook.Event, fields map[string]any) {
+	for _, ep := range s.opts.Endpoints {
+		if !ep.Wants(event.Type) {
+			continue
+		}
+		if err := s.opts.Sender.Send(ctx, ep, event); err != nil {
+			logging.Warn("serve: webhook delivery failed", withFields(fields, "error", err.Error()))
+			continue
+		}
+		logging.Info("serve: webhook delivered", withFields(fields, "url", ep.URL))
+	}
+}
+
+func withFields(fields map[string]any, key string, value any) map[string]any {
+	out := maps.Clone(fields)
+	out[key] = value
+	return out
+}
@@ -0,0 +1,157 @@
+package serve_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/metrics"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/restore"
+	"github.com/jvs-project/jvs/internal/serve"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/webhook"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func createSnapshot(t *testing.T, repoPath, note string, annotations map[string]string) *model.Descriptor {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte(note), 0644))
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetAnnotations(annotations)
+	desc, err := creator.Create("main", note, nil)
+	require.NoError(t, err)
+	return desc
+}
+
+func TestServer_NotifiesAnnotatedSnapshots(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+
+	// Existing snapshots are not announced
+	createSnapshot(t, repoPath, "before", map[string]string{serve.NotifyAnnotation: "true"})
+
+	events := make(chan webhook.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e webhook.Event
+		if json.NewDecoder(r.Body).Decode(&e) == nil {
+			events <- e
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve.New(repoPath, serve.Options{
+			RepoID:    "repo-1",
+			Interval:  10 * time.Millisecond,
+			Endpoints: []webhook.Endpoint{{URL: srv.URL}},
+		}).Run(ctx)
+	}()
+	// Let the server take its initial inventory
+	time.Sleep(50 * time.Millisecond)
+
+	createSnapshot(t, repoPath, "quiet", nil)
+	notified := createSnapshot(t, repoPath, "loud", map[string]string{serve.NotifyAnnotation: "true"})
+
+	select {
+	case e := <-events:
+		assert.Equal(t, webhook.EventSnapshotCreated, e.Type)
+		assert.Equal(t, notified.SnapshotID, e.Snapshot.SnapshotID)
+		assert.Equal(t, "repo-1", e.RepoID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivered")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, events, "only the annotated new snapshot is announced")
+}
+
+func TestServer_CountsSnapshots(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+	createSnapshot(t, repoPath, "before", nil)
+
+	m := metrics.New(prometheus.NewRegistry())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve.New(repoPath, serve.Options{Interval: 10 * time.Millisecond, Metrics: m}).Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	first := createSnapshot(t, repoPath, "first", nil)
+	createSnapshot(t, repoPath, "second", nil)
+	require.Eventually(t, func() bool { return testutil.ToFloat64(m.SnapshotsCreated) == 2 },
+		5*time.Second, 10*time.Millisecond, "existing snapshots are not counted")
+
+	// Detach main so its latest snapshot can be collected
+	require.NoError(t, restore.NewRestorer(repoPath, model.EngineCopy).Restore("main", first.SnapshotID))
+	collector := gc.NewCollector(repoPath)
+	plan, err := collector.PlanWithPolicy(model.RetentionPolicy{KeepMinAge: time.Nanosecond})
+	require.NoError(t, err)
+	require.Len(t, plan.ToDelete, 1)
+	require.NoError(t, collector.Run(plan.PlanID))
+	require.Eventually(t, func() bool { return testutil.ToFloat64(m.GCDeletions) == 1 },
+		5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.SnapshotsCreated))
+}
+
+func TestServer_NotifiesStaleWorktrees(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := repo.Init(repoPath, "test")
+	require.NoError(t, err)
+
+	events := make(chan webhook.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e webhook.Event
+		if json.NewDecoder(r.Body).Decode(&e) == nil {
+			events <- e
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve.New(repoPath, serve.Options{
+			Interval:  10 * time.Millisecond,
+			Endpoints: []webhook.Endpoint{{URL: srv.URL}},
+			Staleness: model.StalenessPolicy{MaxAge: time.Nanosecond},
+		}).Run(ctx)
+	}()
+
+	select {
+	case e := <-events:
+		assert.Equal(t, webhook.EventWorktreeStale, e.Type)
+		require.NotNil(t, e.Staleness)
+		assert.Equal(t, "main", e.Staleness.Worktree)
+		assert.Empty(t, e.Staleness.LastSnapshotID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivered")
+	}
+
+	// Still stale on later polls, but announced once
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, events)
+}
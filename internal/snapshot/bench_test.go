@@ -109,6 +109,22 @@ func BenchmarkSnapshotCreation_CopyEngine_Large(b *testing.B) {
 	}
 }
 
+// BenchmarkSnapshotCreation_CopyEngine_TwoPassHash benchmarks snapshot creation
+// with the payload hashed after copying, for comparison with the single-pass default.
+func BenchmarkSnapshotCreation_CopyEngine_TwoPassHash(b *testing.B) {
+	repoPath := setupBenchRepo(b, 1024*1024) // 1MB
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetTwoPassHash(true)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := creator.Create("main", "bench snapshot", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // BenchmarkSnapshotCreation_ReflinkEngine_Small benchmarks snapshot creation with small payload using reflink engine.
 func BenchmarkSnapshotCreation_ReflinkEngine_Small(b *testing.B) {
 	repoPath := setupBenchRepo(b, 1024) // 1KB
@@ -1,17 +1,24 @@
 package snapshot
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
-// ListAll returns all snapshot descriptors sorted by creation time (newest first).
+// ListAll returns all snapshot descriptors, newest first as ordered by
+// model.CompareNewestFirst.
 func ListAll(repoRoot string) ([]*model.Descriptor, error) {
 	snapshotsDir := filepath.Join(repoRoot, ".jvs", "snapshots")
 	entries, err := os.ReadDir(snapshotsDir)
@@ -22,24 +29,33 @@ func ListAll(repoRoot string) ([]*model.Descriptor, error) {
 		return nil, fmt.Errorf("read snapshots directory: %w", err)
 	}
 
-	var descriptors []*model.Descriptor
+	present := make(map[model.SnapshotID]bool, len(entries))
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		if entry.IsDir() {
+			present[model.SnapshotID(entry.Name())] = true
 		}
-		snapshotID := model.SnapshotID(entry.Name())
-		desc, err := LoadDescriptor(repoRoot, snapshotID)
-		if err != nil {
-			// Skip corrupted/missing descriptors
-			continue
+	}
+
+	store, err := descstore.Open(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	all, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only READY snapshots with a payload directory are listed; corrupted
+	// descriptors are skipped by the store
+	var descriptors []*model.Descriptor
+	for _, desc := range all {
+		if present[desc.SnapshotID] && repo.CheckSnapshotReady(repoRoot, desc.SnapshotID) == nil {
+			descriptors = append(descriptors, desc)
 		}
-		descriptors = append(descriptors, desc)
 	}
 
-	// Sort by creation time (newest first)
-	sort.Slice(descriptors, func(i, j int) bool {
-		return descriptors[i].CreatedAt.After(descriptors[j].CreatedAt)
-	})
+	slices.SortFunc(descriptors, model.CompareNewestFirst)
 
 	return descriptors, nil
 }
@@ -49,13 +65,64 @@ type FilterOptions struct {
 	WorktreeName string
 	NoteContains string
 	HasTag       string
+	LacksTag     string
 	Since        time.Time
 	Until        time.Time
+	// Tags keeps snapshots carrying all of these tags.
+	Tags []string
+	// Environment keeps snapshots whose captured environment has all of
+	// these key/values. The EnvKey* keys select the hostname, image digest
+	// and jvs version; other keys select captured variables.
+	Environment map[string]string
+	// Author keeps snapshots taken by this author: the same identity or,
+	// given without a host, the same user on any host.
+	Author string
+}
+
+// ListIndexed returns all snapshot descriptors, newest first, like
+// ListAll, but reads them from the repository index instead of parsing
+// every descriptor, building the index if the repository has none yet. If
+// the index cannot be read or built, e.g. in a read-only repository, it
+// falls back to ListAll.
+//
+// The index is updated as snapshots are created, amended and deleted, but
+// failed updates only warn and snapshots removed behind jvs's back are not
+// noticed; callers that must not act on a stale listing, such as GC, use
+// ListAll.
+func ListIndexed(repoRoot string) ([]*model.Descriptor, error) {
+	return listIndexed(repoRoot, FilterOptions{})
+}
+
+// listIndexed returns the indexed descriptors that may match opts, newest
+// first: those of its worktree and first tag, if set, so that finding the
+// snapshots of a worktree or tag reads only those. It falls back to
+// ListAll as ListIndexed does.
+func listIndexed(repoRoot string, opts FilterOptions) ([]*model.Descriptor, error) {
+	idx, err := openIndex(repoRoot)
+	if err != nil {
+		return ListAll(repoRoot)
+	}
+	defer idx.Close()
+	tag := opts.HasTag
+	if tag == "" && len(opts.Tags) > 0 {
+		tag = opts.Tags[0]
+	}
+	var descs []*model.Descriptor
+	if tag != "" {
+		descs, err = idx.Tagged(tag, opts.WorktreeName)
+	} else {
+		descs, err = idx.List(opts.WorktreeName)
+	}
+	if err != nil {
+		return ListAll(repoRoot)
+	}
+	return descs, nil
 }
 
-// Find returns snapshots matching filter criteria.
+// Find returns snapshots matching filter criteria, newest first. It reads
+// the repository index; see ListIndexed.
 func Find(repoRoot string, opts FilterOptions) ([]*model.Descriptor, error) {
-	all, err := ListAll(repoRoot)
+	all, err := listIndexed(repoRoot, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +138,63 @@ func Find(repoRoot string, opts FilterOptions) ([]*model.Descriptor, error) {
 	return result, nil
 }
 
+// ListPage returns the first limit snapshots matching opts after cursor,
+// in the order of ListAll, and the cursor of the next page, which is empty
+// after the last page. An empty cursor starts with the newest snapshot.
+// Unlike ListAll, it keeps at most about twice limit descriptors in memory.
+func ListPage(repoRoot string, opts FilterOptions, cursor string, limit int) ([]*model.Descriptor, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("page limit must be positive, got %d", limit)
+	}
+	pageOpts := descstore.PageOptions{
+		// One more than asked for tells whether there is a next page
+		Limit: limit + 1,
+		Match: func(desc *model.Descriptor) bool {
+			return matchesFilter(desc, opts) && repo.CheckSnapshotReady(repoRoot, desc.SnapshotID) == nil
+		},
+	}
+	if cursor != "" {
+		var err error
+		if pageOpts.AfterTime, pageOpts.AfterID, err = decodeCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	store, err := descstore.Open(repoRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	defer store.Close()
+	page, err := store.Page(pageOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(page) <= limit {
+		return page, "", nil
+	}
+	page = page[:limit]
+	last := page[limit-1]
+	return page, encodeCursor(last.CreatedAt, last.SnapshotID), nil
+}
+
+// encodeCursor returns an opaque page cursor for the snapshot ordered
+// last on a page.
+func encodeCursor(createdAt time.Time, id model.SnapshotID) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + "/" + string(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, model.SnapshotID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		nanos, id, ok := strings.Cut(string(raw), "/")
+		if n, convErr := strconv.ParseInt(nanos, 10, 64); ok && convErr == nil && id != "" {
+			return time.Unix(0, n).UTC(), model.SnapshotID(id), nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("invalid page cursor %q", cursor)
+}
+
 func matchesFilter(desc *model.Descriptor, opts FilterOptions) bool {
 	if opts.WorktreeName != "" && desc.WorktreeName != opts.WorktreeName {
 		return false
@@ -81,12 +205,26 @@ func matchesFilter(desc *model.Descriptor, opts FilterOptions) bool {
 	if opts.HasTag != "" && !hasTag(desc, opts.HasTag) {
 		return false
 	}
+	if opts.LacksTag != "" && hasTag(desc, opts.LacksTag) {
+		return false
+	}
+	for _, tag := range opts.Tags {
+		if !hasTag(desc, tag) {
+			return false
+		}
+	}
 	if !opts.Since.IsZero() && desc.CreatedAt.Before(opts.Since) {
 		return false
 	}
 	if !opts.Until.IsZero() && desc.CreatedAt.After(opts.Until) {
 		return false
 	}
+	if !MatchesEnvironment(desc.Environment, opts.Environment) {
+		return false
+	}
+	if opts.Author != "" && !identity.Matches(desc.Author, opts.Author) {
+		return false
+	}
 	return true
 }
 
@@ -102,7 +240,7 @@ func hasTag(desc *model.Descriptor, tag string) bool {
 // FindOne finds a single snapshot by fuzzy match (note/tag prefix).
 // Returns error if multiple matches or no matches.
 func FindOne(repoRoot string, query string) (*model.Descriptor, error) {
-	all, err := ListAll(repoRoot)
+	all, err := ListIndexed(repoRoot)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +253,7 @@ func FindOne(repoRoot string, query string) (*model.Descriptor, error) {
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no snapshot found matching %q", query)
+		return nil, errclass.ErrSnapshotNotFound.WithMessagef("no snapshot found matching %q", query)
 	}
 	if len(matches) > 1 {
 		var ids []string
@@ -139,11 +277,12 @@ func matchesQuery(desc *model.Descriptor, query string) bool {
 			return true
 		}
 	}
-	// Check if query matches snapshot ID prefix
+	// Check if query matches snapshot ID prefix, with or without the
+	// worktree prefix of prefixed IDs
 	if strings.HasPrefix(string(desc.SnapshotID), query) {
 		return true
 	}
-	return false
+	return desc.SnapshotID.Prefix() != "" && strings.HasPrefix(desc.SnapshotID.Unprefixed(), query)
 }
 
 // FindByTag returns the latest snapshot with the given tag.
@@ -154,8 +293,33 @@ func FindByTag(repoRoot string, tag string) (*model.Descriptor, error) {
 		return nil, err
 	}
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no snapshot found with tag %q", tag)
+		return nil, errclass.ErrSnapshotNotFound.WithMessagef("no snapshot found with tag %q", tag)
 	}
 	// ListAll returns newest first, so first match is latest
 	return matches[0], nil
 }
+
+// LatestWithTag returns the newest snapshot of a worktree that carries tag.
+func LatestWithTag(repoRoot, worktreeName, tag string) (*model.Descriptor, error) {
+	matches, err := Find(repoRoot, FilterOptions{WorktreeName: worktreeName, HasTag: tag})
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, errclass.ErrSnapshotNotFound.WithMessagef("no snapshot of worktree %q has tag %q", worktreeName, tag)
+	}
+	return matches[0], nil
+}
+
+// LatestWithoutTag returns the newest snapshot of a worktree that does not
+// carry tag, e.g. the last state before an experiment was started.
+func LatestWithoutTag(repoRoot, worktreeName, tag string) (*model.Descriptor, error) {
+	matches, err := Find(repoRoot, FilterOptions{WorktreeName: worktreeName, LacksTag: tag})
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, errclass.ErrSnapshotNotFound.WithMessagef("every snapshot of worktree %q has tag %q", worktreeName, tag)
+	}
+	return matches[0], nil
+}
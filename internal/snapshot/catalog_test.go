@@ -3,12 +3,15 @@ package snapshot_test
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/jvs-project/jvs/internal/descstore"
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -92,6 +95,34 @@ func TestListAll_SortedByTime(t *testing.T) {
 	assert.Equal(t, desc1.SnapshotID, all[2].SnapshotID)
 }
 
+func TestListAll_SameCreatedAtOrderedByID(t *testing.T) {
+	repoPath := setupCatalogTestRepo(t)
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Snapshots created in the same instant, as on a coarse clock
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var ids []model.SnapshotID
+	for _, note := range []string{"a", "b", "c"} {
+		desc := createCatalogSnapshot(t, repoPath, note, nil)
+		desc.CreatedAt = createdAt
+		require.NoError(t, store.Put(desc))
+		ids = append(ids, desc.SnapshotID)
+	}
+	slices.Sort(ids)
+	slices.Reverse(ids)
+
+	for range 3 {
+		all, err := snapshot.ListAll(repoPath)
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		for i, desc := range all {
+			assert.Equal(t, ids[i], desc.SnapshotID)
+		}
+	}
+}
+
 func TestFind_ByNote(t *testing.T) {
 	repoPath := setupCatalogTestRepo(t)
 
@@ -119,6 +150,41 @@ func TestFind_ByTag(t *testing.T) {
 	assert.Equal(t, "release", matches[0].Tags[1])
 }
 
+func TestListPage(t *testing.T) {
+	repoPath := setupCatalogTestRepo(t)
+
+	var tagged []model.SnapshotID
+	for i := range 5 {
+		tags := []string{"nightly"}
+		if i%2 == 0 {
+			tags = append(tags, "release")
+		}
+		desc := createCatalogSnapshot(t, repoPath, "", tags)
+		if i%2 == 0 {
+			tagged = append(tagged, desc.SnapshotID)
+		}
+	}
+	slices.Reverse(tagged)
+
+	opts := snapshot.FilterOptions{Tags: []string{"nightly", "release"}}
+	page, cursor, err := snapshot.ListPage(repoPath, opts, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.NotEmpty(t, cursor)
+	assert.Equal(t, tagged[:2], []model.SnapshotID{page[0].SnapshotID, page[1].SnapshotID})
+
+	page, cursor, err = snapshot.ListPage(repoPath, opts, cursor, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, tagged[2], page[0].SnapshotID)
+	assert.Empty(t, cursor, "last page")
+
+	_, _, err = snapshot.ListPage(repoPath, opts, "not a cursor", 2)
+	assert.Error(t, err)
+	_, _, err = snapshot.ListPage(repoPath, opts, "", 0)
+	assert.Error(t, err)
+}
+
 func TestFind_ByWorktree(t *testing.T) {
 	repoPath := setupCatalogTestRepo(t)
 
@@ -574,3 +640,75 @@ func TestLoadDescriptor_NonExistent(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
 }
+
+func TestLatestWithTag_AndWithoutTag(t *testing.T) {
+	repoPath := setupCatalogTestRepo(t)
+
+	stable := createCatalogSnapshot(t, repoPath, "stable", []string{"release"})
+	time.Sleep(10 * time.Millisecond)
+	exp1 := createCatalogSnapshot(t, repoPath, "try a", []string{"experiment"})
+	time.Sleep(10 * time.Millisecond)
+	exp2 := createCatalogSnapshot(t, repoPath, "try b", []string{"experiment"})
+
+	desc, err := snapshot.LatestWithoutTag(repoPath, "main", "experiment")
+	require.NoError(t, err)
+	assert.Equal(t, stable.SnapshotID, desc.SnapshotID)
+
+	desc, err = snapshot.LatestWithTag(repoPath, "main", "experiment")
+	require.NoError(t, err)
+	assert.Equal(t, exp2.SnapshotID, desc.SnapshotID)
+	assert.NotEqual(t, exp1.SnapshotID, desc.SnapshotID)
+
+	_, err = snapshot.LatestWithTag(repoPath, "main", "missing")
+	assert.Error(t, err)
+	_, err = snapshot.LatestWithoutTag(repoPath, "other", "experiment")
+	assert.Error(t, err)
+}
+
+func TestFindOne_MixedIDSchemes(t *testing.T) {
+	repoPath := setupCatalogTestRepo(t)
+	defer config.InvalidateCache(repoPath)
+	plain := createCatalogSnapshot(t, repoPath, "plain", nil)
+
+	// Switching schemes needs no migration; both kinds of ID coexist
+	cfg := config.Default()
+	require.NoError(t, cfg.Set("snapshot_id_scheme", config.SnapshotIDSchemeWorktree))
+	require.NoError(t, config.Save(repoPath, cfg))
+	prefixed := createCatalogSnapshot(t, repoPath, "prefixed", nil)
+	assert.Equal(t, "main", prefixed.SnapshotID.Prefix())
+	require.NotNil(t, prefixed.ParentID)
+	assert.Equal(t, plain.SnapshotID, *prefixed.ParentID)
+
+	// Prefixed IDs resolve by full ID, by prefix, and by the part after
+	// the worktree prefix
+	for _, query := range []string{string(prefixed.SnapshotID), prefixed.SnapshotID.ShortID(), prefixed.SnapshotID.Unprefixed()[:18]} {
+		found, err := snapshot.FindOne(repoPath, query)
+		require.NoError(t, err, query)
+		assert.Equal(t, prefixed.SnapshotID, found.SnapshotID)
+	}
+	found, err := snapshot.FindOne(repoPath, string(plain.SnapshotID))
+	require.NoError(t, err)
+	assert.Equal(t, plain.SnapshotID, found.SnapshotID)
+}
+
+func TestFindOne_DigitLeadingPrefix(t *testing.T) {
+	repoPath := setupCatalogTestRepo(t)
+	defer config.InvalidateCache(repoPath)
+	cfg := config.Default()
+	require.NoError(t, cfg.Set("snapshot_id_scheme", config.SnapshotIDSchemeWorktree))
+	require.NoError(t, config.Save(repoPath, cfg))
+
+	wtCfg, err := worktree.NewManager(repoPath).Create("2024exp", nil)
+	require.NoError(t, err)
+	payload := worktree.NewManager(repoPath).Path(wtCfg.Name)
+	require.NoError(t, os.WriteFile(filepath.Join(payload, "file.txt"), []byte("content"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("2024exp", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2024exp", desc.SnapshotID.Prefix())
+
+	for _, query := range []string{desc.SnapshotID.ShortID(), desc.SnapshotID.Unprefixed()[:18]} {
+		found, err := snapshot.FindOne(repoPath, query)
+		require.NoError(t, err, query)
+		assert.Equal(t, desc.SnapshotID, found.SnapshotID)
+	}
+}
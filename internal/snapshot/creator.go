@@ -2,21 +2,34 @@
 package snapshot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"filippo.io/age"
+
 	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/cas"
 	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/encryption"
 	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/hooks"
 	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/webhook"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/fsutil"
 	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/progress"
 )
 
 // Creator handles snapshot creation using the 12-step protocol.
@@ -26,6 +39,25 @@ type Creator struct {
 	engine      engine.Engine
 	auditLogger *audit.FileAppender
 	compression *compression.Compressor
+
+	followSymlinks      bool
+	maxDereferenceBytes int64
+	twoPassHash         bool
+	annotations         map[string]string
+	skipIfUnchanged     bool
+	incremental         bool
+	excludes            []string
+	captureEnv          bool
+	envAllow            []string
+	readErrorPolicy     model.ReadErrorPolicy
+	phaseObserver       PhaseObserver
+	progress            progress.ByteCallback
+	lockOwner           string
+	author              string
+	correlationID       string
+	lockWait            *time.Duration // nil waits lock_max_wait
+	ctx                 context.Context
+	degradations        []string
 }
 
 // NewCreator creates a new snapshot creator.
@@ -44,6 +76,7 @@ func NewCreatorWithCompression(repoRoot string, engineType model.EngineType, com
 		engine:      eng,
 		auditLogger: audit.NewFileAppender(auditPath),
 		compression: comp,
+		ctx:         context.Background(),
 	}
 }
 
@@ -52,20 +85,195 @@ func (c *Creator) SetCompression(level compression.CompressionLevel) {
 	c.compression = compression.NewCompressor(level)
 }
 
+// SetCompressor sets the compressor, and so the algorithm, for this creator.
+func (c *Creator) SetCompressor(comp *compression.Compressor) {
+	c.compression = comp
+}
+
+// SetFollowSymlinks enables dereferencing of symlinks that point outside the
+// worktree. maxBytes caps the amount of dereferenced data; zero or negative
+// uses DefaultMaxDereferenceBytes.
+func (c *Creator) SetFollowSymlinks(follow bool, maxBytes int64) {
+	c.followSymlinks = follow
+	c.maxDereferenceBytes = maxBytes
+}
+
+// SetTwoPassHash forces the payload hash to be computed in a separate pass
+// after cloning, even when the engine can hash while copying.
+func (c *Creator) SetTwoPassHash(twoPass bool) {
+	c.twoPassHash = twoPass
+}
+
+// SetSkipIfUnchanged makes full snapshots return the worktree's HEAD
+// descriptor, marked Skipped, instead of creating a new snapshot when the
+// payload is identical to HEAD.
+func (c *Creator) SetSkipIfUnchanged(skip bool) {
+	c.skipIfUnchanged = skip
+}
+
+// SetIncremental makes full snapshots hard-link files that are unchanged
+// since the worktree's HEAD snapshot instead of copying them. It has no
+// effect on partial, compressed, encrypted or frozen-worktree snapshots, or
+// when HEAD is compressed or encrypted.
+func (c *Creator) SetIncremental(incremental bool) {
+	c.incremental = incremental
+}
+
+// SetExcludes adds exclude patterns, in .jvsignore syntax, applied after the
+// worktree's .jvsignore file.
+func (c *Creator) SetExcludes(patterns []string) {
+	c.excludes = patterns
+}
+
+// SetCaptureEnvironment records the environment in the descriptor of new
+// snapshots: hostname, container image digest, jvs version and the
+// variables matching allow (see CaptureEnvironment).
+func (c *Creator) SetCaptureEnvironment(capture bool, allow []string) {
+	c.captureEnv = capture
+	c.envAllow = allow
+}
+
+// SetReadErrorPolicy selects what full snapshots do with files they cannot
+// read. Under model.ReadErrorSkip such files are left out and listed in the
+// descriptor's ReadErrors; the default fails the snapshot. Partial
+// snapshots always fail.
+func (c *Creator) SetReadErrorPolicy(policy model.ReadErrorPolicy) {
+	c.readErrorPolicy = policy
+}
+
+// SetPhaseObserver makes the creator report each phase of snapshot creation
+// (copy or copy_hash, hash, descriptor, publish, descriptor_write, ...) to
+// observe as it ends.
+func (c *Creator) SetPhaseObserver(observe PhaseObserver) {
+	c.phaseObserver = observe
+}
+
+// SetProgress makes the creator report the progress of a snapshot to fn:
+// phase "copy" as the payload is cloned and, if it is hashed in a pass of
+// its own, phase "hash". done and total count bytes of regular files.
+func (c *Creator) SetProgress(fn progress.ByteCallback) {
+	c.progress = fn
+}
+
+// SetAnnotations sets the key/value annotations recorded in the descriptor.
+func (c *Creator) SetAnnotations(annotations map[string]string) {
+	c.annotations = annotations
+}
+
+// SetAuthor sets who the descriptor and audit record name as the
+// snapshot's author, also passed to its hooks and webhooks. Empty, the
+// default, uses identity.Author.
+func (c *Creator) SetAuthor(author string) {
+	c.author = author
+	c.auditLogger.SetAuthor(author)
+}
+
+// SetCorrelationID sets the correlation ID of the operation the snapshot
+// belongs to, recorded in its audit record and passed to its hooks and
+// webhooks. Empty, the default, uses the one set through
+// audit.CorrelationEnvVar, if any.
+func (c *Creator) SetCorrelationID(id string) {
+	c.correlationID = id
+	c.auditLogger.SetCorrelationID(id)
+}
+
+// SetLockOwner sets who takes snapshots, as far as worktree locks are
+// concerned: a worktree locked by another owner is refused. Empty, the
+// default, uses CurrentActor.
+func (c *Creator) SetLockOwner(owner string) {
+	c.lockOwner = owner
+}
+
+// SetLockWait sets how long snapshots wait for the repository lock, instead
+// of the configured lock_max_wait. Zero gives up at once if an exclusive
+// operation such as GC holds it.
+func (c *Creator) SetLockWait(d time.Duration) {
+	c.lockWait = &d
+}
+
+// SetContext makes snapshots stop once ctx is done: cloning and hashing
+// the payload fail with ctx's error, and the unpublished snapshot is
+// removed. A snapshot that was already published is not affected.
+func (c *Creator) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Degradations returns the engine degradations of the last snapshot, such
+// as a reflink falling back to a copy. Only whole-payload clones report
+// them.
+func (c *Creator) Degradations() []string {
+	return c.degradations
+}
+
 // Create performs a full snapshot of the worktree using the 12-step protocol.
 func (c *Creator) Create(worktreeName, note string, tags []string) (*model.Descriptor, error) {
 	return c.CreatePartial(worktreeName, note, tags, nil)
 }
 
 // CreatePartial performs a snapshot of specific paths within the worktree.
-// If paths is nil or empty, performs a full snapshot.
+// If paths is nil or empty, performs a full snapshot. The repository's
+// pre-snapshot hook runs first and may abort it; the post-snapshot hook
+// runs after it succeeds, and then its snapshot.completed webhooks.
 func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths []string) (*model.Descriptor, error) {
+	ctx := audit.WithAuthor(audit.WithCorrelationID(c.ctx, c.correlationID), c.author)
+	vars := map[string]string{
+		hooks.EnvOperation:    "snapshot",
+		hooks.EnvWorktree:     worktreeName,
+		hooks.EnvWorktreePath: worktree.NewManager(c.repoRoot).Path(worktreeName),
+		hooks.EnvNote:         note,
+		hooks.EnvTags:         hooks.List(tags),
+	}
+	if len(paths) > 0 {
+		vars[hooks.EnvPaths] = hooks.List(paths)
+	}
+	if err := hooks.Run(ctx, c.repoRoot, hooks.PreSnapshot, vars); err != nil {
+		return nil, err
+	}
+	desc, err := c.createPartial(worktreeName, note, tags, paths)
+	if err != nil {
+		return nil, err
+	}
+	vars[hooks.EnvSnapshotID] = string(desc.SnapshotID)
+	if desc.Skipped {
+		vars[hooks.EnvSkipped] = "true"
+	}
+	hooks.RunPost(ctx, c.repoRoot, hooks.PostSnapshot, vars)
+	if !desc.Skipped {
+		webhook.Notify(ctx, c.repoRoot, webhook.NewSnapshotCompletedEvent(desc))
+	}
+	return desc, nil
+}
+
+func (c *Creator) createPartial(worktreeName, note string, tags []string, paths []string) (*model.Descriptor, error) {
+	locks := repolock.NewManager(c.repoRoot)
+	if c.lockWait != nil {
+		locks.SetMaxWait(*c.lockWait)
+	}
+	guard, err := locks.GuardWorktree(worktreeName, "snapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer guard.Release()
+	lock, err := locks.Acquire(model.LockShared, "snapshot")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+	timer := newPhaseTimer(c.phaseObserver)
+
 	// Step 1: Validate worktree exists
 	wtMgr := worktree.NewManager(c.repoRoot)
 	cfg, err := wtMgr.Get(worktreeName)
 	if err != nil {
 		return nil, fmt.Errorf("get worktree: %w", err)
 	}
+	owner := c.lockOwner
+	if owner == "" {
+		owner = CurrentActor()
+	}
+	if err := wtMgr.CheckUnlocked(worktreeName, owner); err != nil {
+		return nil, err
+	}
 
 	// Normalize and validate paths if provided
 	var partialPaths []string
@@ -76,8 +284,50 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 		}
 	}
 
-	// Step 2: Generate snapshot ID
-	snapshotID := model.NewSnapshotID()
+	// Full snapshots leave out paths matched by .jvsignore and the
+	// creator's excludes; partial snapshots copy the named paths as given
+	var ignore *Ignore
+	if len(partialPaths) == 0 {
+		ignore, err = LoadIgnore(wtMgr.Path(worktreeName), c.excludes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Skip no-op full snapshots before doing any work. The payload of a
+	// frozen worktree has its write bits cleared, and excluded paths are
+	// still in the worktree, so those are compared after the clone below
+	skipReadErrors := c.readErrorPolicy == model.ReadErrorSkip && len(partialPaths) == 0
+	if c.skipIfUnchanged && len(partialPaths) == 0 && cfg.HeadSnapshotID != "" && !cfg.Frozen && ignore.Empty() && !skipReadErrors {
+		head, err := c.unchangedHead(cfg.HeadSnapshotID, func(alg model.HashAlgorithm) (model.HashValue, error) {
+			return integrity.ComputePayloadRootHashContext(c.ctx, wtMgr.Path(worktreeName), alg)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if head != nil {
+			return head, nil
+		}
+	}
+
+	// Step 2: Generate snapshot ID using the repository's ID scheme
+	jvsCfg, err := config.Load(c.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	snapshotID := jvsCfg.NewSnapshotID(worktreeName)
+	timer.snapshotID = snapshotID
+	hashAlg := jvsCfg.GetHashAlgorithm()
+
+	// Encryption replaces every stored file, so encrypted snapshots are not
+	// compressed, put in the content store or linked from their parent
+	var recipients []age.Recipient
+	if jvsCfg.Encryption.Enabled() {
+		if recipients, err = encryption.ParseRecipients(jvsCfg.Encryption.Recipients); err != nil {
+			return nil, err
+		}
+	}
+	compress := c.compression != nil && c.compression.IsEnabled() && recipients == nil
 
 	// Step 3: Create intent record (for crash recovery)
 	intentPath := filepath.Join(c.repoRoot, ".jvs", "intents", string(snapshotID)+".json")
@@ -104,8 +354,34 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 		os.RemoveAll(snapshotTmpDir)
 	}
 
+	timer.mark("prepare")
+
 	// Step 5: Clone payload to snapshot .tmp directory
 	payloadPath := wtMgr.Path(worktreeName)
+	var payloadBytes int64
+	if c.progress != nil {
+		// Without progress reporting, the clone walks the tree as it
+		// copies and its walk is part of the copy phase
+		payloadBytes = payloadSize(payloadPath, partialPaths)
+		timer.mark("walk")
+	}
+	copied := progress.NewBytes("copy", payloadBytes, c.progress)
+	if pe, ok := c.engine.(engine.ProgressEngine); ok && c.progress != nil {
+		pe.SetProgress(copied.Add)
+		defer pe.SetProgress(nil)
+	}
+	var payloadHash model.HashValue
+	var hasher *integrity.PayloadHasher
+	var contentStore *model.ContentStoreInfo
+	var incremental *model.IncrementalInfo
+	var readErrors []model.ReadError
+	var parentDir string
+	if recipients == nil {
+		if parentDir, err = c.incrementalParent(cfg, partialPaths); err != nil {
+			cleanupTmp()
+			return nil, err
+		}
+	}
 
 	// For partial snapshots, only copy specified paths
 	if len(partialPaths) > 0 {
@@ -113,11 +389,82 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 			cleanupTmp()
 			return nil, fmt.Errorf("clone partial paths: %w", err)
 		}
+	} else if parentDir != "" {
+		if incremental, readErrors, err = c.cloneIncremental(payloadPath, snapshotTmpDir, parentDir, ignore, copied); err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("clone payload incrementally: %w", err)
+		}
+	} else if !ignore.Empty() || skipReadErrors {
+		// Copy file by file, so excluded and unreadable files can be
+		// left out
+		if _, readErrors, err = c.cloneIncremental(payloadPath, snapshotTmpDir, "", ignore, copied); err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("clone payload: %w", err)
+		}
+	} else if he, ok := c.engine.(engine.HashingEngine); ok && !c.twoPassHash {
+		// Single pass: hash while copying
+		hasher = integrity.NewPayloadHasher(hashAlg)
+		result, err := he.CloneWithHash(c.ctx, payloadPath, snapshotTmpDir, hasher)
+		if err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("clone payload: %w", err)
+		}
+		payloadHash = hasher.Sum()
+		c.degradations = result.Degradations
 	} else {
-		if _, err := c.engine.Clone(payloadPath, snapshotTmpDir); err != nil {
+		result, err := c.engine.CloneContext(c.ctx, payloadPath, snapshotTmpDir)
+		if err != nil {
 			cleanupTmp()
 			return nil, fmt.Errorf("clone payload: %w", err)
 		}
+		c.degradations = result.Degradations
+	}
+
+	copied.Done()
+	if payloadHash != "" {
+		timer.mark("copy_hash")
+	} else {
+		timer.mark("copy")
+	}
+
+	// Step 5.5: Dereference external symlinks if requested
+	var dereferenced []string
+	if c.followSymlinks {
+		dereferenced, err = dereferenceSymlinks(payloadPath, snapshotTmpDir, c.maxDereferenceBytes)
+		if err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("dereference symlinks: %w", err)
+		}
+		if len(dereferenced) > 0 {
+			// Tree changed after cloning; rehash below
+			payloadHash = ""
+		}
+		timer.mark("dereference")
+	}
+
+	// Step 5.6: Record a frozen worktree as it was before it was frozen
+	if cfg.Frozen {
+		modes, err := wtMgr.FrozenModes(worktreeName)
+		if err != nil {
+			cleanupTmp()
+			return nil, err
+		}
+		if err := worktree.ApplyModes(snapshotTmpDir, modes); err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("restore frozen permissions: %w", err)
+		}
+		payloadHash = ""
+	}
+
+	// Step 5.7: Store file content once per repository. Compression
+	// rewrites the files after publish, so it gains nothing there
+	if jvsCfg.ContentStore && !compress && recipients == nil {
+		contentStore, err = cas.NewStore(c.repoRoot).Ingest(snapshotTmpDir)
+		if err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("content store: %w", err)
+		}
+		timer.mark("content_store")
 	}
 
 	// Step 6: Fsync the cloned tree for durability
@@ -125,12 +472,55 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 		cleanupTmp()
 		return nil, fmt.Errorf("fsync snapshot tree: %w", err)
 	}
+	timer.mark("fsync")
 
-	// Step 7: Compute payload root hash
-	payloadHash, err := integrity.ComputePayloadRootHash(snapshotTmpDir)
-	if err != nil {
-		cleanupTmp()
-		return nil, fmt.Errorf("compute payload hash: %w", err)
+	// Step 7: Compute payload root hash (unless computed during clone)
+	if payloadHash == "" {
+		hashed := progress.NewBytes("hash", payloadBytes, c.progress)
+		hasher, err = integrity.HashPayloadContext(c.ctx, snapshotTmpDir, hashAlg)
+		if err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("compute payload hash: %w", err)
+		}
+		payloadHash = hasher.Sum()
+		hashed.Done()
+		timer.mark("hash")
+	}
+
+	if (cfg.Frozen || !ignore.Empty() || skipReadErrors) && c.skipIfUnchanged && len(partialPaths) == 0 && cfg.HeadSnapshotID != "" {
+		head, err := c.unchangedHead(cfg.HeadSnapshotID, func(alg model.HashAlgorithm) (model.HashValue, error) {
+			if alg == hashAlg {
+				return payloadHash, nil
+			}
+			return integrity.ComputePayloadRootHashContext(c.ctx, snapshotTmpDir, alg)
+		})
+		if err != nil {
+			cleanupTmp()
+			return nil, err
+		}
+		if head != nil {
+			cleanupTmp()
+			return head, nil
+		}
+	}
+
+	// Step 7.5: Encrypt the payload before it is published, so that its
+	// plaintext never appears under a published snapshot
+	var encryptionInfo *model.EncryptionInfo
+	if recipients != nil {
+		if _, err := encryption.EncryptDir(snapshotTmpDir, recipients); err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("encrypt payload: %w", err)
+		}
+		if err := fsutil.FsyncTree(snapshotTmpDir); err != nil {
+			cleanupTmp()
+			return nil, fmt.Errorf("fsync snapshot tree: %w", err)
+		}
+		encryptionInfo = &model.EncryptionInfo{
+			Type:       encryption.Type,
+			Recipients: slices.Clone(jvsCfg.Encryption.Recipients),
+		}
+		timer.mark("encrypt")
 	}
 
 	// Step 8: Create descriptor
@@ -142,26 +532,41 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 
 	// Build descriptor with compression info if enabled
 	desc := &model.Descriptor{
-		SnapshotID:      snapshotID,
-		ParentID:        parentID,
-		WorktreeName:    worktreeName,
-		CreatedAt:       time.Now().UTC(),
-		Note:            note,
-		Tags:            tags,
-		Engine:          c.engineType,
-		PayloadRootHash: payloadHash,
-		IntegrityState:  model.IntegrityVerified,
-		PartialPaths:    partialPaths,
+		SnapshotID:        snapshotID,
+		ParentID:          parentID,
+		WorktreeName:      worktreeName,
+		CreatedAt:         time.Now().UTC(),
+		Note:              note,
+		Tags:              tags,
+		Engine:            c.engineType,
+		PayloadRootHash:   payloadHash,
+		HashAlgorithm:     hashAlg,
+		IntegrityState:    model.IntegrityVerified,
+		PartialPaths:      partialPaths,
+		DereferencedPaths: dereferenced,
+		Encryption:        encryptionInfo,
+		ContentStore:      contentStore,
+		Incremental:       incremental,
+		ReadErrors:        readErrors,
+	}
+	desc.Author = audit.ResolveAuthor(c.author)
+	if len(c.annotations) > 0 {
+		desc.Annotations = c.annotations
+	}
+	if c.captureEnv {
+		desc.Environment = CaptureEnvironment(c.envAllow)
 	}
 
 	// Add compression info if compression is enabled
-	if c.compression != nil && c.compression.IsEnabled() {
+	if compress {
 		desc.Compression = &model.CompressionInfo{
 			Type:  string(c.compression.Type),
 			Level: int(c.compression.Level),
 		}
 	}
 
+	desc.ManifestHash = ManifestHash(hasher.Entries())
+
 	// Step 9: Compute descriptor checksum
 	checksum, err := integrity.ComputeDescriptorChecksum(desc)
 	if err != nil {
@@ -183,15 +588,28 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 		cleanupTmp()
 		return nil, fmt.Errorf("write ready marker: %w", err)
 	}
+	timer.mark("descriptor")
+
+	// Last chance to stop before the snapshot becomes visible. Another
+	// node may have taken the lock over, and GC with it
+	if err := c.ctx.Err(); err != nil {
+		cleanupTmp()
+		return nil, err
+	}
+	if err := lock.Check(); err != nil {
+		cleanupTmp()
+		return nil, err
+	}
 
 	// Step 11: Atomic rename tmp -> final
 	if err := fsutil.RenameAndSync(snapshotTmpDir, snapshotDir); err != nil {
 		cleanupTmp()
 		return nil, fmt.Errorf("atomic rename snapshot: %w", err)
 	}
+	timer.mark("publish")
 
 	// Step 11.5: Compress snapshot if enabled
-	if c.compression != nil && c.compression.IsEnabled() {
+	if compress {
 		count, err := c.compression.CompressDir(snapshotDir)
 		if err != nil {
 			// Compression failure is non-fatal; snapshot is valid
@@ -200,20 +618,40 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 			// Log compression success
 			fmt.Fprintf(os.Stderr, "compressed %d files\n", count)
 		}
+		timer.mark("compress")
+	}
+
+	// Step 11.7: Write the per-file manifest. It is only an index of the
+	// payload, so a snapshot without one is still valid
+	manifest := &model.Manifest{SnapshotID: snapshotID, Entries: hasher.Entries()}
+	if err := WriteManifest(c.repoRoot, manifest); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write manifest: %v\n", err)
 	}
+	timer.mark("manifest")
 
 	// Step 12: Write descriptor atomically
-	descriptorPath := filepath.Join(c.repoRoot, ".jvs", "descriptors", string(snapshotID)+".json")
-	if err := c.writeDescriptor(descriptorPath, desc); err != nil {
+	if err := c.writeDescriptor(desc); err != nil {
 		// Snapshot is already renamed, don't remove it
 		return nil, fmt.Errorf("write descriptor: %w", err)
 	}
+	updateIndex(c.repoRoot, desc)
+	timer.mark("descriptor_write")
 
 	// Step 13: Update worktree head and latest
 	if err := wtMgr.SetLatest(worktreeName, snapshotID); err != nil {
 		// Don't remove snapshot, it's valid
 		return nil, fmt.Errorf("update head: %w", err)
 	}
+	timer.mark("head_update")
+
+	// Stats are excluded from the checksum, so they are attached once the
+	// last timed phase is over, by writing the descriptor again
+	if timingEnabled() {
+		desc.Stats = timer.stats()
+		if err := c.writeDescriptor(desc); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to record timings: %v\n", err)
+		}
+	}
 
 	// Step 14: Write audit log
 	auditData := map[string]any{
@@ -221,9 +659,21 @@ func (c *Creator) CreatePartial(worktreeName, note string, tags []string, paths
 		"note":     note,
 		"checksum": string(checksum),
 	}
+	if parentID != nil {
+		auditData["parent_id"] = string(*parentID)
+	}
 	if len(partialPaths) > 0 {
 		auditData["partial_paths"] = partialPaths
 	}
+	if len(dereferenced) > 0 {
+		auditData["dereferenced_paths"] = dereferenced
+	}
+	if incremental != nil {
+		auditData["incremental_linked"] = incremental.Linked
+	}
+	if len(readErrors) > 0 {
+		auditData["read_errors"] = len(readErrors)
+	}
 	if err := c.auditLogger.Append(model.EventTypeSnapshotCreate, worktreeName, snapshotID, auditData); err != nil {
 		// Non-fatal, just log
 		fmt.Fprintf(os.Stderr, "warning: failed to write audit log: %v\n", err)
@@ -289,6 +739,19 @@ func (c *Creator) validateAndNormalizePaths(paths []string, worktreeName string)
 	return unique, nil
 }
 
+// payloadSize returns the bytes of regular files a snapshot of the worktree
+// at src copies: all of them, or those below paths if set.
+func payloadSize(src string, paths []string) int64 {
+	if len(paths) == 0 {
+		return fsutil.DirSize(src)
+	}
+	var total int64
+	for _, p := range paths {
+		total += fsutil.DirSize(filepath.Join(src, p))
+	}
+	return total
+}
+
 // clonePaths clones only the specified paths from source to destination.
 func (c *Creator) clonePaths(src, dst string, paths []string) error {
 	for _, p := range paths {
@@ -303,7 +766,7 @@ func (c *Creator) clonePaths(src, dst string, paths []string) error {
 
 		if info.IsDir() {
 			// Clone directory tree
-			if _, err := c.engine.Clone(srcPath, dstPath); err != nil {
+			if _, err := c.engine.CloneContext(c.ctx, srcPath, dstPath); err != nil {
 				return fmt.Errorf("clone directory %s: %w", p, err)
 			}
 		} else {
@@ -311,7 +774,7 @@ func (c *Creator) clonePaths(src, dst string, paths []string) error {
 			if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
 				return fmt.Errorf("create parent dir for %s: %w", p, err)
 			}
-			if _, err := c.engine.Clone(srcPath, dstPath); err != nil {
+			if _, err := c.engine.CloneContext(c.ctx, srcPath, dstPath); err != nil {
 				return fmt.Errorf("clone file %s: %w", p, err)
 			}
 		}
@@ -319,6 +782,51 @@ func (c *Creator) clonePaths(src, dst string, paths []string) error {
 	return nil
 }
 
+// incrementalParent returns the payload directory of the HEAD snapshot to
+// link unchanged files from, or "" if the snapshot is not incremental.
+// Frozen worktrees are excluded because their snapshot files get their
+// permissions changed after cloning, which would change the parent's too.
+func (c *Creator) incrementalParent(cfg *model.WorktreeConfig, partialPaths []string) (string, error) {
+	if !c.incremental || len(partialPaths) > 0 || cfg.HeadSnapshotID == "" || cfg.Frozen {
+		return "", nil
+	}
+	if c.compression != nil && c.compression.IsEnabled() {
+		return "", nil
+	}
+	parent, dir, err := OpenPayload(c.repoRoot, cfg.HeadSnapshotID)
+	if err != nil {
+		return "", fmt.Errorf("load parent snapshot: %w", err)
+	}
+	if parent.Compression != nil || parent.Encryption != nil {
+		return "", nil
+	}
+	return dir, nil
+}
+
+// unchangedHead returns the HEAD descriptor marked Skipped if the hash
+// returned by payloadHash, under HEAD's hash algorithm, matches its payload
+// root hash, or nil if the payload
+// changed. Partial and dereferenced HEAD snapshots never match since their
+// hash does not describe the worktree as it is.
+func (c *Creator) unchangedHead(headID model.SnapshotID, payloadHash func(alg model.HashAlgorithm) (model.HashValue, error)) (*model.Descriptor, error) {
+	head, err := LoadDescriptor(c.repoRoot, headID)
+	if err != nil {
+		return nil, fmt.Errorf("load head snapshot: %w", err)
+	}
+	if len(head.PartialPaths) > 0 || len(head.DereferencedPaths) > 0 {
+		return nil, nil
+	}
+	hash, err := payloadHash(head.PayloadHashAlgorithm())
+	if err != nil {
+		return nil, fmt.Errorf("compute payload hash: %w", err)
+	}
+	if hash != head.PayloadRootHash {
+		return nil, nil
+	}
+	head.Skipped = true
+	return head, nil
+}
+
 func (c *Creator) writeIntent(path string, intent *model.IntentRecord) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
@@ -338,36 +846,99 @@ func (c *Creator) writeReadyMarker(path string, marker *model.ReadyMarker) error
 	return fsutil.AtomicWrite(path, data, 0644)
 }
 
-func (c *Creator) writeDescriptor(path string, desc *model.Descriptor) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
-	}
-	data, err := json.MarshalIndent(desc, "", "  ")
+func (c *Creator) writeDescriptor(desc *model.Descriptor) error {
+	store, err := descstore.Open(c.repoRoot)
 	if err != nil {
 		return err
 	}
-	return fsutil.AtomicWrite(path, data, 0644)
+	defer store.Close()
+	return store.Put(desc)
 }
 
-// LoadDescriptor loads a descriptor from disk.
+// LoadDescriptor loads a descriptor from the repository's descriptor store.
+// It fails with errclass.ErrSnapshotNotReady if the snapshot has no .READY
+// marker, so callers never act on an unpublished snapshot.
 func LoadDescriptor(repoRoot string, snapshotID model.SnapshotID) (*model.Descriptor, error) {
-	path := filepath.Join(repoRoot, ".jvs", "descriptors", string(snapshotID)+".json")
-	data, err := os.ReadFile(path)
+	store, err := descstore.Open(repoRoot)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, errclass.ErrDescriptorCorrupt.WithMessagef("descriptor not found: %s", snapshotID)
-		}
-		return nil, fmt.Errorf("read descriptor: %w", err)
+		return nil, err
 	}
-	var desc model.Descriptor
-	if err := json.Unmarshal(data, &desc); err != nil {
-		return nil, errclass.ErrDescriptorCorrupt.WithMessagef("parse descriptor: %v", err)
+	defer store.Close()
+	desc, err := store.Get(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.CheckSnapshotReady(repoRoot, snapshotID); err != nil {
+		return nil, err
 	}
-	return &desc, nil
+	return desc, nil
+}
+
+// OpenPayload loads the descriptor of a READY snapshot and returns it with
+// the snapshot's payload directory. Every consumer that reads snapshot
+// content goes through it (or LoadDescriptor) to honor the READY protocol.
+func OpenPayload(repoRoot string, snapshotID model.SnapshotID) (*model.Descriptor, string, error) {
+	desc, err := LoadDescriptor(repoRoot, snapshotID)
+	if err != nil {
+		return nil, "", err
+	}
+	return desc, repo.SnapshotPath(repoRoot, snapshotID), nil
+}
+
+// UncompressedPayload is OpenPayload for consumers that read file content:
+// for a compressed or encrypted snapshot, it clones the payload into a
+// temporary directory under .jvs, named after purpose, and decrypts and
+// decompresses it there. The returned cleanup removes that directory and
+// must always be called.
+func UncompressedPayload(repoRoot string, id model.SnapshotID, purpose string) (*model.Descriptor, string, func(), error) {
+	desc, snapshotDir, err := OpenPayload(repoRoot, id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if desc.Compression == nil && desc.Encryption == nil {
+		return desc, snapshotDir, func() {}, nil
+	}
+
+	// Named so 'jvs doctor --repair-runtime' cleans it up after a crash
+	tmp, err := os.MkdirTemp(filepath.Join(repoRoot, ".jvs"), ".jvs-tmp-"+purpose+"-")
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	dst := filepath.Join(tmp, "payload")
+	if _, err := engine.NewEngine(desc.Engine).Clone(snapshotDir, dst); err != nil {
+		cleanup()
+		return nil, "", nil, fmt.Errorf("clone snapshot %s: %w", id, err)
+	}
+	if _, err := DecryptPayload(repoRoot, desc, dst); err != nil {
+		cleanup()
+		return nil, "", nil, err
+	}
+	if desc.Compression != nil {
+		if _, err := compression.DecompressDirType(dst, compression.CompressionType(desc.Compression.Type)); err != nil {
+			cleanup()
+			return nil, "", nil, fmt.Errorf("decompress snapshot %s: %w", id, err)
+		}
+	}
+	return desc, dst, cleanup, nil
+}
+
+// DecryptPayload decrypts dir, a copy of the stored payload of the
+// snapshot desc describes, with the identities encryption.LoadIdentities
+// finds for the repository. It does nothing if the snapshot is not
+// encrypted. Returns the count of decrypted files.
+func DecryptPayload(repoRoot string, desc *model.Descriptor, dir string) (int, error) {
+	return encryption.DecryptSnapshot(repoRoot, desc, dir)
 }
 
 // VerifySnapshot verifies a snapshot's integrity.
 func VerifySnapshot(repoRoot string, snapshotID model.SnapshotID, verifyPayloadHash bool) error {
+	return VerifySnapshotContext(context.Background(), repoRoot, snapshotID, verifyPayloadHash)
+}
+
+// VerifySnapshotContext is VerifySnapshot stopping with ctx's error once
+// ctx is done while hashing the payload.
+func VerifySnapshotContext(ctx context.Context, repoRoot string, snapshotID model.SnapshotID, verifyPayloadHash bool) error {
 	desc, err := LoadDescriptor(repoRoot, snapshotID)
 	if err != nil {
 		return err
@@ -381,10 +952,19 @@ func VerifySnapshot(repoRoot string, snapshotID model.SnapshotID, verifyPayloadH
 	if computedChecksum != desc.DescriptorChecksum {
 		return errclass.ErrDescriptorCorrupt.WithMessage("checksum mismatch")
 	}
+	if err := integrity.CheckHashAlgorithm(desc.HashAlgorithm); err != nil {
+		return err
+	}
 
 	if verifyPayloadHash {
-		snapshotDir := filepath.Join(repoRoot, ".jvs", "snapshots", string(snapshotID))
-		computedHash, err := integrity.ComputePayloadRootHash(snapshotDir)
+		payloadDir, cleanup := repo.SnapshotPath(repoRoot, snapshotID), func() {}
+		if desc.Encryption != nil {
+			if _, payloadDir, cleanup, err = UncompressedPayload(repoRoot, snapshotID, "verify"); err != nil {
+				return err
+			}
+		}
+		defer cleanup()
+		computedHash, err := integrity.ComputePayloadRootHashContext(ctx, payloadDir, desc.HashAlgorithm)
 		if err != nil {
 			return fmt.Errorf("compute payload hash: %w", err)
 		}
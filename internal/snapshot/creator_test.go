@@ -1,15 +1,28 @@
 package snapshot_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
+	"filippo.io/age"
+
+	"github.com/jvs-project/jvs/internal/audit"
 	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/hooks"
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/internal/integrity"
 	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -101,6 +114,173 @@ func TestCreator_UpdatesHead(t *testing.T) {
 	assert.Equal(t, desc2.SnapshotID, cfg.HeadSnapshotID)
 }
 
+func TestCreator_LockedWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	_, err := worktree.NewManager(repoPath).Lock("main", "alice", 0)
+	require.NoError(t, err)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetLockOwner("bob")
+	_, err = creator.Create("main", "by bob", nil)
+	require.ErrorIs(t, err, errclass.ErrWorktreeLocked)
+
+	creator.SetLockOwner("alice")
+	_, err = creator.Create("main", "by alice", nil)
+	require.NoError(t, err)
+}
+
+func TestCreator_SetContext(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte("hello"), 0644))
+	snapshotsDir := filepath.Join(repoPath, ".jvs", "snapshots")
+	before, err := os.ReadDir(snapshotsDir)
+	require.NoError(t, err)
+
+	// Cancelled before the clone, and between the clone and the hash
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetContext(ctx)
+	_, err = creator.Create("main", "cancelled", nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	creator = snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetContext(ctx)
+	creator.SetTwoPassHash(true)
+	creator.SetPhaseObserver(func(phase string, _, _ time.Time) {
+		if phase == "copy" {
+			cancel()
+		}
+	})
+	_, err = creator.Create("main", "cancelled", nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Nothing was published or left behind
+	after, err := os.ReadDir(snapshotsDir)
+	require.NoError(t, err)
+	assert.Equal(t, len(before), len(after))
+	cfg, err := repo.LoadWorktreeConfig(repoPath, "main")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.HeadSnapshotID)
+}
+
+func TestCreator_SetProgress(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "b.txt"), []byte("world!"), 0644))
+
+	last := map[string][2]int64{}
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetTwoPassHash(true)
+	creator.SetProgress(func(phase string, done, total int64) {
+		assert.LessOrEqual(t, done, total)
+		last[phase] = [2]int64{done, total}
+	})
+	_, err := creator.Create("main", "progress", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][2]int64{"copy": {11, 11}, "hash": {11, 11}}, last)
+
+	// Linked files of an incremental snapshot count as copied
+	clear(last)
+	creator = snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetIncremental(true)
+	creator.SetProgress(func(phase string, done, total int64) {
+		last[phase] = [2]int64{done, total}
+	})
+	_, err = creator.Create("main", "incremental", nil)
+	require.NoError(t, err)
+	assert.Equal(t, [2]int64{11, 11}, last["copy"])
+}
+
+func TestCreator_FrozenWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("v1"), 0644))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetSkipIfUnchanged(true)
+	before, err := creator.Create("main", "before freeze", nil)
+	require.NoError(t, err)
+
+	_, err = worktree.NewManager(repoPath).Freeze("main")
+	require.NoError(t, err)
+
+	// The read-only permissions of the frozen payload are not a change
+	same, err := creator.Create("main", "frozen", nil)
+	require.NoError(t, err)
+	assert.True(t, same.Skipped)
+	assert.Equal(t, before.SnapshotID, same.SnapshotID)
+
+	// Snapshots record the permissions from before the freeze
+	creator.SetSkipIfUnchanged(false)
+	frozen, err := creator.Create("main", "frozen", nil)
+	require.NoError(t, err)
+	assert.Equal(t, before.PayloadRootHash, frozen.PayloadRootHash)
+	info, err := os.Stat(filepath.Join(repo.SnapshotPath(repoPath, frozen.SnapshotID), "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, frozen.SnapshotID, true))
+}
+
+func TestCreator_SkipIfUnchanged(t *testing.T) {
+	repoPath := setupTestRepo(t)
+
+	mainPath := filepath.Join(repoPath, "main")
+	os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("v1"), 0644)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetSkipIfUnchanged(true)
+	desc1, err := creator.Create("main", "first", nil)
+	require.NoError(t, err)
+	assert.False(t, desc1.Skipped, "no HEAD to compare against")
+
+	// Unchanged payload returns HEAD
+	desc2, err := creator.Create("main", "again", nil)
+	require.NoError(t, err)
+	assert.True(t, desc2.Skipped)
+	assert.Equal(t, desc1.SnapshotID, desc2.SnapshotID)
+	assert.Equal(t, "first", desc2.Note)
+
+	entries, err := os.ReadDir(filepath.Join(repoPath, ".jvs", "snapshots"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Persisted descriptor is not marked
+	loaded, err := snapshot.LoadDescriptor(repoPath, desc1.SnapshotID)
+	require.NoError(t, err)
+	assert.False(t, loaded.Skipped)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc1.SnapshotID, true))
+
+	// Changed payload creates a snapshot
+	os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("v2"), 0644)
+	desc3, err := creator.Create("main", "changed", nil)
+	require.NoError(t, err)
+	assert.False(t, desc3.Skipped)
+	assert.Equal(t, desc1.SnapshotID, *desc3.ParentID)
+}
+
+func TestCreator_PayloadHashIgnoresTimestamps(t *testing.T) {
+	// Identical content written in separate repositories at different times
+	// must hash identically so the hash can serve as a dedup or cache key
+	var hashes []model.HashValue
+	for i, mtime := range []time.Time{time.Unix(1_000_000, 0), time.Unix(2_000_000, 0)} {
+		repoPath := setupTestRepo(t)
+		dataDir := filepath.Join(repoPath, "main", "data")
+		require.NoError(t, os.MkdirAll(dataDir, 0755))
+		file := filepath.Join(dataDir, "weights.bin")
+		require.NoError(t, os.WriteFile(file, []byte("identical"), 0644))
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+		require.NoError(t, os.Chtimes(dataDir, mtime, mtime))
+
+		desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", fmt.Sprintf("run %d", i), nil)
+		require.NoError(t, err)
+		hashes = append(hashes, desc.PayloadRootHash)
+	}
+	assert.Equal(t, hashes[0], hashes[1])
+}
+
 func TestCreator_PayloadContentPreserved(t *testing.T) {
 	repoPath := setupTestRepo(t)
 
@@ -350,6 +530,9 @@ func TestLoadDescriptor_EmptySnapshotID(t *testing.T) {
 	descriptorPath := filepath.Join(descriptorsDir, "test-snapshot.json")
 	// Valid JSON but minimal fields
 	require.NoError(t, os.WriteFile(descriptorPath, []byte(`{"snapshot_id": "", "created_at": "2024-01-01T00:00:00Z", "engine": "copy", "payload_root_hash": "abc", "descriptor_checksum": "def", "integrity_state": "verified"}`), 0644))
+	snapshotDir := filepath.Join(repoPath, ".jvs", "snapshots", "test-snapshot")
+	require.NoError(t, os.MkdirAll(snapshotDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(snapshotDir, ".READY"), []byte("{}"), 0644))
 
 	desc, err := snapshot.LoadDescriptor(repoPath, "test-snapshot")
 	// Should load without error (empty snapshot_id is valid JSON)
@@ -789,3 +972,471 @@ func TestCreator_Create_EmptyWorktree(t *testing.T) {
 	assert.DirExists(t, snapshotDir)
 	assert.FileExists(t, filepath.Join(snapshotDir, ".READY"))
 }
+
+func TestCreator_SinglePassHashMatchesTwoPass(t *testing.T) {
+	repoPath := setupTestRepo(t)
+
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("content"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "sub", "nested.txt"), []byte("nested"), 0600))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	single, err := creator.Create("main", "single", nil)
+	require.NoError(t, err)
+
+	creator.SetTwoPassHash(true)
+	twoPass, err := creator.Create("main", "two-pass", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, twoPass.PayloadRootHash, single.PayloadRootHash)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, single.SnapshotID, true))
+}
+
+func TestCreator_DebugTimingRecordsStats(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte("content"), 0644)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	desc, err := creator.Create("main", "untimed", nil)
+	require.NoError(t, err)
+	assert.Nil(t, desc.Stats)
+
+	t.Setenv("JVS_DEBUG_TIMING", "1")
+	desc, err = creator.Create("main", "timed", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Stats)
+
+	var phases []string
+	for _, pt := range desc.Stats.Timings {
+		phases = append(phases, pt.Phase)
+	}
+	assert.Contains(t, phases, "copy_hash")
+	assert.Contains(t, phases, "fsync")
+	assert.Contains(t, phases, "publish")
+	assert.Equal(t, "head_update", phases[len(phases)-1], "stats cover the writes of the descriptor and head")
+
+	// Stats persist and do not affect the checksum
+	loaded, err := snapshot.LoadDescriptor(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.Stats)
+	assert.Len(t, loaded.Stats.Timings, len(desc.Stats.Timings))
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true))
+}
+
+func TestCreator_PhaseObserver(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte("content"), 0644)
+
+	var phases []string
+	var last time.Time
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetPhaseObserver(func(phase string, start, end time.Time) {
+		phases = append(phases, phase)
+		assert.False(t, end.Before(start))
+		if !last.IsZero() {
+			assert.Equal(t, last, start, "phases are consecutive")
+		}
+		last = end
+	})
+	desc, err := creator.Create("main", "observed", nil)
+	require.NoError(t, err)
+	assert.Nil(t, desc.Stats, "observing does not record stats")
+	assert.Equal(t, []string{"prepare", "copy_hash", "fsync", "descriptor", "publish", "manifest", "descriptor_write", "head_update"}, phases)
+}
+
+func TestLoadDescriptor_NotReady(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte("hello"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "", nil)
+	require.NoError(t, err)
+
+	// Simulate a payload left without its marker
+	snapshotDir := repo.SnapshotPath(repoPath, desc.SnapshotID)
+	require.NoError(t, os.Remove(filepath.Join(snapshotDir, ".READY")))
+
+	_, err = snapshot.LoadDescriptor(repoPath, desc.SnapshotID)
+	require.ErrorIs(t, err, errclass.ErrSnapshotNotReady)
+	_, _, err = snapshot.OpenPayload(repoPath, desc.SnapshotID)
+	require.ErrorIs(t, err, errclass.ErrSnapshotNotReady)
+	require.ErrorIs(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true), errclass.ErrSnapshotNotReady)
+
+	all, err := snapshot.ListAll(repoPath)
+	require.NoError(t, err)
+	assert.Empty(t, all, "unpublished snapshots are not listed")
+}
+
+func TestOpenPayload_Compressed(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "file.txt"), []byte("hello"), 0644))
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompression(compression.LevelDefault)
+	desc, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+
+	// Compressed snapshots keep their marker as .READY.gz
+	loaded, dir, err := snapshot.OpenPayload(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, desc.SnapshotID, loaded.SnapshotID)
+	assert.FileExists(t, filepath.Join(dir, ".READY.gz"))
+}
+
+func TestCreator_HashAlgorithmBLAKE3(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "model.bin"), []byte("weights"), 0644))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetSkipIfUnchanged(true)
+	before, err := creator.Create("main", "sha256", nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.HashSHA256, before.HashAlgorithm)
+
+	cfg := config.Default()
+	cfg.HashAlgorithm = string(model.HashBLAKE3)
+	require.NoError(t, config.Save(repoPath, cfg))
+	defer config.InvalidateCache(repoPath)
+
+	// Unchanged payloads are detected across algorithms
+	same, err := creator.Create("main", "blake3", nil)
+	require.NoError(t, err)
+	assert.True(t, same.Skipped)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "model.bin"), []byte("weights v2"), 0644))
+	desc, err := creator.Create("main", "blake3", nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.HashBLAKE3, desc.HashAlgorithm)
+	hash, err := integrity.ComputePayloadRootHash(repo.SnapshotPath(repoPath, desc.SnapshotID), model.HashBLAKE3)
+	require.NoError(t, err)
+	assert.Equal(t, hash, desc.PayloadRootHash)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true))
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, before.SnapshotID, true))
+
+	manifest, err := snapshot.LoadManifest(repoPath, desc)
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+	require.NoError(t, snapshot.VerifyManifestEntry(repo.SnapshotPath(repoPath, desc.SnapshotID), desc, manifest.Entries[0]))
+
+	// Manifests hashed with different algorithms are not compared
+	d, err := snapshot.ManifestDiff(repoPath, before.SnapshotID, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Nil(t, d)
+}
+
+func TestCreator_Encryption(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "secret.txt"), []byte("top secret"), 0640))
+
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	cfg := config.Default()
+	cfg.Encryption = &config.Encryption{Recipients: []string{id.Recipient().String()}}
+	require.NoError(t, config.Save(repoPath, cfg))
+	defer config.InvalidateCache(repoPath)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompression(6)
+	desc, err := creator.Create("main", "encrypted", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Encryption)
+	assert.Equal(t, "age", desc.Encryption.Type)
+	assert.Nil(t, desc.Compression, "encrypted payloads are not compressed")
+
+	snapshotDir := repo.SnapshotPath(repoPath, desc.SnapshotID)
+	assert.NoFileExists(t, filepath.Join(snapshotDir, "secret.txt"))
+	data, err := os.ReadFile(filepath.Join(snapshotDir, "secret.txt.age"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "top secret")
+
+	// Reading the payload needs an identity
+	t.Setenv("JVS_AGE_IDENTITY", "")
+	t.Setenv("JVS_AGE_IDENTITY_FILE", "")
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, false))
+	require.Error(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true))
+
+	t.Setenv("JVS_AGE_IDENTITY", id.String())
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true))
+	_, dir, cleanup, err := snapshot.UncompressedPayload(repoPath, desc.SnapshotID, "test")
+	require.NoError(t, err)
+	defer cleanup()
+	data, err = os.ReadFile(filepath.Join(dir, "secret.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "top secret", string(data))
+
+	entry, err := snapshot.Peek(repoPath, desc.SnapshotID, "secret.txt", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("top secret")), entry.Size)
+}
+
+func TestCreator_EncryptionInvalidConfig(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "secret.txt"), []byte("top secret"), 0640))
+
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	cfg := config.Default()
+	cfg.Encryption = &config.Encryption{Recipients: []string{id.Recipient().String()}}
+	require.NoError(t, config.Save(repoPath, cfg))
+	defer config.InvalidateCache(repoPath)
+
+	// An invalid setting elsewhere in config.yaml must not make the snapshot
+	// fall back to defaults and write the payload unencrypted
+	configPath := filepath.Join(repoPath, ".jvs", "config.yaml")
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("read_error_policy: ignore\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	config.InvalidateCache(repoPath)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	_, err = creator.Create("main", "encrypted", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "load config")
+
+	entries, err := os.ReadDir(filepath.Join(repoPath, ".jvs", "snapshots"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCreator_ContentStore(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	cfg := config.Default()
+	cfg.ContentStore = true
+	require.NoError(t, config.Save(repoPath, cfg))
+
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "model.bin"), []byte("weights"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "log.txt"), []byte("step 1"), 0644))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	first, err := creator.Create("main", "first", nil)
+	require.NoError(t, err)
+	require.NotNil(t, first.ContentStore)
+	assert.Equal(t, 2, first.ContentStore.Blobs)
+	assert.Zero(t, first.ContentStore.Reused)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "log.txt"), []byte("step 2"), 0644))
+	second, err := creator.Create("main", "second", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, second.ContentStore.Reused)
+	assert.Equal(t, int64(len("weights")), second.ContentStore.ReusedBytes)
+
+	// The unchanged file is stored once
+	a, err := os.Stat(filepath.Join(repo.SnapshotPath(repoPath, first.SnapshotID), "model.bin"))
+	require.NoError(t, err)
+	b, err := os.Stat(filepath.Join(repo.SnapshotPath(repoPath, second.SnapshotID), "model.bin"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(a, b))
+
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, first.SnapshotID, true))
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, second.SnapshotID, true))
+}
+
+func TestCreator_Incremental(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "data", "model.bin"), []byte("weights"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "log.txt"), []byte("step 1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "touched.txt"), []byte("same"), 0644))
+	require.NoError(t, os.Symlink("log.txt", filepath.Join(mainPath, "latest.log")))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetIncremental(true)
+	first, err := creator.Create("main", "first", nil)
+	require.NoError(t, err)
+	assert.Nil(t, first.Incremental, "the first snapshot has no parent to link from")
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "log.txt"), []byte("step 2"), 0644))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(mainPath, "touched.txt"), later, later))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "new.txt"), []byte("new"), 0644))
+	second, err := creator.Create("main", "second", nil)
+	require.NoError(t, err)
+	require.NotNil(t, second.Incremental)
+	assert.Equal(t, 2, second.Incremental.Linked)
+	assert.Equal(t, int64(len("weights")+len("same")), second.Incremental.LinkedBytes)
+	assert.Equal(t, 2, second.Incremental.Copied)
+
+	firstDir := repo.SnapshotPath(repoPath, first.SnapshotID)
+	secondDir := repo.SnapshotPath(repoPath, second.SnapshotID)
+	for name, linked := range map[string]bool{"data/model.bin": true, "touched.txt": true, "log.txt": false} {
+		a, err := os.Stat(filepath.Join(firstDir, name))
+		require.NoError(t, err)
+		b, err := os.Stat(filepath.Join(secondDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, linked, os.SameFile(a, b), name)
+	}
+	target, err := os.Readlink(filepath.Join(secondDir, "latest.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "log.txt", target)
+
+	// The payload hash matches a full copy of the same worktree
+	full := snapshot.NewCreator(repoPath, model.EngineCopy)
+	third, err := full.Create("main", "third", nil)
+	require.NoError(t, err)
+	assert.Equal(t, third.PayloadRootHash, second.PayloadRootHash)
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, first.SnapshotID, true))
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, second.SnapshotID, true))
+}
+
+func TestCreator_Excludes(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "node_modules", "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "node_modules", "pkg", "index.js"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "app.js"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "debug.log"), []byte("log"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, snapshot.IgnoreFileName), []byte("node_modules/\n"), 0644))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetExcludes([]string{"*.log"})
+	creator.SetSkipIfUnchanged(true)
+	desc, err := creator.Create("main", "excludes", nil)
+	require.NoError(t, err)
+
+	snapDir := repo.SnapshotPath(repoPath, desc.SnapshotID)
+	assert.FileExists(t, filepath.Join(snapDir, "app.js"))
+	assert.FileExists(t, filepath.Join(snapDir, snapshot.IgnoreFileName))
+	assert.NoDirExists(t, filepath.Join(snapDir, "node_modules"))
+	assert.NoFileExists(t, filepath.Join(snapDir, "debug.log"))
+	hash, err := integrity.ComputePayloadRootHash(snapDir, model.HashSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, desc.PayloadRootHash, hash)
+
+	// Changes to excluded paths alone leave the snapshot unchanged
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "debug.log"), []byte("more log"), 0644))
+	again, err := creator.Create("main", "excludes again", nil)
+	require.NoError(t, err)
+	assert.True(t, again.Skipped)
+	assert.Equal(t, desc.SnapshotID, again.SnapshotID)
+}
+
+func TestCreator_ReadErrorSkip(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("running as root, skip permission test")
+	}
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "secret.txt"), []byte("s"), 0000))
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "locked"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "locked", "b.txt"), []byte("b"), 0644))
+	require.NoError(t, os.Chmod(filepath.Join(mainPath, "locked"), 0000))
+	t.Cleanup(func() { os.Chmod(filepath.Join(mainPath, "locked"), 0755) })
+
+	_, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "fail", nil)
+	require.Error(t, err, "fail is the default policy")
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetReadErrorPolicy(model.ReadErrorSkip)
+	desc, err := creator.Create("main", "skip", nil)
+	require.NoError(t, err)
+	require.True(t, desc.HasReadErrors())
+	require.Len(t, desc.ReadErrors, 2)
+	assert.Equal(t, "locked", desc.ReadErrors[0].Path)
+	assert.Equal(t, "open: permission denied", desc.ReadErrors[0].Error)
+	assert.Equal(t, "secret.txt", desc.ReadErrors[1].Path)
+
+	snapDir := repo.SnapshotPath(repoPath, desc.SnapshotID)
+	assert.FileExists(t, filepath.Join(snapDir, "a.txt"))
+	assert.NoDirExists(t, filepath.Join(snapDir, "locked"))
+	assert.NoFileExists(t, filepath.Join(snapDir, "secret.txt"))
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true))
+}
+
+func TestCreator_ReadErrorSkip_Readable(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "sub", "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.Symlink("sub/a.txt", filepath.Join(mainPath, "link")))
+
+	plain, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "plain", nil)
+	require.NoError(t, err)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetReadErrorPolicy(model.ReadErrorSkip)
+	desc, err := creator.Create("main", "skip", nil)
+	require.NoError(t, err)
+	assert.False(t, desc.HasReadErrors())
+	assert.Equal(t, plain.PayloadRootHash, desc.PayloadRootHash, "the file-by-file copy stores the same payload")
+}
+
+func TestCreator_WorktreeOperationInProgress(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	guard, err := repolock.NewManager(repoPath).GuardWorktree("main", "restore")
+	require.NoError(t, err)
+
+	_, err = snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "blocked", nil)
+	require.ErrorIs(t, err, errclass.ErrWorktreeBusy)
+	assert.Contains(t, err.Error(), "restore already running on worktree main")
+
+	require.NoError(t, guard.Release())
+	_, err = snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "after", nil)
+	require.NoError(t, err)
+}
+
+func TestCreate_RecordsAuthor(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	t.Setenv(identity.EnvVar, "alice@build-1")
+
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "from env", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@build-1", desc.Author)
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetAuthor("sandbox-7")
+	desc, err = creator.Create("main", "explicit", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox-7", desc.Author)
+
+	creator = snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetAuthor("bob@laptop")
+	desc, err = creator.Create("main", "explicit", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@laptop", desc.Author)
+
+	// The author is covered by the checksum and audited
+	desc.Author = "mallory"
+	checksum, err := integrity.ComputeDescriptorChecksum(desc)
+	require.NoError(t, err)
+	assert.NotEqual(t, desc.DescriptorChecksum, checksum)
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeSnapshotCreate})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "sandbox-7", records[1].Author)
+
+	found, err := snapshot.Find(repoPath, snapshot.FilterOptions{Author: "alice"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "from env", found[0].Note)
+}
+
+func TestCreate_Hooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need an executable bit and sh")
+	}
+	repoPath := setupTestRepo(t)
+	hookDir := hooks.Dir(repoPath)
+	require.NoError(t, os.MkdirAll(hookDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(hookDir, hooks.PreSnapshot),
+		[]byte("#!/bin/sh\n[ \"$JVS_NOTE\" != blocked ]\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(hookDir, hooks.PostSnapshot),
+		[]byte("#!/bin/sh\necho \"$JVS_WORKTREE $JVS_SNAPSHOT_ID $JVS_TAGS\" >> posted.txt\n"), 0755))
+
+	_, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "blocked", nil)
+	require.ErrorIs(t, err, errclass.ErrHookFailed)
+	all, err := snapshot.ListAll(repoPath)
+	require.NoError(t, err)
+	assert.Empty(t, all, "a failing pre-snapshot hook aborts the snapshot")
+	assert.NoFileExists(t, filepath.Join(repoPath, "posted.txt"))
+
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "ok", []string{"v1", "rc"})
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(repoPath, "posted.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "main "+string(desc.SnapshotID)+" v1,rc\n", string(data))
+}
@@ -0,0 +1,166 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxDereferenceBytes caps the amount of data copied when following
+// symlinks that point outside the worktree (10 GiB).
+const DefaultMaxDereferenceBytes int64 = 10 << 30
+
+// dereferencer replaces symlinks that escape the worktree with copies of
+// their targets so that a snapshot is self-contained.
+type dereferencer struct {
+	srcRoot  string // real path of the worktree payload
+	maxBytes int64
+	copied   int64
+	paths    []string
+}
+
+// dereferenceSymlinks walks the cloned tree in dstRoot and, for every symlink
+// whose target (resolved against the original payload in srcRoot) lies outside
+// srcRoot, replaces the link with a copy of the target. Symlinks pointing inside
+// the worktree and dangling symlinks are left untouched.
+// Returns the sorted list of dereferenced paths relative to dstRoot.
+func dereferenceSymlinks(srcRoot, dstRoot string, maxBytes int64) ([]string, error) {
+	realRoot, err := filepath.EvalSymlinks(srcRoot)
+	if err != nil {
+		return nil, fmt.Errorf("resolve worktree root: %w", err)
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxDereferenceBytes
+	}
+	d := &dereferencer{srcRoot: realRoot, maxBytes: maxBytes}
+
+	err = filepath.Walk(dstRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dstRoot, path)
+		if err != nil {
+			return fmt.Errorf("relative path: %w", err)
+		}
+
+		target, err := filepath.EvalSymlinks(filepath.Join(srcRoot, rel))
+		if err != nil {
+			// Dangling link; keep it as-is
+			return nil
+		}
+		if isWithin(d.srcRoot, target) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove symlink %s: %w", rel, err)
+		}
+		if err := d.copyTarget(target, path, rel, nil); err != nil {
+			return err
+		}
+		d.paths = append(d.paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(d.paths)
+	return d.paths, nil
+}
+
+// copyTarget copies the resolved target src to dst, following nested symlinks.
+// ancestors holds the real paths of directories currently being copied and is
+// used to detect symlink loops.
+func (d *dereferencer) copyTarget(src, dst, rel string, ancestors map[string]bool) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("stat dereferenced %s: %w", rel, err)
+	}
+
+	if !info.IsDir() {
+		return d.copyFile(src, dst, rel, info)
+	}
+
+	real, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return fmt.Errorf("resolve dereferenced %s: %w", rel, err)
+	}
+	if ancestors[real] {
+		return fmt.Errorf("symlink loop detected at %s", rel)
+	}
+	next := make(map[string]bool, len(ancestors)+1)
+	for k := range ancestors {
+		next[k] = true
+	}
+	next[real] = true
+
+	if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("mkdir %s: %w", rel, err)
+	}
+	entries, err := os.ReadDir(real)
+	if err != nil {
+		return fmt.Errorf("read dereferenced dir %s: %w", rel, err)
+	}
+	for _, e := range entries {
+		childSrc := filepath.Join(real, e.Name())
+		if e.Type()&os.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(childSrc)
+			if err != nil {
+				// Dangling link inside the target; skip it
+				continue
+			}
+			childSrc = resolved
+		}
+		if err := d.copyTarget(childSrc, filepath.Join(dst, e.Name()), filepath.Join(rel, e.Name()), next); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func (d *dereferencer) copyFile(src, dst, rel string, info os.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot dereference non-regular file %s", rel)
+	}
+	d.copied += info.Size()
+	if d.copied > d.maxBytes {
+		return fmt.Errorf("dereferenced content exceeds limit of %d bytes at %s", d.maxBytes, rel)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open dereferenced %s: %w", rel, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("create %s: %w", rel, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("copy dereferenced %s: %w", rel, err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", rel, err)
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// isWithin reports whether path is root or lies beneath it.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
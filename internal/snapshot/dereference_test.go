@@ -0,0 +1,98 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreator_FollowSymlinks_Disabled(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	shared := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(shared, "data.bin"), []byte("dataset"), 0644))
+
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.Symlink(shared, filepath.Join(mainPath, "dataset")))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	desc, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+	assert.Empty(t, desc.DereferencedPaths)
+
+	info, err := os.Lstat(filepath.Join(repoPath, ".jvs", "snapshots", string(desc.SnapshotID), "dataset"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "symlink should be preserved by default")
+}
+
+func TestCreator_FollowSymlinks_ExternalDir(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	shared := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(shared, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(shared, "sub", "data.bin"), []byte("dataset"), 0644))
+
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("local"), 0644))
+	require.NoError(t, os.Symlink(shared, filepath.Join(mainPath, "dataset")))
+	// Internal symlinks stay as links
+	require.NoError(t, os.Symlink("file.txt", filepath.Join(mainPath, "alias.txt")))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetFollowSymlinks(true, 0)
+	desc, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dataset"}, desc.DereferencedPaths)
+
+	snapshotDir := filepath.Join(repoPath, ".jvs", "snapshots", string(desc.SnapshotID))
+	info, err := os.Lstat(filepath.Join(snapshotDir, "dataset"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	content, err := os.ReadFile(filepath.Join(snapshotDir, "dataset", "sub", "data.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "dataset", string(content))
+
+	info, err = os.Lstat(filepath.Join(snapshotDir, "alias.txt"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+
+	// Descriptor checksum covers the dereferenced list
+	require.NoError(t, snapshot.VerifySnapshot(repoPath, desc.SnapshotID, true))
+}
+
+func TestCreator_FollowSymlinks_LoopDetected(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	shared := t.TempDir()
+	require.NoError(t, os.Symlink(shared, filepath.Join(shared, "self")))
+
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.Symlink(shared, filepath.Join(mainPath, "dataset")))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetFollowSymlinks(true, 0)
+	_, err := creator.Create("main", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "symlink loop")
+
+	entries, err := os.ReadDir(filepath.Join(repoPath, ".jvs", "snapshots"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "failed snapshot should leave no tmp dir")
+}
+
+func TestCreator_FollowSymlinks_SizeLimit(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	shared := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(shared, "big.bin"), make([]byte, 1024), 0644))
+
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.Symlink(shared, filepath.Join(mainPath, "dataset")))
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetFollowSymlinks(true, 512)
+	_, err := creator.Create("main", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds limit")
+}
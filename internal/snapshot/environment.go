@@ -0,0 +1,83 @@
+package snapshot
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/version"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// ImageDigestEnvVar names the environment variable a sandbox manager sets
+// to the digest of the container image jvs runs in.
+const ImageDigestEnvVar = "JVS_IMAGE_DIGEST"
+
+// Environment filter keys that select the fixed fields of
+// model.Environment; any other key selects a captured variable.
+const (
+	EnvKeyHostname    = "hostname"
+	EnvKeyImageDigest = "image_digest"
+	EnvKeyJVSVersion  = "jvs_version"
+)
+
+// CaptureEnvironment records the current environment. allow lists the
+// variables to record, as exact names or path.Match patterns such as
+// "AGENT_*"; variables that are not set are left out.
+func CaptureEnvironment(allow []string) *model.Environment {
+	env := &model.Environment{
+		ImageDigest: os.Getenv(ImageDigestEnvVar),
+		JVSVersion:  version.String(),
+	}
+	env.Hostname, _ = os.Hostname()
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !allowedVar(allow, name) {
+			continue
+		}
+		if env.Vars == nil {
+			env.Vars = make(map[string]string)
+		}
+		env.Vars[name] = value
+	}
+	return env
+}
+
+func allowedVar(allow []string, name string) bool {
+	for _, pattern := range allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesEnvironment reports whether env has every key/value in want. The
+// EnvKey* keys compare the fixed fields; other keys compare variables. An
+// empty want matches any descriptor, even one without an environment.
+func MatchesEnvironment(env *model.Environment, want map[string]string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	if env == nil {
+		return false
+	}
+	for key, value := range want {
+		var got string
+		var ok bool
+		switch key {
+		case EnvKeyHostname:
+			got, ok = env.Hostname, true
+		case EnvKeyImageDigest:
+			got, ok = env.ImageDigest, true
+		case EnvKeyJVSVersion:
+			got, ok = env.JVSVersion, true
+		default:
+			got, ok = env.Vars[key]
+		}
+		if !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestCaptureEnvironment(t *testing.T) {
+	t.Setenv(snapshot.ImageDigestEnvVar, "sha256:abc")
+	t.Setenv("AGENT_ID", "a42")
+	t.Setenv("AGENT_RUN", "7")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+
+	env := snapshot.CaptureEnvironment([]string{"AGENT_*", "NOT_SET"})
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, env.Hostname)
+	assert.Equal(t, "sha256:abc", env.ImageDigest)
+	assert.NotEmpty(t, env.JVSVersion)
+	assert.Equal(t, map[string]string{"AGENT_ID": "a42", "AGENT_RUN": "7"}, env.Vars)
+}
+
+func TestCreate_CaptureEnvironment(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "a.txt"), []byte("a"), 0644))
+	t.Setenv("AGENT_ID", "a42")
+
+	plain, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "plain", nil)
+	require.NoError(t, err)
+	assert.Nil(t, plain.Environment, "not captured by default")
+
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCaptureEnvironment(true, []string{"AGENT_ID"})
+	desc, err := creator.Create("main", "captured", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Environment)
+	assert.Equal(t, "a42", desc.Environment.Vars["AGENT_ID"])
+
+	// The environment is covered by the checksum
+	checksum, err := integrity.ComputeDescriptorChecksum(desc)
+	require.NoError(t, err)
+	assert.Equal(t, desc.DescriptorChecksum, checksum)
+	desc.Environment.Vars["AGENT_ID"] = "other"
+	checksum, err = integrity.ComputeDescriptorChecksum(desc)
+	require.NoError(t, err)
+	assert.NotEqual(t, desc.DescriptorChecksum, checksum)
+
+	found, err := snapshot.Find(repoPath, snapshot.FilterOptions{Environment: map[string]string{"AGENT_ID": "a42"}})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "captured", found[0].Note)
+
+	found, err = snapshot.Find(repoPath, snapshot.FilterOptions{Environment: map[string]string{snapshot.EnvKeyHostname: "elsewhere"}})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
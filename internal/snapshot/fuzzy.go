@@ -22,7 +22,7 @@ func FindMultiple(repoRoot string, query string, maxResults int) ([]*MatchScore,
 		return nil, fmt.Errorf("use explicit HEAD handling")
 	}
 
-	all, err := ListAll(repoRoot)
+	all, err := ListIndexed(repoRoot)
 	if err != nil {
 		return nil, err
 	}
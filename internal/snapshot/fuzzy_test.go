@@ -57,9 +57,10 @@ func setupSnapshotTestRepo(t *testing.T) string {
 	}
 
 	for _, snap := range snapshots {
-		// Create snapshot directory
+		// Create published snapshot directory
 		snapDir := filepath.Join(snapshotsDir, string(snap.SnapshotID))
 		require.NoError(t, os.MkdirAll(snapDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(snapDir, ".READY"), []byte("{}"), 0644))
 
 		// Write descriptor
 		descPath := filepath.Join(descriptorsDir, string(snap.SnapshotID)+".json")
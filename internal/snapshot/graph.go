@@ -0,0 +1,104 @@
+package snapshot
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// GraphEdge links a snapshot to its parent in a lineage graph.
+type GraphEdge struct {
+	SnapshotID model.SnapshotID `json:"snapshot_id"`
+	ParentID   model.SnapshotID `json:"parent_id"`
+	// Fork is set when the snapshot was taken in another worktree than its
+	// parent, i.e. where a worktree was forked.
+	Fork bool `json:"fork,omitempty"`
+}
+
+// LineageGraph orders descs for drawing as a lineage graph, as git log
+// --graph does: every snapshot comes after its descendants, and the
+// branches growing from a snapshot are kept contiguous, the one with the
+// newest snapshot first. It also returns the edges from each snapshot to
+// its parent, for the parents among descs; snapshots whose parent is not
+// among them start a new graph.
+func LineageGraph(descs []*model.Descriptor) ([]*model.Descriptor, []GraphEdge) {
+	byID := make(map[model.SnapshotID]*model.Descriptor, len(descs))
+	for _, desc := range descs {
+		byID[desc.SnapshotID] = desc
+	}
+	children := make(map[model.SnapshotID][]*model.Descriptor)
+	var roots []*model.Descriptor
+	for _, desc := range descs {
+		if desc.ParentID != nil && byID[*desc.ParentID] != nil {
+			children[*desc.ParentID] = append(children[*desc.ParentID], desc)
+		} else {
+			roots = append(roots, desc)
+		}
+	}
+
+	// newest is the creation time of the newest snapshot of each branch
+	newest := make(map[model.SnapshotID]time.Time, len(descs))
+	var measure func(desc *model.Descriptor) time.Time
+	measure = func(desc *model.Descriptor) time.Time {
+		if t, ok := newest[desc.SnapshotID]; ok {
+			return t
+		}
+		t := desc.CreatedAt
+		newest[desc.SnapshotID] = t // guards against parent cycles
+		for _, child := range children[desc.SnapshotID] {
+			if ct := measure(child); ct.After(t) {
+				t = ct
+			}
+		}
+		newest[desc.SnapshotID] = t
+		return t
+	}
+	newestFirst := func(a, b *model.Descriptor) int {
+		if c := measure(b).Compare(measure(a)); c != 0 {
+			return c
+		}
+		return model.CompareNewestFirst(a, b)
+	}
+
+	ordered := make([]*model.Descriptor, 0, len(descs))
+	visited := make(map[model.SnapshotID]bool, len(descs))
+	var visit func(desc *model.Descriptor)
+	visit = func(desc *model.Descriptor) {
+		if visited[desc.SnapshotID] {
+			return
+		}
+		visited[desc.SnapshotID] = true
+		branches := slices.Clone(children[desc.SnapshotID])
+		slices.SortFunc(branches, newestFirst)
+		for _, child := range branches {
+			visit(child)
+		}
+		ordered = append(ordered, desc)
+	}
+	slices.SortFunc(roots, newestFirst)
+	for _, root := range roots {
+		visit(root)
+	}
+	// Snapshots in a parent cycle are unreachable from any root
+	rest := slices.Clone(descs)
+	slices.SortFunc(rest, model.CompareNewestFirst)
+	for _, desc := range rest {
+		visit(desc)
+	}
+
+	var edges []GraphEdge
+	for _, desc := range ordered {
+		if desc.ParentID == nil {
+			continue
+		}
+		if parent := byID[*desc.ParentID]; parent != nil {
+			edges = append(edges, GraphEdge{
+				SnapshotID: desc.SnapshotID,
+				ParentID:   parent.SnapshotID,
+				Fork:       desc.WorktreeName != parent.WorktreeName,
+			})
+		}
+	}
+	return ordered, edges
+}
@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the file in a worktree root listing paths that
+// snapshots leave out, one gitignore-style pattern per line.
+const IgnoreFileName = ".jvsignore"
+
+// ignorePattern is one parsed exclude pattern.
+type ignorePattern struct {
+	segments []string // slash-separated glob segments; "**" matches any number
+	negate   bool     // "!pattern" re-includes a path
+	dirOnly  bool     // "pattern/" matches directories only
+}
+
+// Ignore decides which worktree paths a snapshot excludes. Patterns follow
+// .gitignore: "#" starts a comment, "!" negates, a trailing "/" matches only
+// directories, a pattern containing another "/" is relative to the worktree
+// root while one without matches at any depth, and "**" matches any number
+// of directories. The last matching pattern wins. As in git, a path inside
+// an excluded directory cannot be re-included.
+type Ignore struct {
+	patterns []ignorePattern
+}
+
+// LoadIgnore reads the .jvsignore file of the worktree at root, if any, and
+// appends the extra patterns after it.
+func LoadIgnore(root string, extra []string) (*Ignore, error) {
+	ig := &Ignore{}
+	f, err := os.Open(filepath.Join(root, IgnoreFileName))
+	switch {
+	case err == nil:
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			if err := ig.add(scanner.Text()); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", IgnoreFileName, n, err)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read %s: %w", IgnoreFileName, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("open %s: %w", IgnoreFileName, err)
+	}
+
+	for _, p := range extra {
+		if err := ig.add(p); err != nil {
+			return nil, fmt.Errorf("exclude %q: %w", p, err)
+		}
+	}
+	return ig, nil
+}
+
+// add parses one pattern line; blank lines and comments are skipped.
+func (ig *Ignore) add(line string) error {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	p := ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // escaped leading "#" or "!"
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	if !anchored {
+		line = "**/" + line
+	}
+	p.segments = strings.Split(line, "/")
+	for _, seg := range p.segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	ig.patterns = append(ig.patterns, p)
+	return nil
+}
+
+// Empty reports whether there are no patterns, so nothing is excluded.
+func (ig *Ignore) Empty() bool {
+	return ig == nil || len(ig.patterns) == 0
+}
+
+// Match reports whether the worktree path rel (relative, either separator)
+// is excluded. isDir tells whether it is a directory.
+func (ig *Ignore) Match(rel string, isDir bool) bool {
+	if ig.Empty() {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	excluded := false
+	for _, p := range ig.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, parts) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// matchSegments matches path segments against glob segments, where "**"
+// matches zero or more path segments.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
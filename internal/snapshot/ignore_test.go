@@ -0,0 +1,62 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+)
+
+func TestIgnore_Match(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, snapshot.IgnoreFileName), []byte(`
+# dependencies
+node_modules/
+__pycache__/
+*.pyc
+/build
+docs/**/*.tmp
+\#literal
+`), 0644))
+	ig, err := snapshot.LoadIgnore(dir, []string{"*.log", "!keep.log"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false},
+		{"src/__pycache__", true, true},
+		{"src/mod.pyc", false, true},
+		{"src/mod.py", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/a/b/draft.tmp", false, true},
+		{"docs/draft.tmp", false, true},
+		{"draft.tmp", false, false},
+		{"#literal", false, true},
+		{"train.log", false, true},
+		{"keep.log", false, false},
+		{snapshot.IgnoreFileName, false, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.excluded, ig.Match(tt.path, tt.isDir), tt.path)
+	}
+}
+
+func TestIgnore_NoFile(t *testing.T) {
+	ig, err := snapshot.LoadIgnore(t.TempDir(), nil)
+	require.NoError(t, err)
+	assert.True(t, ig.Empty())
+	assert.False(t, ig.Match("anything", false))
+
+	_, err = snapshot.LoadIgnore(t.TempDir(), []string{"[bad"})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
@@ -0,0 +1,191 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/progress"
+)
+
+// cloneIncremental copies the worktree at src to dst, hard-linking regular
+// files that are unchanged since the parent snapshot at parentDir instead of
+// copying them. A file is unchanged if the parent has a regular file at the
+// same path with the same size and permissions, and either the same
+// modification time or the same content. Snapshots are never modified after
+// publish, so sharing their files is safe; a linked file keeps the
+// modification time recorded in the parent. With parentDir "", every file
+// is copied. Paths matched by ignore are left out. Under ReadErrorSkip,
+// files and directories that cannot be read are left out too, and returned
+// as read errors. Linked files are added to copied; copied ones are reported
+// by the engine.
+func (c *Creator) cloneIncremental(src, dst, parentDir string, ignore *Ignore, copied *progress.Bytes) (*model.IncrementalInfo, []model.ReadError, error) {
+	info := &model.IncrementalInfo{}
+	var readErrs []model.ReadError
+	// skip records rel as unreadable if the policy allows it
+	skip := func(rel string, err error) bool {
+		if c.readErrorPolicy != model.ReadErrorSkip {
+			return false
+		}
+		readErrs = append(readErrs, model.ReadError{Path: filepath.ToSlash(rel), Error: readErrorReason(err)})
+		return true
+	}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// A directory that cannot be listed is reported after it was
+			// created; leave it out entirely
+			if path != src && d != nil && d.IsDir() {
+				if rel, relErr := filepath.Rel(src, path); relErr == nil && skip(rel, err) {
+					if err := os.RemoveAll(filepath.Join(dst, rel)); err != nil {
+						return fmt.Errorf("remove unreadable directory %s: %w", rel, err)
+					}
+					return filepath.SkipDir
+				}
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("relative path: %w", err)
+		}
+		dstPath := filepath.Join(dst, rel)
+		fi, err := d.Info()
+		if err != nil {
+			if rel != "." && skip(rel, err) {
+				return nil
+			}
+			return err
+		}
+		if rel != "." && ignore.Match(rel, fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case fi.IsDir():
+			if err := os.MkdirAll(dstPath, fi.Mode()); err != nil {
+				return fmt.Errorf("mkdir %s: %w", dstPath, err)
+			}
+			return nil
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				if skip(rel, err) {
+					return nil
+				}
+				return fmt.Errorf("readlink %s: %w", path, err)
+			}
+			return os.Symlink(target, dstPath)
+		case !fi.Mode().IsRegular():
+			return nil
+		}
+
+		if parentDir != "" {
+			parentPath := filepath.Join(parentDir, rel)
+			unchanged, err := unchangedFile(path, fi, parentPath)
+			if err != nil {
+				if readErr := checkReadable(path); readErr != nil && skip(rel, readErr) {
+					return nil
+				}
+				return err
+			}
+			// Fall back to copying if the link fails, e.g. at the link limit
+			if unchanged && os.Link(parentPath, dstPath) == nil {
+				info.Linked++
+				info.LinkedBytes += fi.Size()
+				copied.Add(fi.Size())
+				return nil
+			}
+		}
+		if _, err := c.engine.CloneContext(c.ctx, path, dstPath); err != nil {
+			// Only a failure to read the source is skipped, not one to
+			// write the copy
+			if readErr := checkReadable(path); readErr != nil && skip(rel, readErr) {
+				os.Remove(dstPath)
+				return nil
+			}
+			return fmt.Errorf("clone file %s: %w", rel, err)
+		}
+		info.Copied++
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, readErrs, nil
+}
+
+// checkReadable reads the file at path to the end and returns the error
+// that stopped it, if any.
+func checkReadable(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(io.Discard, f)
+	return err
+}
+
+// readErrorReason describes a read error without the worktree path, e.g.
+// "open: permission denied".
+func readErrorReason(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Op + ": " + pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// unchangedFile reports whether the parent snapshot file at parentPath can
+// stand in for the worktree file at path.
+func unchangedFile(path string, fi os.FileInfo, parentPath string) (bool, error) {
+	parent, err := os.Lstat(parentPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("stat parent %s: %w", parentPath, err)
+	}
+	if !parent.Mode().IsRegular() || parent.Size() != fi.Size() || parent.Mode().Perm() != fi.Mode().Perm() {
+		return false, nil
+	}
+	if parent.ModTime().Equal(fi.ModTime()) {
+		return true, nil
+	}
+
+	// Touched but possibly identical
+	sum, err := hashFile(path)
+	if err != nil {
+		return false, fmt.Errorf("hash %s: %w", path, err)
+	}
+	parentSum, err := hashFile(parentPath)
+	if err != nil {
+		return false, fmt.Errorf("hash %s: %w", parentPath, err)
+	}
+	return sum == parentSum, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
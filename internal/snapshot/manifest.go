@@ -0,0 +1,559 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/diff"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Manifests are stored zstd-compressed, in sections: the entries, in path
+// order, are split by their first path component into runs of at most
+// manifestSectionSize, and each run is written as JSON lines in a zstd
+// frame of its own. The file starts with a zstd skippable frame holding the
+// length of the next frame, the section table, which gives each section's
+// hash and the offset of its frame, so that the entries under a path can be
+// read without decompressing the rest. The whole file is still a valid zstd
+// stream.
+const (
+	manifestMagic       = 0x184D2A5A // a zstd skippable frame
+	manifestHeaderSize  = 16
+	manifestSectionSize = 4096
+)
+
+// manifestSection describes a section of a manifest file.
+type manifestSection struct {
+	Key     string          `json:"key"` // first path component of its entries
+	Entries int             `json:"entries"`
+	Hash    model.HashValue `json:"hash"` // SHA-256 of its JSON lines
+	// Offset and Length locate its frame after the section table. They are
+	// left out of ManifestHash, as they depend on the compressor.
+	Offset int64 `json:"offset,omitempty"`
+	Length int64 `json:"length,omitempty"`
+}
+
+type manifestTable struct {
+	SnapshotID model.SnapshotID  `json:"snapshot_id"`
+	Sections   []manifestSection `json:"sections"`
+}
+
+// ManifestHash returns the hash the descriptor of a snapshot with these
+// manifest entries records as its manifest_hash: that of its section table
+// without offsets. It vouches for each section's hash, so that a section
+// can be trusted on its own, without rebuilding the payload root hash from
+// every entry.
+func ManifestHash(entries []model.ManifestEntry) model.HashValue {
+	sections, _, err := manifestSections(sortedEntries(entries), nil)
+	if err != nil {
+		return ""
+	}
+	return sectionTableHash(sections)
+}
+
+func sectionTableHash(sections []manifestSection) model.HashValue {
+	bare := make([]manifestSection, len(sections))
+	for i, sec := range sections {
+		bare[i] = manifestSection{Key: sec.Key, Entries: sec.Entries, Hash: sec.Hash}
+	}
+	data, _ := json.Marshal(bare)
+	return integrity.SumBytes(model.HashSHA256, data)
+}
+
+// manifestSections splits entries into sections, passing the JSON lines of
+// each to frame, if not nil, which returns the bytes written for it.
+func manifestSections(entries []model.ManifestEntry, frame func(body []byte) (int64, error)) ([]manifestSection, int64, error) {
+	var sections []manifestSection
+	var offset int64
+	var body bytes.Buffer
+	flush := func() error {
+		if body.Len() == 0 {
+			return nil
+		}
+		sec := &sections[len(sections)-1]
+		sec.Hash = integrity.SumBytes(model.HashSHA256, body.Bytes())
+		if frame != nil {
+			n, err := frame(body.Bytes())
+			if err != nil {
+				return err
+			}
+			sec.Offset, sec.Length = offset, n
+			offset += n
+		}
+		body.Reset()
+		return nil
+	}
+	for _, e := range entries {
+		key := manifestKey(e.Path)
+		if n := len(sections); n == 0 || sections[n-1].Key != key || sections[n-1].Entries == manifestSectionSize {
+			if err := flush(); err != nil {
+				return nil, 0, err
+			}
+			sections = append(sections, manifestSection{Key: key})
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return nil, 0, err
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+		sections[len(sections)-1].Entries++
+	}
+	if err := flush(); err != nil {
+		return nil, 0, err
+	}
+	return sections, offset, nil
+}
+
+// manifestKey returns the first component of a payload path.
+func manifestKey(path string) string {
+	key, _, _ := strings.Cut(path, "/")
+	return key
+}
+
+func sortedEntries(entries []model.ManifestEntry) []model.ManifestEntry {
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b model.ManifestEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return sorted
+}
+
+// WriteManifest stores a snapshot's per-file manifest, with its entries in
+// path order.
+func WriteManifest(repoRoot string, m *model.Manifest) error {
+	path := repo.ManifestPath(repoRoot, m.SnapshotID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return fmt.Errorf("create zstd writer: %w", err)
+	}
+	defer enc.Close()
+
+	var frames bytes.Buffer
+	sections, _, err := manifestSections(sortedEntries(m.Entries), func(body []byte) (int64, error) {
+		n := frames.Len()
+		frames.Write(enc.EncodeAll(body, nil))
+		return int64(frames.Len() - n), nil
+	})
+	if err != nil {
+		return err
+	}
+	table, err := json.Marshal(manifestTable{SnapshotID: m.SnapshotID, Sections: sections})
+	if err != nil {
+		return err
+	}
+	tableFrame := enc.EncodeAll(table, nil)
+
+	data := make([]byte, manifestHeaderSize, manifestHeaderSize+len(tableFrame)+frames.Len())
+	binary.LittleEndian.PutUint32(data[0:], manifestMagic)
+	binary.LittleEndian.PutUint32(data[4:], 8)
+	binary.LittleEndian.PutUint64(data[8:], uint64(len(tableFrame)))
+	data = append(data, tableFrame...)
+	data = append(data, frames.Bytes()...)
+	return fsutil.AtomicWrite(path, data, 0644)
+}
+
+// manifestFile is an open manifest file.
+type manifestFile struct {
+	f     *os.File
+	id    model.SnapshotID
+	table manifestTable
+	base  int64 // offset of the first section frame
+}
+
+// openManifest opens the manifest of snapshot id and reads its section
+// table. It returns nil if the snapshot has no manifest in this format.
+func openManifest(repoRoot string, id model.SnapshotID) (*manifestFile, error) {
+	f, err := os.Open(repo.ManifestPath(repoRoot, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	mf := &manifestFile{f: f, id: id}
+	if err := mf.readTable(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return mf, nil
+}
+
+func (mf *manifestFile) readTable() error {
+	var header [manifestHeaderSize]byte
+	if _, err := io.ReadFull(mf.f, header[:]); err != nil {
+		return mf.corrupt(err)
+	}
+	if binary.LittleEndian.Uint32(header[0:]) != manifestMagic || binary.LittleEndian.Uint32(header[4:]) != 8 {
+		return mf.corrupt(fmt.Errorf("bad header"))
+	}
+	n := binary.LittleEndian.Uint64(header[8:])
+	info, err := mf.f.Stat()
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+	if n > uint64(info.Size()-manifestHeaderSize) {
+		return mf.corrupt(fmt.Errorf("section table overruns the file"))
+	}
+	dec, err := zstd.NewReader(io.NewSectionReader(mf.f, manifestHeaderSize, int64(n)))
+	if err != nil {
+		return fmt.Errorf("create zstd reader: %w", err)
+	}
+	defer dec.Close()
+	if err := json.NewDecoder(dec).Decode(&mf.table); err != nil {
+		return mf.corrupt(err)
+	}
+	if mf.table.SnapshotID != mf.id {
+		return mf.corrupt(fmt.Errorf("it is the manifest of %s", mf.table.SnapshotID))
+	}
+	mf.base = manifestHeaderSize + int64(n)
+	return nil
+}
+
+// readSection streams the entries of sec to fn, failing once they are read
+// if they do not match its hash.
+func (mf *manifestFile) readSection(sec manifestSection, fn func(model.ManifestEntry)) error {
+	dec, err := zstd.NewReader(io.NewSectionReader(mf.f, mf.base+sec.Offset, sec.Length))
+	if err != nil {
+		return fmt.Errorf("create zstd reader: %w", err)
+	}
+	defer dec.Close()
+	h := integrity.NewHash(model.HashSHA256)
+	d := json.NewDecoder(io.TeeReader(dec, h))
+	count := 0
+	for {
+		var e model.ManifestEntry
+		if err := d.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return mf.corrupt(err)
+		}
+		count++
+		fn(e)
+	}
+	if count != sec.Entries || model.HashValue(hex.EncodeToString(h.Sum(nil))) != sec.Hash {
+		return mf.corrupt(fmt.Errorf("section %q does not match its hash", sec.Key))
+	}
+	return nil
+}
+
+func (mf *manifestFile) corrupt(err error) error {
+	return errclass.ErrPayloadHashMismatch.WithMessagef("manifest of %s is corrupt: %v", mf.id, err)
+}
+
+func (mf *manifestFile) Close() error {
+	return mf.f.Close()
+}
+
+// LoadManifest returns the per-file manifest of the snapshot desc
+// describes, or nil if it has none, like snapshots created before manifests
+// were written. The manifest is only returned if its entries rebuild the
+// descriptor's payload root hash; otherwise LoadManifest fails with
+// errclass.ErrPayloadHashMismatch, so callers can trust each entry's hash
+// as much as the descriptor. Manifests written as plain JSON, before they
+// were compressed, are still read.
+func LoadManifest(repoRoot string, desc *model.Descriptor) (*model.Manifest, error) {
+	m, err := readManifest(repoRoot, desc.SnapshotID)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	if m.SnapshotID != desc.SnapshotID || integrity.ManifestRootHash(desc.HashAlgorithm, m.Entries) != desc.PayloadRootHash {
+		return nil, errclass.ErrPayloadHashMismatch.WithMessagef("manifest of %s does not match its payload hash", desc.SnapshotID)
+	}
+	return m, nil
+}
+
+func readManifest(repoRoot string, id model.SnapshotID) (*model.Manifest, error) {
+	mf, err := openManifest(repoRoot, id)
+	if err != nil {
+		return nil, err
+	}
+	if mf == nil {
+		return readLegacyManifest(repoRoot, id)
+	}
+	defer mf.Close()
+	m := &model.Manifest{SnapshotID: id}
+	for _, sec := range mf.table.Sections {
+		if err := mf.readSection(sec, func(e model.ManifestEntry) {
+			m.Entries = append(m.Entries, e)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func readLegacyManifest(repoRoot string, id model.SnapshotID) (*model.Manifest, error) {
+	data, err := os.ReadFile(repo.LegacyManifestPath(repoRoot, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m model.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errclass.ErrPayloadHashMismatch.WithMessagef("manifest of %s is corrupt: %v", id, err)
+	}
+	return &m, nil
+}
+
+// LoadManifestEntriesUnder returns the entries of the manifest of the
+// snapshot desc describes at or below each of the payload paths, in path
+// order, or nil if the snapshot has no manifest. It fails if a path is not
+// in the manifest. If the descriptor records the manifest's hash, only the
+// sections holding the paths are read, each checked against its hash;
+// otherwise the whole manifest is loaded and checked as by LoadManifest.
+func LoadManifestEntriesUnder(repoRoot string, desc *model.Descriptor, paths []string) ([]model.ManifestEntry, error) {
+	if desc.ManifestHash != "" {
+		mf, err := openManifest(repoRoot, desc.SnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		if mf != nil {
+			defer mf.Close()
+			if sectionTableHash(mf.table.Sections) != desc.ManifestHash {
+				return nil, errclass.ErrPayloadHashMismatch.WithMessagef("manifest of %s does not match its hash", desc.SnapshotID)
+			}
+			keys := make(map[string]bool)
+			for _, p := range paths {
+				keys[manifestKey(manifestPrefix(p))] = true
+			}
+			m := &model.Manifest{SnapshotID: desc.SnapshotID}
+			for _, sec := range mf.table.Sections {
+				if !keys[sec.Key] && !keys["."] {
+					continue
+				}
+				if err := mf.readSection(sec, func(e model.ManifestEntry) {
+					m.Entries = append(m.Entries, e)
+				}); err != nil {
+					return nil, err
+				}
+			}
+			return ManifestEntriesUnder(m, paths)
+		}
+	}
+
+	m, err := LoadManifest(repoRoot, desc)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return ManifestEntriesUnder(m, paths)
+}
+
+// ManifestDiff compares two snapshots by their manifests, without reading
+// their payloads. An empty from compares against an empty tree. It returns
+// nil if either snapshot has no manifest, for the caller to diff the
+// payloads instead, or if their manifests were hashed with different
+// algorithms and so cannot be compared entry by entry.
+func ManifestDiff(repoRoot string, from, to model.SnapshotID) (*diff.DiffResult, error) {
+	var fromManifest *model.Manifest
+	var fromDesc *model.Descriptor
+	if from != "" {
+		desc, err := LoadDescriptor(repoRoot, from)
+		if err != nil {
+			return nil, err
+		}
+		if fromManifest, err = LoadManifest(repoRoot, desc); err != nil || fromManifest == nil {
+			return nil, err
+		}
+		fromDesc = desc
+	}
+	toDesc, err := LoadDescriptor(repoRoot, to)
+	if err != nil {
+		return nil, err
+	}
+	toManifest, err := LoadManifest(repoRoot, toDesc)
+	if err != nil || toManifest == nil {
+		return nil, err
+	}
+	if fromDesc != nil && fromDesc.PayloadHashAlgorithm() != toDesc.PayloadHashAlgorithm() {
+		return nil, nil
+	}
+
+	result := diff.DiffManifests(fromManifest, toManifest)
+	if fromDesc != nil {
+		result.FromTime = fromDesc.CreatedAt
+	}
+	result.ToTime = toDesc.CreatedAt
+	return result, nil
+}
+
+// ManifestEntriesUnder returns the entries of m at or below each of the
+// payload paths, in manifest order. It fails if a path is not in the
+// manifest.
+func ManifestEntriesUnder(m *model.Manifest, paths []string) ([]model.ManifestEntry, error) {
+	prefixes := make([]string, len(paths))
+	found := make([]bool, len(paths))
+	for i, p := range paths {
+		prefixes[i] = manifestPrefix(p)
+	}
+
+	var entries []model.ManifestEntry
+	for _, e := range m.Entries {
+		matched := false
+		for i, prefix := range prefixes {
+			if prefix == "." || e.Path == prefix || strings.HasPrefix(e.Path, prefix+"/") {
+				found[i] = true
+				matched = true
+			}
+		}
+		if matched {
+			entries = append(entries, e)
+		}
+	}
+	for i, ok := range found {
+		if !ok {
+			return nil, fmt.Errorf("path not in snapshot %s: %s", m.SnapshotID, paths[i])
+		}
+	}
+	return entries, nil
+}
+
+// manifestPrefix returns payload path p as it appears in manifest entries,
+// or "." for the payload root.
+func manifestPrefix(p string) string {
+	return strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/")
+}
+
+// VerifyManifestEntry rehashes entry e in the payload of the snapshot desc
+// describes, stored in snapshotDir, and fails with
+// errclass.ErrPayloadHashMismatch if it no longer matches the manifest.
+// Files of compressed snapshots are hashed decompressed.
+func VerifyManifestEntry(snapshotDir string, desc *model.Descriptor, e model.ManifestEntry) error {
+	return readManifestEntry(snapshotDir, desc, e, "")
+}
+
+// ExtractManifestEntry writes entry e of the snapshot's payload to dst,
+// with the entry's permissions, checking its content against the manifest
+// as it is copied. The parent of dst must exist. Directories are created
+// writable, so that their entries can be extracted into them; the caller
+// applies their mode last.
+func ExtractManifestEntry(snapshotDir string, desc *model.Descriptor, e model.ManifestEntry, dst string) error {
+	return readManifestEntry(snapshotDir, desc, e, dst)
+}
+
+// readManifestEntry checks entry e against the payload, writing it to dst
+// unless dst is empty. Encrypted payloads cannot be read entry by entry.
+func readManifestEntry(snapshotDir string, desc *model.Descriptor, e model.ManifestEntry, dst string) error {
+	if desc.Encryption != nil {
+		return fmt.Errorf("snapshot %s is encrypted; its files cannot be read one by one", desc.SnapshotID)
+	}
+	path := filepath.Join(snapshotDir, filepath.FromSlash(e.Path))
+	mode := os.FileMode(e.Mode)
+	mismatch := func(what string) error {
+		return errclass.ErrPayloadHashMismatch.WithMessagef("%s of %s differs from the manifest of %s", what, e.Path, desc.SnapshotID)
+	}
+
+	switch e.Type {
+	case "dir":
+		info, err := os.Lstat(path)
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", e.Path, err)
+		}
+		if !info.IsDir() {
+			return mismatch("type")
+		}
+		if dst == "" {
+			return nil
+		}
+		if err := os.Mkdir(dst, 0755); err != nil && !os.IsExist(err) {
+			return err
+		}
+		return nil
+
+	case "symlink":
+		target, err := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("read symlink %s: %w", e.Path, err)
+		}
+		if integrity.SumBytes(desc.HashAlgorithm, []byte(target)) != e.Hash {
+			return mismatch("target")
+		}
+		if dst == "" {
+			return nil
+		}
+		return os.Symlink(target, dst)
+	}
+
+	// Compressed snapshots store files under their name plus the
+	// compression suffix, without their permissions
+	var r io.Reader
+	compressed := false
+	if desc.Compression != nil {
+		stored := path + compression.Extension(compression.CompressionType(desc.Compression.Type))
+		if _, err := os.Lstat(stored); err == nil {
+			data, err := compression.ReadFile(stored)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", e.Path, err)
+			}
+			r, compressed = bytes.NewReader(data), true
+		}
+	}
+	if !compressed {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", e.Path, err)
+		}
+		if !info.Mode().IsRegular() {
+			return mismatch("type")
+		}
+		if info.Mode().Perm() != mode {
+			return mismatch("mode")
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open %s: %w", e.Path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	h := integrity.NewHash(desc.HashAlgorithm)
+	w := io.Writer(h)
+	var out *os.File
+	if dst != "" {
+		var err error
+		out, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		w = io.MultiWriter(h, out)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", e.Path, err)
+	}
+	if n != e.Size || model.HashValue(hex.EncodeToString(h.Sum(nil))) != e.Hash {
+		return mismatch("content")
+	}
+	if out == nil {
+		return nil
+	}
+	if err := out.Chmod(mode); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,245 @@
+package snapshot_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestCreate_WritesManifest(t *testing.T) {
+	for _, twoPass := range []bool{false, true} {
+		repoPath := setupTestRepo(t)
+		mainPath := filepath.Join(repoPath, "main")
+		require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "dir"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(mainPath, "dir", "a.txt"), []byte("hello"), 0640))
+
+		creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+		creator.SetTwoPassHash(twoPass)
+		desc, err := creator.Create("main", "", nil)
+		require.NoError(t, err)
+
+		m, err := snapshot.LoadManifest(repoPath, desc)
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		assert.Equal(t, desc.SnapshotID, m.SnapshotID)
+		require.Len(t, m.Entries, 2)
+		assert.Equal(t, "dir/a.txt", m.Entries[1].Path)
+		assert.Equal(t, int64(5), m.Entries[1].Size)
+		assert.Equal(t, uint32(0640), m.Entries[1].Mode)
+	}
+}
+
+func TestLoadManifest_MissingOrTampered(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "", nil)
+	require.NoError(t, err)
+	path := repo.ManifestPath(repoPath, desc.SnapshotID)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(path))
+	m, err := snapshot.LoadManifest(repoPath, desc)
+	require.NoError(t, err)
+	assert.Nil(t, m, "snapshots without a manifest are still valid")
+
+	other := *desc
+	other.PayloadRootHash = "0000"
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	_, err = snapshot.LoadManifest(repoPath, &other)
+	assert.ErrorIs(t, err, errclass.ErrPayloadHashMismatch)
+}
+
+func TestWriteManifest_Compressed(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "src", "main.go"), []byte("package main"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, desc.ManifestHash)
+
+	// The file is a zstd stream of the section table and the entries
+	f, err := os.Open(repo.ManifestPath(repoPath, desc.SnapshotID))
+	require.NoError(t, err)
+	defer f.Close()
+	dec, err := zstd.NewReader(f)
+	require.NoError(t, err)
+	defer dec.Close()
+	data, err := io.ReadAll(dec)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"path":"src/main.go"`)
+}
+
+// writeSyntheticManifest writes a manifest of entries for a snapshot that
+// only exists as the returned descriptor.
+func writeSyntheticManifest(t *testing.T, repoPath string, entries []model.ManifestEntry) *model.Descriptor {
+	t.Helper()
+	desc := &model.Descriptor{
+		SnapshotID:      "1700000000000-abcd1234",
+		PayloadRootHash: integrity.ManifestRootHash(model.HashSHA256, entries),
+		ManifestHash:    snapshot.ManifestHash(entries),
+	}
+	require.NoError(t, snapshot.WriteManifest(repoPath, &model.Manifest{SnapshotID: desc.SnapshotID, Entries: entries}))
+	return desc
+}
+
+func TestLoadManifestEntriesUnder(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	entries := []model.ManifestEntry{{Path: "big", Type: "dir", Mode: 0755, Hash: "00"}}
+	for i := range 10000 {
+		entries = append(entries, model.ManifestEntry{Path: fmt.Sprintf("big/f%05d", i), Type: "file", Mode: 0644, Hash: "01"})
+	}
+	entries = append(entries,
+		model.ManifestEntry{Path: "small", Type: "dir", Mode: 0755, Hash: "02"},
+		model.ManifestEntry{Path: "small/a.txt", Type: "file", Mode: 0644, Size: 1, Hash: "03"},
+		model.ManifestEntry{Path: "zz.txt", Type: "file", Mode: 0644, Size: 1, Hash: "04"},
+	)
+	desc := writeSyntheticManifest(t, repoPath, entries)
+
+	got, err := snapshot.LoadManifestEntriesUnder(repoPath, desc, []string{"big/f09999", "small"})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, "big/f09999", got[0].Path, "runs longer than a section are found")
+	assert.Equal(t, "small", got[1].Path)
+	assert.Equal(t, "small/a.txt", got[2].Path)
+
+	all, err := snapshot.LoadManifestEntriesUnder(repoPath, desc, []string{"."})
+	require.NoError(t, err)
+	assert.Len(t, all, len(entries))
+	_, err = snapshot.LoadManifestEntriesUnder(repoPath, desc, []string{"missing"})
+	assert.Error(t, err)
+
+	// A damaged section only fails the lookups that read it
+	path := repo.ManifestPath(repoPath, desc.SnapshotID)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	_, err = snapshot.LoadManifestEntriesUnder(repoPath, desc, []string{"small"})
+	require.NoError(t, err)
+	_, err = snapshot.LoadManifestEntriesUnder(repoPath, desc, []string{"zz.txt"})
+	assert.ErrorIs(t, err, errclass.ErrPayloadHashMismatch)
+	_, err = snapshot.LoadManifest(repoPath, desc)
+	assert.ErrorIs(t, err, errclass.ErrPayloadHashMismatch)
+
+	// So does a section table the descriptor does not vouch for
+	other := *desc
+	other.ManifestHash = "0000"
+	_, err = snapshot.LoadManifestEntriesUnder(repoPath, &other, []string{"small"})
+	assert.ErrorIs(t, err, errclass.ErrPayloadHashMismatch)
+}
+
+func TestLoadManifest_Legacy(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	entries := []model.ManifestEntry{
+		{Path: "a.txt", Type: "file", Mode: 0644, Size: 1, Hash: "01"},
+		{Path: "b.txt", Type: "file", Mode: 0644, Size: 1, Hash: "02"},
+	}
+	desc := writeSyntheticManifest(t, repoPath, entries)
+	require.NoError(t, os.Remove(repo.ManifestPath(repoPath, desc.SnapshotID)))
+	data, err := json.Marshal(model.Manifest{SnapshotID: desc.SnapshotID, Entries: entries})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(repo.LegacyManifestPath(repoPath, desc.SnapshotID), data, 0644))
+
+	m, err := snapshot.LoadManifest(repoPath, desc)
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, entries, m.Entries)
+	got, err := snapshot.LoadManifestEntriesUnder(repoPath, desc, []string{"b.txt"})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "b.txt", got[0].Path)
+}
+
+func TestManifestDiff(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "keep.txt"), []byte("same"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "edit.txt"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "gone.txt"), []byte("bye"), 0644))
+	first, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "edit.txt"), []byte("v22"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(mainPath, "gone.txt")))
+	require.NoError(t, os.Symlink("keep.txt", filepath.Join(mainPath, "new")))
+	second, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+
+	result, err := snapshot.ManifestDiff(repoPath, first.SnapshotID, second.SnapshotID)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, first.SnapshotID, result.FromSnapshotID)
+	assert.Equal(t, second.CreatedAt, result.ToTime)
+	require.Len(t, result.Added, 1)
+	assert.True(t, result.Added[0].IsSymlink)
+	require.Len(t, result.Modified, 1)
+	assert.Equal(t, "edit.txt", result.Modified[0].Path)
+	assert.Equal(t, int64(1), result.Modified[0].SizeDelta)
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, "gone.txt", result.Removed[0].Path)
+
+	// An empty from lists every file as added
+	payload, err := snapshot.ManifestDiff(repoPath, "", second.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, payload.TotalAdded)
+
+	require.NoError(t, os.Remove(repo.ManifestPath(repoPath, first.SnapshotID)))
+	result, err = snapshot.ManifestDiff(repoPath, first.SnapshotID, second.SnapshotID)
+	require.NoError(t, err)
+	assert.Nil(t, result, "without both manifests the payloads must be compared")
+}
+
+func TestVerifyManifestEntry(t *testing.T) {
+	for _, comp := range []*compression.Compressor{nil, compression.NewCompressor(compression.LevelFast)} {
+		repoPath := setupTestRepo(t)
+		mainPath := filepath.Join(repoPath, "main")
+		require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("hello"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(mainPath, "b.txt"), []byte("world"), 0644))
+
+		creator := snapshot.NewCreatorWithCompression(repoPath, model.EngineCopy, comp)
+		desc, err := creator.Create("main", "", nil)
+		require.NoError(t, err)
+		m, err := snapshot.LoadManifest(repoPath, desc)
+		require.NoError(t, err)
+		entries, err := snapshot.ManifestEntriesUnder(m, []string{"b.txt"})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		snapshotDir := repo.SnapshotPath(repoPath, desc.SnapshotID)
+		require.NoError(t, snapshot.VerifyManifestEntry(snapshotDir, desc, entries[0]))
+
+		dst := filepath.Join(t.TempDir(), "b.txt")
+		require.NoError(t, snapshot.ExtractManifestEntry(snapshotDir, desc, entries[0], dst))
+		content, err := os.ReadFile(dst)
+		require.NoError(t, err)
+		assert.Equal(t, "world", string(content))
+
+		// Corrupt the stored file
+		if comp != nil {
+			require.NoError(t, os.Rename(filepath.Join(snapshotDir, "a.txt.gz"), filepath.Join(snapshotDir, "b.txt.gz")))
+		} else {
+			require.NoError(t, os.WriteFile(filepath.Join(snapshotDir, "b.txt"), []byte("WORLD"), 0644))
+		}
+		assert.ErrorIs(t, snapshot.VerifyManifestEntry(snapshotDir, desc, entries[0]), errclass.ErrPayloadHashMismatch)
+
+		_, err = snapshot.ManifestEntriesUnder(m, []string{"missing"})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,182 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Amend changes the metadata of a published snapshot: update edits a copy
+// of its descriptor, whose checksum is then recomputed before it replaces
+// the stored one. If the note or tags change, the old and new values are
+// first appended to the snapshot's metadata history, so that rewriting a
+// label never loses it, and the change is audited as snapshot_amend.
+// Amends hold the repository lock exclusively, so they do not race each
+// other or GC. The audit record carries the author and correlation ID ctx
+// carries (see audit.WithAuthor and audit.WithCorrelationID).
+//
+// operation names the amend in the history and actor who made it; an
+// empty actor uses the author ctx carries, or else identity.Author.
+func Amend(ctx context.Context, repoRoot string, id model.SnapshotID, operation, actor string, update func(*model.Descriptor) error) (*model.Descriptor, error) {
+	lock, err := repolock.NewManager(repoRoot).Acquire(model.LockExclusive, operation)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	desc, err := LoadDescriptor(repoRoot, id)
+	if err != nil {
+		return nil, err
+	}
+	change := &model.MetadataChange{
+		Operation: operation,
+		Actor:     actor,
+		OldNote:   desc.Note,
+		OldTags:   slices.Clone(desc.Tags),
+	}
+	if err := update(desc); err != nil {
+		return nil, err
+	}
+	checksum, err := integrity.ComputeDescriptorChecksum(desc)
+	if err != nil {
+		return nil, fmt.Errorf("compute checksum: %w", err)
+	}
+	desc.DescriptorChecksum = checksum
+
+	changed := desc.Note != change.OldNote || !slices.Equal(desc.Tags, change.OldTags)
+	if changed {
+		change.ChangedAt = time.Now().UTC()
+		if change.Actor == "" {
+			change.Actor = audit.ResolveAuthor(audit.Author(ctx))
+		}
+		change.NewNote = desc.Note
+		change.NewTags = slices.Clone(desc.Tags)
+		if err := appendMetadataChange(repoRoot, id, change); err != nil {
+			return nil, fmt.Errorf("record metadata history: %w", err)
+		}
+	}
+
+	store, err := descstore.Open(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	if err := store.Put(desc); err != nil {
+		return nil, fmt.Errorf("write descriptor: %w", err)
+	}
+	if changed {
+		updateIndex(repoRoot, desc)
+		auditAmend(ctx, repoRoot, desc, change)
+	}
+	return desc, nil
+}
+
+// NoteSeparator joins the text appended to a note by Annotate to the note.
+const NoteSeparator = "; "
+
+// Annotate replaces the note of a published snapshot with note or, if
+// appendNote is set, appends note to it after NoteSeparator. It amends the
+// snapshot as Amend does, with operation "annotate".
+func Annotate(ctx context.Context, repoRoot string, id model.SnapshotID, actor, note string, appendNote bool) (*model.Descriptor, error) {
+	return Amend(ctx, repoRoot, id, "annotate", actor, func(d *model.Descriptor) error {
+		if appendNote && d.Note != "" {
+			d.Note += NoteSeparator + note
+		} else {
+			d.Note = note
+		}
+		return nil
+	})
+}
+
+// auditAmend records a metadata change in the audit log. Like snapshot
+// creation, it only warns if the log cannot be written.
+func auditAmend(ctx context.Context, repoRoot string, desc *model.Descriptor, change *model.MetadataChange) {
+	details := map[string]any{
+		"operation": change.Operation,
+		"actor":     change.Actor,
+	}
+	if change.NewNote != change.OldNote {
+		details["old_note"] = change.OldNote
+		details["new_note"] = change.NewNote
+	}
+	if !slices.Equal(change.NewTags, change.OldTags) {
+		details["old_tags"] = change.OldTags
+		details["new_tags"] = change.NewTags
+	}
+	appender := audit.NewFileAppender(audit.LogPath(repoRoot))
+	appender.SetCorrelationID(audit.CorrelationID(ctx))
+	appender.SetAuthor(audit.Author(ctx))
+	if err := appender.Append(model.EventTypeSnapshotAmend, desc.WorktreeName, desc.SnapshotID, details); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// MetadataHistory returns the metadata changes made to a snapshot, oldest
+// first. A snapshot that was never amended has none.
+func MetadataHistory(repoRoot string, id model.SnapshotID) ([]*model.MetadataChange, error) {
+	f, err := os.Open(repo.MetadataHistoryPath(repoRoot, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read metadata history: %w", err)
+	}
+	defer f.Close()
+
+	var changes []*model.MetadataChange
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var change model.MetadataChange
+		if err := json.Unmarshal(scanner.Bytes(), &change); err != nil {
+			return nil, fmt.Errorf("parse metadata history line %d: %w", line, err)
+		}
+		changes = append(changes, &change)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read metadata history: %w", err)
+	}
+	return changes, nil
+}
+
+// CurrentActor identifies who is running jvs, as user@host.
+func CurrentActor() string {
+	return identity.Default()
+}
+
+func appendMetadataChange(repoRoot string, id model.SnapshotID, change *model.MetadataChange) error {
+	path := repo.MetadataHistoryPath(repoRoot, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
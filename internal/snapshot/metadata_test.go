@@ -0,0 +1,114 @@
+package snapshot_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestAmend_RecordsMetadataHistory(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", []string{"v1"})
+	require.NoError(t, err)
+
+	changes, err := snapshot.MetadataHistory(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = snapshot.Amend(context.Background(), repoPath, desc.SnapshotID, "annotate", "alice@host", func(d *model.Descriptor) error {
+		d.Note = "first, reviewed"
+		return nil
+	})
+	require.NoError(t, err)
+	amended, err := snapshot.Amend(context.Background(), repoPath, desc.SnapshotID, "tag", "", func(d *model.Descriptor) error {
+		d.Tags = append(d.Tags, "stable")
+		return nil
+	})
+	require.NoError(t, err)
+
+	stored, err := snapshot.LoadDescriptor(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, amended, stored)
+	assert.Equal(t, "first, reviewed", stored.Note)
+	assert.Equal(t, []string{"v1", "stable"}, stored.Tags)
+	checksum, err := integrity.ComputeDescriptorChecksum(stored)
+	require.NoError(t, err)
+	assert.Equal(t, checksum, stored.DescriptorChecksum)
+
+	changes, err = snapshot.MetadataHistory(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "annotate", changes[0].Operation)
+	assert.Equal(t, "alice@host", changes[0].Actor)
+	assert.Equal(t, "first", changes[0].OldNote)
+	assert.Equal(t, "first, reviewed", changes[0].NewNote)
+	assert.Equal(t, []string{"v1"}, changes[0].OldTags)
+	assert.Equal(t, "tag", changes[1].Operation)
+	assert.Equal(t, snapshot.CurrentActor(), changes[1].Actor)
+	assert.Equal(t, []string{"v1", "stable"}, changes[1].NewTags)
+	assert.False(t, changes[1].ChangedAt.Before(changes[0].ChangedAt))
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeSnapshotAmend})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, desc.SnapshotID, records[1].SnapshotID)
+	assert.Equal(t, "tag", records[1].Details["operation"])
+	assert.Contains(t, records[1].Details, "new_tags")
+	assert.NotContains(t, records[1].Details, "new_note")
+}
+
+func TestAmend_NoChangeOrError(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", nil)
+	require.NoError(t, err)
+
+	_, err = snapshot.Amend(context.Background(), repoPath, desc.SnapshotID, "annotate", "", func(d *model.Descriptor) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	boom := errors.New("boom")
+	_, err = snapshot.Amend(context.Background(), repoPath, desc.SnapshotID, "annotate", "", func(d *model.Descriptor) error {
+		d.Note = "lost"
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	stored, err := snapshot.LoadDescriptor(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, "first", stored.Note)
+	changes, err := snapshot.MetadataHistory(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "unchanged and failed amends leave no history")
+}
+
+func TestAnnotate(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "", nil)
+	require.NoError(t, err)
+
+	// Appending to an empty note sets it
+	amended, err := snapshot.Annotate(context.Background(), repoPath, desc.SnapshotID, "", "tests pass", true)
+	require.NoError(t, err)
+	assert.Equal(t, "tests pass", amended.Note)
+	amended, err = snapshot.Annotate(context.Background(), repoPath, desc.SnapshotID, "", "deployed", true)
+	require.NoError(t, err)
+	assert.Equal(t, "tests pass; deployed", amended.Note)
+	amended, err = snapshot.Annotate(context.Background(), repoPath, desc.SnapshotID, "", "rolled back", false)
+	require.NoError(t, err)
+	assert.Equal(t, "rolled back", amended.Note)
+
+	changes, err := snapshot.MetadataHistory(repoPath, desc.SnapshotID)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "annotate", changes[2].Operation)
+	assert.Equal(t, "tests pass; deployed", changes[2].OldNote)
+}
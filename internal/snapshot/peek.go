@@ -0,0 +1,231 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Entry types reported by Peek.
+const (
+	PeekDir     = "dir"
+	PeekFile    = "file"
+	PeekSymlink = "symlink"
+)
+
+// PeekEntry describes one entry of a snapshot payload. Sizes are logical
+// (uncompressed) byte counts; for directories, Size and Files cover the whole
+// subtree even when Children is cut off by the depth limit.
+type PeekEntry struct {
+	Name     string       `json:"name"`
+	Path     string       `json:"path"` // slash-separated, relative to the payload root
+	Type     string       `json:"type"`
+	Size     int64        `json:"size"`
+	Files    int          `json:"files,omitempty"`
+	Target   string       `json:"target,omitempty"` // symlink target
+	Children []*PeekEntry `json:"children,omitempty"`
+}
+
+// Peek lists the payload of a snapshot at relPath ("" for the root) without
+// restoring it, descending depth levels (at least one). Directories come
+// before files, each sorted by name. Encrypted snapshots are listed from a
+// decrypted copy.
+func Peek(repoRoot string, snapshotID model.SnapshotID, relPath string, depth int) (*PeekEntry, error) {
+	desc, root, err := OpenPayload(repoRoot, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if desc.Encryption != nil {
+		var cleanup func()
+		if desc, root, cleanup, err = UncompressedPayload(repoRoot, snapshotID, "peek"); err != nil {
+			return nil, err
+		}
+		defer cleanup()
+	}
+	if depth < 1 {
+		depth = 1
+	}
+
+	if strings.Contains(relPath, "..") {
+		return nil, fmt.Errorf("path cannot contain '..': %s", relPath)
+	}
+	rel := path.Clean("/" + filepath.ToSlash(relPath))[1:]
+
+	p := &peeker{}
+	if desc.Compression != nil && desc.Encryption == nil {
+		p.ext = compression.Extension(compression.CompressionType(desc.Compression.Type))
+	}
+	full := filepath.Join(root, filepath.FromSlash(rel))
+
+	info, err := os.Lstat(full)
+	if err != nil && p.ext != "" && rel != "" {
+		info, err = os.Lstat(full + p.ext)
+		full += p.ext
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("path not found in snapshot %s: %s", snapshotID, relPath)
+		}
+		return nil, err
+	}
+
+	name := path.Base(rel)
+	if rel == "" {
+		name = "."
+	}
+	return p.entry(full, name, rel, info, depth)
+}
+
+type peeker struct {
+	// ext is the suffix of compressed payload files, or "" if the snapshot
+	// is not compressed.
+	ext string
+}
+
+func (p *peeker) entry(full, name, rel string, info os.FileInfo, depth int) (*PeekEntry, error) {
+	e := &PeekEntry{Name: name, Path: rel}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		e.Type = PeekSymlink
+		target, err := os.Readlink(full)
+		if err != nil {
+			return nil, err
+		}
+		e.Target = target
+	case info.IsDir():
+		e.Type = PeekDir
+		if e.Path == "" {
+			e.Path = "."
+		}
+		if err := p.dir(e, full, rel, depth); err != nil {
+			return nil, err
+		}
+	default:
+		e.Type = PeekFile
+		e.Files = 1
+		size, err := LogicalSize(full, info, p.ext)
+		if err != nil {
+			return nil, err
+		}
+		e.Size = size
+	}
+	return e, nil
+}
+
+// dir totals a directory's subtree, listing children down to depth levels.
+func (p *peeker) dir(e *PeekEntry, full, rel string, depth int) error {
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return err
+	}
+	for _, d := range entries {
+		name := d.Name()
+		if rel == "" && (name == ".READY" || name == ".READY"+p.ext) {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		displayName := name
+		if p.ext != "" && info.Mode().IsRegular() {
+			displayName = strings.TrimSuffix(name, p.ext)
+		}
+
+		child, err := p.entry(filepath.Join(full, name), displayName, path.Join(rel, displayName), info, depth-1)
+		if err != nil {
+			return err
+		}
+		e.Size += child.Size
+		e.Files += child.Files
+		if depth >= 1 {
+			e.Children = append(e.Children, child)
+		}
+	}
+	sort.Slice(e.Children, func(i, j int) bool {
+		a, b := e.Children[i], e.Children[j]
+		if (a.Type == PeekDir) != (b.Type == PeekDir) {
+			return a.Type == PeekDir
+		}
+		return a.Name < b.Name
+	})
+	return nil
+}
+
+// LogicalSize returns the size of a payload file once uncompressed. ext is
+// the suffix of compressed files in the snapshot, or "" if it is not
+// compressed; for compressed files, the size is read from the file's header
+// or trailer.
+func LogicalSize(path string, info os.FileInfo, ext string) (int64, error) {
+	if ext == "" || !strings.HasSuffix(path, ext) {
+		return info.Size(), nil
+	}
+	if ext == compression.Extension(compression.TypeZstd) {
+		return zstdSize(path)
+	}
+	return gzipSize(path)
+}
+
+// gzipSize returns the uncompressed size recorded in a gzip file's trailer
+// (modulo 4 GiB, as the format stores it).
+func gzipSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(-4, io.SeekEnd); err != nil {
+		return 0, fmt.Errorf("read gzip size %s: %w", path, err)
+	}
+	var buf [4]byte
+	if _, err := io.ReadFull(f, buf[:]); err != nil {
+		return 0, fmt.Errorf("read gzip size %s: %w", path, err)
+	}
+	return int64(binary.LittleEndian.Uint32(buf[:])), nil
+}
+
+// zstdSize returns the uncompressed size of a zstd file, from its frame
+// header if recorded there, otherwise by decompressing it.
+func zstdSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	buf := make([]byte, zstd.HeaderMaxSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, fmt.Errorf("read zstd size %s: %w", path, err)
+	}
+	var h zstd.Header
+	if err := h.Decode(buf[:n]); err != nil {
+		return 0, fmt.Errorf("read zstd size %s: %w", path, err)
+	}
+	if h.HasFCS {
+		return int64(h.FrameContentSize), nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	dec, err := zstd.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("read zstd size %s: %w", path, err)
+	}
+	defer dec.Close()
+	size, err := io.Copy(io.Discard, dec)
+	if err != nil {
+		return 0, fmt.Errorf("read zstd size %s: %w", path, err)
+	}
+	return size, nil
+}
@@ -0,0 +1,112 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func setupPeekPayload(t *testing.T, repoPath string) {
+	t.Helper()
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "src", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "README.md"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "src", "main.go"), []byte(strings.Repeat("x", 100)), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "src", "lib", "a.go"), []byte(strings.Repeat("y", 1000)), 0644))
+	require.NoError(t, os.Symlink("README.md", filepath.Join(mainPath, "link")))
+}
+
+func TestPeek_TopLevel(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	setupPeekPayload(t, repoPath)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "peek", nil)
+	require.NoError(t, err)
+
+	root, err := snapshot.Peek(repoPath, desc.SnapshotID, "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.PeekDir, root.Type)
+	assert.Equal(t, ".", root.Path)
+	assert.Equal(t, int64(1105), root.Size)
+	assert.Equal(t, 3, root.Files)
+
+	require.Len(t, root.Children, 3, ".READY is not listed")
+	src := root.Children[0]
+	assert.Equal(t, "src", src.Name)
+	assert.Equal(t, int64(1100), src.Size)
+	assert.Equal(t, 2, src.Files)
+	assert.Empty(t, src.Children, "not listed beyond depth 1")
+	assert.Equal(t, "README.md", root.Children[1].Name)
+	assert.Equal(t, snapshot.PeekSymlink, root.Children[2].Type)
+	assert.Equal(t, "README.md", root.Children[2].Target)
+}
+
+func TestPeek_SubdirAndDepth(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	setupPeekPayload(t, repoPath)
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "peek", nil)
+	require.NoError(t, err)
+
+	src, err := snapshot.Peek(repoPath, desc.SnapshotID, "src", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "src", src.Path)
+	require.Len(t, src.Children, 2)
+	assert.Equal(t, "src/lib", src.Children[0].Path)
+	require.Len(t, src.Children[0].Children, 1)
+	assert.Equal(t, "src/lib/a.go", src.Children[0].Children[0].Path)
+
+	file, err := snapshot.Peek(repoPath, desc.SnapshotID, "src/main.go", 1)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.PeekFile, file.Type)
+	assert.Equal(t, int64(100), file.Size)
+
+	_, err = snapshot.Peek(repoPath, desc.SnapshotID, "missing", 1)
+	assert.ErrorContains(t, err, "not found")
+	_, err = snapshot.Peek(repoPath, desc.SnapshotID, "../../..", 1)
+	assert.Error(t, err)
+}
+
+func TestPeek_CompressedShowsLogicalNamesAndSizes(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	setupPeekPayload(t, repoPath)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompression(compression.LevelDefault)
+	desc, err := creator.Create("main", "peek", nil)
+	require.NoError(t, err)
+
+	root, err := snapshot.Peek(repoPath, desc.SnapshotID, "", 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1105), root.Size)
+	assert.Equal(t, "README.md", root.Children[1].Name)
+
+	file, err := snapshot.Peek(repoPath, desc.SnapshotID, "src/lib/a.go", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "a.go", file.Name)
+	assert.Equal(t, int64(1000), file.Size)
+}
+
+func TestPeek_ZstdCompressed(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	setupPeekPayload(t, repoPath)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	creator.SetCompressor(compression.NewCompressorWithType(compression.TypeZstd, compression.LevelFast))
+	desc, err := creator.Create("main", "peek", nil)
+	require.NoError(t, err)
+
+	root, err := snapshot.Peek(repoPath, desc.SnapshotID, "", 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1105), root.Size)
+	assert.Equal(t, "README.md", root.Children[1].Name)
+
+	file, err := snapshot.Peek(repoPath, desc.SnapshotID, "src/lib/a.go", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "a.go", file.Name)
+	assert.Equal(t, int64(1000), file.Size)
+}
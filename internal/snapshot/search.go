@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/index"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Search returns the snapshots whose note, tags, worktree name or metadata
+// (annotations and recorded environment) contain every word of query,
+// best match first. limit caps the results; zero means no limit.
+//
+// It reads the repository index, building it if the repository has none
+// yet. Snapshots deleted behind the index's back are left out.
+func Search(repoRoot, query string, limit int) ([]*model.Descriptor, error) {
+	idx, err := openIndex(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer idx.Close()
+	ids, err := idx.Search(query, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := descstore.Open(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	var result []*model.Descriptor
+	for _, id := range ids {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		desc, err := store.Get(id)
+		if errors.Is(err, errclass.ErrSnapshotNotFound) || errors.Is(err, errclass.ErrDescriptorCorrupt) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if repo.CheckSnapshotReady(repoRoot, id) != nil {
+			continue
+		}
+		result = append(result, desc)
+	}
+	return result, nil
+}
+
+// RebuildIndex replaces the repository index with one of every listed
+// snapshot and returns how many it indexed.
+func RebuildIndex(repoRoot string) (int, error) {
+	all, err := ListAll(repoRoot)
+	if err != nil {
+		return 0, err
+	}
+	if err := index.Build(repoRoot, all); err != nil {
+		return 0, err
+	}
+
+	// Snapshots created, amended or deleted while the index was built did
+	// not update it, as it did not exist yet: catch up with them
+	idx, err := index.Open(repoRoot)
+	if err != nil {
+		return 0, err
+	}
+	defer idx.Close()
+	now, err := ListAll(repoRoot)
+	if err != nil {
+		return 0, err
+	}
+	built := make(map[model.SnapshotID]model.HashValue, len(all))
+	for _, desc := range all {
+		built[desc.SnapshotID] = desc.DescriptorChecksum
+	}
+	for _, desc := range now {
+		if checksum, ok := built[desc.SnapshotID]; !ok || checksum != desc.DescriptorChecksum {
+			if err := idx.Put(desc); err != nil {
+				return 0, err
+			}
+		}
+		delete(built, desc.SnapshotID)
+	}
+	for id := range built {
+		if err := idx.Delete(id); err != nil {
+			return 0, err
+		}
+	}
+	return len(now), nil
+}
+
+// openIndex opens the repository index, building it if the repository has
+// none yet.
+func openIndex(repoRoot string) (*index.Index, error) {
+	idx, err := index.Open(repoRoot)
+	if errors.Is(err, index.ErrNotBuilt) {
+		if _, err := RebuildIndex(repoRoot); err != nil {
+			return nil, err
+		}
+		idx, err = index.Open(repoRoot)
+	}
+	return idx, err
+}
+
+// updateIndex indexes a new or amended descriptor. The index is a cache
+// that 'jvs doctor --repair rebuild_index' can rebuild, so a failed update
+// only warns.
+func updateIndex(repoRoot string, desc *model.Descriptor) {
+	if err := index.Update(repoRoot, desc); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update index: %v\n", err)
+	}
+}
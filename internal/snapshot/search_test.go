@@ -0,0 +1,82 @@
+package snapshot_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestSearch(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	crash, err := creator.Create("main", "OOM crash at step 400", []string{"nightly"})
+	require.NoError(t, err)
+	_, err = creator.Create("main", "clean run", nil)
+	require.NoError(t, err)
+
+	// The first search builds the index from every descriptor
+	assert.NoFileExists(t, filepath.Join(repoPath, ".jvs", "index.sqlite"))
+	found, err := snapshot.Search(repoPath, "oom crash", 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, crash.SnapshotID, found[0].SnapshotID)
+
+	// Later snapshots and amends update it
+	creator.SetAnnotations(map[string]string{"run": "oom-repro"})
+	repro, err := creator.Create("main", "", nil)
+	require.NoError(t, err)
+	_, err = snapshot.Annotate(context.Background(), repoPath, crash.SnapshotID, "", "fixed", false)
+	require.NoError(t, err)
+
+	found, err = snapshot.Search(repoPath, "oom", 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, repro.SnapshotID, found[0].SnapshotID)
+	found, err = snapshot.Search(repoPath, "fixed nightly", 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	// Snapshots deleted behind the index's back are left out
+	require.NoError(t, os.RemoveAll(filepath.Join(repoPath, ".jvs", "snapshots", string(repro.SnapshotID))))
+	found, err = snapshot.Search(repoPath, "oom", 0)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestFind_ReadsIndex(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	creator := snapshot.NewCreator(repoPath, model.EngineCopy)
+	first, err := creator.Create("main", "first", []string{"nightly"})
+	require.NoError(t, err)
+	second, err := creator.Create("main", "second", nil)
+	require.NoError(t, err)
+
+	found, err := snapshot.FindByTag(repoPath, "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, first.SnapshotID, found.SnapshotID)
+	assert.FileExists(t, filepath.Join(repoPath, ".jvs", "index.sqlite"))
+
+	// Amends keep the index current
+	_, err = snapshot.Amend(context.Background(), repoPath, second.SnapshotID, "tag", "", func(d *model.Descriptor) error {
+		d.Tags = append(d.Tags, "nightly")
+		return nil
+	})
+	require.NoError(t, err)
+	found, err = snapshot.FindByTag(repoPath, "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, second.SnapshotID, found.SnapshotID)
+
+	// A deleted index is rebuilt
+	require.NoError(t, os.Remove(filepath.Join(repoPath, ".jvs", "index.sqlite")))
+	matches, err := snapshot.Find(repoPath, snapshot.FilterOptions{WorktreeName: "main", Tags: []string{"nightly"}})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, second.SnapshotID, matches[0].SnapshotID)
+}
@@ -0,0 +1,37 @@
+package snapshot
+
+import (
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// CheckStale reports whether worktree cfg is stale under policy at now: its
+// latest snapshot, or its creation if it has none, is older than its
+// maximum age. It returns nil if the worktree is not stale or the check is
+// disabled for it.
+func CheckStale(repoRoot string, cfg *model.WorktreeConfig, policy model.StalenessPolicy, now time.Time) (*model.Staleness, error) {
+	maxAge := policy.MaxAgeFor(cfg.Name)
+	if maxAge <= 0 {
+		return nil, nil
+	}
+	since := cfg.CreatedAt
+	if cfg.LatestSnapshotID != "" {
+		desc, err := LoadDescriptor(repoRoot, cfg.LatestSnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		since = desc.CreatedAt
+	}
+	age := now.Sub(since)
+	if age <= maxAge {
+		return nil, nil
+	}
+	return &model.Staleness{
+		Worktree:       cfg.Name,
+		LastSnapshotID: cfg.LatestSnapshotID,
+		Since:          since,
+		Age:            age,
+		MaxAge:         maxAge,
+	}, nil
+}
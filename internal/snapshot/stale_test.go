@@ -0,0 +1,49 @@
+package snapshot_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestCheckStale(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	wtMgr := worktree.NewManager(repoPath)
+	policy := model.StalenessPolicy{MaxAge: time.Hour, Worktrees: map[string]time.Duration{"scratch": 0}}
+
+	cfg, err := wtMgr.Get("main")
+	require.NoError(t, err)
+	st, err := snapshot.CheckStale(repoPath, cfg, policy, cfg.CreatedAt.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, st, "a worktree without snapshots ages from its creation")
+	assert.Equal(t, cfg.CreatedAt, st.Since)
+	assert.Empty(t, st.LastSnapshotID)
+
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", nil)
+	require.NoError(t, err)
+	cfg, err = wtMgr.Get("main")
+	require.NoError(t, err)
+	st, err = snapshot.CheckStale(repoPath, cfg, policy, desc.CreatedAt.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, st)
+
+	st, err = snapshot.CheckStale(repoPath, cfg, policy, desc.CreatedAt.Add(90*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, st)
+	assert.Equal(t, desc.SnapshotID, st.LastSnapshotID)
+	assert.Equal(t, 90*time.Minute, st.Age)
+	assert.Equal(t, time.Hour, st.MaxAge)
+	assert.Contains(t, st.Message(), "last snapshotted 1h30m0s ago")
+
+	// A zero maximum age disables the check
+	cfg.Name = "scratch"
+	st, err = snapshot.CheckStale(repoPath, cfg, policy, desc.CreatedAt.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, st)
+}
@@ -0,0 +1,100 @@
+package snapshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/diff"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// WorktreeChanges lists how a worktree's payload differs from its HEAD
+// snapshot.
+type WorktreeChanges struct {
+	Worktree       string           `json:"worktree"`
+	HeadSnapshotID model.SnapshotID `json:"head_snapshot_id,omitempty"`
+	// Dirty is set if anything changed, so a new snapshot would record
+	// something.
+	Dirty    bool           `json:"dirty"`
+	Added    []*diff.Change `json:"added"`
+	Removed  []*diff.Change `json:"removed"`
+	Modified []*diff.Change `json:"modified"`
+}
+
+// Changes compares the payload of a worktree with its HEAD snapshot, as
+// diff.QuickDiff does. Paths excluded by .jvsignore are left out; against a
+// partial snapshot only its paths are compared. Without a HEAD snapshot
+// every file is reported as added.
+func Changes(repoRoot, worktreeName string) (*WorktreeChanges, error) {
+	wtMgr := worktree.NewManager(repoRoot)
+	cfg, err := wtMgr.Get(worktreeName)
+	if err != nil {
+		return nil, fmt.Errorf("get worktree: %w", err)
+	}
+	payloadPath := wtMgr.Path(worktreeName)
+
+	var headDir string
+	var partialPaths []string
+	if cfg.HeadSnapshotID != "" {
+		desc, dir, cleanup, err := UncompressedPayload(repoRoot, cfg.HeadSnapshotID, "status")
+		if err != nil {
+			return nil, fmt.Errorf("open head snapshot: %w", err)
+		}
+		defer cleanup()
+		headDir, partialPaths = dir, desc.PartialPaths
+	}
+
+	skip, err := WorktreeSkip(payloadPath, partialPaths)
+	if err != nil {
+		return nil, err
+	}
+	result, err := diff.QuickDiff(headDir, payloadPath, skip)
+	if err != nil {
+		return nil, fmt.Errorf("compare payload: %w", err)
+	}
+	changes := &WorktreeChanges{
+		Worktree:       worktreeName,
+		HeadSnapshotID: cfg.HeadSnapshotID,
+		Dirty:          result.TotalAdded+result.TotalRemoved+result.TotalModified > 0,
+		Added:          append([]*diff.Change{}, result.Added...),
+		Removed:        append([]*diff.Change{}, result.Removed...),
+		Modified:       append([]*diff.Change{}, result.Modified...),
+	}
+	return changes, nil
+}
+
+// WorktreeSkip returns the skip function for comparing the worktree payload
+// at payloadPath with a snapshot, as diff.QuickDiff and diff.Differ take
+// it: against a partial snapshot of partialPaths, it keeps only those
+// paths; otherwise it leaves out the paths excluded by .jvsignore. It
+// returns nil if nothing is left out.
+func WorktreeSkip(payloadPath string, partialPaths []string) (func(rel string, isDir bool) bool, error) {
+	if len(partialPaths) > 0 {
+		return outsidePaths(partialPaths), nil
+	}
+	ignore, err := LoadIgnore(payloadPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ignore.Empty() {
+		return nil, nil
+	}
+	return ignore.Match, nil
+}
+
+// outsidePaths returns a skip function for diff.QuickDiff that keeps only
+// the given paths, everything below them and the directories above them.
+func outsidePaths(paths []string) func(rel string, isDir bool) bool {
+	return func(rel string, isDir bool) bool {
+		rel = filepath.ToSlash(rel)
+		for _, p := range paths {
+			p = filepath.ToSlash(p)
+			if rel == p || strings.HasPrefix(rel, p+"/") || isDir && strings.HasPrefix(p, rel+"/") {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,78 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestChanges(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("a"), 0644))
+
+	changes, err := snapshot.Changes(repoPath, "main")
+	require.NoError(t, err)
+	assert.True(t, changes.Dirty)
+	assert.Empty(t, changes.HeadSnapshotID)
+	require.Len(t, changes.Added, 1, "without a HEAD every file is added")
+
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", nil)
+	require.NoError(t, err)
+	changes, err = snapshot.Changes(repoPath, "main")
+	require.NoError(t, err)
+	assert.False(t, changes.Dirty)
+	assert.Equal(t, desc.SnapshotID, changes.HeadSnapshotID)
+	assert.NotNil(t, changes.Added)
+	assert.Empty(t, changes.Added)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "a.txt"), []byte("aa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "b.txt"), []byte("b"), 0644))
+	changes, err = snapshot.Changes(repoPath, "main")
+	require.NoError(t, err)
+	assert.True(t, changes.Dirty)
+	require.Len(t, changes.Modified, 1)
+	assert.Equal(t, "a.txt", changes.Modified[0].Path)
+	assert.Equal(t, int64(1), changes.Modified[0].SizeDelta)
+	require.Len(t, changes.Added, 1)
+	assert.Equal(t, "b.txt", changes.Added[0].Path)
+}
+
+func TestChanges_Ignore(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, snapshot.IgnoreFileName), []byte("*.log\n"), 0644))
+	_, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "debug.log"), []byte("noise"), 0644))
+	changes, err := snapshot.Changes(repoPath, "main")
+	require.NoError(t, err)
+	assert.False(t, changes.Dirty, "ignored files do not make the worktree dirty")
+}
+
+func TestChanges_PartialHead(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "models"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "models", "m.bin"), []byte("m"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "notes.txt"), []byte("n"), 0644))
+	_, err := snapshot.NewCreator(repoPath, model.EngineCopy).CreatePartial("main", "models", nil, []string{"models"})
+	require.NoError(t, err)
+
+	changes, err := snapshot.Changes(repoPath, "main")
+	require.NoError(t, err)
+	assert.False(t, changes.Dirty, "paths outside a partial snapshot are not compared")
+
+	require.NoError(t, os.Remove(filepath.Join(mainPath, "models", "m.bin")))
+	changes, err = snapshot.Changes(repoPath, "main")
+	require.NoError(t, err)
+	require.Len(t, changes.Removed, 1)
+	assert.Equal(t, "models/m.bin", changes.Removed[0].Path)
+}
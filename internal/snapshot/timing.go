@@ -0,0 +1,64 @@
+package snapshot
+
+import (
+	"os"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/logging"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// timingEnvVar enables recording of per-phase timings in snapshot descriptors.
+const timingEnvVar = "JVS_DEBUG_TIMING"
+
+// timingEnabled reports whether JVS_DEBUG_TIMING is set to a truthy value.
+func timingEnabled() bool {
+	switch os.Getenv(timingEnvVar) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+// PhaseObserver is called at the end of each phase of a long operation,
+// such as "copy" or "hash", with the phase's start and end times.
+type PhaseObserver func(phase string, start, end time.Time)
+
+// phaseTimer measures consecutive phases of snapshot creation.
+// Each phase is also emitted as a debug log entry and passed to the
+// observer, if any.
+type phaseTimer struct {
+	snapshotID model.SnapshotID
+	observe    PhaseObserver
+	last       time.Time
+	timings    []model.PhaseTiming
+}
+
+// newPhaseTimer starts timing the first phase. The caller sets snapshotID
+// once it is known.
+func newPhaseTimer(observe PhaseObserver) *phaseTimer {
+	return &phaseTimer{observe: observe, last: time.Now()}
+}
+
+// mark ends the current phase under the given name and starts the next one.
+func (t *phaseTimer) mark(phase string) {
+	now := time.Now()
+	d := now.Sub(t.last)
+	if t.observe != nil {
+		t.observe(phase, t.last, now)
+	}
+	t.last = now
+	t.timings = append(t.timings, model.PhaseTiming{Phase: phase, Duration: d})
+	logging.Debug("snapshot phase", map[string]any{
+		"snapshot_id": string(t.snapshotID),
+		"phase":       phase,
+		"duration_ms": float64(d.Microseconds()) / 1000,
+	})
+}
+
+// stats returns the recorded timings as a descriptor stats section.
+func (t *phaseTimer) stats() *model.SnapshotStats {
+	timings := make([]model.PhaseTiming, len(t.timings))
+	copy(timings, t.timings)
+	return &model.SnapshotStats{Timings: timings}
+}
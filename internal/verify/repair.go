@@ -0,0 +1,337 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/cas"
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// RepairedEntry is a payload entry rewritten by Repair.
+type RepairedEntry struct {
+	Path string `json:"path"`
+	// Source is the snapshot the entry was copied from; Replica is the
+	// repository holding it, if it is not this one. Both are empty for
+	// directories that were recreated.
+	Source  model.SnapshotID `json:"source,omitempty"`
+	Replica string           `json:"replica,omitempty"`
+}
+
+// RepairResult reports what Repair did.
+type RepairResult struct {
+	SnapshotID model.SnapshotID `json:"snapshot_id"`
+	Repaired   []RepairedEntry  `json:"repaired,omitempty"`
+	// Unrepaired are the corrupted entries no other snapshot had.
+	Unrepaired []string `json:"unrepaired,omitempty"`
+	// Result verifies the snapshot's whole payload after the repair.
+	Result *Result `json:"result"`
+}
+
+// donor is a snapshot that may hold copies of corrupted entries.
+type donor struct {
+	repoRoot string
+	replica  string
+	id       model.SnapshotID
+	loaded   bool
+	desc     *model.Descriptor
+	dir      string
+	byHash   map[model.HashValue][]model.ManifestEntry
+}
+
+// Repair checks every entry of a snapshot's payload against its manifest
+// and rewrites the corrupted ones with copies from other snapshots: first
+// its lineage, nearest first, then the same snapshot in each replica, from
+// the repository's replicas config and then replicas. A copy is only used
+// if its content matches the corrupted entry's manifest hash. Missing
+// directories are recreated. Repair holds the repository lock exclusive,
+// audits what it rewrote as snapshot_repair, and returns the verification
+// of the whole payload afterwards.
+//
+// The snapshot's descriptor and manifest must be intact, as they decide
+// what the payload should be. Encrypted snapshots cannot be repaired entry
+// by entry. Files shared with other snapshots through hard links, by
+// incremental snapshots or the content store, were corrupted in those
+// snapshots too; only this snapshot is rewritten.
+func (v *Verifier) Repair(snapshotID model.SnapshotID, replicas []string) (*RepairResult, error) {
+	lock, err := repolock.NewManager(v.repoRoot).Acquire(model.LockExclusive, "verify repair")
+	if err != nil {
+		return nil, fmt.Errorf("acquire repo lock: %w", err)
+	}
+	defer lock.Release()
+
+	check, desc := v.verifyDescriptor(snapshotID)
+	if desc == nil {
+		return nil, fmt.Errorf("cannot repair %s: %s", snapshotID, check.Error)
+	}
+	if desc.Encryption != nil {
+		return nil, fmt.Errorf("snapshot %s is encrypted; it cannot be repaired entry by entry", snapshotID)
+	}
+	manifest, err := snapshot.LoadManifest(v.repoRoot, desc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot repair %s: %w", snapshotID, err)
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("snapshot %s has no manifest; it cannot be repaired", snapshotID)
+	}
+
+	snapshotDir := repo.SnapshotPath(v.repoRoot, snapshotID)
+	var corrupted []model.ManifestEntry
+	for _, e := range manifest.Entries {
+		err := snapshot.VerifyManifestEntry(snapshotDir, desc, e)
+		if errors.Is(err, errclass.ErrPayloadHashMismatch) || errors.Is(err, os.ErrNotExist) {
+			corrupted = append(corrupted, e)
+		} else if err != nil {
+			return nil, fmt.Errorf("check %s: %w", e.Path, err)
+		}
+	}
+
+	result := &RepairResult{SnapshotID: snapshotID}
+	if len(corrupted) > 0 {
+		donors, release, err := v.donors(desc, replicas)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		// Staged under .jvs, on the snapshot's filesystem, and named so
+		// 'jvs doctor --repair-runtime' cleans it up after a crash
+		staging, err := os.MkdirTemp(filepath.Join(v.repoRoot, repo.JVSDirName), ".jvs-tmp-repair-")
+		if err != nil {
+			return nil, fmt.Errorf("create temp dir: %w", err)
+		}
+		defer os.RemoveAll(staging)
+
+		for i, e := range corrupted {
+			repaired, err := v.repairEntry(desc, snapshotDir, e, donors, filepath.Join(staging, fmt.Sprint(i)))
+			if err != nil {
+				return nil, fmt.Errorf("repair %s: %w", e.Path, err)
+			}
+			if repaired == nil {
+				result.Unrepaired = append(result.Unrepaired, e.Path)
+				continue
+			}
+			result.Repaired = append(result.Repaired, *repaired)
+		}
+	}
+
+	if len(result.Repaired) > 0 {
+		paths := make([]string, len(result.Repaired))
+		for i, r := range result.Repaired {
+			paths[i] = r.Path
+		}
+		details := map[string]any{"repaired": paths}
+		if len(result.Unrepaired) > 0 {
+			details["unrepaired"] = result.Unrepaired
+		}
+		if err := audit.NewFileAppender(audit.LogPath(v.repoRoot)).Append(model.EventTypeSnapshotRepair, desc.WorktreeName, snapshotID, details); err != nil {
+			return nil, fmt.Errorf("write audit log: %w", err)
+		}
+	}
+
+	if result.Result, err = v.VerifySnapshot(snapshotID, true); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// repairEntry rewrites entry e of the snapshot from the first donor with a
+// matching copy, staged at tmp. It returns nil if no donor has one.
+func (v *Verifier) repairEntry(desc *model.Descriptor, snapshotDir string, e model.ManifestEntry, donors []*donor, tmp string) (*RepairedEntry, error) {
+	target := filepath.Join(snapshotDir, filepath.FromSlash(e.Path))
+	if e.Type == "dir" {
+		if _, err := os.Lstat(target); !os.IsNotExist(err) {
+			// Something else is in its place
+			return nil, nil
+		}
+		err := withWritableParent(target, func() error {
+			if err := os.Mkdir(target, 0755); err != nil {
+				return err
+			}
+			return os.Chmod(target, os.FileMode(e.Mode))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &RepairedEntry{Path: e.Path}, nil
+	}
+
+	for _, d := range donors {
+		if !d.load(desc.PayloadHashAlgorithm()) {
+			continue
+		}
+		for _, candidate := range d.byHash[e.Hash] {
+			if candidate.Type != e.Type || candidate.Size != e.Size {
+				continue
+			}
+			// The copy is checked against the hash as it is extracted, so
+			// a donor corrupted in the same way is never used
+			os.RemoveAll(tmp)
+			if err := snapshot.ExtractManifestEntry(d.dir, d.desc, candidate, tmp); err != nil {
+				continue
+			}
+			if err := v.install(desc, target, e, tmp); err != nil {
+				return nil, err
+			}
+			return &RepairedEntry{Path: e.Path, Source: d.id, Replica: d.replica}, nil
+		}
+	}
+	return nil, nil
+}
+
+// install replaces the stored form of entry e at target with tmp, a
+// verified copy of its content.
+func (v *Verifier) install(desc *model.Descriptor, target string, e model.ManifestEntry, tmp string) error {
+	stored := target
+	if e.Type == "file" {
+		if err := os.Chmod(tmp, os.FileMode(e.Mode)); err != nil {
+			return err
+		}
+		// Compressed snapshots store files compressed, except .gz files
+		// under gzip
+		if c := desc.Compression; c != nil {
+			typ := compression.CompressionType(c.Type)
+			ext := compression.Extension(typ)
+			if typ == compression.TypeZstd || !strings.HasSuffix(target, ".gz") {
+				compressed, err := compression.NewCompressorWithType(typ, compression.CompressionLevel(c.Level)).CompressFile(tmp)
+				if err != nil {
+					return err
+				}
+				tmp, stored = compressed, target+ext
+			}
+		}
+	}
+
+	err := withWritableParent(target, func() error {
+		if err := os.RemoveAll(stored); err != nil {
+			return err
+		}
+		return fsutil.RenameAndSync(tmp, stored)
+	})
+	if err != nil {
+		return err
+	}
+	if desc.ContentStore != nil && e.Type == "file" {
+		return cas.NewStore(v.repoRoot).Relink(stored)
+	}
+	return nil
+}
+
+// donors lists the snapshots that may hold copies of desc's entries: its
+// lineage, breadth first from its parent and children, then desc's
+// snapshot in each replica, each under a shared lock that the returned
+// release drops.
+func (v *Verifier) donors(desc *model.Descriptor, replicas []string) ([]*donor, func(), error) {
+	store, err := descstore.Open(v.repoRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	descs, err := store.List()
+	store.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("list descriptors: %w", err)
+	}
+	parents := make(map[model.SnapshotID]model.SnapshotID)
+	children := make(map[model.SnapshotID][]model.SnapshotID)
+	for _, d := range descs {
+		if d.ParentID != nil {
+			parents[d.SnapshotID] = *d.ParentID
+			children[*d.ParentID] = append(children[*d.ParentID], d.SnapshotID)
+		}
+	}
+
+	var donors []*donor
+	seen := map[model.SnapshotID]bool{desc.SnapshotID: true}
+	queue := []model.SnapshotID{desc.SnapshotID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		next := children[id]
+		if parent, ok := parents[id]; ok {
+			next = append([]model.SnapshotID{parent}, next...)
+		}
+		for _, n := range next {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+				donors = append(donors, &donor{repoRoot: v.repoRoot, id: n})
+			}
+		}
+	}
+
+	cfg, err := config.Load(v.repoRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	var locks []*repolock.Lock
+	release := func() {
+		for _, l := range locks {
+			l.Release()
+		}
+	}
+	for _, replica := range append(cfg.Replicas, replicas...) {
+		if !filepath.IsAbs(replica) {
+			replica = filepath.Join(v.repoRoot, replica)
+		}
+		lock, err := repolock.NewManager(replica).Acquire(model.LockShared, "verify repair")
+		if err != nil {
+			release()
+			return nil, nil, fmt.Errorf("lock replica %s: %w", replica, err)
+		}
+		locks = append(locks, lock)
+		donors = append(donors, &donor{repoRoot: replica, replica: replica, id: desc.SnapshotID})
+	}
+	return donors, release, nil
+}
+
+// load reads the donor's manifest the first time it is needed, and reports
+// whether the donor can be used: it must be ready, unencrypted, and hashed
+// with alg.
+func (d *donor) load(alg model.HashAlgorithm) bool {
+	if !d.loaded {
+		d.loaded = true
+		desc, dir, err := snapshot.OpenPayload(d.repoRoot, d.id)
+		if err != nil || desc.Encryption != nil || desc.PayloadHashAlgorithm() != alg {
+			return false
+		}
+		manifest, err := snapshot.LoadManifest(d.repoRoot, desc)
+		if err != nil || manifest == nil {
+			return false
+		}
+		d.desc, d.dir = desc, dir
+		d.byHash = make(map[model.HashValue][]model.ManifestEntry)
+		for _, e := range manifest.Entries {
+			d.byHash[e.Hash] = append(d.byHash[e.Hash], e)
+		}
+	}
+	return d.desc != nil
+}
+
+// withWritableParent runs fn with the directory containing path writable,
+// as frozen snapshots may have read-only directories.
+func withWritableParent(path string, fn func() error) error {
+	parent := filepath.Dir(path)
+	info, err := os.Stat(parent)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode&0200 == 0 {
+		if err := os.Chmod(parent, mode|0200); err != nil {
+			return err
+		}
+		defer os.Chmod(parent, mode)
+	}
+	return fn()
+}
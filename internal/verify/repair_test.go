@@ -0,0 +1,118 @@
+package verify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/verify"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// createLineage snapshots main twice: shared.txt is in both, first.txt and
+// second.txt only in the first and second.
+func createLineage(t *testing.T, repoPath string) (model.SnapshotID, model.SnapshotID) {
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "dir", "shared.txt"), []byte("shared"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "first.txt"), []byte("first"), 0644))
+	first, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "first", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(mainPath, "first.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "second.txt"), []byte("second"), 0644))
+	second, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "second", nil)
+	require.NoError(t, err)
+	return first.SnapshotID, second.SnapshotID
+}
+
+func TestVerifier_Repair_FromLineage(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	first, second := createLineage(t, repoPath)
+
+	// The parent lost a directory, the child's copy of the shared file
+	// was overwritten
+	require.NoError(t, os.RemoveAll(filepath.Join(repo.SnapshotPath(repoPath, first), "dir")))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.SnapshotPath(repoPath, second), "dir", "shared.txt"), []byte("rotten"), 0644))
+
+	v := verify.NewVerifier(repoPath)
+	result, err := v.Repair(first, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []verify.RepairedEntry{{Path: "dir"}}, result.Repaired)
+	// The child's copy is corrupt too, so it was not used
+	assert.Equal(t, []string{"dir/shared.txt"}, result.Unrepaired)
+	assert.True(t, result.Result.TamperDetected)
+
+	// Repairing the child restores its copy from the manifest hash, and
+	// the parent then takes it from the child
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "dir", "shared.txt"), []byte("shared"), 0644))
+	third, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "third", nil)
+	require.NoError(t, err)
+
+	result, err = v.Repair(second, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []verify.RepairedEntry{{Path: "dir/shared.txt", Source: third.SnapshotID}}, result.Repaired)
+	assert.Empty(t, result.Unrepaired)
+	assert.True(t, result.Result.PayloadHashValid)
+
+	result, err = v.Repair(first, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []verify.RepairedEntry{{Path: "dir/shared.txt", Source: second}}, result.Repaired)
+	assert.True(t, result.Result.PayloadHashValid)
+
+	records, err := audit.Read(audit.LogPath(repoPath), audit.Filter{EventType: model.EventTypeSnapshotRepair})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestVerifier_Repair_Intact(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	_, second := createLineage(t, repoPath)
+
+	result, err := verify.NewVerifier(repoPath).Repair(second, nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Repaired)
+	assert.Empty(t, result.Unrepaired)
+	assert.True(t, result.Result.PayloadHashValid)
+}
+
+func TestVerifier_Repair_FromReplica(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	_, second := createLineage(t, repoPath)
+	replica := filepath.Join(t.TempDir(), "replica")
+	_, err := engine.NewEngine(model.EngineCopy).Clone(repoPath, replica)
+	require.NoError(t, err)
+
+	// No other snapshot of the repository has second.txt
+	secondFile := filepath.Join(repo.SnapshotPath(repoPath, second), "second.txt")
+	require.NoError(t, os.WriteFile(secondFile, []byte("rotten"), 0644))
+	v := verify.NewVerifier(repoPath)
+	result, err := v.Repair(second, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second.txt"}, result.Unrepaired)
+
+	result, err = v.Repair(second, []string{replica})
+	require.NoError(t, err)
+	assert.Equal(t, []verify.RepairedEntry{{Path: "second.txt", Source: second, Replica: replica}}, result.Repaired)
+	assert.True(t, result.Result.PayloadHashValid)
+	data, err := os.ReadFile(secondFile)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+}
+
+func TestVerifier_Repair_CorruptDescriptor(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	snapshotID := createTestSnapshot(t, repoPath)
+	descPath := filepath.Join(repoPath, ".jvs", "descriptors", string(snapshotID)+".json")
+	require.NoError(t, os.WriteFile(descPath, []byte("{}"), 0644))
+
+	_, err := verify.NewVerifier(repoPath).Repair(snapshotID, nil)
+	assert.Error(t, err)
+}
@@ -2,12 +2,17 @@
 package verify
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/model"
 )
 
@@ -19,25 +24,140 @@ type Result struct {
 	TamperDetected   bool             `json:"tamper_detected"`
 	Severity         string           `json:"severity,omitempty"`
 	Error            string           `json:"error,omitempty"`
+	// ReadErrors lists the paths left out of the snapshot because they
+	// could not be read. They are not part of the payload hash, so they do
+	// not fail verification; Severity is "warning" if nothing else is wrong.
+	ReadErrors []model.ReadError `json:"read_errors,omitempty"`
+	// Paths are the payload paths checked by VerifyPaths; the rest of the
+	// payload was not.
+	Paths []string `json:"paths,omitempty"`
 }
 
 // Verifier performs integrity verification on snapshots.
 type Verifier struct {
 	repoRoot string
+	ctx      context.Context
 }
 
 // NewVerifier creates a new verifier.
 func NewVerifier(repoRoot string) *Verifier {
-	return &Verifier{repoRoot: repoRoot}
+	return &Verifier{repoRoot: repoRoot, ctx: context.Background()}
+}
+
+// SetContext makes verification stop once ctx is done: hashing a payload
+// fails with ctx's error, which is returned rather than reported as a
+// result.
+func (v *Verifier) SetContext(ctx context.Context) {
+	v.ctx = ctx
 }
 
 // VerifySnapshot verifies a single snapshot's integrity.
 func (v *Verifier) VerifySnapshot(snapshotID model.SnapshotID, verifyPayloadHash bool) (*Result, error) {
+	result, desc := v.verifyDescriptor(snapshotID)
+	if desc == nil {
+		return result, nil
+	}
+
+	// Optionally verify payload hash (expensive)
+	if verifyPayloadHash {
+		// Encrypted payloads are hashed decrypted; without a key they
+		// cannot be checked, which is not tampering
+		payloadDir, cleanup := repo.SnapshotPath(v.repoRoot, snapshotID), func() {}
+		if desc.Encryption != nil {
+			var err error
+			if _, payloadDir, cleanup, err = snapshot.UncompressedPayload(v.repoRoot, snapshotID, "verify"); err != nil {
+				result.Error = err.Error()
+				result.Severity = "error"
+				return result, nil
+			}
+		}
+		defer cleanup()
+		computedHash, err := integrity.ComputePayloadRootHashContext(v.ctx, payloadDir, desc.HashAlgorithm)
+		if ctxErr := v.ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("compute payload hash: %v", err)
+			result.Severity = "error"
+			return result, nil
+		}
+
+		result.PayloadHashValid = computedHash == desc.PayloadRootHash
+		if !result.PayloadHashValid {
+			result.TamperDetected = true
+			result.Severity = "critical"
+			result.Error = "payload hash mismatch"
+		}
+	}
+
+	return result, nil
+}
+
+// VerifyPaths verifies a snapshot's descriptor and, instead of its whole
+// payload, only the entries at or below the given payload paths, which are
+// rehashed and compared with the snapshot's manifest. It fails if the
+// snapshot has no manifest or a path is not in it.
+func (v *Verifier) VerifyPaths(snapshotID model.SnapshotID, paths []string) (*Result, error) {
+	result, desc := v.verifyDescriptor(snapshotID)
+	result.Paths = paths
+	if desc == nil {
+		return result, nil
+	}
+
+	if desc.Encryption != nil {
+		return nil, fmt.Errorf("snapshot %s is encrypted; verify it without paths", snapshotID)
+	}
+	entries, err := snapshot.LoadManifestEntriesUnder(v.repoRoot, desc, paths)
+	if errors.Is(err, errclass.ErrPayloadHashMismatch) {
+		result.TamperDetected = true
+		result.Severity = "critical"
+		result.Error = err.Error()
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		return nil, fmt.Errorf("snapshot %s has no manifest; verify it without paths", snapshotID)
+	}
+
+	snapshotDir := repo.SnapshotPath(v.repoRoot, snapshotID)
+	for _, e := range entries {
+		if err := v.ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := snapshot.VerifyManifestEntry(snapshotDir, desc, e)
+		if errors.Is(err, errclass.ErrPayloadHashMismatch) || errors.Is(err, os.ErrNotExist) {
+			result.TamperDetected = true
+			result.Severity = "critical"
+			result.Error = err.Error()
+			return result, nil
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.Severity = "error"
+			return result, nil
+		}
+	}
+	result.PayloadHashValid = true
+	return result, nil
+}
+
+// verifyDescriptor loads a snapshot's descriptor and checks its checksum.
+// It returns the descriptor only if the payload is worth checking next;
+// otherwise the result says why not.
+func (v *Verifier) verifyDescriptor(snapshotID model.SnapshotID) (*Result, *model.Descriptor) {
 	result := &Result{
 		SnapshotID: snapshotID,
 	}
 
 	desc, err := snapshot.LoadDescriptor(v.repoRoot, snapshotID)
+	if errors.Is(err, errclass.ErrSnapshotNotReady) {
+		// Unpublished, not tampered: never safe to read, but nothing to repair
+		result.Error = err.Error()
+		result.Severity = "error"
+		return result, nil
+	}
 	if err != nil {
 		result.Error = err.Error()
 		result.TamperDetected = true
@@ -60,26 +180,16 @@ func (v *Verifier) VerifySnapshot(snapshotID model.SnapshotID, verifyPayloadHash
 		result.Error = "descriptor checksum mismatch"
 		return result, nil
 	}
-
-	// Optionally verify payload hash (expensive)
-	if verifyPayloadHash {
-		snapshotDir := filepath.Join(v.repoRoot, ".jvs", "snapshots", string(snapshotID))
-		computedHash, err := integrity.ComputePayloadRootHash(snapshotDir)
-		if err != nil {
-			result.Error = fmt.Sprintf("compute payload hash: %v", err)
-			result.Severity = "error"
-			return result, nil
-		}
-
-		result.PayloadHashValid = computedHash == desc.PayloadRootHash
-		if !result.PayloadHashValid {
-			result.TamperDetected = true
-			result.Severity = "critical"
-			result.Error = "payload hash mismatch"
-		}
+	if err := integrity.CheckHashAlgorithm(desc.HashAlgorithm); err != nil {
+		result.Error = err.Error()
+		result.Severity = "error"
+		return result, nil
 	}
-
-	return result, nil
+	if desc.HasReadErrors() {
+		result.ReadErrors = desc.ReadErrors
+		result.Severity = "warning"
+	}
+	return result, desc
 }
 
 // VerifyAll verifies all snapshots in the repository.
@@ -95,7 +205,9 @@ func (v *Verifier) VerifyAll(verifyPayloadHash bool) ([]*Result, error) {
 
 	var results []*Result
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Unpublished snapshots left by a crash are not snapshots yet;
+		// doctor reports them as orphan tmp directories
+		if !entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
 			continue
 		}
 		snapshotID := model.SnapshotID(entry.Name())
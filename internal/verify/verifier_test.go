@@ -1,10 +1,13 @@
 package verify_test
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/jvs-project/jvs/internal/integrity"
 	"github.com/jvs-project/jvs/internal/repo"
 	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/verify"
@@ -215,6 +218,20 @@ func TestVerifier_VerifyAll_WithNonDirectoryEntries(t *testing.T) {
 	assert.Len(t, results, 1)
 }
 
+func TestVerifier_VerifyAll_SkipsUnpublishedTmpDirs(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	createTestSnapshot(t, repoPath)
+
+	// Left behind by a snapshot that crashed before publishing
+	snapshotsDir := filepath.Join(repoPath, ".jvs", "snapshots")
+	require.NoError(t, os.MkdirAll(filepath.Join(snapshotsDir, "1708300800000-deadbeef.tmp"), 0755))
+
+	results, err := verify.NewVerifier(repoPath).VerifyAll(true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].TamperDetected)
+}
+
 func TestVerifier_VerifyAll_WithDeletedSnapshotsDir(t *testing.T) {
 	repoPath := setupTestRepo(t)
 
@@ -312,3 +329,113 @@ func TestVerifier_VerifyAll_SnapshotsDirReadError(t *testing.T) {
 	// Restore permissions for cleanup
 	os.Chmod(snapshotsDir, 0755)
 }
+
+func TestVerifier_VerifyWorktree(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	snapshotID := createTestSnapshot(t, repoPath)
+
+	v := verify.NewVerifier(repoPath)
+	result, err := v.VerifyWorktree("main", snapshotID)
+	require.NoError(t, err)
+	assert.True(t, result.Match)
+	assert.Equal(t, result.ExpectedHash, result.ActualHash)
+	assert.Empty(t, result.Mismatched)
+
+	// Diverge the payload from the snapshot
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "file.txt"), []byte("changed"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "extra.txt"), []byte("x"), 0644))
+
+	result, err = v.VerifyWorktree("main", snapshotID)
+	require.NoError(t, err)
+	assert.False(t, result.Match)
+	assert.Equal(t, []string{"added: extra.txt", "modified: file.txt"}, result.Mismatched)
+}
+
+func TestVerifier_VerifySnapshot_NotReady(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	snapshotID := createTestSnapshot(t, repoPath)
+	require.NoError(t, os.Remove(filepath.Join(repo.SnapshotPath(repoPath, snapshotID), ".READY")))
+
+	result, err := verify.NewVerifier(repoPath).VerifySnapshot(snapshotID, true)
+	require.NoError(t, err)
+	assert.False(t, result.TamperDetected)
+	assert.Equal(t, "error", result.Severity)
+	assert.Contains(t, result.Error, "E_SNAPSHOT_NOT_READY")
+}
+
+func TestVerifier_VerifySnapshot_ReadErrors(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	snapshotID := createTestSnapshot(t, repoPath)
+
+	// Record a read error as a skip-and-record snapshot would
+	descPath := filepath.Join(repoPath, ".jvs", "descriptors", string(snapshotID)+".json")
+	content, err := os.ReadFile(descPath)
+	require.NoError(t, err)
+	var desc model.Descriptor
+	require.NoError(t, json.Unmarshal(content, &desc))
+	desc.ReadErrors = []model.ReadError{{Path: "secret.txt", Error: "open: permission denied"}}
+	desc.DescriptorChecksum, err = integrity.ComputeDescriptorChecksum(&desc)
+	require.NoError(t, err)
+	content, err = json.Marshal(&desc)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(descPath, content, 0644))
+
+	result, err := verify.NewVerifier(repoPath).VerifySnapshot(snapshotID, true)
+	require.NoError(t, err)
+	assert.True(t, result.ChecksumValid)
+	assert.True(t, result.PayloadHashValid, "skipped files are not part of the payload hash")
+	assert.False(t, result.TamperDetected)
+	assert.Equal(t, "warning", result.Severity)
+	assert.Equal(t, desc.ReadErrors, result.ReadErrors)
+}
+
+func TestVerifier_VerifyPaths(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mainPath := filepath.Join(repoPath, "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainPath, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainPath, "dir", "a.txt"), []byte("a"), 0644))
+	snapshotID := createTestSnapshot(t, repoPath)
+
+	v := verify.NewVerifier(repoPath)
+	result, err := v.VerifyPaths(snapshotID, []string{"dir"})
+	require.NoError(t, err)
+	assert.True(t, result.PayloadHashValid)
+	assert.False(t, result.TamperDetected)
+	assert.Equal(t, []string{"dir"}, result.Paths)
+
+	// Only the named paths are checked
+	snapshotDir := repo.SnapshotPath(repoPath, snapshotID)
+	require.NoError(t, os.WriteFile(filepath.Join(snapshotDir, "file.txt"), []byte("tampered"), 0644))
+	result, err = v.VerifyPaths(snapshotID, []string{"dir/a.txt"})
+	require.NoError(t, err)
+	assert.True(t, result.PayloadHashValid)
+
+	result, err = v.VerifyPaths(snapshotID, []string{"file.txt"})
+	require.NoError(t, err)
+	assert.False(t, result.PayloadHashValid)
+	assert.True(t, result.TamperDetected)
+	assert.Equal(t, "critical", result.Severity)
+
+	_, err = v.VerifyPaths(snapshotID, []string{"nope"})
+	assert.Error(t, err)
+}
+
+func TestVerifier_SetContext(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	snapshotID := createTestSnapshot(t, repoPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	v := verify.NewVerifier(repoPath)
+	v.SetContext(ctx)
+	_, err := v.VerifySnapshot(snapshotID, true)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = v.VerifyAll(true)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The descriptor alone is checked without hashing
+	result, err := v.VerifySnapshot(snapshotID, false)
+	require.NoError(t, err)
+	assert.True(t, result.ChecksumValid)
+}
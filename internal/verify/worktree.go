@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jvs-project/jvs/internal/diff"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// maxMismatchedPaths caps the paths listed in a WorktreeResult.
+const maxMismatchedPaths = 20
+
+// WorktreeResult reports whether a worktree payload matches the snapshot it
+// was cloned from.
+type WorktreeResult struct {
+	WorktreeName string           `json:"worktree"`
+	SnapshotID   model.SnapshotID `json:"snapshot_id"`
+	ExpectedHash model.HashValue  `json:"expected_hash"`
+	ActualHash   model.HashValue  `json:"actual_hash"`
+	Match        bool             `json:"match"`
+	// Mismatched lists differing paths ("added:", "removed:", "modified:"
+	// prefixed), truncated to a few entries. Empty with Match false means
+	// only file modes differ.
+	Mismatched []string `json:"mismatched,omitempty"`
+	// Degradations reported by the engine while cloning, if any.
+	Degradations []string `json:"degradations,omitempty"`
+}
+
+// VerifyWorktree compares the payload of worktree name against the payload
+// root hash recorded for snapshotID. It is meant to run right after a fork,
+// before the worktree is modified.
+func (v *Verifier) VerifyWorktree(name string, snapshotID model.SnapshotID) (*WorktreeResult, error) {
+	desc, err := snapshot.LoadDescriptor(v.repoRoot, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadPath := repo.WorktreePayloadPath(v.repoRoot, name)
+	actual, err := integrity.ComputePayloadRootHashContext(v.ctx, payloadPath, desc.HashAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("compute worktree payload hash: %w", err)
+	}
+
+	result := &WorktreeResult{
+		WorktreeName: name,
+		SnapshotID:   snapshotID,
+		ExpectedHash: desc.PayloadRootHash,
+		ActualHash:   actual,
+		Match:        actual == desc.PayloadRootHash,
+	}
+	if result.Match {
+		return result, nil
+	}
+
+	// Name the offending paths to make the report actionable
+	d, err := diff.NewDiffer(v.repoRoot).DiffPaths(repo.SnapshotPath(v.repoRoot, snapshotID), payloadPath)
+	if err != nil {
+		return nil, fmt.Errorf("compare worktree to snapshot: %w", err)
+	}
+	for _, group := range [][]*diff.Change{d.Added, d.Removed, d.Modified} {
+		for _, c := range group {
+			if len(result.Mismatched) == maxMismatchedPaths {
+				return result, nil
+			}
+			result.Mismatched = append(result.Mismatched, fmt.Sprintf("%s: %s", c.Type, filepath.ToSlash(c.Path)))
+		}
+	}
+	return result, nil
+}
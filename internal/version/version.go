@@ -0,0 +1,20 @@
+// Package version reports the version of the jvs build.
+package version
+
+import "runtime/debug"
+
+// version is set at release build time with
+// -ldflags "-X github.com/jvs-project/jvs/internal/version.version=v0.4.0".
+var version string
+
+// String returns the jvs version: the one set at build time, else the
+// module version recorded by 'go install', else "dev".
+func String() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/config"
+)
+
+// Completion events are delivered in the background, in the order Notify
+// queued them, so that an operation never waits for slow or unreachable
+// endpoints. At most QueueSize events wait at a time; Notify drops any
+// beyond that with a warning. A process about to exit calls Flush to give
+// the events it queued a bounded time to go out.
+const (
+	QueueSize = 256
+	// FlushTimeout is how long the CLI waits in Flush before exiting.
+	FlushTimeout = 30 * time.Second
+)
+
+type delivery struct {
+	ctx       context.Context
+	endpoints []Endpoint
+	event     *Event
+}
+
+var (
+	queueOnce sync.Once
+	queue     chan delivery
+
+	pendingMu sync.Mutex
+	pending   int
+	flushed   []chan struct{}
+)
+
+// Endpoints returns the endpoints of configured webhooks.
+func Endpoints(webhooks []config.Webhook) []Endpoint {
+	endpoints := make([]Endpoint, 0, len(webhooks))
+	for _, w := range webhooks {
+		endpoints = append(endpoints, Endpoint{URL: w.URL, Secret: w.Secret, Events: w.Events})
+	}
+	return endpoints
+}
+
+// Notify queues event for every webhook of the repository that wants it,
+// stamped with the repository ID and the author and correlation ID ctx
+// carries (see audit.WithAuthor and audit.WithCorrelationID). It is called
+// once an operation has completed and returns without waiting for
+// delivery; delivery failures, after retries, only print a warning.
+func Notify(ctx context.Context, repoRoot string, event *Event) {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s webhooks: %v\n", event.Type, err)
+		return
+	}
+	var endpoints []Endpoint
+	for _, ep := range Endpoints(cfg.Webhooks) {
+		if ep.Wants(event.Type) {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	if r, err := repo.Discover(repoRoot); err == nil {
+		event.RepoID = r.RepoID
+	}
+	event.Author = audit.ResolveAuthor(audit.Author(ctx))
+	event.CorrelationID = audit.ResolveCorrelationID(audit.CorrelationID(ctx))
+
+	queueOnce.Do(func() {
+		queue = make(chan delivery, QueueSize)
+		go deliver()
+	})
+	pendingMu.Lock()
+	pending++
+	pendingMu.Unlock()
+	// The operation's context may end as soon as it returns; delivery
+	// keeps its values but not its cancellation.
+	select {
+	case queue <- delivery{ctx: context.WithoutCancel(ctx), endpoints: endpoints, event: event}:
+	default:
+		fmt.Fprintf(os.Stderr, "warning: webhook: queue full, dropping %s event %s\n", event.Type, event.ID)
+		done()
+	}
+}
+
+// Flush waits until every event queued by Notify has been delivered, or
+// has failed, or until ctx is done.
+func Flush(ctx context.Context) error {
+	pendingMu.Lock()
+	if pending == 0 {
+		pendingMu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	flushed = append(flushed, ch)
+	pendingMu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("webhook: %w waiting for pending deliveries", ctx.Err())
+	}
+}
+
+func deliver() {
+	sender := NewSender()
+	for d := range queue {
+		for _, ep := range d.endpoints {
+			if err := sender.Send(d.ctx, ep, d.event); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: webhook: %v\n", err)
+			}
+		}
+		done()
+	}
+}
+
+// done marks a queued event as delivered or dropped.
+func done() {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	pending--
+	if pending == 0 {
+		for _, ch := range flushed {
+			close(ch)
+		}
+		flushed = nil
+	}
+}
@@ -0,0 +1,125 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/webhook"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestEndpoint_Wants(t *testing.T) {
+	ep := webhook.Endpoint{URL: "https://ci.example.com/hook"}
+	assert.True(t, ep.Wants(webhook.EventSnapshotCreated))
+	assert.True(t, ep.Wants(webhook.EventWorktreeStale))
+	assert.False(t, ep.Wants(webhook.EventRestoreCompleted))
+
+	ep.Events = []string{webhook.EventRestoreCompleted, webhook.EventGCCompleted}
+	assert.False(t, ep.Wants(webhook.EventSnapshotCreated))
+	assert.True(t, ep.Wants(webhook.EventRestoreCompleted))
+	assert.True(t, ep.Wants(webhook.EventGCCompleted))
+}
+
+func TestEvents_AreConfigurable(t *testing.T) {
+	for _, e := range []string{
+		webhook.EventSnapshotCreated, webhook.EventWorktreeStale,
+		webhook.EventSnapshotCompleted, webhook.EventRestoreCompleted, webhook.EventGCCompleted,
+	} {
+		assert.True(t, slices.Contains(config.WebhookEvents, e), e)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	r, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	defer config.InvalidateCache(dir)
+
+	var mu sync.Mutex
+	received := make(map[string][]webhook.Event)
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			var e webhook.Event
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&e))
+			assert.Equal(t, e.Type, req.Header.Get(webhook.EventHeader))
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = append(received[name], e)
+		}
+	}
+	restores := httptest.NewServer(handler("restores"))
+	defer restores.Close()
+	legacy := httptest.NewServer(handler("legacy"))
+	defer legacy.Close()
+
+	cfg, err := config.Load(dir)
+	require.NoError(t, err)
+	cfg.Webhooks = []config.Webhook{
+		{URL: restores.URL, Events: []string{webhook.EventRestoreCompleted}},
+		{URL: legacy.URL},
+	}
+	require.NoError(t, config.Save(dir, cfg))
+
+	ctx := audit.WithAuthor(audit.WithCorrelationID(context.Background(), "job-1"), "agent-7")
+	webhook.Notify(ctx, dir, webhook.NewRestoreEvent("main", "1700000000000-abcd1234", []string{"src"}))
+	webhook.Notify(ctx, dir, webhook.NewGCEvent("plan-1", nil))
+	require.NoError(t, webhook.Flush(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received["restores"], 1)
+	e := received["restores"][0]
+	assert.Equal(t, webhook.EventRestoreCompleted, e.Type)
+	assert.Equal(t, r.RepoID, e.RepoID)
+	assert.Equal(t, "agent-7", e.Author)
+	assert.Equal(t, "job-1", e.CorrelationID)
+	assert.Equal(t, "main", e.Worktree)
+	assert.Equal(t, model.SnapshotID("1700000000000-abcd1234"), e.SnapshotID)
+	assert.Equal(t, []string{"src"}, e.Paths)
+	assert.Empty(t, received["legacy"], "endpoints without events only get those of jvs serve")
+}
+
+func TestNotify_DoesNotWait(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	_, err := repo.Init(dir, "test")
+	require.NoError(t, err)
+	defer config.InvalidateCache(dir)
+
+	release := make(chan struct{})
+	var delivered atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		delivered.Add(1)
+	}))
+	defer srv.Close()
+	cfg, err := config.Load(dir)
+	require.NoError(t, err)
+	cfg.Webhooks = []config.Webhook{{URL: srv.URL, Events: []string{webhook.EventGCCompleted}}}
+	require.NoError(t, config.Save(dir, cfg))
+
+	webhook.Notify(context.Background(), dir, webhook.NewGCEvent("plan-1", nil))
+	webhook.Notify(context.Background(), dir, webhook.NewGCEvent("plan-2", nil))
+	assert.Zero(t, delivered.Load(), "Notify returns before the endpoint answers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, webhook.Flush(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, webhook.Flush(context.Background()))
+	assert.Equal(t, int32(2), delivered.Load())
+}
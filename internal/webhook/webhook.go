@@ -0,0 +1,241 @@
+// Package webhook delivers JSON event notifications to HTTP endpoints with
+// HMAC signing and retries.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/uuidutil"
+)
+
+// HTTP headers sent with every delivery.
+const (
+	SignatureHeader = "X-JVS-Signature"
+	EventHeader     = "X-JVS-Event"
+	DeliveryHeader  = "X-JVS-Delivery"
+)
+
+// Event types. 'jvs serve' sends snapshot.created, for snapshots annotated
+// ci.notify=true, and worktree.stale; the others are sent by the operations
+// themselves once they complete, whichever program runs them.
+const (
+	EventSnapshotCreated   = "snapshot.created"
+	EventWorktreeStale     = "worktree.stale"
+	EventSnapshotCompleted = "snapshot.completed"
+	EventRestoreCompleted  = "restore.completed"
+	EventGCCompleted       = "gc.completed"
+)
+
+// DefaultEvents are the events of endpoints without an event filter: those
+// of 'jvs serve', as before endpoints could choose.
+var DefaultEvents = []string{EventSnapshotCreated, EventWorktreeStale}
+
+// Default retry settings.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = 500 * time.Millisecond
+	maxDelay           = 30 * time.Second
+)
+
+// Endpoint is a webhook receiver.
+type Endpoint struct {
+	URL    string
+	Secret string   // empty disables signing
+	Events []string // event types to send; empty means DefaultEvents
+}
+
+// Wants reports whether events of type eventType are sent to ep.
+func (ep Endpoint) Wants(eventType string) bool {
+	if len(ep.Events) == 0 {
+		return slices.Contains(DefaultEvents, eventType)
+	}
+	return slices.Contains(ep.Events, eventType)
+}
+
+// Event is the JSON payload POSTed to endpoints.
+type Event struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Timestamp time.Time         `json:"timestamp"`
+	RepoID    string            `json:"repo_id,omitempty"`
+	Snapshot  *model.Descriptor `json:"snapshot,omitempty"`
+	Staleness *model.Staleness  `json:"staleness,omitempty"`
+
+	// Author and CorrelationID are those of the operation that completed,
+	// as in its audit records.
+	Author        string `json:"author,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// Restore events name the worktree restored, the snapshot and, for a
+	// restore of some paths only, the paths.
+	Worktree   string           `json:"worktree,omitempty"`
+	SnapshotID model.SnapshotID `json:"snapshot_id,omitempty"`
+	Paths      []string         `json:"paths,omitempty"`
+
+	// GC events name the plan run and the snapshots it deleted.
+	PlanID  string             `json:"plan_id,omitempty"`
+	Deleted []model.SnapshotID `json:"deleted,omitempty"`
+}
+
+// NewSnapshotEvent builds a snapshot.created event for desc.
+func NewSnapshotEvent(repoID string, desc *model.Descriptor) *Event {
+	return &Event{
+		ID:        uuidutil.NewV4(),
+		Type:      EventSnapshotCreated,
+		Timestamp: time.Now().UTC(),
+		RepoID:    repoID,
+		Snapshot:  desc,
+	}
+}
+
+// NewStaleEvent builds a worktree.stale event for st.
+func NewStaleEvent(repoID string, st *model.Staleness) *Event {
+	return &Event{
+		ID:        uuidutil.NewV4(),
+		Type:      EventWorktreeStale,
+		Timestamp: time.Now().UTC(),
+		RepoID:    repoID,
+		Staleness: st,
+	}
+}
+
+// NewSnapshotCompletedEvent builds a snapshot.completed event for desc.
+func NewSnapshotCompletedEvent(desc *model.Descriptor) *Event {
+	return &Event{
+		ID:        uuidutil.NewV4(),
+		Type:      EventSnapshotCompleted,
+		Timestamp: time.Now().UTC(),
+		Snapshot:  desc,
+	}
+}
+
+// NewRestoreEvent builds a restore.completed event for a restore of
+// worktreeName to snapshotID, of paths only if any are given.
+func NewRestoreEvent(worktreeName string, snapshotID model.SnapshotID, paths []string) *Event {
+	return &Event{
+		ID:         uuidutil.NewV4(),
+		Type:       EventRestoreCompleted,
+		Timestamp:  time.Now().UTC(),
+		Worktree:   worktreeName,
+		SnapshotID: snapshotID,
+		Paths:      paths,
+	}
+}
+
+// NewGCEvent builds a gc.completed event for a run of plan planID.
+func NewGCEvent(planID string, deleted []model.SnapshotID) *Event {
+	return &Event{
+		ID:        uuidutil.NewV4(),
+		Type:      EventGCCompleted,
+		Timestamp: time.Now().UTC(),
+		PlanID:    planID,
+		Deleted:   deleted,
+	}
+}
+
+// Sender POSTs events to endpoints. Transport errors, 429 and 5xx responses
+// are retried with exponential backoff; other responses are final.
+type Sender struct {
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// NewSender creates a Sender with default retry settings.
+func NewSender() *Sender {
+	return &Sender{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultBaseDelay,
+	}
+}
+
+// SetRetry overrides the number of attempts and the initial backoff delay,
+// which doubles after each failed attempt.
+func (s *Sender) SetRetry(maxAttempts int, baseDelay time.Duration) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	s.maxAttempts = maxAttempts
+	s.baseDelay = baseDelay
+}
+
+// Send delivers event to ep, retrying transient failures. Every attempt
+// carries the same delivery ID so receivers can deduplicate.
+func (s *Sender) Send(ctx context.Context, ep Endpoint, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	delay := s.baseDelay
+	var lastErr error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		retry, err := s.post(ctx, ep, event, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == s.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+	return fmt.Errorf("deliver %s to %s: %w", event.Type, ep.URL, lastErr)
+}
+
+// post makes one delivery attempt and reports whether a failure is retryable.
+func (s *Sender) post(ctx context.Context, ep Endpoint, event *Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID)
+	if ep.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(ep.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,78 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/webhook"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestSender_SignsPayload(t *testing.T) {
+	var gotSig, gotEvent string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(webhook.SignatureHeader)
+		gotEvent = r.Header.Get(webhook.EventHeader)
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	event := webhook.NewSnapshotEvent("repo-1", &model.Descriptor{SnapshotID: "1700000000000-abcd1234"})
+	err := webhook.NewSender().Send(context.Background(), webhook.Endpoint{URL: srv.URL, Secret: "s3cret"}, event)
+	require.NoError(t, err)
+
+	assert.Equal(t, webhook.EventSnapshotCreated, gotEvent)
+	assert.Equal(t, webhook.Sign("s3cret", body), gotSig)
+	var decoded webhook.Event
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, model.SnapshotID("1700000000000-abcd1234"), decoded.Snapshot.SnapshotID)
+	assert.Equal(t, "repo-1", decoded.RepoID)
+}
+
+func TestSender_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	deliveries := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries[r.Header.Get(webhook.DeliveryHeader)] = true
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sender := webhook.NewSender()
+	sender.SetRetry(5, time.Millisecond)
+	event := webhook.NewSnapshotEvent("", &model.Descriptor{})
+	require.NoError(t, sender.Send(context.Background(), webhook.Endpoint{URL: srv.URL}, event))
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Len(t, deliveries, 1, "retries reuse the delivery ID")
+}
+
+func TestSender_ClientErrorIsFinal(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	sender := webhook.NewSender()
+	sender.SetRetry(5, time.Millisecond)
+	err := sender.Send(context.Background(), webhook.Endpoint{URL: srv.URL}, webhook.NewSnapshotEvent("", &model.Descriptor{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Equal(t, int32(1), calls.Load())
+}
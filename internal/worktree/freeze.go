@@ -0,0 +1,161 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// frozenState is stored at .jvs/worktrees/<name>/frozen.json while a
+// worktree is frozen.
+type frozenState struct {
+	FrozenAt time.Time `json:"frozen_at"`
+	// Modes holds the permissions of the payload entries whose write bits
+	// were cleared, by slash-separated path relative to the payload.
+	Modes map[string]fs.FileMode `json:"modes"`
+}
+
+func (m *Manager) frozenStatePath(name string) string {
+	return filepath.Join(filepath.Dir(repo.WorktreeConfigPath(m.repoRoot, name)), "frozen.json")
+}
+
+// Freeze makes a worktree read-only: write permissions are removed from
+// every payload entry and restores are refused until Thaw. Snapshots still
+// work and record the permissions the payload had before it was frozen.
+func (m *Manager) Freeze(name string) (*model.WorktreeConfig, error) {
+	cfg, err := repo.LoadWorktreeConfig(m.repoRoot, name)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Frozen {
+		return nil, errclass.ErrWorktreeFrozen.WithMessagef("worktree %s is already frozen", name)
+	}
+
+	payloadPath := m.Path(name)
+	state := &frozenState{FrozenAt: time.Now().UTC(), Modes: make(map[string]fs.FileMode)}
+	err = filepath.WalkDir(payloadPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0222 == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(payloadPath, p)
+		if err != nil {
+			return err
+		}
+		state.Modes[filepath.ToSlash(rel)] = info.Mode().Perm()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan payload: %w", err)
+	}
+
+	// Record the modes before changing them, so an interrupted freeze can
+	// still be thawed
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal frozen state: %w", err)
+	}
+	if err := fsutil.AtomicWrite(m.frozenStatePath(name), data, 0644); err != nil {
+		return nil, fmt.Errorf("write frozen state: %w", err)
+	}
+	cfg.Frozen = true
+	if err := repo.WriteWorktreeConfig(m.repoRoot, name, cfg); err != nil {
+		return nil, fmt.Errorf("write config: %w", err)
+	}
+
+	for rel, mode := range state.Modes {
+		if err := os.Chmod(filepath.Join(payloadPath, filepath.FromSlash(rel)), mode&^0222); err != nil {
+			return nil, fmt.Errorf("make %s read-only: %w", rel, err)
+		}
+	}
+
+	m.auditLogger().Append(model.EventTypeWorktreeFreeze, name, cfg.HeadSnapshotID, map[string]any{
+		"entries": len(state.Modes),
+	})
+	return cfg, nil
+}
+
+// Thaw undoes Freeze, restoring the payload permissions recorded when the
+// worktree was frozen. Entries that were removed in the meantime are skipped.
+func (m *Manager) Thaw(name string) (*model.WorktreeConfig, error) {
+	cfg, err := repo.LoadWorktreeConfig(m.repoRoot, name)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if !cfg.Frozen {
+		return nil, fmt.Errorf("worktree %s is not frozen", name)
+	}
+	modes, err := m.FrozenModes(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ApplyModes(m.Path(name), modes); err != nil {
+		return nil, fmt.Errorf("restore permissions: %w", err)
+	}
+
+	cfg.Frozen = false
+	if err := repo.WriteWorktreeConfig(m.repoRoot, name, cfg); err != nil {
+		return nil, fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Remove(m.frozenStatePath(name)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove frozen state: %w", err)
+	}
+
+	m.auditLogger().Append(model.EventTypeWorktreeThaw, name, cfg.HeadSnapshotID, nil)
+	return cfg, nil
+}
+
+// FrozenModes returns the payload permissions a frozen worktree had before
+// it was frozen, by slash-separated relative path. It returns nil for a
+// worktree that is not frozen.
+func (m *Manager) FrozenModes(name string) (map[string]fs.FileMode, error) {
+	data, err := os.ReadFile(m.frozenStatePath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read frozen state: %w", err)
+	}
+	var state frozenState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse frozen state: %w", err)
+	}
+	return state.Modes, nil
+}
+
+// ApplyModes sets the permissions of the entries below dir listed in modes,
+// as returned by FrozenModes. Entries missing from dir are skipped.
+func ApplyModes(dir string, modes map[string]fs.FileMode) error {
+	for rel, mode := range modes {
+		if err := os.Chmod(filepath.Join(dir, filepath.FromSlash(rel)), mode); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("chmod %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// CheckNotFrozen returns errclass.ErrWorktreeFrozen if cfg is frozen.
+func CheckNotFrozen(cfg *model.WorktreeConfig) error {
+	if cfg != nil && cfg.Frozen {
+		return errclass.ErrWorktreeFrozen.WithMessagef("worktree %s is frozen; run 'jvs worktree thaw %s' first", cfg.Name, cfg.Name)
+	}
+	return nil
+}
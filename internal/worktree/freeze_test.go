@@ -0,0 +1,79 @@
+package worktree_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+func perm(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Mode().Perm()
+}
+
+func TestManager_FreezeThaw(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	payload := mgr.Path("main")
+	require.NoError(t, os.MkdirAll(filepath.Join(payload, "sub"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(payload, "a.txt"), []byte("a"), 0640))
+	require.NoError(t, os.WriteFile(filepath.Join(payload, "sub", "ro.txt"), []byte("ro"), 0444))
+	require.NoError(t, os.Chmod(filepath.Join(payload, "sub", "ro.txt"), 0444))
+	require.NoError(t, os.Chmod(filepath.Join(payload, "a.txt"), 0640))
+
+	cfg, err := mgr.Freeze("main")
+	require.NoError(t, err)
+	assert.True(t, cfg.Frozen)
+	assert.Equal(t, os.FileMode(0440), perm(t, filepath.Join(payload, "a.txt")))
+	assert.Equal(t, os.FileMode(0550), perm(t, filepath.Join(payload, "sub")))
+	assert.Equal(t, os.FileMode(0444), perm(t, filepath.Join(payload, "sub", "ro.txt")))
+
+	modes, err := mgr.FrozenModes("main")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), modes["a.txt"])
+	assert.NotContains(t, modes, "sub/ro.txt", "already read-only entries are not recorded")
+
+	_, err = mgr.Freeze("main")
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeFrozen))
+
+	cfg, err = mgr.Thaw("main")
+	require.NoError(t, err)
+	assert.False(t, cfg.Frozen)
+	assert.Equal(t, os.FileMode(0640), perm(t, filepath.Join(payload, "a.txt")))
+	assert.Equal(t, os.FileMode(0750), perm(t, filepath.Join(payload, "sub")))
+	assert.Equal(t, os.FileMode(0444), perm(t, filepath.Join(payload, "sub", "ro.txt")))
+
+	modes, err = mgr.FrozenModes("main")
+	require.NoError(t, err)
+	assert.Nil(t, modes)
+
+	_, err = mgr.Thaw("main")
+	assert.ErrorContains(t, err, "not frozen")
+}
+
+func TestManager_Remove_Frozen(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	_, err := mgr.Create("investigate", nil)
+	require.NoError(t, err)
+	_, err = mgr.Freeze("investigate")
+	require.NoError(t, err)
+
+	err = mgr.Remove("investigate")
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeFrozen))
+	_, err = mgr.RemoveRetaining("investigate", "archived/x")
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeFrozen))
+
+	_, err = mgr.Thaw("investigate")
+	require.NoError(t, err)
+	require.NoError(t, mgr.Remove("investigate"))
+}
@@ -0,0 +1,110 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/leasefile"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func (m *Manager) lockFile(name string) *leasefile.File[model.WorktreeLock, *model.WorktreeLock] {
+	path := filepath.Join(filepath.Dir(repo.WorktreeConfigPath(m.repoRoot, name)), "lock.json")
+	return leasefile.New(path, "lock", func(l *model.WorktreeLock) string { return l.Owner })
+}
+
+// Lock takes an advisory lock on a worktree for owner, held for ttl or, if
+// ttl is zero, until Unlock. While it is held, snapshots and restores by
+// other owners are refused, as are rename and remove. Locking a worktree
+// already locked by owner renews the lock; a lock held by another owner
+// fails with errclass.ErrWorktreeLocked.
+func (m *Manager) Lock(name, owner string, ttl time.Duration) (*model.WorktreeLock, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("lock owner is required")
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("lock ttl must be non-negative")
+	}
+	if _, err := m.Get(name); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	host, _ := os.Hostname()
+	var l *model.WorktreeLock
+	existing, err := m.lockFile(name).Acquire(owner, now, func(prev *model.WorktreeLock) *model.WorktreeLock {
+		l = &model.WorktreeLock{WorktreeName: name, Owner: owner, Host: host, LockedAt: now}
+		if prev != nil {
+			l.LockedAt = prev.LockedAt
+		}
+		if ttl > 0 {
+			expiresAt := now.Add(ttl)
+			l.ExpiresAt = &expiresAt
+		}
+		return l
+	})
+	if errors.Is(err, leasefile.ErrHeld) {
+		return nil, lockedError(existing)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		details := map[string]any{"owner": owner, "host": host}
+		if l.ExpiresAt != nil {
+			details["expires_at"] = *l.ExpiresAt
+		}
+		m.auditLogger().Append(model.EventTypeWorktreeLock, name, "", details)
+	}
+	return l, nil
+}
+
+// Unlock releases the lock owner holds on a worktree. Unlocking a worktree
+// that is not locked, or whose lock has expired, is not an error; a lock held
+// by another owner fails with errclass.ErrWorktreeLocked.
+func (m *Manager) Unlock(name, owner string) error {
+	existing, err := m.lockFile(name).Release(owner, time.Now())
+	if errors.Is(err, leasefile.ErrHeld) {
+		return lockedError(existing)
+	}
+	if err != nil || existing == nil {
+		return err
+	}
+	m.auditLogger().Append(model.EventTypeWorktreeUnlock, name, "", map[string]any{
+		"owner": owner,
+	})
+	return nil
+}
+
+// LockInfo returns the active lock on a worktree, or nil if it is not locked
+// or its lock has expired.
+func (m *Manager) LockInfo(name string) (*model.WorktreeLock, error) {
+	return m.lockFile(name).Read(time.Now())
+}
+
+// CheckUnlocked returns errclass.ErrWorktreeLocked if the worktree has an
+// active lock held by anyone but owner. An empty owner matches no lock.
+func (m *Manager) CheckUnlocked(name, owner string) error {
+	l, err := m.LockInfo(name)
+	if err != nil {
+		return err
+	}
+	if l != nil && (owner == "" || l.Owner != owner) {
+		return lockedError(l)
+	}
+	return nil
+}
+
+func lockedError(l *model.WorktreeLock) error {
+	where := l.Owner
+	if l.Host != "" {
+		where += " on host " + l.Host
+	}
+	return errclass.ErrWorktreeLocked.WithMessagef("worktree %s is locked by %s since %s; run 'jvs worktree unlock %s' first",
+		l.WorktreeName, where, l.LockedAt.Local().Format(time.DateTime), l.WorktreeName)
+}
@@ -0,0 +1,105 @@
+package worktree_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+func TestManager_LockUnlock(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	l, err := mgr.Lock("main", "alice", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", l.Owner)
+	assert.Nil(t, l.ExpiresAt)
+
+	// The owner renews; anyone else is refused
+	renewed, err := mgr.Lock("main", "alice", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, l.LockedAt, renewed.LockedAt)
+	require.NotNil(t, renewed.ExpiresAt)
+	_, err = mgr.Lock("main", "bob", 0)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeLocked))
+	assert.True(t, errors.Is(mgr.Unlock("main", "bob"), errclass.ErrWorktreeLocked))
+
+	require.NoError(t, mgr.CheckUnlocked("main", "alice"))
+	assert.True(t, errors.Is(mgr.CheckUnlocked("main", "bob"), errclass.ErrWorktreeLocked))
+
+	require.NoError(t, mgr.Unlock("main", "alice"))
+	got, err := mgr.LockInfo("main")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	require.NoError(t, mgr.Unlock("main", "alice"), "unlocking an unlocked worktree succeeds")
+	require.NoError(t, mgr.CheckUnlocked("main", "bob"))
+
+	_, err = mgr.Lock("missing", "alice", 0)
+	assert.Error(t, err)
+}
+
+func TestManager_LockExpires(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	_, err := mgr.Lock("main", "alice", time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	l, err := mgr.LockInfo("main")
+	require.NoError(t, err)
+	assert.Nil(t, l)
+	_, err = mgr.Lock("main", "bob", 0)
+	require.NoError(t, err)
+}
+
+func TestManager_Remove_Locked(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	_, err := mgr.Create("investigate", nil)
+	require.NoError(t, err)
+	_, err = mgr.Lock("investigate", "alice", 0)
+	require.NoError(t, err)
+
+	assert.True(t, errors.Is(mgr.Remove("investigate"), errclass.ErrWorktreeLocked))
+	assert.True(t, errors.Is(mgr.Rename("investigate", "other"), errclass.ErrWorktreeLocked))
+	_, err = mgr.RemoveRetaining("investigate", "archived/x")
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeLocked))
+
+	require.NoError(t, mgr.Unlock("investigate", "alice"))
+	require.NoError(t, mgr.Remove("investigate"))
+}
+
+func TestManager_LockConcurrent(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = mgr.Lock("main", fmt.Sprintf("owner-%d", i), 0)
+		}()
+	}
+	wg.Wait()
+
+	locked := 0
+	for _, err := range errs {
+		if err == nil {
+			locked++
+			continue
+		}
+		assert.True(t, errors.Is(err, errclass.ErrWorktreeLocked), "%v", err)
+	}
+	assert.Equal(t, 1, locked, "exactly one owner takes the lock")
+}
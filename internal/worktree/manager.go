@@ -6,17 +6,27 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/compression"
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/encryption"
 	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/jvs-project/jvs/pkg/pathutil"
 )
 
 // Manager handles worktree CRUD operations.
 type Manager struct {
-	repoRoot string
+	repoRoot      string
+	quota         model.Quota
+	suggest       func() ([]model.SnapshotUsage, error)
+	correlationID string
+	author        string
 }
 
 // NewManager creates a new worktree manager.
@@ -24,6 +34,28 @@ func NewManager(repoRoot string) *Manager {
 	return &Manager{repoRoot: repoRoot}
 }
 
+// SetCorrelationID sets the correlation ID recorded in the audit records
+// of the manager's operations. Empty, the default, uses the one set through
+// audit.CorrelationEnvVar, if any.
+func (m *Manager) SetCorrelationID(id string) {
+	m.correlationID = id
+}
+
+// SetAuthor sets who the audit records of the manager's operations name as
+// their author. Empty, the default, uses identity.Author.
+func (m *Manager) SetAuthor(author string) {
+	m.author = author
+}
+
+// auditLogger returns an appender for the repository's audit log stamping
+// records as set by SetCorrelationID and SetAuthor.
+func (m *Manager) auditLogger() *audit.FileAppender {
+	a := audit.NewFileAppender(audit.LogPath(m.repoRoot))
+	a.SetCorrelationID(m.correlationID)
+	a.SetAuthor(m.author)
+	return a
+}
+
 // Create creates a new worktree with the given name.
 func (m *Manager) Create(name string, baseSnapshotID *model.SnapshotID) (*model.WorktreeConfig, error) {
 	if err := pathutil.ValidateName(name); err != nil {
@@ -67,6 +99,7 @@ func (m *Manager) Create(name string, baseSnapshotID *model.SnapshotID) (*model.
 
 // CreateFromSnapshot creates a new worktree with content cloned from a snapshot.
 // This is similar to Fork but uses "create" semantics (for the --from flag).
+// Quotas set with SetQuota apply as for Fork.
 func (m *Manager) CreateFromSnapshot(name string, snapshotID model.SnapshotID, cloneFunc func(src, dst string) error) (*model.WorktreeConfig, error) {
 	if err := pathutil.ValidateName(name); err != nil {
 		return nil, err
@@ -78,6 +111,15 @@ func (m *Manager) CreateFromSnapshot(name string, snapshotID model.SnapshotID, c
 		return nil, fmt.Errorf("worktree %s already exists", name)
 	}
 
+	// Never clone an unpublished snapshot
+	if err := repo.CheckSnapshotReady(m.repoRoot, snapshotID); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkQuota(snapshotID, name); err != nil {
+		return nil, err
+	}
+
 	// Create payload directory
 	payloadPath := repo.WorktreePayloadPath(m.repoRoot, name)
 	if err := os.MkdirAll(payloadPath, 0755); err != nil {
@@ -85,11 +127,19 @@ func (m *Manager) CreateFromSnapshot(name string, snapshotID model.SnapshotID, c
 	}
 
 	// Clone snapshot content to worktree
-	snapshotDir := filepath.Join(m.repoRoot, ".jvs", "snapshots", string(snapshotID))
+	snapshotDir := repo.SnapshotPath(m.repoRoot, snapshotID)
 	if err := cloneFunc(snapshotDir, payloadPath); err != nil {
 		os.RemoveAll(payloadPath)
 		return nil, fmt.Errorf("clone snapshot content: %w", err)
 	}
+	if err := repo.RemoveReadyMarkers(payloadPath); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, err
+	}
+	if err := m.decodePayload(snapshotID, payloadPath); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, err
+	}
 
 	// Create config directory
 	configDir := filepath.Dir(configPath)
@@ -113,7 +163,98 @@ func (m *Manager) CreateFromSnapshot(name string, snapshotID model.SnapshotID, c
 	return cfg, nil
 }
 
-// List returns all worktrees.
+// decodePayload decrypts and decompresses dir, a clone of the stored
+// payload of a snapshot, as restores do, so the worktree holds the
+// snapshot's plain files.
+func (m *Manager) decodePayload(snapshotID model.SnapshotID, dir string) error {
+	store, err := descstore.Open(m.repoRoot)
+	if err != nil {
+		return err
+	}
+	desc, err := store.Get(snapshotID)
+	store.Close()
+	if err != nil {
+		return err
+	}
+	if _, err := encryption.DecryptSnapshot(m.repoRoot, desc, dir); err != nil {
+		return err
+	}
+	if desc.Compression != nil {
+		if _, err := compression.DecompressDirType(dir, compression.CompressionType(desc.Compression.Type)); err != nil {
+			return fmt.Errorf("decompress snapshot %s: %w", snapshotID, err)
+		}
+	}
+	return nil
+}
+
+// CreateFromDir creates a new worktree with content cloned from an arbitrary
+// directory outside the repository. The worktree has no snapshots yet; callers
+// seed it with a baseline snapshot and Remove it if that fails.
+func (m *Manager) CreateFromDir(name, srcDir string, cloneFunc func(src, dst string) error) (*model.WorktreeConfig, error) {
+	if err := pathutil.ValidateName(name); err != nil {
+		return nil, err
+	}
+	if err := m.validateSourceDir(srcDir); err != nil {
+		return nil, err
+	}
+
+	// Check if already exists
+	configPath := repo.WorktreeConfigPath(m.repoRoot, name)
+	if _, err := os.Stat(configPath); err == nil {
+		return nil, fmt.Errorf("worktree %s already exists", name)
+	}
+
+	// Clone into the payload directory, which must not exist yet
+	payloadPath := repo.WorktreePayloadPath(m.repoRoot, name)
+	if _, err := os.Stat(payloadPath); err == nil {
+		return nil, fmt.Errorf("payload directory %s already exists", payloadPath)
+	}
+	if err := os.MkdirAll(payloadPath, 0755); err != nil {
+		return nil, fmt.Errorf("create payload directory: %w", err)
+	}
+	if err := cloneFunc(srcDir, payloadPath); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, fmt.Errorf("clone source directory: %w", err)
+	}
+
+	// Create config directory
+	configDir := filepath.Dir(configPath)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, fmt.Errorf("create config directory: %w", err)
+	}
+
+	cfg := &model.WorktreeConfig{
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := repo.WriteWorktreeConfig(m.repoRoot, name, cfg); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, fmt.Errorf("write config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// validateSourceDir checks that srcDir is a directory that neither contains
+// nor lies within the repository.
+func (m *Manager) validateSourceDir(srcDir string) error {
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return fmt.Errorf("source directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("source %s is not a directory", srcDir)
+	}
+
+	// ValidatePathSafety succeeds when its second argument lies within the first
+	if pathutil.ValidatePathSafety(m.repoRoot, srcDir) == nil || pathutil.ValidatePathSafety(srcDir, m.repoRoot) == nil {
+		return fmt.Errorf("source directory %s overlaps the repository", srcDir)
+	}
+	return nil
+}
+
+// List returns all worktrees, sorted by name in byte order.
 func (m *Manager) List() ([]*model.WorktreeConfig, error) {
 	worktreesDir := filepath.Join(m.repoRoot, ".jvs", "worktrees")
 	entries, err := os.ReadDir(worktreesDir)
@@ -133,6 +274,9 @@ func (m *Manager) List() ([]*model.WorktreeConfig, error) {
 		}
 		configs = append(configs, cfg)
 	}
+	slices.SortFunc(configs, func(a, b *model.WorktreeConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
 	return configs, nil
 }
 
@@ -152,6 +296,16 @@ func (m *Manager) Rename(oldName, newName string) error {
 		return err
 	}
 
+	guard, err := repolock.NewManager(m.repoRoot).GuardWorktree(oldName, "worktree rename")
+	if err != nil {
+		return err
+	}
+	defer guard.Release()
+
+	if err := m.CheckUnlocked(oldName, ""); err != nil {
+		return err
+	}
+
 	// Check if new name exists
 	newConfigPath := repo.WorktreeConfigPath(m.repoRoot, newName)
 	if _, err := os.Stat(newConfigPath); err == nil {
@@ -180,10 +334,20 @@ func (m *Manager) Rename(oldName, newName string) error {
 		return fmt.Errorf("load config after rename: %w", err)
 	}
 	cfg.Name = newName
-	return repo.WriteWorktreeConfig(m.repoRoot, newName, cfg)
+	if err := repo.WriteWorktreeConfig(m.repoRoot, newName, cfg); err != nil {
+		return err
+	}
+
+	// Retained roots move with the worktree
+	if err := os.Rename(m.bucketPath(oldName), m.bucketPath(newName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rename retention bucket: %w", err)
+	}
+	return nil
 }
 
-// Remove deletes a worktree. Fails if the worktree is main.
+// Remove deletes a worktree. Fails if the worktree is main. Snapshots it
+// protected from GC, including roots retained in it by RemoveRetaining, lose
+// that protection.
 func (m *Manager) Remove(name string) error {
 	if name == "main" {
 		return errors.New("cannot remove main worktree")
@@ -191,6 +355,21 @@ func (m *Manager) Remove(name string) error {
 
 	// Get config before removal for audit logging
 	cfg, _ := repo.LoadWorktreeConfig(m.repoRoot, name)
+	if err := CheckNotFrozen(cfg); err != nil {
+		return err
+	}
+	if err := m.CheckUnlocked(name, ""); err != nil {
+		return err
+	}
+	return m.remove(name, cfg, nil)
+}
+
+func (m *Manager) remove(name string, cfg *model.WorktreeConfig, details map[string]any) error {
+	guard, err := repolock.NewManager(m.repoRoot).GuardWorktree(name, "worktree remove")
+	if err != nil {
+		return err
+	}
+	defer guard.Release()
 
 	// Remove payload directory
 	payloadPath := repo.WorktreePayloadPath(m.repoRoot, name)
@@ -204,13 +383,19 @@ func (m *Manager) Remove(name string) error {
 		return fmt.Errorf("remove config: %w", err)
 	}
 
+	// Drop roots this worktree carried; a later worktree of the same name
+	// must not inherit them
+	if err := os.Remove(m.bucketPath(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove retention bucket: %w", err)
+	}
+
 	// Audit log the removal
 	if cfg != nil {
-		auditPath := filepath.Join(m.repoRoot, ".jvs", "audit", "audit.jsonl")
-		auditLogger := audit.NewFileAppender(auditPath)
-		auditLogger.Append(model.EventTypeWorktreeRemove, name, "", map[string]any{
-			"head_snapshot_id": string(cfg.HeadSnapshotID),
-		})
+		if details == nil {
+			details = make(map[string]any)
+		}
+		details["head_snapshot_id"] = string(cfg.HeadSnapshotID)
+		m.auditLogger().Append(model.EventTypeWorktreeRemove, name, "", details)
 	}
 
 	return nil
@@ -239,8 +424,36 @@ func (m *Manager) SetLatest(name string, snapshotID model.SnapshotID) error {
 	return repo.WriteWorktreeConfig(m.repoRoot, name, cfg)
 }
 
-// Fork creates a new worktree from a snapshot with content cloned.
+// Promote makes the current detached head the new latest snapshot of a worktree.
+// Newer snapshots in the previous lineage are kept but are no longer the latest.
+// The payload is not modified.
+func (m *Manager) Promote(name string) (*model.WorktreeConfig, error) {
+	cfg, err := repo.LoadWorktreeConfig(m.repoRoot, name)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if !cfg.IsDetached() {
+		return nil, fmt.Errorf("worktree %s is not in detached state", name)
+	}
+
+	previous := cfg.LatestSnapshotID
+	cfg.LatestSnapshotID = cfg.HeadSnapshotID
+	if err := repo.WriteWorktreeConfig(m.repoRoot, name, cfg); err != nil {
+		return nil, fmt.Errorf("write config: %w", err)
+	}
+
+	m.auditLogger().Append(model.EventTypeWorktreePromote, name, cfg.HeadSnapshotID, map[string]any{
+		"previous_latest_snapshot_id": string(previous),
+	})
+
+	return cfg, nil
+}
+
+// Fork creates a new worktree from a snapshot with content cloned, then
+// decrypted and decompressed if the snapshot is stored so.
 // The new worktree will be at HEAD state (can create snapshots immediately).
+// With SetQuota, a fork that would exceed a quota fails with a *QuotaError
+// before anything is copied.
 func (m *Manager) Fork(snapshotID model.SnapshotID, name string, cloneFunc func(src, dst string) error) (*model.WorktreeConfig, error) {
 	if err := pathutil.ValidateName(name); err != nil {
 		return nil, err
@@ -252,6 +465,15 @@ func (m *Manager) Fork(snapshotID model.SnapshotID, name string, cloneFunc func(
 		return nil, fmt.Errorf("worktree %s already exists", name)
 	}
 
+	// Never clone an unpublished snapshot
+	if err := repo.CheckSnapshotReady(m.repoRoot, snapshotID); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkQuota(snapshotID, name); err != nil {
+		return nil, err
+	}
+
 	// Create payload directory
 	payloadPath := repo.WorktreePayloadPath(m.repoRoot, name)
 	if err := os.MkdirAll(payloadPath, 0755); err != nil {
@@ -259,11 +481,19 @@ func (m *Manager) Fork(snapshotID model.SnapshotID, name string, cloneFunc func(
 	}
 
 	// Clone snapshot content to worktree
-	snapshotDir := filepath.Join(m.repoRoot, ".jvs", "snapshots", string(snapshotID))
+	snapshotDir := repo.SnapshotPath(m.repoRoot, snapshotID)
 	if err := cloneFunc(snapshotDir, payloadPath); err != nil {
 		os.RemoveAll(payloadPath)
 		return nil, fmt.Errorf("clone snapshot content: %w", err)
 	}
+	if err := repo.RemoveReadyMarkers(payloadPath); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, err
+	}
+	if err := m.decodePayload(snapshotID, payloadPath); err != nil {
+		os.RemoveAll(payloadPath)
+		return nil, err
+	}
 
 	// Create config directory
 	configDir := filepath.Dir(configPath)
@@ -5,8 +5,14 @@ import (
 	"path/filepath"
 	"testing"
 
+	"filippo.io/age"
+
+	"github.com/jvs-project/jvs/internal/descstore"
+	"github.com/jvs-project/jvs/internal/engine"
 	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
 	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
@@ -17,9 +23,26 @@ func setupTestRepo(t *testing.T) string {
 	dir := t.TempDir()
 	_, err := repo.Init(dir, "test")
 	require.NoError(t, err)
+	// Fork and CreateFromSnapshot only clone READY snapshots
+	for _, id := range []model.SnapshotID{"1708300800000-a3f7c1b2", "1708300900000-b4d8e2c3", "snap-id"} {
+		publishSnapshot(t, dir, id)
+	}
 	return dir
 }
 
+// publishSnapshot creates an empty snapshot directory with a .READY marker
+// and a descriptor.
+func publishSnapshot(t *testing.T, repoPath string, id model.SnapshotID) {
+	t.Helper()
+	dir := repo.SnapshotPath(repoPath, id)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".READY"), []byte("{}"), 0644))
+	store, err := descstore.Open(repoPath)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Put(&model.Descriptor{SnapshotID: id, WorktreeName: "main"}))
+}
+
 func TestManager_Create(t *testing.T) {
 	repoPath := setupTestRepo(t)
 	mgr := worktree.NewManager(repoPath)
@@ -43,6 +66,62 @@ func TestManager_Create_InvalidName(t *testing.T) {
 	require.ErrorIs(t, err, errclass.ErrNameInvalid)
 }
 
+func TestManager_CreateFromDir(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "data.csv"), []byte("a,b"), 0644))
+
+	var cloned [2]string
+	cfg, err := mgr.CreateFromDir("data", src, func(from, to string) error {
+		cloned = [2]string{from, to}
+		return os.WriteFile(filepath.Join(to, "data.csv"), []byte("a,b"), 0644)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "data", cfg.Name)
+	assert.Empty(t, cfg.HeadSnapshotID)
+	assert.Equal(t, src, cloned[0])
+	assert.Equal(t, mgr.Path("data"), cloned[1])
+	assert.FileExists(t, filepath.Join(mgr.Path("data"), "data.csv"))
+}
+
+func TestManager_CreateFromDir_Rejected(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	noClone := func(string, string) error {
+		t.Fatal("clone must not run")
+		return nil
+	}
+
+	_, err := mgr.CreateFromDir("a", filepath.Join(t.TempDir(), "missing"), noClone)
+	assert.Error(t, err)
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	_, err = mgr.CreateFromDir("b", file, noClone)
+	assert.ErrorContains(t, err, "not a directory")
+
+	// Inside the repository, or containing it
+	_, err = mgr.CreateFromDir("c", filepath.Join(repoPath, "main"), noClone)
+	assert.ErrorContains(t, err, "overlaps the repository")
+	_, err = mgr.CreateFromDir("d", filepath.Dir(repoPath), noClone)
+	assert.ErrorContains(t, err, "overlaps the repository")
+}
+
+func TestManager_CreateFromDir_CloneFailure(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	_, err := mgr.CreateFromDir("data", t.TempDir(), func(string, string) error {
+		return os.ErrPermission
+	})
+	require.Error(t, err)
+	assert.NoDirExists(t, mgr.Path("data"))
+	_, err = mgr.Get("data")
+	assert.Error(t, err)
+}
+
 func TestManager_List(t *testing.T) {
 	repoPath := setupTestRepo(t)
 	mgr := worktree.NewManager(repoPath)
@@ -63,6 +142,24 @@ func TestManager_List(t *testing.T) {
 	assert.True(t, names["feature2"])
 }
 
+func TestManager_List_SortedByName(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	for _, name := range []string{"zeta", "Beta", "alpha", "beta-2"} {
+		_, err := mgr.Create(name, nil)
+		require.NoError(t, err)
+	}
+
+	list, err := mgr.List()
+	require.NoError(t, err)
+	var names []string
+	for _, cfg := range list {
+		names = append(names, cfg.Name)
+	}
+	// Byte order: upper case before lower case, whatever the locale
+	assert.Equal(t, []string{"Beta", "alpha", "beta-2", "main", "zeta"}, names)
+}
+
 func TestManager_Path(t *testing.T) {
 	repoPath := setupTestRepo(t)
 	mgr := worktree.NewManager(repoPath)
@@ -250,6 +347,112 @@ func TestManager_Fork(t *testing.T) {
 	assert.Equal(t, model.SnapshotID("1708300800000-a3f7c1b2"), cfg.BaseSnapshotID)
 }
 
+func TestManager_Fork_EncryptedSnapshot(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	cfg := config.Default()
+	cfg.Encryption = &config.Encryption{Recipients: []string{identity.Recipient().String()}}
+	require.NoError(t, config.Save(repoPath, cfg))
+	defer config.InvalidateCache(repoPath)
+	t.Setenv("JVS_AGE_IDENTITY", identity.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main", "a.txt"), []byte("secret"), 0644))
+	desc, err := snapshot.NewCreator(repoPath, model.EngineCopy).Create("main", "encrypted", nil)
+	require.NoError(t, err)
+	require.NotNil(t, desc.Encryption)
+
+	mgr := worktree.NewManager(repoPath)
+	eng := engine.NewEngine(model.EngineCopy)
+	clone := func(src, dst string) error {
+		_, err := eng.Clone(src, dst)
+		return err
+	}
+	_, err = mgr.Fork(desc.SnapshotID, "forked", clone)
+	require.NoError(t, err)
+	_, err = mgr.CreateFromSnapshot("created", desc.SnapshotID, clone)
+	require.NoError(t, err)
+	for _, name := range []string{"forked", "created"} {
+		data, err := os.ReadFile(filepath.Join(mgr.Path(name), "a.txt"))
+		require.NoError(t, err, name)
+		assert.Equal(t, "secret", string(data))
+		assert.NoFileExists(t, filepath.Join(mgr.Path(name), "a.txt.age"))
+	}
+
+	// Without an identity nothing is left behind
+	t.Setenv("JVS_AGE_IDENTITY", "")
+	t.Setenv("JVS_AGE_IDENTITY_FILE", "")
+	_, err = mgr.Fork(desc.SnapshotID, "locked", clone)
+	require.Error(t, err)
+	assert.NoDirExists(t, mgr.Path("locked"))
+}
+
+func TestManager_Fork_SnapshotNotReady(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	// Crash leftover: the payload exists but .READY was never written
+	id := model.SnapshotID("1708301000000-c5e9f3d4")
+	require.NoError(t, os.MkdirAll(repo.SnapshotPath(repoPath, id), 0755))
+
+	cloned := false
+	cloneFunc := func(src, dst string) error { cloned = true; return nil }
+
+	_, err := mgr.Fork(id, "forked", cloneFunc)
+	require.ErrorIs(t, err, errclass.ErrSnapshotNotReady)
+	_, err = mgr.CreateFromSnapshot("created", id, cloneFunc)
+	require.ErrorIs(t, err, errclass.ErrSnapshotNotReady)
+
+	assert.False(t, cloned)
+	assert.NoDirExists(t, filepath.Join(repoPath, "worktrees", "forked"))
+	assert.NoDirExists(t, filepath.Join(repoPath, "worktrees", "created"))
+}
+
+func TestManager_Fork_Quota(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	id := model.SnapshotID("1708301000000-c5e9f3d4")
+	publishSnapshot(t, repoPath, id)
+	require.NoError(t, os.WriteFile(filepath.Join(repo.SnapshotPath(repoPath, id), "data.bin"), make([]byte, 4096), 0644))
+	cloneFunc := func(src, dst string) error {
+		return os.WriteFile(filepath.Join(dst, "data.bin"), make([]byte, 4096), 0644)
+	}
+
+	t.Run("repository", func(t *testing.T) {
+		mgr := worktree.NewManager(repoPath)
+		suggested := []model.SnapshotUsage{{SnapshotID: "1708300800000-a3f7c1b2", Bytes: 1 << 20}}
+		mgr.SetQuota(model.Quota{MaxBytes: 6000}, func() ([]model.SnapshotUsage, error) { return suggested, nil })
+
+		_, err := mgr.Fork(id, "big", cloneFunc)
+		require.ErrorIs(t, err, errclass.ErrQuotaExceeded)
+		var qerr *worktree.QuotaError
+		require.ErrorAs(t, err, &qerr)
+		assert.Equal(t, "", qerr.Namespace)
+		assert.GreaterOrEqual(t, qerr.Needed, int64(4096))
+		assert.Equal(t, suggested, qerr.Candidates)
+		assert.Contains(t, err.Error(), suggested[0].SnapshotID.ShortID())
+		assert.NoDirExists(t, mgr.Path("big"))
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		mgr := worktree.NewManager(repoPath)
+		mgr.SetQuota(model.Quota{Namespaces: []model.NamespaceQuota{{Prefix: "agent-", MaxBytes: 6000}}}, nil)
+
+		_, err := mgr.Fork(id, "agent-1", cloneFunc)
+		require.NoError(t, err)
+		// Outside the namespace
+		_, err = mgr.Fork(id, "other", cloneFunc)
+		require.NoError(t, err)
+
+		_, err = mgr.Fork(id, "agent-2", cloneFunc)
+		var qerr *worktree.QuotaError
+		require.ErrorAs(t, err, &qerr)
+		assert.Equal(t, "agent-", qerr.Namespace)
+		assert.Equal(t, int64(4096), qerr.Used)
+		assert.Equal(t, int64(6000-4096), qerr.Available())
+		assert.NoDirExists(t, mgr.Path("agent-2"))
+	})
+}
+
 func TestManager_Fork_InvalidName(t *testing.T) {
 	repoPath := setupTestRepo(t)
 	mgr := worktree.NewManager(repoPath)
@@ -661,3 +864,31 @@ func TestManager_Rename_PayloadRenameError(t *testing.T) {
 	// Cleanup
 	os.Remove(newPayloadPath)
 }
+
+func TestManager_Promote(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	require.NoError(t, mgr.SetLatest("main", "1708300900000-b4d8e2c3"))
+	require.NoError(t, mgr.UpdateHead("main", "1708300800000-a3f7c1b2"))
+
+	cfg, err := mgr.Promote("main")
+	require.NoError(t, err)
+	assert.False(t, cfg.IsDetached())
+	assert.Equal(t, model.SnapshotID("1708300800000-a3f7c1b2"), cfg.LatestSnapshotID)
+
+	cfg, err = mgr.Get("main")
+	require.NoError(t, err)
+	assert.Equal(t, model.SnapshotID("1708300800000-a3f7c1b2"), cfg.LatestSnapshotID)
+}
+
+func TestManager_Promote_NotDetached(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+
+	require.NoError(t, mgr.SetLatest("main", "1708300800000-a3f7c1b2"))
+
+	_, err := mgr.Promote("main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not in detached state")
+}
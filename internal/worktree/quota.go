@@ -0,0 +1,127 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jvs-project/jvs/internal/format"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// MaxQuotaCandidates is how many GC candidates callers of SetQuota should
+// have a QuotaError suggest.
+const MaxQuotaCandidates = 5
+
+// QuotaError is returned by Fork when the new worktree would not fit in a
+// quota. It matches errclass.ErrQuotaExceeded with errors.Is.
+type QuotaError struct {
+	// Namespace is the prefix of the exceeded namespace quota, or "" for
+	// the repository quota.
+	Namespace string
+	Limit     int64
+	Used      int64
+	// Needed is the size of the snapshot payload the fork would copy.
+	Needed int64
+	// Candidates are the largest snapshots GC may delete, if known.
+	Candidates []model.SnapshotUsage
+}
+
+// Available returns the space left in the quota.
+func (e *QuotaError) Available() int64 {
+	return max(e.Limit-e.Used, 0)
+}
+
+func (e *QuotaError) Error() string {
+	scope := "repository quota"
+	if e.Namespace != "" {
+		scope = fmt.Sprintf("namespace quota %q", e.Namespace)
+	}
+	msg := fmt.Sprintf("%s: fork needs %s but %s has %s available (limit %s, used %s)",
+		errclass.ErrQuotaExceeded.Code, format.Bytes(e.Needed), scope,
+		format.Bytes(e.Available()), format.Bytes(e.Limit), format.Bytes(e.Used))
+	if len(e.Candidates) > 0 {
+		parts := make([]string, len(e.Candidates))
+		for i, c := range e.Candidates {
+			parts[i] = fmt.Sprintf("%s (%s)", c.SnapshotID.ShortID(), format.Bytes(c.Bytes))
+		}
+		msg += "; largest snapshots GC can delete: " + strings.Join(parts, ", ")
+	}
+	return msg
+}
+
+// Is reports whether target is errclass.ErrQuotaExceeded.
+func (e *QuotaError) Is(target error) bool {
+	return errors.Is(errclass.ErrQuotaExceeded, target)
+}
+
+// SetQuota makes Fork check quota before copying. suggest, if not nil,
+// lists snapshots GC could delete to make room; its result is reported in
+// the QuotaError.
+func (m *Manager) SetQuota(quota model.Quota, suggest func() ([]model.SnapshotUsage, error)) {
+	m.quota = quota
+	m.suggest = suggest
+}
+
+// checkQuota returns a *QuotaError if forking snapshotID as name would
+// exceed the repository quota or the quota of a namespace name belongs to.
+// Sizes are apparent file sizes, which quotas on JuiceFS also count, even
+// where the clone itself shares data.
+func (m *Manager) checkQuota(snapshotID model.SnapshotID, name string) error {
+	if m.quota.IsZero() {
+		return nil
+	}
+	needed := fsutil.DirSize(repo.SnapshotPath(m.repoRoot, snapshotID))
+
+	var qerr *QuotaError
+	if m.quota.MaxBytes > 0 {
+		if used := fsutil.DirSize(m.repoRoot); used+needed > m.quota.MaxBytes {
+			qerr = &QuotaError{Limit: m.quota.MaxBytes, Used: used, Needed: needed}
+		}
+	}
+	for _, ns := range m.quota.Namespaces {
+		if qerr != nil {
+			break
+		}
+		if ns.MaxBytes <= 0 || !strings.HasPrefix(name, ns.Prefix) {
+			continue
+		}
+		used, err := m.namespaceUsage(ns.Prefix)
+		if err != nil {
+			return err
+		}
+		if used+needed > ns.MaxBytes {
+			qerr = &QuotaError{Namespace: ns.Prefix, Limit: ns.MaxBytes, Used: used, Needed: needed}
+		}
+	}
+	if qerr == nil {
+		return nil
+	}
+
+	if m.suggest != nil {
+		candidates, err := m.suggest()
+		if err != nil {
+			return fmt.Errorf("%w (listing GC candidates failed: %v)", qerr, err)
+		}
+		qerr.Candidates = candidates
+	}
+	return qerr
+}
+
+// namespaceUsage sums the payload sizes of the worktrees named with prefix.
+func (m *Manager) namespaceUsage(prefix string) (int64, error) {
+	cfgs, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	for _, cfg := range cfgs {
+		if strings.HasPrefix(cfg.Name, prefix) {
+			used += fsutil.DirSize(m.Path(cfg.Name))
+		}
+	}
+	return used, nil
+}
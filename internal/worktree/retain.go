@@ -0,0 +1,170 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// ArchivePrefix starts the names of archive retention buckets, which keep
+// snapshots of removed worktrees until the bucket file is deleted.
+const ArchivePrefix = "archived/"
+
+// retainedDir holds retention buckets: archived/<name>.json for archives and
+// worktrees/<name>.json for roots carried by a live worktree.
+func (m *Manager) retainedDir() string {
+	return filepath.Join(m.repoRoot, ".jvs", "gc", "retained")
+}
+
+func (m *Manager) bucketPath(bucket string) string {
+	if name, ok := strings.CutPrefix(bucket, ArchivePrefix); ok {
+		return filepath.Join(m.retainedDir(), "archived", name+".json")
+	}
+	return filepath.Join(m.retainedDir(), "worktrees", bucket+".json")
+}
+
+// RemoveRetaining removes a worktree like Remove, first moving GC protection
+// of its head and latest snapshots (and any roots it carried for earlier
+// removals) to bucket: another worktree's name or "archived/<name>".
+// It returns the roots that were moved.
+func (m *Manager) RemoveRetaining(name, bucket string) ([]model.RetainedRoot, error) {
+	if name == "main" {
+		return nil, fmt.Errorf("cannot remove main worktree")
+	}
+	if err := m.validateBucket(name, bucket); err != nil {
+		return nil, err
+	}
+	cfg, err := repo.LoadWorktreeConfig(m.repoRoot, name)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if err := CheckNotFrozen(cfg); err != nil {
+		return nil, err
+	}
+	if err := m.CheckUnlocked(name, ""); err != nil {
+		return nil, err
+	}
+
+	carried, err := m.readBucket(name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	roots := carried
+	for _, id := range []model.SnapshotID{cfg.HeadSnapshotID, cfg.LatestSnapshotID} {
+		if id != "" && !slices.ContainsFunc(roots, func(r model.RetainedRoot) bool { return r.SnapshotID == id }) {
+			roots = append(roots, model.RetainedRoot{SnapshotID: id, Worktree: name, RetainedAt: now})
+		}
+	}
+
+	// Protect in the new bucket before dropping the old protection
+	if err := m.addToBucket(bucket, roots); err != nil {
+		return nil, err
+	}
+	if err := m.remove(name, cfg, map[string]any{"retained_as": bucket, "retained_roots": len(roots)}); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// RetainedRoots returns the roots of every retention bucket, by bucket name.
+// Buckets of worktrees that no longer exist are ignored.
+func (m *Manager) RetainedRoots() (map[string][]model.RetainedRoot, error) {
+	buckets := make(map[string][]model.RetainedRoot)
+	for _, kind := range []string{"archived", "worktrees"} {
+		entries, err := os.ReadDir(filepath.Join(m.retainedDir(), kind))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutSuffix(entry.Name(), ".json")
+			if !ok {
+				continue
+			}
+			bucket := name
+			if kind == "archived" {
+				bucket = ArchivePrefix + name
+			} else if _, err := os.Stat(repo.WorktreeConfigPath(m.repoRoot, name)); err != nil {
+				continue
+			}
+			roots, err := m.readBucket(bucket)
+			if err != nil {
+				return nil, err
+			}
+			buckets[bucket] = roots
+		}
+	}
+	return buckets, nil
+}
+
+func (m *Manager) validateBucket(name, bucket string) error {
+	if archive, ok := strings.CutPrefix(bucket, ArchivePrefix); ok {
+		return pathutil.ValidateName(archive)
+	}
+	if bucket == name {
+		return fmt.Errorf("cannot retain snapshots in the worktree being removed")
+	}
+	if _, err := os.Stat(repo.WorktreeConfigPath(m.repoRoot, bucket)); err != nil {
+		return fmt.Errorf("retention target %q is neither an existing worktree nor %s<name>", bucket, ArchivePrefix)
+	}
+	return nil
+}
+
+func (m *Manager) readBucket(bucket string) ([]model.RetainedRoot, error) {
+	data, err := os.ReadFile(m.bucketPath(bucket))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read retention bucket %s: %w", bucket, err)
+	}
+	var roots []model.RetainedRoot
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, fmt.Errorf("parse retention bucket %s: %w", bucket, err)
+	}
+	return roots, nil
+}
+
+func (m *Manager) addToBucket(bucket string, roots []model.RetainedRoot) error {
+	existing, err := m.readBucket(bucket)
+	if err != nil {
+		return err
+	}
+	seen := make(map[model.SnapshotID]bool)
+	for _, r := range existing {
+		seen[r.SnapshotID] = true
+	}
+	for _, r := range roots {
+		if !seen[r.SnapshotID] {
+			seen[r.SnapshotID] = true
+			existing = append(existing, r)
+		}
+	}
+	sort.SliceStable(existing, func(i, j int) bool { return existing[i].RetainedAt.Before(existing[j].RetainedAt) })
+
+	path := m.bucketPath(bucket)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create retention dir: %w", err)
+	}
+	data, err := json.MarshalIndent(existing, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.AtomicWrite(path, data, 0644); err != nil {
+		return fmt.Errorf("write retention bucket %s: %w", bucket, err)
+	}
+	return nil
+}
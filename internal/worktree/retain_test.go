@@ -0,0 +1,90 @@
+package worktree_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestManager_RemoveRetaining_Archive(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	_, err := mgr.Create("exp", nil)
+	require.NoError(t, err)
+	require.NoError(t, mgr.SetLatest("exp", "1700000000000-aaaaaaaa"))
+
+	roots, err := mgr.RemoveRetaining("exp", "archived/experiments")
+	require.NoError(t, err)
+	require.Len(t, roots, 1, "head and latest are the same snapshot")
+	assert.Equal(t, "exp", roots[0].Worktree)
+
+	_, err = mgr.Get("exp")
+	assert.Error(t, err, "worktree is removed")
+
+	buckets, err := mgr.RetainedRoots()
+	require.NoError(t, err)
+	require.Contains(t, buckets, "archived/experiments")
+	assert.Equal(t, model.SnapshotID("1700000000000-aaaaaaaa"), buckets["archived/experiments"][0].SnapshotID)
+}
+
+func TestManager_RemoveRetaining_WorktreeCarriesRoots(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := mgr.Create(name, nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mgr.SetLatest("a", "1700000000000-aaaaaaaa"))
+	require.NoError(t, mgr.SetLatest("b", "1700000000001-bbbbbbbb"))
+
+	_, err := mgr.RemoveRetaining("a", "b")
+	require.NoError(t, err)
+
+	// Removing b retains its own head and the roots it carried for a
+	roots, err := mgr.RemoveRetaining("b", "c")
+	require.NoError(t, err)
+	assert.Len(t, roots, 2)
+	buckets, err := mgr.RetainedRoots()
+	require.NoError(t, err)
+	assert.Len(t, buckets["c"], 2)
+
+	// Roots move with a rename and are dropped with a plain remove
+	require.NoError(t, mgr.Rename("c", "d"))
+	buckets, err = mgr.RetainedRoots()
+	require.NoError(t, err)
+	assert.Len(t, buckets["d"], 2)
+	require.NoError(t, mgr.Remove("d"))
+	buckets, err = mgr.RetainedRoots()
+	require.NoError(t, err)
+	assert.Empty(t, buckets)
+
+	// A new worktree of the same name does not inherit them
+	_, err = mgr.Create("d", nil)
+	require.NoError(t, err)
+	buckets, err = mgr.RetainedRoots()
+	require.NoError(t, err)
+	assert.Empty(t, buckets)
+}
+
+func TestManager_RemoveRetaining_InvalidTarget(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	mgr := worktree.NewManager(repoPath)
+	_, err := mgr.Create("exp", nil)
+	require.NoError(t, err)
+
+	_, err = mgr.RemoveRetaining("exp", "missing")
+	assert.ErrorContains(t, err, "neither an existing worktree")
+	_, err = mgr.RemoveRetaining("exp", "exp")
+	assert.Error(t, err)
+	_, err = mgr.RemoveRetaining("exp", "archived/../x")
+	assert.Error(t, err)
+	_, err = mgr.RemoveRetaining("main", "archived/x")
+	assert.Error(t, err)
+
+	_, err = mgr.Get("exp")
+	assert.NoError(t, err, "nothing removed on error")
+}
@@ -3,11 +3,20 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"filippo.io/age"
+
+	"github.com/jvs-project/jvs/pkg/fsutil"
 	"github.com/jvs-project/jvs/pkg/model"
 	"gopkg.in/yaml.v3"
 )
@@ -34,6 +43,210 @@ type Config struct {
 
 	// Retention configures garbage collection behavior.
 	Retention *RetentionPolicy `yaml:"retention,omitempty"`
+
+	// Webhooks are endpoints notified of repository events, by 'jvs serve'
+	// and by the operations that complete.
+	Webhooks []Webhook `yaml:"webhooks,omitempty"`
+
+	// SnapshotIDScheme selects how new snapshot IDs are formed: "timestamp"
+	// (the default, <unix_ms>-<rand8hex>) or "worktree", which prepends a
+	// short worktree prefix. Existing IDs keep working under either scheme.
+	SnapshotIDScheme string `yaml:"snapshot_id_scheme,omitempty"`
+
+	// LockMaxWait is how long an operation waits in the repository lock
+	// queue before failing with E_LOCK_TIMEOUT (e.g., "30s", "10m").
+	// Defaults to DefaultLockMaxWait; "0s" fails at once if the lock is busy.
+	LockMaxWait string `yaml:"lock_max_wait,omitempty"`
+
+	// LockLeaseTTL turns on lease locking for repositories shared across
+	// nodes, where flock may not be honoured (e.g., "30s"): holders and
+	// waiters of the repository lock heartbeat their queue tickets, and a
+	// ticket whose heartbeat is older than the TTL is taken over. Unset or
+	// "0s" relies on flock alone.
+	LockLeaseTTL string `yaml:"lock_lease_ttl,omitempty"`
+
+	// HashAlgorithm is the payload hash algorithm of new snapshots: "sha256"
+	// (the default) or "blake3", which is much faster on large files. It is
+	// chosen with 'jvs init --hash'; each descriptor records its own.
+	HashAlgorithm string `yaml:"hash_algorithm,omitempty"`
+
+	// ContentStore stores each distinct file content of new snapshots once,
+	// in .jvs/objects, and hard-links snapshot files to it.
+	ContentStore bool `yaml:"content_store,omitempty"`
+
+	// Quota caps the space used by the repository and by groups of
+	// worktrees; forks that would exceed it are refused.
+	Quota *Quota `yaml:"quota,omitempty"`
+
+	// ReadErrorPolicy selects what snapshots do with files they cannot read:
+	// "fail" (the default) or "skip", which leaves them out and lists them
+	// in the descriptor.
+	ReadErrorPolicy string `yaml:"read_error_policy,omitempty"`
+
+	// Environment records where each snapshot was taken in its descriptor.
+	Environment *EnvironmentCapture `yaml:"environment,omitempty"`
+
+	// Staleness reports worktrees that have not been snapshotted for too
+	// long.
+	Staleness *Staleness `yaml:"staleness,omitempty"`
+
+	// Resolvers are external services asked, in order, for snapshot
+	// references that name no snapshot, tag or note of the repository,
+	// such as "nightly" kept in a release system.
+	Resolvers []Resolver `yaml:"resolvers,omitempty"`
+
+	// Encryption encrypts the payload of new snapshots at rest.
+	Encryption *Encryption `yaml:"encryption,omitempty"`
+
+	// Replicas are other repositories holding copies of this one's
+	// snapshots, such as a mirror kept with 'jvs export' and 'jvs import'.
+	// 'jvs verify --repair' fetches corrupted files from them. Relative
+	// paths are relative to the repository root.
+	Replicas []string `yaml:"replicas,omitempty"`
+}
+
+// DefaultLockMaxWait is the lock wait used when lock_max_wait is not set.
+const DefaultLockMaxWait = 5 * time.Minute
+
+// Snapshot ID schemes.
+const (
+	SnapshotIDSchemeTimestamp = "timestamp"
+	SnapshotIDSchemeWorktree  = "worktree"
+)
+
+// Webhook is an HTTP endpoint that receives JSON event payloads.
+type Webhook struct {
+	// URL is the http or https endpoint to POST events to.
+	URL string `yaml:"url"`
+
+	// Secret, if set, signs each payload with HMAC-SHA256; the signature is
+	// sent in the X-JVS-Signature header as "sha256=<hex>".
+	Secret string `yaml:"secret,omitempty"`
+
+	// Events lists the event types to send, from WebhookEvents. Empty
+	// sends the events of 'jvs serve': snapshot.created and
+	// worktree.stale.
+	Events []string `yaml:"events,omitempty"`
+}
+
+// WebhookEvents are the event types webhooks can receive.
+var WebhookEvents = []string{
+	"snapshot.created", "worktree.stale",
+	"snapshot.completed", "restore.completed", "gc.completed",
+}
+
+// Resolver is an external snapshot reference resolver: an HTTP endpoint or
+// a command. Exactly one of URL and Command is set.
+type Resolver struct {
+	// Name identifies the resolver in audit records.
+	Name string `yaml:"name"`
+
+	// URL is queried as GET <url>?ref=<reference>. It answers 200 with
+	// {"snapshot_id": "<id>"}, or 404 if it does not know the reference.
+	URL string `yaml:"url,omitempty"`
+
+	// Command is run with the reference appended as its last argument. It
+	// prints the snapshot ID, or nothing if it does not know the reference.
+	Command []string `yaml:"command,omitempty"`
+
+	// Timeout bounds each lookup (default 5s).
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// CacheTTL is how long an answer is reused before the resolver is
+	// asked again (default 1m); "0s" disables caching.
+	CacheTTL string `yaml:"cache_ttl,omitempty"`
+}
+
+// Default resolver settings.
+const (
+	DefaultResolverTimeout  = 5 * time.Second
+	DefaultResolverCacheTTL = time.Minute
+)
+
+// GetTimeout returns the lookup timeout of the resolver.
+func (r *Resolver) GetTimeout() time.Duration {
+	if d, err := time.ParseDuration(r.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultResolverTimeout
+}
+
+// GetCacheTTL returns how long answers of the resolver are cached.
+func (r *Resolver) GetCacheTTL() time.Duration {
+	if d, err := time.ParseDuration(r.CacheTTL); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultResolverCacheTTL
+}
+
+// EnvironmentCapture configures the environment recorded in descriptors.
+type EnvironmentCapture struct {
+	// Capture records the hostname, container image digest and jvs version
+	// of each new snapshot.
+	Capture bool `yaml:"capture"`
+
+	// Vars lists the environment variables to record as well, as names or
+	// glob patterns (e.g., "AGENT_ID", "CI_*"). Only listed variables are
+	// recorded, so secrets stay out of descriptors.
+	Vars []string `yaml:"vars,omitempty"`
+}
+
+// Staleness configures when a worktree counts as stale: its newest snapshot
+// is older than the worktree's maximum age.
+type Staleness struct {
+	// MaxAge applies to every worktree not listed in Worktrees (e.g.,
+	// "24h", "7d"). Empty disables the check for them.
+	MaxAge string `yaml:"max_age,omitempty"`
+
+	// Worktrees set the maximum age of single worktrees by name; "0s"
+	// disables the check for a worktree.
+	Worktrees map[string]string `yaml:"worktrees,omitempty"`
+
+	// Notify makes 'jvs serve' POST a worktree.stale event to the webhooks
+	// when a worktree becomes stale.
+	Notify bool `yaml:"notify,omitempty"`
+}
+
+// Encryption configures at-rest encryption of snapshot payloads with age.
+// New snapshots are encrypted to Recipients; reading them back needs one
+// of the matching identities, taken from the JVS_AGE_IDENTITY environment
+// variable (secret keys), the file named by JVS_AGE_IDENTITY_FILE or
+// IdentityFile, or the output of IdentityCommand, in that order.
+type Encryption struct {
+	// Recipients are age X25519 public keys ("age1..."). Setting them
+	// turns encryption on for new snapshots.
+	Recipients []string `yaml:"recipients,omitempty"`
+
+	// IdentityFile is an age identity file, as written by age-keygen.
+	IdentityFile string `yaml:"identity_file,omitempty"`
+
+	// IdentityCommand is run to fetch identities, e.g. from a KMS; it
+	// prints them on stdout in age identity file format.
+	IdentityCommand []string `yaml:"identity_command,omitempty"`
+}
+
+// Enabled reports whether new snapshots are encrypted.
+func (e *Encryption) Enabled() bool {
+	return e != nil && len(e.Recipients) > 0
+}
+
+// Quota configures space limits.
+type Quota struct {
+	// MaxSize limits the whole repository (e.g., "500GB").
+	MaxSize string `yaml:"max_size,omitempty"`
+
+	// Namespaces limit the payloads of worktrees by name prefix.
+	Namespaces []NamespaceQuota `yaml:"namespaces,omitempty"`
+}
+
+// NamespaceQuota limits the total payload size of the worktrees whose names
+// start with Prefix.
+type NamespaceQuota struct {
+	// Prefix selects the worktrees, e.g. "agent-".
+	Prefix string `yaml:"prefix"`
+
+	// MaxSize is the size limit (e.g., "50GB").
+	MaxSize string `yaml:"max_size"`
 }
 
 // RetentionPolicy configures GC retention behavior.
@@ -43,6 +256,53 @@ type RetentionPolicy struct {
 
 	// Within is the minimum age before snapshots can be pruned (e.g., "24h", "7d").
 	Within string `yaml:"within,omitempty"`
+
+	// KeepTags are tags whose snapshots are never pruned by age, count or
+	// budget.
+	KeepTags []string `yaml:"keep_tags,omitempty"`
+
+	// TagRules protect snapshots by tag pattern, e.g. all "release-*"
+	// snapshots or the newest 10 "auto" ones.
+	TagRules []TagRule `yaml:"tag_rules,omitempty"`
+
+	// Worktrees replace Keep and Within for the snapshots of single
+	// worktrees by name; a worktree's snapshots are counted apart.
+	Worktrees map[string]WorktreeRetention `yaml:"worktrees,omitempty"`
+
+	// Budgets cap the total size and count of snapshots per tag.
+	Budgets []TagBudget `yaml:"budgets,omitempty"`
+
+	// TombstoneMaxAge makes GC runs remove tombstones older than this
+	// (e.g., "90d", "720h"). Empty keeps them forever.
+	TombstoneMaxAge string `yaml:"tombstone_max_age,omitempty"`
+}
+
+// TagRule protects the snapshots carrying a tag that matches Pattern, a
+// glob such as "release-*": the newest KeepLast of them, or all if unset.
+type TagRule struct {
+	Pattern  string `yaml:"pattern"`
+	KeepLast int    `yaml:"keep_last,omitempty"`
+}
+
+// WorktreeRetention is the retention of one worktree's snapshots. Unset
+// fields keep nothing: {keep: 5} keeps the newest five snapshots and no
+// others by age.
+type WorktreeRetention struct {
+	Keep   int    `yaml:"keep,omitempty"`
+	Within string `yaml:"within,omitempty"`
+}
+
+// TagBudget limits the snapshots carrying Tag. When exceeded, GC plans delete
+// the oldest such snapshots even if they are within the retention window.
+type TagBudget struct {
+	// Tag selects the snapshots the budget applies to.
+	Tag string `yaml:"tag"`
+
+	// MaxSize is the total size limit (e.g., "50GB", "512MiB", "1048576").
+	MaxSize string `yaml:"max_size,omitempty"`
+
+	// MaxCount is the maximum number of snapshots.
+	MaxCount int `yaml:"max_count,omitempty"`
 }
 
 // Default returns the default configuration.
@@ -110,7 +370,7 @@ func Save(repoRoot string, cfg *Config) error {
 		return fmt.Errorf("marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(cfgPath, data, 0600); err != nil {
+	if err := fsutil.WriteFileRetry(cfgPath, data, 0600); err != nil {
 		return fmt.Errorf("write config: %w", err)
 	}
 
@@ -131,11 +391,176 @@ func (c *Config) validate() error {
 		}
 	}
 
+	switch model.HashAlgorithm(c.HashAlgorithm) {
+	case "", model.HashSHA256, model.HashBLAKE3:
+	default:
+		return fmt.Errorf("invalid hash_algorithm: %s (must be sha256 or blake3)", c.HashAlgorithm)
+	}
+
 	// Validate output_format if set
 	if c.OutputFormat != "" && c.OutputFormat != "text" && c.OutputFormat != "json" {
 		return fmt.Errorf("invalid output_format: %s (must be text or json)", c.OutputFormat)
 	}
 
+	// Validate retention budgets if set
+	if c.Retention != nil {
+		for _, tag := range c.Retention.KeepTags {
+			if tag == "" {
+				return fmt.Errorf("invalid retention keep_tags: empty tag")
+			}
+		}
+		if c.Retention.TombstoneMaxAge != "" {
+			if d, err := ParseDuration(c.Retention.TombstoneMaxAge); err != nil || d < 0 {
+				return fmt.Errorf("invalid retention tombstone_max_age: %s (must be a non-negative duration)", c.Retention.TombstoneMaxAge)
+			}
+		}
+		for _, rule := range c.Retention.TagRules {
+			if _, err := path.Match(rule.Pattern, ""); rule.Pattern == "" || err != nil {
+				return fmt.Errorf("invalid retention tag rule: pattern %q must be a valid glob", rule.Pattern)
+			}
+			if rule.KeepLast < 0 {
+				return fmt.Errorf("invalid retention tag rule %s: keep_last must be non-negative", rule.Pattern)
+			}
+		}
+		for name, wr := range c.Retention.Worktrees {
+			if wr.Keep < 0 {
+				return fmt.Errorf("invalid retention for worktree %s: keep must be non-negative", name)
+			}
+			if wr.Within != "" {
+				if d, err := ParseDuration(wr.Within); err != nil || d < 0 {
+					return fmt.Errorf("invalid retention for worktree %s: within: %s (must be a non-negative duration)", name, wr.Within)
+				}
+			}
+		}
+		for _, b := range c.Retention.Budgets {
+			if b.Tag == "" {
+				return fmt.Errorf("invalid retention budget: tag is required")
+			}
+			if b.MaxCount < 0 {
+				return fmt.Errorf("invalid retention budget for tag %s: max_count must be non-negative", b.Tag)
+			}
+			if b.MaxSize != "" {
+				if _, err := ParseSize(b.MaxSize); err != nil {
+					return fmt.Errorf("invalid retention budget for tag %s: %w", b.Tag, err)
+				}
+			}
+		}
+	}
+
+	if c.Quota != nil {
+		if _, err := ParseSize(c.Quota.MaxSize); err != nil {
+			return fmt.Errorf("invalid quota max_size: %w", err)
+		}
+		for _, ns := range c.Quota.Namespaces {
+			if ns.Prefix == "" {
+				return fmt.Errorf("invalid namespace quota: prefix is required")
+			}
+			if _, err := ParseSize(ns.MaxSize); err != nil {
+				return fmt.Errorf("invalid namespace quota for prefix %s: %w", ns.Prefix, err)
+			}
+		}
+	}
+
+	if c.Environment != nil {
+		for _, pattern := range c.Environment.Vars {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid environment var pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	if c.Staleness != nil {
+		if c.Staleness.MaxAge != "" {
+			if d, err := ParseDuration(c.Staleness.MaxAge); err != nil || d < 0 {
+				return fmt.Errorf("invalid staleness max_age: %s (must be a non-negative duration)", c.Staleness.MaxAge)
+			}
+		}
+		for name, maxAge := range c.Staleness.Worktrees {
+			if d, err := ParseDuration(maxAge); err != nil || d < 0 {
+				return fmt.Errorf("invalid staleness max_age for worktree %s: %s (must be a non-negative duration)", name, maxAge)
+			}
+		}
+	}
+
+	switch model.ReadErrorPolicy(c.ReadErrorPolicy) {
+	case "", model.ReadErrorFail, model.ReadErrorSkip:
+	default:
+		return fmt.Errorf("invalid read_error_policy: %s (must be fail or skip)", c.ReadErrorPolicy)
+	}
+
+	switch c.SnapshotIDScheme {
+	case "", SnapshotIDSchemeTimestamp, SnapshotIDSchemeWorktree:
+	default:
+		return fmt.Errorf("invalid snapshot_id_scheme: %s (must be timestamp or worktree)", c.SnapshotIDScheme)
+	}
+
+	if c.LockMaxWait != "" {
+		if d, err := time.ParseDuration(c.LockMaxWait); err != nil || d < 0 {
+			return fmt.Errorf("invalid lock_max_wait: %s (must be a non-negative duration)", c.LockMaxWait)
+		}
+	}
+	if c.LockLeaseTTL != "" {
+		if d, err := time.ParseDuration(c.LockLeaseTTL); err != nil || d < 0 {
+			return fmt.Errorf("invalid lock_lease_ttl: %s (must be a non-negative duration)", c.LockLeaseTTL)
+		}
+	}
+
+	for _, w := range c.Webhooks {
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q (must be an http or https URL)", w.URL)
+		}
+		for _, e := range w.Events {
+			if !slices.Contains(WebhookEvents, e) {
+				return fmt.Errorf("invalid webhook event %q (must be one of %s)", e, strings.Join(WebhookEvents, ", "))
+			}
+		}
+	}
+
+	if c.Encryption != nil {
+		for _, r := range c.Encryption.Recipients {
+			if _, err := age.ParseX25519Recipient(r); err != nil {
+				return fmt.Errorf("invalid encryption recipient %q (must be an age X25519 public key)", r)
+			}
+		}
+	}
+
+	for _, r := range c.Replicas {
+		if r == "" {
+			return fmt.Errorf("invalid replica: path is required")
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, r := range c.Resolvers {
+		if r.Name == "" {
+			return fmt.Errorf("invalid resolver: name is required")
+		}
+		if names[r.Name] {
+			return fmt.Errorf("invalid resolver %s: duplicate name", r.Name)
+		}
+		names[r.Name] = true
+		if (r.URL == "") == (len(r.Command) == 0) {
+			return fmt.Errorf("invalid resolver %s: exactly one of url and command is required", r.Name)
+		}
+		if r.URL != "" {
+			u, err := url.Parse(r.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid resolver %s url %q (must be an http or https URL)", r.Name, r.URL)
+			}
+		}
+		if r.Timeout != "" {
+			if d, err := time.ParseDuration(r.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid resolver %s timeout: %s (must be a positive duration)", r.Name, r.Timeout)
+			}
+		}
+		if r.CacheTTL != "" {
+			if d, err := time.ParseDuration(r.CacheTTL); err != nil || d < 0 {
+				return fmt.Errorf("invalid resolver %s cache_ttl: %s (must be a non-negative duration)", r.Name, r.CacheTTL)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -166,6 +591,36 @@ func (c *Config) GetProgressEnabled() *bool {
 	return c.ProgressEnabled
 }
 
+// NewSnapshotID generates a snapshot ID for worktreeName using the
+// configured scheme.
+func (c *Config) NewSnapshotID(worktreeName string) model.SnapshotID {
+	if c.SnapshotIDScheme == SnapshotIDSchemeWorktree {
+		return model.NewPrefixedSnapshotID(worktreeName)
+	}
+	return model.NewSnapshotID()
+}
+
+// GetLockMaxWait returns the maximum repository lock wait.
+func (c *Config) GetLockMaxWait() time.Duration {
+	if c.LockMaxWait != "" {
+		if d, err := time.ParseDuration(c.LockMaxWait); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return DefaultLockMaxWait
+}
+
+// GetLockLeaseTTL returns the repository lock lease TTL, zero if lease
+// locking is off.
+func (c *Config) GetLockLeaseTTL() time.Duration {
+	if c.LockLeaseTTL != "" {
+		if d, err := time.ParseDuration(c.LockLeaseTTL); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 // GetRetentionPolicy returns the retention policy as a model.RetentionPolicy.
 func (c *Config) GetRetentionPolicy() model.RetentionPolicy {
 	policy := model.DefaultRetentionPolicy()
@@ -175,15 +630,190 @@ func (c *Config) GetRetentionPolicy() model.RetentionPolicy {
 			policy.KeepMinSnapshots = c.Retention.Keep
 		}
 		if c.Retention.Within != "" {
-			if d, err := time.ParseDuration(c.Retention.Within); err == nil {
+			if d, err := ParseDuration(c.Retention.Within); err == nil {
 				policy.KeepMinAge = d
 			}
 		}
+		for _, b := range c.Retention.Budgets {
+			maxBytes, _ := ParseSize(b.MaxSize)
+			policy.Budgets = append(policy.Budgets, model.TagBudget{
+				Tag:      b.Tag,
+				MaxBytes: maxBytes,
+				MaxCount: b.MaxCount,
+			})
+		}
+		policy.KeepTags = append([]string(nil), c.Retention.KeepTags...)
+		policy.TombstoneMaxAge, _ = ParseDuration(c.Retention.TombstoneMaxAge)
+		for _, rule := range c.Retention.TagRules {
+			policy.TagRules = append(policy.TagRules, model.TagRule{Pattern: rule.Pattern, KeepLast: rule.KeepLast})
+		}
+		if len(c.Retention.Worktrees) > 0 {
+			policy.Worktrees = make(map[string]model.WorktreeRetention, len(c.Retention.Worktrees))
+			for name, wr := range c.Retention.Worktrees {
+				keepMinAge, _ := ParseDuration(wr.Within)
+				policy.Worktrees[name] = model.WorktreeRetention{KeepMinSnapshots: wr.Keep, KeepMinAge: keepMinAge}
+			}
+		}
+	}
+
+	return policy
+}
+
+// GetReadErrorPolicy returns the configured read error policy, defaulting
+// to model.ReadErrorFail.
+func (c *Config) GetReadErrorPolicy() model.ReadErrorPolicy {
+	if c.ReadErrorPolicy == "" {
+		return model.ReadErrorFail
+	}
+	return model.ReadErrorPolicy(c.ReadErrorPolicy)
+}
+
+// GetHashAlgorithm returns the payload hash algorithm of new snapshots,
+// defaulting to model.HashSHA256.
+func (c *Config) GetHashAlgorithm() model.HashAlgorithm {
+	if c.HashAlgorithm == "" {
+		return model.HashSHA256
+	}
+	return model.HashAlgorithm(c.HashAlgorithm)
+}
+
+// GetEnvironmentCapture reports whether new snapshots record their
+// environment, and which variables they record.
+func (c *Config) GetEnvironmentCapture() (bool, []string) {
+	if c.Environment == nil {
+		return false, nil
+	}
+	return c.Environment.Capture, c.Environment.Vars
+}
+
+// GetQuota returns the configured quota as a model.Quota; it is zero if
+// none is set.
+func (c *Config) GetQuota() model.Quota {
+	var quota model.Quota
+	if c.Quota == nil {
+		return quota
 	}
+	quota.MaxBytes, _ = ParseSize(c.Quota.MaxSize)
+	for _, ns := range c.Quota.Namespaces {
+		maxBytes, _ := ParseSize(ns.MaxSize)
+		quota.Namespaces = append(quota.Namespaces, model.NamespaceQuota{Prefix: ns.Prefix, MaxBytes: maxBytes})
+	}
+	return quota
+}
 
+// GetStaleness returns the staleness policy as a model.StalenessPolicy; it
+// is zero if none is set.
+func (c *Config) GetStaleness() model.StalenessPolicy {
+	var policy model.StalenessPolicy
+	if c.Staleness == nil {
+		return policy
+	}
+	policy.MaxAge, _ = ParseDuration(c.Staleness.MaxAge)
+	if len(c.Staleness.Worktrees) > 0 {
+		policy.Worktrees = make(map[string]time.Duration, len(c.Staleness.Worktrees))
+		for name, maxAge := range c.Staleness.Worktrees {
+			policy.Worktrees[name], _ = ParseDuration(maxAge)
+		}
+	}
 	return policy
 }
 
+// LoadRetentionPolicy reads a retention policy file, as given to 'jvs gc
+// daemon --policy' or kept in .jvs/retention.json. It holds the fields of the retention section of
+// config.yaml, in YAML or JSON.
+func LoadRetentionPolicy(path string) (model.RetentionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return model.RetentionPolicy{}, fmt.Errorf("read retention policy: %w", err)
+	}
+	var rp RetentionPolicy
+	if err := yaml.Unmarshal(data, &rp); err != nil {
+		return model.RetentionPolicy{}, fmt.Errorf("parse retention policy: %w", err)
+	}
+	if rp.Within != "" {
+		if d, err := ParseDuration(rp.Within); err != nil || d < 0 {
+			return model.RetentionPolicy{}, fmt.Errorf("invalid retention policy: within: %s (must be a non-negative duration)", rp.Within)
+		}
+	}
+	cfg := &Config{Retention: &rp}
+	if err := cfg.validate(); err != nil {
+		return model.RetentionPolicy{}, fmt.Errorf("invalid retention policy: %w", err)
+	}
+	return cfg.GetRetentionPolicy(), nil
+}
+
+// RetentionPolicyPath returns the path of a repository's retention policy
+// file, .jvs/retention.json.
+func RetentionPolicyPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".jvs", "retention.json")
+}
+
+// LoadRepoRetentionPolicy returns the retention policy GC uses by default:
+// .jvs/retention.json if it exists, else the retention section of
+// config.yaml, else model.DefaultRetentionPolicy.
+func LoadRepoRetentionPolicy(repoRoot string) (model.RetentionPolicy, error) {
+	path := RetentionPolicyPath(repoRoot)
+	if _, err := os.Stat(path); err == nil {
+		return LoadRetentionPolicy(path)
+	} else if !os.IsNotExist(err) {
+		return model.RetentionPolicy{}, fmt.Errorf("stat retention policy: %w", err)
+	}
+	cfg, err := Load(repoRoot)
+	if err != nil {
+		return model.RetentionPolicy{}, err
+	}
+	return cfg.GetRetentionPolicy(), nil
+}
+
+// ParseDuration parses a duration such as "90d", "36h" or "1h30m": a
+// whole number of days, or anything time.ParseDuration accepts. An empty
+// string is zero.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseSize parses a byte size such as "50GB", "512MiB", "10k" or "1048576".
+// Decimal (KB, MB, GB, TB) and binary (KiB, MiB, GiB, TiB) units are accepted;
+// single-letter units (K, M, G, T) are binary. An empty string is zero.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	num, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	mult, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+	return int64(num * float64(mult)), nil
+}
+
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1e3, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1e6, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1e9, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1e12, "tib": 1 << 40,
+}
+
 // Set sets a configuration value by key.
 func (c *Config) Set(key, value string) error {
 	switch key {
@@ -207,6 +837,25 @@ func (c *Config) Set(key, value string) error {
 			return fmt.Errorf("invalid progress_enabled value: %s (must be true or false)", value)
 		}
 		c.ProgressEnabled = &enabled
+	case "snapshot_id_scheme":
+		c.SnapshotIDScheme = value
+	case "read_error_policy":
+		c.ReadErrorPolicy = value
+	case "hash_algorithm":
+		c.HashAlgorithm = value
+	case "lock_max_wait":
+		c.LockMaxWait = value
+	case "lock_lease_ttl":
+		c.LockLeaseTTL = value
+	case "content_store":
+		switch value {
+		case "true":
+			c.ContentStore = true
+		case "false":
+			c.ContentStore = false
+		default:
+			return fmt.Errorf("invalid content_store value: %s (must be true or false)", value)
+		}
 	default:
 		return fmt.Errorf("unknown config key: %s", key)
 	}
@@ -240,6 +889,18 @@ func (c *Config) Get(key string) (string, error) {
 			return "true", nil
 		}
 		return "false", nil
+	case "snapshot_id_scheme":
+		return c.SnapshotIDScheme, nil
+	case "read_error_policy":
+		return c.ReadErrorPolicy, nil
+	case "hash_algorithm":
+		return c.HashAlgorithm, nil
+	case "lock_max_wait":
+		return c.LockMaxWait, nil
+	case "lock_lease_ttl":
+		return c.LockLeaseTTL, nil
+	case "content_store":
+		return strconv.FormatBool(c.ContentStore), nil
 	default:
 		return "", fmt.Errorf("unknown config key: %s", key)
 	}
@@ -252,6 +913,12 @@ func Keys() []string {
 		"default_tags",
 		"output_format",
 		"progress_enabled",
+		"snapshot_id_scheme",
+		"read_error_policy",
+		"hash_algorithm",
+		"lock_max_wait",
+		"lock_lease_ttl",
+		"content_store",
 	}
 }
 
@@ -274,8 +941,50 @@ func deepCopy(cfg *Config) *Config {
 	}
 	if cfg.Retention != nil {
 		r := *cfg.Retention
+		r.Budgets = append([]TagBudget(nil), cfg.Retention.Budgets...)
+		r.KeepTags = append([]string(nil), cfg.Retention.KeepTags...)
+		r.TagRules = append([]TagRule(nil), cfg.Retention.TagRules...)
+		r.Worktrees = maps.Clone(cfg.Retention.Worktrees)
 		cp.Retention = &r
 	}
+	if cfg.Quota != nil {
+		q := *cfg.Quota
+		q.Namespaces = append([]NamespaceQuota(nil), cfg.Quota.Namespaces...)
+		cp.Quota = &q
+	}
+	if cfg.Environment != nil {
+		e := *cfg.Environment
+		e.Vars = append([]string(nil), cfg.Environment.Vars...)
+		cp.Environment = &e
+	}
+	if cfg.Webhooks != nil {
+		cp.Webhooks = make([]Webhook, len(cfg.Webhooks))
+		for i, w := range cfg.Webhooks {
+			w.Events = append([]string(nil), w.Events...)
+			cp.Webhooks[i] = w
+		}
+	}
+	if cfg.Resolvers != nil {
+		cp.Resolvers = make([]Resolver, len(cfg.Resolvers))
+		for i, r := range cfg.Resolvers {
+			r.Command = append([]string(nil), r.Command...)
+			cp.Resolvers[i] = r
+		}
+	}
+	if cfg.Encryption != nil {
+		e := *cfg.Encryption
+		e.Recipients = append([]string(nil), cfg.Encryption.Recipients...)
+		e.IdentityCommand = append([]string(nil), cfg.Encryption.IdentityCommand...)
+		cp.Encryption = &e
+	}
+	if cfg.Replicas != nil {
+		cp.Replicas = append([]string(nil), cfg.Replicas...)
+	}
+	if cfg.Staleness != nil {
+		st := *cfg.Staleness
+		st.Worktrees = maps.Clone(cfg.Staleness.Worktrees)
+		cp.Staleness = &st
+	}
 	return &cp
 }
 
@@ -369,15 +369,21 @@ func TestConfig_Validate(t *testing.T) {
 
 func TestKeys(t *testing.T) {
 	keys := Keys()
-	if len(keys) != 4 {
-		t.Errorf("expected 4 keys, got %d", len(keys))
+	if len(keys) != 10 {
+		t.Errorf("expected 10 keys, got %d", len(keys))
 	}
 
 	expectedKeys := map[string]bool{
-		"default_engine":   false,
-		"default_tags":     false,
-		"output_format":    false,
-		"progress_enabled": false,
+		"default_engine":     false,
+		"default_tags":       false,
+		"output_format":      false,
+		"progress_enabled":   false,
+		"snapshot_id_scheme": false,
+		"read_error_policy":  false,
+		"hash_algorithm":     false,
+		"lock_max_wait":      false,
+		"lock_lease_ttl":     false,
+		"content_store":      false,
 	}
 
 	for _, key := range keys {
@@ -428,6 +434,56 @@ func TestGetProgressEnabled(t *testing.T) {
 }
 
 // TestGetRetentionPolicy tests the GetRetentionPolicy method.
+func TestGetQuota(t *testing.T) {
+	if q := (&Config{}).GetQuota(); !q.IsZero() {
+		t.Errorf("expected zero quota, got %+v", q)
+	}
+
+	cfg := &Config{Quota: &Quota{
+		MaxSize:    "1GiB",
+		Namespaces: []NamespaceQuota{{Prefix: "agent-", MaxSize: "10MiB"}},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	q := cfg.GetQuota()
+	if q.MaxBytes != 1<<30 {
+		t.Errorf("expected MaxBytes %d, got %d", 1<<30, q.MaxBytes)
+	}
+	if len(q.Namespaces) != 1 || q.Namespaces[0].Prefix != "agent-" || q.Namespaces[0].MaxBytes != 10<<20 {
+		t.Errorf("unexpected namespaces %+v", q.Namespaces)
+	}
+
+	cfg.Quota.Namespaces[0].Prefix = ""
+	if err := cfg.validate(); err == nil {
+		t.Error("expected error for namespace quota without prefix")
+	}
+	cfg.Quota = &Quota{MaxSize: "lots"}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected error for invalid quota size")
+	}
+}
+
+func TestGetEnvironmentCapture(t *testing.T) {
+	if capture, vars := (&Config{}).GetEnvironmentCapture(); capture || vars != nil {
+		t.Errorf("expected no capture, got %v %v", capture, vars)
+	}
+
+	cfg := &Config{Environment: &EnvironmentCapture{Capture: true, Vars: []string{"AGENT_ID", "CI_*"}}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	capture, vars := cfg.GetEnvironmentCapture()
+	if !capture || len(vars) != 2 {
+		t.Errorf("expected capture of 2 vars, got %v %v", capture, vars)
+	}
+
+	cfg.Environment.Vars = []string{"CI_["}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected error for invalid var pattern")
+	}
+}
+
 func TestGetRetentionPolicy(t *testing.T) {
 	t.Run("Returns default policy when retention is nil", func(t *testing.T) {
 		cfg := &Config{Retention: nil}
@@ -494,6 +550,18 @@ func TestGetRetentionPolicy(t *testing.T) {
 		}
 	})
 
+	t.Run("Within in days", func(t *testing.T) {
+		cfg := &Config{
+			Retention: &RetentionPolicy{
+				Within: "7d",
+			},
+		}
+		policy := cfg.GetRetentionPolicy()
+		if policy.KeepMinAge != 7*24*time.Hour {
+			t.Errorf("expected 168h KeepMinAge, got %v", policy.KeepMinAge)
+		}
+	})
+
 	t.Run("Invalid Within string uses default", func(t *testing.T) {
 		cfg := &Config{
 			Retention: &RetentionPolicy{
@@ -855,3 +923,395 @@ func TestLoad_CacheCopyIndependence(t *testing.T) {
 	assert.Equal(t, model.EngineType("copy"), cfg2.DefaultEngine, "cache should not be mutated by modifying a returned copy")
 	assert.Equal(t, "json", cfg2.OutputFormat, "cache should not be mutated by modifying a returned copy")
 }
+
+func TestGetRetentionPolicy_Budgets(t *testing.T) {
+	cfg := &Config{
+		Retention: &RetentionPolicy{
+			Budgets: []TagBudget{
+				{Tag: "auto", MaxSize: "50GB"},
+				{Tag: "nightly", MaxCount: 7},
+			},
+		},
+	}
+	policy := cfg.GetRetentionPolicy()
+	require.Len(t, policy.Budgets, 2)
+	assert.Equal(t, model.TagBudget{Tag: "auto", MaxBytes: 50_000_000_000}, policy.Budgets[0])
+	assert.Equal(t, model.TagBudget{Tag: "nightly", MaxCount: 7}, policy.Budgets[1])
+}
+
+func TestLoad_RetentionBudgets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+	yaml := "retention:\n  budgets:\n    - tag: auto\n      max_size: 512MiB\n      max_count: 20\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+	defer InvalidateCache(dir)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	policy := cfg.GetRetentionPolicy()
+	require.Len(t, policy.Budgets, 1)
+	assert.Equal(t, int64(512<<20), policy.Budgets[0].MaxBytes)
+	assert.Equal(t, 20, policy.Budgets[0].MaxCount)
+}
+
+func TestLoad_InvalidRetentionBudget(t *testing.T) {
+	tests := map[string]string{
+		"missing tag": "retention:\n  budgets:\n    - max_count: 1\n",
+		"bad size":    "retention:\n  budgets:\n    - tag: auto\n      max_size: lots\n",
+		"negative":    "retention:\n  budgets:\n    - tag: auto\n      max_count: -1\n",
+	}
+	for name, yaml := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+			_, err := Load(dir)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"1048576", 1 << 20},
+		{"10k", 10 << 10},
+		{"50GB", 50_000_000_000},
+		{"50 GiB", 50 << 30},
+		{"1.5MiB", 3 << 19},
+		{"2T", 2 << 40},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, bad := range []string{"GB", "-1GB", "10XB", "abc"} {
+		_, err := ParseSize(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"90d", 90 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, bad := range []string{"d", "1.5d", "soon"} {
+		_, err := ParseDuration(bad)
+		assert.Error(t, err, bad)
+	}
+
+	cfg := &Config{Retention: &RetentionPolicy{TombstoneMaxAge: "90d"}}
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, 90*24*time.Hour, cfg.GetRetentionPolicy().TombstoneMaxAge)
+	cfg.Retention.TombstoneMaxAge = "-1d"
+	assert.Error(t, cfg.validate())
+}
+
+func TestLoad_Webhooks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+	yaml := "webhooks:\n  - url: https://ci.example.com/hook\n    secret: s3cret\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+	defer InvalidateCache(dir)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, cfg.Webhooks, 1)
+	assert.Equal(t, Webhook{URL: "https://ci.example.com/hook", Secret: "s3cret"}, cfg.Webhooks[0])
+}
+
+func TestLoad_WebhookEvents(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+	yaml := "webhooks:\n  - url: https://ci.example.com/hook\n    events: [restore.completed, gc.completed]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+	defer InvalidateCache(dir)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, cfg.Webhooks, 1)
+	assert.Equal(t, []string{"restore.completed", "gc.completed"}, cfg.Webhooks[0].Events)
+
+	cfg.Webhooks[0].Events = []string{"snapshot.deleted"}
+	assert.ErrorContains(t, cfg.validate(), `invalid webhook event "snapshot.deleted"`)
+}
+
+func TestLoad_InvalidWebhookURL(t *testing.T) {
+	for _, u := range []string{"ftp://example.com", "not a url", "https://"} {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+		yaml := "webhooks:\n  - url: \"" + u + "\"\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+		_, err := Load(dir)
+		assert.Error(t, err, u)
+	}
+}
+
+func TestLoad_Resolvers(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+	yaml := "resolvers:\n  - name: releases\n    url: https://releases.example.com/resolve\n    cache_ttl: 0s\n  - name: nightly\n    command: [/usr/local/bin/nightly-id]\n    timeout: 2s\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+	defer InvalidateCache(dir)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, cfg.Resolvers, 2)
+	assert.Equal(t, "https://releases.example.com/resolve", cfg.Resolvers[0].URL)
+	assert.Equal(t, time.Duration(0), cfg.Resolvers[0].GetCacheTTL())
+	assert.Equal(t, DefaultResolverTimeout, cfg.Resolvers[0].GetTimeout())
+	assert.Equal(t, []string{"/usr/local/bin/nightly-id"}, cfg.Resolvers[1].Command)
+	assert.Equal(t, 2*time.Second, cfg.Resolvers[1].GetTimeout())
+	assert.Equal(t, DefaultResolverCacheTTL, cfg.Resolvers[1].GetCacheTTL())
+}
+
+func TestLoad_InvalidResolvers(t *testing.T) {
+	for _, entry := range []string{
+		"url: https://example.com", // no name
+		"name: a",                  // neither url nor command
+		"name: a\n    url: https://x.io\n    command: [x]", // both
+		"name: a\n    url: ftp://x.io",
+		"name: a\n    command: [x]\n    timeout: 0s",
+		"name: a\n    command: [x]\n    cache_ttl: -1m",
+	} {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+		yaml := "resolvers:\n  - " + entry + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte(yaml), 0644))
+		_, err := Load(dir)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestConfig_SnapshotIDScheme(t *testing.T) {
+	cfg := Default()
+	assert.Regexp(t, `^\d{13}-[0-9a-f]{8}$`, string(cfg.NewSnapshotID("main")))
+
+	require.NoError(t, cfg.Set("snapshot_id_scheme", SnapshotIDSchemeWorktree))
+	require.NoError(t, cfg.validate())
+	id := cfg.NewSnapshotID("feature-x")
+	assert.Regexp(t, `^featurex-\d{13}-[0-9a-f]{8}$`, string(id))
+	assert.Equal(t, "featurex", id.Prefix())
+
+	value, err := cfg.Get("snapshot_id_scheme")
+	require.NoError(t, err)
+	assert.Equal(t, "worktree", value)
+
+	require.NoError(t, cfg.Set("snapshot_id_scheme", "uuid"))
+	assert.Error(t, cfg.validate())
+}
+
+func TestConfig_ReadErrorPolicy(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, model.ReadErrorFail, cfg.GetReadErrorPolicy())
+
+	require.NoError(t, cfg.Set("read_error_policy", "skip"))
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, model.ReadErrorSkip, cfg.GetReadErrorPolicy())
+
+	require.NoError(t, cfg.Set("read_error_policy", "ignore"))
+	assert.Error(t, cfg.validate())
+}
+
+func TestConfig_HashAlgorithm(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, model.HashSHA256, cfg.GetHashAlgorithm())
+
+	require.NoError(t, cfg.Set("hash_algorithm", "blake3"))
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, model.HashBLAKE3, cfg.GetHashAlgorithm())
+
+	require.NoError(t, cfg.Set("hash_algorithm", "md5"))
+	assert.Error(t, cfg.validate())
+}
+
+func TestConfig_Encryption(t *testing.T) {
+	cfg := Default()
+	assert.False(t, cfg.Encryption.Enabled())
+
+	cfg.Encryption = &Encryption{Recipients: []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"}}
+	require.NoError(t, cfg.validate())
+	assert.True(t, cfg.Encryption.Enabled())
+
+	cfg.Encryption.Recipients = append(cfg.Encryption.Recipients, "ssh-rsa AAAA")
+	assert.Error(t, cfg.validate())
+}
+
+func TestLoadRetentionPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "retention.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("keep: 5\nwithin: 48h\nbudgets:\n  - tag: auto\n    max_count: 3\n"), 0644))
+	policy, err := LoadRetentionPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, policy.KeepMinSnapshots)
+	assert.Equal(t, 48*time.Hour, policy.KeepMinAge)
+	assert.Equal(t, []model.TagBudget{{Tag: "auto", MaxCount: 3}}, policy.Budgets)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"keep": 2}`), 0644))
+	policy, err = LoadRetentionPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, policy.KeepMinSnapshots)
+
+	require.NoError(t, os.WriteFile(path, []byte("within: 7d\n"), 0644))
+	policy, err = LoadRetentionPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, policy.KeepMinAge)
+
+	require.NoError(t, os.WriteFile(path, []byte("within: soon\n"), 0644))
+	_, err = LoadRetentionPolicy(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("budgets:\n  - max_count: 3\n"), 0644))
+	_, err = LoadRetentionPolicy(path)
+	assert.Error(t, err)
+
+	_, err = LoadRetentionPolicy(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoadRepoRetentionPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".jvs"), 0755))
+
+	policy, err := LoadRepoRetentionPolicy(dir)
+	require.NoError(t, err)
+	assert.Equal(t, model.DefaultRetentionPolicy(), policy)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte("retention:\n  keep: 3\n  keep_tags: [release]\n"), 0644))
+	InvalidateCache(dir)
+	policy, err = LoadRepoRetentionPolicy(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, policy.KeepMinSnapshots)
+	assert.Equal(t, []string{"release"}, policy.KeepTags)
+
+	// retention.json takes precedence over config.yaml
+	require.NoError(t, os.WriteFile(RetentionPolicyPath(dir), []byte(`{"within": "72h", "keep_tags": ["gold"]}`), 0644))
+	policy, err = LoadRepoRetentionPolicy(dir)
+	require.NoError(t, err)
+	assert.Zero(t, policy.KeepMinSnapshots)
+	assert.Equal(t, 72*time.Hour, policy.KeepMinAge)
+	assert.Equal(t, []string{"gold"}, policy.KeepTags)
+
+	require.NoError(t, os.WriteFile(RetentionPolicyPath(dir), []byte(`{"keep_tags": [""]}`), 0644))
+	_, err = LoadRepoRetentionPolicy(dir)
+	assert.Error(t, err)
+}
+
+func TestGetRetentionPolicy_TagRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "retention.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("tag_rules:\n  - pattern: release-*\n  - pattern: auto\n    keep_last: 10\n"), 0644))
+	policy, err := LoadRetentionPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TagRule{{Pattern: "release-*"}, {Pattern: "auto", KeepLast: 10}}, policy.TagRules)
+
+	require.NoError(t, os.WriteFile(path, []byte("tag_rules:\n  - pattern: \"[\"\n"), 0644))
+	_, err = LoadRetentionPolicy(path)
+	assert.Error(t, err)
+}
+
+func TestGetRetentionPolicy_Worktrees(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "retention.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("keep: 50\nworktrees:\n  agent-1:\n    keep: 5\n  scratch:\n    within: 1h\n  archive:\n    within: 7d\n"), 0644))
+	policy, err := LoadRetentionPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, 50, policy.KeepMinSnapshots)
+	assert.Equal(t, map[string]model.WorktreeRetention{
+		"agent-1": {KeepMinSnapshots: 5},
+		"scratch": {KeepMinAge: time.Hour},
+		"archive": {KeepMinAge: 7 * 24 * time.Hour},
+	}, policy.Worktrees)
+
+	require.NoError(t, os.WriteFile(path, []byte("worktrees:\n  scratch:\n    within: soon\n"), 0644))
+	_, err = LoadRetentionPolicy(path)
+	assert.Error(t, err)
+}
+
+func TestConfig_LockMaxWait(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, DefaultLockMaxWait, cfg.GetLockMaxWait())
+
+	require.NoError(t, cfg.Set("lock_max_wait", "30s"))
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, 30*time.Second, cfg.GetLockMaxWait())
+
+	require.NoError(t, cfg.Set("lock_max_wait", "0s"))
+	require.NoError(t, cfg.validate())
+	assert.Zero(t, cfg.GetLockMaxWait())
+
+	for _, bad := range []string{"soon", "-1m"} {
+		require.NoError(t, cfg.Set("lock_max_wait", bad))
+		assert.Error(t, cfg.validate(), bad)
+	}
+}
+
+func TestConfig_LockLeaseTTL(t *testing.T) {
+	cfg := Default()
+	assert.Zero(t, cfg.GetLockLeaseTTL())
+
+	require.NoError(t, cfg.Set("lock_lease_ttl", "30s"))
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, 30*time.Second, cfg.GetLockLeaseTTL())
+
+	for _, bad := range []string{"soon", "-1m"} {
+		require.NoError(t, cfg.Set("lock_lease_ttl", bad))
+		assert.Error(t, cfg.validate(), bad)
+	}
+}
+
+func TestConfig_ContentStore(t *testing.T) {
+	cfg := Default()
+	value, err := cfg.Get("content_store")
+	require.NoError(t, err)
+	assert.Equal(t, "false", value)
+
+	require.NoError(t, cfg.Set("content_store", "true"))
+	assert.True(t, cfg.ContentStore)
+	assert.True(t, deepCopy(cfg).ContentStore)
+	assert.Error(t, cfg.Set("content_store", "yes"))
+}
+
+func TestConfig_Staleness(t *testing.T) {
+	cfg := Default()
+	assert.Zero(t, cfg.GetStaleness())
+
+	cfg.Staleness = &Staleness{MaxAge: "24h", Worktrees: map[string]string{"scratch": "0s", "ci": "2h"}}
+	require.NoError(t, cfg.validate())
+	policy := cfg.GetStaleness()
+	assert.Equal(t, 24*time.Hour, policy.MaxAgeFor("main"))
+	assert.Equal(t, 2*time.Hour, policy.MaxAgeFor("ci"))
+	assert.Zero(t, policy.MaxAgeFor("scratch"))
+
+	cp := deepCopy(cfg)
+	cp.Staleness.Worktrees["ci"] = "1h"
+	assert.Equal(t, "2h", cfg.Staleness.Worktrees["ci"])
+
+	cfg.Staleness = &Staleness{MaxAge: "7d", Worktrees: map[string]string{"archive": "30d"}}
+	require.NoError(t, cfg.validate())
+	policy = cfg.GetStaleness()
+	assert.Equal(t, 7*24*time.Hour, policy.MaxAgeFor("main"))
+	assert.Equal(t, 30*24*time.Hour, policy.MaxAgeFor("archive"))
+
+	cfg.Staleness.Worktrees["ci"] = "daily"
+	assert.Error(t, cfg.validate())
+	cfg.Staleness = &Staleness{MaxAge: "-1h"}
+	assert.Error(t, cfg.validate())
+}
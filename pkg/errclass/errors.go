@@ -1,6 +1,9 @@
 package errclass
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 // JVSError is a stable, machine-readable error class for JVS operations.
 // It implements the error interface and supports error comparison via Is().
@@ -16,9 +19,14 @@ func (e *JVSError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Is reports whether target is e's error class, or a group of classes such
+// as ErrIntegrityFailure that includes it.
 func (e *JVSError) Is(target error) bool {
 	t, ok := target.(*JVSError)
-	return ok && e.Code == t.Code
+	if !ok {
+		return false
+	}
+	return e.Code == t.Code || slices.Contains(groups[t.Code], e.Code)
 }
 
 // WithMessage returns a new JVSError with the same Code but a specific message.
@@ -42,4 +50,33 @@ var (
 	ErrGCPlanMismatch      = &JVSError{Code: "E_GC_PLAN_MISMATCH"}
 	ErrFormatUnsupported   = &JVSError{Code: "E_FORMAT_UNSUPPORTED"}
 	ErrAuditChainBroken    = &JVSError{Code: "E_AUDIT_CHAIN_BROKEN"}
+	ErrWorktreeBusy        = &JVSError{Code: "E_WORKTREE_BUSY"}
+	ErrSnapshotNotReady    = &JVSError{Code: "E_SNAPSHOT_NOT_READY"}
+	ErrWorktreeFrozen      = &JVSError{Code: "E_WORKTREE_FROZEN"}
+	ErrLockTimeout         = &JVSError{Code: "E_LOCK_TIMEOUT"}
+	ErrStorage             = &JVSError{Code: "E_STORAGE"}
+	ErrQuotaExceeded       = &JVSError{Code: "E_QUOTA_EXCEEDED"}
+	ErrWorktreeLocked      = &JVSError{Code: "E_WORKTREE_LOCKED"}
+	ErrLockLost            = &JVSError{Code: "E_LOCK_LOST"}
+	ErrNotARepo            = &JVSError{Code: "E_NOT_A_REPO"}
+	ErrSnapshotNotFound    = &JVSError{Code: "E_SNAPSHOT_NOT_FOUND"}
+	ErrWorktreeNotFound    = &JVSError{Code: "E_WORKTREE_NOT_FOUND"}
+	ErrDetachedHead        = &JVSError{Code: "E_DETACHED_HEAD"}
+	ErrHookFailed          = &JVSError{Code: "E_HOOK_FAILED"}
 )
+
+// ErrIntegrityFailure is matched by errors of every class reporting
+// repository data that failed an integrity check. It groups those classes
+// rather than being one: errors never carry its code.
+var ErrIntegrityFailure = &JVSError{Code: "E_INTEGRITY_FAILURE"}
+
+// groups lists the classes each group error matches.
+var groups = map[string][]string{
+	ErrIntegrityFailure.Code: {
+		ErrDescriptorCorrupt.Code,
+		ErrPayloadHashMismatch.Code,
+		ErrLineageBroken.Code,
+		ErrPartialSnapshot.Code,
+		ErrAuditChainBroken.Code,
+	},
+}
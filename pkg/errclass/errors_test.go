@@ -2,6 +2,7 @@ package errclass_test
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/jvs-project/jvs/pkg/errclass"
@@ -20,6 +21,22 @@ func TestJVSError_Is(t *testing.T) {
 	require.False(t, errors.Is(err, errclass.ErrPathEscape))
 }
 
+func TestJVSError_IsGroup(t *testing.T) {
+	for _, class := range []*errclass.JVSError{
+		errclass.ErrDescriptorCorrupt,
+		errclass.ErrPayloadHashMismatch,
+		errclass.ErrLineageBroken,
+		errclass.ErrPartialSnapshot,
+		errclass.ErrAuditChainBroken,
+	} {
+		err := fmt.Errorf("verify: %w", class.WithMessage("tampered"))
+		assert.True(t, errors.Is(err, errclass.ErrIntegrityFailure), class.Code)
+		// A group does not match the classes in it the other way round
+		assert.False(t, errors.Is(errclass.ErrIntegrityFailure, class), class.Code)
+	}
+	assert.False(t, errors.Is(errclass.ErrSnapshotNotFound, errclass.ErrIntegrityFailure))
+}
+
 func TestJVSError_Code(t *testing.T) {
 	assert.Equal(t, "E_NAME_INVALID", errclass.ErrNameInvalid.Code)
 	assert.Equal(t, "E_PATH_ESCAPE", errclass.ErrPathEscape.Code)
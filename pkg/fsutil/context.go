@@ -0,0 +1,50 @@
+package fsutil
+
+import (
+	"context"
+	"io"
+)
+
+// ContextReader returns a reader that reads from r until ctx is done and
+// then fails with ctx's error, so that copying or hashing a large file stops
+// promptly on cancellation. A context that is never done returns r itself,
+// keeping io.Copy's fast paths.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return r
+	}
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// ContextWriter returns a writer that writes to w until ctx is done and then
+// fails with ctx's error, as ContextReader does for reads.
+func ContextWriter(ctx context.Context, w io.Writer) io.Writer {
+	if ctx.Done() == nil {
+		return w
+	}
+	return &contextWriter{ctx: ctx, w: w}
+}
+
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
@@ -0,0 +1,43 @@
+package fsutil_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextReader(t *testing.T) {
+	r := strings.NewReader("payload")
+	assert.Same(t, r, fsutil.ContextReader(context.Background(), r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cr := fsutil.ContextReader(ctx, strings.NewReader("payload"))
+	buf := make([]byte, 3)
+	n, err := cr.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pay", string(buf[:n]))
+
+	cancel()
+	_, err = io.ReadAll(cr)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestContextWriter(t *testing.T) {
+	var buf strings.Builder
+	assert.Same(t, &buf, fsutil.ContextWriter(context.Background(), &buf))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cw := fsutil.ContextWriter(ctx, &buf)
+	_, err := io.WriteString(cw, "pay")
+	require.NoError(t, err)
+
+	cancel()
+	_, err = io.WriteString(cw, "load")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "pay", buf.String())
+}
@@ -0,0 +1,137 @@
+package fsutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+// RetryPolicy bounds the retries of a storage operation.
+type RetryPolicy struct {
+	// Attempts is the total number of tries, including the first.
+	Attempts int
+	// BaseDelay is the backoff before the first retry; it doubles for
+	// each further retry, up to MaxDelay. Each delay is jittered to a
+	// random duration between half and all of it.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy rides out a JuiceFS mount reconnecting, which takes
+// well under a second, without stalling on a mount that is gone.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 5, BaseDelay: 20 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+
+// retryLimit caps retries across the process, so a reconnecting mount does
+// not turn every pending write into a burst of immediate retries.
+var retryLimit = &tokenBucket{rate: 20, burst: 20, tokens: 20}
+
+// sleep is replaced in tests.
+var sleep = time.Sleep
+
+// StorageError is returned when a storage operation still fails with a
+// transient error after all retries. It matches errclass.ErrStorage with
+// errors.Is and unwraps to the last error, so the errno stays visible:
+// errors.Is(err, syscall.ESTALE) holds for a stale file handle.
+type StorageError struct {
+	Op       string
+	Path     string
+	Attempts int
+	Err      error
+}
+
+func (e *StorageError) Error() string {
+	return fmt.Sprintf("%s: %s %s failed after %d attempts: %v", errclass.ErrStorage.Code, e.Op, e.Path, e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is errclass.ErrStorage.
+func (e *StorageError) Is(target error) bool {
+	return errors.Is(errclass.ErrStorage, target)
+}
+
+// IsTransient reports whether err is a storage error worth retrying: a
+// stale file handle, which network filesystems such as JuiceFS return while
+// their mount reconnects.
+func IsTransient(err error) bool {
+	return errors.Is(err, syscall.ESTALE)
+}
+
+// Retry runs fn, retrying it with jittered exponential backoff while it
+// fails with a transient error. Other errors are returned as they are. If
+// the last attempt still fails transiently, Retry returns a *StorageError
+// naming op and path. fn must be safe to repeat.
+func Retry(policy RetryPolicy, op, path string, fn func() error) error {
+	attempts := max(policy.Attempts, 1)
+	delay := policy.BaseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt == attempts {
+			return &StorageError{Op: op, Path: path, Attempts: attempts, Err: err}
+		}
+		retryLimit.wait()
+		if delay > 0 {
+			sleep(delay/2 + rand.N(delay/2+1))
+		}
+		delay = min(2*delay, policy.MaxDelay)
+	}
+}
+
+// WriteFileRetry is AtomicWrite with retries on transient errors, for
+// descriptor, config and pin writes. Before each retry it re-reads path: a
+// rename that took effect before its reply was lost leaves the new content
+// in place, and the write counts as done.
+func WriteFileRetry(path string, data []byte, perm os.FileMode) error {
+	first := true
+	return Retry(DefaultRetryPolicy, "write", path, func() error {
+		if !first {
+			if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+				return nil
+			}
+		}
+		first = false
+		return atomicWrite(path, data, perm)
+	})
+}
+
+// atomicWrite is replaced in tests.
+var atomicWrite = AtomicWrite
+
+// tokenBucket is a minimal rate limiter: wait takes a token, sleeping until
+// one is available.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) wait() {
+	b.mu.Lock()
+	now := time.Now()
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	// Going negative reserves a future token for this caller
+	b.tokens--
+	deficit := -b.tokens
+	b.mu.Unlock()
+	if deficit > 0 {
+		sleep(time.Duration(deficit / b.rate * float64(time.Second)))
+	}
+}
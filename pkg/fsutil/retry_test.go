@@ -0,0 +1,111 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+func noSleep(t *testing.T) {
+	orig := sleep
+	sleep = func(time.Duration) {}
+	t.Cleanup(func() { sleep = orig })
+}
+
+func staleErr(path string) error {
+	return &os.PathError{Op: "rename", Path: path, Err: syscall.ESTALE}
+}
+
+func TestRetry_RecoversFromStaleHandle(t *testing.T) {
+	noSleep(t)
+	calls := 0
+	err := Retry(DefaultRetryPolicy, "write", "x", func() error {
+		calls++
+		if calls < 3 {
+			return staleErr("x")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_OtherErrorsNotRetried(t *testing.T) {
+	noSleep(t)
+	calls := 0
+	err := Retry(DefaultRetryPolicy, "write", "x", func() error {
+		calls++
+		return os.ErrPermission
+	})
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_ExhaustedReturnsStorageError(t *testing.T) {
+	noSleep(t)
+	calls := 0
+	err := Retry(RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, "write", "x", func() error {
+		calls++
+		return staleErr("x")
+	})
+	assert.Equal(t, 3, calls)
+	assert.ErrorIs(t, err, errclass.ErrStorage)
+	assert.ErrorIs(t, err, syscall.ESTALE)
+	var se *StorageError
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, "x", se.Path)
+	assert.Equal(t, 3, se.Attempts)
+	assert.Contains(t, err.Error(), "E_STORAGE")
+}
+
+func TestWriteFileRetry_LostReplyCountsAsDone(t *testing.T) {
+	noSleep(t)
+	orig := atomicWrite
+	t.Cleanup(func() { atomicWrite = orig })
+	calls := 0
+	// The write lands but its reply is lost
+	atomicWrite = func(path string, data []byte, perm os.FileMode) error {
+		calls++
+		if err := orig(path, data, perm); err != nil {
+			return err
+		}
+		return staleErr(path)
+	}
+
+	path := filepath.Join(t.TempDir(), "desc.json")
+	require.NoError(t, WriteFileRetry(path, []byte("new"), 0644))
+	assert.Equal(t, 1, calls)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestWriteFileRetry_RetriesWhenContentDiffers(t *testing.T) {
+	noSleep(t)
+	orig := atomicWrite
+	t.Cleanup(func() { atomicWrite = orig })
+	calls := 0
+	atomicWrite = func(path string, data []byte, perm os.FileMode) error {
+		calls++
+		if calls == 1 {
+			return staleErr(path)
+		}
+		return orig(path, data, perm)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+	require.NoError(t, WriteFileRetry(path, []byte("new"), 0644))
+	assert.Equal(t, 2, calls)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+}
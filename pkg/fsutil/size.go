@@ -0,0 +1,22 @@
+package fsutil
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// DirSize returns the total size of regular files below dir. Entries that
+// cannot be read are left out.
+func DirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,22 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("world!"), 0644))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+
+	assert.Equal(t, int64(11), fsutil.DirSize(dir))
+	assert.Zero(t, fsutil.DirSize(filepath.Join(dir, "missing")))
+}
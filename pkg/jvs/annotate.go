@@ -0,0 +1,26 @@
+package jvs
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// AnnotateOptions configures Annotate.
+type AnnotateOptions struct {
+	// Append adds the note after the existing one, separated by "; ",
+	// instead of replacing it.
+	Append bool
+}
+
+// Annotate replaces or appends to the note of a published snapshot, for
+// context learned after it was taken, and returns its updated descriptor.
+// Like AddTags, it rewrites the descriptor atomically, records the change
+// in the snapshot's metadata history and audits it as snapshot_amend.
+func (c *Client) Annotate(ctx context.Context, snapshotID model.SnapshotID, note string, opts AnnotateOptions) (*model.Descriptor, error) {
+	ctx = c.beginCall(ctx, "annotate")
+	desc, err := snapshot.Annotate(ctx, c.repoRoot, snapshotID, "", note, opts.Append)
+	c.invalidateDescriptor(snapshotID)
+	return desc, err
+}
@@ -0,0 +1,40 @@
+package jvs
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/audit"
+)
+
+// AuditBatchOptions configures audit batching; see EnableAuditBatching.
+type AuditBatchOptions = audit.BatchOptions
+
+// AuditBatch buffers audit records for a repository. Close it to flush the
+// remaining records and return to unbuffered writes.
+type AuditBatch = audit.BatchWriter
+
+// EnableAuditBatching buffers the repository's audit records in this process
+// and appends them in batches, for high-frequency automation where an fsync
+// of the audit log per operation dominates. Records are written to a
+// write-ahead file first and replayed after a crash; set
+// AuditBatchOptions.SyncWAL to also survive power loss.
+//
+// Only one batch may be active per repository in a process. Close the
+// returned AuditBatch before the process exits.
+func (c *Client) EnableAuditBatching(opts AuditBatchOptions) (*AuditBatch, error) {
+	return audit.EnableBatching(audit.LogPath(c.repoRoot), opts)
+}
+
+// AuditVerifyResult reports the state of the audit log's hash chain; see
+// VerifyAudit.
+type AuditVerifyResult = audit.VerifyResult
+
+// VerifyAudit checks the repository's audit log for edited, removed,
+// inserted or reordered records, and for records removed from its end. The
+// result's Problems list what was found; an error means the log could not
+// be read.
+func (c *Client) VerifyAudit(ctx context.Context) (_ *AuditVerifyResult, err error) {
+	_, span := c.startSpan(ctx, "verify_audit")
+	defer func() { endSpan(span, err) }()
+	return audit.Verify(audit.LogPath(c.repoRoot))
+}
@@ -0,0 +1,105 @@
+package jvs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// Recipients are age X25519 public keys ("age1..."). If set, the bundle
+	// is encrypted so that any one of the matching identities can import it.
+	Recipients []string
+	// Compress zstd-compresses the bundle, before any encryption.
+	Compress bool
+}
+
+// ExportResult describes an exported bundle.
+type ExportResult = bundle.Result
+
+// Export writes a snapshot to w as a bundle, as 'jvs export' does, so it
+// can be kept outside the repository and brought back with Import. The
+// snapshot is verified first. The bundle is streamed: nothing is staged on
+// disk, and w may be an upload to blob storage. If ctx is done, writing
+// stops with its error, leaving a truncated bundle that Import rejects.
+func (c *Client) Export(ctx context.Context, w io.Writer, snapshotID model.SnapshotID, opts ExportOptions) (*ExportResult, error) {
+	ctx = c.beginCall(ctx, "export")
+	return bundle.Write(fsutil.ContextWriter(ctx, w), c.repoRoot, snapshotID, bundle.Options{
+		Recipients: opts.Recipients,
+		Compress:   opts.Compress,
+	})
+}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// Identities are age X25519 secret keys ("AGE-SECRET-KEY-1...") for an
+	// encrypted bundle. Unencrypted bundles need none.
+	Identities []string
+	// Worktree, if set, names a new worktree to create from the imported
+	// snapshot, at HEAD state as with Fork.
+	Worktree string
+}
+
+// ImportResult describes an imported bundle.
+type ImportResult struct {
+	Descriptor *model.Descriptor `json:"descriptor"`
+	Files      int               `json:"files"`
+	Bytes      int64             `json:"bytes"`
+	Compressed bool              `json:"compressed"`
+	Encrypted  bool              `json:"encrypted"`
+	// Worktree is set if ImportOptions.Worktree was.
+	Worktree *model.WorktreeConfig `json:"worktree,omitempty"`
+}
+
+// Import adds the snapshot in a bundle written by Export or 'jvs export' to the
+// repository, under the snapshot ID it was exported with. Compressed and
+// encrypted bundles are detected from their content. The descriptor
+// checksum and every payload file are checked before the snapshot is
+// published, so a damaged bundle leaves the repository unchanged. Importing
+// a snapshot ID that already exists fails.
+func (c *Client) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	ctx = c.beginCall(ctx, "import")
+	identities, err := bundle.ParseIdentities(opts.Identities)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Worktree != "" {
+		if err := pathutil.ValidateName(opts.Worktree); err != nil {
+			return nil, err
+		}
+		if _, err := worktree.NewManager(c.repoRoot).Get(opts.Worktree); err == nil {
+			return nil, fmt.Errorf("worktree %s already exists", opts.Worktree)
+		}
+	}
+
+	imported, err := bundle.Import(fsutil.ContextReader(ctx, r), c.repoRoot, bundle.ImportOptions{
+		Identities:    identities,
+		CorrelationID: CorrelationID(ctx),
+		Author:        c.identity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportResult{
+		Descriptor: imported.Descriptor,
+		Files:      imported.Files,
+		Bytes:      imported.Bytes,
+		Compressed: imported.Compressed,
+		Encrypted:  imported.Encrypted,
+	}
+	if opts.Worktree != "" {
+		cfg, err := c.fork(ctx, result.Descriptor.SnapshotID, opts.Worktree)
+		if err != nil {
+			return result, fmt.Errorf("imported snapshot %s, but could not create worktree: %w", result.Descriptor.SnapshotID, err)
+		}
+		result.Worktree = cfg
+	}
+	return result, nil
+}
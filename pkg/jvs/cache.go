@@ -0,0 +1,169 @@
+package jvs
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jvs-project/jvs/internal/metacache"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// DefaultMetadataCacheSize is the number of descriptors and of worktree
+// configs a metadata cache holds when EnableMetadataCache is given zero.
+const DefaultMetadataCacheSize = 1024
+
+// CacheCounters counts the activity of one metadata cache.
+type CacheCounters = metacache.Stats
+
+// CacheStats reports metadata cache activity since it was enabled.
+type CacheStats struct {
+	Enabled     bool          `json:"enabled"`
+	Descriptors CacheCounters `json:"descriptors"`
+	Worktrees   CacheCounters `json:"worktrees"`
+}
+
+type metadataCache struct {
+	descriptors *metacache.Cache[model.SnapshotID, *model.Descriptor]
+	worktrees   *metacache.Cache[string, *model.WorktreeConfig]
+}
+
+// EnableMetadataCache caches descriptor and worktree config reads in this
+// Client, holding up to size entries of each (zero uses
+// DefaultMetadataCacheSize). Concurrent reads of the same entry share one
+// load. Mutating Client calls invalidate what they change; changes made by
+// other processes or Clients are not seen until InvalidateMetadataCache is
+// called, so enable the cache only where this Client is the repository's
+// writer. Enabling it again replaces the cache and resets its counters.
+func (c *Client) EnableMetadataCache(size int) {
+	if size <= 0 {
+		size = DefaultMetadataCacheSize
+	}
+	c.cache.Store(&metadataCache{
+		descriptors: metacache.New[model.SnapshotID, *model.Descriptor](size),
+		worktrees:   metacache.New[string, *model.WorktreeConfig](size),
+	})
+}
+
+// InvalidateMetadataCache drops every cached entry, for example after another
+// process changed the repository. Counters are kept.
+func (c *Client) InvalidateMetadataCache() {
+	if mc := c.cache.Load(); mc != nil {
+		mc.descriptors.Purge()
+		mc.worktrees.Purge()
+	}
+}
+
+// CacheStats returns the metadata cache counters.
+func (c *Client) CacheStats() CacheStats {
+	mc := c.cache.Load()
+	if mc == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Enabled:     true,
+		Descriptors: mc.descriptors.Stats(),
+		Worktrees:   mc.worktrees.Stats(),
+	}
+}
+
+// Descriptor returns the descriptor of a snapshot.
+func (c *Client) Descriptor(_ context.Context, snapshotID model.SnapshotID) (*model.Descriptor, error) {
+	return c.loadDescriptor(snapshotID)
+}
+
+// Worktree returns the config of a worktree.
+func (c *Client) Worktree(_ context.Context, worktreeName string) (*model.WorktreeConfig, error) {
+	if worktreeName == "" {
+		worktreeName = "main"
+	}
+	cfg, err := c.loadWorktree(worktreeName)
+	if err != nil {
+		return nil, fmt.Errorf("get worktree: %w", err)
+	}
+	return cfg, nil
+}
+
+// loadDescriptor reads a descriptor through the cache, if enabled. Callers
+// get their own copy.
+func (c *Client) loadDescriptor(id model.SnapshotID) (*model.Descriptor, error) {
+	mc := c.cache.Load()
+	if mc == nil {
+		return snapshot.LoadDescriptor(c.repoRoot, id)
+	}
+	desc, err := mc.descriptors.Get(id, func() (*model.Descriptor, error) {
+		return snapshot.LoadDescriptor(c.repoRoot, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cloneDescriptor(desc), nil
+}
+
+// loadWorktree reads a worktree config through the cache, if enabled.
+// Callers get their own copy.
+func (c *Client) loadWorktree(name string) (*model.WorktreeConfig, error) {
+	mc := c.cache.Load()
+	if mc == nil {
+		return worktree.NewManager(c.repoRoot).Get(name)
+	}
+	cfg, err := mc.worktrees.Get(name, func() (*model.WorktreeConfig, error) {
+		return worktree.NewManager(c.repoRoot).Get(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cp := *cfg
+	return &cp, nil
+}
+
+// invalidateWorktree drops a worktree config changed by this Client.
+func (c *Client) invalidateWorktree(name string) {
+	if mc := c.cache.Load(); mc != nil {
+		mc.worktrees.Invalidate(name)
+	}
+}
+
+// invalidateDescriptors drops every cached descriptor after GC deleted
+// snapshots.
+func (c *Client) invalidateDescriptors() {
+	if mc := c.cache.Load(); mc != nil {
+		mc.descriptors.Purge()
+	}
+}
+
+func cloneDescriptor(d *model.Descriptor) *model.Descriptor {
+	cp := *d
+	if d.ParentID != nil {
+		parent := *d.ParentID
+		cp.ParentID = &parent
+	}
+	if d.Compression != nil {
+		comp := *d.Compression
+		cp.Compression = &comp
+	}
+	if d.Encryption != nil {
+		enc := *d.Encryption
+		enc.Recipients = slices.Clone(d.Encryption.Recipients)
+		cp.Encryption = &enc
+	}
+	if d.Stats != nil {
+		stats := *d.Stats
+		stats.Timings = slices.Clone(d.Stats.Timings)
+		cp.Stats = &stats
+	}
+	cp.Tags = slices.Clone(d.Tags)
+	cp.PartialPaths = slices.Clone(d.PartialPaths)
+	cp.DereferencedPaths = slices.Clone(d.DereferencedPaths)
+	cp.ReadErrors = slices.Clone(d.ReadErrors)
+	cp.Annotations = maps.Clone(d.Annotations)
+	if d.Environment != nil {
+		env := *d.Environment
+		env.Vars = maps.Clone(d.Environment.Vars)
+		cp.Environment = &env
+	}
+	return &cp
+}
@@ -5,15 +5,29 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/jvs-project/jvs/internal/engine"
 	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/integrity"
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/metrics"
+	"github.com/jvs-project/jvs/internal/pin"
 	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/resolver"
 	"github.com/jvs-project/jvs/internal/restore"
 	"github.com/jvs-project/jvs/internal/snapshot"
 	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/logging"
 	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/progress"
 )
 
 // Client provides high-level JVS operations on a repository.
@@ -21,12 +35,33 @@ type Client struct {
 	repoRoot   string
 	repoID     string
 	engineType model.EngineType
+	logger     *logging.Logger // nil logs to the global logger
+	identity   string          // empty uses identity.Author
+	now        func() time.Time
+
+	materializeTTL time.Duration  // zero uses DefaultMaterializeTTL
+	lockWait       *time.Duration // nil uses the repository's lock_max_wait
+
+	registry       *prometheus.Registry
+	metrics        *metrics.Metrics
+	tracerProvider trace.TracerProvider // nil uses the global provider
+	hooks          []Hooks
+
+	cache  atomic.Pointer[metadataCache] // nil unless EnableMetadataCache was called
+	events EventBus
+
+	resolver *resolver.Resolver
 }
 
 // InitOptions configures repository initialization.
 type InitOptions struct {
 	Name       string           // Repository name (validated: alphanumeric, hyphens, underscores)
 	EngineType model.EngineType // Snapshot engine; empty string triggers auto-detection
+
+	// HashAlgorithm is the payload hash algorithm of new snapshots, stored
+	// in the repository config; empty uses model.HashSHA256.
+	// model.HashBLAKE3 hashes large payloads much faster.
+	HashAlgorithm model.HashAlgorithm
 }
 
 // SnapshotOptions configures snapshot creation.
@@ -35,19 +70,135 @@ type SnapshotOptions struct {
 	Note         string   // Human-readable description
 	Tags         []string // Organization tags
 	PartialPaths []string // Specific paths to snapshot; nil/empty means full snapshot
+
+	// Annotations are key/value metadata stored in the descriptor, e.g.
+	// "ci.notify": "true" to trigger webhooks from 'jvs serve'.
+	Annotations map[string]string
+
+	// SkipIfUnchanged returns the worktree's HEAD descriptor with Skipped set
+	// instead of creating a snapshot when the payload is identical to HEAD.
+	// Ignored for partial snapshots.
+	SkipIfUnchanged bool
+
+	// Incremental hard-links files that are unchanged since HEAD from the
+	// HEAD snapshot instead of copying them. Ignored for partial snapshots,
+	// frozen worktrees and compressed HEAD snapshots.
+	Incremental bool
+
+	// CaptureEnvironment records the hostname, container image digest (from
+	// JVS_IMAGE_DIGEST), jvs version and the EnvironmentVars that are set
+	// in the descriptor's Environment section.
+	CaptureEnvironment bool
+	// EnvironmentVars lists the variables to record, as names or glob
+	// patterns such as "AGENT_*".
+	EnvironmentVars []string
+
+	// ReadErrorPolicy selects what to do with files that cannot be read:
+	// model.ReadErrorFail (the default) fails the snapshot, and
+	// model.ReadErrorSkip leaves them out and lists them in the
+	// descriptor's ReadErrors. Ignored for partial snapshots.
+	ReadErrorPolicy model.ReadErrorPolicy
+
+	// Exclude lists extra patterns, in .jvsignore syntax, for paths to leave
+	// out of the snapshot. They apply after the worktree's .jvsignore file,
+	// so "!pattern" can re-include a path it excludes. Ignored for partial
+	// snapshots.
+	Exclude []string
+
+	// FollowSymlinks replaces symlinks pointing outside the worktree with copies
+	// of their targets so the snapshot is self-contained. Off by default.
+	FollowSymlinks bool
+	// MaxDereferenceBytes caps data copied through dereferenced symlinks;
+	// zero uses the default limit (10 GiB).
+	MaxDereferenceBytes int64
+
+	// TwoPassHash hashes the payload in a separate pass after cloning instead of
+	// while copying. Intended for benchmarking and comparison.
+	TwoPassHash bool
+
+	// Progress, if set, is called as the snapshot advances: phase "copy"
+	// while the payload is cloned and, if it is hashed in a pass of its
+	// own, phase "hash".
+	Progress ProgressFunc
 }
 
+// ProgressFunc receives the progress of a phase of a long operation: done
+// of total bytes of regular files. It is called on the goroutine running
+// the operation, at the start and end of each phase and whenever another
+// percent of it is done.
+type ProgressFunc func(phase string, done, total int64)
+
 // RestoreOptions configures snapshot restore.
 type RestoreOptions struct {
 	WorktreeName string // Target worktree; defaults to "main"
 	Target       string // Snapshot ID, tag name, or "HEAD" for latest
+
+	// LatestWithoutTag, if set, restores the worktree's newest snapshot that
+	// does not carry this tag; LatestWithTag the newest one that does. They
+	// replace Target and are mutually exclusive.
+	LatestWithoutTag string
+	LatestWithTag    string
+
+	// Force restores even if another consumer holds a lease on the worktree.
+	// Without it, Restore returns an error matching ErrWorktreeBusy.
+	Force bool
+
+	// Ephemeral replaces the payload content only, e.g. to materialize a
+	// snapshot for a throwaway debugging pod. The worktree's head and
+	// detached state are left untouched, so a later Snapshot records the
+	// restored content as a child of the unchanged head. The restore is
+	// audited as restore_ephemeral.
+	Ephemeral bool
+
+	// Paths, if set, restores only these payload paths, read from the
+	// snapshot's manifest; the rest of the worktree and its head are left
+	// untouched. The restore is audited as restore_paths.
+	Paths []string
+
+	// Progress, if set, is called as the restore advances, with phase
+	// "clone" while the snapshot is copied into the worktree.
+	Progress ProgressFunc
 }
 
 // GCOptions configures garbage collection.
 type GCOptions struct {
+	// Policy, if set, replaces the repository's retention policy, from
+	// .jvs/retention.json or config.yaml, as the policy the retention
+	// fields below override.
+	Policy *model.RetentionPolicy
+
+	// Zero-valued retention fields keep the value of Policy or the
+	// repository's retention policy.
 	KeepMinSnapshots int
 	KeepMinAge       time.Duration
 	DryRun           bool
+
+	// KeepTags protects snapshots carrying one of these tags.
+	KeepTags []string
+
+	// TagRules protect snapshots by tag pattern.
+	TagRules []model.TagRule
+
+	// Worktrees set the age and count retention of single worktrees,
+	// replacing KeepMinSnapshots and KeepMinAge for their snapshots.
+	Worktrees map[string]model.WorktreeRetention
+
+	// Budgets cap size and count per tag; the oldest snapshots of an
+	// over-budget tag are collected even within the retention window.
+	Budgets []model.TagBudget
+
+	// CheckConflicts checks the plan against live operations: in-flight
+	// restores and worktrees with an active lease. With DryRun, conflicts
+	// are reported in the plan's Conflicts; otherwise GC aborts before
+	// deleting anything with an error matching ErrGCPlanMismatch.
+	CheckConflicts bool
+}
+
+// PinOptions configures a pin.
+type PinOptions struct {
+	Reason string
+	// TTL, if positive, makes the pin expire; zero pins until Unpin.
+	TTL time.Duration
 }
 
 func (o *SnapshotOptions) worktree() string {
@@ -65,108 +216,326 @@ func (o *RestoreOptions) worktree() string {
 }
 
 // Init initializes a new JVS repository at the given path.
-func Init(path string, opts InitOptions) (*Client, error) {
+func Init(path string, opts InitOptions, options ...Option) (*Client, error) {
 	name := opts.Name
 	if name == "" {
 		name = filepath.Base(path)
 	}
 
+	if err := integrity.CheckHashAlgorithm(opts.HashAlgorithm); err != nil {
+		return nil, fmt.Errorf("jvs init: %w", err)
+	}
 	r, err := repo.Init(path, name)
 	if err != nil {
 		return nil, fmt.Errorf("jvs init: %w", err)
 	}
-
-	engineType := opts.EngineType
-	if engineType == "" {
-		engineType = detectEngineType(path)
+	if opts.HashAlgorithm != "" {
+		cfg := config.Default()
+		cfg.HashAlgorithm = string(opts.HashAlgorithm)
+		if err := config.Save(r.Root, cfg); err != nil {
+			return nil, fmt.Errorf("jvs init: %w", err)
+		}
 	}
 
-	return &Client{
-		repoRoot:   r.Root,
-		repoID:     r.RepoID,
-		engineType: engineType,
-	}, nil
+	return newClient(r.Root, r.RepoID, opts.EngineType, options), nil
 }
 
-// Open opens an existing JVS repository at or above the given path.
-func Open(path string) (*Client, error) {
+// Open opens an existing JVS repository at or above the given path. It
+// fails with ErrIncompatibleFormat if the repository was created by a jvs
+// whose format this build does not support.
+func Open(path string, options ...Option) (*Client, error) {
 	r, err := repo.Discover(path)
 	if err != nil {
 		return nil, fmt.Errorf("jvs open: %w", err)
 	}
 
-	engineType := detectEngineType(r.Root)
-
-	return &Client{
-		repoRoot:   r.Root,
-		repoID:     r.RepoID,
-		engineType: engineType,
-	}, nil
+	return newClient(r.Root, r.RepoID, "", options), nil
 }
 
 // OpenOrInit opens an existing repository, or initializes a new one if none exists.
-// This is the recommended entry point for sandbox-manager integration.
-func OpenOrInit(path string, opts InitOptions) (*Client, error) {
+// This is the recommended entry point for sandbox-manager integration. Like
+// Open, it fails with ErrIncompatibleFormat on an existing repository of an
+// unsupported format rather than initializing over it.
+func OpenOrInit(path string, opts InitOptions, options ...Option) (*Client, error) {
 	jvsDir := filepath.Join(path, ".jvs")
 	if info, err := os.Stat(jvsDir); err == nil && info.IsDir() {
-		return Open(path)
+		return Open(path, options...)
 	}
-	return Init(path, opts)
+	return Init(path, opts, options...)
 }
 
 // Snapshot creates a new snapshot of the worktree.
 // The worktree must not be in detached state unless PartialPaths is used.
-func (c *Client) Snapshot(_ context.Context, opts SnapshotOptions) (*model.Descriptor, error) {
+func (c *Client) Snapshot(ctx context.Context, opts SnapshotOptions) (_ *model.Descriptor, err error) {
+	ctx = c.beginCall(ctx, "snapshot")
+	ctx, span := c.startSpan(ctx, "snapshot", attrWorktree.String(opts.worktree()))
+	defer func() { endSpan(span, err) }()
+	defer c.invalidateWorktree(opts.worktree())
+	if err := c.beforeSnapshot(ctx, opts); err != nil {
+		return nil, err
+	}
 	creator := snapshot.NewCreator(c.repoRoot, c.engineType)
+	creator.SetPhaseObserver(c.phaseSpans(ctx, "snapshot"))
+	creator.SetContext(ctx)
+	creator.SetCorrelationID(CorrelationID(ctx))
+	creator.SetAuthor(c.identity)
+	if c.lockWait != nil {
+		creator.SetLockWait(*c.lockWait)
+	}
+	if opts.FollowSymlinks {
+		creator.SetFollowSymlinks(true, opts.MaxDereferenceBytes)
+	}
+	creator.SetTwoPassHash(opts.TwoPassHash)
+	creator.SetAnnotations(opts.Annotations)
+	creator.SetSkipIfUnchanged(opts.SkipIfUnchanged)
+	creator.SetIncremental(opts.Incremental)
+	creator.SetExcludes(opts.Exclude)
+	creator.SetCaptureEnvironment(opts.CaptureEnvironment, opts.EnvironmentVars)
+	creator.SetReadErrorPolicy(opts.ReadErrorPolicy)
+	creator.SetProgress(progress.ByteCallback(opts.Progress))
+	desc, err := c.create(creator, opts)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attrSnapshotID.String(string(desc.SnapshotID)), attribute.Bool("jvs.skipped", desc.Skipped))
+	c.observeSnapshot(desc)
+	if !desc.Skipped {
+		c.publish(Event{Type: EventSnapshotCreated, Worktree: opts.worktree(), SnapshotID: desc.SnapshotID, Descriptor: desc})
+		c.publishDegradations("snapshot", opts.worktree(), desc.SnapshotID, creator.Degradations())
+	}
+	c.afterSnapshot(ctx, desc)
+	return desc, nil
+}
+
+func (c *Client) create(creator *snapshot.Creator, opts SnapshotOptions) (*model.Descriptor, error) {
 	if len(opts.PartialPaths) > 0 {
 		return creator.CreatePartial(opts.worktree(), opts.Note, opts.Tags, opts.PartialPaths)
 	}
+	cfg, err := worktree.NewManager(c.repoRoot).Get(opts.worktree())
+	if err != nil {
+		return nil, fmt.Errorf("get worktree: %w", err)
+	}
+	if cfg.IsDetached() {
+		return nil, errclass.ErrDetachedHead.WithMessagef("cannot create snapshot in detached state at %s: restore HEAD or fork first", cfg.HeadSnapshotID)
+	}
 	return creator.Create(opts.worktree(), opts.Note, opts.Tags)
 }
 
 // Restore restores a worktree to a specific snapshot identified by opts.Target.
-// Target can be a snapshot ID prefix, tag name, or "HEAD" for the latest.
-func (c *Client) Restore(_ context.Context, opts RestoreOptions) error {
+// Target can be a snapshot ID prefix, tag name, or "HEAD" for the latest;
+// LatestWithoutTag and LatestWithTag select by tag instead.
+func (c *Client) Restore(ctx context.Context, opts RestoreOptions) (err error) {
+	ctx = c.beginCall(ctx, "restore")
+	ctx, span := c.startSpan(ctx, "restore", attrWorktree.String(opts.worktree()))
+	defer func() { endSpan(span, err) }()
+	return c.restore(ctx, opts)
+}
+
+func (c *Client) restore(ctx context.Context, opts RestoreOptions) error {
 	wt := opts.worktree()
+	defer c.invalidateWorktree(wt)
+
+	if opts.LatestWithoutTag != "" || opts.LatestWithTag != "" {
+		if opts.LatestWithoutTag != "" && opts.LatestWithTag != "" {
+			return fmt.Errorf("LatestWithoutTag and LatestWithTag are mutually exclusive")
+		}
+		var desc *model.Descriptor
+		var err error
+		if opts.LatestWithoutTag != "" {
+			desc, err = snapshot.LatestWithoutTag(c.repoRoot, wt, opts.LatestWithoutTag)
+		} else {
+			desc, err = snapshot.LatestWithTag(c.repoRoot, wt, opts.LatestWithTag)
+		}
+		if err != nil {
+			return fmt.Errorf("resolve target: %w", err)
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attrSnapshotID.String(string(desc.SnapshotID)))
+		restorer := c.restorer(ctx, opts)
+		return c.observeRestore(ctx, wt, desc.SnapshotID, restorer, func() error {
+			return restorer.Restore(wt, desc.SnapshotID)
+		})
+	}
 
 	if opts.Target == "HEAD" || opts.Target == "" {
-		return c.RestoreLatest(context.Background(), wt)
+		return c.restoreLatest(ctx, wt, c.restorer(ctx, opts))
 	}
 
-	// Try as snapshot ID first (exact or prefix match)
-	desc, err := snapshot.FindOne(c.repoRoot, opts.Target)
+	desc, err := c.Resolve(ctx, opts.Target)
 	if err != nil {
-		// Try as tag
-		desc, err = snapshot.FindByTag(c.repoRoot, opts.Target)
-		if err != nil {
-			return fmt.Errorf("resolve target %q: %w", opts.Target, err)
-		}
+		return fmt.Errorf("resolve target: %w", err)
 	}
 
+	trace.SpanFromContext(ctx).SetAttributes(attrSnapshotID.String(string(desc.SnapshotID)))
+	restorer := c.restorer(ctx, opts)
+	return c.observeRestore(ctx, wt, desc.SnapshotID, restorer, func() error {
+		return restorer.Restore(wt, desc.SnapshotID)
+	})
+}
+
+// restorer returns a restorer configured from opts that records its phases
+// as spans below ctx's span.
+func (c *Client) restorer(ctx context.Context, opts RestoreOptions) *restore.Restorer {
 	restorer := restore.NewRestorer(c.repoRoot, c.engineType)
-	return restorer.Restore(wt, desc.SnapshotID)
+	restorer.SetForce(opts.Force)
+	restorer.SetEphemeral(opts.Ephemeral)
+	restorer.SetPaths(opts.Paths)
+	restorer.SetClock(c.now)
+	restorer.SetPhaseObserver(c.phaseSpans(ctx, "restore"))
+	restorer.SetContext(ctx)
+	restorer.SetCorrelationID(CorrelationID(ctx))
+	restorer.SetAuthor(c.identity)
+	restorer.SetProgress(progress.ByteCallback(opts.Progress))
+	if c.lockWait != nil {
+		restorer.SetLockWait(*c.lockWait)
+	}
+	return restorer
 }
 
 // RestoreLatest restores a worktree to its most recent snapshot.
 // Returns nil if the worktree has no snapshots (nothing to restore).
-func (c *Client) RestoreLatest(_ context.Context, worktreeName string) error {
+// Returns an error matching ErrWorktreeBusy if the worktree is leased.
+func (c *Client) RestoreLatest(ctx context.Context, worktreeName string) (err error) {
+	ctx = c.beginCall(ctx, "restore_latest")
+	ctx, span := c.startSpan(ctx, "restore_latest", attrWorktree.String(worktreeName))
+	defer func() { endSpan(span, err) }()
+	return c.restoreLatest(ctx, worktreeName, c.restorer(ctx, RestoreOptions{}))
+}
+
+func (c *Client) restoreLatest(ctx context.Context, worktreeName string, restorer *restore.Restorer) error {
 	if worktreeName == "" {
 		worktreeName = "main"
 	}
+	defer c.invalidateWorktree(worktreeName)
 
-	has, err := c.HasSnapshots(context.Background(), worktreeName)
+	has, err := c.HasSnapshots(ctx, worktreeName)
 	if err != nil {
 		return err
 	}
 	if !has {
 		return nil
 	}
+	cfg, err := worktree.NewManager(c.repoRoot).Get(worktreeName)
+	if err != nil {
+		return fmt.Errorf("get worktree: %w", err)
+	}
 
-	restorer := restore.NewRestorer(c.repoRoot, c.engineType)
-	return restorer.RestoreToLatest(worktreeName)
+	return c.observeRestore(ctx, worktreeName, cfg.LatestSnapshotID, restorer, func() error {
+		return restorer.Restore(worktreeName, cfg.LatestSnapshotID)
+	})
+}
+
+// AcquireLease marks a worktree as attached by holder for ttl. While the lease
+// is active, Restore refuses to replace the payload unless forced.
+// Calling it again with the same holder renews the lease.
+func (c *Client) AcquireLease(ctx context.Context, worktreeName, holder string, ttl time.Duration) (*model.Lease, error) {
+	ctx = c.beginCall(ctx, "acquire_lease")
+	return c.leases(ctx).Acquire(worktreeName, holder, ttl)
+}
+
+// ReleaseLease releases a lease held by holder.
+func (c *Client) ReleaseLease(ctx context.Context, worktreeName, holder string) error {
+	ctx = c.beginCall(ctx, "release_lease")
+	return c.leases(ctx).Release(worktreeName, holder)
+}
+
+// Lease returns the active lease on a worktree, or nil if it is not leased.
+func (c *Client) Lease(ctx context.Context, worktreeName string) (*model.Lease, error) {
+	return c.leases(ctx).Get(worktreeName)
+}
+
+// Pin protects a snapshot from GC, whatever the retention policy, until
+// opts.TTL elapses or it is unpinned. Pinning a pinned snapshot replaces its
+// pin.
+func (c *Client) Pin(ctx context.Context, snapshotID model.SnapshotID, opts PinOptions) (*model.Pin, error) {
+	ctx = c.beginCall(ctx, "pin")
+	m := pin.NewManager(c.repoRoot)
+	m.SetClock(c.now)
+	m.SetCorrelationID(CorrelationID(ctx))
+	m.SetAuthor(c.identity)
+	return m.Pin(snapshotID, opts.Reason, opts.TTL)
+}
+
+// Unpin removes every pin of a snapshot. Unpinning a snapshot without pins
+// succeeds.
+func (c *Client) Unpin(ctx context.Context, snapshotID model.SnapshotID) error {
+	ctx = c.beginCall(ctx, "unpin")
+	m := pin.NewManager(c.repoRoot)
+	m.SetCorrelationID(CorrelationID(ctx))
+	m.SetAuthor(c.identity)
+	_, err := m.Unpin(snapshotID)
+	return err
+}
+
+// ListPins returns all pins, oldest first, including expired ones, which no
+// longer protect their snapshot.
+func (c *Client) ListPins(_ context.Context) ([]*model.Pin, error) {
+	return pin.NewManager(c.repoRoot).List()
 }
 
-// History returns snapshot descriptors for a worktree, sorted newest first.
+// leases returns a lease manager using the client's clock and auditing
+// with its identity and ctx's correlation ID.
+func (c *Client) leases(ctx context.Context) *lease.Manager {
+	m := lease.NewManager(c.repoRoot)
+	m.SetClock(c.now)
+	m.SetCorrelationID(CorrelationID(ctx))
+	m.SetAuthor(c.identity)
+	return m
+}
+
+// Fork creates a new worktree named name with content cloned from snapshotID.
+// The new worktree starts at HEAD state and can snapshot immediately.
+// A fork that would exceed the configured quota fails with a *QuotaError
+// (ErrQuotaExceeded) that suggests snapshots to collect.
+func (c *Client) Fork(ctx context.Context, snapshotID model.SnapshotID, name string) (*model.WorktreeConfig, error) {
+	ctx = c.beginCall(ctx, "fork")
+	return c.fork(ctx, snapshotID, name)
+}
+
+func (c *Client) fork(ctx context.Context, snapshotID model.SnapshotID, name string) (*model.WorktreeConfig, error) {
+	if err := snapshot.VerifySnapshot(c.repoRoot, snapshotID, false); err != nil {
+		return nil, fmt.Errorf("verify snapshot: %w", err)
+	}
+
+	cfg, err := config.Load(c.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.invalidateWorktree(name)
+	eng := engine.NewEngine(c.engineType)
+	mgr := worktree.NewManager(c.repoRoot)
+	mgr.SetQuota(cfg.GetQuota(), func() ([]model.SnapshotUsage, error) {
+		return gc.NewCollector(c.repoRoot).LargestCandidates(cfg.GetRetentionPolicy(), worktree.MaxQuotaCandidates)
+	})
+	wtCfg, err := mgr.Fork(snapshotID, name, func(src, dst string) error {
+		_, err := eng.CloneContext(ctx, src, dst)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.metrics.BytesCopied.WithLabelValues(metrics.OpFork).Add(float64(dirSize(mgr.Path(name))))
+	return wtCfg, nil
+}
+
+// Promote makes a detached worktree's current head its new latest snapshot,
+// leaving the payload untouched so new snapshots continue from that point.
+// Returns an error if the worktree is not detached.
+func (c *Client) Promote(ctx context.Context, worktreeName string) error {
+	ctx = c.beginCall(ctx, "promote")
+	if worktreeName == "" {
+		worktreeName = "main"
+	}
+	defer c.invalidateWorktree(worktreeName)
+	mgr := worktree.NewManager(c.repoRoot)
+	mgr.SetCorrelationID(CorrelationID(ctx))
+	mgr.SetAuthor(c.identity)
+	_, err := mgr.Promote(worktreeName)
+	return err
+}
+
+// History returns snapshot descriptors for a worktree, sorted newest first
+// with ties broken by snapshot ID (see model.CompareNewestFirst).
 // Pass limit <= 0 for all snapshots.
 func (c *Client) History(_ context.Context, worktreeName string, limit int) ([]*model.Descriptor, error) {
 	if worktreeName == "" {
@@ -192,8 +561,7 @@ func (c *Client) LatestSnapshot(_ context.Context, worktreeName string) (*model.
 		worktreeName = "main"
 	}
 
-	wtMgr := worktree.NewManager(c.repoRoot)
-	cfg, err := wtMgr.Get(worktreeName)
+	cfg, err := c.loadWorktree(worktreeName)
 	if err != nil {
 		return nil, fmt.Errorf("get worktree: %w", err)
 	}
@@ -202,7 +570,7 @@ func (c *Client) LatestSnapshot(_ context.Context, worktreeName string) (*model.
 		return nil, nil
 	}
 
-	return snapshot.LoadDescriptor(c.repoRoot, cfg.LatestSnapshotID)
+	return c.loadDescriptor(cfg.LatestSnapshotID)
 }
 
 // HasSnapshots returns true if the worktree has at least one snapshot.
@@ -211,8 +579,7 @@ func (c *Client) HasSnapshots(_ context.Context, worktreeName string) (bool, err
 		worktreeName = "main"
 	}
 
-	wtMgr := worktree.NewManager(c.repoRoot)
-	cfg, err := wtMgr.Get(worktreeName)
+	cfg, err := c.loadWorktree(worktreeName)
 	if err != nil {
 		return false, fmt.Errorf("get worktree: %w", err)
 	}
@@ -221,35 +588,204 @@ func (c *Client) HasSnapshots(_ context.Context, worktreeName string) (bool, err
 }
 
 // Verify checks a snapshot's integrity (descriptor checksum + optional payload hash).
-func (c *Client) Verify(_ context.Context, snapshotID model.SnapshotID) error {
-	return snapshot.VerifySnapshot(c.repoRoot, snapshotID, true)
+func (c *Client) Verify(ctx context.Context, snapshotID model.SnapshotID) error {
+	_, span := c.startSpan(ctx, "verify", attrSnapshotID.String(string(snapshotID)))
+	err := snapshot.VerifySnapshotContext(ctx, c.repoRoot, snapshotID, true)
+	endSpan(span, err)
+	c.observeVerify(snapshotID, err)
+	return err
 }
 
 // GC creates and optionally executes a garbage collection plan.
 // If DryRun is true, returns the plan without deleting anything.
-func (c *Client) GC(_ context.Context, opts GCOptions) (*model.GCPlan, error) {
-	collector := gc.NewCollector(c.repoRoot)
+func (c *Client) GC(ctx context.Context, opts GCOptions) (_ *model.GCPlan, err error) {
+	ctx = c.beginCall(ctx, "gc")
+	ctx, span := c.startSpan(ctx, "gc", attribute.Bool("jvs.gc.dry_run", opts.DryRun))
+	defer func() { endSpan(span, err) }()
+	return c.gc(ctx, opts)
+}
+
+func (c *Client) gc(ctx context.Context, opts GCOptions) (*model.GCPlan, error) {
+	var policy model.RetentionPolicy
+	if opts.Policy != nil {
+		policy = *opts.Policy
+	} else {
+		var err error
+		if policy, err = config.LoadRepoRetentionPolicy(c.repoRoot); err != nil {
+			return nil, fmt.Errorf("load retention policy: %w", err)
+		}
+	}
+	if opts.KeepMinSnapshots > 0 {
+		policy.KeepMinSnapshots = opts.KeepMinSnapshots
+	}
+	if opts.KeepMinAge > 0 {
+		policy.KeepMinAge = opts.KeepMinAge
+	}
+	if opts.KeepTags != nil {
+		policy.KeepTags = opts.KeepTags
+	}
+	if opts.TagRules != nil {
+		policy.TagRules = opts.TagRules
+	}
+	if opts.Worktrees != nil {
+		policy.Worktrees = opts.Worktrees
+	}
+	if opts.Budgets != nil {
+		policy.Budgets = opts.Budgets
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	collector := c.collector(ctx)
 
-	plan, err := collector.Plan()
+	_, planSpan := c.startSpan(ctx, "gc.plan")
+	plan, err := collector.PlanWithPolicy(policy)
+	endSpan(planSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("gc plan: %w", err)
 	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("jvs.gc.plan_id", plan.PlanID),
+		attribute.Int("jvs.gc.to_delete", len(plan.ToDelete)),
+	)
 
 	if opts.DryRun {
+		if opts.CheckConflicts {
+			if plan.Conflicts, err = collector.Conflicts(plan); err != nil {
+				return plan, fmt.Errorf("gc check conflicts: %w", err)
+			}
+		}
 		return plan, nil
 	}
 
-	if err := collector.Run(plan.PlanID); err != nil {
+	collector.SetCheckConflicts(opts.CheckConflicts)
+
+	defer c.invalidateDescriptors()
+	if err := c.runGC(ctx, collector, plan.PlanID); err != nil {
 		return plan, fmt.Errorf("gc run: %w", err)
 	}
 
 	return plan, nil
 }
 
-// RunGC executes a previously created GC plan by ID.
-func (c *Client) RunGC(_ context.Context, planID string) error {
+// GCPlan creates a GC plan under policy, instead of the repository's
+// retention policy, and saves it for RunGC; nothing is deleted. Zero fields
+// of policy protect nothing, so callers usually start from
+// model.DefaultRetentionPolicy.
+func (c *Client) GCPlan(ctx context.Context, policy model.RetentionPolicy) (_ *model.GCPlan, err error) {
+	ctx = c.beginCall(ctx, "gc_plan")
+	ctx, span := c.startSpan(ctx, "gc.plan")
+	defer func() { endSpan(span, err) }()
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	plan, err := c.collector(ctx).PlanWithPolicy(policy)
+	if err != nil {
+		return nil, fmt.Errorf("gc plan: %w", err)
+	}
+	span.SetAttributes(
+		attribute.String("jvs.gc.plan_id", plan.PlanID),
+		attribute.Int("jvs.gc.to_delete", len(plan.ToDelete)),
+	)
+	return plan, nil
+}
+
+// RunGC executes a previously created GC plan by ID, as returned by GCPlan
+// or a dry-run GC.
+func (c *Client) RunGC(ctx context.Context, planID string) error {
+	ctx = c.beginCall(ctx, "gc_run")
+	defer c.invalidateDescriptors()
+	return c.runGC(ctx, c.collector(ctx), planID)
+}
+
+// collector returns a GC collector waiting for the repository lock as set
+// by WithLockWait and stopping once ctx is done.
+func (c *Client) collector(ctx context.Context) *gc.Collector {
 	collector := gc.NewCollector(c.repoRoot)
-	return collector.Run(planID)
+	collector.SetContext(ctx)
+	collector.SetCorrelationID(CorrelationID(ctx))
+	collector.SetAuthor(c.identity)
+	if c.lockWait != nil {
+		collector.SetLockWait(*c.lockWait)
+	}
+	return collector
+}
+
+// runGC runs a plan in a "jvs.gc.run" span, between the client's GC hooks,
+// and counts the deletions.
+func (c *Client) runGC(ctx context.Context, collector *gc.Collector, planID string) error {
+	var plan *model.GCPlan
+	if len(c.hooks) > 0 {
+		var err error
+		if plan, err = collector.LoadPlan(planID); err != nil {
+			return fmt.Errorf("load plan: %w", err)
+		}
+		if err := c.beforeGC(ctx, plan); err != nil {
+			return err
+		}
+	}
+	_, span := c.startSpan(ctx, "gc.run", attribute.String("jvs.gc.plan_id", planID))
+	err := collector.Run(planID)
+	deleted := collector.Deleted()
+	span.SetAttributes(attribute.Int("jvs.gc.deleted", len(deleted)))
+	endSpan(span, err)
+	c.metrics.GCDeletions.Add(float64(len(deleted)))
+	if len(deleted) > 0 {
+		c.publish(Event{Type: EventGCDeleted, PlanID: planID, Deleted: deleted})
+	}
+	if err == nil && plan != nil {
+		c.afterGC(ctx, plan, deleted)
+	}
+	return err
+}
+
+// GCScheduleOptions configures ScheduleGC: interval, jitter, retention
+// policy and conflict checks of the cycles.
+type GCScheduleOptions = gc.SchedulerOptions
+
+// GCCycle is the outcome of one scheduled GC.
+type GCCycle = gc.Cycle
+
+// ScheduleGC plans and runs GC every opts.Interval, plus a random jitter,
+// until ctx is cancelled, and then returns nil. Each cycle is audited and
+// reported to opts.OnCycle; a failed cycle does not stop the schedule.
+// The client's GC hooks run around every cycle that would delete
+// snapshots.
+func (c *Client) ScheduleGC(ctx context.Context, opts GCScheduleOptions) error {
+	// Cycles run one at a time, so plan is the plan of the current one
+	var plan *model.GCPlan
+	beforeRun := opts.BeforeRun
+	opts.BeforeRun = func(ctx context.Context, p *model.GCPlan) error {
+		plan = nil
+		if beforeRun != nil {
+			if err := beforeRun(ctx, p); err != nil {
+				return err
+			}
+		}
+		if err := c.beforeGC(ctx, p); err != nil {
+			return err
+		}
+		plan = p
+		return nil
+	}
+	onCycle := opts.OnCycle
+	opts.OnCycle = func(cycle *GCCycle) {
+		if plan != nil && cycle.PlanID == plan.PlanID && cycle.Error == "" {
+			c.afterGC(ctx, plan, cycle.Deleted)
+		}
+		plan = nil
+		c.invalidateDescriptors()
+		c.metrics.GCDeletions.Add(float64(len(cycle.Deleted)))
+		if len(cycle.Deleted) > 0 {
+			c.publish(Event{Type: EventGCDeleted, PlanID: cycle.PlanID, Deleted: cycle.Deleted})
+		}
+		if onCycle != nil {
+			onCycle(cycle)
+		}
+	}
+	return gc.NewScheduler(c.repoRoot, opts).Run(ctx)
 }
 
 // RepoRoot returns the absolute path to the repository root.
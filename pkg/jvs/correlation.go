@@ -0,0 +1,45 @@
+package jvs
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/pkg/logging"
+	"github.com/jvs-project/jvs/pkg/uuidutil"
+)
+
+// WithCorrelationID returns a context that makes Client calls record id as
+// the correlation ID of every audit record they produce. Without it, each
+// top-level call generates its own ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return audit.WithCorrelationID(ctx, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	return audit.CorrelationID(ctx)
+}
+
+// beginCall returns ctx carrying the correlation ID of a Client call, and
+// the client's identity as its author: the ID ctx already carries, so that
+// calls nested in another call inherit it, or else a new one. The call
+// passes both on to the components it uses.
+func (c *Client) beginCall(ctx context.Context, op string) context.Context {
+	id := CorrelationID(ctx)
+	if id == "" {
+		id = uuidutil.NewV4()
+		ctx = WithCorrelationID(ctx, id)
+	}
+	ctx = audit.WithAuthor(ctx, c.identity)
+	fields := map[string]any{
+		"op":             op,
+		"repo":           c.repoRoot,
+		"correlation_id": id,
+	}
+	if c.logger != nil {
+		c.logger.Debug("client call", fields)
+	} else {
+		logging.Debug("client call", fields)
+	}
+	return ctx
+}
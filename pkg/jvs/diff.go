@@ -0,0 +1,136 @@
+package jvs
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/diff"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// Patch sets each changed file's Patch to its unified diff.
+	Patch bool
+	// Context is the number of unchanged lines around each change in a
+	// patch. Zero uses 3; negative shows none.
+	Context int
+	// MaxPatchBytes leaves out the content of files larger than this, as
+	// well as binary files: their patch only says so. Zero uses 1 MiB;
+	// negative means no limit.
+	MaxPatchBytes int64
+}
+
+// DiffResult lists the files added, removed and modified between two
+// snapshots, or a snapshot and a worktree, with their sizes and hashes.
+type DiffResult = diff.DiffResult
+
+// FileChange is one file of a DiffResult.
+type FileChange = diff.Change
+
+// Diff compares the payloads of two snapshots, decompressing compressed
+// ones. Unless patches are requested, snapshots that both have a manifest
+// are compared by their manifests instead, without reading any file. An
+// empty from compares against an empty tree, so every file of to is
+// reported as added.
+func (c *Client) Diff(_ context.Context, from, to model.SnapshotID, opts DiffOptions) (*DiffResult, error) {
+	// Snapshots with manifests are compared without reading their payloads
+	if !opts.Patch {
+		result, err := snapshot.ManifestDiff(c.repoRoot, from, to)
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
+
+	var fromDesc *model.Descriptor
+	var fromDir string
+	if from != "" {
+		desc, dir, cleanup, err := snapshot.UncompressedPayload(c.repoRoot, from, "diff")
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		fromDesc, fromDir = desc, dir
+	}
+	toDesc, toDir, cleanup, err := snapshot.UncompressedPayload(c.repoRoot, to, "diff")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result, err := diffPaths(diff.NewDiffer(c.repoRoot), fromDir, toDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.FromSnapshotID, result.ToSnapshotID = from, to
+	if fromDesc != nil {
+		result.FromTime = fromDesc.CreatedAt
+	}
+	result.ToTime = toDesc.CreatedAt
+	return result, nil
+}
+
+// DiffWorktree compares the payload of a snapshot with the current payload
+// of a worktree, which need not be the one the snapshot was taken from.
+// Paths excluded by the worktree's .jvsignore are left out; against a
+// partial snapshot only its paths are compared, as Status does. An empty
+// from compares against an empty tree. ToWorktree names the worktree in
+// the result, and ToSnapshotID and ToTime are left empty.
+func (c *Client) DiffWorktree(_ context.Context, from model.SnapshotID, worktreeName string, opts DiffOptions) (*DiffResult, error) {
+	if worktreeName == "" {
+		worktreeName = "main"
+	}
+	if _, err := c.loadWorktree(worktreeName); err != nil {
+		return nil, err
+	}
+	payloadPath := c.WorktreePayloadPath(worktreeName)
+
+	var fromDesc *model.Descriptor
+	var fromDir string
+	if from != "" {
+		desc, dir, cleanup, err := snapshot.UncompressedPayload(c.repoRoot, from, "diff")
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		fromDesc, fromDir = desc, dir
+	}
+
+	var partialPaths []string
+	if fromDesc != nil {
+		partialPaths = fromDesc.PartialPaths
+	}
+	skip, err := snapshot.WorktreeSkip(payloadPath, partialPaths)
+	if err != nil {
+		return nil, err
+	}
+	differ := diff.NewDiffer(c.repoRoot)
+	differ.SetSkip(skip)
+
+	result, err := diffPaths(differ, fromDir, payloadPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.FromSnapshotID, result.ToWorktree = from, worktreeName
+	if fromDesc != nil {
+		result.FromTime = fromDesc.CreatedAt
+	}
+	return result, nil
+}
+
+// diffPaths compares two payload directories and attaches the patches
+// opts asks for. Patches read the payloads, so it must run before they are
+// cleaned up.
+func diffPaths(differ *diff.Differ, fromDir, toDir string, opts DiffOptions) (*DiffResult, error) {
+	result, err := differ.DiffPaths(fromDir, toDir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Patch {
+		err := result.AttachPatches(diff.PatchOptions{Context: opts.Context, MaxBytes: opts.MaxPatchBytes})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
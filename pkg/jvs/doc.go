@@ -12,12 +12,116 @@
 //
 //   - Restore() / RestoreLatest() is safe when no concurrent reads from the
 //     payload directory. Always restore BEFORE the agent pod is created.
+//     Pods can hold a lease (AcquireLease) while attached; Restore then fails
+//     with an error matching ErrWorktreeBusy unless RestoreOptions.Force is set.
 //
 //   - Multiple Client instances for DIFFERENT repositories are fully independent
 //     and safe to use concurrently.
 //
-//   - Multiple Client instances for the SAME repository must NOT call
-//     mutating operations (Snapshot, Restore, GC) concurrently.
+//   - Multiple Client instances for the SAME repository, in one process or
+//     several, may call mutating operations concurrently. Snapshot and
+//     Restore hold the repository lock shared and GC holds it exclusive,
+//     so GC waits for running snapshots and restores and they wait for it.
+//     A caller that waits longer than the lock wait (WithLockWait, else
+//     lock_max_wait in the repository config) gets an error matching
+//     ErrRepoBusy. A second Snapshot or Restore of the same worktree fails
+//     at once with an error matching ErrWorktreeBusy.
+//
+// # Cancellation
+//
+// Snapshot, Restore, Fork, MaterializeAt, Verify, VerifyAll and GC stop
+// promptly once their context is done, for example when a pod is being shut
+// down, and return an error matching the context's error. Cancellation is
+// checked while data is copied or hashed and between GC deletions: an
+// interrupted snapshot is never published, an interrupted restore leaves
+// the worktree as it was, and an interrupted GC keeps the snapshots it had
+// not deleted yet. A step that has already published its result completes.
+//
+// # Progress
+//
+// SnapshotOptions.Progress and RestoreOptions.Progress report how many bytes
+// of the payload have been copied, so that a caller can show the progress of
+// restoring a large workspace. The callback runs on the goroutine making the
+// call, about once per percent done, and should return quickly.
+//
+// # Events
+//
+// Subscribe registers a callback, and Events a channel, receiving an Event
+// after every snapshot created, restore completed and GC deletion, and
+// whenever the snapshot engine falls back to a slower method. They let
+// callers log or count operations without wrapping every call. Callbacks
+// run on the goroutine that made the call; channels drop events they have
+// no room for.
+//
+// # Hooks
+//
+// WithHooks registers functions called around every Snapshot, Restore and
+// GC run, including scheduled ones. Unlike events, a before hook can veto
+// the operation: its error aborts it with a *HookError matching
+// ErrHookFailed. They run alongside the hook scripts in .jvs/hooks/, which
+// the CLI runs too: before hooks first, after hooks last.
+//
+// # Correlation IDs
+//
+// Every audit record written during a Client call carries a correlation ID.
+// Each top-level call generates one; wrap the context with WithCorrelationID
+// to group several calls (for example a lease, snapshot and fork done for one
+// job) under an ID of your own. The ID travels with the context, so
+// concurrent calls on one Client keep theirs apart.
+//
+// # Metadata Cache
+//
+// Long-lived orchestrators can call EnableMetadataCache so descriptor and
+// worktree config reads (Descriptor, Worktree, LatestSnapshot, HasSnapshots)
+// are served from an LRU cache, with concurrent reads of one entry sharing a
+// single load. The Client invalidates entries it changes itself; call
+// InvalidateMetadataCache after changes made elsewhere. CacheStats reports
+// hits and misses.
+//
+// # Side-by-side access
+//
+// MaterializeAt copies a snapshot to a read-only directory beside the
+// worktree and returns its path, so a tool can diff "now" against "then"
+// while the worktree stays at HEAD. Copies expire after a TTL (one hour, or
+// WithMaterializeTTL) and are removed by later calls.
+//
+// # Pins
+//
+// Pin protects a snapshot from GC whatever the retention policy, for
+// example while an investigation needs it, until PinOptions.TTL elapses or
+// Unpin is called. ListPins returns the repository's pins, including those
+// made with 'jvs pin'.
+//
+// # Metrics
+//
+// Metrics returns a Prometheus registry with the Client's counters and
+// histograms: snapshots created, restore durations, bytes copied, GC
+// deletions and verify failures. Each Client has its own registry, so
+// serve it from the process that owns the Client.
+//
+// # Tracing
+//
+// Snapshot, Restore, RestoreLatest, GC, GCPlan, RunGC, Verify and VerifyAll start
+// OpenTelemetry spans named "jvs.<operation>" below the span in the
+// context, so a caller's trace shows the workspace operations it ran.
+// Snapshots and restores add one child span per phase, such as
+// "jvs.snapshot.copy_hash" or "jvs.restore.clone". Spans go to the global
+// tracer provider unless WithTracerProvider sets one.
+//
+// # Fleets
+//
+// Fleet runs Doctor, VerifyAll, a GC plan or Stats across every repository
+// below a root directory with bounded concurrency, returning a FleetReport
+// with one FleetResult per repository.
+//
+// # Options and Testing
+//
+// Init, Open and OpenOrInit accept functional options: WithEngine skips
+// engine detection, WithLogger sends call logs to a logger of your own and
+// WithClock replaces the clock used for leases, materialized copies and
+// Status. Code that drives JVS can depend on Interface, which Client
+// implements, and use jvstest.NewFakeClient for an in-memory fake in its
+// tests.
 //
 // # Recommended Usage Pattern (sandbox-manager)
 //
@@ -31,7 +135,8 @@
 //
 //	// Pod shutdown: snapshot after pod is deleted
 //	client.Snapshot(ctx, jvs.SnapshotOptions{
-//	    Note: "auto: pod shutdown",
-//	    Tags: []string{"auto", "shutdown"},
+//	    Note:            "auto: pod shutdown",
+//	    Tags:            []string{"auto", "shutdown"},
+//	    SkipIfUnchanged: true, // no new snapshot if nothing changed
 //	})
 package jvs
@@ -0,0 +1,126 @@
+package jvs
+
+import (
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/lease"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/fsutil"
+)
+
+// ErrWorktreeBusy is matched (via errors.Is) by errors returned when an
+// operation is refused because another consumer holds a lease on the worktree,
+// or another snapshot, restore or removal of it is in progress.
+var ErrWorktreeBusy = errclass.ErrWorktreeBusy
+
+// ErrSnapshotNotReady is matched (via errors.Is) by errors returned when an
+// operation refuses a snapshot whose .READY marker is missing.
+var ErrSnapshotNotReady = errclass.ErrSnapshotNotReady
+
+// ErrWorktreeFrozen is matched (via errors.Is) by errors returned when an
+// operation would modify a frozen worktree.
+var ErrWorktreeFrozen = errclass.ErrWorktreeFrozen
+
+// ErrWorktreeLocked is matched (via errors.Is) by errors returned when an
+// operation would modify a worktree another owner has locked with 'jvs
+// worktree lock'.
+var ErrWorktreeLocked = errclass.ErrWorktreeLocked
+
+// ErrGCPlanMismatch is matched (via errors.Is) by errors returned when GC
+// refuses to run a plan that no longer holds, including plans that conflict
+// with live operations (GCOptions.CheckConflicts).
+var ErrGCPlanMismatch = errclass.ErrGCPlanMismatch
+
+// ErrLockTimeout is matched (via errors.Is) by errors returned when an
+// operation gave up waiting for the repository lock (config lock_max_wait).
+var ErrLockTimeout = errclass.ErrLockTimeout
+
+// ErrRepoBusy is matched (via errors.Is) by errors returned when Snapshot,
+// Restore or GC could not take the repository lock because another
+// operation, of this or any other Client or process, held it for longer
+// than the lock wait (WithLockWait). It is the same error class as
+// ErrLockTimeout; use errors.As with *LockTimeoutError for the blockers.
+var ErrRepoBusy = errclass.ErrLockTimeout
+
+// ErrLockLost is matched (via errors.Is) by errors returned when Snapshot or
+// GC stopped because, under lease locking (config lock_lease_ttl), their
+// lease on the repository lock expired and another node took it over.
+var ErrLockLost = errclass.ErrLockLost
+
+// ErrNotARepo is matched (via errors.Is) by errors returned when Open finds
+// no .jvs directory in the path or any of its parents.
+var ErrNotARepo = errclass.ErrNotARepo
+
+// ErrSnapshotNotFound is matched (via errors.Is) by errors returned when a
+// snapshot ID, tag or other reference names no snapshot of the repository.
+var ErrSnapshotNotFound = errclass.ErrSnapshotNotFound
+
+// ErrWorktreeNotFound is matched (via errors.Is) by errors returned when an
+// operation names a worktree that does not exist.
+var ErrWorktreeNotFound = errclass.ErrWorktreeNotFound
+
+// ErrDetachedHead is matched (via errors.Is) by errors returned when Snapshot
+// refuses a worktree in detached state, after a restore of an older
+// snapshot. Restore HEAD or fork the worktree first.
+var ErrDetachedHead = errclass.ErrDetachedHead
+
+// ErrHookFailed is matched (via errors.Is) by errors returned when a
+// pre-snapshot or pre-restore hook script in .jvs/hooks/ failed, aborting
+// the operation.
+var ErrHookFailed = errclass.ErrHookFailed
+
+// ErrIntegrityFailure is matched (via errors.Is) by errors returned when
+// repository data failed an integrity check: a corrupt descriptor, a
+// payload that does not match its hash, a broken lineage or audit chain,
+// or a partial snapshot. The error keeps its specific class, such as
+// errclass.ErrPayloadHashMismatch.
+var ErrIntegrityFailure = errclass.ErrIntegrityFailure
+
+// ErrStorage is matched (via errors.Is) by errors returned when a
+// descriptor or config write kept failing with a stale file handle after
+// retries.
+var ErrStorage = errclass.ErrStorage
+
+// ErrQuotaExceeded is matched (via errors.Is) by errors returned when a fork
+// would exceed the repository or a namespace quota.
+var ErrQuotaExceeded = errclass.ErrQuotaExceeded
+
+// ErrIncompatibleFormat is matched (via errors.Is) by errors returned when a
+// repository's format version is not supported by this build, typically
+// because it was created by a newer jvs.
+var ErrIncompatibleFormat = errclass.ErrFormatUnsupported
+
+// WorktreeBusyError carries the lease that blocked an operation.
+// Use errors.As to extract holder information.
+type WorktreeBusyError = lease.BusyError
+
+// OperationInProgressError names the operation, process, host and start time
+// of the snapshot, restore or removal already running on a worktree. Use
+// errors.As to extract them.
+type OperationInProgressError = repolock.OperationInProgressError
+
+// IncompatibleFormatError names the repository and format version that
+// could not be opened; its message says how to open it. Use errors.As to
+// extract it.
+type IncompatibleFormatError = repo.IncompatibleFormatError
+
+// GCConflictError lists the snapshots that blocked a GC run with
+// GCOptions.CheckConflicts. Use errors.As to extract them.
+type GCConflictError = gc.ConflictError
+
+// LockTimeoutError names the holders and waiters an operation was queued
+// behind when it gave up on the repository lock. Use errors.As to extract
+// them.
+type LockTimeoutError = repolock.TimeoutError
+
+// StorageError names the write that failed and how often it was tried. It
+// unwraps to the last error, so errors.Is(err, syscall.ESTALE) holds. Use
+// errors.As to extract it.
+type StorageError = fsutil.StorageError
+
+// QuotaError reports the quota a fork would exceed, the space needed and
+// available, and the largest snapshots GC could delete. Use errors.As to
+// extract it.
+type QuotaError = worktree.QuotaError
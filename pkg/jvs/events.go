@@ -0,0 +1,157 @@
+package jvs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// EventType names the kind of an Event.
+type EventType string
+
+// Event types.
+const (
+	// EventSnapshotCreated follows a successful Snapshot. Skipped snapshots
+	// emit no event.
+	EventSnapshotCreated EventType = "snapshot.created"
+	// EventRestoreCompleted follows a successful Restore or RestoreLatest.
+	EventRestoreCompleted EventType = "restore.completed"
+	// EventGCDeleted follows a GC, RunGC or scheduled GC cycle that deleted
+	// snapshots.
+	EventGCDeleted EventType = "gc.deleted"
+	// EventDegradationDetected follows a snapshot or restore whose engine
+	// fell back to a slower method, such as a reflink to a copy.
+	EventDegradationDetected EventType = "degradation.detected"
+)
+
+// Event is something that happened through a Client. Only the fields of
+// its Type are set.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Worktree is set for snapshots, restores and degradations.
+	Worktree string `json:"worktree,omitempty"`
+	// SnapshotID is the snapshot created or restored, or the one being
+	// created or restored when a degradation was detected.
+	SnapshotID model.SnapshotID `json:"snapshot_id,omitempty"`
+	// Descriptor is the created snapshot.
+	Descriptor *model.Descriptor `json:"descriptor,omitempty"`
+
+	// PlanID and Deleted are the GC plan that ran and the snapshots it
+	// deleted.
+	PlanID  string             `json:"plan_id,omitempty"`
+	Deleted []model.SnapshotID `json:"deleted,omitempty"`
+
+	// Operation is "snapshot" or "restore" for a degradation, and
+	// Degradations lists what the engine fell back from.
+	Operation    string   `json:"operation,omitempty"`
+	Degradations []string `json:"degradations,omitempty"`
+}
+
+// EventBus delivers events to subscribers. The zero value is ready to use.
+// Client and jvstest.FakeClient publish their events on one.
+type EventBus struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]func(Event)
+}
+
+// Subscribe calls fn with every event published from now on, until the
+// returned function is called. fn runs in the goroutine that published the
+// event, after the operation completed, so it may call back into the
+// Client but should return quickly.
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]func(Event))
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Events returns a channel receiving every event published until ctx is
+// done, when it is closed. Events that do not fit in its buffer are
+// dropped rather than blocking the operation that published them.
+func (b *EventBus) Events(ctx context.Context, buffer int) <-chan Event {
+	ch := make(chan Event, buffer)
+	var mu sync.Mutex
+	closed := false
+	unsubscribe := b.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+	context.AfterFunc(ctx, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		closed = true
+		close(ch)
+	})
+	return ch
+}
+
+// Publish delivers e to every subscriber, setting its Time if unset.
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b.mu.Lock()
+	subs := make([]func(Event), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.Unlock()
+	for _, fn := range subs {
+		fn(e)
+	}
+}
+
+// Subscribe calls fn with every event of this Client from now on, until
+// the returned function is called; see EventBus.Subscribe.
+func (c *Client) Subscribe(fn func(Event)) (unsubscribe func()) {
+	return c.events.Subscribe(fn)
+}
+
+// Events returns a channel receiving every event of this Client until ctx
+// is done; see EventBus.Events.
+func (c *Client) Events(ctx context.Context, buffer int) <-chan Event {
+	return c.events.Events(ctx, buffer)
+}
+
+// publish sends an event of this Client, stamped with its clock.
+func (c *Client) publish(e Event) {
+	e.Time = c.now().UTC()
+	c.events.Publish(e)
+}
+
+// publishDegradations sends a degradation event if the engine of a
+// snapshot or restore fell back.
+func (c *Client) publishDegradations(op, worktreeName string, id model.SnapshotID, degradations []string) {
+	if len(degradations) == 0 {
+		return
+	}
+	c.publish(Event{
+		Type:         EventDegradationDetected,
+		Worktree:     worktreeName,
+		SnapshotID:   id,
+		Operation:    op,
+		Degradations: degradations,
+	})
+}
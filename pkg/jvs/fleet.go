@@ -0,0 +1,301 @@
+package jvs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/jvs-project/jvs/internal/doctor"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/verify"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+)
+
+// Fleet defaults.
+const (
+	DefaultFleetMaxDepth    = 3
+	DefaultFleetConcurrency = 4
+)
+
+// DoctorResult is the outcome of a repository health check.
+type DoctorResult = doctor.Result
+
+// VerifyResult is the integrity check outcome for one snapshot.
+type VerifyResult = verify.Result
+
+// FleetOptions configures operations across many repositories.
+type FleetOptions struct {
+	Root        string // Directory searched for repositories
+	MaxDepth    int    // Directory levels searched below Root; zero uses DefaultFleetMaxDepth
+	Concurrency int    // Repositories processed at once; zero uses DefaultFleetConcurrency
+}
+
+// FleetResult is the outcome of an operation on one repository.
+type FleetResult struct {
+	Repo       string `json:"repo"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Report     any    `json:"report,omitempty"`
+}
+
+// FleetReport aggregates an operation across all discovered repositories.
+// Results are sorted by repository path.
+type FleetReport struct {
+	Root      string        `json:"root"`
+	Operation string        `json:"operation"`
+	Total     int           `json:"total"`
+	Failed    int           `json:"failed"`
+	Results   []FleetResult `json:"results"`
+}
+
+// RepoStats summarizes a repository's contents.
+type RepoStats struct {
+	Worktrees      int       `json:"worktrees"`
+	Snapshots      int       `json:"snapshots"`
+	SnapshotBytes  int64     `json:"snapshot_bytes"`
+	OldestSnapshot time.Time `json:"oldest_snapshot,omitempty"`
+	NewestSnapshot time.Time `json:"newest_snapshot,omitempty"`
+}
+
+// VerifyReport summarizes verification of every snapshot in a repository.
+type VerifyReport struct {
+	Snapshots int             `json:"snapshots"`
+	Failures  []*VerifyResult `json:"failures,omitempty"`
+}
+
+// Fleet runs operations over every repository below a root directory, for
+// platform teams managing many repositories on one volume.
+type Fleet struct {
+	opts FleetOptions
+}
+
+// NewFleet creates a Fleet.
+func NewFleet(opts FleetOptions) *Fleet {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultFleetMaxDepth
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultFleetConcurrency
+	}
+	return &Fleet{opts: opts}
+}
+
+// Discover returns the roots of all repositories below the fleet root, sorted.
+// Hidden directories are skipped and repositories are not searched for
+// nested repositories.
+func (f *Fleet) Discover() ([]string, error) {
+	root, err := filepath.Abs(f.opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve fleet root: %w", err)
+	}
+	var repos []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return fs.SkipDir // unreadable subdirectory
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, ".jvs", repo.FormatVersionFile)); err == nil {
+			repos = append(repos, path)
+			return fs.SkipDir
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && strings.Count(rel, string(filepath.Separator))+1 >= f.opts.MaxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discover repositories: %w", err)
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// Run applies fn to every discovered repository with bounded concurrency.
+// A repository fails if it cannot be opened or fn returns an error; a report
+// returned alongside an error is kept. Run only fails if discovery fails.
+func (f *Fleet) Run(ctx context.Context, operation string, fn func(context.Context, *Client) (any, error)) (*FleetReport, error) {
+	repos, err := f.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FleetReport{
+		Root:      f.opts.Root,
+		Operation: operation,
+		Total:     len(repos),
+		Results:   make([]FleetResult, len(repos)),
+	}
+
+	sem := make(chan struct{}, f.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, path := range repos {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			report.Results[i] = f.runOne(ctx, path, fn)
+		}(i, path)
+	}
+	wg.Wait()
+
+	for _, r := range report.Results {
+		if !r.OK {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+func (f *Fleet) runOne(ctx context.Context, path string, fn func(context.Context, *Client) (any, error)) FleetResult {
+	result := FleetResult{Repo: path}
+	if err := ctx.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	client, err := Open(path)
+	if err == nil {
+		result.Report, err = fn(ctx, client)
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// Doctor runs health checks on every repository. Unhealthy repositories fail.
+func (f *Fleet) Doctor(ctx context.Context, strict bool) (*FleetReport, error) {
+	return f.Run(ctx, "doctor", func(ctx context.Context, c *Client) (any, error) {
+		return c.Doctor(ctx, strict)
+	})
+}
+
+// Verify checks every snapshot of every repository, including payload hashes.
+// Repositories with any failed snapshot fail.
+func (f *Fleet) Verify(ctx context.Context) (*FleetReport, error) {
+	return f.Run(ctx, "verify", func(ctx context.Context, c *Client) (any, error) {
+		return c.VerifyAll(ctx)
+	})
+}
+
+// GCPlan creates a GC plan in every repository using its configured
+// retention policy. Nothing is deleted.
+func (f *Fleet) GCPlan(ctx context.Context) (*FleetReport, error) {
+	return f.Run(ctx, "gc plan", func(_ context.Context, c *Client) (any, error) {
+		policy, err := config.LoadRepoRetentionPolicy(c.repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		return gc.NewCollector(c.repoRoot).PlanWithPolicy(policy)
+	})
+}
+
+// Stats collects RepoStats for every repository.
+func (f *Fleet) Stats(ctx context.Context) (*FleetReport, error) {
+	return f.Run(ctx, "stats", func(ctx context.Context, c *Client) (any, error) {
+		return c.Stats(ctx)
+	})
+}
+
+// Doctor runs repository health checks; strict adds snapshot integrity and
+// audit chain verification. An unhealthy repository returns the result
+// together with an error.
+func (c *Client) Doctor(_ context.Context, strict bool) (*DoctorResult, error) {
+	result, err := doctor.NewDoctor(c.repoRoot).Check(strict)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Healthy {
+		return result, fmt.Errorf("unhealthy: %d findings", len(result.Findings))
+	}
+	return result, nil
+}
+
+// VerifyAll verifies every snapshot including payload hashes. If any
+// snapshot fails, the report is returned together with an error.
+func (c *Client) VerifyAll(ctx context.Context) (_ *VerifyReport, err error) {
+	_, span := c.startSpan(ctx, "verify_all")
+	defer func() { endSpan(span, err) }()
+	verifier := verify.NewVerifier(c.repoRoot)
+	verifier.SetContext(ctx)
+	results, err := verifier.VerifyAll(true)
+	if err != nil {
+		return nil, err
+	}
+	report := &VerifyReport{Snapshots: len(results)}
+	for _, r := range results {
+		if r.TamperDetected || r.Error != "" {
+			report.Failures = append(report.Failures, r)
+		}
+	}
+	c.metrics.VerifyFailures.Add(float64(len(report.Failures)))
+	span.SetAttributes(attribute.Int("jvs.snapshots", report.Snapshots), attribute.Int("jvs.verify.failures", len(report.Failures)))
+	if len(report.Failures) > 0 {
+		return report, fmt.Errorf("%d of %d snapshots failed verification", len(report.Failures), len(results))
+	}
+	return report, nil
+}
+
+// Stats summarizes the repository's worktrees and snapshots.
+func (c *Client) Stats(_ context.Context) (*RepoStats, error) {
+	wts, err := worktree.NewManager(c.repoRoot).List()
+	if err != nil {
+		return nil, fmt.Errorf("list worktrees: %w", err)
+	}
+	descs, err := snapshot.ListAll(c.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+
+	stats := &RepoStats{Worktrees: len(wts), Snapshots: len(descs)}
+	if len(descs) > 0 {
+		// ListAll is newest first
+		stats.NewestSnapshot = descs[0].CreatedAt
+		stats.OldestSnapshot = descs[len(descs)-1].CreatedAt
+	}
+	for _, desc := range descs {
+		stats.SnapshotBytes += dirSize(filepath.Join(c.repoRoot, ".jvs", "snapshots", string(desc.SnapshotID)))
+	}
+	return stats, nil
+}
+
+// dirSize returns the total size of regular files below dir.
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
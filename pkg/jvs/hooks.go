@@ -0,0 +1,122 @@
+package jvs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Hooks are functions a Client calls around its snapshots, restores and
+// GC runs, registered with WithHooks. Any of them may be nil.
+//
+// Before hooks run on the goroutine making the call, before the operation
+// takes any lock or runs the repository's pre hook scripts; an error
+// aborts the operation with a *HookError. After hooks run once a
+// successful operation has released its locks and run the post hook
+// scripts; they cannot fail it.
+type Hooks struct {
+	// BeforeSnapshot is called with the options of each Snapshot.
+	BeforeSnapshot func(ctx context.Context, opts SnapshotOptions) error
+	// AfterSnapshot is called with the snapshot taken, or with the HEAD
+	// descriptor with Skipped set for an unchanged worktree.
+	AfterSnapshot func(ctx context.Context, desc *model.Descriptor)
+
+	// BeforeRestore is called with the worktree and the snapshot it is
+	// about to be restored to, once the target is resolved.
+	BeforeRestore func(ctx context.Context, worktreeName string, snapshotID model.SnapshotID) error
+	// AfterRestore is called after the worktree was restored.
+	AfterRestore func(ctx context.Context, worktreeName string, snapshotID model.SnapshotID)
+
+	// BeforeGC is called with the plan GC or RunGC is about to run, and
+	// with the plan of every ScheduleGC cycle that would delete snapshots.
+	BeforeGC func(ctx context.Context, plan *model.GCPlan) error
+	// AfterGC is called with the plan run and the snapshots it deleted.
+	AfterGC func(ctx context.Context, plan *model.GCPlan, deleted []model.SnapshotID)
+}
+
+// WithHooks registers hooks the client calls around its operations.
+// Hooks registered by several options are all called, in order; the first
+// before hook to fail aborts the operation.
+func WithHooks(h Hooks) Option {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, h)
+	}
+}
+
+// HookError is returned when a before hook aborted an operation. It
+// matches ErrHookFailed and the hook's error via errors.Is.
+type HookError struct {
+	Hook string // "BeforeSnapshot", "BeforeRestore" or "BeforeGC"
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook: %v", e.Hook, e.Err)
+}
+
+// Unwrap returns ErrHookFailed and the hook's error.
+func (e *HookError) Unwrap() []error {
+	return []error{errclass.ErrHookFailed, e.Err}
+}
+
+func (c *Client) beforeSnapshot(ctx context.Context, opts SnapshotOptions) error {
+	for _, h := range c.hooks {
+		if h.BeforeSnapshot == nil {
+			continue
+		}
+		if err := h.BeforeSnapshot(ctx, opts); err != nil {
+			return &HookError{Hook: "BeforeSnapshot", Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *Client) afterSnapshot(ctx context.Context, desc *model.Descriptor) {
+	for _, h := range c.hooks {
+		if h.AfterSnapshot != nil {
+			h.AfterSnapshot(ctx, desc)
+		}
+	}
+}
+
+func (c *Client) beforeRestore(ctx context.Context, worktreeName string, id model.SnapshotID) error {
+	for _, h := range c.hooks {
+		if h.BeforeRestore == nil {
+			continue
+		}
+		if err := h.BeforeRestore(ctx, worktreeName, id); err != nil {
+			return &HookError{Hook: "BeforeRestore", Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *Client) afterRestore(ctx context.Context, worktreeName string, id model.SnapshotID) {
+	for _, h := range c.hooks {
+		if h.AfterRestore != nil {
+			h.AfterRestore(ctx, worktreeName, id)
+		}
+	}
+}
+
+func (c *Client) beforeGC(ctx context.Context, plan *model.GCPlan) error {
+	for _, h := range c.hooks {
+		if h.BeforeGC == nil {
+			continue
+		}
+		if err := h.BeforeGC(ctx, plan); err != nil {
+			return &HookError{Hook: "BeforeGC", Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *Client) afterGC(ctx context.Context, plan *model.GCPlan, deleted []model.SnapshotID) {
+	for _, h := range c.hooks {
+		if h.AfterGC != nil {
+			h.AfterGC(ctx, plan, deleted)
+		}
+	}
+}
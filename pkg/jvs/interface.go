@@ -0,0 +1,83 @@
+package jvs
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Interface is the set of Client methods, so that code driving JVS can be
+// tested against a fake such as jvstest.FakeClient.
+type Interface interface {
+	// Snapshots and restores
+	Snapshot(ctx context.Context, opts SnapshotOptions) (*model.Descriptor, error)
+	Restore(ctx context.Context, opts RestoreOptions) error
+	RestoreLatest(ctx context.Context, worktreeName string) error
+	Fork(ctx context.Context, snapshotID model.SnapshotID, name string) (*model.WorktreeConfig, error)
+	Promote(ctx context.Context, worktreeName string) error
+	MaterializeAt(ctx context.Context, worktreeName string, snapshotID model.SnapshotID) (*model.Materialization, error)
+	Export(ctx context.Context, w io.Writer, snapshotID model.SnapshotID, opts ExportOptions) (*ExportResult, error)
+	Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error)
+
+	// Leases
+	AcquireLease(ctx context.Context, worktreeName, holder string, ttl time.Duration) (*model.Lease, error)
+	ReleaseLease(ctx context.Context, worktreeName, holder string) error
+	Lease(ctx context.Context, worktreeName string) (*model.Lease, error)
+
+	// Tags and notes
+	AddTags(ctx context.Context, snapshotID model.SnapshotID, tags []string) (*model.Descriptor, error)
+	RemoveTags(ctx context.Context, snapshotID model.SnapshotID, tags []string) (*model.Descriptor, error)
+	Annotate(ctx context.Context, snapshotID model.SnapshotID, note string, opts AnnotateOptions) (*model.Descriptor, error)
+
+	// Pins
+	Pin(ctx context.Context, snapshotID model.SnapshotID, opts PinOptions) (*model.Pin, error)
+	Unpin(ctx context.Context, snapshotID model.SnapshotID) error
+	ListPins(ctx context.Context) ([]*model.Pin, error)
+
+	// Queries
+	History(ctx context.Context, worktreeName string, limit int) ([]*model.Descriptor, error)
+	ListSnapshots(ctx context.Context, opts ListOptions) (*SnapshotPage, error)
+	LatestSnapshot(ctx context.Context, worktreeName string) (*model.Descriptor, error)
+	HasSnapshots(ctx context.Context, worktreeName string) (bool, error)
+	Descriptor(ctx context.Context, snapshotID model.SnapshotID) (*model.Descriptor, error)
+	Resolve(ctx context.Context, ref string) (*model.Descriptor, error)
+	Worktree(ctx context.Context, worktreeName string) (*model.WorktreeConfig, error)
+	Stats(ctx context.Context) (*RepoStats, error)
+	Status(ctx context.Context, opts StatusOptions) (*RepoStatus, error)
+	Diff(ctx context.Context, from, to model.SnapshotID, opts DiffOptions) (*DiffResult, error)
+	DiffWorktree(ctx context.Context, from model.SnapshotID, worktreeName string, opts DiffOptions) (*DiffResult, error)
+
+	// Integrity and maintenance
+	Verify(ctx context.Context, snapshotID model.SnapshotID) error
+	VerifyAll(ctx context.Context) (*VerifyReport, error)
+	VerifyAudit(ctx context.Context) (*AuditVerifyResult, error)
+	Doctor(ctx context.Context, strict bool) (*DoctorResult, error)
+	GC(ctx context.Context, opts GCOptions) (*model.GCPlan, error)
+	GCPlan(ctx context.Context, policy model.RetentionPolicy) (*model.GCPlan, error)
+	RunGC(ctx context.Context, planID string) error
+	ScheduleGC(ctx context.Context, opts GCScheduleOptions) error
+
+	// Events
+	Subscribe(fn func(Event)) (unsubscribe func())
+	Events(ctx context.Context, buffer int) <-chan Event
+
+	// Client settings
+	EnableAuditBatching(opts AuditBatchOptions) (*AuditBatch, error)
+	EnableMetadataCache(size int)
+	InvalidateMetadataCache()
+	CacheStats() CacheStats
+	Metrics() *prometheus.Registry
+
+	// Repository
+	RepoRoot() string
+	RepoID() string
+	Version() (VersionInfo, error)
+	EngineType() model.EngineType
+	WorktreePayloadPath(worktreeName string) string
+}
+
+var _ Interface = (*Client)(nil)
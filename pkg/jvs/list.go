@@ -0,0 +1,67 @@
+package jvs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// DefaultListLimit is the page size of ListSnapshots when
+// ListOptions.Limit is zero.
+const DefaultListLimit = 100
+
+// ListOptions configures ListSnapshots.
+type ListOptions struct {
+	// Worktree keeps the snapshots of one worktree; empty lists every
+	// worktree's.
+	Worktree string
+	// Limit is the most snapshots on a page. Zero uses DefaultListLimit.
+	Limit int
+	// Cursor is the NextCursor of the previous page; empty starts with the
+	// newest snapshot. Cursors stay valid as snapshots are added or
+	// deleted.
+	Cursor string
+	// Tags keeps snapshots carrying all of these tags.
+	Tags []string
+	// Since and Until, if set, keep snapshots created in this time range.
+	Since time.Time
+	Until time.Time
+	// Author keeps snapshots taken by this author: the same identity or,
+	// given without a host, the same user on any host.
+	Author string
+}
+
+// SnapshotPage is one page of ListSnapshots.
+type SnapshotPage struct {
+	// Snapshots are newest first, as History orders them.
+	Snapshots []*model.Descriptor `json:"snapshots"`
+	// NextCursor fetches the next page; it is empty on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListSnapshots returns one page of the snapshots selected by opts. Pages
+// are read incrementally: unlike History, a repository with tens of
+// thousands of snapshots is never held in memory at once.
+func (c *Client) ListSnapshots(ctx context.Context, opts ListOptions) (*SnapshotPage, error) {
+	ctx = c.beginCall(ctx, "list_snapshots")
+	if opts.Limit == 0 {
+		opts.Limit = DefaultListLimit
+	}
+	filter := snapshot.FilterOptions{
+		WorktreeName: opts.Worktree,
+		Tags:         opts.Tags,
+		Since:        opts.Since,
+		Until:        opts.Until,
+		Author:       opts.Author,
+	}
+	descs, next, err := snapshot.ListPage(c.repoRoot, filter, opts.Cursor, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if descs == nil {
+		descs = []*model.Descriptor{}
+	}
+	return &SnapshotPage{Snapshots: descs, NextCursor: next}, nil
+}
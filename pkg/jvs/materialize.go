@@ -0,0 +1,36 @@
+package jvs
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/engine"
+	"github.com/jvs-project/jvs/internal/materialize"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// DefaultMaterializeTTL is how long MaterializeAt keeps a copy unless
+// WithMaterializeTTL sets another TTL.
+const DefaultMaterializeTTL = materialize.DefaultTTL
+
+// MaterializeAt returns a read-only copy of a snapshot kept beside the
+// worktree, so tools can compare "now" with "then" side by side. Unlike
+// Restore, it leaves the worktree's payload and HEAD untouched.
+//
+// The copy is kept for the client's materialize TTL from the last call for
+// the same worktree and snapshot, which reuses it. Expired copies are
+// removed by later calls and by 'jvs doctor --repair-runtime'; callers must
+// not read a copy past its ExpiresAt. Compressed snapshots are
+// decompressed.
+func (c *Client) MaterializeAt(ctx context.Context, worktreeName string, snapshotID model.SnapshotID) (*model.Materialization, error) {
+	ctx = c.beginCall(ctx, "materialize")
+	if worktreeName == "" {
+		worktreeName = "main"
+	}
+	m := materialize.NewManager(c.repoRoot)
+	m.SetClock(c.now)
+	eng := engine.NewEngine(c.engineType)
+	return m.Materialize(worktreeName, snapshotID, c.materializeTTL, func(src, dst string) error {
+		_, err := eng.CloneContext(ctx, src, dst)
+		return err
+	})
+}
@@ -0,0 +1,85 @@
+package jvs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jvs-project/jvs/internal/metrics"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/restore"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Metrics returns the Prometheus registry holding this Client's metrics:
+//
+//	jvs_snapshots_created_total       snapshots created (skipped ones are not counted)
+//	jvs_restore_duration_seconds      histogram of successful restores
+//	jvs_bytes_copied_total{operation} payload bytes written by snapshot, restore and fork
+//	jvs_gc_deleted_snapshots_total    snapshots deleted by GC and RunGC
+//	jvs_verify_failures_total         snapshots failing Verify or VerifyAll
+//
+// Only this Client's calls are counted. Serve the registry with
+// promhttp.HandlerFor, or combine several Clients' registries with
+// prometheus.Gatherers.
+func (c *Client) Metrics() *prometheus.Registry {
+	return c.registry
+}
+
+// observeSnapshot counts a created snapshot and the bytes it did not link
+// from its parent or the content store.
+func (c *Client) observeSnapshot(desc *model.Descriptor) {
+	if desc.Skipped {
+		return
+	}
+	c.metrics.SnapshotsCreated.Inc()
+	dir := repo.SnapshotPath(c.repoRoot, desc.SnapshotID)
+	copied := dirSize(dir)
+	markers, _ := filepath.Glob(filepath.Join(dir, ".READY*"))
+	for _, marker := range markers {
+		if info, err := os.Lstat(marker); err == nil {
+			copied -= info.Size()
+		}
+	}
+	if desc.Incremental != nil {
+		copied -= desc.Incremental.LinkedBytes
+	}
+	if desc.ContentStore != nil {
+		copied -= desc.ContentStore.ReusedBytes
+	}
+	c.metrics.BytesCopied.WithLabelValues(metrics.OpSnapshot).Add(float64(max(copied, 0)))
+}
+
+// observeRestore runs a restore of snapshot id by restorer, between the
+// client's restore hooks, and, if it succeeds, records its duration and
+// the size of the restored payload and publishes its events.
+func (c *Client) observeRestore(ctx context.Context, worktreeName string, id model.SnapshotID, restorer *restore.Restorer, run func() error) error {
+	if err := c.beforeRestore(ctx, worktreeName, id); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := run(); err != nil {
+		return err
+	}
+	c.metrics.RestoreDuration.Observe(time.Since(start).Seconds())
+	c.metrics.BytesCopied.WithLabelValues(metrics.OpRestore).Add(float64(dirSize(c.WorktreePayloadPath(worktreeName))))
+	c.publish(Event{Type: EventRestoreCompleted, Worktree: worktreeName, SnapshotID: id})
+	c.publishDegradations("restore", worktreeName, id, restorer.Degradations())
+	c.afterRestore(ctx, worktreeName, id)
+	return nil
+}
+
+// observeVerify counts a failed verification of snapshot id, unless the
+// snapshot does not exist.
+func (c *Client) observeVerify(id model.SnapshotID, err error) {
+	if err == nil {
+		return
+	}
+	if _, statErr := os.Stat(repo.SnapshotPath(c.repoRoot, id)); os.IsNotExist(statErr) {
+		return
+	}
+	c.metrics.VerifyFailures.Inc()
+}
@@ -0,0 +1,98 @@
+package jvs
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jvs-project/jvs/internal/metrics"
+	"github.com/jvs-project/jvs/internal/resolver"
+	"github.com/jvs-project/jvs/pkg/logging"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Option configures a Client created by Init, Open or OpenOrInit.
+type Option func(*Client)
+
+// WithEngine makes the client use engineType instead of detecting the best
+// engine for the repository. It takes precedence over InitOptions.EngineType.
+func WithEngine(engineType model.EngineType) Option {
+	return func(c *Client) {
+		c.engineType = engineType
+	}
+}
+
+// WithLogger makes the client log its calls to l instead of the global
+// logger.
+func WithLogger(l *logging.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithClock replaces the clock the client uses to stamp and expire leases
+// and materialized copies, and to stamp pins and Status. Intended for tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// WithMaterializeTTL sets how long MaterializeAt keeps a copy. Zero uses
+// DefaultMaterializeTTL.
+func WithMaterializeTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.materializeTTL = ttl
+	}
+}
+
+// WithLockWait sets how long Snapshot, Restore, RestoreLatest, GC and RunGC
+// wait for the repository lock held by another operation, of this or any
+// other Client or process, instead of the repository's lock_max_wait. Zero
+// fails at once. Giving up returns an error matching ErrRepoBusy.
+func WithLockWait(d time.Duration) Option {
+	return func(c *Client) {
+		c.lockWait = &d
+	}
+}
+
+// WithTracerProvider makes the client record Snapshot, Restore, GC and
+// Verify calls, and the phases of snapshots and restores, as OpenTelemetry
+// spans of tp instead of the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Client) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithIdentity sets the author the client's snapshots and audit records
+// name, such as a pod or agent name, instead of $JVS_AUTHOR or the local
+// user@host. Amends made through the client record it as their actor.
+func WithIdentity(identity string) Option {
+	return func(c *Client) {
+		c.identity = identity
+	}
+}
+
+// newClient returns a client for the repository at root with options
+// applied, detecting the engine unless one was chosen.
+func newClient(root, repoID string, engineType model.EngineType, options []Option) *Client {
+	c := &Client{
+		repoRoot:   root,
+		repoID:     repoID,
+		engineType: engineType,
+		now:        time.Now,
+		registry:   prometheus.NewRegistry(),
+	}
+	c.metrics = metrics.New(c.registry)
+	for _, opt := range options {
+		opt(c)
+	}
+	if c.engineType == "" {
+		c.engineType = detectEngineType(root)
+	}
+	c.resolver = resolver.New(root)
+	c.resolver.SetClock(c.now)
+	return c
+}
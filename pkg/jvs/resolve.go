@@ -0,0 +1,48 @@
+package jvs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Resolve returns the descriptor of the snapshot ref names: a snapshot ID,
+// a unique prefix of an ID, note or tag, or a tag carried by several
+// snapshots, naming the newest. References the repository does not know
+// are passed to the external resolvers configured under "resolvers" in the
+// repository config, whose answers are cached and audited as ref_resolve.
+// Resolve fails with an error matching both ErrSnapshotNotFound and
+// fs.ErrNotExist if nothing resolves ref.
+func (c *Client) Resolve(ctx context.Context, ref string) (*model.Descriptor, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("reference is required")
+	}
+	if desc, err := c.loadDescriptor(model.SnapshotID(ref)); err == nil {
+		return desc, nil
+	}
+	desc, findErr := snapshot.FindOne(c.repoRoot, ref)
+	if findErr == nil {
+		return desc, nil
+	}
+	if desc, err := snapshot.FindByTag(c.repoRoot, ref); err == nil {
+		return desc, nil
+	}
+
+	desc, err := c.resolver.Resolve(ctx, ref)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !errors.Is(findErr, errclass.ErrSnapshotNotFound) {
+			// Ambiguous in the repository, and unknown to the resolvers
+			findErr = errclass.ErrSnapshotNotFound.WithMessage(findErr.Error())
+		}
+		return nil, fmt.Errorf("resolve %q: %w: %w", ref, findErr, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
@@ -0,0 +1,165 @@
+package jvs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// DefaultStatusEvents is the number of audit events Status returns when
+// StatusOptions.Events is zero.
+const DefaultStatusEvents = 10
+
+// StatusOptions configures Status.
+type StatusOptions struct {
+	// Events is the number of most recent audit events to include.
+	// Zero uses DefaultStatusEvents; negative includes none.
+	Events int
+	// Worktree, if set, compares that worktree's payload with its HEAD
+	// snapshot and reports the result in RepoStatus.Changes.
+	Worktree string
+}
+
+// RepoStatus is a point-in-time view of a repository for operators.
+type RepoStatus struct {
+	CollectedAt time.Time `json:"collected_at"`
+	// Stats is the same summary Stats returns.
+	Stats *RepoStats `json:"stats"`
+	// Worktrees are sorted by name.
+	Worktrees  []WorktreeStatus  `json:"worktrees"`
+	Operations []ActiveOperation `json:"operations"`
+	// LockQueue lists the repository lock holders, then its waiters.
+	LockQueue []*model.LockTicket `json:"lock_queue"`
+	// Events are the most recent audit events, oldest first.
+	Events []*model.AuditRecord `json:"events"`
+	// Changes is set if StatusOptions.Worktree was.
+	Changes *WorktreeChanges `json:"changes,omitempty"`
+}
+
+// WorktreeChanges lists the files added, removed and modified in a
+// worktree's payload since its HEAD snapshot. Files are compared by type,
+// size, executable bits and modification time, hashing content only when
+// the modification time alone differs. Paths excluded by .jvsignore are
+// left out. Dirty tells whether a snapshot would record anything.
+type WorktreeChanges = snapshot.WorktreeChanges
+
+// WorktreeStatus describes the state of one worktree.
+type WorktreeStatus struct {
+	Name             string           `json:"name"`
+	HeadSnapshotID   model.SnapshotID `json:"head_snapshot_id,omitempty"`
+	LatestSnapshotID model.SnapshotID `json:"latest_snapshot_id,omitempty"`
+	Detached         bool             `json:"detached"`
+	Frozen           bool             `json:"frozen,omitempty"`
+	Lease            *model.Lease     `json:"lease,omitempty"`
+	// Stale is set if the worktree has not been snapshotted within the
+	// maximum age set under staleness in .jvs/config.yaml.
+	Stale *model.Staleness `json:"stale,omitempty"`
+}
+
+// ActiveOperation is a snapshot or restore in progress, from its intent
+// record.
+type ActiveOperation struct {
+	Operation  string           `json:"operation"`
+	Worktree   string           `json:"worktree"`
+	SnapshotID model.SnapshotID `json:"snapshot_id"`
+	Since      time.Time        `json:"since"`
+}
+
+// Status collects the repository's worktree states, in-progress
+// operations, lock queue, recent audit events and Stats, and, with
+// StatusOptions.Worktree, the uncommitted changes of a worktree.
+func (c *Client) Status(ctx context.Context, opts StatusOptions) (*RepoStatus, error) {
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &RepoStatus{
+		CollectedAt: c.now().UTC(),
+		Stats:       stats,
+		Worktrees:   []WorktreeStatus{},
+		Operations:  []ActiveOperation{},
+		LockQueue:   []*model.LockTicket{},
+		Events:      []*model.AuditRecord{},
+	}
+
+	wts, err := worktree.NewManager(c.repoRoot).List()
+	if err != nil {
+		return nil, fmt.Errorf("list worktrees: %w", err)
+	}
+	repoCfg, err := config.Load(c.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	staleness := repoCfg.GetStaleness()
+	leases := c.leases(ctx)
+	for _, cfg := range wts {
+		l, err := leases.Get(cfg.Name)
+		if err != nil {
+			return nil, fmt.Errorf("read lease: %w", err)
+		}
+		// An unreadable latest snapshot is left to doctor to report
+		stale, _ := snapshot.CheckStale(c.repoRoot, cfg, staleness, status.CollectedAt)
+		status.Worktrees = append(status.Worktrees, WorktreeStatus{
+			Name:             cfg.Name,
+			HeadSnapshotID:   cfg.HeadSnapshotID,
+			LatestSnapshotID: cfg.LatestSnapshotID,
+			Detached:         cfg.IsDetached(),
+			Frozen:           cfg.Frozen,
+			Lease:            l,
+			Stale:            stale,
+		})
+	}
+
+	intents, err := repo.ListIntents(c.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, intent := range intents {
+		op := intent.Operation
+		if op == "" {
+			op = "snapshot"
+		}
+		status.Operations = append(status.Operations, ActiveOperation{
+			Operation:  op,
+			Worktree:   intent.WorktreeName,
+			SnapshotID: intent.SnapshotID,
+			Since:      intent.StartedAt,
+		})
+	}
+
+	queue, err := repolock.NewManager(c.repoRoot).Queue()
+	if err != nil {
+		return nil, err
+	}
+	status.LockQueue = append(status.LockQueue, queue...)
+
+	if opts.Worktree != "" {
+		if status.Changes, err = snapshot.Changes(c.repoRoot, opts.Worktree); err != nil {
+			return nil, err
+		}
+	}
+
+	events := opts.Events
+	if events == 0 {
+		events = DefaultStatusEvents
+	}
+	if events > 0 {
+		records, err := audit.Read(audit.LogPath(c.repoRoot), audit.Filter{})
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > events {
+			records = records[len(records)-events:]
+		}
+		status.Events = append(status.Events, records...)
+	}
+	return status, nil
+}
@@ -0,0 +1,56 @@
+package jvs
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// AddTags adds tags to a published snapshot and returns its updated
+// descriptor. Tags the snapshot already carries are kept once. The
+// descriptor is rewritten atomically with a recomputed checksum; the change
+// is recorded in the snapshot's metadata history and audited as
+// snapshot_amend.
+func (c *Client) AddTags(ctx context.Context, snapshotID model.SnapshotID, tags []string) (*model.Descriptor, error) {
+	ctx = c.beginCall(ctx, "add_tags")
+	for _, tag := range tags {
+		if err := pathutil.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+	return c.amendTags(ctx, snapshotID, "tag", func(d *model.Descriptor) {
+		for _, tag := range tags {
+			if !slices.Contains(d.Tags, tag) {
+				d.Tags = append(d.Tags, tag)
+			}
+		}
+	})
+}
+
+// RemoveTags removes tags from a published snapshot as AddTags adds them.
+// Tags the snapshot does not carry are ignored.
+func (c *Client) RemoveTags(ctx context.Context, snapshotID model.SnapshotID, tags []string) (*model.Descriptor, error) {
+	ctx = c.beginCall(ctx, "remove_tags")
+	return c.amendTags(ctx, snapshotID, "untag", func(d *model.Descriptor) {
+		d.Tags = slices.DeleteFunc(d.Tags, func(t string) bool { return slices.Contains(tags, t) })
+	})
+}
+
+func (c *Client) amendTags(ctx context.Context, snapshotID model.SnapshotID, operation string, update func(*model.Descriptor)) (*model.Descriptor, error) {
+	desc, err := snapshot.Amend(ctx, c.repoRoot, snapshotID, operation, "", func(d *model.Descriptor) error {
+		update(d)
+		return nil
+	})
+	c.invalidateDescriptor(snapshotID)
+	return desc, err
+}
+
+// invalidateDescriptor drops the cached descriptor of an amended snapshot.
+func (c *Client) invalidateDescriptor(snapshotID model.SnapshotID) {
+	if mc := c.cache.Load(); mc != nil {
+		mc.descriptors.Invalidate(snapshotID)
+	}
+}
@@ -0,0 +1,58 @@
+package jvs
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jvs-project/jvs/internal/snapshot"
+	"github.com/jvs-project/jvs/internal/version"
+)
+
+// tracerName is the instrumentation scope of the spans a Client starts.
+const tracerName = "github.com/jvs-project/jvs/pkg/jvs"
+
+// Span attribute keys.
+const (
+	attrWorktree   = attribute.Key("jvs.worktree")
+	attrSnapshotID = attribute.Key("jvs.snapshot_id")
+)
+
+// tracer returns the tracer of the provider set with WithTracerProvider, or
+// of the global provider.
+func (c *Client) tracer() trace.Tracer {
+	tp := c.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName, trace.WithInstrumentationVersion(version.String()))
+}
+
+// startSpan starts the span of a Client operation, named "jvs.<op>" like
+// the op of its audit correlation. End it with endSpan.
+func (c *Client) startSpan(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return c.tracer().Start(ctx, "jvs."+op, trace.WithAttributes(attrs...))
+}
+
+// phaseSpans returns an observer that records each phase of operation op
+// as a child span of ctx's span, named "jvs.<op>.<phase>".
+func (c *Client) phaseSpans(ctx context.Context, op string) snapshot.PhaseObserver {
+	tracer := c.tracer()
+	return func(phase string, start, end time.Time) {
+		_, span := tracer.Start(ctx, "jvs."+op+"."+phase, trace.WithTimestamp(start))
+		span.End(trace.WithTimestamp(end))
+	}
+}
+
+// endSpan ends span, marking it failed if err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
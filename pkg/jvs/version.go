@@ -0,0 +1,48 @@
+package jvs
+
+import (
+	"fmt"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/version"
+)
+
+// VersionInfo reports the version of the jvs library and CLI, and the
+// formats it reads and writes.
+type VersionInfo struct {
+	// Version is the jvs release, or "dev" for development builds.
+	Version string `json:"version"`
+	// FormatVersion is the repository format written by this build, and
+	// MinFormatVersion the oldest it opens.
+	FormatVersion    int `json:"format_version"`
+	MinFormatVersion int `json:"min_format_version"`
+	// BundleFormatVersion is the newest export bundle format read and
+	// written.
+	BundleFormatVersion int `json:"bundle_format_version"`
+	// RepoFormatVersion is the format version of the open repository; zero
+	// when no repository is open.
+	RepoFormatVersion int `json:"repo_format_version,omitempty"`
+}
+
+// BuildVersion returns the versions of this build, without a repository.
+func BuildVersion() VersionInfo {
+	return VersionInfo{
+		Version:             version.String(),
+		FormatVersion:       repo.FormatVersion,
+		MinFormatVersion:    repo.MinFormatVersion,
+		BundleFormatVersion: bundle.FormatVersion,
+	}
+}
+
+// Version returns the versions of this build and the format version of the
+// repository.
+func (c *Client) Version() (VersionInfo, error) {
+	info := BuildVersion()
+	v, err := repo.ReadFormatVersion(c.repoRoot)
+	if err != nil {
+		return info, fmt.Errorf("jvs version: %w", err)
+	}
+	info.RepoFormatVersion = v
+	return info, nil
+}
@@ -0,0 +1,16 @@
+package jvs
+
+import (
+	"context"
+
+	"github.com/jvs-project/jvs/internal/webhook"
+)
+
+// FlushWebhooks waits until the completion events queued by Snapshot,
+// Restore and GC calls have been delivered to the repository's webhooks,
+// or have failed after retries, or until ctx is done. Calls do not wait for
+// delivery themselves; a program that exits soon after one should call
+// FlushWebhooks first, or its events may be lost.
+func FlushWebhooks(ctx context.Context) error {
+	return webhook.Flush(ctx)
+}
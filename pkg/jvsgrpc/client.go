@@ -0,0 +1,90 @@
+package jvsgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// Client calls a JVS gRPC server. Errors returned by the server are
+// *Error values.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient creates a Client using the connection cc, for example one
+// opened with grpc.NewClient.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// Snapshot creates a snapshot. progress, if not nil, is called as each
+// step starts.
+func (c *Client) Snapshot(ctx context.Context, req *SnapshotRequest, progress func(*Progress)) (*model.Descriptor, error) {
+	return call[model.Descriptor](ctx, c, SnapshotMethod, req, progress)
+}
+
+// Restore restores a worktree and returns its config afterwards.
+// progress, if not nil, is called as each step starts.
+func (c *Client) Restore(ctx context.Context, req *RestoreRequest, progress func(*Progress)) (*model.WorktreeConfig, error) {
+	return call[model.WorktreeConfig](ctx, c, RestoreMethod, req, progress)
+}
+
+// GC creates a GC plan, runs it unless req.DryRun is set, and returns it.
+// progress, if not nil, is called as each step starts.
+func (c *Client) GC(ctx context.Context, req *GCRequest, progress func(*Progress)) (*model.GCPlan, error) {
+	return call[model.GCPlan](ctx, c, GCMethod, req, progress)
+}
+
+// Verify verifies one snapshot, or all of them if req.SnapshotID is
+// empty. progress, if not nil, is called as each step starts.
+func (c *Client) Verify(ctx context.Context, req *VerifyRequest, progress func(*Progress)) (*jvs.VerifyReport, error) {
+	return call[jvs.VerifyReport](ctx, c, VerifyMethod, req, progress)
+}
+
+// History lists a worktree's snapshots, newest first.
+func (c *Client) History(ctx context.Context, req *HistoryRequest) ([]*model.Descriptor, error) {
+	var reply HistoryReply
+	if err := c.cc.Invoke(ctx, HistoryMethod, req, &reply, grpc.CallContentSubtype(codecName)); err != nil {
+		return nil, fromStatus(err)
+	}
+	return reply.Snapshots, nil
+}
+
+// call makes a server-streaming call to method, passing progress replies to
+// progress, and returns the result.
+func call[T any](ctx context.Context, c *Client, method string, req any, progress func(*Progress)) (*T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s, err := c.cc.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, method, grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	if err := s.SendMsg(req); err != nil {
+		return nil, fromStatus(err)
+	}
+	if err := s.CloseSend(); err != nil {
+		return nil, fromStatus(err)
+	}
+	for {
+		var reply Reply[T]
+		if err := s.RecvMsg(&reply); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%s: stream ended without a result", method)
+			}
+			return nil, fromStatus(err)
+		}
+		if reply.Result != nil {
+			return reply.Result, nil
+		}
+		if reply.Progress != nil && progress != nil {
+			progress(reply.Progress)
+		}
+	}
+}
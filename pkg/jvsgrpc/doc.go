@@ -0,0 +1,37 @@
+// Package jvsgrpc serves a jvs.Interface over gRPC and provides a client for
+// it, so that programs such as Kubernetes controllers can drive a
+// repository through 'jvs serve --grpc' instead of exec-ing the CLI on the
+// node that mounts it.
+//
+// # Service
+//
+// The service is jvs.v1.JVS:
+//
+//	Snapshot(SnapshotRequest) returns (stream Reply)  creates a snapshot
+//	Restore(RestoreRequest)   returns (stream Reply)  restores a worktree
+//	GC(GCRequest)             returns (stream Reply)  plans and runs garbage collection
+//	Verify(VerifyRequest)     returns (stream Reply)  verifies one or all snapshots
+//	History(HistoryRequest)   returns (HistoryReply)  lists a worktree's snapshots
+//
+// The streaming methods send Progress replies as each step of the operation
+// starts, then one reply with the result. Snapshot and Restore also send
+// Progress replies as they copy data, counting its bytes in Done and Total.
+//
+// # Wire format
+//
+// Messages are JSON, with the content type application/grpc+json; the
+// field names are those of the json tags in this package. Go clients use
+// Client, which selects the codec itself. Errors carry a gRPC status code
+// and a google.rpc.ErrorInfo detail in the "jvs" domain whose reason is the
+// JVS error class, such as E_WORKTREE_BUSY; Client turns them back into
+// errors that match the errclass sentinels with errors.Is.
+//
+// # Authentication
+//
+// Servers built with the options of TokenAuth refuse calls that do not
+// present their token as a bearer token in the authorization metadata,
+// with codes.Unauthenticated; clients present it with WithToken. 'jvs
+// serve --grpc' requires the token in JVS_SERVE_TOKEN. Without transport
+// security the token travels in clear text: listen on a local or otherwise
+// protected address.
+package jvsgrpc
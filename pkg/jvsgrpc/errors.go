@@ -0,0 +1,97 @@
+package jvsgrpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+// errorDomain is the ErrorInfo domain of JVS error classes.
+const errorDomain = "jvs"
+
+// errorClasses maps each JVS error class to the gRPC code it is sent with.
+var errorClasses = []struct {
+	class *errclass.JVSError
+	code  codes.Code
+}{
+	{errclass.ErrNameInvalid, codes.InvalidArgument},
+	{errclass.ErrPathEscape, codes.InvalidArgument},
+	{errclass.ErrDescriptorCorrupt, codes.DataLoss},
+	{errclass.ErrPayloadHashMismatch, codes.DataLoss},
+	{errclass.ErrLineageBroken, codes.DataLoss},
+	{errclass.ErrPartialSnapshot, codes.DataLoss},
+	{errclass.ErrAuditChainBroken, codes.DataLoss},
+	{errclass.ErrGCPlanMismatch, codes.Aborted},
+	{errclass.ErrFormatUnsupported, codes.FailedPrecondition},
+	{errclass.ErrWorktreeBusy, codes.FailedPrecondition},
+	{errclass.ErrSnapshotNotReady, codes.FailedPrecondition},
+	{errclass.ErrWorktreeFrozen, codes.FailedPrecondition},
+	{errclass.ErrWorktreeLocked, codes.FailedPrecondition},
+	{errclass.ErrLockTimeout, codes.Unavailable},
+	{errclass.ErrLockLost, codes.Aborted},
+	{errclass.ErrStorage, codes.Unavailable},
+	{errclass.ErrQuotaExceeded, codes.ResourceExhausted},
+	{errclass.ErrNotARepo, codes.FailedPrecondition},
+	{errclass.ErrSnapshotNotFound, codes.NotFound},
+	{errclass.ErrWorktreeNotFound, codes.NotFound},
+	{errclass.ErrDetachedHead, codes.FailedPrecondition},
+}
+
+// Error is an error returned by the server. errors.Is matches it against
+// the errclass sentinel of its class, if it has one.
+type Error struct {
+	Code    codes.Code
+	Class   string // JVS error class, such as E_WORKTREE_BUSY; empty if none
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the errclass error of e's class, or a group
+// of classes such as errclass.ErrIntegrityFailure that includes it.
+func (e *Error) Is(target error) bool {
+	return e.Class != "" && (&errclass.JVSError{Code: e.Class}).Is(target)
+}
+
+// toStatus converts an error of the library to a gRPC status error.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	for _, c := range errorClasses {
+		if !errors.Is(err, c.class) {
+			continue
+		}
+		st := status.New(c.code, err.Error())
+		if withInfo, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: c.class.Code, Domain: errorDomain}); detailErr == nil {
+			st = withInfo
+		}
+		return st.Err()
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// fromStatus converts a gRPC status error to an *Error.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	e := &Error{Code: st.Code(), Message: st.Message()}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+			e.Class = info.Reason
+		}
+	}
+	return e
+}
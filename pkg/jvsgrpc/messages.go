@@ -0,0 +1,134 @@
+package jvsgrpc
+
+import (
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// SnapshotRequest asks for a snapshot; see jvs.SnapshotOptions.
+type SnapshotRequest struct {
+	WorktreeName    string            `json:"worktree_name,omitempty"`
+	Note            string            `json:"note,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	PartialPaths    []string          `json:"partial_paths,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	SkipIfUnchanged bool              `json:"skip_if_unchanged,omitempty"`
+	Incremental     bool              `json:"incremental,omitempty"`
+}
+
+func (r *SnapshotRequest) options() jvs.SnapshotOptions {
+	return jvs.SnapshotOptions{
+		WorktreeName:    r.WorktreeName,
+		Note:            r.Note,
+		Tags:            r.Tags,
+		PartialPaths:    r.PartialPaths,
+		Annotations:     r.Annotations,
+		SkipIfUnchanged: r.SkipIfUnchanged,
+		Incremental:     r.Incremental,
+	}
+}
+
+// RestoreRequest asks for a restore; see jvs.RestoreOptions. The result
+// is the worktree's config after the restore.
+type RestoreRequest struct {
+	WorktreeName     string `json:"worktree_name,omitempty"`
+	Target           string `json:"target,omitempty"`
+	LatestWithoutTag string `json:"latest_without_tag,omitempty"`
+	LatestWithTag    string `json:"latest_with_tag,omitempty"`
+	Force            bool   `json:"force,omitempty"`
+	Ephemeral        bool   `json:"ephemeral,omitempty"`
+}
+
+func (r *RestoreRequest) options() jvs.RestoreOptions {
+	return jvs.RestoreOptions{
+		WorktreeName:     r.WorktreeName,
+		Target:           r.Target,
+		LatestWithoutTag: r.LatestWithoutTag,
+		LatestWithTag:    r.LatestWithTag,
+		Force:            r.Force,
+		Ephemeral:        r.Ephemeral,
+	}
+}
+
+// GCRequest asks for garbage collection; see jvs.GCOptions. The result is
+// the plan, which has been run unless DryRun is set.
+type GCRequest struct {
+	KeepMinSnapshots  int               `json:"keep_min_snapshots,omitempty"`
+	KeepMinAgeSeconds int64             `json:"keep_min_age_seconds,omitempty"`
+	DryRun            bool              `json:"dry_run,omitempty"`
+	KeepTags          []string          `json:"keep_tags,omitempty"`
+	TagRules          []model.TagRule   `json:"tag_rules,omitempty"`
+	Budgets           []model.TagBudget `json:"budgets,omitempty"`
+	CheckConflicts    bool              `json:"check_conflicts,omitempty"`
+
+	Worktrees map[string]GCWorktreeRetention `json:"worktrees,omitempty"`
+}
+
+// GCWorktreeRetention is the retention of one worktree's snapshots; see
+// model.WorktreeRetention.
+type GCWorktreeRetention struct {
+	KeepMinSnapshots  int   `json:"keep_min_snapshots,omitempty"`
+	KeepMinAgeSeconds int64 `json:"keep_min_age_seconds,omitempty"`
+}
+
+func (r *GCRequest) options() jvs.GCOptions {
+	var worktrees map[string]model.WorktreeRetention
+	if r.Worktrees != nil {
+		worktrees = make(map[string]model.WorktreeRetention, len(r.Worktrees))
+		for name, wr := range r.Worktrees {
+			worktrees[name] = model.WorktreeRetention{
+				KeepMinSnapshots: wr.KeepMinSnapshots,
+				KeepMinAge:       time.Duration(wr.KeepMinAgeSeconds) * time.Second,
+			}
+		}
+	}
+	return jvs.GCOptions{
+		KeepMinSnapshots: r.KeepMinSnapshots,
+		KeepMinAge:       time.Duration(r.KeepMinAgeSeconds) * time.Second,
+		DryRun:           r.DryRun,
+		KeepTags:         r.KeepTags,
+		TagRules:         r.TagRules,
+		Worktrees:        worktrees,
+		Budgets:          r.Budgets,
+		CheckConflicts:   r.CheckConflicts,
+	}
+}
+
+// VerifyRequest asks for the verification of a snapshot, or of every
+// snapshot if SnapshotID is empty. A snapshot that fails on its own is
+// reported as an error; failures found verifying every snapshot are listed
+// in the resulting report.
+type VerifyRequest struct {
+	SnapshotID model.SnapshotID `json:"snapshot_id,omitempty"`
+}
+
+// HistoryRequest asks for a worktree's snapshots, newest first; see
+// jvs.Client.History.
+type HistoryRequest struct {
+	WorktreeName string `json:"worktree_name,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+}
+
+// HistoryReply lists a worktree's snapshots.
+type HistoryReply struct {
+	Snapshots []*model.Descriptor `json:"snapshots"`
+}
+
+// Progress reports the step a streaming call has reached. Total is set
+// when the step works through a known number of items, or of bytes for the
+// copy step of Snapshot and the clone step of Restore, and Done counts
+// those worked through so far.
+type Progress struct {
+	Phase string `json:"phase"`
+	Done  int64  `json:"done,omitempty"`
+	Total int64  `json:"total,omitempty"`
+}
+
+// Reply is a message of a streaming call: either Progress or, last, the
+// Result.
+type Reply[T any] struct {
+	Progress *Progress `json:"progress,omitempty"`
+	Result   *T        `json:"result,omitempty"`
+}
@@ -0,0 +1,190 @@
+package jvsgrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// ServiceName is the full name of the gRPC service.
+const ServiceName = "jvs.v1.JVS"
+
+// Full method names.
+const (
+	SnapshotMethod = "/" + ServiceName + "/Snapshot"
+	RestoreMethod  = "/" + ServiceName + "/Restore"
+	GCMethod       = "/" + ServiceName + "/GC"
+	VerifyMethod   = "/" + ServiceName + "/Verify"
+	HistoryMethod  = "/" + ServiceName + "/History"
+)
+
+// Server implements the service on top of a jvs.Interface.
+type Server struct {
+	client jvs.Interface
+}
+
+// NewServer creates a Server running calls with client.
+func NewServer(client jvs.Interface) *Server {
+	return &Server{client: client}
+}
+
+// Register registers the service with a gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&serviceDesc, s)
+}
+
+// service is the handler type of serviceDesc.
+type service interface {
+	snapshot(ctx context.Context, req *SnapshotRequest, send func(*Reply[model.Descriptor]) error) error
+	restore(ctx context.Context, req *RestoreRequest, send func(*Reply[model.WorktreeConfig]) error) error
+	gc(ctx context.Context, req *GCRequest, send func(*Reply[model.GCPlan]) error) error
+	verify(ctx context.Context, req *VerifyRequest, send func(*Reply[jvs.VerifyReport]) error) error
+	history(ctx context.Context, req *HistoryRequest) (*HistoryReply, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "History", Handler: historyHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Snapshot", Handler: streamHandler(service.snapshot), ServerStreams: true},
+		{StreamName: "Restore", Handler: streamHandler(service.restore), ServerStreams: true},
+		{StreamName: "GC", Handler: streamHandler(service.gc), ServerStreams: true},
+		{StreamName: "Verify", Handler: streamHandler(service.verify), ServerStreams: true},
+	},
+}
+
+// streamHandler adapts a server-streaming method of service to a gRPC
+// stream handler.
+func streamHandler[Req, Res any](method func(service, context.Context, *Req, func(*Reply[Res]) error) error) grpc.StreamHandler {
+	return func(srv any, stream grpc.ServerStream) error {
+		req := new(Req)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		err := method(srv.(service), stream.Context(), req, func(reply *Reply[Res]) error {
+			return stream.SendMsg(reply)
+		})
+		return toStatus(err)
+	}
+}
+
+func historyHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := new(HistoryRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		reply, err := srv.(service).history(ctx, req.(*HistoryRequest))
+		return reply, toStatus(err)
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: HistoryMethod}, handler)
+}
+
+func (s *Server) snapshot(ctx context.Context, req *SnapshotRequest, send func(*Reply[model.Descriptor]) error) error {
+	if err := send(&Reply[model.Descriptor]{Progress: &Progress{Phase: "snapshot"}}); err != nil {
+		return err
+	}
+	opts := req.options()
+	opts.Progress = func(phase string, done, total int64) {
+		// A failed send means the stream is gone, which cancels ctx
+		send(&Reply[model.Descriptor]{Progress: &Progress{Phase: phase, Done: done, Total: total}})
+	}
+	desc, err := s.client.Snapshot(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return send(&Reply[model.Descriptor]{Result: desc})
+}
+
+func (s *Server) restore(ctx context.Context, req *RestoreRequest, send func(*Reply[model.WorktreeConfig]) error) error {
+	if err := send(&Reply[model.WorktreeConfig]{Progress: &Progress{Phase: "restore"}}); err != nil {
+		return err
+	}
+	opts := req.options()
+	opts.Progress = func(phase string, done, total int64) {
+		send(&Reply[model.WorktreeConfig]{Progress: &Progress{Phase: phase, Done: done, Total: total}})
+	}
+	if err := s.client.Restore(ctx, opts); err != nil {
+		return err
+	}
+	worktreeName := opts.WorktreeName
+	if worktreeName == "" {
+		worktreeName = "main"
+	}
+	cfg, err := s.client.Worktree(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+	return send(&Reply[model.WorktreeConfig]{Result: cfg})
+}
+
+func (s *Server) gc(ctx context.Context, req *GCRequest, send func(*Reply[model.GCPlan]) error) error {
+	if err := send(&Reply[model.GCPlan]{Progress: &Progress{Phase: "plan"}}); err != nil {
+		return err
+	}
+	opts := req.options()
+	if opts.DryRun || opts.CheckConflicts {
+		// Conflicts are checked while the plan runs, which RunGC does not do
+		plan, err := s.client.GC(ctx, opts)
+		if err != nil {
+			return err
+		}
+		return send(&Reply[model.GCPlan]{Result: plan})
+	}
+
+	// Planned and run separately, so the deletions can be announced
+	opts.DryRun = true
+	plan, err := s.client.GC(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := send(&Reply[model.GCPlan]{Progress: &Progress{Phase: "run", Total: int64(len(plan.ToDelete))}}); err != nil {
+		return err
+	}
+	if err := s.client.RunGC(ctx, plan.PlanID); err != nil {
+		return err
+	}
+	return send(&Reply[model.GCPlan]{Result: plan})
+}
+
+func (s *Server) verify(ctx context.Context, req *VerifyRequest, send func(*Reply[jvs.VerifyReport]) error) error {
+	if req.SnapshotID == "" {
+		if err := send(&Reply[jvs.VerifyReport]{Progress: &Progress{Phase: "verify"}}); err != nil {
+			return err
+		}
+		report, err := s.client.VerifyAll(ctx)
+		if err != nil {
+			return err
+		}
+		return send(&Reply[jvs.VerifyReport]{Result: report})
+	}
+
+	if err := send(&Reply[jvs.VerifyReport]{Progress: &Progress{Phase: "verify", Total: 1}}); err != nil {
+		return err
+	}
+	if err := s.client.Verify(ctx, req.SnapshotID); err != nil {
+		return err
+	}
+	return send(&Reply[jvs.VerifyReport]{Result: &jvs.VerifyReport{Snapshots: 1}})
+}
+
+func (s *Server) history(ctx context.Context, req *HistoryRequest) (*HistoryReply, error) {
+	worktreeName := req.WorktreeName
+	if worktreeName == "" {
+		worktreeName = "main"
+	}
+	snapshots, err := s.client.History(ctx, worktreeName, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryReply{Snapshots: snapshots}, nil
+}
@@ -0,0 +1,182 @@
+package jvsgrpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/jvsgrpc"
+)
+
+// testToken is the token servers started by startServer require.
+const testToken = "s3cret"
+
+// startServer serves a new repository over an in-memory connection and
+// returns the library client behind the server and a client connected to it.
+func startServer(t *testing.T) (*jvs.Client, *jvsgrpc.Client) {
+	t.Helper()
+	lib, ln := listen(t)
+	return lib, jvsgrpc.NewClient(dial(t, ln, jvsgrpc.WithToken(testToken)))
+}
+
+// listen serves a new repository over an in-memory listener, requiring
+// testToken.
+func listen(t *testing.T) (*jvs.Client, *bufconn.Listener) {
+	t.Helper()
+	lib, err := jvs.Init(t.TempDir(), jvs.InitOptions{Name: "test"})
+	require.NoError(t, err)
+
+	ln := bufconn.Listen(1 << 20)
+	auth, err := jvsgrpc.TokenAuth(testToken)
+	require.NoError(t, err)
+	srv := grpc.NewServer(auth...)
+	jvsgrpc.NewServer(lib).Register(srv)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return lib, ln
+}
+
+func dial(t *testing.T, ln *bufconn.Listener, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	_, ln := listen(t)
+	ctx := context.Background()
+
+	for name, opts := range map[string][]grpc.DialOption{
+		"missing": nil,
+		"wrong":   {jvsgrpc.WithToken("guess")},
+	} {
+		client := jvsgrpc.NewClient(dial(t, ln, opts...))
+		var rpcErr *jvsgrpc.Error
+		_, err := client.History(ctx, &jvsgrpc.HistoryRequest{})
+		require.ErrorAs(t, err, &rpcErr, name)
+		assert.Equal(t, codes.Unauthenticated, rpcErr.Code, name)
+		_, err = client.Snapshot(ctx, &jvsgrpc.SnapshotRequest{Note: "denied"}, nil)
+		require.ErrorAs(t, err, &rpcErr, name)
+		assert.Equal(t, codes.Unauthenticated, rpcErr.Code, name)
+	}
+
+	_, err := jvsgrpc.NewClient(dial(t, ln, jvsgrpc.WithToken(testToken))).History(ctx, &jvsgrpc.HistoryRequest{})
+	require.NoError(t, err)
+
+	_, err = jvsgrpc.TokenAuth("")
+	require.Error(t, err)
+}
+
+func TestServer_SnapshotRestoreHistory(t *testing.T) {
+	lib, client := startServer(t)
+	ctx := context.Background()
+	file := filepath.Join(lib.WorktreePayloadPath("main"), "state.txt")
+
+	require.NoError(t, os.WriteFile(file, []byte("v1"), 0644))
+	var progress []jvsgrpc.Progress
+	first, err := client.Snapshot(ctx, &jvsgrpc.SnapshotRequest{Note: "first", Tags: []string{"v1"}}, func(p *jvsgrpc.Progress) {
+		progress = append(progress, *p)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []jvsgrpc.Progress{{Phase: "snapshot"}, {Phase: "copy", Total: 2}, {Phase: "copy", Done: 2, Total: 2}}, progress)
+	assert.Equal(t, "first", first.Note)
+	assert.Equal(t, []string{"v1"}, first.Tags)
+
+	require.NoError(t, os.WriteFile(file, []byte("v2"), 0644))
+	second, err := client.Snapshot(ctx, &jvsgrpc.SnapshotRequest{Note: "second"}, nil)
+	require.NoError(t, err)
+
+	history, err := client.History(ctx, &jvsgrpc.HistoryRequest{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, second.SnapshotID, history[0].SnapshotID)
+	assert.Equal(t, first.SnapshotID, history[1].SnapshotID)
+
+	progress = nil
+	cfg, err := client.Restore(ctx, &jvsgrpc.RestoreRequest{Target: "v1"}, func(p *jvsgrpc.Progress) {
+		progress = append(progress, *p)
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, progress)
+	last := progress[len(progress)-1]
+	assert.Equal(t, "clone", last.Phase)
+	assert.Equal(t, last.Total, last.Done)
+	assert.Equal(t, first.SnapshotID, cfg.HeadSnapshotID)
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+}
+
+func TestServer_VerifyAndGC(t *testing.T) {
+	_, client := startServer(t)
+	ctx := context.Background()
+
+	desc, err := client.Snapshot(ctx, &jvsgrpc.SnapshotRequest{}, nil)
+	require.NoError(t, err)
+
+	report, err := client.Verify(ctx, &jvsgrpc.VerifyRequest{SnapshotID: desc.SnapshotID}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Snapshots)
+	report, err = client.Verify(ctx, &jvsgrpc.VerifyRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Snapshots)
+	assert.Empty(t, report.Failures)
+
+	var phases []string
+	plan, err := client.GC(ctx, &jvsgrpc.GCRequest{}, func(p *jvsgrpc.Progress) {
+		phases = append(phases, p.Phase)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan", "run"}, phases)
+	assert.NotEmpty(t, plan.PlanID)
+	assert.Contains(t, plan.ProtectedSet, desc.SnapshotID)
+}
+
+func TestServer_ErrorClasses(t *testing.T) {
+	lib, client := startServer(t)
+	ctx := context.Background()
+
+	desc, err := client.Snapshot(ctx, &jvsgrpc.SnapshotRequest{}, nil)
+	require.NoError(t, err)
+	_, err = lib.AcquireLease(ctx, "main", "agent-pod", time.Hour)
+	require.NoError(t, err)
+
+	_, err = client.Restore(ctx, &jvsgrpc.RestoreRequest{Target: string(desc.SnapshotID)}, nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errclass.ErrWorktreeBusy))
+	var rpcErr *jvsgrpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, codes.FailedPrecondition, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "agent-pod")
+
+	_, err = client.Restore(ctx, &jvsgrpc.RestoreRequest{Target: "no-such-snapshot", Force: true}, nil)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, codes.NotFound, rpcErr.Code)
+	assert.True(t, errors.Is(err, errclass.ErrSnapshotNotFound))
+	assert.False(t, errors.Is(err, errclass.ErrWorktreeBusy))
+
+	// Groups of classes match on the client too
+	require.NoError(t, os.WriteFile(filepath.Join(lib.RepoRoot(), ".jvs", "snapshots", string(desc.SnapshotID), "tampered.txt"), []byte("x"), 0644))
+	_, err = client.Verify(ctx, &jvsgrpc.VerifyRequest{SnapshotID: desc.SnapshotID}, nil)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, codes.DataLoss, rpcErr.Code)
+	assert.True(t, errors.Is(err, errclass.ErrIntegrityFailure))
+}
@@ -0,0 +1,40 @@
+// Package jvshttp serves a jvs.Interface as a JSON REST API, for
+// lightweight integrations and for looking at a repository from a browser
+// through 'jvs serve --http'.
+//
+// # Endpoints
+//
+//	GET    /healthz                        reports that the server is up; needs no token
+//	GET    /v1/status[?worktree=<name>]     jvs.Client.Status
+//	GET    /v1/stats                        jvs.Client.Stats
+//	POST   /v1/snapshots                    creates a snapshot (SnapshotRequest)
+//	GET    /v1/snapshots/{id}               a snapshot's descriptor
+//	GET    /v1/resolve/{ref}                jvs.Client.Resolve: the descriptor a reference names
+//	POST   /v1/snapshots/{id}/verify        verifies a snapshot
+//	POST   /v1/verify                       verifies every snapshot
+//	GET    /v1/diff?from=<id>&to=<id>       jvs.Client.Diff
+//	GET    /v1/worktrees/{name}             a worktree's config
+//	GET    /v1/worktrees/{name}/history     its snapshots, newest first [?limit=N]
+//	POST   /v1/worktrees/{name}/restore     restores it (RestoreRequest)
+//	GET    /v1/worktrees/{name}/lease       its lease, null if none
+//	POST   /v1/worktrees/{name}/lease       acquires its lease (LeaseRequest)
+//	DELETE /v1/worktrees/{name}/lease?holder=<id>
+//	                                        releases its lease
+//	POST   /v1/gc                           plans GC, and runs it unless dry_run is set (GCRequest)
+//	POST   /v1/gc/{plan_id}/run             runs a GC plan
+//	GET    /v1/doctor[?strict=true]         jvs.Client.Doctor
+//
+// Request and response bodies are JSON, with the field names of the json
+// tags in this package and in package model. Errors are sent as
+//
+//	{"error": {"class": "E_WORKTREE_BUSY", "message": "..."}}
+//
+// with an HTTP status derived from the JVS error class; class is omitted
+// for errors that have none.
+//
+// # Authentication
+//
+// Every endpoint but /healthz requires the server's token, either as a
+// bearer token (Authorization: Bearer <token>) or, so that a browser can
+// prompt for it, as the password of HTTP basic authentication.
+package jvshttp
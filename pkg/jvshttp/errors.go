@@ -0,0 +1,62 @@
+package jvshttp
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/jvs-project/jvs/pkg/errclass"
+)
+
+// errorClasses maps each JVS error class to the HTTP status it is sent
+// with.
+var errorClasses = []struct {
+	class  *errclass.JVSError
+	status int
+}{
+	{errclass.ErrNameInvalid, http.StatusBadRequest},
+	{errclass.ErrPathEscape, http.StatusBadRequest},
+	{errclass.ErrDescriptorCorrupt, http.StatusInternalServerError},
+	{errclass.ErrPayloadHashMismatch, http.StatusInternalServerError},
+	{errclass.ErrLineageBroken, http.StatusInternalServerError},
+	{errclass.ErrPartialSnapshot, http.StatusInternalServerError},
+	{errclass.ErrAuditChainBroken, http.StatusInternalServerError},
+	{errclass.ErrGCPlanMismatch, http.StatusConflict},
+	{errclass.ErrFormatUnsupported, http.StatusConflict},
+	{errclass.ErrWorktreeBusy, http.StatusConflict},
+	{errclass.ErrSnapshotNotReady, http.StatusConflict},
+	{errclass.ErrWorktreeFrozen, http.StatusConflict},
+	{errclass.ErrWorktreeLocked, http.StatusConflict},
+	{errclass.ErrLockTimeout, http.StatusServiceUnavailable},
+	{errclass.ErrLockLost, http.StatusConflict},
+	{errclass.ErrStorage, http.StatusServiceUnavailable},
+	{errclass.ErrQuotaExceeded, http.StatusInsufficientStorage},
+	{errclass.ErrNotARepo, http.StatusNotFound},
+	{errclass.ErrSnapshotNotFound, http.StatusNotFound},
+	{errclass.ErrWorktreeNotFound, http.StatusNotFound},
+	{errclass.ErrDetachedHead, http.StatusConflict},
+}
+
+// toError converts an error of the library to an HTTP status and response
+// body.
+func toError(err error) (int, *Error) {
+	var badReq *badRequestError
+	if errors.As(err, &badReq) {
+		return http.StatusBadRequest, &Error{Message: err.Error()}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout, &Error{Message: err.Error()}
+	}
+	status, body := http.StatusInternalServerError, &Error{Message: err.Error()}
+	for _, c := range errorClasses {
+		if errors.Is(err, c.class) {
+			status, body.Class = c.status, c.class.Code
+			break
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		status = http.StatusNotFound
+	}
+	return status, body
+}
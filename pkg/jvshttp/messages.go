@@ -0,0 +1,114 @@
+package jvshttp
+
+import (
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// SnapshotRequest asks for a snapshot; see jvs.SnapshotOptions.
+type SnapshotRequest struct {
+	WorktreeName    string            `json:"worktree_name,omitempty"`
+	Note            string            `json:"note,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	PartialPaths    []string          `json:"partial_paths,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	SkipIfUnchanged bool              `json:"skip_if_unchanged,omitempty"`
+	Incremental     bool              `json:"incremental,omitempty"`
+}
+
+func (r *SnapshotRequest) options() jvs.SnapshotOptions {
+	return jvs.SnapshotOptions{
+		WorktreeName:    r.WorktreeName,
+		Note:            r.Note,
+		Tags:            r.Tags,
+		PartialPaths:    r.PartialPaths,
+		Annotations:     r.Annotations,
+		SkipIfUnchanged: r.SkipIfUnchanged,
+		Incremental:     r.Incremental,
+	}
+}
+
+// RestoreRequest asks for a restore of the worktree named in the path; see
+// jvs.RestoreOptions. The response is the worktree's config after the
+// restore.
+type RestoreRequest struct {
+	Target           string `json:"target,omitempty"`
+	LatestWithoutTag string `json:"latest_without_tag,omitempty"`
+	LatestWithTag    string `json:"latest_with_tag,omitempty"`
+	Force            bool   `json:"force,omitempty"`
+	Ephemeral        bool   `json:"ephemeral,omitempty"`
+}
+
+func (r *RestoreRequest) options(worktreeName string) jvs.RestoreOptions {
+	return jvs.RestoreOptions{
+		WorktreeName:     worktreeName,
+		Target:           r.Target,
+		LatestWithoutTag: r.LatestWithoutTag,
+		LatestWithTag:    r.LatestWithTag,
+		Force:            r.Force,
+		Ephemeral:        r.Ephemeral,
+	}
+}
+
+// GCRequest asks for garbage collection; see jvs.GCOptions. The response
+// is the plan, which has been run unless DryRun is set.
+type GCRequest struct {
+	KeepMinSnapshots  int               `json:"keep_min_snapshots,omitempty"`
+	KeepMinAgeSeconds int64             `json:"keep_min_age_seconds,omitempty"`
+	DryRun            bool              `json:"dry_run,omitempty"`
+	KeepTags          []string          `json:"keep_tags,omitempty"`
+	TagRules          []model.TagRule   `json:"tag_rules,omitempty"`
+	Budgets           []model.TagBudget `json:"budgets,omitempty"`
+	CheckConflicts    bool              `json:"check_conflicts,omitempty"`
+
+	Worktrees map[string]GCWorktreeRetention `json:"worktrees,omitempty"`
+}
+
+// GCWorktreeRetention is the retention of one worktree's snapshots; see
+// model.WorktreeRetention.
+type GCWorktreeRetention struct {
+	KeepMinSnapshots  int   `json:"keep_min_snapshots,omitempty"`
+	KeepMinAgeSeconds int64 `json:"keep_min_age_seconds,omitempty"`
+}
+
+func (r *GCRequest) options() jvs.GCOptions {
+	var worktrees map[string]model.WorktreeRetention
+	if r.Worktrees != nil {
+		worktrees = make(map[string]model.WorktreeRetention, len(r.Worktrees))
+		for name, wr := range r.Worktrees {
+			worktrees[name] = model.WorktreeRetention{
+				KeepMinSnapshots: wr.KeepMinSnapshots,
+				KeepMinAge:       time.Duration(wr.KeepMinAgeSeconds) * time.Second,
+			}
+		}
+	}
+	return jvs.GCOptions{
+		KeepMinSnapshots: r.KeepMinSnapshots,
+		KeepMinAge:       time.Duration(r.KeepMinAgeSeconds) * time.Second,
+		DryRun:           r.DryRun,
+		KeepTags:         r.KeepTags,
+		TagRules:         r.TagRules,
+		Worktrees:        worktrees,
+		Budgets:          r.Budgets,
+		CheckConflicts:   r.CheckConflicts,
+	}
+}
+
+// LeaseRequest asks for the lease of the worktree named in the path; see
+// jvs.Client.AcquireLease. The response is the lease.
+type LeaseRequest struct {
+	Holder     string `json:"holder"`
+	TTLSeconds int64  `json:"ttl_seconds"`
+}
+
+// Error is the body of an error response.
+type Error struct {
+	Class   string `json:"class,omitempty"` // JVS error class, such as E_WORKTREE_BUSY
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
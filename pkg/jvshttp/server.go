@@ -0,0 +1,255 @@
+package jvshttp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+// maxRequestBytes bounds the size of request bodies.
+const maxRequestBytes = 1 << 20
+
+// Server implements the API on top of a jvs.Interface. It is an
+// http.Handler.
+type Server struct {
+	client jvs.Interface
+	token  string
+	mux    *http.ServeMux
+}
+
+// NewServer creates a Server running calls with client for callers
+// presenting token, which must not be empty.
+func NewServer(client jvs.Interface, token string) (*Server, error) {
+	if token == "" {
+		return nil, fmt.Errorf("jvshttp: token is required")
+	}
+	s := &Server{client: client, token: token, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+	s.handle("GET /v1/status", s.status)
+	s.handle("GET /v1/stats", s.stats)
+	s.handle("POST /v1/snapshots", s.snapshot)
+	s.handle("GET /v1/snapshots/{id}", s.descriptor)
+	s.handle("GET /v1/resolve/{ref}", s.resolve)
+	s.handle("POST /v1/snapshots/{id}/verify", s.verify)
+	s.handle("POST /v1/verify", s.verifyAll)
+	s.handle("GET /v1/diff", s.diff)
+	s.handle("GET /v1/worktrees/{name}", s.worktree)
+	s.handle("GET /v1/worktrees/{name}/history", s.history)
+	s.handle("POST /v1/worktrees/{name}/restore", s.restore)
+	s.handle("GET /v1/worktrees/{name}/lease", s.lease)
+	s.handle("POST /v1/worktrees/{name}/lease", s.acquireLease)
+	s.handle("DELETE /v1/worktrees/{name}/lease", s.releaseLease)
+	s.handle("POST /v1/gc", s.gc)
+	s.handle("POST /v1/gc/{plan_id}/run", s.runGC)
+	s.handle("GET /v1/doctor", s.doctor)
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers an authenticated endpoint whose handler returns the
+// response body, or an error.
+func (s *Server) handle(pattern string, h func(r *http.Request) (any, error)) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="jvs"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]*Error{"error": {Message: "missing or invalid token"}})
+			return
+		}
+		res, err := h(r)
+		if err != nil {
+			status, body := toError(err)
+			writeJSON(w, status, map[string]*Error{"error": body})
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+}
+
+// authorized reports whether r carries the server's token.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, ok = r.BasicAuth()
+	}
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// decode reads the JSON request body into v. An empty body leaves v as is.
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return badRequest("decode request: %v", err)
+	}
+	return nil
+}
+
+// badRequestError is an invalid request, sent with status 400.
+type badRequestError struct {
+	msg string
+}
+
+func (e *badRequestError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return &badRequestError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) status(r *http.Request) (any, error) {
+	return s.client.Status(r.Context(), jvs.StatusOptions{Worktree: r.URL.Query().Get("worktree")})
+}
+
+func (s *Server) stats(r *http.Request) (any, error) {
+	return s.client.Stats(r.Context())
+}
+
+func (s *Server) snapshot(r *http.Request) (any, error) {
+	var req SnapshotRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	return s.client.Snapshot(r.Context(), req.options())
+}
+
+func (s *Server) descriptor(r *http.Request) (any, error) {
+	return s.client.Descriptor(r.Context(), model.SnapshotID(r.PathValue("id")))
+}
+
+func (s *Server) resolve(r *http.Request) (any, error) {
+	return s.client.Resolve(r.Context(), r.PathValue("ref"))
+}
+
+func (s *Server) verify(r *http.Request) (any, error) {
+	if err := s.client.Verify(r.Context(), model.SnapshotID(r.PathValue("id"))); err != nil {
+		return nil, err
+	}
+	return &jvs.VerifyReport{Snapshots: 1}, nil
+}
+
+func (s *Server) verifyAll(r *http.Request) (any, error) {
+	return s.client.VerifyAll(r.Context())
+}
+
+func (s *Server) diff(r *http.Request) (any, error) {
+	q := r.URL.Query()
+	return s.client.Diff(r.Context(), model.SnapshotID(q.Get("from")), model.SnapshotID(q.Get("to")), jvs.DiffOptions{})
+}
+
+func (s *Server) worktree(r *http.Request) (any, error) {
+	return s.client.Worktree(r.Context(), r.PathValue("name"))
+}
+
+func (s *Server) history(r *http.Request) (any, error) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, badRequest("invalid limit %q", v)
+		}
+		limit = n
+	}
+	// History does not fail for unknown worktrees, which should be a 404
+	if _, err := s.client.Worktree(r.Context(), r.PathValue("name")); err != nil {
+		return nil, err
+	}
+	snapshots, err := s.client.History(r.Context(), r.PathValue("name"), limit)
+	if err != nil {
+		return nil, err
+	}
+	if snapshots == nil {
+		snapshots = []*model.Descriptor{}
+	}
+	return snapshots, nil
+}
+
+func (s *Server) restore(r *http.Request) (any, error) {
+	var req RestoreRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	name := r.PathValue("name")
+	if err := s.client.Restore(r.Context(), req.options(name)); err != nil {
+		return nil, err
+	}
+	return s.client.Worktree(r.Context(), name)
+}
+
+func (s *Server) lease(r *http.Request) (any, error) {
+	return s.client.Lease(r.Context(), r.PathValue("name"))
+}
+
+func (s *Server) acquireLease(r *http.Request) (any, error) {
+	var req LeaseRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Holder == "" || req.TTLSeconds <= 0 {
+		return nil, badRequest("holder and a positive ttl_seconds are required")
+	}
+	return s.client.AcquireLease(r.Context(), r.PathValue("name"), req.Holder, time.Duration(req.TTLSeconds)*time.Second)
+}
+
+func (s *Server) releaseLease(r *http.Request) (any, error) {
+	holder := r.URL.Query().Get("holder")
+	if holder == "" {
+		return nil, badRequest("holder is required")
+	}
+	if err := s.client.ReleaseLease(r.Context(), r.PathValue("name"), holder); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (s *Server) gc(r *http.Request) (any, error) {
+	var req GCRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	return s.client.GC(r.Context(), req.options())
+}
+
+func (s *Server) runGC(r *http.Request) (any, error) {
+	if err := s.client.RunGC(r.Context(), r.PathValue("plan_id")); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (s *Server) doctor(r *http.Request) (any, error) {
+	strict := false
+	if v := r.URL.Query().Get("strict"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, badRequest("invalid strict %q", v)
+		}
+		strict = b
+	}
+	return s.client.Doctor(r.Context(), strict)
+}
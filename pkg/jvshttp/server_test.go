@@ -0,0 +1,176 @@
+package jvshttp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/jvshttp"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+const testToken = "s3cret"
+
+// startServer serves a new repository and returns the library client
+// behind the server and the server's URL.
+func startServer(t *testing.T) (*jvs.Client, string) {
+	t.Helper()
+	lib, err := jvs.Init(t.TempDir(), jvs.InitOptions{Name: "test"})
+	require.NoError(t, err)
+	srv, err := jvshttp.NewServer(lib, testToken)
+	require.NoError(t, err)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return lib, ts.URL
+}
+
+// do sends an authenticated request with body encoded as JSON, decodes the
+// response into out if it is not nil, and returns the status code.
+func do(t *testing.T, method, url string, body, out any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func TestNewServer_RequiresToken(t *testing.T) {
+	lib, err := jvs.Init(t.TempDir(), jvs.InitOptions{Name: "test"})
+	require.NoError(t, err)
+	_, err = jvshttp.NewServer(lib, "")
+	assert.Error(t, err)
+}
+
+func TestServer_Auth(t *testing.T) {
+	_, url := startServer(t)
+
+	resp, err := http.Get(url + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(url + "/v1/stats")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Basic realm="jvs"`, resp.Header.Get("WWW-Authenticate"))
+
+	req, err := http.NewRequest(http.MethodGet, url+"/v1/stats", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Browsers send the token as the basic auth password
+	req.Header.Del("Authorization")
+	req.SetBasicAuth("", testToken)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_SnapshotRestoreHistory(t *testing.T) {
+	lib, url := startServer(t)
+	file := filepath.Join(lib.WorktreePayloadPath("main"), "state.txt")
+
+	require.NoError(t, os.WriteFile(file, []byte("v1"), 0644))
+	var first model.Descriptor
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/snapshots", jvshttp.SnapshotRequest{Note: "first", Tags: []string{"v1"}}, &first))
+	assert.Equal(t, "first", first.Note)
+
+	require.NoError(t, os.WriteFile(file, []byte("v2"), 0644))
+	var second model.Descriptor
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/snapshots", nil, &second))
+
+	var history []*model.Descriptor
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, url+"/v1/worktrees/main/history?limit=1", nil, &history))
+	require.Len(t, history, 1)
+	assert.Equal(t, second.SnapshotID, history[0].SnapshotID)
+
+	var desc model.Descriptor
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, url+"/v1/snapshots/"+string(first.SnapshotID), nil, &desc))
+	assert.Equal(t, []string{"v1"}, desc.Tags)
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, url+"/v1/resolve/v1", nil, &desc))
+	assert.Equal(t, first.SnapshotID, desc.SnapshotID)
+
+	var cfg model.WorktreeConfig
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/worktrees/main/restore", jvshttp.RestoreRequest{Target: "v1"}, &cfg))
+	assert.Equal(t, first.SnapshotID, cfg.HeadSnapshotID)
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	var report jvs.VerifyReport
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/verify", nil, &report))
+	assert.Equal(t, 2, report.Snapshots)
+	assert.Empty(t, report.Failures)
+}
+
+func TestServer_LeaseAndGC(t *testing.T) {
+	_, url := startServer(t)
+	var snap model.Descriptor
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/snapshots", nil, &snap))
+
+	var lease model.Lease
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/worktrees/main/lease", jvshttp.LeaseRequest{Holder: "ci", TTLSeconds: 60}, &lease))
+	assert.Equal(t, "ci", lease.Holder)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lease.ExpiresAt, 5*time.Second)
+
+	var body struct {
+		Error jvshttp.Error `json:"error"`
+	}
+	restore := jvshttp.RestoreRequest{Target: string(snap.SnapshotID)}
+	assert.Equal(t, http.StatusConflict, do(t, http.MethodPost, url+"/v1/worktrees/main/restore", restore, &body))
+	assert.Equal(t, "E_WORKTREE_BUSY", body.Error.Class)
+	assert.Contains(t, body.Error.Message, "ci")
+
+	require.Equal(t, http.StatusOK, do(t, http.MethodDelete, url+"/v1/worktrees/main/lease?holder=ci", nil, nil))
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/worktrees/main/restore", restore, nil))
+
+	var plan model.GCPlan
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/gc", jvshttp.GCRequest{DryRun: true}, &plan))
+	assert.Contains(t, plan.ProtectedSet, snap.SnapshotID)
+	require.Equal(t, http.StatusOK, do(t, http.MethodPost, url+"/v1/gc/"+plan.PlanID+"/run", nil, nil))
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, url := startServer(t)
+	var body struct {
+		Error jvshttp.Error `json:"error"`
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, url+"/v1/worktrees/nope/history", nil, &body))
+	assert.Equal(t, "E_WORKTREE_NOT_FOUND", body.Error.Class)
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, url+"/v1/resolve/nightly", nil, &body))
+	assert.Equal(t, "E_SNAPSHOT_NOT_FOUND", body.Error.Class)
+
+	assert.Equal(t, http.StatusBadRequest, do(t, http.MethodPost, url+"/v1/snapshots", map[string]any{"bogus": 1}, &body))
+	assert.Contains(t, body.Error.Message, "bogus")
+
+	assert.Equal(t, http.StatusBadRequest, do(t, http.MethodGet, url+"/v1/worktrees/main/history?limit=x", nil, &body))
+
+	assert.Equal(t, http.StatusBadRequest, do(t, http.MethodPost, url+"/v1/worktrees/main/lease", jvshttp.LeaseRequest{Holder: "ci"}, &body))
+}
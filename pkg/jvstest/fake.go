@@ -0,0 +1,1260 @@
+// Package jvstest provides an in-memory fake of jvs.Interface for testing
+// code that drives JVS, such as sandbox managers, without a repository on
+// disk.
+//
+// The fake keeps worktrees, snapshot descriptors and leases in memory and
+// follows the lineage rules of the real client: snapshots advance HEAD,
+// restoring an older snapshot detaches the worktree and snapshots are refused
+// while detached. Payloads are not modeled; SetDirty stands in for changes
+// to them. Every call is recorded, and
+// FailWith makes a method return an error, for testing failure handling:
+//
+//	fake := jvstest.NewFakeClient("/repos/agent-1")
+//	fake.FailWith("Restore", jvs.ErrWorktreeBusy)
+//	err := manager.Reset(ctx, fake) // code under test
+//	assert.ErrorIs(t, err, jvs.ErrWorktreeBusy)
+//	assert.Equal(t, []string{"Restore"}, fake.Calls())
+package jvstest
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/internal/gc"
+	"github.com/jvs-project/jvs/internal/identity"
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/model"
+	"github.com/jvs-project/jvs/pkg/pathutil"
+)
+
+// FakeClient is an in-memory jvs.Interface. It is safe for concurrent use.
+type FakeClient struct {
+	mu         sync.Mutex
+	repoRoot   string
+	repoID     string
+	engineType model.EngineType
+	now        func() time.Time
+	identity   string
+
+	worktrees map[string]*model.WorktreeConfig
+	snapshots map[model.SnapshotID]*model.Descriptor
+	leases    map[string]*model.Lease
+	pins      map[model.SnapshotID]*model.Pin
+	gcPlans   map[string]*model.GCPlan
+	schedule  func(ctx context.Context, opts jvs.GCScheduleOptions) error
+	dirty     map[string]bool
+	errs      map[string]error
+	calls     []string
+	seq       int
+	cache     bool
+	registry  *prometheus.Registry
+	events    jvs.EventBus
+	pending   []jvs.Event // published once the call unlocks
+}
+
+var _ jvs.Interface = (*FakeClient)(nil)
+
+// NewFakeClient returns a fake client for a repository at repoRoot with an
+// empty main worktree.
+func NewFakeClient(repoRoot string) *FakeClient {
+	f := &FakeClient{
+		repoRoot:   repoRoot,
+		repoID:     "fake-" + filepath.Base(repoRoot),
+		engineType: model.EngineCopy,
+		now:        time.Now,
+		worktrees:  make(map[string]*model.WorktreeConfig),
+		snapshots:  make(map[model.SnapshotID]*model.Descriptor),
+		leases:     make(map[string]*model.Lease),
+		pins:       make(map[model.SnapshotID]*model.Pin),
+		gcPlans:    make(map[string]*model.GCPlan),
+		dirty:      make(map[string]bool),
+		errs:       make(map[string]error),
+		registry:   prometheus.NewRegistry(),
+	}
+	f.worktrees["main"] = &model.WorktreeConfig{Name: "main", CreatedAt: f.now().UTC()}
+	return f
+}
+
+// FailWith makes every later call of the named method, e.g. "Snapshot",
+// return err without changing any state. A nil err restores the normal
+// behavior. Methods without an error result ignore it.
+func (f *FakeClient) FailWith(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errs, method)
+	} else {
+		f.errs[method] = err
+	}
+}
+
+// SetClock replaces the clock used to stamp snapshots and leases and to
+// expire leases.
+func (f *FakeClient) SetClock(now func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// SetEngineType sets the engine EngineType reports.
+func (f *FakeClient) SetEngineType(engineType model.EngineType) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.engineType = engineType
+}
+
+// SetIdentity sets the author recorded on snapshots, as jvs.WithIdentity
+// does. Empty, the default, records none.
+func (f *FakeClient) SetIdentity(identity string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.identity = identity
+}
+
+// SetScheduleGC replaces what ScheduleGC does after recording the call,
+// e.g. to report canned cycles to opts.OnCycle or to return at once. A nil
+// fn restores the default of running cycles on the fake.
+func (f *FakeClient) SetScheduleGC(fn func(ctx context.Context, opts jvs.GCScheduleOptions) error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule = fn
+}
+
+// SetDirty marks a worktree's payload as changed since its HEAD snapshot,
+// as reported by Status with StatusOptions.Worktree. Snapshots and restores
+// of the worktree clear the mark.
+func (f *FakeClient) SetDirty(worktreeName string, dirty bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dirty[nameOrMain(worktreeName)] = dirty
+}
+
+// Calls returns the names of the methods called so far, in order.
+func (f *FakeClient) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// begin locks the fake, records a call of method and returns the error set
+// with FailWith. The caller must unlock.
+func (f *FakeClient) begin(method string) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, method)
+	return f.errs[method]
+}
+
+// end unlocks the fake and then publishes the events queued by the call,
+// so that subscribers may call back into it.
+func (f *FakeClient) end() {
+	pending := f.pending
+	f.pending = nil
+	f.mu.Unlock()
+	for _, e := range pending {
+		f.events.Publish(e)
+	}
+}
+
+// queue stamps an event with the fake clock for end to publish.
+func (f *FakeClient) queue(e jvs.Event) {
+	e.Time = f.now().UTC()
+	f.pending = append(f.pending, e)
+}
+
+func nameOrMain(name string) string {
+	if name == "" {
+		return "main"
+	}
+	return name
+}
+
+func (f *FakeClient) worktree(name string) (*model.WorktreeConfig, error) {
+	cfg, ok := f.worktrees[name]
+	if !ok {
+		return nil, fmt.Errorf("get worktree: worktree %s not found", name)
+	}
+	return cfg, nil
+}
+
+func (f *FakeClient) descriptor(id model.SnapshotID) (*model.Descriptor, error) {
+	desc, ok := f.snapshots[id]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	return desc, nil
+}
+
+// activeLease returns the unexpired lease on a worktree, dropping an
+// expired one.
+func (f *FakeClient) activeLease(name string) *model.Lease {
+	l := f.leases[name]
+	if l != nil && l.Expired(f.now()) {
+		delete(f.leases, name)
+		return nil
+	}
+	return l
+}
+
+// history returns the snapshots of a worktree, newest first.
+func (f *FakeClient) history(name string) []*model.Descriptor {
+	var descs []*model.Descriptor
+	for _, d := range f.snapshots {
+		if d.WorktreeName == name {
+			descs = append(descs, d)
+		}
+	}
+	slices.SortFunc(descs, model.CompareNewestFirst)
+	return descs
+}
+
+func copyDescriptor(d *model.Descriptor) *model.Descriptor {
+	cp := *d
+	cp.Tags = slices.Clone(d.Tags)
+	cp.PartialPaths = slices.Clone(d.PartialPaths)
+	return &cp
+}
+
+func copyWorktree(cfg *model.WorktreeConfig) *model.WorktreeConfig {
+	cp := *cfg
+	return &cp
+}
+
+// Snapshot records a new snapshot of the worktree and advances its HEAD.
+func (f *FakeClient) Snapshot(_ context.Context, opts jvs.SnapshotOptions) (*model.Descriptor, error) {
+	err := f.begin("Snapshot")
+	defer f.end()
+	if err != nil {
+		return nil, err
+	}
+	name := nameOrMain(opts.WorktreeName)
+	cfg, err := f.worktree(name)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.IsDetached() && len(opts.PartialPaths) == 0 {
+		return nil, fmt.Errorf("cannot create snapshot in detached state at %s: restore HEAD or fork first", cfg.HeadSnapshotID)
+	}
+
+	f.seq++
+	now := f.now().UTC()
+	desc := &model.Descriptor{
+		SnapshotID:     model.SnapshotID(fmt.Sprintf("%d-%08x", now.UnixMilli(), f.seq)),
+		WorktreeName:   name,
+		CreatedAt:      now,
+		Author:         f.identity,
+		Note:           opts.Note,
+		Tags:           slices.Clone(opts.Tags),
+		Engine:         f.engineType,
+		IntegrityState: model.IntegrityVerified,
+		PartialPaths:   slices.Clone(opts.PartialPaths),
+	}
+	if len(opts.Annotations) > 0 {
+		desc.Annotations = make(map[string]string, len(opts.Annotations))
+		for k, v := range opts.Annotations {
+			desc.Annotations[k] = v
+		}
+	}
+	if cfg.HeadSnapshotID != "" {
+		parent := cfg.HeadSnapshotID
+		desc.ParentID = &parent
+	}
+	f.snapshots[desc.SnapshotID] = desc
+	cfg.HeadSnapshotID = desc.SnapshotID
+	cfg.LatestSnapshotID = desc.SnapshotID
+	delete(f.dirty, name)
+	f.queue(jvs.Event{Type: jvs.EventSnapshotCreated, Worktree: name, SnapshotID: desc.SnapshotID, Descriptor: copyDescriptor(desc)})
+	return copyDescriptor(desc), nil
+}
+
+// Restore moves the worktree's HEAD to the target snapshot, which is
+// resolved like the real client does: "HEAD" or empty for the latest
+// snapshot, otherwise a snapshot ID prefix or a tag.
+func (f *FakeClient) Restore(_ context.Context, opts jvs.RestoreOptions) error {
+	return f.restore("Restore", opts)
+}
+
+// RestoreLatest moves the worktree's HEAD to its latest snapshot.
+func (f *FakeClient) RestoreLatest(_ context.Context, worktreeName string) error {
+	return f.restore("RestoreLatest", jvs.RestoreOptions{WorktreeName: worktreeName})
+}
+
+func (f *FakeClient) restore(method string, opts jvs.RestoreOptions) error {
+	err := f.begin(method)
+	defer f.end()
+	if err != nil {
+		return err
+	}
+	name := nameOrMain(opts.WorktreeName)
+	cfg, err := f.worktree(name)
+	if err != nil {
+		return err
+	}
+
+	var target *model.Descriptor
+	switch {
+	case opts.LatestWithoutTag != "" && opts.LatestWithTag != "":
+		return fmt.Errorf("LatestWithoutTag and LatestWithTag are mutually exclusive")
+	case opts.LatestWithoutTag != "" || opts.LatestWithTag != "":
+		for _, d := range f.history(name) {
+			if opts.LatestWithTag != "" && slices.Contains(d.Tags, opts.LatestWithTag) ||
+				opts.LatestWithoutTag != "" && !slices.Contains(d.Tags, opts.LatestWithoutTag) {
+				target = d
+				break
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("resolve target: no matching snapshot in worktree %s", name)
+		}
+	case opts.Target == "" || opts.Target == "HEAD":
+		if cfg.LatestSnapshotID == "" {
+			return nil
+		}
+		target = f.snapshots[cfg.LatestSnapshotID]
+	default:
+		if target = f.resolve(opts.Target); target == nil {
+			return fmt.Errorf("resolve target %q: no snapshot or tag matches", opts.Target)
+		}
+	}
+
+	if l := f.activeLease(name); l != nil && !opts.Force {
+		return &jvs.WorktreeBusyError{Lease: l}
+	}
+	if !opts.Ephemeral {
+		cfg.HeadSnapshotID = target.SnapshotID
+	}
+	delete(f.dirty, name)
+	f.queue(jvs.Event{Type: jvs.EventRestoreCompleted, Worktree: name, SnapshotID: target.SnapshotID})
+	return nil
+}
+
+// resolve finds a snapshot by unique ID prefix, then by tag (newest first).
+func (f *FakeClient) resolve(query string) *model.Descriptor {
+	var match *model.Descriptor
+	for id, d := range f.snapshots {
+		if strings.HasPrefix(string(id), query) {
+			if match != nil {
+				return nil // ambiguous
+			}
+			match = d
+		}
+	}
+	if match != nil {
+		return match
+	}
+	for _, d := range f.snapshots {
+		if slices.Contains(d.Tags, query) && (match == nil || d.CreatedAt.After(match.CreatedAt)) {
+			match = d
+		}
+	}
+	return match
+}
+
+// Fork creates a worktree starting at snapshotID.
+func (f *FakeClient) Fork(_ context.Context, snapshotID model.SnapshotID, name string) (*model.WorktreeConfig, error) {
+	err := f.begin("Fork")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.descriptor(snapshotID); err != nil {
+		return nil, fmt.Errorf("verify snapshot: %w", err)
+	}
+	if _, ok := f.worktrees[name]; ok {
+		return nil, fmt.Errorf("worktree %s already exists", name)
+	}
+	cfg := &model.WorktreeConfig{
+		Name:             name,
+		BaseSnapshotID:   snapshotID,
+		HeadSnapshotID:   snapshotID,
+		LatestSnapshotID: snapshotID,
+		CreatedAt:        f.now().UTC(),
+	}
+	f.worktrees[name] = cfg
+	return copyWorktree(cfg), nil
+}
+
+// MaterializeAt returns the record of a copy of the snapshot beside the
+// worktree, expiring after jvs.DefaultMaterializeTTL. No copy is made.
+func (f *FakeClient) MaterializeAt(_ context.Context, worktreeName string, snapshotID model.SnapshotID) (*model.Materialization, error) {
+	err := f.begin("MaterializeAt")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	name := nameOrMain(worktreeName)
+	if _, err := f.worktree(name); err != nil {
+		return nil, err
+	}
+	if _, err := f.descriptor(snapshotID); err != nil {
+		return nil, err
+	}
+	now := f.now().UTC()
+	return &model.Materialization{
+		WorktreeName: name,
+		SnapshotID:   snapshotID,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(jvs.DefaultMaterializeTTL),
+		Path:         filepath.Join(f.repoRoot, ".jvs", "materialized", name, string(snapshotID)),
+	}, nil
+}
+
+// Export writes a bundle holding only the snapshot's descriptor, which
+// Import reads back, as payloads are not modeled. The bundle is neither
+// compressed nor encrypted.
+func (f *FakeClient) Export(_ context.Context, w io.Writer, snapshotID model.SnapshotID, _ jvs.ExportOptions) (*jvs.ExportResult, error) {
+	err := f.begin("Export")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := f.descriptor(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(desc)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(w)
+	hdr := &tar.Header{Name: bundle.DescriptorName, Mode: 0644, Size: int64(len(data)), ModTime: desc.CreatedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &jvs.ExportResult{SnapshotID: snapshotID}, nil
+}
+
+// Import registers the descriptor of a bundle written by Export or 'jvs
+// export'. The payload and integrity metadata are not read.
+func (f *FakeClient) Import(_ context.Context, r io.Reader, opts jvs.ImportOptions) (*jvs.ImportResult, error) {
+	err := f.begin("Import")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	identities, err := bundle.ParseIdentities(opts.Identities)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.worktrees[opts.Worktree]; ok && opts.Worktree != "" {
+		return nil, fmt.Errorf("worktree %s already exists", opts.Worktree)
+	}
+	desc, err := bundle.ReadDescriptor(r, bundle.ImportOptions{Identities: identities})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.snapshots[desc.SnapshotID]; ok {
+		return nil, fmt.Errorf("snapshot %s already exists", desc.SnapshotID)
+	}
+	f.snapshots[desc.SnapshotID] = copyDescriptor(desc)
+	result := &jvs.ImportResult{Descriptor: copyDescriptor(desc)}
+	if opts.Worktree != "" {
+		cfg := &model.WorktreeConfig{
+			Name:             opts.Worktree,
+			BaseSnapshotID:   desc.SnapshotID,
+			HeadSnapshotID:   desc.SnapshotID,
+			LatestSnapshotID: desc.SnapshotID,
+			CreatedAt:        f.now().UTC(),
+		}
+		f.worktrees[opts.Worktree] = cfg
+		result.Worktree = copyWorktree(cfg)
+	}
+	return result, nil
+}
+
+// AddTags adds tags to a snapshot, keeping each tag once.
+func (f *FakeClient) AddTags(_ context.Context, snapshotID model.SnapshotID, tags []string) (*model.Descriptor, error) {
+	err := f.begin("AddTags")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := f.descriptor(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if err := pathutil.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range tags {
+		if !slices.Contains(desc.Tags, tag) {
+			desc.Tags = append(desc.Tags, tag)
+		}
+	}
+	return copyDescriptor(desc), nil
+}
+
+// RemoveTags removes tags from a snapshot.
+func (f *FakeClient) RemoveTags(_ context.Context, snapshotID model.SnapshotID, tags []string) (*model.Descriptor, error) {
+	err := f.begin("RemoveTags")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := f.descriptor(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	desc.Tags = slices.DeleteFunc(desc.Tags, func(t string) bool { return slices.Contains(tags, t) })
+	return copyDescriptor(desc), nil
+}
+
+// Annotate replaces or appends to a snapshot's note.
+func (f *FakeClient) Annotate(_ context.Context, snapshotID model.SnapshotID, note string, opts jvs.AnnotateOptions) (*model.Descriptor, error) {
+	err := f.begin("Annotate")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := f.descriptor(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Append && desc.Note != "" {
+		desc.Note += "; " + note
+	} else {
+		desc.Note = note
+	}
+	return copyDescriptor(desc), nil
+}
+
+// Promote makes a detached worktree's HEAD its latest snapshot.
+func (f *FakeClient) Promote(_ context.Context, worktreeName string) error {
+	err := f.begin("Promote")
+	defer f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	cfg, err := f.worktree(nameOrMain(worktreeName))
+	if err != nil {
+		return err
+	}
+	if !cfg.IsDetached() {
+		return fmt.Errorf("worktree %s is not detached", cfg.Name)
+	}
+	cfg.LatestSnapshotID = cfg.HeadSnapshotID
+	return nil
+}
+
+// AcquireLease leases a worktree to holder, renewing a lease it already
+// holds. Returns a *jvs.WorktreeBusyError if another holder's lease is
+// active.
+func (f *FakeClient) AcquireLease(_ context.Context, worktreeName, holder string, ttl time.Duration) (*model.Lease, error) {
+	err := f.begin("AcquireLease")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.worktree(worktreeName); err != nil {
+		return nil, err
+	}
+	if holder == "" {
+		return nil, fmt.Errorf("lease holder is required")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lease ttl must be positive")
+	}
+	if l := f.activeLease(worktreeName); l != nil && l.Holder != holder {
+		return nil, &jvs.WorktreeBusyError{Lease: l}
+	}
+	now := f.now().UTC()
+	l := &model.Lease{WorktreeName: worktreeName, Holder: holder, AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+	f.leases[worktreeName] = l
+	cp := *l
+	return &cp, nil
+}
+
+// ReleaseLease releases a lease held by holder.
+func (f *FakeClient) ReleaseLease(_ context.Context, worktreeName, holder string) error {
+	err := f.begin("ReleaseLease")
+	defer f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	l := f.activeLease(worktreeName)
+	if l == nil {
+		return nil
+	}
+	if l.Holder != holder {
+		return &jvs.WorktreeBusyError{Lease: l}
+	}
+	delete(f.leases, worktreeName)
+	return nil
+}
+
+// Lease returns the active lease on a worktree, or nil.
+func (f *FakeClient) Lease(_ context.Context, worktreeName string) (*model.Lease, error) {
+	err := f.begin("Lease")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	l := f.activeLease(worktreeName)
+	if l == nil {
+		return nil, nil
+	}
+	cp := *l
+	return &cp, nil
+}
+
+// Pin pins a snapshot, replacing its pin if it has one.
+func (f *FakeClient) Pin(_ context.Context, snapshotID model.SnapshotID, opts jvs.PinOptions) (*model.Pin, error) {
+	err := f.begin("Pin")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.descriptor(snapshotID); err != nil {
+		return nil, err
+	}
+	if opts.TTL < 0 {
+		return nil, fmt.Errorf("pin ttl must be non-negative")
+	}
+	now := f.now().UTC()
+	p := &model.Pin{SnapshotID: snapshotID, PinnedAt: now, Reason: opts.Reason}
+	if opts.TTL > 0 {
+		expiresAt := now.Add(opts.TTL)
+		p.ExpiresAt = &expiresAt
+	}
+	f.pins[snapshotID] = p
+	cp := *p
+	return &cp, nil
+}
+
+// Unpin removes the pin of a snapshot, if any.
+func (f *FakeClient) Unpin(_ context.Context, snapshotID model.SnapshotID) error {
+	err := f.begin("Unpin")
+	defer f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	delete(f.pins, snapshotID)
+	return nil
+}
+
+// ListPins returns all pins, expired ones included, oldest first.
+func (f *FakeClient) ListPins(_ context.Context) ([]*model.Pin, error) {
+	err := f.begin("ListPins")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var pins []*model.Pin
+	for _, p := range f.pins {
+		cp := *p
+		pins = append(pins, &cp)
+	}
+	slices.SortFunc(pins, func(a, b *model.Pin) int {
+		if c := a.PinnedAt.Compare(b.PinnedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.SnapshotID), string(b.SnapshotID))
+	})
+	return pins, nil
+}
+
+// History returns the worktree's snapshots, newest first.
+func (f *FakeClient) History(_ context.Context, worktreeName string, limit int) ([]*model.Descriptor, error) {
+	err := f.begin("History")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	descs := f.history(nameOrMain(worktreeName))
+	if limit > 0 && len(descs) > limit {
+		descs = descs[:limit]
+	}
+	out := make([]*model.Descriptor, len(descs))
+	for i, d := range descs {
+		out[i] = copyDescriptor(d)
+	}
+	return out, nil
+}
+
+// ListSnapshots pages through the snapshots selected by opts, newest
+// first. Its cursor is the last snapshot ID of the previous page, so a
+// cursor whose snapshot was deleted is rejected.
+func (f *FakeClient) ListSnapshots(_ context.Context, opts jvs.ListOptions) (*jvs.SnapshotPage, error) {
+	err := f.begin("ListSnapshots")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
+	if limit == 0 {
+		limit = jvs.DefaultListLimit
+	}
+	var after *model.Descriptor
+	if opts.Cursor != "" {
+		if after = f.snapshots[model.SnapshotID(opts.Cursor)]; after == nil {
+			return nil, fmt.Errorf("invalid page cursor %q", opts.Cursor)
+		}
+	}
+
+	var descs []*model.Descriptor
+	for _, d := range f.snapshots {
+		switch {
+		case opts.Worktree != "" && d.WorktreeName != opts.Worktree,
+			after != nil && model.CompareNewestFirst(after, d) >= 0,
+			!opts.Since.IsZero() && d.CreatedAt.Before(opts.Since),
+			!opts.Until.IsZero() && d.CreatedAt.After(opts.Until),
+			opts.Author != "" && !identity.Matches(d.Author, opts.Author),
+			slices.ContainsFunc(opts.Tags, func(t string) bool { return !slices.Contains(d.Tags, t) }):
+			continue
+		}
+		descs = append(descs, d)
+	}
+	slices.SortFunc(descs, model.CompareNewestFirst)
+
+	page := &jvs.SnapshotPage{Snapshots: []*model.Descriptor{}}
+	if len(descs) > limit {
+		descs = descs[:limit]
+		page.NextCursor = string(descs[limit-1].SnapshotID)
+	}
+	for _, d := range descs {
+		page.Snapshots = append(page.Snapshots, copyDescriptor(d))
+	}
+	return page, nil
+}
+
+// LatestSnapshot returns the worktree's latest snapshot, or nil.
+func (f *FakeClient) LatestSnapshot(_ context.Context, worktreeName string) (*model.Descriptor, error) {
+	err := f.begin("LatestSnapshot")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := f.worktree(nameOrMain(worktreeName))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.LatestSnapshotID == "" {
+		return nil, nil
+	}
+	return copyDescriptor(f.snapshots[cfg.LatestSnapshotID]), nil
+}
+
+// HasSnapshots reports whether the worktree has a snapshot.
+func (f *FakeClient) HasSnapshots(_ context.Context, worktreeName string) (bool, error) {
+	err := f.begin("HasSnapshots")
+	defer f.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+	cfg, err := f.worktree(nameOrMain(worktreeName))
+	if err != nil {
+		return false, err
+	}
+	return cfg.LatestSnapshotID != "", nil
+}
+
+// Descriptor returns a snapshot's descriptor.
+func (f *FakeClient) Descriptor(_ context.Context, snapshotID model.SnapshotID) (*model.Descriptor, error) {
+	err := f.begin("Descriptor")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := f.descriptor(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return copyDescriptor(desc), nil
+}
+
+// Resolve returns the snapshot ref names, resolved like Restore resolves
+// its target. The fake has no external resolvers.
+func (f *FakeClient) Resolve(_ context.Context, ref string) (*model.Descriptor, error) {
+	err := f.begin("Resolve")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if desc, ok := f.snapshots[model.SnapshotID(ref)]; ok {
+		return copyDescriptor(desc), nil
+	}
+	desc := f.resolve(ref)
+	if desc == nil {
+		return nil, fmt.Errorf("resolve %q: no snapshot or tag matches: %w", ref, fs.ErrNotExist)
+	}
+	return copyDescriptor(desc), nil
+}
+
+// Worktree returns a worktree's config.
+func (f *FakeClient) Worktree(_ context.Context, worktreeName string) (*model.WorktreeConfig, error) {
+	err := f.begin("Worktree")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := f.worktree(nameOrMain(worktreeName))
+	if err != nil {
+		return nil, err
+	}
+	return copyWorktree(cfg), nil
+}
+
+// Stats counts worktrees and snapshots. SnapshotBytes is always zero.
+func (f *FakeClient) Stats(_ context.Context) (*jvs.RepoStats, error) {
+	err := f.begin("Stats")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return f.stats(), nil
+}
+
+func (f *FakeClient) stats() *jvs.RepoStats {
+	stats := &jvs.RepoStats{Worktrees: len(f.worktrees), Snapshots: len(f.snapshots)}
+	for _, d := range f.snapshots {
+		if stats.OldestSnapshot.IsZero() || d.CreatedAt.Before(stats.OldestSnapshot) {
+			stats.OldestSnapshot = d.CreatedAt
+		}
+		if d.CreatedAt.After(stats.NewestSnapshot) {
+			stats.NewestSnapshot = d.CreatedAt
+		}
+	}
+	return stats
+}
+
+// Status reports worktrees and leases. There are never operations in
+// progress, lock waiters or audit events. Changes lists no files; Dirty is
+// set with SetDirty.
+func (f *FakeClient) Status(_ context.Context, opts jvs.StatusOptions) (*jvs.RepoStatus, error) {
+	err := f.begin("Status")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	status := &jvs.RepoStatus{
+		CollectedAt: f.now().UTC(),
+		Stats:       f.stats(),
+		Worktrees:   []jvs.WorktreeStatus{},
+		Operations:  []jvs.ActiveOperation{},
+		LockQueue:   []*model.LockTicket{},
+		Events:      []*model.AuditRecord{},
+	}
+	names := make([]string, 0, len(f.worktrees))
+	for name := range f.worktrees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cfg := f.worktrees[name]
+		status.Worktrees = append(status.Worktrees, jvs.WorktreeStatus{
+			Name:             name,
+			HeadSnapshotID:   cfg.HeadSnapshotID,
+			LatestSnapshotID: cfg.LatestSnapshotID,
+			Detached:         cfg.IsDetached(),
+			Frozen:           cfg.Frozen,
+			Lease:            f.activeLease(name),
+		})
+	}
+	if opts.Worktree != "" {
+		cfg, err := f.worktree(opts.Worktree)
+		if err != nil {
+			return nil, err
+		}
+		status.Changes = &jvs.WorktreeChanges{
+			Worktree:       opts.Worktree,
+			HeadSnapshotID: cfg.HeadSnapshotID,
+			Dirty:          f.dirty[opts.Worktree],
+			Added:          []*jvs.FileChange{},
+			Removed:        []*jvs.FileChange{},
+			Modified:       []*jvs.FileChange{},
+		}
+	}
+	return status, nil
+}
+
+// Diff checks that both snapshots exist and reports no changes, as payloads
+// are not modeled.
+func (f *FakeClient) Diff(_ context.Context, from, to model.SnapshotID, _ jvs.DiffOptions) (*jvs.DiffResult, error) {
+	err := f.begin("Diff")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	result := &jvs.DiffResult{FromSnapshotID: from, ToSnapshotID: to}
+	if from != "" {
+		desc, err := f.descriptor(from)
+		if err != nil {
+			return nil, err
+		}
+		result.FromTime = desc.CreatedAt
+	}
+	desc, err := f.descriptor(to)
+	if err != nil {
+		return nil, err
+	}
+	result.ToTime = desc.CreatedAt
+	return result, nil
+}
+
+// DiffWorktree checks that the snapshot and worktree exist and reports no
+// changes, as payloads are not modeled.
+func (f *FakeClient) DiffWorktree(_ context.Context, from model.SnapshotID, worktreeName string, _ jvs.DiffOptions) (*jvs.DiffResult, error) {
+	err := f.begin("DiffWorktree")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	worktreeName = nameOrMain(worktreeName)
+	if _, err := f.worktree(worktreeName); err != nil {
+		return nil, err
+	}
+	result := &jvs.DiffResult{FromSnapshotID: from, ToWorktree: worktreeName}
+	if from != "" {
+		desc, err := f.descriptor(from)
+		if err != nil {
+			return nil, err
+		}
+		result.FromTime = desc.CreatedAt
+	}
+	return result, nil
+}
+
+// Verify succeeds for any snapshot the fake knows.
+func (f *FakeClient) Verify(_ context.Context, snapshotID model.SnapshotID) error {
+	err := f.begin("Verify")
+	defer f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = f.descriptor(snapshotID)
+	return err
+}
+
+// VerifyAll reports every snapshot as intact.
+func (f *FakeClient) VerifyAll(_ context.Context) (*jvs.VerifyReport, error) {
+	err := f.begin("VerifyAll")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &jvs.VerifyReport{Snapshots: len(f.snapshots)}, nil
+}
+
+// Doctor reports a healthy repository.
+func (f *FakeClient) Doctor(_ context.Context, _ bool) (*jvs.DoctorResult, error) {
+	err := f.begin("Doctor")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &jvs.DoctorResult{Healthy: true}, nil
+}
+
+// GC plans the deletion of snapshots that are older than KeepMinAge and
+// are not the base, HEAD or latest snapshot of a worktree, pinned, nor
+// carry one of KeepTags, and deletes them unless DryRun is set. KeepMinSnapshots and
+// Budgets are ignored, and the repository retention policy is the default one.
+func (f *FakeClient) GC(_ context.Context, opts jvs.GCOptions) (*model.GCPlan, error) {
+	err := f.begin("GC")
+	defer f.end()
+	if err != nil {
+		return nil, err
+	}
+	policy := model.DefaultRetentionPolicy()
+	if opts.Policy != nil {
+		policy = *opts.Policy
+	}
+	if opts.KeepMinAge > 0 {
+		policy.KeepMinAge = opts.KeepMinAge
+	}
+	if opts.KeepTags != nil {
+		policy.KeepTags = opts.KeepTags
+	}
+
+	plan := f.plan(policy)
+	if opts.DryRun {
+		f.gcPlans[plan.PlanID] = plan
+		return plan, nil
+	}
+	f.deleteSnapshots(plan.PlanID, plan.ToDelete)
+	return plan, nil
+}
+
+// GCPlan plans GC under policy as GC does, saving the plan for RunGC.
+func (f *FakeClient) GCPlan(_ context.Context, policy model.RetentionPolicy) (*model.GCPlan, error) {
+	err := f.begin("GCPlan")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	plan := f.plan(policy)
+	f.gcPlans[plan.PlanID] = plan
+	return plan, nil
+}
+
+// plan applies the KeepMinAge and KeepTags of policy, pins and worktree
+// lineage to every snapshot.
+func (f *FakeClient) plan(policy model.RetentionPolicy) *model.GCPlan {
+	protected := make(map[model.SnapshotID]bool)
+	for _, cfg := range f.worktrees {
+		protected[cfg.BaseSnapshotID] = true
+		protected[cfg.HeadSnapshotID] = true
+		protected[cfg.LatestSnapshotID] = true
+	}
+	f.seq++
+	now := f.now()
+	pinned := make(map[model.SnapshotID]bool)
+	for id, p := range f.pins {
+		pinned[id] = !p.Expired(now)
+	}
+	plan := &model.GCPlan{
+		PlanID:          fmt.Sprintf("fake-plan-%d", f.seq),
+		CreatedAt:       now.UTC(),
+		ProtectedSet:    []model.SnapshotID{},
+		ToDelete:        []model.SnapshotID{},
+		RetentionPolicy: policy,
+	}
+	for id, d := range f.snapshots {
+		switch {
+		case protected[id]:
+			plan.ProtectedSet = append(plan.ProtectedSet, id)
+			plan.ProtectedByLineage++
+		case pinned[id]:
+			plan.ProtectedSet = append(plan.ProtectedSet, id)
+			plan.ProtectedByPin++
+		case now.Sub(d.CreatedAt) < policy.KeepMinAge,
+			slices.ContainsFunc(d.Tags, func(t string) bool { return slices.Contains(policy.KeepTags, t) }):
+			plan.ProtectedSet = append(plan.ProtectedSet, id)
+			plan.ProtectedByRetention++
+		default:
+			plan.ToDelete = append(plan.ToDelete, id)
+		}
+	}
+	slices.Sort(plan.ProtectedSet)
+	slices.Sort(plan.ToDelete)
+	plan.CandidateCount = len(plan.ToDelete)
+	return plan
+}
+
+// RunGC deletes the snapshots of a plan returned by GCPlan or a dry-run GC.
+func (f *FakeClient) RunGC(_ context.Context, planID string) error {
+	err := f.begin("RunGC")
+	defer f.end()
+	if err != nil {
+		return err
+	}
+	plan, ok := f.gcPlans[planID]
+	if !ok {
+		return fmt.Errorf("load plan: plan %s not found", planID)
+	}
+	delete(f.gcPlans, planID)
+	f.deleteSnapshots(planID, plan.ToDelete)
+	return nil
+}
+
+// ScheduleGC runs a GC cycle at once and then every opts.Interval until ctx
+// is cancelled, and then returns nil. Cycles plan as GCPlan does with the
+// policy of opts.Policy, or the default one, call opts.BeforeRun before
+// deleting anything and report to opts.OnCycle. Jitter and CheckConflicts
+// are ignored. SetScheduleGC replaces this behavior.
+func (f *FakeClient) ScheduleGC(ctx context.Context, opts jvs.GCScheduleOptions) error {
+	err := f.begin("ScheduleGC")
+	schedule := f.schedule
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if schedule != nil {
+		return schedule(ctx, opts)
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = gc.DefaultScheduleInterval
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			cycle := f.gcCycle(ctx, opts)
+			if opts.OnCycle != nil {
+				opts.OnCycle(cycle)
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// gcCycle plans and runs one scheduled GC. The fake is unlocked while
+// opts.Policy and opts.BeforeRun run, so they may call back into it.
+func (f *FakeClient) gcCycle(ctx context.Context, opts jvs.GCScheduleOptions) *jvs.GCCycle {
+	f.mu.Lock()
+	cycle := &jvs.GCCycle{StartedAt: f.now().UTC()}
+	f.mu.Unlock()
+
+	policy := model.DefaultRetentionPolicy()
+	if opts.Policy != nil {
+		p, err := opts.Policy()
+		if err != nil {
+			cycle.Error = err.Error()
+			return cycle
+		}
+		policy = p
+	}
+
+	f.mu.Lock()
+	plan := f.plan(policy)
+	f.mu.Unlock()
+	cycle.PlanID = plan.PlanID
+	cycle.Planned = len(plan.ToDelete)
+	if len(plan.ToDelete) == 0 {
+		return cycle
+	}
+	if opts.BeforeRun != nil {
+		if err := opts.BeforeRun(ctx, plan); err != nil {
+			cycle.Error = "before run: " + err.Error()
+			return cycle
+		}
+	}
+
+	f.mu.Lock()
+	for _, id := range plan.ToDelete {
+		if _, ok := f.snapshots[id]; ok {
+			cycle.Deleted = append(cycle.Deleted, id)
+		}
+	}
+	f.deleteSnapshots(plan.PlanID, cycle.Deleted)
+	f.end()
+	return cycle
+}
+
+func (f *FakeClient) deleteSnapshots(planID string, ids []model.SnapshotID) {
+	for _, id := range ids {
+		delete(f.snapshots, id)
+	}
+	if len(ids) > 0 {
+		f.queue(jvs.Event{Type: jvs.EventGCDeleted, PlanID: planID, Deleted: slices.Clone(ids)})
+	}
+}
+
+// VerifyAudit reports an empty, valid audit log: the fake writes none.
+func (f *FakeClient) VerifyAudit(_ context.Context) (*jvs.AuditVerifyResult, error) {
+	err := f.begin("VerifyAudit")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &jvs.AuditVerifyResult{}, nil
+}
+
+// EnableAuditBatching is not supported: the fake writes no audit log.
+func (f *FakeClient) EnableAuditBatching(_ jvs.AuditBatchOptions) (*jvs.AuditBatch, error) {
+	err := f.begin("EnableAuditBatching")
+	defer f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("jvstest: audit batching is not supported by FakeClient")
+}
+
+// EnableMetadataCache makes CacheStats report the cache as enabled.
+func (f *FakeClient) EnableMetadataCache(_ int) {
+	f.begin("EnableMetadataCache")
+	defer f.mu.Unlock()
+	f.cache = true
+}
+
+// InvalidateMetadataCache does nothing.
+func (f *FakeClient) InvalidateMetadataCache() {
+	f.begin("InvalidateMetadataCache")
+	f.mu.Unlock()
+}
+
+// CacheStats reports whether EnableMetadataCache was called; the counters
+// are always zero.
+func (f *FakeClient) CacheStats() jvs.CacheStats {
+	f.begin("CacheStats")
+	defer f.mu.Unlock()
+	return jvs.CacheStats{Enabled: f.cache}
+}
+
+// Subscribe calls fn with the events of Snapshot, Restore, RestoreLatest,
+// GC and RunGC. The fake detects no engine degradations.
+func (f *FakeClient) Subscribe(fn func(jvs.Event)) (unsubscribe func()) {
+	f.begin("Subscribe")
+	f.mu.Unlock()
+	return f.events.Subscribe(fn)
+}
+
+// Events returns a channel receiving the events Subscribe reports until
+// ctx is done.
+func (f *FakeClient) Events(ctx context.Context, buffer int) <-chan jvs.Event {
+	f.begin("Events")
+	f.mu.Unlock()
+	return f.events.Events(ctx, buffer)
+}
+
+// Metrics returns an empty registry; the fake records no metrics.
+func (f *FakeClient) Metrics() *prometheus.Registry {
+	f.begin("Metrics")
+	defer f.mu.Unlock()
+	return f.registry
+}
+
+// RepoRoot returns the root passed to NewFakeClient.
+func (f *FakeClient) RepoRoot() string {
+	return f.repoRoot
+}
+
+// RepoID returns an ID derived from the repository root.
+func (f *FakeClient) RepoID() string {
+	return f.repoID
+}
+
+// Version returns the versions of this build, with the fake repository at
+// the format version it writes.
+func (f *FakeClient) Version() (jvs.VersionInfo, error) {
+	info := jvs.BuildVersion()
+	err := f.begin("Version")
+	defer f.mu.Unlock()
+	if err != nil {
+		return info, err
+	}
+	info.RepoFormatVersion = info.FormatVersion
+	return info, nil
+}
+
+// EngineType returns the engine set with SetEngineType, copy by default.
+func (f *FakeClient) EngineType() model.EngineType {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.engineType
+}
+
+// WorktreePayloadPath returns the path the worktree would have in a real
+// repository at the fake's root. Nothing exists there.
+func (f *FakeClient) WorktreePayloadPath(worktreeName string) string {
+	name := nameOrMain(worktreeName)
+	if name == "main" {
+		return filepath.Join(f.repoRoot, "main")
+	}
+	return filepath.Join(f.repoRoot, "worktrees", name)
+}
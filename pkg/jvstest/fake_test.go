@@ -0,0 +1,377 @@
+package jvstest_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/jvstest"
+	"github.com/jvs-project/jvs/pkg/model"
+)
+
+func TestFakeClient_Lineage(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+
+	has, err := fake.HasSnapshots(ctx, "")
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	first, err := fake.Snapshot(ctx, jvs.SnapshotOptions{Note: "first", Tags: []string{"stable"}})
+	require.NoError(t, err)
+	second, err := fake.Snapshot(ctx, jvs.SnapshotOptions{Note: "second"})
+	require.NoError(t, err)
+	require.NotNil(t, second.ParentID)
+	assert.Equal(t, first.SnapshotID, *second.ParentID)
+
+	history, err := fake.History(ctx, "main", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, second.SnapshotID, history[0].SnapshotID)
+
+	diff, err := fake.Diff(ctx, first.SnapshotID, second.SnapshotID, jvs.DiffOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, second.SnapshotID, diff.ToSnapshotID)
+	diff, err = fake.DiffWorktree(ctx, second.SnapshotID, "", jvs.DiffOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "main", diff.ToWorktree)
+
+	// Restoring by tag detaches the worktree and blocks snapshots
+	require.NoError(t, fake.Restore(ctx, jvs.RestoreOptions{Target: "stable"}))
+	cfg, err := fake.Worktree(ctx, "main")
+	require.NoError(t, err)
+	assert.True(t, cfg.IsDetached())
+	_, err = fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.Error(t, err)
+
+	forked, err := fake.Fork(ctx, first.SnapshotID, "experiment")
+	require.NoError(t, err)
+	assert.Equal(t, first.SnapshotID, forked.HeadSnapshotID)
+
+	require.NoError(t, fake.RestoreLatest(ctx, "main"))
+	latest, err := fake.LatestSnapshot(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, second.SnapshotID, latest.SnapshotID)
+}
+
+func TestFakeClient_Leases(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.SetClock(func() time.Time { return now })
+	_, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	_, err = fake.AcquireLease(ctx, "main", "pod-a", time.Minute)
+	require.NoError(t, err)
+	_, err = fake.AcquireLease(ctx, "main", "pod-b", time.Minute)
+	require.ErrorIs(t, err, jvs.ErrWorktreeBusy)
+	err = fake.Restore(ctx, jvs.RestoreOptions{Target: "HEAD"})
+	var busy *jvs.WorktreeBusyError
+	require.ErrorAs(t, err, &busy)
+	assert.Equal(t, "pod-a", busy.Lease.Holder)
+
+	now = now.Add(2 * time.Minute)
+	l, err := fake.Lease(ctx, "main")
+	require.NoError(t, err)
+	assert.Nil(t, l, "expired")
+	_, err = fake.AcquireLease(ctx, "main", "pod-b", time.Minute)
+	require.NoError(t, err)
+}
+
+func TestFakeClient_Dirty(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	_, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	fake.SetDirty("main", true)
+	status, err := fake.Status(ctx, jvs.StatusOptions{Worktree: "main"})
+	require.NoError(t, err)
+	assert.True(t, status.Changes.Dirty)
+
+	_, err = fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	status, err = fake.Status(ctx, jvs.StatusOptions{Worktree: "main"})
+	require.NoError(t, err)
+	assert.False(t, status.Changes.Dirty, "a snapshot records the changes")
+
+	_, err = fake.Status(ctx, jvs.StatusOptions{Worktree: "missing"})
+	require.Error(t, err)
+}
+
+func TestFakeClient_FailWith(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	boom := errors.New("boom")
+
+	fake.FailWith("Snapshot", boom)
+	_, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.ErrorIs(t, err, boom)
+	has, err := fake.HasSnapshots(ctx, "main")
+	require.NoError(t, err)
+	assert.False(t, has, "a failed call changes nothing")
+
+	fake.FailWith("Snapshot", nil)
+	_, err = fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Snapshot", "HasSnapshots", "Snapshot"}, fake.Calls())
+}
+
+func TestFakeClient_GC(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.SetClock(func() time.Time { return now })
+	old, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	now = now.Add(48 * time.Hour)
+
+	plan, err := fake.GC(ctx, jvs.GCOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, plan.ToDelete, 1)
+	assert.Equal(t, old.SnapshotID, plan.ToDelete[0])
+	require.NoError(t, fake.RunGC(ctx, plan.PlanID))
+	require.Error(t, fake.Verify(ctx, old.SnapshotID))
+	require.Error(t, fake.RunGC(ctx, plan.PlanID), "plans run once")
+
+	plan, err = fake.GCPlan(ctx, model.RetentionPolicy{KeepMinAge: 72 * time.Hour})
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+}
+
+func TestFakeClient_ScheduleGC(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.SetClock(func() time.Time { return now })
+	old, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	now = now.Add(48 * time.Hour)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	var cycles []*jvs.GCCycle
+	err = fake.ScheduleGC(runCtx, jvs.GCScheduleOptions{
+		Interval: time.Hour,
+		OnCycle: func(c *jvs.GCCycle) {
+			cycles = append(cycles, c)
+			cancel()
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, cycles, 1)
+	assert.Equal(t, []model.SnapshotID{old.SnapshotID}, cycles[0].Deleted)
+	require.Error(t, fake.Verify(ctx, old.SnapshotID))
+
+	fake.SetScheduleGC(func(_ context.Context, opts jvs.GCScheduleOptions) error {
+		opts.OnCycle(&jvs.GCCycle{PlanID: "canned", Error: "run: boom"})
+		return nil
+	})
+	cycles = nil
+	require.NoError(t, fake.ScheduleGC(ctx, jvs.GCScheduleOptions{OnCycle: func(c *jvs.GCCycle) { cycles = append(cycles, c) }}))
+	require.Len(t, cycles, 1)
+	assert.Equal(t, "canned", cycles[0].PlanID)
+
+	fake.FailWith("ScheduleGC", jvs.ErrWorktreeBusy)
+	assert.ErrorIs(t, fake.ScheduleGC(ctx, jvs.GCScheduleOptions{}), jvs.ErrWorktreeBusy)
+}
+
+func TestFakeClient_VerifyAudit(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	result, err := fake.VerifyAudit(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+
+	fake.FailWith("VerifyAudit", errors.New("audit log unreadable"))
+	_, err = fake.VerifyAudit(ctx)
+	assert.Error(t, err)
+}
+
+func TestFakeClient_Version(t *testing.T) {
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	info, err := fake.Version()
+	require.NoError(t, err)
+	assert.Equal(t, jvs.BuildVersion().Version, info.Version)
+	assert.Equal(t, info.FormatVersion, info.RepoFormatVersion)
+
+	fake.FailWith("Version", errors.New("format version unreadable"))
+	_, err = fake.Version()
+	assert.Error(t, err)
+}
+
+func TestFakeClient_Tags(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	desc, err := fake.Snapshot(ctx, jvs.SnapshotOptions{Tags: []string{"nightly"}})
+	require.NoError(t, err)
+
+	_, err = fake.AddTags(ctx, desc.SnapshotID, []string{"release"})
+	require.NoError(t, err)
+	resolved, err := fake.Resolve(ctx, "release")
+	require.NoError(t, err)
+	assert.Equal(t, desc.SnapshotID, resolved.SnapshotID)
+	amended, err := fake.RemoveTags(ctx, desc.SnapshotID, []string{"nightly"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"release"}, amended.Tags)
+}
+
+func TestFakeClient_Annotate(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	desc, err := fake.Snapshot(ctx, jvs.SnapshotOptions{Note: "build"})
+	require.NoError(t, err)
+
+	amended, err := fake.Annotate(ctx, desc.SnapshotID, "tests pass", jvs.AnnotateOptions{Append: true})
+	require.NoError(t, err)
+	assert.Equal(t, "build; tests pass", amended.Note)
+	amended, err = fake.Annotate(ctx, desc.SnapshotID, "flaky", jvs.AnnotateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "flaky", amended.Note)
+}
+
+func TestFakeClient_ListSnapshots(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	for range 3 {
+		_, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+	history, err := fake.History(ctx, "main", 0)
+	require.NoError(t, err)
+
+	page, err := fake.ListSnapshots(ctx, jvs.ListOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Snapshots, 2)
+	require.NotEmpty(t, page.NextCursor)
+	page, err = fake.ListSnapshots(ctx, jvs.ListOptions{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Snapshots, 1)
+	assert.Equal(t, history[2].SnapshotID, page.Snapshots[0].SnapshotID)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestFakeClient_SetIdentity(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	_, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	fake.SetIdentity("alice@build-1")
+	desc, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "alice@build-1", desc.Author)
+
+	page, err := fake.ListSnapshots(ctx, jvs.ListOptions{Author: "alice"})
+	require.NoError(t, err)
+	require.Len(t, page.Snapshots, 1)
+	assert.Equal(t, desc.SnapshotID, page.Snapshots[0].SnapshotID)
+}
+
+func TestFakeClient_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+
+	// Subscribers may call back into the fake
+	var heads []model.SnapshotID
+	fake.Subscribe(func(e jvs.Event) {
+		cfg, err := fake.Worktree(ctx, e.Worktree)
+		require.NoError(t, err)
+		heads = append(heads, cfg.HeadSnapshotID)
+	})
+	desc, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, fake.Restore(ctx, jvs.RestoreOptions{}))
+	assert.Equal(t, []model.SnapshotID{desc.SnapshotID, desc.SnapshotID}, heads)
+}
+
+func TestFakeClient_Import(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+
+	descData, err := json.Marshal(&model.Descriptor{SnapshotID: "1700000000000-deadbeef", WorktreeName: "main", Note: "shared"})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "descriptor.json", Mode: 0644, Size: int64(len(descData))}))
+	_, err = tw.Write(descData)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	result, err := fake.Import(ctx, bytes.NewReader(buf.Bytes()), jvs.ImportOptions{Worktree: "review"})
+	require.NoError(t, err)
+	assert.Equal(t, "shared", result.Descriptor.Note)
+	require.NotNil(t, result.Worktree)
+	assert.Equal(t, result.Descriptor.SnapshotID, result.Worktree.HeadSnapshotID)
+	desc, err := fake.Descriptor(ctx, "1700000000000-deadbeef")
+	require.NoError(t, err)
+	assert.Equal(t, "shared", desc.Note)
+
+	_, err = fake.Import(ctx, bytes.NewReader(buf.Bytes()), jvs.ImportOptions{})
+	assert.ErrorContains(t, err, "already exists")
+
+	// Export writes what Import reads
+	other := jvstest.NewFakeClient("/repos/agent-2")
+	buf.Reset()
+	_, err = fake.Export(ctx, &buf, desc.SnapshotID, jvs.ExportOptions{})
+	require.NoError(t, err)
+	result, err = other.Import(ctx, &buf, jvs.ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "shared", result.Descriptor.Note)
+}
+
+func TestFakeClient_MaterializeAt(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	snap, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	mat, err := fake.MaterializeAt(ctx, "", snap.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, "main", mat.WorktreeName)
+	assert.Equal(t, "/repos/agent-1/.jvs/materialized/main/"+string(snap.SnapshotID), mat.Path)
+	assert.Equal(t, jvs.DefaultMaterializeTTL, mat.ExpiresAt.Sub(mat.CreatedAt))
+
+	_, err = fake.MaterializeAt(ctx, "main", "missing")
+	assert.Error(t, err)
+}
+
+func TestFakeClient_Pins(t *testing.T) {
+	ctx := context.Background()
+	fake := jvstest.NewFakeClient("/repos/agent-1")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.SetClock(func() time.Time { return now })
+	first, err := fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = fake.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	_, err = fake.Pin(ctx, first.SnapshotID, jvs.PinOptions{Reason: "investigation", TTL: 48 * time.Hour})
+	require.NoError(t, err)
+	pins, err := fake.ListPins(ctx)
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, "investigation", pins[0].Reason)
+
+	now = now.Add(36 * time.Hour)
+	plan, err := fake.GC(ctx, jvs.GCOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+	assert.Equal(t, 1, plan.ProtectedByPin)
+
+	require.NoError(t, fake.Unpin(ctx, first.SnapshotID))
+	plan, err = fake.GC(ctx, jvs.GCOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{first.SnapshotID}, plan.ToDelete)
+}
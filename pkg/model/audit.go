@@ -6,14 +6,31 @@ import "time"
 type AuditEventType string
 
 const (
-	EventTypeSnapshotCreate AuditEventType = "snapshot_create"
-	EventTypeSnapshotDelete AuditEventType = "snapshot_delete"
-	EventTypeRestore        AuditEventType = "restore"
-	EventTypeWorktreeCreate AuditEventType = "worktree_create"
-	EventTypeWorktreeRename AuditEventType = "worktree_rename"
-	EventTypeWorktreeRemove AuditEventType = "worktree_remove"
-	EventTypeGCPlan         AuditEventType = "gc_plan"
-	EventTypeGCRun          AuditEventType = "gc_run"
+	EventTypeSnapshotCreate   AuditEventType = "snapshot_create"
+	EventTypeSnapshotDelete   AuditEventType = "snapshot_delete"
+	EventTypeSnapshotImport   AuditEventType = "snapshot_import"
+	EventTypeRestore          AuditEventType = "restore"
+	EventTypeRestoreEphemeral AuditEventType = "restore_ephemeral"
+	EventTypeRestorePaths     AuditEventType = "restore_paths"
+	EventTypeWorktreeCreate   AuditEventType = "worktree_create"
+	EventTypeWorktreeRename   AuditEventType = "worktree_rename"
+	EventTypeWorktreeRemove   AuditEventType = "worktree_remove"
+	EventTypeWorktreePromote  AuditEventType = "worktree_promote"
+	EventTypeWorktreeFreeze   AuditEventType = "worktree_freeze"
+	EventTypeWorktreeThaw     AuditEventType = "worktree_thaw"
+	EventTypeWorktreeLock     AuditEventType = "worktree_lock"
+	EventTypeWorktreeUnlock   AuditEventType = "worktree_unlock"
+	EventTypeLeaseAcquire     AuditEventType = "lease_acquire"
+	EventTypeLeaseRelease     AuditEventType = "lease_release"
+	EventTypeGCPlan           AuditEventType = "gc_plan"
+	EventTypeGCRun            AuditEventType = "gc_run"
+	EventTypeGCTombstonePrune AuditEventType = "gc_tombstone_prune"
+	EventTypeRefResolve       AuditEventType = "ref_resolve"
+	EventTypeLineageRepair    AuditEventType = "lineage_repair"
+	EventTypeSnapshotRepair   AuditEventType = "snapshot_repair"
+	EventTypeSnapshotPin      AuditEventType = "snapshot_pin"
+	EventTypeSnapshotUnpin    AuditEventType = "snapshot_unpin"
+	EventTypeSnapshotAmend    AuditEventType = "snapshot_amend"
 )
 
 // AuditRecord is a single line in the audit log (JSONL format).
@@ -22,7 +39,12 @@ type AuditRecord struct {
 	EventType    AuditEventType `json:"event_type"`
 	SnapshotID   SnapshotID     `json:"snapshot_id,omitempty"`
 	WorktreeName string         `json:"worktree_name,omitempty"`
-	Details      map[string]any `json:"details,omitempty"`
-	PrevHash     HashValue      `json:"prev_hash"`
-	RecordHash   HashValue      `json:"record_hash"`
+	// CorrelationID groups the records produced by one top-level operation.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Author is who performed the operation, e.g. "alice@host". Empty in
+	// records written before it was recorded.
+	Author     string         `json:"author,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	PrevHash   HashValue      `json:"prev_hash"`
+	RecordHash HashValue      `json:"record_hash"`
 }
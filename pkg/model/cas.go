@@ -0,0 +1,13 @@
+package model
+
+// ContentStoreInfo records how many files of a snapshot are hard links to
+// blobs in the repository content store (.jvs/objects).
+type ContentStoreInfo struct {
+	// Blobs is the number of files linked to blobs.
+	Blobs int `json:"blobs"`
+	// Reused is the number of those files whose blob already existed, that
+	// is, content that was not stored again.
+	Reused int `json:"reused"`
+	// ReusedBytes is the size of the reused files.
+	ReusedBytes int64 `json:"reused_bytes"`
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"path"
 	"time"
 )
 
@@ -13,6 +14,22 @@ type Pin struct {
 	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 }
 
+// Expired reports whether the pin no longer protects its snapshot at time
+// now. Pins without an expiry never expire.
+func (p *Pin) Expired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// RetainedRoot keeps a snapshot and its ancestors from GC after the
+// worktree that held it was removed. Roots are grouped in retention buckets:
+// another worktree, which carries them for as long as it exists, or a named
+// archive ("archived/<name>").
+type RetainedRoot struct {
+	SnapshotID SnapshotID `json:"snapshot_id"`
+	Worktree   string     `json:"worktree"` // the removed worktree
+	RetainedAt time.Time  `json:"retained_at"`
+}
+
 // GCPlan is the output of gc plan phase.
 type GCPlan struct {
 	PlanID                 string          `json:"plan_id"`
@@ -25,6 +42,60 @@ type GCPlan struct {
 	ToDelete               []SnapshotID    `json:"to_delete"`
 	DeletableBytesEstimate int64           `json:"deletable_bytes_estimate"`
 	RetentionPolicy        RetentionPolicy `json:"retention_policy"`
+	BudgetUsage            []BudgetUsage   `json:"budget_usage,omitempty"`
+	// Reclaim lists the size of each snapshot in ToDelete and the space
+	// its deletion frees; DeletableBytesEstimate is their total.
+	Reclaim []SnapshotReclaim `json:"reclaim,omitempty"`
+	// ProtectedByRule names the retention rule that protects each snapshot
+	// counted in ProtectedByRetention; see RetentionPolicy.
+	ProtectedByRule map[SnapshotID]string `json:"protected_by_rule,omitempty"`
+	// Conflicts is filled in on request when the plan is checked against
+	// live operations; it is not saved with the plan.
+	Conflicts []GCConflict `json:"conflicts,omitempty"`
+}
+
+// SnapshotReclaim is the on-disk size of a snapshot to delete, and the
+// part of it a GC run frees. Files hard-linked from kept snapshots free
+// nothing; files shared only by deleted snapshots count for the first of
+// them in the plan.
+type SnapshotReclaim struct {
+	SnapshotID   SnapshotID `json:"snapshot_id"`
+	Bytes        int64      `json:"bytes"`
+	ReclaimBytes int64      `json:"reclaim_bytes"`
+}
+
+// GC conflict reasons.
+const (
+	GCConflictRestore = "restore" // source of an in-flight restore
+	GCConflictLease   = "lease"   // latest or base snapshot of a leased worktree
+)
+
+// GCConflict is a snapshot selected for deletion that a live operation
+// still needs.
+type GCConflict struct {
+	SnapshotID SnapshotID `json:"snapshot_id"`
+	Reason     string     `json:"reason"`
+	Worktree   string     `json:"worktree"`
+	Holder     string     `json:"holder,omitempty"` // lease holder
+	Since      time.Time  `json:"since"`            // restore start or lease acquisition
+}
+
+// BudgetUsage reports how much of a tag budget is in use.
+// Used values are measured before GC; Evicted counts snapshots the plan
+// deletes because the budget was exceeded.
+type BudgetUsage struct {
+	Tag       string `json:"tag"`
+	MaxBytes  int64  `json:"max_bytes,omitempty"`
+	MaxCount  int    `json:"max_count,omitempty"`
+	UsedBytes int64  `json:"used_bytes"`
+	UsedCount int    `json:"used_count"`
+	Evicted   int    `json:"evicted"`
+}
+
+// OverBudget reports whether usage exceeds either limit.
+func (u BudgetUsage) OverBudget() bool {
+	return (u.MaxBytes > 0 && u.UsedBytes > u.MaxBytes) ||
+		(u.MaxCount > 0 && u.UsedCount > u.MaxCount)
 }
 
 // Tombstone marks a snapshot as deleted but not yet reclaimed.
@@ -46,8 +117,22 @@ func DefaultRetentionPolicy() RetentionPolicy {
 // Snapshots are protected if they match ANY of these rules:
 // - Within the last N snapshots (KeepMinSnapshots)
 // - Created within the last duration (KeepMinAge)
+// - Tagged with one of KeepTags, or matched by TagRules
 // - Pinned explicitly
 // - Part of a worktree's lineage
+//
+// Worktrees replace the age and count rules for the snapshots of single
+// worktrees, which are then counted apart from the others.
+//
+// Budgets override the age and count rules: once a tag class exceeds its
+// budget, its oldest snapshots become deletable unless pinned, in lineage or
+// kept by tag.
+//
+// A GC plan reports the rule that protects each snapshot it keeps by
+// retention: "keep_tags:<tag>", "tag_rules:<pattern>", "keep_min_age",
+// "keep_min_snapshots", or for a worktree with its own retention
+// "worktrees.<name>.keep_min_age" and "worktrees.<name>.keep_min_snapshots".
+// Tag rules are applied first, so they are reported over age and count.
 type RetentionPolicy struct {
 	// KeepMinSnapshots ensures at least N snapshots are always kept.
 	// The most recent snapshots by creation time are protected.
@@ -56,6 +141,67 @@ type RetentionPolicy struct {
 	// KeepMinAge protects snapshots younger than this duration.
 	// Snapshots created within this time window are never deleted.
 	KeepMinAge time.Duration `json:"keep_min_age"`
+
+	// KeepTags protects every snapshot carrying one of these tags, whatever
+	// its age and any budget.
+	KeepTags []string `json:"keep_tags,omitempty"`
+
+	// TagRules protect snapshots by tag pattern.
+	TagRules []TagRule `json:"tag_rules,omitempty"`
+
+	// Worktrees set the age and count rules of single worktrees by name.
+	Worktrees map[string]WorktreeRetention `json:"worktrees,omitempty"`
+
+	// Budgets limit the total size and count of snapshots carrying a tag.
+	Budgets []TagBudget `json:"budgets,omitempty"`
+
+	// TombstoneMaxAge, if set, makes GC runs remove the tombstones of
+	// snapshots deleted longer ago.
+	TombstoneMaxAge time.Duration `json:"tombstone_max_age,omitempty"`
+}
+
+// WorktreeRetention is the age and count retention of one worktree's
+// snapshots: its newest KeepMinSnapshots and those younger than KeepMinAge
+// are kept. Zero fields keep nothing.
+type WorktreeRetention struct {
+	KeepMinSnapshots int           `json:"keep_min_snapshots"`
+	KeepMinAge       time.Duration `json:"keep_min_age"`
+}
+
+// RetentionFor returns the age and count retention of a worktree's
+// snapshots, and whether the worktree has its own.
+func (rp RetentionPolicy) RetentionFor(worktreeName string) (WorktreeRetention, bool) {
+	if wr, ok := rp.Worktrees[worktreeName]; ok {
+		return wr, true
+	}
+	return WorktreeRetention{KeepMinSnapshots: rp.KeepMinSnapshots, KeepMinAge: rp.KeepMinAge}, false
+}
+
+// TagRule protects snapshots carrying a tag that matches Pattern, a glob
+// as in path.Match (e.g. "release-*"): the newest KeepLast of them, or all
+// if KeepLast is zero. Like KeepTags, tag rules are never overridden by
+// budgets.
+type TagRule struct {
+	Pattern  string `json:"pattern"`
+	KeepLast int    `json:"keep_last,omitempty"`
+}
+
+// Matches reports whether any of tags matches the rule's pattern.
+func (r TagRule) Matches(tags []string) bool {
+	for _, tag := range tags {
+		if ok, _ := path.Match(r.Pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TagBudget caps the snapshots in a tag class, e.g. "auto-tagged snapshots
+// may use at most 50GB". A zero limit means unlimited.
+type TagBudget struct {
+	Tag      string `json:"tag"`
+	MaxBytes int64  `json:"max_bytes,omitempty"`
+	MaxCount int    `json:"max_count,omitempty"`
 }
 
 // Validate checks if the retention policy is valid.
@@ -74,6 +220,77 @@ func (rp *RetentionPolicy) Validate() error {
 			Value:  rp.KeepMinAge,
 		}
 	}
+	if rp.TombstoneMaxAge < 0 {
+		return &InvalidRetentionPolicyError{
+			Field:  "tombstone_max_age",
+			Reason: "must be non-negative",
+			Value:  rp.TombstoneMaxAge,
+		}
+	}
+	for _, tag := range rp.KeepTags {
+		if tag == "" {
+			return &InvalidRetentionPolicyError{
+				Field:  "keep_tags",
+				Reason: "must not contain empty tags",
+				Value:  tag,
+			}
+		}
+	}
+	for _, rule := range rp.TagRules {
+		if _, err := path.Match(rule.Pattern, ""); rule.Pattern == "" || err != nil {
+			return &InvalidRetentionPolicyError{
+				Field:  "tag_rules.pattern",
+				Reason: "must be a valid glob pattern",
+				Value:  rule.Pattern,
+			}
+		}
+		if rule.KeepLast < 0 {
+			return &InvalidRetentionPolicyError{
+				Field:  "tag_rules.keep_last",
+				Reason: "must be non-negative",
+				Value:  rule.KeepLast,
+			}
+		}
+	}
+	for name, wr := range rp.Worktrees {
+		if wr.KeepMinSnapshots < 0 {
+			return &InvalidRetentionPolicyError{
+				Field:  "worktrees." + name + ".keep_min_snapshots",
+				Reason: "must be non-negative",
+				Value:  wr.KeepMinSnapshots,
+			}
+		}
+		if wr.KeepMinAge < 0 {
+			return &InvalidRetentionPolicyError{
+				Field:  "worktrees." + name + ".keep_min_age",
+				Reason: "must be non-negative",
+				Value:  wr.KeepMinAge,
+			}
+		}
+	}
+	for _, b := range rp.Budgets {
+		if b.Tag == "" {
+			return &InvalidRetentionPolicyError{
+				Field:  "budgets.tag",
+				Reason: "must not be empty",
+				Value:  b.Tag,
+			}
+		}
+		if b.MaxBytes < 0 {
+			return &InvalidRetentionPolicyError{
+				Field:  "budgets.max_bytes",
+				Reason: "must be non-negative",
+				Value:  b.MaxBytes,
+			}
+		}
+		if b.MaxCount < 0 {
+			return &InvalidRetentionPolicyError{
+				Field:  "budgets.max_count",
+				Reason: "must be non-negative",
+				Value:  b.MaxCount,
+			}
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Lease records that a consumer (e.g. an agent pod) is attached to a worktree.
+// Stored at .jvs/leases/<worktree>.json. While an unexpired lease exists,
+// operations that replace the payload (restore) are refused.
+type Lease struct {
+	WorktreeName string    `json:"worktree_name"`
+	Holder       string    `json:"holder"`
+	Host         string    `json:"host,omitempty"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the lease is no longer valid at time now.
+func (l *Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
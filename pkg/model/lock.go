@@ -0,0 +1,77 @@
+package model
+
+import "time"
+
+// LockMode is how the repository lock is held.
+type LockMode string
+
+const (
+	// LockShared is held by operations that may run concurrently with each
+	// other, such as snapshot and restore.
+	LockShared LockMode = "shared"
+	// LockExclusive is held by operations that must run alone, such as GC.
+	LockExclusive LockMode = "exclusive"
+)
+
+// LockTicket records a process holding or waiting for the repository lock.
+// Stored at .jvs/lock/queue/<ticket>.json for as long as the process holds or
+// waits for the lock. Waiters are served in ticket order.
+type LockTicket struct {
+	Ticket    string    `json:"ticket"`
+	Mode      LockMode  `json:"mode"`
+	Operation string    `json:"operation"`
+	PID       int       `json:"pid"`
+	Host      string    `json:"host,omitempty"`
+	QueuedAt  time.Time `json:"queued_at"`
+	// AcquiredAt is nil while the process is still waiting.
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+
+	// HeartbeatAt and LeaseExpiresAt are set under lease locking: the
+	// process renews its lease while it holds or waits for the lock, and
+	// once the lease expires the ticket is taken over by the next process
+	// that lists the queue, on any node.
+	HeartbeatAt    *time.Time `json:"heartbeat_at,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	// FencingToken is the repository's fencing counter when the lock was
+	// taken under lease locking; exclusive holders increment it first.
+	FencingToken uint64 `json:"fencing_token,omitempty"`
+}
+
+// Held reports whether the ticket's process holds the lock.
+func (t *LockTicket) Held() bool {
+	return t.AcquiredAt != nil
+}
+
+// LeaseExpired reports whether the ticket's lease has expired at time now.
+// Tickets without a lease never expire.
+func (t *LockTicket) LeaseExpired(now time.Time) bool {
+	return t.LeaseExpiresAt != nil && now.After(*t.LeaseExpiresAt)
+}
+
+// OperationMarker records a mutating operation in progress on a worktree,
+// such as a snapshot or restore. Stored at .jvs/lock/worktrees/<name>.json
+// and held locked by the operation's process for as long as it runs.
+type OperationMarker struct {
+	WorktreeName string    `json:"worktree_name"`
+	Operation    string    `json:"operation"`
+	PID          int       `json:"pid"`
+	Host         string    `json:"host,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// WorktreeLock is an advisory lock an operator holds on a worktree. Stored
+// at .jvs/worktrees/<name>/lock.json; while it is active, snapshots and
+// restores by anyone but its owner are refused, as are rename and remove.
+type WorktreeLock struct {
+	WorktreeName string    `json:"worktree_name"`
+	Owner        string    `json:"owner"`
+	Host         string    `json:"host,omitempty"`
+	LockedAt     time.Time `json:"locked_at"`
+	// ExpiresAt is nil for locks held until unlocked.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the lock is no longer held at time now.
+func (l *WorktreeLock) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Materialization is a read-only copy of a snapshot kept beside a worktree,
+// so that tools can read a past state while the worktree stays where it is.
+// Its record is stored at .jvs/materialized/<worktree>/<snapshot>.json and
+// the copy is removed once the record has expired.
+type Materialization struct {
+	WorktreeName string     `json:"worktree_name"`
+	SnapshotID   SnapshotID `json:"snapshot_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+
+	// Path is the absolute path of the copy. It is not stored.
+	Path string `json:"path,omitempty"`
+}
+
+// Expired reports whether the copy may be removed at time now.
+func (m *Materialization) Expired(now time.Time) bool {
+	return !now.Before(m.ExpiresAt)
+}
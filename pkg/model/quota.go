@@ -0,0 +1,29 @@
+package model
+
+// Quota caps the space a repository may use. Forks that would exceed it are
+// refused before anything is copied. A zero limit means unlimited.
+type Quota struct {
+	// MaxBytes limits everything under the repository root: worktree
+	// payloads and .jvs, including snapshots.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// Namespaces limit the payloads of groups of worktrees.
+	Namespaces []NamespaceQuota `json:"namespaces,omitempty"`
+}
+
+// NamespaceQuota limits the total payload size of the worktrees whose names
+// start with Prefix, e.g. all "agent-" worktrees.
+type NamespaceQuota struct {
+	Prefix   string `json:"prefix"`
+	MaxBytes int64  `json:"max_bytes"`
+}
+
+// IsZero reports whether q sets no limit.
+func (q Quota) IsZero() bool {
+	return q.MaxBytes == 0 && len(q.Namespaces) == 0
+}
+
+// SnapshotUsage is the payload size of a snapshot.
+type SnapshotUsage struct {
+	SnapshotID SnapshotID `json:"snapshot_id"`
+	Bytes      int64      `json:"bytes"`
+}
@@ -4,12 +4,18 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 )
 
-// SnapshotID is the unique identifier for a snapshot: <unix_ms>-<rand8hex>
+// SnapshotID is the unique identifier for a snapshot: <unix_ms>-<rand8hex>,
+// or <prefix>-<unix_ms>-<rand8hex> when the repository embeds a short
+// worktree prefix (see WorktreeIDPrefix).
 type SnapshotID string
 
+// maxIDPrefixLen bounds the worktree prefix of a snapshot ID.
+const maxIDPrefixLen = 8
+
 // NewSnapshotID generates a new unique snapshot ID.
 func NewSnapshotID() SnapshotID {
 	ts := time.Now().UnixMilli()
@@ -20,11 +26,100 @@ func NewSnapshotID() SnapshotID {
 	return SnapshotID(fmt.Sprintf("%013d-%s", ts, hex.EncodeToString(randBytes[:])))
 }
 
-// ShortID returns the first 8 characters for display.
-func (id SnapshotID) ShortID() string {
+// NewPrefixedSnapshotID generates a new unique snapshot ID carrying the
+// prefix of worktreeName, e.g. "main-1708300800000-a3f7c1b2".
+func NewPrefixedSnapshotID(worktreeName string) SnapshotID {
+	return SnapshotID(WorktreeIDPrefix(worktreeName) + "-" + string(NewSnapshotID()))
+}
+
+// WorktreeIDPrefix returns the snapshot ID prefix for a worktree: its name
+// lowercased, reduced to letters and digits and cut to 8 characters, or
+// "wt" if nothing is left.
+func WorktreeIDPrefix(worktreeName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(worktreeName) {
+		if b.Len() == maxIDPrefixLen {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "wt"
+	}
+	return b.String()
+}
+
+// Prefix returns the worktree prefix of the ID, or "" for unprefixed IDs.
+func (id SnapshotID) Prefix() string {
+	prefix, _ := id.split()
+	return prefix
+}
+
+// Unprefixed returns the ID without its worktree prefix.
+func (id SnapshotID) Unprefixed() string {
+	_, rest := id.split()
+	return rest
+}
+
+// unprefixedIDLen is the length of <unix_ms>-<rand8hex>.
+const unprefixedIDLen = 13 + 1 + 8
+
+// split separates a worktree prefix from the <unix_ms>-<rand8hex> part,
+// which it recognizes by its shape at the end of the ID: prefixes may start
+// with a digit, like that of a worktree named "2024exp", so the first
+// character does not tell them from timestamps. IDs of any other shape have
+// no prefix.
+func (id SnapshotID) split() (string, string) {
 	s := string(id)
+	n := len(s) - unprefixedIDLen
+	if n < 2 || s[n-1] != '-' {
+		return "", s
+	}
+	prefix, rest := s[:n-1], s[n:]
+	if len(prefix) > maxIDPrefixLen || !isIDPrefix(prefix) || !isUnprefixedID(rest) {
+		return "", s
+	}
+	return prefix, rest
+}
+
+// isIDPrefix reports whether s could have been returned by WorktreeIDPrefix.
+func isIDPrefix(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnprefixedID reports whether s has the shape <unix_ms>-<rand8hex>.
+func isUnprefixedID(s string) bool {
+	if len(s) != unprefixedIDLen || s[13] != '-' {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case i == 13:
+		case i < 13 && r >= '0' && r <= '9':
+		case i > 13 && ((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f')):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ShortID returns the first 8 characters for display, after the worktree
+// prefix if the ID has one.
+func (id SnapshotID) ShortID() string {
+	prefix, s := id.split()
 	if len(s) >= 8 {
-		return s[:8]
+		s = s[:8]
+	}
+	if prefix != "" {
+		return prefix + "-" + s
 	}
 	return s
 }
@@ -36,29 +131,182 @@ func (id SnapshotID) String() string {
 
 // Descriptor is the on-disk snapshot metadata.
 type Descriptor struct {
-	SnapshotID         SnapshotID     `json:"snapshot_id"`
-	ParentID           *SnapshotID    `json:"parent_id,omitempty"`
-	WorktreeName       string         `json:"worktree_name"`
-	CreatedAt          time.Time      `json:"created_at"`
+	SnapshotID   SnapshotID  `json:"snapshot_id"`
+	ParentID     *SnapshotID `json:"parent_id,omitempty"`
+	WorktreeName string      `json:"worktree_name"`
+	CreatedAt    time.Time   `json:"created_at"`
+	// Author is who took the snapshot, e.g. "alice@host" or a pod name.
+	// Empty in descriptors written before it was recorded.
+	Author             string         `json:"author,omitempty"`
 	Note               string         `json:"note,omitempty"`
 	Tags               []string       `json:"tags,omitempty"`
 	Engine             EngineType     `json:"engine"`
 	PayloadRootHash    HashValue      `json:"payload_root_hash"`
 	DescriptorChecksum HashValue      `json:"descriptor_checksum"`
 	IntegrityState     IntegrityState `json:"integrity_state"`
+	// HashAlgorithm is the algorithm of PayloadRootHash and the manifest.
+	// Empty in descriptors written before it was recorded, which use
+	// SHA-256.
+	HashAlgorithm HashAlgorithm `json:"hash_algorithm,omitempty"`
+	// ManifestHash is the hash of the section table of the snapshot's
+	// manifest, which lets single sections of it be trusted on their own.
+	// Empty in descriptors written before it was recorded.
+	ManifestHash HashValue `json:"manifest_hash,omitempty"`
 	// PartialPaths is set for partial snapshots, listing the specific paths included.
 	// Empty or nil means a full worktree snapshot.
 	PartialPaths []string `json:"partial_paths,omitempty"`
 	// Compression stores compression metadata if the snapshot is compressed.
 	Compression *CompressionInfo `json:"compression,omitempty"`
+	// Encryption is set if the payload files are encrypted at rest.
+	Encryption *EncryptionInfo `json:"encryption,omitempty"`
+	// ContentStore is set if the payload files are hard links to blobs in
+	// the repository content store.
+	ContentStore *ContentStoreInfo `json:"content_store,omitempty"`
+	// Incremental is set if unchanged files were hard-linked from the
+	// parent snapshot instead of copied.
+	Incremental *IncrementalInfo `json:"incremental,omitempty"`
+	// DereferencedPaths lists symlinks (relative to the payload root) that pointed
+	// outside the worktree and were replaced by copies of their targets.
+	DereferencedPaths []string `json:"dereferenced_paths,omitempty"`
+	// Annotations are free-form key/value metadata set at creation, e.g.
+	// "ci.notify": "true" to trigger webhooks in serve mode.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// ReadErrors lists the files that could not be read and were left out
+	// under ReadErrorSkip. A snapshot with read errors is incomplete.
+	ReadErrors []ReadError `json:"read_errors,omitempty"`
+	// Environment describes where the snapshot was taken, if captured.
+	Environment *Environment `json:"environment,omitempty"`
+	// Skipped is set on the HEAD descriptor returned in place of a new
+	// snapshot when the payload was unchanged. It is never persisted.
+	Skipped bool `json:"skipped,omitempty"`
+	// Stats holds diagnostic data recorded during creation (e.g. with
+	// JVS_DEBUG_TIMING set). It is not covered by the descriptor checksum.
+	Stats *SnapshotStats `json:"stats,omitempty"`
+}
+
+// MetadataChange is an entry of a snapshot's metadata history, recording
+// the note and tags a descriptor had before it was amended and after.
+type MetadataChange struct {
+	ChangedAt time.Time `json:"changed_at"`
+	Actor     string    `json:"actor,omitempty"`     // Who made the change, e.g. "alice@host"
+	Operation string    `json:"operation,omitempty"` // The amend operation, e.g. "annotate"
+	OldNote   string    `json:"old_note,omitempty"`
+	NewNote   string    `json:"new_note,omitempty"`
+	OldTags   []string  `json:"old_tags,omitempty"`
+	NewTags   []string  `json:"new_tags,omitempty"`
+}
+
+// Manifest lists every entry of a snapshot's payload with its own hash, so
+// single files can be compared, verified and restored without rehashing the
+// whole tree. Its entries rebuild the descriptor's PayloadRootHash, which
+// is how a manifest is checked before it is trusted.
+type Manifest struct {
+	SnapshotID SnapshotID      `json:"snapshot_id"`
+	Entries    []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry is a file, directory or symlink of a snapshot's payload.
+// Hashes are of the uncompressed content: SHA-256 of a file's data, a
+// symlink's target or a directory's name, as in the payload root hash.
+type ManifestEntry struct {
+	Path string    `json:"path"`           // Relative to the payload root, with forward slashes
+	Type string    `json:"type"`           // "file", "dir" or "symlink"
+	Mode uint32    `json:"mode"`           // Permission bits
+	Size int64     `json:"size,omitempty"` // Length of a file or a symlink's target
+	Hash HashValue `json:"hash"`
+}
+
+// HasReadErrors reports whether files were left out of the snapshot because
+// they could not be read.
+func (d *Descriptor) HasReadErrors() bool {
+	return len(d.ReadErrors) > 0
+}
+
+// PayloadHashAlgorithm returns the algorithm of the payload root hash,
+// which is SHA-256 for descriptors that do not record one.
+func (d *Descriptor) PayloadHashAlgorithm() HashAlgorithm {
+	if d.HashAlgorithm == "" {
+		return HashSHA256
+	}
+	return d.HashAlgorithm
+}
+
+// CompareNewestFirst orders descriptors the way every snapshot listing
+// does: by creation time, newest first, then by snapshot ID in descending
+// byte order, so that snapshots created in the same instant are still listed
+// in the same order on every platform and locale. It returns a negative
+// number if a comes first, for use with slices.SortFunc.
+func CompareNewestFirst(a, b *Descriptor) int {
+	if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(string(b.SnapshotID), string(a.SnapshotID))
+}
+
+// ReadErrorPolicy selects what a snapshot does with files it cannot read.
+type ReadErrorPolicy string
+
+const (
+	// ReadErrorFail fails the snapshot. It is the default.
+	ReadErrorFail ReadErrorPolicy = "fail"
+	// ReadErrorSkip leaves the file out and records it in the descriptor.
+	ReadErrorSkip ReadErrorPolicy = "skip"
+)
+
+// ReadError records a path left out of a snapshot and why. A directory
+// that could not be listed is left out with everything below it.
+type ReadError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Environment is the context a snapshot was taken in, recorded to help
+// reproduce agent runs.
+type Environment struct {
+	Hostname string `json:"hostname,omitempty"`
+	// ImageDigest is the container image digest, taken from the
+	// JVS_IMAGE_DIGEST environment variable.
+	ImageDigest string `json:"image_digest,omitempty"`
+	JVSVersion  string `json:"jvs_version"`
+	// Vars holds the allowlisted environment variables that were set.
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// SnapshotStats is the internal stats section of a descriptor.
+type SnapshotStats struct {
+	Timings []PhaseTiming `json:"timings,omitempty"`
+}
+
+// PhaseTiming records the duration of one snapshot creation phase.
+type PhaseTiming struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"duration_ns"`
 }
 
 // CompressionInfo stores compression metadata for snapshots.
 type CompressionInfo struct {
-	Type  string `json:"type"`  // e.g., "gzip"
+	Type  string `json:"type"`  // "gzip" or "zstd"
 	Level int    `json:"level"` // Compression level (0-9)
 }
 
+// EncryptionInfo records how a snapshot's payload files are encrypted.
+type EncryptionInfo struct {
+	Type string `json:"type"` // "age"
+	// Recipients are the public keys the files were encrypted to.
+	Recipients []string `json:"recipients"`
+}
+
+// IncrementalInfo records how an incremental snapshot was built from its
+// parent snapshot.
+type IncrementalInfo struct {
+	// Linked is the number of unchanged files hard-linked from the parent.
+	Linked int `json:"linked"`
+	// LinkedBytes is the size of the linked files.
+	LinkedBytes int64 `json:"linked_bytes"`
+	// Copied is the number of new or changed files copied from the worktree.
+	Copied int `json:"copied"`
+}
+
 // ReadyMarker is the .READY file content indicating complete snapshot.
 type ReadyMarker struct {
 	SnapshotID         SnapshotID `json:"snapshot_id"`
@@ -68,10 +316,17 @@ type ReadyMarker struct {
 	DescriptorChecksum HashValue  `json:"descriptor_checksum"`
 }
 
-// IntentRecord tracks in-progress snapshot creation for crash recovery.
+// IntentRecord tracks an in-progress operation on a snapshot: its creation
+// (for crash recovery), or a restore reading from it.
 type IntentRecord struct {
 	SnapshotID   SnapshotID `json:"snapshot_id"`
 	WorktreeName string     `json:"worktree_name"`
 	StartedAt    time.Time  `json:"started_at"`
 	Engine       EngineType `json:"engine"`
+	// Operation is IntentOperationRestore for restores; empty for snapshot
+	// creation.
+	Operation string `json:"operation,omitempty"`
 }
+
+// IntentOperationRestore marks the intent of an in-flight restore.
+const IntentOperationRestore = "restore"
@@ -2,6 +2,7 @@ package model_test
 
 import (
 	"regexp"
+	"slices"
 	"testing"
 	"time"
 
@@ -32,6 +33,48 @@ func TestSnapshotID_ShortID_Empty(t *testing.T) {
 	assert.Equal(t, "", id.ShortID())
 }
 
+func TestNewPrefixedSnapshotID(t *testing.T) {
+	id := model.NewPrefixedSnapshotID("main")
+	require.Regexp(t, `^main-\d{13}-[0-9a-f]{8}$`, string(id))
+	assert.Equal(t, "main", id.Prefix())
+	assert.Regexp(t, snapshotIDPattern, id.Unprefixed())
+}
+
+func TestWorktreeIDPrefix(t *testing.T) {
+	assert.Equal(t, "main", model.WorktreeIDPrefix("main"))
+	assert.Equal(t, "featurel", model.WorktreeIDPrefix("Feature-Login_v2"))
+	assert.Equal(t, "wt", model.WorktreeIDPrefix("--"))
+}
+
+func TestSnapshotID_Prefix(t *testing.T) {
+	plain := model.SnapshotID("1708300800000-a3f7c1b2")
+	assert.Equal(t, "", plain.Prefix())
+	assert.Equal(t, "1708300800000-a3f7c1b2", plain.Unprefixed())
+
+	prefixed := model.SnapshotID("main-1708300800000-a3f7c1b2")
+	assert.Equal(t, "main", prefixed.Prefix())
+	assert.Equal(t, "1708300800000-a3f7c1b2", prefixed.Unprefixed())
+	assert.Equal(t, "main-17083008", prefixed.ShortID())
+
+	// Prefixes of worktrees whose names start with a digit
+	for _, prefix := range []string{"2024exp", "1run", "20240101"} {
+		id := model.SnapshotID(prefix + "-1708300800000-a3f7c1b2")
+		assert.Equal(t, prefix, id.Prefix(), id)
+		assert.Equal(t, "1708300800000-a3f7c1b2", id.Unprefixed(), id)
+		assert.Equal(t, prefix+"-17083008", id.ShortID(), id)
+	}
+	generated := model.NewPrefixedSnapshotID("2024-experiment")
+	assert.Equal(t, "2024expe", generated.Prefix())
+	assert.Regexp(t, snapshotIDPattern, generated.Unprefixed())
+
+	// Anything not ending in <unix_ms>-<rand8hex> has no prefix
+	for _, s := range []string{"main-abc", "main-1708300800000-A3F7C1B2", "toolongpfx-1708300800000-a3f7c1b2", "-1708300800000-a3f7c1b2"} {
+		id := model.SnapshotID(s)
+		assert.Equal(t, "", id.Prefix(), s)
+		assert.Equal(t, s, id.Unprefixed(), s)
+	}
+}
+
 func TestSnapshotID_String(t *testing.T) {
 	id := model.SnapshotID("1708300800000-a3f7c1b2")
 	assert.Equal(t, "1708300800000-a3f7c1b2", id.String())
@@ -384,6 +427,20 @@ func TestRetentionPolicy_Validate_Invalid(t *testing.T) {
 			},
 			expectedErr: "invalid retention policy: keep_min_snapshots must be non-negative",
 		},
+		{
+			name: "negative worktree keep_min_age",
+			policy: model.RetentionPolicy{
+				Worktrees: map[string]model.WorktreeRetention{"scratch": {KeepMinAge: -time.Hour}},
+			},
+			expectedErr: "invalid retention policy: worktrees.scratch.keep_min_age must be non-negative",
+		},
+		{
+			name: "invalid tag rule pattern",
+			policy: model.RetentionPolicy{
+				TagRules: []model.TagRule{{Pattern: "release-["}},
+			},
+			expectedErr: "invalid retention policy: tag_rules.pattern must be a valid glob pattern",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -482,3 +539,16 @@ func TestHashValue_Empty(t *testing.T) {
 	hash := model.HashValue("")
 	assert.Equal(t, "", string(hash))
 }
+
+func TestCompareNewestFirst(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	older := &model.Descriptor{SnapshotID: "1767323045000-ffffffff", CreatedAt: t0}
+	newer := &model.Descriptor{SnapshotID: "1767323045001-00000000", CreatedAt: t0.Add(time.Millisecond)}
+	tieLow := &model.Descriptor{SnapshotID: "main-1767323045000-0000000a", CreatedAt: t0}
+	tieHigh := &model.Descriptor{SnapshotID: "main-1767323045000-0000000b", CreatedAt: t0}
+
+	descs := []*model.Descriptor{tieLow, older, newer, tieHigh}
+	slices.SortFunc(descs, model.CompareNewestFirst)
+	assert.Equal(t, []*model.Descriptor{newer, tieHigh, tieLow, older}, descs)
+	assert.Zero(t, model.CompareNewestFirst(older, older))
+}
@@ -18,5 +18,15 @@ const (
 	IntegrityUnknown  IntegrityState = "unknown"
 )
 
-// HashValue is a SHA-256 hash stored as a hex string.
+// HashValue is a hash stored as a hex string: SHA-256, or the payload hash
+// algorithm of the descriptor it belongs to.
 type HashValue string
+
+// HashAlgorithm identifies the hash function of a snapshot's payload root
+// hash and manifest.
+type HashAlgorithm string
+
+const (
+	HashSHA256 HashAlgorithm = "sha256"
+	HashBLAKE3 HashAlgorithm = "blake3"
+)
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // WorktreeConfig is stored at .jvs/worktrees/<name>/config.json
 type WorktreeConfig struct {
@@ -9,6 +12,9 @@ type WorktreeConfig struct {
 	HeadSnapshotID   SnapshotID `json:"head_snapshot_id,omitempty"`   // Current position (may differ from latest if detached)
 	LatestSnapshotID SnapshotID `json:"latest_snapshot_id,omitempty"` // The most recent snapshot in this worktree's lineage
 	CreatedAt        time.Time  `json:"created_at"`
+	// Frozen marks a read-only worktree: its payload has write permissions
+	// removed and restores are refused until it is thawed.
+	Frozen bool `json:"frozen,omitempty"`
 }
 
 // IsDetached returns true if the worktree is at a historical snapshot (not at HEAD).
@@ -30,3 +36,38 @@ func (c *WorktreeConfig) CanSnapshot() bool {
 	}
 	return !c.IsDetached()
 }
+
+// StalenessPolicy sets how old the newest snapshot of a worktree may get
+// before the worktree is reported as stale. A zero age disables the check.
+type StalenessPolicy struct {
+	MaxAge    time.Duration            // for worktrees not in Worktrees
+	Worktrees map[string]time.Duration // by worktree name
+}
+
+// MaxAgeFor returns the staleness threshold of a worktree.
+func (p StalenessPolicy) MaxAgeFor(worktreeName string) time.Duration {
+	if maxAge, ok := p.Worktrees[worktreeName]; ok {
+		return maxAge
+	}
+	return p.MaxAge
+}
+
+// Staleness reports a worktree whose newest snapshot is older than its
+// staleness threshold. A worktree without snapshots is aged from its
+// creation.
+type Staleness struct {
+	Worktree       string        `json:"worktree"`
+	LastSnapshotID SnapshotID    `json:"last_snapshot_id,omitempty"`
+	Since          time.Time     `json:"since"`
+	Age            time.Duration `json:"age_ns"`
+	MaxAge         time.Duration `json:"max_age_ns"`
+}
+
+// Message describes the staleness for warnings.
+func (s *Staleness) Message() string {
+	age := s.Age.Round(time.Minute)
+	if s.LastSnapshotID == "" {
+		return fmt.Sprintf("worktree '%s' has had no snapshot since it was created %s ago (max age %s)", s.Worktree, age, s.MaxAge)
+	}
+	return fmt.Sprintf("worktree '%s' was last snapshotted %s ago (max age %s)", s.Worktree, age, s.MaxAge)
+}
@@ -0,0 +1,55 @@
+package progress
+
+// ByteCallback receives the bytes done of the total of a phase of a long
+// operation, such as the copy of a snapshot's payload.
+type ByteCallback func(phase string, done, total int64)
+
+// Bytes tracks the bytes done in one phase of an operation. It reports them
+// when the phase starts, whenever another percent of the total is done and
+// when the phase ends, so that a callback sending updates over the network
+// is not called for every small file.
+type Bytes struct {
+	phase    string
+	total    int64
+	done     int64
+	reported int64
+	cb       ByteCallback
+}
+
+// NewBytes starts tracking a phase of total bytes and reports it started.
+// With a nil cb, the tracker does nothing.
+func NewBytes(phase string, total int64, cb ByteCallback) *Bytes {
+	b := &Bytes{phase: phase, total: total, cb: cb}
+	if cb != nil {
+		cb(phase, 0, total)
+	}
+	return b
+}
+
+// Add records n more bytes done. Done never exceeds the total, which may
+// have been estimated before the phase started.
+func (b *Bytes) Add(n int64) {
+	if b.cb == nil {
+		return
+	}
+	b.done = min(b.done+n, b.total)
+	if b.done-b.reported >= max(b.total/100, 1) {
+		b.report()
+	}
+}
+
+// Done reports the phase as complete.
+func (b *Bytes) Done() {
+	if b.cb == nil {
+		return
+	}
+	b.done = b.total
+	if b.reported != b.done {
+		b.report()
+	}
+}
+
+func (b *Bytes) report() {
+	b.reported = b.done
+	b.cb(b.phase, b.done, b.total)
+}
@@ -0,0 +1,46 @@
+package progress
+
+import "testing"
+
+type byteReport struct {
+	phase       string
+	done, total int64
+}
+
+func TestBytes(t *testing.T) {
+	var reports []byteReport
+	b := NewBytes("copy", 1000, func(phase string, done, total int64) {
+		reports = append(reports, byteReport{phase, done, total})
+	})
+	b.Add(5)  // below a percent: not reported
+	b.Add(10) // 15 bytes done
+	b.Add(2000)
+	b.Done()
+
+	want := []byteReport{{"copy", 0, 1000}, {"copy", 15, 1000}, {"copy", 1000, 1000}}
+	if len(reports) != len(want) {
+		t.Fatalf("expected %d reports, got %v", len(want), reports)
+	}
+	for i := range want {
+		if reports[i] != want[i] {
+			t.Errorf("report %d: expected %v, got %v", i, want[i], reports[i])
+		}
+	}
+}
+
+func TestBytes_DoneReportsRemainder(t *testing.T) {
+	var last byteReport
+	b := NewBytes("hash", 50, func(phase string, done, total int64) {
+		last = byteReport{phase, done, total}
+	})
+	b.Done()
+	if last != (byteReport{"hash", 50, 50}) {
+		t.Errorf("expected final report of 50/50, got %v", last)
+	}
+}
+
+func TestBytes_NilCallback(t *testing.T) {
+	b := NewBytes("copy", 10, nil)
+	b.Add(5)
+	b.Done()
+}
@@ -1,15 +1,39 @@
 package library_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
-
+	"time"
+
+	"github.com/jvs-project/jvs/internal/audit"
+	"github.com/jvs-project/jvs/internal/bundle"
+	"github.com/jvs-project/jvs/internal/materialize"
+	"github.com/jvs-project/jvs/internal/repo"
+	"github.com/jvs-project/jvs/internal/repolock"
+	"github.com/jvs-project/jvs/internal/webhook"
+	"github.com/jvs-project/jvs/internal/worktree"
+	"github.com/jvs-project/jvs/pkg/config"
+	"github.com/jvs-project/jvs/pkg/errclass"
 	"github.com/jvs-project/jvs/pkg/jvs"
+	"github.com/jvs-project/jvs/pkg/logging"
 	"github.com/jvs-project/jvs/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func testRepoDir(t *testing.T) string {
@@ -49,6 +73,38 @@ func TestOpen_OpensExistingRepo(t *testing.T) {
 	assert.Equal(t, original.RepoID(), opened.RepoID())
 }
 
+func TestOpen_WithOptions(t *testing.T) {
+	dir := testRepoDir(t)
+	_, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var logs bytes.Buffer
+	logger := logging.NewLogger(logging.LevelDebug)
+	logger.SetOutput(&logs)
+	now := time.Now()
+	client, err := jvs.Open(dir,
+		jvs.WithEngine(model.EngineCopy),
+		jvs.WithLogger(logger),
+		jvs.WithClock(func() time.Time { return now }),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, model.EngineCopy, client.EngineType())
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	_, err = client.AcquireLease(ctx, "main", "pod-a", time.Minute)
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "acquire_lease")
+
+	// Leases expire on the client's clock
+	now = now.Add(2 * time.Minute)
+	l, err := client.Lease(ctx, "main")
+	require.NoError(t, err)
+	assert.Nil(t, l)
+	require.NoError(t, client.RestoreLatest(ctx, "main"), "the restore sees the lease expired too")
+}
+
 func TestOpenOrInit_InitializesWhenMissing(t *testing.T) {
 	dir := testRepoDir(t)
 
@@ -69,6 +125,26 @@ func TestOpenOrInit_OpensWhenExists(t *testing.T) {
 	assert.Equal(t, first.RepoID(), second.RepoID())
 }
 
+func TestOpenOrInit_NewerFormat(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+
+	info, err := client.Version()
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Version)
+	assert.Equal(t, repo.FormatVersion, info.RepoFormatVersion)
+	assert.LessOrEqual(t, info.MinFormatVersion, info.FormatVersion)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "format_version"), []byte(fmt.Sprintf("%d\n", repo.FormatVersion+1)), 0600))
+	_, err = jvs.OpenOrInit(dir, jvs.InitOptions{Name: "test-repo"})
+	require.ErrorIs(t, err, jvs.ErrIncompatibleFormat)
+	var incompatible *jvs.IncompatibleFormatError
+	require.ErrorAs(t, err, &incompatible)
+	assert.Equal(t, repo.FormatVersion+1, incompatible.FormatVersion)
+	assert.Contains(t, err.Error(), "upgrade jvs")
+}
+
 func TestHasSnapshots_FalseOnEmptyRepo(t *testing.T) {
 	dir := testRepoDir(t)
 	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
@@ -235,6 +311,10 @@ func TestRestore_ByTarget(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "v1", string(data))
 
+	// Detached: snapshots are refused
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{Note: "detached"})
+	assert.ErrorContains(t, err, "detached")
+
 	// Restore by tag
 	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: "v2"}))
 	data, err = os.ReadFile(filepath.Join(mainDir, "file.txt"))
@@ -248,6 +328,45 @@ func TestRestore_ByTarget(t *testing.T) {
 	assert.Equal(t, "v2", string(data))
 }
 
+func TestClient_Resolve(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+	ctx := context.Background()
+	mainDir := client.WorktreePayloadPath("main")
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "file.txt"), []byte("v1"), 0644))
+	desc1, err := client.Snapshot(ctx, jvs.SnapshotOptions{Note: "version-1", Tags: []string{"v1"}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "file.txt"), []byte("v2"), 0644))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{Note: "version-2"})
+	require.NoError(t, err)
+
+	desc, err := client.Resolve(ctx, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, desc1.SnapshotID, desc.SnapshotID)
+	_, err = client.Resolve(ctx, "nightly")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// References the repository does not know go to external resolvers
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ref") != "nightly" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"snapshot_id": %q}`, desc1.SnapshotID)
+	}))
+	defer srv.Close()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "config.yaml"), []byte("resolvers:\n  - name: releases\n    url: "+srv.URL+"\n"), 0644))
+	config.InvalidateCache(dir)
+	defer config.InvalidateCache(dir)
+
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: "nightly"}))
+	data, err := os.ReadFile(filepath.Join(mainDir, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+}
+
 func TestGC_DryRun(t *testing.T) {
 	dir := testRepoDir(t)
 	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
@@ -268,6 +387,332 @@ func TestGC_DryRun(t *testing.T) {
 	assert.Contains(t, plan.ProtectedSet, plan.ProtectedSet[0])
 }
 
+func TestClient_AddRemoveTags(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	client.EnableMetadataCache(0)
+	ctx := context.Background()
+
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "data.txt"), []byte("v1"), 0644))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{Tags: []string{"nightly"}})
+	require.NoError(t, err)
+	_, err = client.Descriptor(ctx, desc.SnapshotID) // cached
+	require.NoError(t, err)
+
+	amended, err := client.AddTags(ctx, desc.SnapshotID, []string{"release", "nightly"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nightly", "release"}, amended.Tags)
+	assert.NotEqual(t, desc.DescriptorChecksum, amended.DescriptorChecksum)
+	got, err := client.Descriptor(ctx, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, amended.Tags, got.Tags)
+	require.NoError(t, client.Verify(ctx, desc.SnapshotID))
+	resolved, err := client.Resolve(ctx, "release")
+	require.NoError(t, err)
+	assert.Equal(t, desc.SnapshotID, resolved.SnapshotID)
+
+	amended, err = client.RemoveTags(ctx, desc.SnapshotID, []string{"nightly", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"release"}, amended.Tags)
+	require.NoError(t, client.Verify(ctx, desc.SnapshotID))
+
+	records, err := audit.Read(audit.LogPath(dir), audit.Filter{EventType: model.EventTypeSnapshotAmend})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "untag", records[1].Details["operation"])
+
+	_, err = client.AddTags(ctx, desc.SnapshotID, []string{"bad tag/"})
+	assert.Error(t, err)
+	_, err = client.AddTags(ctx, "1700000000000-deadbeef", []string{"x"})
+	assert.ErrorIs(t, err, jvs.ErrSnapshotNotFound)
+}
+
+func TestClient_Annotate(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	client.EnableMetadataCache(0)
+	ctx := context.Background()
+
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{Note: "build"})
+	require.NoError(t, err)
+	_, err = client.Descriptor(ctx, desc.SnapshotID) // cached
+	require.NoError(t, err)
+
+	_, err = client.Annotate(ctx, desc.SnapshotID, "tests pass", jvs.AnnotateOptions{Append: true})
+	require.NoError(t, err)
+	got, err := client.Descriptor(ctx, desc.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, "build; tests pass", got.Note)
+	require.NoError(t, client.Verify(ctx, desc.SnapshotID))
+
+	amended, err := client.Annotate(ctx, desc.SnapshotID, "flaky", jvs.AnnotateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "flaky", amended.Note)
+
+	records, err := audit.Read(audit.LogPath(dir), audit.Filter{EventType: model.EventTypeSnapshotAmend})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "build; tests pass", records[1].Details["old_note"])
+	assert.Equal(t, "flaky", records[1].Details["new_note"])
+}
+
+func TestClient_WithIdentity(t *testing.T) {
+	dir := testRepoDir(t)
+	t.Setenv("JVS_AUTHOR", "")
+	agent, err := jvs.Init(dir, jvs.InitOptions{}, jvs.WithIdentity("agent-7"))
+	require.NoError(t, err)
+	local, err := jvs.Open(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	desc, err := agent.Snapshot(ctx, jvs.SnapshotOptions{Note: "by agent"})
+	require.NoError(t, err)
+	assert.Equal(t, "agent-7", desc.Author)
+	require.NoError(t, os.WriteFile(filepath.Join(agent.WorktreePayloadPath("main"), "f.txt"), []byte("x"), 0644))
+	mine, err := local.Snapshot(ctx, jvs.SnapshotOptions{Note: "by user"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, mine.Author)
+	assert.NotEqual(t, "agent-7", mine.Author)
+
+	page, err := local.ListSnapshots(ctx, jvs.ListOptions{Author: "agent-7"})
+	require.NoError(t, err)
+	require.Len(t, page.Snapshots, 1)
+	assert.Equal(t, desc.SnapshotID, page.Snapshots[0].SnapshotID)
+
+	records, err := audit.Read(audit.LogPath(dir), audit.Filter{EventType: model.EventTypeSnapshotCreate})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "agent-7", records[0].Author)
+	assert.Equal(t, mine.Author, records[1].Author)
+}
+
+func TestClient_WithHooks(t *testing.T) {
+	dir := testRepoDir(t)
+	var calls []string
+	veto := errors.New("vetoed")
+	var vetoRestore bool
+	client, err := jvs.Init(dir, jvs.InitOptions{}, jvs.WithHooks(jvs.Hooks{
+		BeforeSnapshot: func(_ context.Context, opts jvs.SnapshotOptions) error {
+			calls = append(calls, "before snapshot "+opts.Note)
+			if opts.Note == "forbidden" {
+				return veto
+			}
+			return nil
+		},
+		AfterSnapshot: func(_ context.Context, desc *model.Descriptor) {
+			calls = append(calls, "after snapshot "+desc.Note)
+		},
+		BeforeRestore: func(_ context.Context, worktreeName string, _ model.SnapshotID) error {
+			calls = append(calls, "before restore "+worktreeName)
+			if vetoRestore {
+				return veto
+			}
+			return nil
+		},
+		AfterRestore: func(_ context.Context, worktreeName string, _ model.SnapshotID) {
+			calls = append(calls, "after restore "+worktreeName)
+		},
+		BeforeGC: func(_ context.Context, plan *model.GCPlan) error {
+			calls = append(calls, fmt.Sprintf("before gc %d", len(plan.ToDelete)))
+			return nil
+		},
+		AfterGC: func(_ context.Context, _ *model.GCPlan, deleted []model.SnapshotID) {
+			calls = append(calls, fmt.Sprintf("after gc %d", len(deleted)))
+		},
+	}), jvs.WithHooks(jvs.Hooks{
+		AfterSnapshot: func(_ context.Context, desc *model.Descriptor) {
+			calls = append(calls, "second after snapshot")
+		},
+	}))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	base, err := client.Snapshot(ctx, jvs.SnapshotOptions{Note: "base"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"before snapshot base", "after snapshot base", "second after snapshot"}, calls)
+
+	// A failing before hook aborts the snapshot
+	calls = nil
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{Note: "forbidden"})
+	require.ErrorIs(t, err, jvs.ErrHookFailed)
+	assert.ErrorIs(t, err, veto)
+	var hookErr *jvs.HookError
+	require.ErrorAs(t, err, &hookErr)
+	assert.Equal(t, "BeforeSnapshot", hookErr.Hook)
+	assert.Equal(t, []string{"before snapshot forbidden"}, calls)
+	history, err := client.History(ctx, "main", 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	calls = nil
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(base.SnapshotID)}))
+	assert.Equal(t, []string{"before restore main", "after restore main"}, calls)
+
+	calls = nil
+	vetoRestore = true
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "keep.txt"), []byte("x"), 0644))
+	err = client.RestoreLatest(ctx, "main")
+	require.ErrorIs(t, err, jvs.ErrHookFailed)
+	assert.Equal(t, []string{"before restore main"}, calls)
+	assert.FileExists(t, filepath.Join(client.WorktreePayloadPath("main"), "keep.txt"))
+
+	calls = nil
+	_, err = client.GC(ctx, jvs.GCOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"before gc 0", "after gc 0"}, calls)
+}
+
+func TestClient_CompletionWebhooks(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var events []webhook.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e webhook.Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	cfg, err := config.Load(dir)
+	require.NoError(t, err)
+	cfg.Webhooks = []config.Webhook{{URL: srv.URL, Events: []string{
+		webhook.EventSnapshotCompleted, webhook.EventRestoreCompleted, webhook.EventGCCompleted,
+	}}}
+	require.NoError(t, config.Save(dir, cfg))
+	defer config.InvalidateCache(dir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "f.txt"), []byte("v1"), 0644))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{Note: "v1"})
+	require.NoError(t, err)
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{SkipIfUnchanged: true})
+	require.NoError(t, err)
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(desc.SnapshotID)}))
+	_, err = client.GC(ctx, jvs.GCOptions{})
+	require.NoError(t, err)
+	require.NoError(t, jvs.FlushWebhooks(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 3, "unchanged snapshots are not announced")
+	assert.Equal(t, webhook.EventSnapshotCompleted, events[0].Type)
+	require.NotNil(t, events[0].Snapshot)
+	assert.Equal(t, desc.SnapshotID, events[0].Snapshot.SnapshotID)
+	assert.Equal(t, client.RepoID(), events[0].RepoID)
+	assert.Equal(t, webhook.EventRestoreCompleted, events[1].Type)
+	assert.Equal(t, "main", events[1].Worktree)
+	assert.Equal(t, desc.SnapshotID, events[1].SnapshotID)
+	assert.Equal(t, webhook.EventGCCompleted, events[2].Type)
+	assert.NotEmpty(t, events[2].PlanID)
+}
+
+func TestClient_ListSnapshots(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	for i := range 5 {
+		require.NoError(t, os.WriteFile(filepath.Join(mainDir, "file.txt"), []byte(fmt.Sprint(i)), 0644))
+		tags := []string{"nightly"}
+		if i == 4 {
+			tags = append(tags, "release")
+		}
+		_, err := client.Snapshot(ctx, jvs.SnapshotOptions{Tags: tags})
+		require.NoError(t, err)
+	}
+	history, err := client.History(ctx, "main", 0)
+	require.NoError(t, err)
+	_, err = client.Fork(ctx, history[0].SnapshotID, "experiment")
+	require.NoError(t, err)
+	forked, err := client.Snapshot(ctx, jvs.SnapshotOptions{WorktreeName: "experiment"})
+	require.NoError(t, err)
+
+	// Paging through main returns its history
+	var listed []*model.Descriptor
+	opts := jvs.ListOptions{Worktree: "main", Limit: 2}
+	for pages := 1; ; pages++ {
+		page, err := client.ListSnapshots(ctx, opts)
+		require.NoError(t, err)
+		listed = append(listed, page.Snapshots...)
+		if page.NextCursor == "" {
+			assert.Equal(t, 3, pages)
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	assert.Equal(t, history, listed)
+
+	page, err := client.ListSnapshots(ctx, jvs.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.Snapshots, 6)
+	assert.Equal(t, forked.SnapshotID, page.Snapshots[0].SnapshotID)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = client.ListSnapshots(ctx, jvs.ListOptions{Tags: []string{"nightly", "release"}})
+	require.NoError(t, err)
+	require.Len(t, page.Snapshots, 1)
+	assert.Equal(t, history[0].SnapshotID, page.Snapshots[0].SnapshotID)
+
+	page, err = client.ListSnapshots(ctx, jvs.ListOptions{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, page.Snapshots)
+
+	_, err = client.ListSnapshots(ctx, jvs.ListOptions{Cursor: "bogus"})
+	assert.Error(t, err)
+}
+
+func TestClient_GCPlan(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "file.txt"), []byte("v1"), 0644))
+	base, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	// A snapshot of a removed worktree is only kept by retention
+	_, err = client.Fork(ctx, base.SnapshotID, "experiment")
+	require.NoError(t, err)
+	stale, err := client.Snapshot(ctx, jvs.SnapshotOptions{WorktreeName: "experiment"})
+	require.NoError(t, err)
+	require.NoError(t, worktree.NewManager(dir).Remove("experiment"))
+
+	// The default policy keeps it; an explicit one does not
+	plan, err := client.GC(ctx, jvs.GCOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, plan.ToDelete)
+
+	policy := model.RetentionPolicy{KeepMinAge: time.Nanosecond}
+	plan, err = client.GC(ctx, jvs.GCOptions{Policy: &policy, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{stale.SnapshotID}, plan.ToDelete)
+
+	plan, err = client.GCPlan(ctx, policy)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SnapshotID{stale.SnapshotID}, plan.ToDelete)
+	_, err = client.Descriptor(ctx, stale.SnapshotID)
+	require.NoError(t, err, "planning deletes nothing")
+
+	require.NoError(t, client.RunGC(ctx, plan.PlanID))
+	_, err = client.Descriptor(ctx, stale.SnapshotID)
+	assert.ErrorIs(t, err, jvs.ErrSnapshotNotFound)
+	_, err = client.Descriptor(ctx, base.SnapshotID)
+	require.NoError(t, err)
+
+	_, err = client.GCPlan(ctx, model.RetentionPolicy{KeepMinSnapshots: -1})
+	assert.Error(t, err)
+}
+
 func TestWorktreePayloadPath(t *testing.T) {
 	dir := testRepoDir(t)
 	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
@@ -353,3 +798,994 @@ func TestFullLifecycle_CreateSnapshotRestoreCleanup(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, plan.CandidateCount) // only 1 snapshot, protected as HEAD
 }
+
+func TestClient_CorrelationIDs(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+	mainDir := client.WorktreePayloadPath("main")
+	logPath := audit.LogPath(dir)
+
+	// Separate calls get separate IDs
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "a.txt"), []byte("a"), 0644))
+	_, err = client.Snapshot(context.Background(), jvs.SnapshotOptions{Note: "one"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "a.txt"), []byte("b"), 0644))
+	_, err = client.Snapshot(context.Background(), jvs.SnapshotOptions{Note: "two"})
+	require.NoError(t, err)
+
+	records, err := audit.Read(logPath, audit.Filter{EventType: model.EventTypeSnapshotCreate})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.NotEmpty(t, records[0].CorrelationID)
+	assert.NotEqual(t, records[0].CorrelationID, records[1].CorrelationID)
+
+	// A caller-supplied ID ties several calls together
+	ctx := jvs.WithCorrelationID(context.Background(), "job-42")
+	_, err = client.AcquireLease(ctx, "main", "agent", time.Minute)
+	require.NoError(t, err)
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{Note: "three"})
+	require.NoError(t, err)
+	require.NoError(t, client.ReleaseLease(ctx, "main", "agent"))
+
+	records, err = audit.Read(logPath, audit.Filter{CorrelationID: "job-42"})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, model.EventTypeLeaseAcquire, records[0].EventType)
+	assert.Equal(t, model.EventTypeSnapshotCreate, records[1].EventType)
+	assert.Equal(t, model.EventTypeLeaseRelease, records[2].EventType)
+}
+
+func TestClient_ConcurrentCorrelationIDs(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "a.txt"), []byte("a"), 0644))
+	desc, err := client.Snapshot(context.Background(), jvs.SnapshotOptions{Note: "base"})
+	require.NoError(t, err)
+
+	// Concurrent calls on the same repository keep their own IDs
+	const calls = 8
+	var wg sync.WaitGroup
+	errs := make([]error, calls)
+	for i := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := jvs.WithCorrelationID(context.Background(), fmt.Sprintf("job-%d", i))
+			_, errs[i] = client.Annotate(ctx, desc.SnapshotID, fmt.Sprintf("note %d", i), jvs.AnnotateOptions{Append: true})
+		}()
+	}
+	wg.Wait()
+
+	for i := range calls {
+		require.NoError(t, errs[i])
+		records, err := audit.Read(audit.LogPath(dir), audit.Filter{CorrelationID: fmt.Sprintf("job-%d", i)})
+		require.NoError(t, err)
+		require.Len(t, records, 1, "job-%d", i)
+		assert.Equal(t, model.EventTypeSnapshotAmend, records[0].EventType)
+	}
+}
+
+func TestClient_ConcurrentIdentities(t *testing.T) {
+	dir := testRepoDir(t)
+	base, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(base.WorktreePayloadPath("main"), "a.txt"), []byte("a"), 0644))
+	desc, err := base.Snapshot(context.Background(), jvs.SnapshotOptions{Note: "base"})
+	require.NoError(t, err)
+
+	// Clients with different identities on one repository keep them apart
+	const clients = 8
+	var wg sync.WaitGroup
+	errs := make([]error, clients)
+	for i := range clients {
+		client, err := jvs.Open(dir, jvs.WithIdentity(fmt.Sprintf("agent-%d", i)))
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = client.Pin(context.Background(), desc.SnapshotID, jvs.PinOptions{Reason: "keep"})
+		}()
+	}
+	wg.Wait()
+
+	records, err := audit.Read(audit.LogPath(dir), audit.Filter{EventType: model.EventTypeSnapshotPin})
+	require.NoError(t, err)
+	require.Len(t, records, clients)
+	authors := make(map[string]bool)
+	for i := range clients {
+		require.NoError(t, errs[i])
+		authors[records[i].Author] = true
+	}
+	for i := range clients {
+		assert.True(t, authors[fmt.Sprintf("agent-%d", i)], "agent-%d", i)
+	}
+}
+
+func TestClient_EnableAuditBatching(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+	mainDir := client.WorktreePayloadPath("main")
+	logPath := audit.LogPath(dir)
+
+	batch, err := client.EnableAuditBatching(jvs.AuditBatchOptions{FlushInterval: time.Hour})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(mainDir, "a.txt"), []byte{byte('a' + i)}, 0644))
+		_, err = client.Snapshot(context.Background(), jvs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, batch.Close())
+
+	records, err := audit.Read(logPath, audit.Filter{EventType: model.EventTypeSnapshotCreate})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	// The chain stays intact across batched and unbatched writes
+	result, err := client.Doctor(context.Background(), true)
+	require.NoError(t, err)
+	assert.True(t, result.Healthy)
+}
+
+func TestClient_MetadataCache(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{Name: "test-repo"})
+	require.NoError(t, err)
+	mainDir := client.WorktreePayloadPath("main")
+	ctx := context.Background()
+	assert.False(t, client.CacheStats().Enabled)
+
+	client.EnableMetadataCache(0)
+	has, err := client.HasSnapshots(ctx, "main")
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	// Snapshot invalidates the cached worktree config
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "a.txt"), []byte("a"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{Note: "first"})
+	require.NoError(t, err)
+	latest, err := client.LatestSnapshot(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, first.SnapshotID, latest.SnapshotID)
+
+	for i := 0; i < 3; i++ {
+		_, err = client.LatestSnapshot(ctx, "main")
+		require.NoError(t, err)
+	}
+	stats := client.CacheStats()
+	assert.True(t, stats.Enabled)
+	assert.Equal(t, uint64(3), stats.Descriptors.Hits)
+	assert.Equal(t, uint64(1), stats.Descriptors.Misses)
+	assert.Equal(t, uint64(3), stats.Worktrees.Hits)
+
+	// Callers get copies
+	latest.Tags = append(latest.Tags, "mutated")
+	again, err := client.Descriptor(ctx, first.SnapshotID)
+	require.NoError(t, err)
+	assert.Empty(t, again.Tags)
+
+	// Restore moves HEAD; the cached config must not be served afterwards
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "a.txt"), []byte("b"), 0644))
+	second, err := client.Snapshot(ctx, jvs.SnapshotOptions{Note: "second"})
+	require.NoError(t, err)
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(first.SnapshotID)}))
+	cfg, err := client.Worktree(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, first.SnapshotID, cfg.HeadSnapshotID)
+	assert.Equal(t, second.SnapshotID, cfg.LatestSnapshotID)
+
+	// Changes by another client are seen after explicit invalidation
+	other, err := jvs.Open(dir)
+	require.NoError(t, err)
+	require.NoError(t, other.RestoreLatest(ctx, "main"))
+	cfg, err = client.Worktree(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, first.SnapshotID, cfg.HeadSnapshotID, "stale until invalidated")
+	client.InvalidateMetadataCache()
+	cfg, err = client.Worktree(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, second.SnapshotID, cfg.HeadSnapshotID)
+}
+
+func TestClient_SnapshotNotReady(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v1"), 0644))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	// A payload without its marker must never be read
+	require.NoError(t, os.Remove(filepath.Join(dir, ".jvs", "snapshots", string(desc.SnapshotID), ".READY")))
+
+	require.ErrorIs(t, client.RestoreLatest(ctx, "main"), jvs.ErrSnapshotNotReady)
+	_, err = client.Fork(ctx, desc.SnapshotID, "forked")
+	require.ErrorIs(t, err, jvs.ErrSnapshotNotReady)
+	require.ErrorIs(t, client.Verify(ctx, desc.SnapshotID), jvs.ErrSnapshotNotReady)
+	assert.NoDirExists(t, client.WorktreePayloadPath("forked"))
+}
+
+func TestRestore_Ephemeral(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v1"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{Tags: []string{"v1"}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v2"), 0644))
+	second, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: "v1", Ephemeral: true}))
+	data, err := os.ReadFile(filepath.Join(mainDir, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// Metadata is untouched: still at HEAD, not detached
+	cfg, err := client.Worktree(ctx, "main")
+	require.NoError(t, err)
+	assert.False(t, cfg.IsDetached())
+	assert.Equal(t, second.SnapshotID, cfg.HeadSnapshotID)
+
+	records, err := audit.Read(audit.LogPath(dir), audit.Filter{EventType: model.EventTypeRestoreEphemeral})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, first.SnapshotID, records[0].SnapshotID)
+	assert.Equal(t, string(second.SnapshotID), records[0].Details["head_snapshot_id"])
+	records, err = audit.Read(audit.LogPath(dir), audit.Filter{EventType: model.EventTypeRestore})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	// HEAD works ephemerally too
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: "HEAD", Ephemeral: true}))
+	data, err = os.ReadFile(filepath.Join(mainDir, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+}
+
+func TestClient_Pins(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v1"), 0644))
+	base, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	// A snapshot of a removed worktree is only kept by its pin
+	_, err = client.Fork(ctx, base.SnapshotID, "investigation")
+	require.NoError(t, err)
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{WorktreeName: "investigation"})
+	require.NoError(t, err)
+	require.NoError(t, worktree.NewManager(dir).Remove("investigation"))
+
+	p, err := client.Pin(ctx, first.SnapshotID, jvs.PinOptions{Reason: "investigation", TTL: time.Hour})
+	require.NoError(t, err)
+	require.NotNil(t, p.ExpiresAt)
+	pins, err := client.ListPins(ctx)
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, first.SnapshotID, pins[0].SnapshotID)
+
+	opts := jvs.GCOptions{KeepMinAge: time.Nanosecond, DryRun: true}
+	plan, err := client.GC(ctx, opts)
+	require.NoError(t, err)
+	assert.NotContains(t, plan.ToDelete, first.SnapshotID)
+	assert.Equal(t, 1, plan.ProtectedByPin)
+
+	require.NoError(t, client.Unpin(ctx, first.SnapshotID))
+	require.NoError(t, client.Unpin(ctx, first.SnapshotID), "unpinning twice succeeds")
+	plan, err = client.GC(ctx, opts)
+	require.NoError(t, err)
+	assert.Contains(t, plan.ToDelete, first.SnapshotID)
+
+	_, err = client.Pin(ctx, "1700000000000-deadbeef", jvs.PinOptions{})
+	assert.Error(t, err)
+}
+
+func TestGC_CheckConflicts(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v1"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v2"), 0644))
+	latest, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	// An attached consumer at an older snapshot may still return to latest
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(first.SnapshotID)}))
+	_, err = client.AcquireLease(ctx, "main", "debug-pod", time.Hour)
+	require.NoError(t, err)
+
+	opts := jvs.GCOptions{KeepMinAge: time.Nanosecond, DryRun: true, CheckConflicts: true}
+	plan, err := client.GC(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, plan.Conflicts, 1)
+	assert.Equal(t, latest.SnapshotID, plan.Conflicts[0].SnapshotID)
+	assert.Equal(t, "debug-pod", plan.Conflicts[0].Holder)
+
+	opts.DryRun = false
+	_, err = client.GC(ctx, opts)
+	require.ErrorIs(t, err, jvs.ErrGCPlanMismatch)
+	var conflictErr *jvs.GCConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Len(t, conflictErr.Conflicts, 1)
+	require.NoError(t, client.Verify(ctx, latest.SnapshotID), "nothing was deleted")
+}
+
+func TestSnapshot_LockTimeout(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	cfg := config.Default()
+	cfg.LockMaxWait = "20ms"
+	require.NoError(t, config.Save(dir, cfg))
+
+	gc, err := repolock.NewManager(dir).Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.ErrorIs(t, err, jvs.ErrLockTimeout)
+	var timeout *jvs.LockTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	require.Len(t, timeout.Blockers, 1)
+	assert.Equal(t, "gc", timeout.Blockers[0].Operation)
+
+	require.NoError(t, gc.Release())
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+}
+
+func TestClient_WithLockWait(t *testing.T) {
+	dir := testRepoDir(t)
+	_, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Two clients of one repository: GC by one waits for, or gives up on,
+	// the other's operations
+	client, err := jvs.Open(dir, jvs.WithLockWait(0))
+	require.NoError(t, err)
+	other, err := jvs.Open(dir)
+	require.NoError(t, err)
+	_, err = other.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	gc, err := repolock.NewManager(dir).Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.ErrorIs(t, err, jvs.ErrRepoBusy)
+	err = client.RestoreLatest(ctx, "main")
+	require.ErrorIs(t, err, jvs.ErrRepoBusy)
+	_, err = client.GC(ctx, jvs.GCOptions{})
+	require.ErrorIs(t, err, jvs.ErrRepoBusy)
+
+	// A longer wait outlasts the holder
+	waiting, err := jvs.Open(dir, jvs.WithLockWait(10*time.Second))
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		gc.Release()
+	}()
+	_, err = waiting.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+}
+
+func TestClient_Cancellation(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "data.txt"), []byte("v1"), 0644))
+	snap, err := client.Snapshot(context.Background(), jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{Note: "cancelled"})
+	require.ErrorIs(t, err, context.Canceled)
+	err = client.Restore(ctx, jvs.RestoreOptions{Target: string(snap.SnapshotID)})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = client.Fork(ctx, snap.SnapshotID, "cancelled")
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, client.Verify(ctx, snap.SnapshotID), context.Canceled)
+	_, err = client.GC(ctx, jvs.GCOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Nothing changed
+	latest, err := client.LatestSnapshot(context.Background(), "main")
+	require.NoError(t, err)
+	assert.Equal(t, snap.SnapshotID, latest.SnapshotID)
+	require.NoError(t, client.Verify(context.Background(), snap.SnapshotID))
+}
+
+func TestClient_SentinelErrors(t *testing.T) {
+	_, err := jvs.Open(t.TempDir())
+	assert.ErrorIs(t, err, jvs.ErrNotARepo)
+
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	file := filepath.Join(client.WorktreePayloadPath("main"), "data.txt")
+	require.NoError(t, os.WriteFile(file, []byte("v1"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, []byte("v2"), 0644))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	err = client.Restore(ctx, jvs.RestoreOptions{Target: "no-such-snapshot"})
+	assert.ErrorIs(t, err, jvs.ErrSnapshotNotFound)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{WorktreeName: "no-such-worktree"})
+	assert.ErrorIs(t, err, jvs.ErrWorktreeNotFound)
+
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(first.SnapshotID)}))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	assert.ErrorIs(t, err, jvs.ErrDetachedHead)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".jvs", "snapshots", string(first.SnapshotID), "data.txt"), []byte("v3"), 0644))
+	err = client.Verify(ctx, first.SnapshotID)
+	assert.ErrorIs(t, err, jvs.ErrIntegrityFailure)
+	assert.ErrorIs(t, err, errclass.ErrPayloadHashMismatch)
+}
+
+func TestClient_Events(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{}, jvs.WithEngine(model.EngineCopy))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var events []jvs.Event
+	unsubscribe := client.Subscribe(func(e jvs.Event) { events = append(events, e) })
+	chCtx, cancel := context.WithCancel(ctx)
+	ch := client.Events(chCtx, 16)
+
+	// A hardlinked file is copied twice, which the copy engine reports
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v1"), 0644))
+	require.NoError(t, os.Link(filepath.Join(mainDir, "data.txt"), filepath.Join(mainDir, "link.txt")))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(desc.SnapshotID)}))
+
+	require.Len(t, events, 3)
+	assert.Equal(t, jvs.EventSnapshotCreated, events[0].Type)
+	assert.Equal(t, desc.SnapshotID, events[0].Descriptor.SnapshotID)
+	assert.Equal(t, "main", events[0].Worktree)
+	assert.False(t, events[0].Time.IsZero())
+	assert.Equal(t, jvs.EventDegradationDetected, events[1].Type)
+	assert.Equal(t, "snapshot", events[1].Operation)
+	assert.Equal(t, []string{"hardlink"}, events[1].Degradations)
+	assert.Equal(t, jvs.EventRestoreCompleted, events[2].Type)
+	assert.Equal(t, desc.SnapshotID, events[2].SnapshotID)
+	unsubscribe()
+
+	// Collecting a removed worktree's snapshot
+	_, err = client.Fork(ctx, desc.SnapshotID, "experiment")
+	require.NoError(t, err)
+	stale, err := client.Snapshot(ctx, jvs.SnapshotOptions{WorktreeName: "experiment"})
+	require.NoError(t, err)
+	require.NoError(t, worktree.NewManager(dir).Remove("experiment"))
+	plan, err := client.GC(ctx, jvs.GCOptions{KeepMinAge: time.Nanosecond})
+	require.NoError(t, err)
+	assert.Len(t, events, 3, "no events after unsubscribing")
+
+	cancel()
+	var received []jvs.Event
+	for e := range ch {
+		received = append(received, e)
+	}
+	require.Len(t, received, 5)
+	assert.Equal(t, stale.SnapshotID, received[3].SnapshotID)
+	gcEvent := received[4]
+	assert.Equal(t, jvs.EventGCDeleted, gcEvent.Type)
+	assert.Equal(t, plan.PlanID, gcEvent.PlanID)
+	assert.Equal(t, []model.SnapshotID{stale.SnapshotID}, gcEvent.Deleted)
+}
+
+func TestClient_Progress(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "data.txt"), make([]byte, 4096), 0644))
+
+	last := map[string][2]int64{}
+	record := func(phase string, done, total int64) {
+		assert.LessOrEqual(t, done, total)
+		last[phase] = [2]int64{done, total}
+	}
+	snap, err := client.Snapshot(ctx, jvs.SnapshotOptions{Progress: record})
+	require.NoError(t, err)
+	assert.Equal(t, [2]int64{4096, 4096}, last["copy"])
+
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(snap.SnapshotID), Progress: record}))
+	require.Contains(t, last, "clone")
+	assert.Equal(t, last["clone"][1], last["clone"][0])
+	assert.GreaterOrEqual(t, last["clone"][1], int64(4096))
+}
+
+func TestClient_Status(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, os.WriteFile(filepath.Join(client.WorktreePayloadPath("main"), "data.txt"), []byte("v1"), 0644))
+	snap, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = client.AcquireLease(ctx, "main", "debug-pod", time.Hour)
+	require.NoError(t, err)
+
+	// An in-flight restore, as left by its intent record
+	intent := model.IntentRecord{
+		SnapshotID:   snap.SnapshotID,
+		WorktreeName: "main",
+		StartedAt:    time.Now().UTC(),
+		Operation:    model.IntentOperationRestore,
+	}
+	data, err := json.Marshal(intent)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repo.IntentsDir(dir), repo.RestoreIntentPrefix+"x.json"), data, 0644))
+
+	gc, err := repolock.NewManager(dir).Acquire(model.LockExclusive, "gc")
+	require.NoError(t, err)
+	defer gc.Release()
+
+	status, err := client.Status(ctx, jvs.StatusOptions{Events: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Stats.Snapshots)
+	require.Len(t, status.Worktrees, 1)
+	assert.Equal(t, snap.SnapshotID, status.Worktrees[0].HeadSnapshotID)
+	require.NotNil(t, status.Worktrees[0].Lease)
+	assert.Equal(t, "debug-pod", status.Worktrees[0].Lease.Holder)
+	require.Len(t, status.Operations, 1)
+	assert.Equal(t, "restore", status.Operations[0].Operation)
+	assert.Equal(t, snap.SnapshotID, status.Operations[0].SnapshotID)
+	require.Len(t, status.LockQueue, 1)
+	assert.Equal(t, "gc", status.LockQueue[0].Operation)
+	assert.True(t, status.LockQueue[0].Held())
+	require.Len(t, status.Events, 1)
+	assert.Equal(t, model.EventTypeLeaseAcquire, status.Events[0].EventType)
+
+	status, err = client.Status(ctx, jvs.StatusOptions{Events: -1})
+	require.NoError(t, err)
+	assert.Empty(t, status.Events)
+}
+
+func TestClient_Status_WorktreeChanges(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	dataPath := filepath.Join(client.WorktreePayloadPath("main"), "data.txt")
+	require.NoError(t, os.WriteFile(dataPath, []byte("v1"), 0644))
+	snap, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	status, err := client.Status(ctx, jvs.StatusOptions{Worktree: "main"})
+	require.NoError(t, err)
+	require.NotNil(t, status.Changes)
+	assert.Equal(t, snap.SnapshotID, status.Changes.HeadSnapshotID)
+	assert.False(t, status.Changes.Dirty)
+
+	require.NoError(t, os.WriteFile(dataPath, []byte("v2, longer"), 0644))
+	status, err = client.Status(ctx, jvs.StatusOptions{Worktree: "main"})
+	require.NoError(t, err)
+	assert.True(t, status.Changes.Dirty)
+	require.Len(t, status.Changes.Modified, 1)
+	assert.Equal(t, "data.txt", status.Changes.Modified[0].Path)
+
+	status, err = client.Status(ctx, jvs.StatusOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, status.Changes, "only computed when asked for")
+}
+
+func TestClient_Status_Stale(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	status, err := client.Status(ctx, jvs.StatusOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, status.Worktrees[0].Stale, "no staleness check by default")
+
+	require.NoError(t, config.Save(dir, &config.Config{Staleness: &config.Staleness{MaxAge: "1ns"}}))
+	status, err = client.Status(ctx, jvs.StatusOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, status.Worktrees[0].Stale)
+	assert.Equal(t, "main", status.Worktrees[0].Stale.Worktree)
+	assert.Equal(t, time.Nanosecond, status.Worktrees[0].Stale.MaxAge)
+}
+
+func TestSnapshot_Exclude(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.MkdirAll(filepath.Join(mainDir, "cache"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "cache", "blob"), []byte("cached"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "train.py"), []byte("train"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, ".jvsignore"), []byte("*.py\n"), 0644))
+
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{Exclude: []string{"cache/", "!train.py"}})
+	require.NoError(t, err)
+	require.NoError(t, client.Verify(ctx, desc.SnapshotID))
+	assert.NoDirExists(t, filepath.Join(client.RepoRoot(), ".jvs", "snapshots", string(desc.SnapshotID), "cache"))
+
+	require.NoError(t, os.RemoveAll(filepath.Join(mainDir, "train.py")))
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(desc.SnapshotID)}))
+	assert.FileExists(t, filepath.Join(mainDir, "train.py"))
+}
+
+func TestSnapshot_Incremental(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "model.bin"), []byte("weights"), 0644))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{Incremental: true})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "log.txt"), []byte("step 2"), 0644))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{Incremental: true})
+	require.NoError(t, err)
+	require.NotNil(t, desc.Incremental)
+	assert.Equal(t, 1, desc.Incremental.Linked)
+	assert.Equal(t, 1, desc.Incremental.Copied)
+	require.NoError(t, client.Verify(ctx, desc.SnapshotID))
+}
+
+func TestClient_Diff(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "config.txt"), []byte("lr=0.1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "old.txt"), []byte("old"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "config.txt"), []byte("lr=0.01\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(mainDir, "old.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "new.txt"), []byte("new file"), 0644))
+	second, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	result, err := client.Diff(ctx, first.SnapshotID, second.SnapshotID, jvs.DiffOptions{Patch: true})
+	require.NoError(t, err)
+	require.Len(t, result.Added, 1)
+	require.Len(t, result.Removed, 1)
+	require.Len(t, result.Modified, 1)
+	assert.Equal(t, "new.txt", result.Added[0].Path)
+	assert.Equal(t, int64(8), result.Added[0].SizeDelta)
+	assert.Equal(t, int64(-3), result.Removed[0].SizeDelta)
+	assert.Equal(t, int64(1), result.Modified[0].SizeDelta)
+	assert.Equal(t, int64(6), result.SizeDelta)
+	assert.Contains(t, result.Modified[0].Patch, "-lr=0.1\n+lr=0.01\n")
+	assert.Equal(t, first.CreatedAt, result.FromTime)
+
+	_, err = client.Diff(ctx, first.SnapshotID, "missing", jvs.DiffOptions{})
+	require.Error(t, err)
+}
+
+func TestClient_DiffWorktree(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, ".jvsignore"), []byte("*.tmp\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "config.txt"), []byte("lr=0.1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "old.txt"), []byte("old"), 0644))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "config.txt"), []byte("lr=0.01\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(mainDir, "old.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "new.txt"), []byte("new file"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "scratch.tmp"), []byte("ignored"), 0644))
+
+	result, err := client.DiffWorktree(ctx, desc.SnapshotID, "main", jvs.DiffOptions{Patch: true})
+	require.NoError(t, err)
+	assert.Equal(t, desc.SnapshotID, result.FromSnapshotID)
+	assert.Equal(t, "main", result.ToWorktree)
+	assert.Equal(t, desc.CreatedAt, result.FromTime)
+	require.Len(t, result.Added, 1)
+	require.Len(t, result.Removed, 1)
+	require.Len(t, result.Modified, 1)
+	assert.Equal(t, "new.txt", result.Added[0].Path)
+	assert.NotEmpty(t, result.Added[0].NewHash)
+	assert.Equal(t, "old.txt", result.Removed[0].Path)
+	assert.NotEmpty(t, result.Removed[0].OldHash)
+	modified := result.Modified[0]
+	assert.Equal(t, int64(7), modified.OldSize)
+	assert.Equal(t, int64(8), modified.Size)
+	assert.NotEqual(t, modified.OldHash, modified.NewHash)
+	assert.Contains(t, modified.Patch, "-lr=0.1\n+lr=0.01\n")
+
+	_, err = client.DiffWorktree(ctx, desc.SnapshotID, "missing", jvs.DiffOptions{})
+	assert.ErrorIs(t, err, jvs.ErrWorktreeNotFound)
+}
+
+func TestFork_Quota(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "model.bin"), make([]byte, 64<<10), 0644))
+	desc, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, config.Save(client.RepoRoot(), &config.Config{Quota: &config.Quota{MaxSize: "100KiB"}}))
+	_, err = client.Fork(ctx, desc.SnapshotID, "experiment")
+	require.ErrorIs(t, err, jvs.ErrQuotaExceeded)
+	var qerr *jvs.QuotaError
+	require.ErrorAs(t, err, &qerr)
+	assert.Equal(t, int64(100<<10), qerr.Limit)
+	assert.GreaterOrEqual(t, qerr.Needed, int64(64<<10))
+	assert.NoDirExists(t, client.WorktreePayloadPath("experiment"))
+
+	require.NoError(t, config.Save(client.RepoRoot(), &config.Config{Quota: &config.Quota{MaxSize: "1MiB"}}))
+	_, err = client.Fork(ctx, desc.SnapshotID, "experiment")
+	require.NoError(t, err)
+}
+
+func TestClient_Import(t *testing.T) {
+	base := testRepoDir(t)
+	src, err := jvs.Init(filepath.Join(base, "src"), jvs.InitOptions{})
+	require.NoError(t, err)
+	dest, err := jvs.Init(filepath.Join(base, "dest"), jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, os.WriteFile(filepath.Join(src.WorktreePayloadPath("main"), "data.txt"), []byte("v1"), 0644))
+	snap, err := src.Snapshot(ctx, jvs.SnapshotOptions{Note: "shared"})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = bundle.Write(&buf, src.RepoRoot(), snap.SnapshotID, bundle.Options{Compress: true})
+	require.NoError(t, err)
+	data := buf.Bytes()
+
+	result, err := dest.Import(ctx, bytes.NewReader(data), jvs.ImportOptions{Worktree: "review"})
+	require.NoError(t, err)
+	assert.Equal(t, snap.SnapshotID, result.Descriptor.SnapshotID)
+	assert.True(t, result.Compressed)
+	require.NotNil(t, result.Worktree)
+	assert.Equal(t, snap.SnapshotID, result.Worktree.HeadSnapshotID)
+	require.NoError(t, dest.Verify(ctx, snap.SnapshotID))
+	content, err := os.ReadFile(filepath.Join(dest.WorktreePayloadPath("review"), "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	_, err = dest.Import(ctx, bytes.NewReader(data), jvs.ImportOptions{})
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestClient_Export(t *testing.T) {
+	base := testRepoDir(t)
+	src, err := jvs.Init(filepath.Join(base, "src"), jvs.InitOptions{})
+	require.NoError(t, err)
+	dest, err := jvs.Init(filepath.Join(base, "dest"), jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, os.WriteFile(filepath.Join(src.WorktreePayloadPath("main"), "data.txt"), []byte("v1"), 0644))
+	snap, err := src.Snapshot(ctx, jvs.SnapshotOptions{Note: "archived"})
+	require.NoError(t, err)
+
+	// The bundle streams through a pipe, as to an upload
+	pr, pw := io.Pipe()
+	exported := make(chan *jvs.ExportResult, 1)
+	go func() {
+		result, err := src.Export(ctx, pw, snap.SnapshotID, jvs.ExportOptions{Compress: true})
+		exported <- result
+		pw.CloseWithError(err)
+	}()
+	result, err := dest.Import(ctx, pr, jvs.ImportOptions{Worktree: "restored"})
+	require.NoError(t, err)
+	assert.True(t, result.Compressed)
+	exportResult := <-exported
+	require.NotNil(t, exportResult)
+	assert.Equal(t, 1, exportResult.Files)
+	assert.True(t, exportResult.Compressed)
+	content, err := os.ReadFile(filepath.Join(dest.WorktreePayloadPath("restored"), "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = src.Export(cancelled, io.Discard, snap.SnapshotID, jvs.ExportOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = src.Export(ctx, io.Discard, "1700000000000-deadbeef", jvs.ExportOptions{})
+	assert.Error(t, err)
+}
+
+func TestClient_MaterializeAt(t *testing.T) {
+	dir := testRepoDir(t)
+	now := time.Now()
+	client, err := jvs.Init(dir, jvs.InitOptions{}, jvs.WithMaterializeTTL(time.Minute), jvs.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	// Copies are read-only; remove them before the temp dir is cleaned up
+	t.Cleanup(func() {
+		m := materialize.NewManager(dir)
+		m.SetClock(func() time.Time { return now.Add(24 * time.Hour) })
+		m.Sweep()
+	})
+	ctx := context.Background()
+
+	dataPath := filepath.Join(client.WorktreePayloadPath("main"), "data.txt")
+	require.NoError(t, os.WriteFile(dataPath, []byte("then"), 0644))
+	then, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dataPath, []byte("now"), 0644))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	mat, err := client.MaterializeAt(ctx, "main", then.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute).UTC(), mat.ExpiresAt)
+	old, err := os.ReadFile(filepath.Join(mat.Path, "data.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "then", string(old))
+	current, err := os.ReadFile(dataPath)
+	require.NoError(t, err)
+	assert.Equal(t, "now", string(current))
+
+	wt, err := client.Worktree(ctx, "main")
+	require.NoError(t, err)
+	assert.False(t, wt.IsDetached(), "materializing does not detach the worktree")
+
+	// Expired copies are removed by later calls
+	now = now.Add(time.Hour)
+	_, err = client.MaterializeAt(ctx, "main", wt.HeadSnapshotID)
+	require.NoError(t, err)
+	assert.NoDirExists(t, mat.Path)
+}
+
+// metricValue returns the value of a counter, or the sample count of a
+// histogram, whose labels include labels.
+func metricValue(t *testing.T, client *jvs.Client, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := client.Metrics().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if want, ok := labels[l.GetName()]; ok && want != l.GetValue() {
+					continue metrics
+				}
+			}
+			if h := m.GetHistogram(); h != nil {
+				return float64(h.GetSampleCount())
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestClient_Metrics(t *testing.T) {
+	dir := testRepoDir(t)
+	client, err := jvs.Init(dir, jvs.InitOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mainDir := client.WorktreePayloadPath("main")
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v1"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte("v2"), 0644))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	skipped, err := client.Snapshot(ctx, jvs.SnapshotOptions{SkipIfUnchanged: true})
+	require.NoError(t, err)
+	require.True(t, skipped.Skipped)
+	assert.Equal(t, 2.0, metricValue(t, client, "jvs_snapshots_created_total", nil))
+	assert.Equal(t, 4.0, metricValue(t, client, "jvs_bytes_copied_total", map[string]string{"operation": "snapshot"}))
+
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(first.SnapshotID)}))
+	assert.Equal(t, 1.0, metricValue(t, client, "jvs_restore_duration_seconds", nil))
+	assert.Equal(t, 2.0, metricValue(t, client, "jvs_bytes_copied_total", map[string]string{"operation": "restore"}))
+
+	// The restore detached main, so its latest snapshot is collectable
+	plan, err := client.GC(ctx, jvs.GCOptions{KeepMinAge: time.Nanosecond})
+	require.NoError(t, err)
+	require.Len(t, plan.ToDelete, 1)
+	assert.Equal(t, 1.0, metricValue(t, client, "jvs_gc_deleted_snapshots_total", nil))
+
+	require.Error(t, client.Verify(ctx, "1708300800000-deadbeef"))
+	assert.Equal(t, 0.0, metricValue(t, client, "jvs_verify_failures_total", nil), "a missing snapshot is not a verify failure")
+	tampered := filepath.Join(repo.SnapshotPath(dir, first.SnapshotID), "data.txt")
+	require.NoError(t, os.WriteFile(tampered, []byte("xx"), 0644))
+	require.ErrorIs(t, client.Verify(ctx, first.SnapshotID), errclass.ErrPayloadHashMismatch)
+	_, err = client.VerifyAll(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 2.0, metricValue(t, client, "jvs_verify_failures_total", nil))
+
+	other, err := jvs.Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, metricValue(t, other, "jvs_snapshots_created_total", nil), "metrics are per client")
+}
+
+func TestClient_Tracing(t *testing.T) {
+	dir := testRepoDir(t)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client, err := jvs.Init(dir, jvs.InitOptions{}, jvs.WithTracerProvider(tp))
+	require.NoError(t, err)
+
+	ctx, parent := tp.Tracer("sandbox-manager").Start(context.Background(), "reset workspace")
+	dataPath := filepath.Join(client.WorktreePayloadPath("main"), "data.txt")
+	require.NoError(t, os.WriteFile(dataPath, []byte("v1"), 0644))
+	first, err := client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dataPath, []byte("v2"), 0644))
+	_, err = client.Snapshot(ctx, jvs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, client.Restore(ctx, jvs.RestoreOptions{Target: string(first.SnapshotID)}))
+	_, err = client.GC(ctx, jvs.GCOptions{KeepMinAge: time.Nanosecond})
+	require.NoError(t, err)
+	require.Error(t, client.Verify(ctx, "1708300800000-deadbeef"))
+	parent.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	for parentName, children := range map[string][]string{
+		"reset workspace": {"jvs.snapshot", "jvs.restore", "jvs.gc", "jvs.verify"},
+		"jvs.snapshot":    {"jvs.snapshot.fsync", "jvs.snapshot.descriptor_write", "jvs.snapshot.head_update"},
+		"jvs.restore":     {"jvs.restore.verify", "jvs.restore.clone", "jvs.restore.swap", "jvs.restore.head_update"},
+		"jvs.gc":          {"jvs.gc.plan", "jvs.gc.run"},
+	} {
+		require.Contains(t, spans, parentName)
+		for _, name := range children {
+			require.Contains(t, spans, name)
+			assert.Equal(t, spans[parentName].SpanContext().SpanID(), spans[name].Parent().SpanID(), "%s is a child of %s", name, parentName)
+		}
+	}
+
+	snapshotSpan := spans["jvs.snapshot"]
+	assert.Contains(t, snapshotSpan.Attributes(), attribute.String("jvs.worktree", "main"))
+	phase := spans["jvs.snapshot.descriptor_write"]
+	assert.False(t, phase.StartTime().Before(snapshotSpan.StartTime()))
+	assert.False(t, phase.EndTime().After(snapshotSpan.EndTime()))
+	assert.Contains(t, spans["jvs.gc.run"].Attributes(), attribute.Int("jvs.gc.deleted", 1))
+	assert.Equal(t, codes.Error, spans["jvs.verify"].Status().Code)
+	assert.Equal(t, codes.Unset, spans["jvs.restore"].Status().Code)
+}
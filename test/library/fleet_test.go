@@ -0,0 +1,113 @@
+package library_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jvs-project/jvs/pkg/jvs"
+)
+
+// setupFleet creates repos a, b and team/c below a root, each with one
+// snapshot, and returns the root.
+func setupFleet(t *testing.T) string {
+	t.Helper()
+	root := testRepoDir(t)
+	for _, name := range []string{"a", "b", filepath.Join("team", "c")} {
+		client, err := jvs.Init(filepath.Join(root, name), jvs.InitOptions{})
+		require.NoError(t, err)
+		mainDir := client.WorktreePayloadPath("main")
+		require.NoError(t, os.WriteFile(filepath.Join(mainDir, "data.txt"), []byte(name), 0644))
+		_, err = client.Snapshot(context.Background(), jvs.SnapshotOptions{Note: name})
+		require.NoError(t, err)
+	}
+	// Not repositories
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".hidden", ".jvs"), 0755))
+	return root
+}
+
+func TestFleet_Discover(t *testing.T) {
+	root := setupFleet(t)
+
+	repos, err := jvs.NewFleet(jvs.FleetOptions{Root: root}).Discover()
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+	assert.Equal(t, filepath.Join(root, "a"), repos[0])
+	assert.Equal(t, filepath.Join(root, "b"), repos[1])
+	assert.Equal(t, filepath.Join(root, "team", "c"), repos[2])
+
+	repos, err = jvs.NewFleet(jvs.FleetOptions{Root: root, MaxDepth: 1}).Discover()
+	require.NoError(t, err)
+	assert.Len(t, repos, 2, "team/c is beyond depth 1")
+
+	_, err = jvs.NewFleet(jvs.FleetOptions{Root: filepath.Join(root, "missing")}).Discover()
+	assert.Error(t, err)
+}
+
+func TestFleet_VerifyReportsCorruptRepo(t *testing.T) {
+	root := setupFleet(t)
+
+	// Tamper with b's only snapshot payload
+	snapshotsDir := filepath.Join(root, "b", ".jvs", "snapshots")
+	entries, err := os.ReadDir(snapshotsDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NoError(t, os.WriteFile(filepath.Join(snapshotsDir, entries[0].Name(), "data.txt"), []byte("tampered"), 0644))
+
+	report, err := jvs.NewFleet(jvs.FleetOptions{Root: root, Concurrency: 2}).Verify(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "verify", report.Operation)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Failed)
+
+	require.Len(t, report.Results, 3)
+	assert.True(t, report.Results[0].OK)
+	bad := report.Results[1]
+	assert.False(t, bad.OK)
+	assert.Contains(t, bad.Error, "failed verification")
+	vr, ok := bad.Report.(*jvs.VerifyReport)
+	require.True(t, ok, "report is kept for failed repositories")
+	assert.Equal(t, 1, vr.Snapshots)
+	assert.Len(t, vr.Failures, 1)
+	assert.True(t, report.Results[2].OK)
+}
+
+func TestFleet_DoctorGCPlanStats(t *testing.T) {
+	root := setupFleet(t)
+	fleet := jvs.NewFleet(jvs.FleetOptions{Root: root})
+	ctx := context.Background()
+
+	report, err := fleet.Doctor(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Failed)
+
+	report, err = fleet.GCPlan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 0, report.Failed)
+
+	report, err = fleet.Stats(ctx)
+	require.NoError(t, err)
+	for _, r := range report.Results {
+		stats, ok := r.Report.(*jvs.RepoStats)
+		require.True(t, ok)
+		assert.Equal(t, 1, stats.Worktrees)
+		assert.Equal(t, 1, stats.Snapshots)
+		assert.Positive(t, stats.SnapshotBytes)
+	}
+}
+
+func TestFleet_CancelledContext(t *testing.T) {
+	root := setupFleet(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := jvs.NewFleet(jvs.FleetOptions{Root: root}).Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Failed)
+}